@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// audit records in the audit log that r's authenticated user took action on
+// target, with the reason that r gives (see thesrc.AuditReasonHeader). It is
+// called after the action is taken; if recording it fails, the error should
+// still be returned, so that the unrecorded action is noticed.
+func audit(r *http.Request, action, target, details string) error {
+	entry, err := auditEntry(r)
+	if err != nil {
+		return err
+	}
+	entry.Action, entry.Target, entry.Details = action, target, details
+	return store(r).AuditLog.Create(entry)
+}
+
+// auditEntry returns an audit log entry for an action that r's
+// authenticated user takes, with the reason that r gives (see
+// thesrc.AuditReasonHeader). The caller fills in the rest.
+func auditEntry(r *http.Request) (*thesrc.AuditEntry, error) {
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		return nil, err
+	}
+	reason, err := url.QueryUnescape(r.Header.Get(thesrc.AuditReasonHeader))
+	if err != nil {
+		reason = r.Header.Get(thesrc.AuditReasonHeader)
+	}
+	return &thesrc.AuditEntry{ActorUserID: userID, Reason: reason}, nil
+}
+
+// auditTarget returns the audit log target (see thesrc.AuditEntry.Target)
+// for the thing of the given kind (such as "post") with the given ID or
+// login.
+func auditTarget(kind string, id interface{}) string {
+	return fmt.Sprintf("%s:%v", kind, id)
+}
+
+func serveAuditLog(w http.ResponseWriter, r *http.Request) error {
+	var opt thesrc.AuditLogListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+
+	entries, err := store(r).AuditLog.List(&opt)
+	if err != nil {
+		return err
+	}
+	if entries == nil {
+		entries = []*thesrc.AuditEntry{}
+	}
+
+	logins := map[int]string{}
+	for _, e := range entries {
+		if e.ActorUserID == 0 {
+			continue
+		}
+		if _, present := logins[e.ActorUserID]; !present {
+			user, err := store(r).Users.Get(e.ActorUserID)
+			if err != nil && err != thesrc.ErrUserNotFound {
+				return err
+			}
+			if user != nil {
+				logins[e.ActorUserID] = user.Login
+			}
+		}
+		e.ActorLogin = logins[e.ActorUserID]
+	}
+
+	return writeJSON(w, r, entries)
+}
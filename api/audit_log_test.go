@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestAuditLog_List(t *testing.T) {
+	setup()
+	mockAdmin(1)
+
+	Store.AuditLog.(*datastore.MockAuditLogStore).List_ = func(opt *thesrc.AuditLogListOptions) ([]*thesrc.AuditEntry, error) {
+		if opt.Target != "user:carol" {
+			t.Errorf("got Target %q, want %q", opt.Target, "user:carol")
+		}
+		return []*thesrc.AuditEntry{
+			{ID: 2, ActorUserID: 1, Action: thesrc.AuditShadowBan, Target: "user:carol"},
+			{ID: 1, Action: thesrc.AuditSetRole, Target: "user:carol", Details: "member (was admin)"},
+		}, nil
+	}
+
+	opt := &thesrc.AuditLogListOptions{Target: "user:carol"}
+	if _, err := apiClient.WithAuthToken(newAuthToken(2)).AuditLog.List(opt); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v listing the audit log as non-admin, want HTTP %d", err, http.StatusForbidden)
+	}
+
+	entries, err := apiClient.WithAuthToken(newAuthToken(1)).AuditLog.List(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].ActorUserID != 1 || entries[1].ActorLogin != "" {
+		t.Errorf("got entries %+v, want user 1's and one from the command line", entries)
+	}
+}
+
+func TestShadowBanUser_audit(t *testing.T) {
+	setup()
+	mockAdmin(1)
+
+	Store.Users.(*datastore.MockUsersStore).GetByLogin_ = func(login string) (*thesrc.User, error) {
+		return &thesrc.User{ID: 3, Login: login}, nil
+	}
+	var entry *thesrc.AuditEntry
+	Store.AuditLog.(*datastore.MockAuditLogStore).Create_ = func(e *thesrc.AuditEntry) error {
+		entry = e
+		return nil
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).WithAuditReason("sock puppet").Users.SetShadowBanned("carol", true); err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || entry.Action != thesrc.AuditShadowBan || entry.Target != "user:carol" || entry.Reason != "sock puppet" {
+		t.Errorf("got audit entry %+v, want carol's shadow ban with the reason", entry)
+	}
+}
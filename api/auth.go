@@ -0,0 +1,178 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// AuthSecret is the key used to sign and verify the short-lived tokens in
+// password reset links and two-factor authentication. All servers that
+// share a datastore must use the same AuthSecret, and changing it
+// invalidates all previously issued tokens. (Session tokens are random
+// instead; see newSession.)
+var AuthSecret []byte
+
+var errInvalidAuthToken = &httpError{http.StatusUnauthorized, errors.New("invalid or expired API token")}
+
+// authenticatedUser returns the user that r is authenticated as, or nil if
+// r has no credentials.
+func authenticatedUser(r *http.Request) (*thesrc.User, error) {
+	userID, err := authenticatedUserID(r)
+	if err != nil || userID == 0 {
+		return nil, err
+	}
+	user, err := store(r).Users.Get(userID)
+	if err == thesrc.ErrUserNotFound {
+		return nil, errInvalidAuthToken
+	}
+	return user, err
+}
+
+// hasRole returns whether r's authenticated user (if any) has role (see
+// thesrc.User.HasRole), and may use it (see RequireTwoFactorRole).
+func hasRole(r *http.Request, role string) (bool, error) {
+	user, err := authenticatedUser(r)
+	if err != nil {
+		return false, err
+	}
+	return user.HasRole(role) && !needsTwoFactor(user, role), nil
+}
+
+// checkRole returns an error unless r's authenticated user has role and may
+// use it.
+func checkRole(r *http.Request, role string) error {
+	if _, err := requireUserID(r); err != nil {
+		return err
+	}
+	user, err := authenticatedUser(r)
+	if err != nil {
+		return err
+	}
+	if !user.HasRole(role) {
+		return &httpError{http.StatusForbidden, fmt.Errorf("%s role required", role)}
+	}
+	if needsTwoFactor(user, role) {
+		return &httpError{http.StatusForbidden, fmt.Errorf("two-factor authentication must be enabled to use the %s role", role)}
+	}
+	return nil
+}
+
+// requireRole wraps h so that it is only called for requests whose
+// authenticated user has role.
+func requireRole(role string, h handler) handler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if err := checkRole(r, role); err != nil {
+			return err
+		}
+		return h(w, r)
+	}
+}
+
+var errInvalidUserToken = errors.New("invalid or expired token")
+
+// newUserToken returns a signed token for purpose (such as
+// "password-reset") that identifies user until it expires. It is also signed
+// with the user's current password hash, so it is revoked when their
+// password changes.
+func newUserToken(purpose string, user *thesrc.User, lifetime time.Duration) string {
+	payload := fmt.Sprintf("%d:%d", user.ID, time.Now().Add(lifetime).Unix())
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(signUserToken(purpose, payload, user))
+}
+
+// userTokenUser verifies a token returned by newUserToken for purpose and
+// returns the user it identifies. If the token is invalid or expired, it
+// returns errInvalidUserToken.
+func userTokenUser(r *http.Request, purpose, token string) (*thesrc.User, error) {
+	i := strings.Index(token, ".")
+	if i == -1 {
+		return nil, errInvalidUserToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(token[:i])
+	if err != nil {
+		return nil, errInvalidUserToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil {
+		return nil, errInvalidUserToken
+	}
+
+	var userID int
+	var expiry int64
+	if _, err := fmt.Sscanf(string(payload), "%d:%d", &userID, &expiry); err != nil {
+		return nil, errInvalidUserToken
+	}
+	if time.Now().Unix() > expiry {
+		return nil, errInvalidUserToken
+	}
+
+	user, err := store(r).Users.Get(userID)
+	if err == thesrc.ErrUserNotFound {
+		return nil, errInvalidUserToken
+	} else if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(sig, signUserToken(purpose, string(payload), user)) {
+		return nil, errInvalidUserToken
+	}
+	return user, nil
+}
+
+func signUserToken(purpose, payload string, user *thesrc.User) []byte {
+	mac := hmac.New(sha256.New, AuthSecret)
+	fmt.Fprintf(mac, "%s:%s:", purpose, payload)
+	mac.Write(user.PasswordHash)
+	return mac.Sum(nil)
+}
+
+// authenticatedUserID returns the ID of the user that r is authenticated
+// as, or 0 if r has no credentials. If r has invalid credentials, an error
+// is returned.
+func authenticatedUserID(r *http.Request) (int, error) {
+	token, err := bearerToken(r)
+	if err != nil || token == "" {
+		return 0, err
+	}
+	if strings.HasPrefix(token, thesrc.PersonalTokenPrefix) {
+		return personalTokenUserID(r, token)
+	}
+	session, err := tokenSession(r, token)
+	if err != nil {
+		return 0, err
+	}
+	return session.UserID, nil
+}
+
+// bearerToken returns the API token in r's Authorization header, or "" if
+// it has none.
+func bearerToken(r *http.Request) (string, error) {
+	authz := r.Header.Get("authorization")
+	if authz == "" {
+		return "", nil
+	}
+	const prefix = "bearer "
+	if len(authz) < len(prefix) || !strings.EqualFold(authz[:len(prefix)], prefix) {
+		return "", errInvalidAuthToken
+	}
+	return strings.TrimSpace(authz[len(prefix):]), nil
+}
+
+// requireUserID is like authenticatedUserID, but it returns an error if r
+// has no credentials.
+func requireUserID(r *http.Request) (int, error) {
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		return 0, err
+	}
+	if userID == 0 {
+		return 0, &httpError{http.StatusUnauthorized, errors.New("authentication required")}
+	}
+	return userID, nil
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestAuthToken(t *testing.T) {
+	setup()
+
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return &thesrc.User{ID: id}, nil
+	}
+
+	token := newAuthToken(123)
+	if user, err := apiClient.WithAuthToken(token).Users.Current(); err != nil {
+		t.Fatal(err)
+	} else if user.ID != 123 {
+		t.Errorf("got user ID %d, want %d", user.ID, 123)
+	}
+
+	for _, bad := range []string{"x", token + "x", token[1:]} {
+		if _, err := apiClient.WithAuthToken(bad).Users.Current(); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+			t.Errorf("%q: got error %v, want HTTP %d", bad, err, http.StatusUnauthorized)
+		}
+	}
+}
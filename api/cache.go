@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/redis"
+)
+
+// PostListCacheTTL is how long post list results are cached in memory (or
+// in Redis, if UseRedis is called). The cache is invalidated whenever a post
+// is submitted or voted on (through this server, or through any server
+// sharing the Redis server). If PostListCacheTTL is 0, post lists are not
+// cached.
+var PostListCacheTTL = 30 * time.Second
+
+var postListCache listCacher = newListCache()
+
+// listCacher caches the results of Posts.List calls by their options.
+type listCacher interface {
+	// list returns the (possibly cached) result of store(r).Posts.List(opt).
+	// The returned posts are copies, so callers may modify them.
+	list(r *http.Request, opt *thesrc.PostListOptions) ([]*thesrc.Post, error)
+
+	// invalidate removes all cached results.
+	invalidate()
+}
+
+// maxCachedLists is the maximum number of distinct post list queries to
+// cache. When it is exceeded, the cache is cleared.
+const maxCachedLists = 1000
+
+// listCache caches post lists in memory.
+type listCache struct {
+	mu      sync.Mutex
+	entries map[string]*listCacheEntry
+
+	// generation is incremented on each invalidation, so that results fetched
+	// before an invalidation are not cached after it.
+	generation int
+}
+
+type listCacheEntry struct {
+	posts   []*thesrc.Post
+	expires time.Time
+}
+
+func newListCache() *listCache {
+	return &listCache{entries: map[string]*listCacheEntry{}}
+}
+
+func (c *listCache) list(r *http.Request, opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+	if PostListCacheTTL <= 0 {
+		return store(r).Posts.List(opt)
+	}
+
+	key := fmt.Sprintf("%+v", *opt)
+	now := time.Now()
+
+	c.mu.Lock()
+	e := c.entries[key]
+	gen := c.generation
+	c.mu.Unlock()
+	if e != nil && now.Before(e.expires) {
+		return copyPosts(e.posts), nil
+	}
+
+	posts, err := store(r).Posts.List(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.generation == gen {
+		if len(c.entries) >= maxCachedLists {
+			c.entries = map[string]*listCacheEntry{}
+		}
+		c.entries[key] = &listCacheEntry{posts: copyPosts(posts), expires: now.Add(PostListCacheTTL)}
+	}
+	c.mu.Unlock()
+	return posts, nil
+}
+
+func (c *listCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*listCacheEntry{}
+	c.generation++
+}
+
+// redisListCache caches post lists in Redis. Each list is stored as JSON
+// under a key that includes the current generation (which invalidate
+// increments), so invalidated lists are no longer found and expire on their
+// own. If Redis is unavailable, lists are fetched from the store.
+type redisListCache struct {
+	c *redis.Client
+}
+
+const redisListGenerationKey = "thesrc:postlist:generation"
+
+func (c *redisListCache) list(r *http.Request, opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+	if PostListCacheTTL <= 0 {
+		return store(r).Posts.List(opt)
+	}
+
+	ctx := r.Context()
+	log := logging.FromContext(ctx)
+	gen, err := redis.String(c.c.Do(ctx, "GET", redisListGenerationKey))
+	if err == redis.ErrNil {
+		gen, err = "0", nil
+	}
+	if err != nil {
+		log.Log("Getting post list cache generation failed", "error", err)
+		return store(r).Posts.List(opt)
+	}
+	sum := sha1.Sum([]byte(fmt.Sprintf("%+v", *opt)))
+	key := "thesrc:postlist:" + gen + ":" + hex.EncodeToString(sum[:])
+
+	data, err := redis.Bytes(c.c.Do(ctx, "GET", key))
+	if err == nil {
+		var posts []*thesrc.Post
+		if err := json.Unmarshal(data, &posts); err == nil {
+			return posts, nil
+		}
+	} else if err != redis.ErrNil {
+		log.Log("Getting cached post list failed", "error", err)
+	}
+
+	posts, err := store(r).Posts.List(opt)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(posts); err != nil {
+		return nil, err
+	} else if _, err := c.c.Do(ctx, "SET", key, data, "PX", int64(PostListCacheTTL/time.Millisecond)); err != nil {
+		log.Log("Caching post list failed", "error", err)
+	}
+	return posts, nil
+}
+
+func (c *redisListCache) invalidate() {
+	if _, err := c.c.Do(context.Background(), "INCR", redisListGenerationKey); err != nil {
+		logging.Default.Log("Invalidating post list cache failed", "error", err)
+	}
+}
+
+func copyPosts(posts []*thesrc.Post) []*thesrc.Post {
+	if posts == nil {
+		return nil
+	}
+	posts2 := make([]*thesrc.Post, len(posts))
+	for i, p := range posts {
+		p2 := *p
+		posts2[i] = &p2
+	}
+	return posts2
+}
+
+// writeCacheableJSON is like writeJSON, but it also sets an ETag and
+// Cache-Control header on the response (allowing shared caches to store
+// responses to anonymous requests for maxAge). If the request's
+// If-None-Match header matches the ETag, it responds with HTTP 304 Not
+// Modified instead.
+func writeCacheableJSON(w http.ResponseWriter, r *http.Request, v interface{}, maxAge time.Duration) error {
+	data, contentType, err := encodeResponse(r, v)
+	if err != nil {
+		return err
+	}
+
+	sum := sha1.Sum(data)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Add("Vary", "Authorization")
+	w.Header().Add("Vary", "Accept")
+	if r.Header.Get("Authorization") == "" {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	} else {
+		w.Header().Set("Cache-Control", "private, max-age=0")
+	}
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("content-type", contentType)
+	_, err = w.Write(data)
+	return err
+}
+
+// etagMatches reports whether the If-None-Match header value ifNoneMatch
+// matches etag.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == etag || t == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestPosts_List_cache(t *testing.T) {
+	setup()
+
+	var listCalls int
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		listCalls++
+		return []*thesrc.Post{{ID: 1}}, nil
+	}
+	Store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		return true, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := apiClient.Posts.List(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if listCalls != 1 {
+		t.Errorf("got %d Posts.List calls, want 1 (cached)", listCalls)
+	}
+
+	if _, err := apiClient.Posts.Submit(&thesrc.Post{Body: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apiClient.Posts.List(nil); err != nil {
+		t.Fatal(err)
+	}
+	if listCalls != 2 {
+		t.Errorf("got %d Posts.List calls after submitting a post, want 2 (cache invalidated)", listCalls)
+	}
+
+	if err := apiClient.Comments.Create(&thesrc.Comment{PostID: 1, Body: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apiClient.Posts.List(nil); err != nil {
+		t.Fatal(err)
+	}
+	if listCalls != 3 {
+		t.Errorf("got %d Posts.List calls after commenting, want 3 (cache invalidated)", listCalls)
+	}
+}
+
+func TestPosts_List_etag(t *testing.T) {
+	setup()
+
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		return []*thesrc.Post{{ID: 1}}, nil
+	}
+
+	rw := httptest.NewRecorder()
+	serveMux.ServeHTTP(rw, httptest.NewRequest("GET", "/api/posts", nil))
+	etag := rw.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	if got, want := rw.Header().Get("Cache-Control"), "public, max-age=30"; got != want {
+		t.Errorf("got Cache-Control %q, want %q", got, want)
+	}
+
+	req := httptest.NewRequest("GET", "/api/posts", nil)
+	req.Header.Set("If-None-Match", etag)
+	rw = httptest.NewRecorder()
+	serveMux.ServeHTTP(rw, req)
+	if rw.Code != http.StatusNotModified {
+		t.Errorf("got HTTP %d, want %d", rw.Code, http.StatusNotModified)
+	}
+	if rw.Body.Len() != 0 {
+		t.Errorf("got body %q, want empty", rw.Body)
+	}
+}
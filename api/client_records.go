@@ -0,0 +1,127 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+)
+
+var (
+	// RecordClients is whether to record the (hashed) IP address and
+	// User-Agent of the clients that submit and vote on posts, for abuse
+	// detection (see thesrc.ClientRecord).
+	RecordClients = false
+
+	// ClientSaltPeriod is how long each salt that clients' IP addresses and
+	// User-Agents are hashed with is used before it is replaced. Hashes only
+	// match other hashes from the same period.
+	ClientSaltPeriod = 24 * time.Hour
+)
+
+// clientSalts caches the current period's salt, so that recording a client
+// needn't query the datastore for it.
+var clientSalts struct {
+	mu     sync.Mutex
+	period time.Time
+	salt   []byte
+}
+
+// clientSalt returns the salt for hashing clients' IP addresses and
+// User-Agents at time t.
+func clientSalt(r *http.Request, t time.Time) ([]byte, error) {
+	period := t.Truncate(ClientSaltPeriod)
+
+	clientSalts.mu.Lock()
+	defer clientSalts.mu.Unlock()
+	if clientSalts.salt != nil && clientSalts.period.Equal(period) {
+		return clientSalts.salt, nil
+	}
+	salt, err := store(r).ClientRecords.Salt(period)
+	if err != nil {
+		return nil, err
+	}
+	clientSalts.period, clientSalts.salt = period, salt
+	return salt, nil
+}
+
+// endUserIP returns the IP address of the end user on whose behalf r was
+// made: the address that the app forwarded (see
+// thesrc.Client.WithForwardedClient), if r has a valid signature from it,
+// or else the address of the client that made r.
+func endUserIP(r *http.Request) string {
+	if ip, ok := thesrc.VerifyForwardedIP(AuthSecret, r.Header.Get(thesrc.ForwardedIPHeader)); ok {
+		return ip
+	}
+	return clientIP(r)
+}
+
+// recordClient records the client that made r as having done action on the
+// post with ID postID as the user with ID userID, if RecordClients is set.
+// Errors are logged, not returned, so that the action succeeds even if the
+// client can't be recorded.
+func recordClient(r *http.Request, action string, userID, postID int) {
+	if !RecordClients {
+		return
+	}
+
+	now := time.Now()
+	salt, err := clientSalt(r, now)
+	if err != nil {
+		logging.FromContext(r.Context()).Log("Getting client salt failed", "error", err)
+		return
+	}
+	hash := func(s string) string {
+		mac := hmac.New(sha256.New, salt)
+		mac.Write([]byte(s))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	record := &thesrc.ClientRecord{
+		Action:        action,
+		UserID:        userID,
+		PostID:        postID,
+		IPHash:        hash(endUserIP(r)),
+		UserAgentHash: hash(r.UserAgent()),
+		CreatedAt:     now,
+	}
+	if err := store(r).ClientRecords.Create(record); err != nil {
+		logging.FromContext(r.Context()).Log("Recording client failed", "action", action, "user_id", userID, "post_id", postID, "error", err)
+	}
+}
+
+func serveClientRecords(w http.ResponseWriter, r *http.Request) error {
+	var opt thesrc.ClientRecordListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+
+	records, err := store(r).ClientRecords.List(&opt)
+	if err != nil {
+		return err
+	}
+	if records == nil {
+		records = []*thesrc.ClientRecord{}
+	}
+
+	logins := map[int]string{}
+	for _, record := range records {
+		if _, present := logins[record.UserID]; !present {
+			user, err := store(r).Users.Get(record.UserID)
+			if err != nil && err != thesrc.ErrUserNotFound {
+				return err
+			}
+			if user != nil {
+				logins[record.UserID] = user.Login
+			}
+		}
+		record.Login = logins[record.UserID]
+	}
+
+	return writeJSON(w, r, records)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestClientRecords_List(t *testing.T) {
+	setup()
+	mockAdmin(1)
+
+	Store.ClientRecords.(*datastore.MockClientRecordsStore).List_ = func(opt *thesrc.ClientRecordListOptions) ([]*thesrc.ClientRecord, error) {
+		if opt.IPHash != "x" {
+			t.Errorf("got IPHash %q, want %q", opt.IPHash, "x")
+		}
+		return []*thesrc.ClientRecord{{ID: 1, Action: thesrc.ClientActionVote, UserID: 3, IPHash: "x"}}, nil
+	}
+
+	if _, err := apiClient.WithAuthToken(newAuthToken(2)).ClientRecords.List(&thesrc.ClientRecordListOptions{IPHash: "x"}); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v listing client records as non-admin, want HTTP %d", err, http.StatusForbidden)
+	}
+
+	records, err := apiClient.WithAuthToken(newAuthToken(1)).ClientRecords.List(&thesrc.ClientRecordListOptions{IPHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].UserID != 3 {
+		t.Errorf("got records %+v, want user 3's", records)
+	}
+}
+
+func TestRecordClient(t *testing.T) {
+	setup()
+	RecordClients = true
+
+	salt := []byte("salt")
+	Store.ClientRecords.(*datastore.MockClientRecordsStore).Salt_ = func(start time.Time) ([]byte, error) {
+		if !start.Equal(start.Truncate(ClientSaltPeriod)) {
+			t.Errorf("got salt period start %v, want the start of a period", start)
+		}
+		return salt, nil
+	}
+	var record *thesrc.ClientRecord
+	Store.ClientRecords.(*datastore.MockClientRecordsStore).Create_ = func(r *thesrc.ClientRecord) error {
+		record = r
+		return nil
+	}
+	hash := func(s string) string {
+		mac := hmac.New(sha256.New, salt)
+		mac.Write([]byte(s))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	client := apiClient.WithAuthToken(newAuthToken(1))
+	client.ForwardingKey = AuthSecret
+	if err := client.WithForwardedClient("203.0.113.1", "browser").Votes.Upvote(2); err != nil {
+		t.Fatal(err)
+	}
+	if record == nil {
+		t.Fatal("client wasn't recorded")
+	}
+	if record.Action != thesrc.ClientActionVote || record.UserID != 1 || record.PostID != 2 {
+		t.Errorf("got record %+v, want user 1's vote on post 2", record)
+	}
+	if want := hash("203.0.113.1"); record.IPHash != want {
+		t.Errorf("got IPHash %q, want the hash of the forwarded IP (%q)", record.IPHash, want)
+	}
+	if want := hash("browser"); record.UserAgentHash != want {
+		t.Errorf("got UserAgentHash %q, want the hash of the forwarded User-Agent (%q)", record.UserAgentHash, want)
+	}
+
+	// A forwarded IP signed with another key isn't trusted.
+	client.ForwardingKey = []byte("other")
+	if err := client.WithForwardedClient("203.0.113.1", "").Votes.Upvote(2); err != nil {
+		t.Fatal(err)
+	}
+	if record.IPHash == hash("203.0.113.1") {
+		t.Error("got the hash of the forwarded IP with an invalid signature")
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveComment(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	comment, err := store(r).Comments.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := hideDeadComments(r, comment); err != nil {
+		return err
+	}
+	if err := markCommentsVoted(r, comment); err != nil {
+		return err
+	}
+	if renderBody(r) {
+		renderCommentBodies(comment)
+	}
+
+	return writeJSON(w, r, comment)
+}
+
+func serveComments(w http.ResponseWriter, r *http.Request) error {
+	var opt thesrc.CommentListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+
+	comments, err := store(r).Comments.List(&opt)
+	if err != nil {
+		return err
+	}
+	if err := hideDeadComments(r, comments...); err != nil {
+		return err
+	}
+	if err := markCommentsVoted(r, comments...); err != nil {
+		return err
+	}
+	if opt.RenderBody {
+		renderCommentBodies(comments...)
+	}
+	if comments == nil {
+		comments = []*thesrc.Comment{}
+	}
+
+	writePaginationLinks(w, r, opt.ListOptions, len(comments))
+	return writeJSON(w, r, comments)
+}
+
+func servePostComments(w http.ResponseWriter, r *http.Request) error {
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	comments, err := store(r).Comments.ListForPost(postID)
+	if err != nil {
+		return err
+	}
+	if err := hideDeadComments(r, comments...); err != nil {
+		return err
+	}
+	if err := markCommentsVoted(r, comments...); err != nil {
+		return err
+	}
+	if renderBody(r) {
+		renderCommentBodies(comments...)
+	}
+	if comments == nil {
+		comments = []*thesrc.Comment{}
+	}
+
+	return writeJSON(w, r, comments)
+}
+
+// hideDeadComments clears the bodies of dead comments (see
+// thesrc.Comment.Dead) unless r's authenticated user is a moderator.
+func hideDeadComments(r *http.Request, comments ...*thesrc.Comment) error {
+	var dead bool
+	for _, c := range comments {
+		dead = dead || c.Dead
+	}
+	if !dead {
+		return nil
+	}
+	if isMod, err := hasRole(r, thesrc.RoleModerator); err != nil || isMod {
+		return err
+	}
+	for _, c := range comments {
+		if c.Dead {
+			c.Body, c.BodyHTML = "", ""
+		}
+	}
+	return nil
+}
+
+func serveCreateComment(w http.ResponseWriter, r *http.Request) error {
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var comment thesrc.Comment
+	err = json.NewDecoder(r.Body).Decode(&comment)
+	if err != nil {
+		return err
+	}
+	comment.AuthorUserID = userID
+	comment.Dead = false // only moderators may kill comments
+
+	if err := createComment(r, &comment); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	return writeJSON(w, r, comment)
+}
+
+// createComment validates and creates comment, and notifies the users it
+// replies to and mentions.
+func createComment(r *http.Request, comment *thesrc.Comment) error {
+	if strings.TrimSpace(comment.Body) == "" {
+		return invalidField("Body", errors.New("comment body must not be empty"))
+	}
+
+	if err := store(r).Comments.Create(comment); err != nil {
+		return err
+	}
+	// Comments count toward posts' velocity (see
+	// thesrc.CommentVelocityWeight), by which post lists may be sorted.
+	postListCache.invalidate()
+	logNotifyError(r, notifyComment(r, comment))
+	return nil
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestComment(t *testing.T) {
+	setup()
+
+	wantComment := &thesrc.Comment{ID: 1, PostID: 2}
+
+	calledGet := false
+	Store.Comments.(*thesrc.MockCommentsService).Get_ = func(id int) (*thesrc.Comment, error) {
+		if id != wantComment.ID {
+			t.Errorf("wanted request for comment %d but got %d", wantComment.ID, id)
+		}
+		calledGet = true
+		return wantComment, nil
+	}
+
+	gotComment, err := apiClient.Comments.Get(wantComment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledGet {
+		t.Error("!calledGet")
+	}
+	if !normalizeDeepEqual(wantComment, gotComment) {
+		t.Errorf("got comment %+v but wanted comment %+v", gotComment, wantComment)
+	}
+}
+
+func TestPostComments(t *testing.T) {
+	setup()
+
+	wantComments := []*thesrc.Comment{{ID: 1, PostID: 2}}
+
+	calledList := false
+	Store.Comments.(*thesrc.MockCommentsService).ListForPost_ = func(postID int) ([]*thesrc.Comment, error) {
+		if postID != 2 {
+			t.Errorf("wanted request for comments on post %d but got %d", 2, postID)
+		}
+		calledList = true
+		return wantComments, nil
+	}
+
+	comments, err := apiClient.Comments.ListForPost(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledList {
+		t.Error("!calledList")
+	}
+	if !normalizeDeepEqual(&wantComments, &comments) {
+		t.Errorf("got comments %+v but wanted comments %+v", comments, wantComments)
+	}
+}
+
+func TestPostComments_dead(t *testing.T) {
+	setup()
+
+	mockModerator(1)
+	Store.Comments.(*thesrc.MockCommentsService).ListForPost_ = func(postID int) ([]*thesrc.Comment, error) {
+		return []*thesrc.Comment{{ID: 1, PostID: postID, Body: "spam", Dead: true}, {ID: 2, PostID: postID, ParentID: 1, Body: "b"}}, nil
+	}
+
+	// Only moderators see the bodies of dead comments.
+	tests := map[int]string{0: "", 2: "", 1: "spam"}
+	for userID, wantBody := range tests {
+		c := apiClient
+		if userID != 0 {
+			c = apiClient.WithAuthToken(newAuthToken(userID))
+		}
+		comments, err := c.Comments.ListForPost(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(comments) != 2 || !comments[0].Dead || comments[0].Body != wantBody || comments[1].Body != "b" {
+			t.Errorf("user %d: got comments %+v, want dead comment with body %q and its reply", userID, comments, wantBody)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	setup()
+
+	wantComments := []*thesrc.Comment{{ID: 1, PostID: 2, AuthorUserID: 3}}
+	wantOpt := &thesrc.CommentListOptions{AuthorUserID: 3}
+
+	calledList := false
+	Store.Comments.(*thesrc.MockCommentsService).List_ = func(opt *thesrc.CommentListOptions) ([]*thesrc.Comment, error) {
+		if !normalizeDeepEqual(wantOpt, opt) {
+			t.Errorf("wanted list options %+v but got %+v", wantOpt, opt)
+		}
+		calledList = true
+		return wantComments, nil
+	}
+
+	comments, err := apiClient.Comments.List(wantOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledList {
+		t.Error("!calledList")
+	}
+	if !normalizeDeepEqual(&wantComments, &comments) {
+		t.Errorf("got comments %+v but wanted comments %+v", comments, wantComments)
+	}
+}
+
+func TestComment_Create(t *testing.T) {
+	setup()
+
+	wantComment := &thesrc.Comment{PostID: 2, Body: "b"}
+
+	calledCreate := false
+	Store.Comments.(*thesrc.MockCommentsService).Create_ = func(comment *thesrc.Comment) error {
+		if !normalizeDeepEqual(wantComment, comment) {
+			t.Errorf("wanted request for comment %+v but got %+v", wantComment, comment)
+		}
+		calledCreate = true
+		comment.ID = 1
+		return nil
+	}
+
+	comment := &thesrc.Comment{PostID: 2, Body: "b"}
+	if err := apiClient.Comments.Create(comment); err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledCreate {
+		t.Error("!calledCreate")
+	}
+	if comment.ID != 1 {
+		t.Errorf("got comment ID %d, want %d", comment.ID, 1)
+	}
+}
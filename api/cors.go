@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+var (
+	// CORSOrigins lists the origins (e.g., "https://example.com") of web
+	// pages that may call the API from browsers, using Cross-Origin Resource
+	// Sharing. "*" allows any origin. If it is empty, browsers don't allow
+	// cross-origin API requests.
+	CORSOrigins []string
+
+	// CORSMethods lists the HTTP methods that cross-origin requests may use.
+	CORSMethods = []string{"GET", "POST", "PUT", "DELETE"}
+
+	// CORSCredentials is whether cross-origin requests may include the
+	// browser's credentials (cookies and HTTP authentication) for the API's
+	// origin. The API doesn't need them (it authenticates requests with
+	// tokens in the Authorization header, which CORS always allows), but a
+	// proxy in front of it might. It is ignored if CORSOrigins allows any
+	// origin ("*"), so that any web page can't make requests with the
+	// browser's credentials.
+	CORSCredentials = false
+
+	// CORSMaxAge is how long browsers may cache the response to a preflight
+	// request.
+	CORSMaxAge = 10 * time.Minute
+)
+
+// corsHeaders are the request headers that cross-origin requests may set
+// (other than the headers that CORS always allows), and corsExposedHeaders
+// are the response headers that they may read.
+var (
+	corsHeaders        = []string{"Authorization", "Content-Type", "If-None-Match", "Last-Event-ID", VersionHeader, thesrc.AuditReasonHeader, thesrc.IdempotencyKeyHeader, thesrc.PostVersionHeader}
+	corsExposedHeaders = []string{"ETag", "Link", "Retry-After", "X-Next-Cursor", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", VersionHeader, thesrc.PostVersionHeader}
+)
+
+// handleCORS wraps h so that it allows cross-origin requests from
+// CORSOrigins, and answers their preflight (OPTIONS) requests.
+func handleCORS(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(CORSOrigins) == 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		if origin == "" || !corsAllowed(origin) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		hdr := w.Header()
+		if corsAnyOrigin() {
+			hdr.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			hdr.Set("Access-Control-Allow-Origin", origin)
+			if CORSCredentials {
+				hdr.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+			hdr.Set("Access-Control-Allow-Methods", strings.Join(CORSMethods, ", "))
+			hdr.Set("Access-Control-Allow-Headers", strings.Join(corsHeaders, ", "))
+			hdr.Set("Access-Control-Max-Age", strconv.Itoa(int(CORSMaxAge.Seconds())))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		hdr.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		h.ServeHTTP(w, r)
+	})
+}
+
+// corsAnyOrigin reports whether CORSOrigins allows any origin.
+func corsAnyOrigin() bool {
+	for _, o := range CORSOrigins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// corsAllowed reports whether CORSOrigins allows origin.
+func corsAllowed(origin string) bool {
+	for _, o := range CORSOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestCORS(t *testing.T) {
+	setup()
+	defer func(origins []string, credentials bool) {
+		CORSOrigins, CORSCredentials = origins, credentials
+	}(CORSOrigins, CORSCredentials)
+
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id}, nil
+	}
+
+	tests := []struct {
+		origins     []string
+		credentials bool
+		method      string
+		origin      string
+
+		wantStatus      int
+		wantAllowOrigin string
+		wantCredentials string
+		wantMethods     string
+	}{
+		{method: "GET", origin: "https://a.example.com", wantStatus: http.StatusOK},
+		{origins: []string{"https://a.example.com"}, method: "GET", origin: "https://a.example.com", wantStatus: http.StatusOK, wantAllowOrigin: "https://a.example.com"},
+		{origins: []string{"https://a.example.com"}, method: "GET", origin: "https://b.example.com", wantStatus: http.StatusOK},
+		{origins: []string{"*"}, method: "GET", origin: "https://b.example.com", wantStatus: http.StatusOK, wantAllowOrigin: "*"},
+		{origins: []string{"https://a.example.com"}, credentials: true, method: "GET", origin: "https://a.example.com", wantStatus: http.StatusOK, wantAllowOrigin: "https://a.example.com", wantCredentials: "true"},
+		{origins: []string{"*"}, credentials: true, method: "GET", origin: "https://b.example.com", wantStatus: http.StatusOK, wantAllowOrigin: "*"},
+		{origins: []string{"https://a.example.com", "*"}, credentials: true, method: "GET", origin: "https://a.example.com", wantStatus: http.StatusOK, wantAllowOrigin: "*"},
+		{origins: []string{"https://a.example.com"}, method: "OPTIONS", origin: "https://a.example.com", wantStatus: http.StatusNoContent, wantAllowOrigin: "https://a.example.com", wantMethods: "GET, POST, PUT, DELETE"},
+	}
+	for _, test := range tests {
+		CORSOrigins, CORSCredentials = test.origins, test.credentials
+
+		req := httptest.NewRequest(test.method, "/api/posts/1", nil)
+		req.Header.Set("Origin", test.origin)
+		if test.method == "OPTIONS" {
+			req.Header.Set("Access-Control-Request-Method", "PUT")
+		}
+		rw := httptest.NewRecorder()
+		serveMux.ServeHTTP(rw, req)
+
+		if rw.Code != test.wantStatus {
+			t.Errorf("%+v: got HTTP %d, want %d", test, rw.Code, test.wantStatus)
+		}
+		if got := rw.Header().Get("Access-Control-Allow-Origin"); got != test.wantAllowOrigin {
+			t.Errorf("%+v: got Access-Control-Allow-Origin %q, want %q", test, got, test.wantAllowOrigin)
+		}
+		if got := rw.Header().Get("Access-Control-Allow-Credentials"); got != test.wantCredentials {
+			t.Errorf("%+v: got Access-Control-Allow-Credentials %q, want %q", test, got, test.wantCredentials)
+		}
+		if got := rw.Header().Get("Access-Control-Allow-Methods"); got != test.wantMethods {
+			t.Errorf("%+v: got Access-Control-Allow-Methods %q, want %q", test, got, test.wantMethods)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveDomain(w http.ResponseWriter, r *http.Request) error {
+	domain := thesrc.NormalizeDomain(mux.Vars(r)["Domain"])
+	if domain == "" {
+		return &httpError{http.StatusBadRequest, errors.New("empty domain")}
+	}
+
+	stats, err := store(r).Domains.Get(domain)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, r, stats)
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestDomain(t *testing.T) {
+	setup()
+
+	want := &thesrc.DomainStats{Domain: "example.com", NumPosts: 2, AverageScore: 1.5}
+
+	calledGet := false
+	Store.Domains.(*thesrc.MockDomainsService).Get_ = func(domain string) (*thesrc.DomainStats, error) {
+		if domain != want.Domain {
+			t.Errorf("got domain %q, want normalized domain %q", domain, want.Domain)
+		}
+		calledGet = true
+		return want, nil
+	}
+
+	stats, err := apiClient.Domains.Get("WWW.Example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledGet {
+		t.Error("!calledGet")
+	}
+	if !normalizeDeepEqual(want, stats) {
+		t.Errorf("got stats %+v, want %+v", stats, want)
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+)
+
+// FlagHideThreshold is the number of flags after which a post is
+// automatically hidden from post listings. If it is 0, posts are never hidden
+// automatically.
+var FlagHideThreshold = 3
+
+func serveFlagPost(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Flags.Flag(userID, postID); err != nil {
+		return err
+	}
+
+	post, err := store(r).Posts.Get(postID)
+	if err != nil {
+		return err
+	}
+	if FlagHideThreshold > 0 && post.Flags >= FlagHideThreshold && !post.Hidden {
+		if err := store(r).Posts.Moderate(postID, &thesrc.PostModeration{Hidden: true, Dead: post.Dead}); err != nil {
+			return err
+		}
+		logging.FromContext(r.Context()).Printf("Hid post %d after %d flags.", postID, post.Flags)
+		postListCache.invalidate()
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveModeratePost(w http.ResponseWriter, r *http.Request) error {
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	var mod thesrc.PostModeration
+	if err := json.NewDecoder(r.Body).Decode(&mod); err != nil {
+		return err
+	}
+	if mod.Version, err = requestedVersion(r, mod.Version); err != nil {
+		return err
+	}
+
+	// Get the post's current status, to record what changed in the audit
+	// log.
+	post, err := store(r).Posts.Get(postID)
+	if err != nil {
+		if err == thesrc.ErrPostNotFound {
+			return &httpError{http.StatusNotFound, err}
+		}
+		return err
+	}
+
+	if err := store(r).Posts.Moderate(postID, &mod); err != nil {
+		if err == thesrc.ErrPostNotFound {
+			return &httpError{http.StatusNotFound, err}
+		}
+		return err
+	}
+	postListCache.invalidate()
+
+	var actions []string
+	if mod.Hidden && !post.Hidden {
+		actions = append(actions, thesrc.AuditHidePost)
+	} else if !mod.Hidden && post.Hidden {
+		actions = append(actions, thesrc.AuditUnhidePost)
+	}
+	if mod.Dead && !post.Dead {
+		actions = append(actions, thesrc.AuditKillPost)
+	} else if !mod.Dead && post.Dead {
+		actions = append(actions, thesrc.AuditUnkillPost)
+	}
+	for _, action := range actions {
+		if err := audit(r, action, auditTarget("post", postID), post.Title); err != nil {
+			return err
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveBulkModerate(w http.ResponseWriter, r *http.Request) error {
+	var mod thesrc.BulkModeration
+	if err := json.NewDecoder(r.Body).Decode(&mod); err != nil {
+		return err
+	}
+
+	if mod.Action == thesrc.BulkUntag || mod.Action == thesrc.BulkRetag {
+		var err error
+		if mod.Tags, err = thesrc.NormalizeTags(mod.Tags); err != nil {
+			return invalidField("Tags", err)
+		}
+	}
+	if err := mod.Validate(); err != nil {
+		return &httpError{http.StatusBadRequest, err}
+	}
+
+	entry, err := auditEntry(r)
+	if err != nil {
+		return err
+	}
+	if err := store(r).Moderation.Bulk(&mod, entry); err != nil {
+		return err
+	}
+	postListCache.invalidate()
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
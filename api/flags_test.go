@@ -0,0 +1,224 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestPost_Flag(t *testing.T) {
+	setup()
+
+	calledFlag := false
+	Store.Flags.(*datastore.MockFlagsStore).Flag_ = func(userID, postID int) error {
+		if userID != 1 || postID != 2 {
+			t.Errorf("got flag by user %d on post %d, want user %d on post %d", userID, postID, 1, 2)
+		}
+		calledFlag = true
+		return nil
+	}
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, Flags: 1}, nil
+	}
+	Store.Posts.(*thesrc.MockPostsService).Moderate_ = func(id int, mod *thesrc.PostModeration) error {
+		t.Errorf("post %d was moderated, but it has fewer flags than the threshold", id)
+		return nil
+	}
+
+	if err := apiClient.Posts.Flag(2); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for unauthenticated flag, want HTTP %d", err, http.StatusUnauthorized)
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Posts.Flag(2); err != nil {
+		t.Fatal(err)
+	}
+	if !calledFlag {
+		t.Error("!calledFlag")
+	}
+}
+
+func TestPost_Flag_hideAtThreshold(t *testing.T) {
+	setup()
+
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, Flags: FlagHideThreshold}, nil
+	}
+	var hidden bool
+	Store.Posts.(*thesrc.MockPostsService).Moderate_ = func(id int, mod *thesrc.PostModeration) error {
+		hidden = mod.Hidden && !mod.Dead
+		return nil
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Posts.Flag(2); err != nil {
+		t.Fatal(err)
+	}
+	if !hidden {
+		t.Error("!hidden")
+	}
+}
+
+func TestPost_Moderate(t *testing.T) {
+	setup()
+
+	mockModerator(1)
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, Title: "t", Hidden: true}, nil
+	}
+	var moderated *thesrc.PostModeration
+	Store.Posts.(*thesrc.MockPostsService).Moderate_ = func(id int, mod *thesrc.PostModeration) error {
+		moderated = mod
+		return nil
+	}
+	var audited []*thesrc.AuditEntry
+	Store.AuditLog.(*datastore.MockAuditLogStore).Create_ = func(entry *thesrc.AuditEntry) error {
+		audited = append(audited, entry)
+		return nil
+	}
+
+	mod := &thesrc.PostModeration{Dead: true}
+	if err := apiClient.Posts.Moderate(3, mod); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for unauthenticated moderation, want HTTP %d", err, http.StatusUnauthorized)
+	}
+	if err := apiClient.WithAuthToken(newAuthToken(2)).Posts.Moderate(3, mod); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v for non-moderator moderation, want HTTP %d", err, http.StatusForbidden)
+	}
+	if moderated != nil {
+		t.Fatal("non-moderator moderated post")
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).WithAuditReason("spam\nring").Posts.Moderate(3, mod); err != nil {
+		t.Fatal(err)
+	}
+	if moderated == nil || !moderated.Dead {
+		t.Errorf("got moderation %+v, want %+v", moderated, mod)
+	}
+
+	// The post was hidden, and is now killed and unhidden.
+	if len(audited) != 2 {
+		t.Fatalf("got audit entries %+v, want 2", audited)
+	}
+	for i, action := range []string{thesrc.AuditUnhidePost, thesrc.AuditKillPost} {
+		if e := audited[i]; e.Action != action || e.ActorUserID != 1 || e.Target != "post:3" || e.Reason != "spam\nring" {
+			t.Errorf("got audit entry %+v, want %s of post:3 by user 1 with the reason", e, action)
+		}
+	}
+}
+
+func TestPosts_List_flagged(t *testing.T) {
+	setup()
+
+	mockModerator(1)
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		if !opt.Flagged {
+			t.Error("!opt.Flagged")
+		}
+		return []*thesrc.Post{{ID: 1, Flags: 2, Hidden: true}}, nil
+	}
+
+	opt := &thesrc.PostListOptions{Flagged: true}
+	if _, err := apiClient.WithAuthToken(newAuthToken(2)).Posts.List(opt); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v for non-moderator, want HTTP %d", err, http.StatusForbidden)
+	}
+
+	posts, err := apiClient.WithAuthToken(newAuthToken(1)).Posts.List(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || !posts[0].Hidden {
+		t.Errorf("got posts %+v, want 1 hidden post", posts)
+	}
+}
+
+func TestPost_dead(t *testing.T) {
+	setup()
+
+	mockModerator(1)
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, Dead: true}, nil
+	}
+
+	if _, err := apiClient.Posts.Get(1); !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v getting dead post anonymously, want HTTP %d", err, http.StatusNotFound)
+	}
+	if post, err := apiClient.WithAuthToken(newAuthToken(1)).Posts.Get(1); err != nil {
+		t.Fatal(err)
+	} else if !post.Dead {
+		t.Errorf("got post %+v, want dead post", post)
+	}
+}
+
+func TestBulkModerate(t *testing.T) {
+	setup()
+
+	mockModerator(1)
+	var moderated *thesrc.BulkModeration
+	var audit *thesrc.AuditEntry
+	Store.Moderation.(*datastore.MockModerationStore).Bulk_ = func(mod *thesrc.BulkModeration, entry *thesrc.AuditEntry) error {
+		moderated, audit = mod, entry
+		return nil
+	}
+
+	mod := &thesrc.BulkModeration{Action: thesrc.BulkKill, PostIDs: []int{1, 2}, CommentIDs: []int{3}}
+	if err := apiClient.WithAuthToken(newAuthToken(2)).Moderation.Bulk(mod); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v for non-moderator, want HTTP %d", err, http.StatusForbidden)
+	}
+	if moderated != nil {
+		t.Fatal("non-moderator moderated posts")
+	}
+
+	c := apiClient.WithAuthToken(newAuthToken(1)).WithAuditReason("spam")
+	if err := c.Moderation.Bulk(mod); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(moderated, mod) {
+		t.Errorf("got moderation %+v, want %+v", moderated, mod)
+	}
+	if audit == nil || audit.ActorUserID != 1 || audit.Reason != "spam" {
+		t.Errorf("got audit entry %+v, want one by user 1 with the reason", audit)
+	}
+
+	if err := c.Moderation.Bulk(&thesrc.BulkModeration{Action: thesrc.BulkRetag, PostIDs: []int{1}, Tags: []string{"Golang"}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"golang"}; !reflect.DeepEqual(moderated.Tags, want) {
+		t.Errorf("got tags %q, want normalized tags %q", moderated.Tags, want)
+	}
+
+	invalid := []*thesrc.BulkModeration{
+		{Action: "delete", PostIDs: []int{1}},
+		{Action: thesrc.BulkKill},
+		{Action: thesrc.BulkKill, PostIDs: make([]int, thesrc.MaxBulkModeration+1)},
+		{Action: thesrc.BulkQueue, CommentIDs: []int{1}},
+		{Action: thesrc.BulkUntag, PostIDs: []int{1}},
+	}
+	for _, mod := range invalid {
+		moderated = nil
+		if err := c.Moderation.Bulk(mod); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+			t.Errorf("%+v: got error %v, want HTTP %d", mod, err, http.StatusBadRequest)
+		}
+		if moderated != nil {
+			t.Errorf("%+v: moderated invalid request", mod)
+		}
+	}
+
+	Store.Moderation.(*datastore.MockModerationStore).Bulk_ = func(mod *thesrc.BulkModeration, entry *thesrc.AuditEntry) error {
+		return thesrc.ErrPostNotFound
+	}
+	if err := c.Moderation.Bulk(mod); !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v for nonexistent post, want HTTP %d", err, http.StatusNotFound)
+	}
+}
+
+// mockModerator makes the user with the given ID a moderator, and all other
+// users members.
+func mockModerator(moderatorID int) {
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		if id == moderatorID {
+			return &thesrc.User{ID: id, Role: thesrc.RoleModerator}, nil
+		}
+		return &thesrc.User{ID: id, Role: thesrc.RoleMember}, nil
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveFollows(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	follows, err := store(r).Follows.List(userID)
+	if err != nil {
+		return err
+	}
+	if follows == nil {
+		follows = []*thesrc.Follow{}
+	}
+
+	return writeJSON(w, r, follows)
+}
+
+func serveFollow(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var follow thesrc.Follow
+	if err := json.NewDecoder(r.Body).Decode(&follow); err != nil {
+		return err
+	}
+	follow.UserID = userID
+	follow.Kind = mux.Vars(r)["Kind"]
+	follow.Name = mux.Vars(r)["Name"]
+	follow.CreatedAt = time.Time{}
+	if err := thesrc.NormalizeFollow(&follow); err != nil {
+		return invalidField("Name", err)
+	}
+
+	if err := store(r).Follows.Follow(&follow); err != nil {
+		return err
+	}
+
+	return writeJSON(w, r, follow)
+}
+
+func serveUnfollow(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	follow := thesrc.Follow{Kind: mux.Vars(r)["Kind"], Name: mux.Vars(r)["Name"]}
+	if err := thesrc.NormalizeFollow(&follow); err != nil {
+		return invalidField("Name", err)
+	}
+
+	if err := store(r).Follows.Unfollow(userID, follow.Kind, follow.Name); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
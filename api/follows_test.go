@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestFollows_Follow(t *testing.T) {
+	setup()
+
+	var followed *thesrc.Follow
+	Store.Follows.(*datastore.MockFollowsStore).Follow_ = func(follow *thesrc.Follow) error {
+		followed = follow
+		return nil
+	}
+
+	if err := apiClient.Follows.Follow(&thesrc.Follow{Kind: thesrc.FollowTag, Name: "go"}); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for unauthenticated request, want HTTP %d", err, http.StatusUnauthorized)
+	}
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Follows.Follow(&thesrc.Follow{Kind: "user", Name: "alice"}); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v for invalid kind, want HTTP %d", err, http.StatusBadRequest)
+	}
+
+	follow := &thesrc.Follow{Kind: thesrc.FollowDomain, Name: "WWW.Example.com", Hide: true}
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Follows.Follow(follow); err != nil {
+		t.Fatal(err)
+	}
+	if followed == nil || followed.UserID != 1 || followed.Name != "example.com" || !followed.Hide {
+		t.Errorf("got followed %+v, want hidden domain example.com for user 1", followed)
+	}
+	if follow.Name != "example.com" {
+		t.Errorf("got follow name %q, want normalized name %q", follow.Name, "example.com")
+	}
+}
+
+func TestPosts_List_personalized(t *testing.T) {
+	setup()
+
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return &thesrc.User{ID: id}, nil
+	}
+	var personalizedFor int
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		personalizedFor = opt.PersonalizedForUserID
+		return []*thesrc.Post{{ID: 2}}, nil
+	}
+
+	tests := []struct {
+		client *thesrc.Client
+		opt    *thesrc.PostListOptions
+		want   int
+	}{
+		{apiClient, &thesrc.PostListOptions{Personalized: true}, 0},
+		{apiClient.WithAuthToken(newAuthToken(1)), &thesrc.PostListOptions{}, 0},
+		{apiClient.WithAuthToken(newAuthToken(1)), &thesrc.PostListOptions{Personalized: true}, 1},
+	}
+	for i, test := range tests {
+		// Vary the page so that cached lists aren't reused.
+		test.opt.ListOptions.Page = i + 1
+		if _, err := test.client.Posts.List(test.opt); err != nil {
+			t.Fatal(err)
+		}
+		if personalizedFor != test.want {
+			t.Errorf("#%d: got PersonalizedForUserID %d, want %d", i, personalizedFor, test.want)
+		}
+	}
+}
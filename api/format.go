@@ -0,0 +1,215 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A responseFormat is how a request asked for its response to be encoded.
+type responseFormat struct {
+	// CSV is whether lists should be encoded as CSV, because the request's
+	// Accept header prefers text/csv to JSON. Other responses are always
+	// JSON.
+	CSV bool
+
+	// Pretty is whether JSON should be indented, because the request has
+	// the pretty query parameter (e.g., ?pretty=1).
+	Pretty bool
+}
+
+type contextKey int
+
+const formatKey contextKey = iota
+
+// withResponseFormat returns r with the response format that it requested
+// in its context (see requestedFormat). It removes the pretty query
+// parameter from r's URL, so that handlers don't decode it as an option.
+func withResponseFormat(r *http.Request) *http.Request {
+	var format responseFormat
+	format.CSV = acceptQuality(r, "text/csv") > acceptQuality(r, "application/json")
+
+	q := r.URL.Query()
+	if v, present := q["pretty"]; present {
+		// A bare ?pretty is true too.
+		pretty, _ := strconv.ParseBool(v[0])
+		format.Pretty = pretty || v[0] == ""
+		q.Del("pretty")
+		u := *r.URL
+		u.RawQuery = q.Encode()
+		r = r.WithContext(r.Context()) // a copy of r, to change its URL
+		r.URL = &u
+	}
+	return r.WithContext(context.WithValue(r.Context(), formatKey, format))
+}
+
+// requestedFormat returns the response format that r requested.
+func requestedFormat(r *http.Request) responseFormat {
+	format, _ := r.Context().Value(formatKey).(responseFormat)
+	return format
+}
+
+// acceptQuality returns the quality (between 0 and 1) of the media type
+// in r's Accept header: the q parameter of the most specific media range
+// that matches it. A request with no Accept header accepts anything.
+func acceptQuality(r *http.Request, mediaType string) float64 {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return 1
+	}
+	typ := strings.SplitN(mediaType, "/", 2)[0]
+	q, specificity := 0.0, -1
+	for _, rng := range strings.Split(accept, ",") {
+		params := strings.Split(rng, ";")
+		var s int
+		switch strings.ToLower(strings.TrimSpace(params[0])) {
+		case mediaType:
+			s = 2
+		case typ + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s < specificity {
+			continue
+		}
+		rq := 1.0
+		for _, p := range params[1:] {
+			if kv := strings.SplitN(strings.TrimSpace(p), "=", 2); len(kv) == 2 && kv[0] == "q" {
+				rq, _ = strconv.ParseFloat(kv[1], 64)
+			}
+		}
+		q, specificity = rq, s
+	}
+	return q
+}
+
+// encodeResponse encodes v in the format that r requested, returning the
+// encoded data and its content type.
+func encodeResponse(r *http.Request, v interface{}) ([]byte, string, error) {
+	format := requestedFormat(r)
+	if format.CSV {
+		if data, ok, err := encodeCSV(v); ok || err != nil {
+			return data, "text/csv; charset=utf-8", err
+		}
+	}
+
+	var data []byte
+	var err error
+	if format.Pretty {
+		data, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		data, err = json.Marshal(v)
+	}
+	return data, "application/json; charset=utf-8", err
+}
+
+// encodeCSV encodes v as CSV if it is a list (a slice of structs or struct
+// pointers), with a header row of the structs' JSON field names and a row
+// for each element. If v isn't a list, ok is false.
+func encodeCSV(v interface{}) (data []byte, ok bool, err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, false, nil
+	}
+	elem := rv.Type().Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, false, nil
+	}
+
+	fields := csvFields(elem, nil)
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	header := make([]string, len(fields))
+	for i, f := range fields {
+		header[i] = f.name
+	}
+	cw.Write(header)
+	for i := 0; i < rv.Len(); i++ {
+		e := reflect.Indirect(rv.Index(i))
+		row := make([]string, len(fields))
+		if e.IsValid() {
+			for j, f := range fields {
+				if row[j], err = csvValue(e, f.index); err != nil {
+					return nil, true, err
+				}
+			}
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return buf.Bytes(), true, cw.Error()
+}
+
+// A csvField is a CSV column: a (possibly promoted) struct field.
+type csvField struct {
+	name  string
+	index []int
+}
+
+// csvFields returns the fields of struct type t that encoding/json encodes
+// (including the fields of exported embedded structs), in order.
+func csvFields(t reflect.Type, index []int) []csvField {
+	var fields []csvField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		fi := append(append([]int(nil), index...), i)
+		if f.Anonymous && f.PkgPath == "" && tag[0] == "" && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, csvFields(f.Type, fi)...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		name := tag[0]
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, csvField{name, fi})
+	}
+	return fields
+}
+
+// csvValue returns the CSV cell of the field of struct v at index. Times
+// are formatted as RFC 3339, lists of strings are joined with commas, and
+// other non-scalar values are encoded as JSON.
+func csvValue(v reflect.Value, index []int) (string, error) {
+	f := v.FieldByIndex(index)
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return "", nil
+		}
+		f = f.Elem()
+	}
+	switch x := f.Interface().(type) {
+	case time.Time:
+		if x.IsZero() {
+			return "", nil
+		}
+		return x.Format(time.RFC3339), nil
+	case []string:
+		return strings.Join(x, ","), nil
+	}
+	switch f.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return fmt.Sprint(f.Interface()), nil
+	}
+	data, err := json.Marshal(f.Interface())
+	return string(data), err
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestAcceptQuality(t *testing.T) {
+	tests := []struct {
+		accept  string
+		wantCSV bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"text/csv", true},
+		{"text/*", true},
+		{"text/csv;q=0.5, application/json", false},
+		{"text/csv, application/json;q=0.9", true},
+		{"text/csv, */*;q=0.1", true},
+		{"text/html, */*;q=0.8", false},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/api/posts", nil)
+		req.Header.Set("Accept", test.accept)
+		if got := requestedFormat(withResponseFormat(req)).CSV; got != test.wantCSV {
+			t.Errorf("Accept %q: got CSV %v, want %v", test.accept, got, test.wantCSV)
+		}
+	}
+}
+
+func TestEncodeCSV(t *testing.T) {
+	type embedded struct{ Hidden string }
+	type Embedded struct{ E int }
+	type item struct {
+		Embedded
+		embedded
+		ID      int `json:",omitempty"`
+		Name    string
+		Tags    []string
+		At      time.Time
+		Deleted *time.Time
+		Meta    map[string]int `json:"meta"`
+		Skipped int            `json:"-"`
+	}
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	data, ok, err := encodeCSV([]*item{
+		{ID: 1, Name: `a "quoted", name`, Tags: []string{"go", "db"}, At: at, Meta: map[string]int{"x": 1}},
+		{Embedded: Embedded{2}, ID: 2, Deleted: &at},
+	})
+	if err != nil || !ok {
+		t.Fatalf("got ok %v and error %v, want true and nil", ok, err)
+	}
+	want := `E,ID,Name,Tags,At,Deleted,meta
+0,1,"a ""quoted"", name","go,db",2026-01-02T03:04:05Z,,"{""x"":1}"
+2,2,,,,2026-01-02T03:04:05Z,null
+`
+	if string(data) != want {
+		t.Errorf("got CSV\n%s\nwant\n%s", data, want)
+	}
+
+	if _, ok, _ := encodeCSV(&item{}); ok {
+		t.Error("got ok encoding a non-list as CSV")
+	}
+}
+
+func TestPosts_formats(t *testing.T) {
+	setup()
+
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		return []*thesrc.Post{{ID: 1, Title: "t"}}, nil
+	}
+
+	req := httptest.NewRequest("GET", "/api/posts", nil)
+	req.Header.Set("Accept", "text/csv")
+	rw := httptest.NewRecorder()
+	serveMux.ServeHTTP(rw, req)
+	if rw.Code != http.StatusOK {
+		t.Fatalf("got HTTP %d, want %d", rw.Code, http.StatusOK)
+	}
+	if ct := rw.Header().Get("content-type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("got content-type %q, want CSV", ct)
+	}
+	if lines := strings.Split(rw.Body.String(), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[0], "ID,Title,") || !strings.HasPrefix(lines[1], "1,t,") {
+		t.Errorf("got CSV %q, want a header and a row for the post", rw.Body.String())
+	}
+
+	// Other responses are JSON even when CSV is preferred.
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id}, nil
+	}
+	req = httptest.NewRequest("GET", "/api/posts/1", nil)
+	req.Header.Set("Accept", "text/csv")
+	rw = httptest.NewRecorder()
+	serveMux.ServeHTTP(rw, req)
+	if ct := rw.Header().Get("content-type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("got content-type %q for a single post, want JSON", ct)
+	}
+
+	// JSON is compact unless ?pretty is given (which isn't decoded as a
+	// list option).
+	for query, wantIndented := range map[string]bool{"": false, "?pretty=1": true, "?pretty": true, "?pretty=0": false} {
+		rw := httptest.NewRecorder()
+		serveMux.ServeHTTP(rw, httptest.NewRequest("GET", "/api/posts"+query, nil))
+		if rw.Code != http.StatusOK {
+			t.Errorf("%q: got HTTP %d, want %d", query, rw.Code, http.StatusOK)
+		}
+		if indented := strings.Contains(rw.Body.String(), "\n  "); indented != wantIndented {
+			t.Errorf("%q: got indented %v, want %v", query, indented, wantIndented)
+		}
+	}
+}
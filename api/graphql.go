@@ -0,0 +1,334 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/graphql"
+	"sourcegraph.com/sourcegraph/thesrc/markdown"
+)
+
+func serveGraphQL(w http.ResponseWriter, r *http.Request) error {
+	var req graphql.Request
+	schema := graphqlSchema
+	if r.Method == "GET" {
+		// GET requests must not have side effects, so they may only run
+		// queries.
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if vars := q.Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				return &httpError{http.StatusBadRequest, err}
+			}
+		}
+		schema = &graphql.Schema{Query: graphqlSchema.Query}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return &httpError{http.StatusBadRequest, err}
+	}
+	if ReadOnly() {
+		// Nor may any requests in read-only mode.
+		schema = &graphql.Schema{Query: graphqlSchema.Query}
+	}
+
+	resp := graphql.Execute(schema, &req, r)
+	if resp.Data == nil {
+		// The request couldn't be executed at all.
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	return writeJSON(w, r, resp)
+}
+
+// graphqlSchema exposes posts, comments, users, and votes over GraphQL. Its
+// resolvers receive the *http.Request as their context and apply the same
+// access rules as the REST endpoints.
+var graphqlSchema = &graphql.Schema{
+	Query:    &graphql.Object{Name: "Query"},
+	Mutation: &graphql.Object{Name: "Mutation"},
+}
+
+var (
+	graphqlPost    = &graphql.Object{Name: "Post"}
+	graphqlComment = &graphql.Object{Name: "Comment"}
+	graphqlUser    = &graphql.Object{Name: "User"}
+)
+
+var (
+	idArg     = map[string]*graphql.Arg{"id": {Type: graphql.Int, NonNull: true}}
+	pagingArg = map[string]*graphql.Arg{"page": {Type: graphql.Int}, "perPage": {Type: graphql.Int}}
+)
+
+func init() {
+	graphqlSchema.Query.Fields = map[string]*graphql.Field{
+		"post": {Type: graphqlPost, Args: idArg, Resolve: func(p *graphql.Params) (interface{}, error) {
+			return notFoundIsNull(getPost(p.Context.(*http.Request), p.Int("id")))
+		}},
+		"posts": {Type: graphqlPost, List: true, Args: map[string]*graphql.Arg{
+			"sort":         {Type: graphql.String},
+			"tag":          {Type: graphql.String},
+			"domain":       {Type: graphql.String},
+			"authorUserID": {Type: graphql.Int},
+			"codeOnly":     {Type: graphql.Boolean},
+			"saved":        {Type: graphql.Boolean},
+			"period":       {Type: graphql.String},
+			"show":         {Type: graphql.Boolean},
+			"query":        {Type: graphql.String},
+			"after":        {Type: graphql.String},
+			"page":         {Type: graphql.Int},
+			"perPage":      {Type: graphql.Int},
+		}, Resolve: func(p *graphql.Params) (interface{}, error) {
+			return listPosts(p.Context.(*http.Request), &thesrc.PostListOptions{
+				Sort:         p.String("sort"),
+				Tag:          p.String("tag"),
+				Domain:       p.String("domain"),
+				AuthorUserID: p.Int("authorUserID"),
+				CodeOnly:     p.Bool("codeOnly"),
+				Saved:        p.Bool("saved"),
+				Period:       p.String("period"),
+				Show:         p.Bool("show"),
+				Query:        p.String("query"),
+				After:        p.String("after"),
+				ListOptions:  thesrc.ListOptions{Page: p.Int("page"), PerPage: p.Int("perPage")},
+			})
+		}},
+		"comment": {Type: graphqlComment, Args: idArg, Resolve: func(p *graphql.Params) (interface{}, error) {
+			return notFoundIsNull(getComment(p.Context.(*http.Request), p.Int("id")))
+		}},
+		"user": {Type: graphqlUser, Args: map[string]*graphql.Arg{"login": {Type: graphql.String, NonNull: true}}, Resolve: func(p *graphql.Params) (interface{}, error) {
+			r := p.Context.(*http.Request)
+			user, err := store(p.Context.(*http.Request)).Users.GetByLogin(p.String("login"))
+			if user == nil || err != nil {
+				return notFoundIsNull(nil, err)
+			}
+			return user, hidePrivateUserFields(r, user)
+		}},
+		"currentUser": {Type: graphqlUser, Resolve: func(p *graphql.Params) (interface{}, error) {
+			user, err := authenticatedUser(p.Context.(*http.Request))
+			if user == nil || err != nil {
+				return nil, err
+			}
+			// Shadow-banned users aren't told that they are.
+			user.ShadowBanned = false
+			return user, nil
+		}},
+	}
+
+	graphqlSchema.Mutation.Fields = map[string]*graphql.Field{
+		"submitPost": {Type: graphqlPost, Args: map[string]*graphql.Arg{
+			"title":   {Type: graphql.String},
+			"linkURL": {Type: graphql.String},
+			"body":    {Type: graphql.String},
+			"tags":    {Type: graphql.String, List: true},
+		}, Resolve: func(p *graphql.Params) (interface{}, error) {
+			r := p.Context.(*http.Request)
+			userID, err := authenticatedUserID(r)
+			if err != nil {
+				return nil, err
+			}
+			post := &thesrc.Post{
+				Title:   p.String("title"),
+				LinkURL: p.String("linkURL"),
+				Body:    p.String("body"),
+				Tags:    p.Strings("tags"),
+			}
+			if _, err := submitPost(r, post, userID); err != nil {
+				return nil, err
+			}
+			return post, nil
+		}},
+		"createComment": {Type: graphqlComment, Args: map[string]*graphql.Arg{
+			"postID":   {Type: graphql.Int, NonNull: true},
+			"parentID": {Type: graphql.Int},
+			"body":     {Type: graphql.String, NonNull: true},
+		}, Resolve: func(p *graphql.Params) (interface{}, error) {
+			userID, err := authenticatedUserID(p.Context.(*http.Request))
+			if err != nil {
+				return nil, err
+			}
+			comment := &thesrc.Comment{
+				PostID:       p.Int("postID"),
+				ParentID:     p.Int("parentID"),
+				Body:         p.String("body"),
+				AuthorUserID: userID,
+			}
+			if err := createComment(p.Context.(*http.Request), comment); err != nil {
+				return nil, err
+			}
+			return comment, nil
+		}},
+		"upvote":        {Type: graphqlPost, Args: idArg, Resolve: votePost(true)},
+		"unvote":        {Type: graphqlPost, Args: idArg, Resolve: votePost(false)},
+		"upvoteComment": {Type: graphqlComment, Args: idArg, Resolve: voteComment(true)},
+		"unvoteComment": {Type: graphqlComment, Args: idArg, Resolve: voteComment(false)},
+	}
+
+	graphqlPost.Fields = map[string]*graphql.Field{
+		"id":              {},
+		"title":           {},
+		"linkURL":         {},
+		"domain":          {},
+		"linkDescription": {},
+		"linkImageURL":    {},
+		"linkFaviconURL":  {},
+		"thumbnailURL":    {},
+		"body":            {},
+		"bodyHTML": {Resolve: func(p *graphql.Params) (interface{}, error) {
+			return markdown.HTML(p.Source.(*thesrc.Post).Body), nil
+		}},
+		"submittedAt":  {},
+		"authorUserID": {},
+		"author": {Type: graphqlUser, Resolve: func(p *graphql.Params) (interface{}, error) {
+			return getAuthor(p.Context.(*http.Request), p.Source.(*thesrc.Post).AuthorUserID)
+		}},
+		"score":        {},
+		"tags":         {},
+		"voted":        {},
+		"saved":        {},
+		"hiddenByUser": {},
+		"comments": {Type: graphqlComment, List: true, Resolve: func(p *graphql.Params) (interface{}, error) {
+			comments, err := store(p.Context.(*http.Request)).Comments.ListForPost(p.Source.(*thesrc.Post).ID)
+			if err != nil {
+				return nil, err
+			}
+			if err := hideDeadComments(p.Context.(*http.Request), comments...); err != nil {
+				return nil, err
+			}
+			return comments, markCommentsVoted(p.Context.(*http.Request), comments...)
+		}},
+	}
+
+	graphqlComment.Fields = map[string]*graphql.Field{
+		"id":       {},
+		"postID":   {},
+		"parentID": {},
+		"body":     {},
+		"bodyHTML": {Resolve: func(p *graphql.Params) (interface{}, error) {
+			return markdown.HTML(p.Source.(*thesrc.Comment).Body), nil
+		}},
+		"submittedAt":  {},
+		"authorUserID": {},
+		"author": {Type: graphqlUser, Resolve: func(p *graphql.Params) (interface{}, error) {
+			return getAuthor(p.Context.(*http.Request), p.Source.(*thesrc.Comment).AuthorUserID)
+		}},
+		"score": {},
+		"voted": {},
+		"dead":  {},
+		"post": {Type: graphqlPost, Resolve: func(p *graphql.Params) (interface{}, error) {
+			return notFoundIsNull(getPost(p.Context.(*http.Request), p.Source.(*thesrc.Comment).PostID))
+		}},
+	}
+
+	graphqlUser.Fields = map[string]*graphql.Field{
+		"id":           {},
+		"login":        {},
+		"displayName":  {},
+		"email":        {},
+		"registeredAt": {},
+		"role":         {},
+		"shadowBanned": {},
+		"karma": {Resolve: func(p *graphql.Params) (interface{}, error) {
+			return store(p.Context.(*http.Request)).Users.Karma(p.Source.(*thesrc.User).ID)
+		}},
+		"posts": {Type: graphqlPost, List: true, Args: pagingArg, Resolve: func(p *graphql.Params) (interface{}, error) {
+			return listPosts(p.Context.(*http.Request), &thesrc.PostListOptions{
+				AuthorUserID: p.Source.(*thesrc.User).ID,
+				ListOptions:  thesrc.ListOptions{Page: p.Int("page"), PerPage: p.Int("perPage")},
+			})
+		}},
+		"comments": {Type: graphqlComment, List: true, Args: pagingArg, Resolve: func(p *graphql.Params) (interface{}, error) {
+			comments, err := store(p.Context.(*http.Request)).Comments.List(&thesrc.CommentListOptions{
+				AuthorUserID: p.Source.(*thesrc.User).ID,
+				ListOptions:  thesrc.ListOptions{Page: p.Int("page"), PerPage: p.Int("perPage")},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := hideDeadComments(p.Context.(*http.Request), comments...); err != nil {
+				return nil, err
+			}
+			return comments, markCommentsVoted(p.Context.(*http.Request), comments...)
+		}},
+	}
+}
+
+// getComment gets a comment as seen by r's authenticated user.
+func getComment(r *http.Request, id int) (*thesrc.Comment, error) {
+	comment, err := store(r).Comments.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := hideDeadComments(r, comment); err != nil {
+		return nil, err
+	}
+	return comment, markCommentsVoted(r, comment)
+}
+
+// getAuthor gets the public profile of the user with ID userID, or nil if
+// there is no such user (e.g., the post or comment was anonymous).
+func getAuthor(r *http.Request, userID int) (*thesrc.User, error) {
+	if userID == 0 {
+		return nil, nil
+	}
+	user, err := store(r).Users.Get(userID)
+	if err == thesrc.ErrUserNotFound || user == nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return user, hidePrivateUserFields(r, user)
+}
+
+func votePost(upvote bool) func(*graphql.Params) (interface{}, error) {
+	return func(p *graphql.Params) (interface{}, error) {
+		r := p.Context.(*http.Request)
+		userID, err := requireUserID(r)
+		if err != nil {
+			return nil, err
+		}
+		postID := p.Int("id")
+		if upvote {
+			err = store(p.Context.(*http.Request)).Votes.Upvote(userID, postID)
+		} else {
+			err = store(p.Context.(*http.Request)).Votes.Unvote(userID, postID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		postListCache.invalidate()
+		return notFoundIsNull(getPost(r, postID))
+	}
+}
+
+func voteComment(upvote bool) func(*graphql.Params) (interface{}, error) {
+	return func(p *graphql.Params) (interface{}, error) {
+		r := p.Context.(*http.Request)
+		userID, err := requireUserID(r)
+		if err != nil {
+			return nil, err
+		}
+		commentID := p.Int("id")
+		if upvote {
+			err = store(p.Context.(*http.Request)).Votes.UpvoteComment(userID, commentID)
+		} else {
+			err = store(p.Context.(*http.Request)).Votes.UnvoteComment(userID, commentID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return notFoundIsNull(getComment(r, commentID))
+	}
+}
+
+// notFoundIsNull returns a nil value instead of a "not found" error, so that
+// GraphQL queries for nonexistent posts, comments, and users return null.
+func notFoundIsNull(v interface{}, err error) (interface{}, error) {
+	if e, ok := err.(*httpError); ok && e.status == http.StatusNotFound {
+		err = e.err
+	}
+	switch err {
+	case thesrc.ErrPostNotFound, thesrc.ErrCommentNotFound, thesrc.ErrUserNotFound:
+		return nil, nil
+	}
+	return v, err
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestGraphQL_postWithCommentsAndAuthors(t *testing.T) {
+	setup()
+
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		if id != 1 {
+			return nil, thesrc.ErrPostNotFound
+		}
+		return &thesrc.Post{ID: 1, Title: "t", AuthorUserID: 2}, nil
+	}
+	Store.Comments.(*thesrc.MockCommentsService).ListForPost_ = func(postID int) ([]*thesrc.Comment, error) {
+		return []*thesrc.Comment{{ID: 3, PostID: postID, Body: "*c*", AuthorUserID: 4}}, nil
+	}
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return &thesrc.User{ID: id, Login: map[int]string{2: "alice", 4: "bob"}[id], Email: "secret@example.com"}, nil
+	}
+
+	var data struct {
+		Post struct {
+			Title  string
+			Author struct{ Login, Email string }
+			Comm   []struct {
+				ID       int
+				BodyHTML string
+				Author   struct{ Login string }
+			}
+		}
+		Missing *struct{ ID int }
+	}
+	err := apiClient.GraphQL.Do(`query($id: Int!) {
+		post(id: $id) {
+			title
+			author { login email }
+			comm: comments { id bodyHTML author { login } }
+		}
+		missing: post(id: 9) { id }
+	}`, map[string]interface{}{"id": 1}, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data.Post.Title != "t" || data.Post.Author.Login != "alice" {
+		t.Errorf("got post %+v, want title %q by alice", data.Post, "t")
+	}
+	if data.Post.Author.Email != "" {
+		t.Errorf("got author email %q, want it omitted", data.Post.Author.Email)
+	}
+	if len(data.Post.Comm) != 1 || data.Post.Comm[0].ID != 3 || data.Post.Comm[0].Author.Login != "bob" || data.Post.Comm[0].BodyHTML != "<p><em>c</em></p>\n" {
+		t.Errorf("got comments %+v, want comment 3 by bob", data.Post.Comm)
+	}
+	if data.Missing != nil {
+		t.Errorf("got nonexistent post %+v, want null", data.Missing)
+	}
+}
+
+func TestGraphQL_upvote(t *testing.T) {
+	setup()
+
+	calledUpvote := false
+	Store.Votes.(*datastore.MockVotesStore).Upvote_ = func(userID, postID int) error {
+		if userID != 1 || postID != 2 {
+			t.Errorf("got upvote by user %d on post %d, want user %d on post %d", userID, postID, 1, 2)
+		}
+		calledUpvote = true
+		return nil
+	}
+	Store.Votes.(*datastore.MockVotesStore).Voted_ = func(userID int, postIDs []int) (map[int]bool, error) {
+		return map[int]bool{2: calledUpvote}, nil
+	}
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id}, nil
+	}
+
+	const mutation = `mutation { upvote(id: 2) { id voted } }`
+	err := apiClient.GraphQL.Do(mutation, nil, nil)
+	if errs, ok := err.(thesrc.GraphQLErrors); !ok || !reflect.DeepEqual(errs[0].Path, []interface{}{"upvote"}) {
+		t.Errorf("got error %v for unauthenticated upvote, want a GraphQL error", err)
+	}
+	if calledUpvote {
+		t.Error("calledUpvote for unauthenticated upvote")
+	}
+
+	var data struct{ Upvote struct{ Voted bool } }
+	if err := apiClient.WithAuthToken(newAuthToken(1)).GraphQL.Do(mutation, nil, &data); err != nil {
+		t.Fatal(err)
+	}
+	if !calledUpvote {
+		t.Error("!calledUpvote")
+	}
+	if !data.Upvote.Voted {
+		t.Error("got !voted after upvote")
+	}
+}
+
+func TestGraphQL_createComment(t *testing.T) {
+	setup()
+
+	var created *thesrc.Comment
+	Store.Comments.(*thesrc.MockCommentsService).Create_ = func(comment *thesrc.Comment) error {
+		created = comment
+		comment.ID = 5
+		return nil
+	}
+
+	var data struct{ CreateComment struct{ ID int } }
+	err := apiClient.WithAuthToken(newAuthToken(1)).GraphQL.Do(`mutation($body: String!) {
+		createComment(postID: 2, parentID: 3, body: $body) { id }
+	}`, map[string]interface{}{"body": "b"}, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (&thesrc.Comment{ID: 5, PostID: 2, ParentID: 3, Body: "b", AuthorUserID: 1}); !normalizeDeepEqual(want, created) {
+		t.Errorf("got created comment %+v, want %+v", created, want)
+	}
+	if data.CreateComment.ID != 5 {
+		t.Errorf("got comment ID %d, want 5", data.CreateComment.ID)
+	}
+}
+
+func TestGraphQL_GET(t *testing.T) {
+	setup()
+
+	Store.Users.(*datastore.MockUsersStore).GetByLogin_ = func(login string) (*thesrc.User, error) {
+		return &thesrc.User{ID: 1, Login: login}, nil
+	}
+
+	get := func(query string) *http.Response {
+		resp, err := httpClient.Get("http://example.com/api/graphql?query=" + url.QueryEscape(query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := get(`{ user(login: "alice") { id login } }`); resp.StatusCode != http.StatusOK {
+		t.Errorf("got HTTP %d for query, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	// GET requests may not run mutations.
+	resp := get(`mutation { upvote(id: 1) { id } }`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("got HTTP %d for mutation, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	var body strings.Builder
+	resp.Write(&body)
+	if !strings.Contains(body.String(), "mutation") {
+		t.Errorf("got body %q, want an error about mutations", body.String())
+	}
+}
@@ -1,36 +1,212 @@
 package api
 
 import (
-	"fmt"
-	"log"
+	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
+	"sourcegraph.com/sourcegraph/thesrc"
 	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
 	"sourcegraph.com/sourcegraph/thesrc/router"
+	"sourcegraph.com/sourcegraph/thesrc/tracing"
 )
 
 var (
-	store         = datastore.NewDatastore(nil)
+	// Store is the datastore that the API reads from and writes to.
+	Store = datastore.NewDatastore(nil)
+
 	schemaDecoder = schema.NewDecoder()
 )
 
+// store returns the datastore to use for the request r, which logs with r's
+// logger.
+func store(r *http.Request) *datastore.Datastore {
+	return Store.WithContext(r.Context())
+}
+
 func Handler() *mux.Router {
 	m := router.API()
 	m.Get(router.Post).Handler(handler(servePost))
 	m.Get(router.SubmitPost).Handler(handler(serveSubmitPost))
+	m.Get(router.PreviewPost).Handler(handler(servePreviewPost))
+	m.Get(router.CreatePostBatch).Handler(handler(serveCreatePostBatch))
+	m.Get(router.UpdatePost).Handler(handler(serveUpdatePost))
+	m.Get(router.DeletePost).Handler(handler(serveDeletePost))
+	m.Get(router.UndeletePost).Handler(requireRole(thesrc.RoleAdmin, serveUndeletePost))
+	m.Get(router.PostRevisions).Handler(requireRole(thesrc.RoleModerator, servePostRevisions))
 	m.Get(router.Posts).Handler(handler(servePosts))
+	m.Get(router.Upvote).Handler(handler(serveUpvote))
+	m.Get(router.Unvote).Handler(handler(serveUnvote))
+	m.Get(router.FlagPost).Handler(handler(serveFlagPost))
+	m.Get(router.ModeratePost).Handler(requireRole(thesrc.RoleModerator, serveModeratePost))
+	m.Get(router.BulkModerate).Handler(requireRole(thesrc.RoleModerator, serveBulkModerate))
+	m.Get(router.ReportPost).Handler(handler(serveReportPost))
+	m.Get(router.Reports).Handler(requireRole(thesrc.RoleModerator, serveReports))
+	m.Get(router.SavePost).Handler(handler(serveSavePost))
+	m.Get(router.UnsavePost).Handler(handler(serveUnsavePost))
+	m.Get(router.HidePost).Handler(handler(serveHidePost))
+	m.Get(router.UnhidePost).Handler(handler(serveUnhidePost))
+	m.Get(router.RelatedPosts).Handler(handler(serveRelatedPosts))
+	m.Get(router.Comment).Handler(handler(serveComment))
+	m.Get(router.Comments).Handler(handler(serveComments))
+	m.Get(router.PostComments).Handler(handler(servePostComments))
+	m.Get(router.CreateComment).Handler(handler(serveCreateComment))
+	m.Get(router.UpvoteComment).Handler(handler(serveUpvoteComment))
+	m.Get(router.UnvoteComment).Handler(handler(serveUnvoteComment))
+	m.Get(router.Signup).Handler(handler(serveSignup))
+	m.Get(router.Authenticate).Handler(handler(serveAuthenticate))
+	m.Get(router.CurrentUser).Handler(handler(serveCurrentUser))
+	m.Get(router.UpdateUserSettings).Handler(handler(serveUpdateUserSettings))
+	m.Get(router.ChangePassword).Handler(handler(serveChangePassword))
+	m.Get(router.RequestPasswordReset).Handler(handler(serveRequestPasswordReset))
+	m.Get(router.ResetPassword).Handler(handler(serveResetPassword))
+	m.Get(router.SetUpTwoFactor).Handler(handler(serveSetUpTwoFactor))
+	m.Get(router.EnableTwoFactor).Handler(handler(serveEnableTwoFactor))
+	m.Get(router.DisableTwoFactor).Handler(handler(serveDisableTwoFactor))
+	m.Get(router.RegenerateRecoveryCodes).Handler(handler(serveRegenerateRecoveryCodes))
+	m.Get(router.AuthenticateTwoFactor).Handler(handler(serveAuthenticateTwoFactor))
+	m.Get(router.User).Handler(handler(serveUser))
+	m.Get(router.ShadowBanUser).Handler(requireRole(thesrc.RoleAdmin, serveShadowBanUser))
+	m.Get(router.Tags).Handler(handler(serveTags))
+	m.Get(router.Domain).Handler(handler(serveDomain))
+	m.Get(router.Unfurl).Handler(handler(serveUnfurl))
+	m.Get(router.Notifications).Handler(handler(serveNotifications))
+	m.Get(router.UnreadNotificationCount).Handler(handler(serveUnreadNotificationCount))
+	m.Get(router.MarkNotificationRead).Handler(handler(serveMarkNotificationRead))
+	m.Get(router.MarkAllNotificationsRead).Handler(handler(serveMarkAllNotificationsRead))
+	m.Get(router.Tokens).Handler(handler(serveTokens))
+	m.Get(router.CreateToken).Handler(handler(serveCreateToken))
+	m.Get(router.RevokeToken).Handler(handler(serveRevokeToken))
+	m.Get(router.Sessions).Handler(handler(serveSessions))
+	m.Get(router.RevokeSession).Handler(handler(serveRevokeSession))
+	m.Get(router.RevokeCurrentSession).Handler(handler(serveRevokeCurrentSession))
+	m.Get(router.Follows).Handler(handler(serveFollows))
+	m.Get(router.Follow).Handler(handler(serveFollow))
+	m.Get(router.Unfollow).Handler(handler(serveUnfollow))
+	m.Get(router.Live).Handler(handler(serveLive))
+	m.Get(router.PostsStream).Handler(handler(servePostsStream))
+	m.Get(router.GraphQL).Handler(handler(serveGraphQL))
+	m.Get(router.Webhooks).Handler(requireRole(thesrc.RoleAdmin, serveWebhooks))
+	m.Get(router.CreateWebhook).Handler(requireRole(thesrc.RoleAdmin, serveCreateWebhook))
+	m.Get(router.DeleteWebhook).Handler(requireRole(thesrc.RoleAdmin, serveDeleteWebhook))
+	m.Get(router.WebhookDeliveries).Handler(requireRole(thesrc.RoleAdmin, serveWebhookDeliveries))
+	m.Get(router.Jobs).Handler(requireRole(thesrc.RoleAdmin, serveJobs))
+	m.Get(router.RetryJob).Handler(requireRole(thesrc.RoleAdmin, serveRetryJob))
+	m.Get(router.VoteSuspects).Handler(requireRole(thesrc.RoleModerator, serveVoteSuspects))
+	m.Get(router.NullifyVoteSuspect).Handler(requireRole(thesrc.RoleModerator, serveNullifyVoteSuspect))
+	m.Get(router.DismissVoteSuspect).Handler(requireRole(thesrc.RoleModerator, serveDismissVoteSuspect))
+	m.Get(router.ClientRecords).Handler(requireRole(thesrc.RoleAdmin, serveClientRecords))
+	m.Get(router.AuditLog).Handler(requireRole(thesrc.RoleAdmin, serveAuditLog))
+	m.Get(router.SiteStatus).Handler(handler(serveSiteStatus))
+	m.Get(router.UpdateSiteStatus).Handler(requireRole(thesrc.RoleAdmin, serveUpdateSiteStatus))
+	m.Get(router.OpenAPISpec).Handler(handler(serveOpenAPISpec))
+	m.Get(router.APIDocs).Handler(handler(serveAPIDocs))
+	m.NotFoundHandler = handler(func(w http.ResponseWriter, r *http.Request) error {
+		return &httpError{http.StatusNotFound, errors.New("no such API endpoint")}
+	})
+	metrics.InstrumentRoutes("api", m)
 	return m
 }
 
 type handler func(http.ResponseWriter, *http.Request) error
 
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	err := h(w, r)
+	if route := mux.CurrentRoute(r); route != nil {
+		logging.SetRoute(r, route.GetName())
+		tracing.SetRoute(r, route.GetName())
+	}
+
+	r = withResponseFormat(r)
+	err := limitRate(w, r)
+	if err == nil {
+		err = checkReadOnly(r)
+	}
+	if err == nil {
+		err = h(w, r)
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "error: %s", err)
-		log.Println(err)
+		writeError(w, err)
+		logging.FromContext(r.Context()).Log("API error", "error", err)
+	}
+}
+
+// writeError writes err to w as a JSON-encoded thesrc.ErrorResponse, with
+// the HTTP status code of err (if it is an *httpError), or HTTP 400 for
+// validation errors, or HTTP 500.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if e, ok := err.(*httpError); ok {
+		status = e.status
+		err = e.err
+	} else {
+		switch err.(type) {
+		case *json.SyntaxError, *json.UnmarshalTypeError:
+			status = http.StatusBadRequest
+		}
+		switch err {
+		case thesrc.ErrPostNotFound, thesrc.ErrCommentNotFound, thesrc.ErrUserNotFound, thesrc.ErrNotificationNotFound, thesrc.ErrTokenNotFound, thesrc.ErrWebhookNotFound, thesrc.ErrJobNotFound, thesrc.ErrVoteSuspectNotFound:
+			status = http.StatusNotFound
+		case thesrc.ErrPostVersionConflict:
+			status = http.StatusConflict
+		}
+	}
+
+	resp := &thesrc.ErrorResponse{Code: errorCode(status), Message: err.Error()}
+	if fields, ok := err.(thesrc.ValidationError); ok {
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		resp.Code = thesrc.ErrCodeInvalid
+		resp.Fields = fields
+	}
+
+	data, _ := json.Marshal(resp)
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// errorCode returns the thesrc.ErrorResponse code for errors with the given
+// HTTP status code.
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return thesrc.ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		return thesrc.ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return thesrc.ErrCodeForbidden
+	case http.StatusNotFound:
+		return thesrc.ErrCodeNotFound
+	case http.StatusConflict:
+		return thesrc.ErrCodeConflict
+	case http.StatusTooManyRequests:
+		return thesrc.ErrCodeRateLimited
+	case http.StatusServiceUnavailable:
+		return thesrc.ErrCodeUnavailable
+	}
+	if status >= 500 {
+		return thesrc.ErrCodeInternal
 	}
+	return thesrc.ErrCodeBadRequest
+}
+
+// httpError is an error that is reported to the client with a specific HTTP
+// status code.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string       { return e.err.Error() }
+func (e *httpError) HTTPStatusCode() int { return e.status }
+
+// invalidField returns an error reporting that the request's field is
+// invalid for the reason described by err.
+func invalidField(field string, err error) error {
+	return &httpError{http.StatusBadRequest, thesrc.ValidationError{{Field: field, Message: err.Error()}}}
 }
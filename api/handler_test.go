@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestErrorResponse_notFound(t *testing.T) {
+	setup()
+
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return nil, thesrc.ErrPostNotFound
+	}
+
+	_, err := apiClient.Posts.Get(1)
+	if !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v, want HTTP %d", err, http.StatusNotFound)
+	}
+	if e, ok := err.(*thesrc.ErrorResponse); !ok || e.Code != thesrc.ErrCodeNotFound || e.Message != thesrc.ErrPostNotFound.Error() {
+		t.Errorf("got error %#v, want code %q and message %q", err, thesrc.ErrCodeNotFound, thesrc.ErrPostNotFound)
+	}
+}
+
+func TestErrorResponse_noSuchEndpoint(t *testing.T) {
+	setup()
+
+	resp, err := httpClient.Get("http://example.com/api/nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("got HTTP %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	if ct := resp.Header.Get("content-type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("got content-type %q, want JSON", ct)
+	}
+	var e thesrc.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Code != thesrc.ErrCodeNotFound {
+		t.Errorf("got error code %q, want %q", e.Code, thesrc.ErrCodeNotFound)
+	}
+}
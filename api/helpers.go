@@ -1,20 +1,92 @@
 package api
 
 import (
-	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/markdown"
 )
 
-// writeJSON writes a JSON Content-Type header and a JSON-encoded object to the
-// http.ResponseWriter.
-func writeJSON(w http.ResponseWriter, v interface{}) error {
-	// Indent the JSON so it's easier to read for hackers.
-	data, err := json.MarshalIndent(v, "", "  ")
+// writeJSON writes v to w, encoded as JSON (or, if it is a list and r
+// prefers CSV, as CSV) with the matching Content-Type header. See
+// responseFormat.
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	data, contentType, err := encodeResponse(r, v)
 	if err != nil {
 		return err
 	}
 
-	w.Header().Set("content-type", "application/json; charset=utf-8")
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("content-type", contentType)
 	_, err = w.Write(data)
 	return err
 }
+
+// writePaginationLinks writes a Link header (RFC 5988) to w with the URLs of
+// the next and previous pages of the paginated result set that r requested.
+// n is the number of results on the current page, which is used to
+// determine whether there might be a next page.
+func writePaginationLinks(w http.ResponseWriter, r *http.Request, opt thesrc.ListOptions, n int) {
+	pageURL := func(page int) string {
+		u, err := url.ParseRequestURI(r.RequestURI)
+		if err != nil {
+			u = &url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		}
+		q := u.Query()
+		q.Set("Page", strconv.Itoa(page))
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+
+	var links []string
+	if n >= opt.PerPageOrDefault() {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(opt.PageOrDefault()+1)))
+	}
+	if opt.PageOrDefault() > 1 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(opt.PageOrDefault()-1)))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// writeCursorLink writes a Link header to w with the URL of the page of a
+// cursor-paginated list (like r's) after the cursor next, if next is
+// non-empty. Cursors only go forward, so there is no "prev" link.
+func writeCursorLink(w http.ResponseWriter, r *http.Request, next string) {
+	if next == "" {
+		return
+	}
+	u, err := url.ParseRequestURI(r.RequestURI)
+	if err != nil {
+		u = &url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	}
+	q := u.Query()
+	q.Set("After", next)
+	q.Del("Page")
+	u.RawQuery = q.Encode()
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u))
+}
+
+// renderBody returns whether r has the RenderBody query parameter, which
+// requests that posts and comments' BodyHTML fields be set.
+func renderBody(r *http.Request) bool {
+	v, _ := strconv.ParseBool(r.URL.Query().Get("RenderBody"))
+	return v
+}
+
+func renderPostBodies(posts ...*thesrc.Post) {
+	for _, post := range posts {
+		post.BodyHTML = markdown.HTML(post.Body)
+	}
+}
+
+func renderCommentBodies(comments ...*thesrc.Comment) {
+	for _, comment := range comments {
+		comment.BodyHTML = markdown.HTML(comment.Body)
+	}
+}
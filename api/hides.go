@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveHidePost(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Hides.Hide(userID, postID); err != nil {
+		if err == thesrc.ErrPostNotFound {
+			return &httpError{http.StatusNotFound, err}
+		}
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveUnhidePost(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Hides.Unhide(userID, postID); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// markHiddenByUser sets the HiddenByUser field on each post that the user r
+// is authenticated as has hidden.
+func markHiddenByUser(r *http.Request, posts ...*thesrc.Post) error {
+	userID, err := authenticatedUserID(r)
+	if err != nil || userID == 0 {
+		return err
+	}
+
+	postIDs := make([]int, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+	hidden, err := store(r).Hides.Hidden(userID, postIDs)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		post.HiddenByUser = hidden[post.ID]
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestPost_Hide(t *testing.T) {
+	setup()
+
+	calledHide := false
+	Store.Hides.(*datastore.MockHidesStore).Hide_ = func(userID, postID int) error {
+		if userID != 1 || postID != 2 {
+			t.Errorf("got hide by user %d of post %d, want user %d of post %d", userID, postID, 1, 2)
+		}
+		calledHide = true
+		return nil
+	}
+
+	if err := apiClient.Posts.Hide(2); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for unauthenticated hide, want HTTP %d", err, http.StatusUnauthorized)
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Posts.Hide(2); err != nil {
+		t.Fatal(err)
+	}
+	if !calledHide {
+		t.Error("!calledHide")
+	}
+}
+
+func TestPost_Unhide(t *testing.T) {
+	setup()
+
+	calledUnhide := false
+	Store.Hides.(*datastore.MockHidesStore).Unhide_ = func(userID, postID int) error {
+		if userID != 1 || postID != 2 {
+			t.Errorf("got unhide by user %d of post %d, want user %d of post %d", userID, postID, 1, 2)
+		}
+		calledUnhide = true
+		return nil
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Posts.Unhide(2); err != nil {
+		t.Fatal(err)
+	}
+	if !calledUnhide {
+		t.Error("!calledUnhide")
+	}
+}
+
+func TestPosts_List_hidden(t *testing.T) {
+	setup()
+
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return &thesrc.User{ID: id}, nil
+	}
+	var excluded int
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		excluded = opt.ExcludeHiddenByUserID
+		return []*thesrc.Post{{ID: 2}}, nil
+	}
+
+	tests := []struct {
+		client *thesrc.Client
+		opt    *thesrc.PostListOptions
+		want   int
+	}{
+		{apiClient, &thesrc.PostListOptions{}, 0},
+		{apiClient.WithAuthToken(newAuthToken(1)), &thesrc.PostListOptions{}, 1},
+		{apiClient.WithAuthToken(newAuthToken(1)), &thesrc.PostListOptions{Saved: true}, 0}, // saved posts are listed even if hidden
+	}
+	for i, test := range tests {
+		// Vary the page so that cached lists aren't reused.
+		test.opt.ListOptions.Page = i + 1
+		if _, err := test.client.Posts.List(test.opt); err != nil {
+			t.Fatal(err)
+		}
+		if excluded != test.want {
+			t.Errorf("#%d: got ExcludeHiddenByUserID %d, want %d", i, excluded, test.want)
+		}
+	}
+}
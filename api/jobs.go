@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/jobs"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+)
+
+// QueueJobs is whether slow work triggered by requests is queued on the job
+// queue (Store.Jobs) for workers to run, instead of being skipped. Currently
+// this is fetching the description, image, and favicon of submitted links
+// that were given a title (links without titles are always unfurled during
+// the request, to get their titles).
+var QueueJobs = false
+
+// queueUnfurl queues a job to fetch the metadata of a newly submitted link
+// post, if it has none (see QueueJobs).
+func queueUnfurl(r *http.Request, post *thesrc.Post) {
+	if !QueueJobs || post.LinkURL == "" || post.LinkDescription != "" || post.LinkImageURL != "" {
+		return
+	}
+	if err := jobs.Enqueue(store(r).Jobs, jobs.UnfurlKind, "", &jobs.UnfurlPayload{PostID: post.ID, LinkURL: post.LinkURL}); err != nil {
+		logging.FromContext(r.Context()).Log("Queueing unfurl job failed", "post_id", post.ID, "error", err)
+	}
+}
+
+func serveJobs(w http.ResponseWriter, r *http.Request) error {
+	var opt thesrc.JobListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+
+	jobs, err := store(r).Jobs.List(&opt)
+	if err != nil {
+		return err
+	}
+	if jobs == nil {
+		jobs = []*thesrc.Job{}
+	}
+
+	return writeJSON(w, r, jobs)
+}
+
+func serveRetryJob(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Jobs.Retry(id); err != nil {
+		return err
+	}
+	if err := audit(r, thesrc.AuditRetryJob, auditTarget("job", id), ""); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
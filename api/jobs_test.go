@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/jobs"
+)
+
+func TestJobs_List(t *testing.T) {
+	setup()
+	mockAdmin(1)
+
+	Store.Jobs.(*datastore.MockJobsStore).List_ = func(opt *thesrc.JobListOptions) ([]*thesrc.Job, error) {
+		if opt.State != thesrc.JobFailed {
+			t.Errorf("got State %q, want %q", opt.State, thesrc.JobFailed)
+		}
+		return []*thesrc.Job{{ID: 1, Kind: "webhook", State: thesrc.JobFailed}}, nil
+	}
+
+	opt := &thesrc.JobListOptions{State: thesrc.JobFailed}
+	if _, err := apiClient.WithAuthToken(newAuthToken(2)).Jobs.List(opt); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v listing jobs as non-admin, want HTTP %d", err, http.StatusForbidden)
+	}
+
+	jobs, err := apiClient.WithAuthToken(newAuthToken(1)).Jobs.List(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != 1 {
+		t.Errorf("got jobs %+v, want job 1", jobs)
+	}
+}
+
+func TestJobs_Retry(t *testing.T) {
+	setup()
+	mockAdmin(1)
+
+	var retried int
+	Store.Jobs.(*datastore.MockJobsStore).Retry_ = func(id int) error {
+		if id != 1 {
+			return thesrc.ErrJobNotFound
+		}
+		retried = id
+		return nil
+	}
+
+	client := apiClient.WithAuthToken(newAuthToken(1))
+	if err := client.Jobs.Retry(1); err != nil {
+		t.Fatal(err)
+	}
+	if retried != 1 {
+		t.Errorf("got retried job %d, want 1", retried)
+	}
+	if err := client.Jobs.Retry(2); !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v retrying a job that isn't failed, want HTTP %d", err, http.StatusNotFound)
+	}
+}
+
+func TestPost_Submit_queueUnfurl(t *testing.T) {
+	setup()
+	QueueJobs = true
+
+	Store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		post.ID = 1
+		return true, nil
+	}
+	var queued []*thesrc.Job
+	Store.Jobs.(*datastore.MockJobsStore).Enqueue_ = func(job *thesrc.Job) error {
+		queued = append(queued, job)
+		return nil
+	}
+
+	if _, err := apiClient.Posts.Submit(&thesrc.Post{Title: "t", LinkURL: "http://example.com/"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 1 || queued[0].Kind != jobs.UnfurlKind || queued[0].Payload != `{"PostID":1,"LinkURL":"http://example.com/"}` {
+		t.Errorf("got queued jobs %+v, want an unfurl job for post 1", queued)
+	}
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"sourcegraph.com/sourcegraph/thesrc/unfurl"
+)
+
+// unfurlLink fetches a link's metadata. It is a variable so that tests can
+// replace it.
+var unfurlLink = unfurl.Fetch
+
+func serveUnfurl(w http.ResponseWriter, r *http.Request) error {
+	linkURL := r.URL.Query().Get("url")
+	if linkURL == "" {
+		return &httpError{http.StatusBadRequest, errors.New("url query parameter is required")}
+	}
+	if err := checkLinkURL(linkURL); err != nil {
+		return err
+	}
+
+	meta, err := unfurlLink(linkURL)
+	if err != nil {
+		return &httpError{http.StatusBadGateway, err}
+	}
+	return writeJSON(w, r, meta)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestUnfurl(t *testing.T) {
+	setup()
+
+	want := &thesrc.LinkMetadata{Title: "t", FaviconURL: "http://example.com/favicon.ico"}
+	unfurlLink = func(linkURL string) (*thesrc.LinkMetadata, error) {
+		if linkURL != "http://example.com/a?b=c" {
+			t.Errorf("got link URL %q, want %q", linkURL, "http://example.com/a?b=c")
+		}
+		return want, nil
+	}
+
+	meta, err := apiClient.Links.Unfurl("http://example.com/a?b=c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("got metadata %+v, want %+v", meta, want)
+	}
+}
+
+func TestUnfurl_invalidURL(t *testing.T) {
+	setup()
+
+	unfurlLink = func(linkURL string) (*thesrc.LinkMetadata, error) {
+		t.Errorf("unexpected fetch of %q", linkURL)
+		return nil, nil
+	}
+
+	for _, linkURL := range []string{"", "ftp://example.com", "http://localhost/", "http://example.com:8080/"} {
+		_, err := apiClient.Links.Unfurl(linkURL)
+		if !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+			t.Errorf("%q: got error %v, want HTTP status %d", linkURL, err, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestPost_Submit_unfurl(t *testing.T) {
+	setup()
+
+	unfurlLink = func(linkURL string) (*thesrc.LinkMetadata, error) {
+		return &thesrc.LinkMetadata{Title: "t", Description: "d", ImageURL: "http://example.com/i.png", FaviconURL: "http://example.com/f.ico"}, nil
+	}
+	var submitted *thesrc.Post
+	Store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		submitted = post
+		return true, nil
+	}
+
+	if _, err := apiClient.Posts.Submit(&thesrc.Post{LinkURL: "http://example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &thesrc.Post{Title: "t", LinkURL: "http://example.com", LinkDescription: "d", LinkImageURL: "http://example.com/i.png", LinkFaviconURL: "http://example.com/f.ico"}
+	if !normalizeDeepEqual(submitted, want) {
+		t.Errorf("got submitted post %+v, want %+v", submitted, want)
+	}
+}
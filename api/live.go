@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/events"
+)
+
+const (
+	// liveWriteTimeout is how long a write to a live updates connection may
+	// take before the client is assumed to be gone.
+	liveWriteTimeout = 10 * time.Second
+
+	// livePingInterval is how often live updates connections are pinged to
+	// keep them open through proxies and detect dead clients.
+	livePingInterval = 30 * time.Second
+)
+
+var liveUpgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 4096}
+
+// liveEventHub, if set, is the hub that live updates are sent from, instead
+// of Store.Events (see UseRedis).
+var liveEventHub *events.Hub
+
+func liveEvents() *events.Hub {
+	if liveEventHub != nil {
+		return liveEventHub
+	}
+	return Store.Events
+}
+
+// serveLive upgrades the connection to a WebSocket and sends each new post
+// and score change (as a JSON-encoded thesrc.Event) as it occurs, until the
+// client disconnects. Events for hidden and dead posts (and posts by
+// shadow-banned users) are not sent.
+func serveLive(w http.ResponseWriter, r *http.Request) error {
+	// Subscribe before upgrading so that the client receives all events
+	// that occur after its connection is established.
+	events, cancel := liveEvents().Subscribe()
+	defer cancel()
+
+	conn, err := liveUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an HTTP error.
+		return nil
+	}
+	defer conn.Close()
+
+	// Clients don't send anything, but reading is necessary to process
+	// control frames and notice when the client disconnects.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(livePingInterval)
+	defer ping.Stop()
+
+	// After the upgrade, errors can't be reported to the client as HTTP
+	// responses, so they just end the connection.
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if e.Type != thesrc.EventPostCreated && e.Type != thesrc.EventPostScore {
+				continue
+			}
+			if e.Post != nil {
+				if e.Post.Hidden || e.Post.Dead {
+					continue
+				}
+				if banned, err := authorShadowBanned(r, e.Post); err != nil {
+					return nil
+				} else if banned {
+					continue
+				}
+			}
+			conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			if err := conn.WriteJSON(e); err != nil {
+				return nil
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteTimeout)); err != nil {
+				return nil
+			}
+		case <-closed:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestLive(t *testing.T) {
+	setup()
+
+	s := httptest.NewServer(serveMux)
+	defer s.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/api/live", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	Store.Events.Publish(&thesrc.Event{Type: thesrc.EventPostCreated, Post: &thesrc.Post{ID: 1, Hidden: true}})
+	Store.Events.Publish(&thesrc.Event{Type: thesrc.EventPostScore, Post: &thesrc.Post{ID: 2, Score: 5}})
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var e thesrc.Event
+	if err := conn.ReadJSON(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != thesrc.EventPostScore || e.Post.ID != 2 || e.Post.Score != 5 {
+		t.Errorf("got event %s with post %+v, want score event for post 2 (hidden posts' events should be skipped)", e.Type, e.Post)
+	}
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+)
+
+func serveNotifications(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var opt thesrc.NotificationListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+
+	notifications, err := store(r).Notifications.List(userID, &opt)
+	if err != nil {
+		return err
+	}
+	if err := setNotificationDetails(r, notifications); err != nil {
+		return err
+	}
+	if notifications == nil {
+		notifications = []*thesrc.Notification{}
+	}
+
+	writePaginationLinks(w, r, opt.ListOptions, len(notifications))
+	return writeJSON(w, r, notifications)
+}
+
+func serveUnreadNotificationCount(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	n, err := store(r).Notifications.UnreadCount(userID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, r, thesrc.NotificationCount{Unread: n})
+}
+
+func serveMarkNotificationRead(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Notifications.MarkRead(userID, id); err == thesrc.ErrNotificationNotFound {
+		return &httpError{http.StatusNotFound, err}
+	} else if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveMarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Notifications.MarkAllRead(userID); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// setNotificationDetails sets the ActorLogin and PostTitle fields of
+// notifications, so that clients can describe them without looking up each
+// user and post.
+func setNotificationDetails(r *http.Request, notifications []*thesrc.Notification) error {
+	logins := map[int]string{}
+	titles := map[int]string{}
+	for _, n := range notifications {
+		if _, present := logins[n.ActorUserID]; !present {
+			user, err := store(r).Users.Get(n.ActorUserID)
+			if err != nil && err != thesrc.ErrUserNotFound {
+				return err
+			}
+			if user != nil {
+				logins[n.ActorUserID] = user.Login
+			}
+		}
+		if _, present := titles[n.PostID]; !present {
+			post, err := store(r).Posts.Get(n.PostID)
+			if err != nil && err != thesrc.ErrPostNotFound {
+				return err
+			}
+			if post != nil {
+				titles[n.PostID] = post.Title
+			}
+		}
+		n.ActorLogin = logins[n.ActorUserID]
+		n.PostTitle = titles[n.PostID]
+	}
+	return nil
+}
+
+// A notifier creates notifications of a post or comment, notifying each user
+// at most once.
+type notifier struct {
+	store *datastore.Datastore
+
+	// template is copied to create each notification.
+	template thesrc.Notification
+
+	// notified is the set of users who have been (or shouldn't be)
+	// notified.
+	notified map[int]bool
+}
+
+// newNotifier returns a notifier of a post or comment by the user with ID
+// actorUserID, or nil if the user's posts and comments shouldn't notify
+// anyone (because they are anonymous or shadow-banned).
+func newNotifier(r *http.Request, actorUserID, postID, commentID int) (*notifier, error) {
+	if actorUserID == 0 {
+		return nil, nil
+	}
+	s := store(r)
+	actor, err := s.Users.Get(actorUserID)
+	if err == thesrc.ErrUserNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if actor == nil || actor.ShadowBanned {
+		return nil, nil
+	}
+	return &notifier{
+		store:    s,
+		template: thesrc.Notification{ActorUserID: actorUserID, PostID: postID, CommentID: commentID},
+		notified: map[int]bool{actorUserID: true}, // users aren't notified of their own actions
+	}, nil
+}
+
+func (n *notifier) notify(userID int, typ string) error {
+	if userID == 0 || n.notified[userID] {
+		return nil
+	}
+	n.notified[userID] = true
+	notification := n.template
+	notification.UserID = userID
+	notification.Type = typ
+	return n.store.Notifications.Create(&notification)
+}
+
+// notifyMentions notifies the users @mentioned in body.
+func (n *notifier) notifyMentions(body string) error {
+	for _, login := range thesrc.Mentions(body) {
+		user, err := n.store.Users.GetByLogin(login)
+		if err == thesrc.ErrUserNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := n.notify(user.ID, thesrc.NotificationMention); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyComment notifies the author of the post or comment that comment
+// replies to, and the users whom comment mentions.
+func notifyComment(r *http.Request, comment *thesrc.Comment) error {
+	n, err := newNotifier(r, comment.AuthorUserID, comment.PostID, comment.ID)
+	if err != nil || n == nil {
+		return err
+	}
+
+	var replyToUserID int
+	if comment.ParentID != 0 {
+		parent, err := n.store.Comments.Get(comment.ParentID)
+		if err != nil {
+			return err
+		}
+		replyToUserID = parent.AuthorUserID
+	} else {
+		post, err := n.store.Posts.Get(comment.PostID)
+		if err != nil {
+			return err
+		}
+		replyToUserID = post.AuthorUserID
+	}
+	if err := n.notify(replyToUserID, thesrc.NotificationReply); err != nil {
+		return err
+	}
+	return n.notifyMentions(comment.Body)
+}
+
+// notifyPost notifies the users whom post's body mentions.
+func notifyPost(r *http.Request, post *thesrc.Post) error {
+	n, err := newNotifier(r, post.AuthorUserID, post.ID, 0)
+	if err != nil || n == nil {
+		return err
+	}
+	return n.notifyMentions(post.Body)
+}
+
+// logNotifyError logs err (if any). Failing to notify users shouldn't fail
+// the request that would have notified them.
+func logNotifyError(r *http.Request, err error) {
+	if err != nil {
+		logging.FromContext(r.Context()).Printf("Creating notifications: %s", err)
+	}
+}
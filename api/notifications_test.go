@@ -0,0 +1,159 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestNotifications(t *testing.T) {
+	setup()
+
+	Store.Notifications.(*datastore.MockNotificationsStore).List_ = func(userID int, opt *thesrc.NotificationListOptions) ([]*thesrc.Notification, error) {
+		if userID != 1 || !opt.Unread {
+			t.Errorf("got user %d and options %+v, want user 1's unread notifications", userID, opt)
+		}
+		return []*thesrc.Notification{{ID: 5, UserID: 1, Type: thesrc.NotificationReply, ActorUserID: 2, PostID: 3, CommentID: 4}}, nil
+	}
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return &thesrc.User{ID: id, Login: "bob"}, nil
+	}
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, Title: "t"}, nil
+	}
+
+	if _, err := apiClient.Notifications.List(nil); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for unauthenticated request, want HTTP %d", err, http.StatusUnauthorized)
+	}
+
+	notifications, err := apiClient.WithAuthToken(newAuthToken(1)).Notifications.List(&thesrc.NotificationListOptions{Unread: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 1 || notifications[0].ActorLogin != "bob" || notifications[0].PostTitle != "t" {
+		t.Errorf("got notifications %+v, want 1 with actor login and post title set", notifications)
+	}
+}
+
+func TestNotifications_UnreadCount(t *testing.T) {
+	setup()
+
+	Store.Notifications.(*datastore.MockNotificationsStore).UnreadCount_ = func(userID int) (int, error) {
+		return userID + 1, nil
+	}
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return &thesrc.User{ID: id}, nil
+	}
+
+	client := apiClient.WithAuthToken(newAuthToken(1))
+	if n, err := client.Notifications.UnreadCount(); err != nil || n != 2 {
+		t.Errorf("got unread count %d and error %v, want 2 and nil", n, err)
+	}
+	if user, err := client.Users.Current(); err != nil || user.UnreadNotifications != 2 {
+		t.Errorf("got current user %+v and error %v, want 2 unread notifications", user, err)
+	}
+}
+
+func TestNotifications_MarkRead(t *testing.T) {
+	setup()
+
+	var marked []int
+	Store.Notifications.(*datastore.MockNotificationsStore).MarkRead_ = func(userID, id int) error {
+		if userID != 1 {
+			t.Errorf("got user %d, want 1", userID)
+		}
+		if id != 2 {
+			return thesrc.ErrNotificationNotFound
+		}
+		marked = append(marked, id)
+		return nil
+	}
+	calledMarkAll := false
+	Store.Notifications.(*datastore.MockNotificationsStore).MarkAllRead_ = func(userID int) error {
+		calledMarkAll = userID == 1
+		return nil
+	}
+
+	client := apiClient.WithAuthToken(newAuthToken(1))
+	if err := client.Notifications.MarkRead(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Notifications.MarkRead(3); !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v for another user's notification, want HTTP %d", err, http.StatusNotFound)
+	}
+	if len(marked) != 1 {
+		t.Errorf("got marked %v, want [2]", marked)
+	}
+
+	if err := client.Notifications.MarkAllRead(); err != nil {
+		t.Fatal(err)
+	}
+	if !calledMarkAll {
+		t.Error("!calledMarkAll")
+	}
+}
+
+func TestComment_Create_notifications(t *testing.T) {
+	setup()
+
+	users := map[int]*thesrc.User{
+		1: {ID: 1, Login: "alice"},
+		2: {ID: 2, Login: "bob"},
+		3: {ID: 3, Login: "carol"},
+		4: {ID: 4, Login: "dave", ShadowBanned: true},
+	}
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		if u := users[id]; u != nil {
+			return u, nil
+		}
+		return nil, thesrc.ErrUserNotFound
+	}
+	Store.Users.(*datastore.MockUsersStore).GetByLogin_ = func(login string) (*thesrc.User, error) {
+		for _, u := range users {
+			if u.Login == login {
+				return u, nil
+			}
+		}
+		return nil, thesrc.ErrUserNotFound
+	}
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, AuthorUserID: 2}, nil
+	}
+	Store.Comments.(*thesrc.MockCommentsService).Create_ = func(comment *thesrc.Comment) error {
+		comment.ID = 5
+		return nil
+	}
+	var notifications []*thesrc.Notification
+	Store.Notifications.(*datastore.MockNotificationsStore).Create_ = func(n *thesrc.Notification) error {
+		notifications = append(notifications, n)
+		return nil
+	}
+
+	// The post's author (bob) is notified of the reply, and carol of the
+	// mention. Nobody is notified of their own comment, and bob isn't
+	// notified twice. Unknown users are ignored.
+	comment := &thesrc.Comment{PostID: 6, Body: "@bob @carol @alice @nobody"}
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Comments.Create(comment); err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]string{2: thesrc.NotificationReply, 3: thesrc.NotificationMention}
+	if len(notifications) != len(want) {
+		t.Fatalf("got %d notifications, want %d", len(notifications), len(want))
+	}
+	for _, n := range notifications {
+		if n.Type != want[n.UserID] || n.ActorUserID != 1 || n.PostID != 6 || n.CommentID != 5 {
+			t.Errorf("got notification %+v, want type %q", n, want[n.UserID])
+		}
+	}
+
+	// Shadow-banned users' comments don't notify anyone.
+	notifications = nil
+	if err := apiClient.WithAuthToken(newAuthToken(4)).Comments.Create(&thesrc.Comment{PostID: 6, Body: "@carol"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 0 {
+		t.Errorf("got notifications %+v of a shadow-banned user's comment, want none", notifications)
+	}
+}
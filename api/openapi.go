@@ -0,0 +1,427 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/graphql"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// An endpointDoc describes an API endpoint for its OpenAPI spec. The
+// endpoint's path, methods, and path parameters come from its route (see
+// router.API).
+type endpointDoc struct {
+	Summary string
+
+	// Auth is whether the endpoint requires an authenticated user, and Role
+	// is the role (other than thesrc.RoleMember) that the user must have, if
+	// any.
+	Auth bool
+	Role string
+
+	// Query is a value of the struct type that the endpoint decodes its
+	// query parameters into (for GET requests), or nil.
+	Query interface{}
+
+	// Headers lists the optional request headers that the endpoint reads
+	// (other than Authorization).
+	Headers []string
+
+	// Body is a value of the type that the endpoint decodes its JSON request
+	// body from (for requests other than GET), or nil.
+	Body interface{}
+
+	// Result is a value of the type that the endpoint responds with as JSON,
+	// or nil if it responds with no body.
+	Result interface{}
+
+	// Status is the HTTP status of successful responses. If it is 0, it is
+	// HTTP 200, or HTTP 204 if Result is nil.
+	Status int
+
+	// ContentType is the content type of successful responses, if they
+	// aren't JSON.
+	ContentType string
+}
+
+// endpointDocs describes each API route, by name.
+var endpointDocs = map[string]endpointDoc{
+	router.Posts:           {Summary: "List posts", Query: thesrc.PostListOptions{}, Result: []*thesrc.Post{}},
+	router.SubmitPost:      {Summary: "Submit a post (or get the post already submitted with its link URL or idempotency key)", Auth: true, Headers: []string{thesrc.IdempotencyKeyHeader}, Body: thesrc.Post{}, Result: thesrc.Post{}, Status: http.StatusCreated},
+	router.PostsStream:     {Summary: "Stream new posts as server-sent events", ContentType: "text/event-stream"},
+	router.CreatePostBatch: {Summary: "Submit a batch of posts", Auth: true, Body: []*thesrc.Post{}, Result: []*thesrc.PostBatchResult{}},
+	router.PreviewPost:     {Summary: "Preview a post without submitting it", Auth: true, Body: thesrc.Post{}, Result: thesrc.PostPreview{}},
+	router.PostComments:    {Summary: "List a post's comments", Result: []*thesrc.Comment{}},
+	router.Upvote:          {Summary: "Upvote a post", Auth: true},
+	router.Unvote:          {Summary: "Remove a vote on a post", Auth: true},
+	router.FlagPost:        {Summary: "Flag a post for moderators", Auth: true},
+	router.ModeratePost:    {Summary: "Hide or kill a post", Role: thesrc.RoleModerator, Headers: []string{thesrc.PostVersionHeader}, Body: thesrc.PostModeration{}},
+	router.ReportPost:      {Summary: "Report an abusive post (no account needed)", Body: thesrc.Report{}, Result: thesrc.Report{}, Status: http.StatusCreated},
+	router.UndeletePost:    {Summary: "Undelete a deleted post", Role: thesrc.RoleAdmin},
+	router.SavePost:        {Summary: "Save a post", Auth: true},
+	router.UnsavePost:      {Summary: "Unsave a post", Auth: true},
+	router.HidePost:        {Summary: "Hide a post from one's listings", Auth: true},
+	router.UnhidePost:      {Summary: "Unhide a post", Auth: true},
+	router.RelatedPosts:    {Summary: "List posts related to a post", Query: thesrc.RelatedPostsOptions{}, Result: []*thesrc.Post{}},
+	router.PostRevisions:   {Summary: "List a post's revisions", Role: thesrc.RoleModerator, Result: []*thesrc.PostRevision{}},
+	router.Post:            {Summary: "Get a post", Result: thesrc.Post{}},
+	router.UpdatePost:      {Summary: "Edit a post (of the version in the Post-Version header or the Version field)", Auth: true, Headers: []string{thesrc.PostVersionHeader}, Body: thesrc.Post{}, Result: thesrc.Post{}},
+	router.DeletePost:      {Summary: "Delete a post", Auth: true},
+
+	router.Comments:      {Summary: "List comments", Query: thesrc.CommentListOptions{}, Result: []*thesrc.Comment{}},
+	router.CreateComment: {Summary: "Comment on a post", Auth: true, Body: thesrc.Comment{}, Result: thesrc.Comment{}, Status: http.StatusCreated},
+	router.UpvoteComment: {Summary: "Upvote a comment", Auth: true},
+	router.UnvoteComment: {Summary: "Remove a vote on a comment", Auth: true},
+	router.Comment:       {Summary: "Get a comment", Result: thesrc.Comment{}},
+
+	router.Signup:                  {Summary: "Sign up", Body: thesrc.NewUser{}, Result: thesrc.Auth{}, Status: http.StatusCreated},
+	router.ShadowBanUser:           {Summary: "Shadow-ban (or unban) a user", Role: thesrc.RoleAdmin, Body: thesrc.UserShadowBan{}},
+	router.User:                    {Summary: "Get a user", Result: thesrc.User{}},
+	router.CurrentUser:             {Summary: "Get the authenticated user", Auth: true, Result: thesrc.User{}},
+	router.UpdateUserSettings:      {Summary: "Update the authenticated user's settings", Auth: true, Body: thesrc.UserSettings{}, Result: thesrc.User{}},
+	router.ChangePassword:          {Summary: "Change the authenticated user's password", Auth: true, Body: thesrc.PasswordChange{}, Result: thesrc.Auth{}},
+	router.RequestPasswordReset:    {Summary: "Email a password reset link", Body: struct{ Login string }{}},
+	router.ResetPassword:           {Summary: "Reset a password", Body: thesrc.PasswordReset{}, Result: thesrc.Auth{}},
+	router.EnableTwoFactor:         {Summary: "Enable two-factor authentication", Auth: true, Body: thesrc.TwoFactorEnable{}, Result: []string{}},
+	router.DisableTwoFactor:        {Summary: "Disable two-factor authentication", Auth: true, Body: struct{ Code string }{}},
+	router.SetUpTwoFactor:          {Summary: "Generate a two-factor authentication secret", Auth: true, Result: thesrc.TwoFactorSetup{}},
+	router.RegenerateRecoveryCodes: {Summary: "Replace the two-factor recovery codes", Auth: true, Body: struct{ Code string }{}, Result: []string{}},
+	router.Sessions:                {Summary: "List the authenticated user's sessions", Auth: true, Result: []*thesrc.Session{}},
+	router.RevokeCurrentSession:    {Summary: "Log out", Auth: true},
+	router.RevokeSession:           {Summary: "Revoke a session", Auth: true},
+	router.Authenticate:            {Summary: "Log in", Body: struct{ Login, Password string }{}, Result: thesrc.Auth{}},
+	router.AuthenticateTwoFactor:   {Summary: "Complete a two-factor login", Body: struct{ Token, Code string }{}, Result: thesrc.Auth{}},
+
+	router.Domain: {Summary: "Get a domain's post statistics", Result: thesrc.DomainStats{}},
+	router.Tags:   {Summary: "List tags", Query: thesrc.TagListOptions{}, Result: []*thesrc.Tag{}},
+	router.Unfurl: {Summary: "Get a link's title and metadata", Query: struct {
+		URL string `url:"url"`
+	}{}, Result: thesrc.LinkMetadata{}},
+
+	router.Notifications:            {Summary: "List the authenticated user's notifications", Auth: true, Query: thesrc.NotificationListOptions{}, Result: []*thesrc.Notification{}},
+	router.UnreadNotificationCount:  {Summary: "Count unread notifications", Auth: true, Result: thesrc.NotificationCount{}},
+	router.MarkAllNotificationsRead: {Summary: "Mark all notifications as read", Auth: true},
+	router.MarkNotificationRead:     {Summary: "Mark a notification as read", Auth: true},
+
+	router.Tokens:      {Summary: "List personal API tokens", Auth: true, Result: []*thesrc.Token{}},
+	router.CreateToken: {Summary: "Create a personal API token", Auth: true, Body: thesrc.Token{}, Result: thesrc.Token{}, Status: http.StatusCreated},
+	router.RevokeToken: {Summary: "Revoke a personal API token", Auth: true},
+
+	router.Follows:  {Summary: "List followed tags and domains", Auth: true, Result: []*thesrc.Follow{}},
+	router.Follow:   {Summary: "Follow (or hide) a tag or domain", Auth: true, Body: thesrc.Follow{}, Result: thesrc.Follow{}},
+	router.Unfollow: {Summary: "Unfollow a tag or domain", Auth: true},
+
+	router.Live: {Summary: "Receive live post updates over a WebSocket", Status: http.StatusSwitchingProtocols},
+	router.GraphQL: {Summary: "Run a GraphQL query (or, with POST, a mutation)", Query: struct {
+		Query         string `url:"query"`
+		OperationName string `url:"operationName"`
+		Variables     string `url:"variables"`
+	}{}, Body: graphql.Request{}, Result: graphql.Response{}},
+
+	router.SiteStatus:       {Summary: "Get the site's status", Result: thesrc.SiteStatus{}},
+	router.UpdateSiteStatus: {Summary: "Put the site in (or out of) read-only mode", Role: thesrc.RoleAdmin, Body: thesrc.SiteStatus{}, Result: thesrc.SiteStatus{}},
+
+	router.Webhooks:          {Summary: "List webhooks", Role: thesrc.RoleAdmin, Result: []*thesrc.Webhook{}},
+	router.CreateWebhook:     {Summary: "Register a webhook", Role: thesrc.RoleAdmin, Body: thesrc.Webhook{}, Result: thesrc.Webhook{}, Status: http.StatusCreated},
+	router.WebhookDeliveries: {Summary: "List a webhook's deliveries", Role: thesrc.RoleAdmin, Query: thesrc.ListOptions{}, Result: []*thesrc.WebhookDelivery{}},
+	router.DeleteWebhook:     {Summary: "Delete a webhook", Role: thesrc.RoleAdmin},
+
+	router.Jobs:               {Summary: "List background jobs", Role: thesrc.RoleAdmin, Query: thesrc.JobListOptions{}, Result: []*thesrc.Job{}},
+	router.RetryJob:           {Summary: "Retry a failed job", Role: thesrc.RoleAdmin},
+	router.BulkModerate:       {Summary: "Kill, untag, retag, or queue many posts (or kill many comments) at once", Role: thesrc.RoleModerator, Body: thesrc.BulkModeration{}},
+	router.Reports:            {Summary: "List abuse reports", Role: thesrc.RoleModerator, Query: thesrc.ReportListOptions{}, Result: []*thesrc.Report{}},
+	router.VoteSuspects:       {Summary: "List users suspected of vote fraud", Role: thesrc.RoleModerator, Query: thesrc.ListOptions{}, Result: []*thesrc.VoteSuspect{}},
+	router.NullifyVoteSuspect: {Summary: "Nullify (or restore) a suspect's votes", Role: thesrc.RoleModerator, Body: thesrc.VoteSuspectNullification{}},
+	router.DismissVoteSuspect: {Summary: "Dismiss a vote suspect", Role: thesrc.RoleModerator},
+	router.ClientRecords:      {Summary: "List client records", Role: thesrc.RoleAdmin, Query: thesrc.ClientRecordListOptions{}, Result: []*thesrc.ClientRecord{}},
+	router.AuditLog:           {Summary: "List the audit log", Role: thesrc.RoleAdmin, Query: thesrc.AuditLogListOptions{}, Result: []*thesrc.AuditEntry{}},
+
+	router.OpenAPISpec: {Summary: "Get this OpenAPI spec", Result: map[string]interface{}{}},
+	router.APIDocs:     {Summary: "Explore the API in Swagger UI", ContentType: "text/html"},
+}
+
+// routeVar matches a route template's variables, such as "{ID:.+}".
+var routeVar = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+// openAPISpec returns the OpenAPI 3 spec of the API version v, generated
+// from the API's routes (see router.API) and endpointDocs.
+func openAPISpec(v string) (map[string]interface{}, error) {
+	schemas := &schemaSet{schemas: map[string]interface{}{}, types: map[string]reflect.Type{}}
+	paths := map[string]map[string]interface{}{}
+	err := router.API().Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		doc := endpointDocs[route.GetName()]
+
+		var params []interface{}
+		for _, m := range routeVar.FindAllStringSubmatch(tmpl, -1) {
+			typ := "string"
+			if m[1] == "ID" {
+				typ = "integer"
+			}
+			params = append(params, map[string]interface{}{
+				"name": m[1], "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": typ},
+			})
+		}
+		p := routeVar.ReplaceAllString(tmpl, "{$1}")
+		if paths[p] == nil {
+			paths[p] = map[string]interface{}{}
+		}
+
+		for _, method := range methods {
+			op := map[string]interface{}{
+				"operationId": route.GetName(),
+				"summary":     doc.Summary,
+				"tags":        []string{strings.SplitN(strings.TrimPrefix(p, "/"), "/", 2)[0]},
+				"responses":   openAPIResponses(doc, schemas),
+			}
+			if len(methods) > 1 {
+				op["operationId"] = route.GetName() + "-" + strings.ToLower(method)
+			}
+			if doc.Role != "" {
+				op["description"] = "Requires the " + doc.Role + " role."
+			}
+			if doc.Auth || doc.Role != "" {
+				op["security"] = []interface{}{map[string]interface{}{"bearerAuth": []string{}}}
+			}
+			opParams := params
+			if method == "GET" && doc.Query != nil {
+				opParams = append(opParams, queryParams(reflect.TypeOf(doc.Query), schemas)...)
+			}
+			for _, h := range doc.Headers {
+				opParams = append(opParams, map[string]interface{}{
+					"name": h, "in": "header",
+					"schema": map[string]interface{}{"type": "string"},
+				})
+			}
+			if len(opParams) > 0 {
+				op["parameters"] = opParams
+			}
+			if method != "GET" && doc.Body != nil {
+				op["requestBody"] = map[string]interface{}{
+					"required": true,
+					"content":  jsonContent(schemas.schema(reflect.TypeOf(doc.Body))),
+				}
+			}
+			paths[p][strings.ToLower(method)] = op
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	schemas.schema(reflect.TypeOf(thesrc.ErrorResponse{}))
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "thesrc API",
+			"version": v,
+		},
+		"servers": []interface{}{map[string]interface{}{"url": "/api/" + v}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		// Authentication is optional for endpoints that don't require it,
+		// but it changes some results (e.g., whether posts are Voted).
+		"security": []interface{}{map[string]interface{}{}, map[string]interface{}{"bearerAuth": []string{}}},
+	}, nil
+}
+
+// openAPIResponses returns the OpenAPI responses of the endpoint described
+// by doc.
+func openAPIResponses(doc endpointDoc, schemas *schemaSet) map[string]interface{} {
+	status := doc.Status
+	if status == 0 {
+		status = http.StatusOK
+		if doc.Result == nil && doc.ContentType == "" {
+			status = http.StatusNoContent
+		}
+	}
+	ok := map[string]interface{}{"description": http.StatusText(status)}
+	switch {
+	case doc.Result != nil:
+		ok["content"] = jsonContent(schemas.schema(reflect.TypeOf(doc.Result)))
+	case doc.ContentType != "":
+		ok["content"] = map[string]interface{}{doc.ContentType: map[string]interface{}{}}
+	}
+	return map[string]interface{}{
+		strconv.Itoa(status): ok,
+		"default": map[string]interface{}{
+			"description": "Error",
+			"content":     jsonContent(map[string]interface{}{"$ref": "#/components/schemas/ErrorResponse"}),
+		},
+	}
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// queryParams returns the OpenAPI query parameters of the options struct
+// type t, which are decoded by schemaDecoder and encoded by the client
+// with their "url" tag names (or field names).
+func queryParams(t reflect.Type, schemas *schemaSet) []interface{} {
+	var params []interface{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			params = append(params, queryParams(f.Type, schemas)...)
+			continue
+		}
+		name := strings.Split(f.Tag.Get("url"), ",")[0]
+		if f.PkgPath != "" || name == "-" || f.Tag.Get("schema") == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		params = append(params, map[string]interface{}{
+			"name": name, "in": "query",
+			"schema": schemas.schema(f.Type),
+		})
+	}
+	return params
+}
+
+// A schemaSet generates OpenAPI schemas of Go types, collecting the schemas
+// of named struct types as components.
+type schemaSet struct {
+	schemas map[string]interface{}  // component schemas, by name
+	types   map[string]reflect.Type // the type of each component, by name
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the OpenAPI schema of values of type t when they are
+// encoded as JSON.
+func (s *schemaSet) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		name := t.Name()
+		if other, ok := s.types[name]; ok && other != t {
+			name = strings.Title(path.Base(t.PkgPath())) + name
+		}
+		if _, ok := s.types[name]; !ok {
+			s.types[name] = t
+			s.schemas[name] = nil // in case t refers to itself
+			s.schemas[name] = s.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{} // any value
+}
+
+// structSchema returns the OpenAPI object schema of the struct type t, with
+// the properties that encoding/json encodes.
+func (s *schemaSet) structSchema(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := strings.Split(f.Tag.Get("json"), ",")
+			if tag[0] == "-" {
+				continue
+			}
+			if f.Anonymous && tag[0] == "" {
+				ft := f.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					addFields(ft)
+					continue
+				}
+			}
+			if f.PkgPath != "" {
+				continue
+			}
+			name := tag[0]
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = s.schema(f.Type)
+		}
+	}
+	addFields(t)
+	return map[string]interface{}{"type": "object", "properties": props}
+}
+
+func serveOpenAPISpec(w http.ResponseWriter, r *http.Request) error {
+	spec, err := openAPISpec(V1)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, r, spec)
+}
+
+// apiDocsPage is the Swagger UI page for exploring the API. It loads the
+// spec from the openapi.json endpoint next to it, so it documents the
+// version of the API whose path it was requested at.
+const apiDocsPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>thesrc API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+func serveAPIDocs(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("content-type", "text/html; charset=utf-8")
+	_, err := io.WriteString(w, apiDocsPage)
+	return err
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestEndpointDocs(t *testing.T) {
+	router.API().Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if doc, ok := endpointDocs[route.GetName()]; !ok || doc.Summary == "" {
+			t.Errorf("API route %q isn't documented in endpointDocs", route.GetName())
+		}
+		return nil
+	})
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	setup()
+
+	rw := httptest.NewRecorder()
+	serveMux.ServeHTTP(rw, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("got HTTP %d, want %d", rw.Code, http.StatusOK)
+	}
+
+	var spec struct {
+		OpenAPI string
+		Servers []struct{ URL string }
+		Paths   map[string]map[string]struct {
+			OperationID string
+			Parameters  []struct{ Name, In string }
+			Security    []map[string][]string
+			RequestBody *struct{}
+			Responses   map[string]struct{}
+		}
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]struct {
+					Type, Format string
+					Ref          string `json:"$ref"`
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(rw.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") || len(spec.Servers) != 1 || spec.Servers[0].URL != "/api/v1" {
+		t.Errorf("got openapi %q and servers %+v, want 3.x and /api/v1", spec.OpenAPI, spec.Servers)
+	}
+
+	getPost := spec.Paths["/posts/{ID}"]["get"]
+	if getPost.OperationID != router.Post || len(getPost.Parameters) != 1 || getPost.Parameters[0].Name != "ID" || getPost.Parameters[0].In != "path" {
+		t.Errorf("got GET /posts/{ID} operation %+v, want %q with an ID path parameter", getPost, router.Post)
+	}
+	if _, ok := getPost.Responses["200"]; !ok {
+		t.Errorf("got GET /posts/{ID} responses %+v, want 200", getPost.Responses)
+	}
+	if deletePost := spec.Paths["/posts/{ID}"]["delete"]; len(deletePost.Security) != 1 {
+		t.Errorf("got DELETE /posts/{ID} security %+v, want bearer auth required", deletePost.Security)
+	}
+	if submit := spec.Paths["/posts"]["post"]; submit.RequestBody == nil {
+		t.Error("got no request body for POST /posts")
+	} else if _, ok := submit.Responses["201"]; !ok {
+		t.Errorf("got POST /posts responses %+v, want 201", submit.Responses)
+	}
+
+	var names []string
+	for _, p := range spec.Paths["/posts"]["get"].Parameters {
+		names = append(names, p.Name)
+	}
+	if got := strings.Join(names, " "); !strings.Contains(got, "Sort") || !strings.Contains(got, "PerPage") || strings.Contains(got, "ViewerUserID") {
+		t.Errorf("got GET /posts parameters %q, want options like Sort and PerPage but not internal ones", got)
+	}
+
+	post := spec.Components.Schemas["Post"].Properties
+	if post["Title"].Type != "string" || post["SubmittedAt"].Format != "date-time" {
+		t.Errorf("got Post properties %+v, want Title string and SubmittedAt date-time", post)
+	}
+	if _, ok := spec.Components.Schemas["ErrorResponse"]; !ok {
+		t.Error("got no ErrorResponse schema")
+	}
+}
+
+func TestAPIDocs(t *testing.T) {
+	setup()
+
+	rw := httptest.NewRecorder()
+	serveMux.ServeHTTP(rw, httptest.NewRequest("GET", "/api/v1/docs", nil))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("got HTTP %d, want %d", rw.Code, http.StatusOK)
+	}
+	if ct := rw.Header().Get("content-type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("got content-type %q, want HTML", ct)
+	}
+	if !strings.Contains(rw.Body.String(), `url: "openapi.json"`) {
+		t.Error("docs page doesn't load openapi.json")
+	}
+}
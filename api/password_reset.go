@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/mail"
+)
+
+var (
+	// Mailer (if set) sends password reset emails. If it is nil, passwords
+	// can't be reset.
+	Mailer mail.Sender
+
+	// PasswordResetURL is the absolute URL of the app's page for resetting
+	// a password. Password reset emails link to it with the reset token in
+	// the Token query parameter.
+	PasswordResetURL = &url.URL{Scheme: "http", Host: "thesrc.org", Path: "/reset-password"}
+)
+
+// passwordResetTokenLifetime is how long a password reset link remains
+// valid after it is emailed.
+const passwordResetTokenLifetime = time.Hour
+
+var errInvalidPasswordResetToken = errors.New("invalid or expired password reset link")
+
+// newPasswordResetToken returns a signed token that allows resetting user's
+// password until it expires. It is signed with the user's current password
+// hash, so it can only be used once.
+func newPasswordResetToken(user *thesrc.User) string {
+	return newUserToken("password-reset", user, passwordResetTokenLifetime)
+}
+
+// passwordResetTokenUser verifies the password reset token and returns the
+// user whose password it allows resetting.
+func passwordResetTokenUser(r *http.Request, token string) (*thesrc.User, error) {
+	user, err := userTokenUser(r, "password-reset", token)
+	if err == errInvalidUserToken {
+		return nil, errInvalidPasswordResetToken
+	}
+	return user, err
+}
+
+func serveRequestPasswordReset(w http.ResponseWriter, r *http.Request) error {
+	var req struct{ Login string }
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+	req.Login = strings.TrimSpace(req.Login)
+	if req.Login == "" {
+		return invalidField("Login", errors.New("login or email address is required"))
+	}
+	if Mailer == nil {
+		return &httpError{http.StatusNotImplemented, errors.New("password reset email is not configured")}
+	}
+
+	var user *thesrc.User
+	var err error
+	if strings.Contains(req.Login, "@") {
+		user, err = store(r).Users.GetByEmail(req.Login)
+	} else {
+		user, err = store(r).Users.GetByLogin(req.Login)
+	}
+	// Respond the same way whether or not there is such a user, so that
+	// this can't be used to find out who has an account.
+	if err == thesrc.ErrUserNotFound || (err == nil && user.Email == "") {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	u := *PasswordResetURL
+	u.RawQuery = url.Values{"Token": []string{newPasswordResetToken(user)}}.Encode()
+	err = Mailer.Send(&mail.Message{
+		To:      user.Email,
+		Subject: "Reset your thesrc password",
+		Body: fmt.Sprintf(`Hi %s,
+
+Someone (hopefully you) asked to reset the password of your thesrc account. To choose a new password, open this link within an hour:
+
+%s
+
+If you didn't ask to reset your password, ignore this email. Your password won't change.
+`, user.Login, u.String()),
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveResetPassword(w http.ResponseWriter, r *http.Request) error {
+	var reset thesrc.PasswordReset
+	if err := json.NewDecoder(r.Body).Decode(&reset); err != nil {
+		return err
+	}
+
+	user, err := passwordResetTokenUser(r, reset.Token)
+	if err == errInvalidPasswordResetToken {
+		return invalidField("Token", err)
+	} else if err != nil {
+		return err
+	}
+	if len(reset.NewPassword) < thesrc.MinPasswordLength {
+		return invalidField("NewPassword", fmt.Errorf("password must be at least %d characters long", thesrc.MinPasswordLength))
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(reset.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := store(r).Users.SetPasswordHash(user.ID, hash); err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	if err := store(r).Sessions.DeleteAll(user.ID, 0); err != nil {
+		return err
+	}
+
+	auth, err := passwordAuth(r, user)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, r, auth)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/mail"
+)
+
+func TestPasswordReset(t *testing.T) {
+	setup()
+
+	var sent []*mail.Message
+	Mailer = &mail.MockSender{Send_: func(msg *mail.Message) error {
+		sent = append(sent, msg)
+		return nil
+	}}
+	defer func() { Mailer = nil }()
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	alice := &thesrc.User{ID: 1, Login: "alice", Email: "alice@example.com", PasswordHash: hash}
+	Store.Users.(*datastore.MockUsersStore).GetByEmail_ = func(email string) (*thesrc.User, error) {
+		if email != alice.Email {
+			return nil, thesrc.ErrUserNotFound
+		}
+		return alice, nil
+	}
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		u := *alice
+		return &u, nil
+	}
+	Store.Users.(*datastore.MockUsersStore).SetPasswordHash_ = func(userID int, hash []byte) error {
+		alice.PasswordHash = hash
+		return nil
+	}
+
+	// Unknown users aren't revealed.
+	if err := apiClient.Users.RequestPasswordReset("bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 0 {
+		t.Fatalf("sent %d messages for unknown user, want 0", len(sent))
+	}
+
+	if err := apiClient.Users.RequestPasswordReset("alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0].To != alice.Email {
+		t.Fatalf("got sent messages %+v, want 1 to %s", sent, alice.Email)
+	}
+	link := regexp.MustCompile(`http\S+`).FindString(sent[0].Body)
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := u.Query().Get("Token")
+
+	if _, err := apiClient.Users.ResetPassword(&thesrc.PasswordReset{Token: token, NewPassword: "short"}); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v for short password, want HTTP %d", err, http.StatusBadRequest)
+	}
+	auth, err := apiClient.Users.ResetPassword(&thesrc.PasswordReset{Token: token, NewPassword: "password2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bcrypt.CompareHashAndPassword(alice.PasswordHash, []byte("password2")); err != nil {
+		t.Errorf("new password hash does not match new password: %s", err)
+	}
+	if userID, err := sessionUserID(auth.Token); err != nil || userID != 1 {
+		t.Errorf("got token for user %d (error %v), want user 1", userID, err)
+	}
+
+	// Reset tokens may only be used once.
+	if _, err := apiClient.Users.ResetPassword(&thesrc.PasswordReset{Token: token, NewPassword: "password3"}); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v reusing token, want HTTP %d", err, http.StatusBadRequest)
+	}
+}
+
+func TestPasswordReset_invalidToken(t *testing.T) {
+	setup()
+
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return &thesrc.User{ID: id, PasswordHash: []byte("h")}, nil
+	}
+
+	for _, token := range []string{"", "x.y", newAuthToken(1)} {
+		if _, err := apiClient.Users.ResetPassword(&thesrc.PasswordReset{Token: token, NewPassword: "password2"}); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+			t.Errorf("%q: got error %v, want HTTP %d", token, err, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// recordRevision records the revision that r's authenticated user made by
+// editing a post from old to updated. The first time a post is edited, its
+// original version is recorded too, so that every edit can be diffed
+// against the version before it.
+func recordRevision(r *http.Request, old, updated *thesrc.Post) error {
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := store(r).Posts.Revisions(old.ID)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		original := &thesrc.PostRevision{
+			PostID:       old.ID,
+			Title:        old.Title,
+			LinkURL:      old.LinkURL,
+			Body:         old.Body,
+			EditorUserID: old.AuthorUserID,
+			CreatedAt:    old.SubmittedAt,
+		}
+		if err := store(r).PostRevisions.Create(original); err != nil {
+			return err
+		}
+	}
+
+	editedAt := time.Now()
+	if updated.EditedAt != nil {
+		editedAt = *updated.EditedAt
+	}
+	return store(r).PostRevisions.Create(&thesrc.PostRevision{
+		PostID:       old.ID,
+		Title:        updated.Title,
+		LinkURL:      updated.LinkURL,
+		Body:         updated.Body,
+		EditorUserID: userID,
+		CreatedAt:    editedAt,
+	})
+}
+
+func servePostRevisions(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if _, err := store(r).Posts.Get(id); err != nil {
+		return err
+	}
+	revisions, err := store(r).Posts.Revisions(id)
+	if err != nil {
+		return err
+	}
+	if revisions == nil {
+		revisions = []*thesrc.PostRevision{}
+	}
+
+	logins := map[int]string{}
+	for _, rev := range revisions {
+		if rev.EditorUserID == 0 {
+			continue
+		}
+		if _, present := logins[rev.EditorUserID]; !present {
+			user, err := store(r).Users.Get(rev.EditorUserID)
+			if err != nil && err != thesrc.ErrUserNotFound {
+				return err
+			}
+			if user != nil {
+				logins[rev.EditorUserID] = user.Login
+			}
+		}
+		rev.EditorLogin = logins[rev.EditorUserID]
+	}
+
+	return writeJSON(w, r, revisions)
+}
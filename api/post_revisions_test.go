@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestPost_Update_recordsRevisions(t *testing.T) {
+	setup()
+
+	mockAdmin(3)
+	submittedAt := time.Now().Add(-time.Hour)
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, Title: "t", Body: "b", AuthorUserID: 1, SubmittedAt: submittedAt}, nil
+	}
+	var revisions []*thesrc.PostRevision
+	Store.Posts.(*thesrc.MockPostsService).Revisions_ = func(id int) ([]*thesrc.PostRevision, error) {
+		return revisions, nil
+	}
+	Store.PostRevisions.(*datastore.MockPostRevisionsStore).Create_ = func(rev *thesrc.PostRevision) error {
+		revisions = append(revisions, rev)
+		return nil
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(3)).Posts.Update(2, &thesrc.Post{Title: "t2", Body: "b", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	// The original version is recorded before the first edit.
+	if len(revisions) != 2 {
+		t.Fatalf("got revisions %+v, want the original and the edit", revisions)
+	}
+	if rev := revisions[0]; rev.PostID != 2 || rev.Title != "t" || rev.EditorUserID != 1 || !rev.CreatedAt.Equal(submittedAt) {
+		t.Errorf("got original revision %+v, want title t by the author when submitted", rev)
+	}
+	if rev := revisions[1]; rev.PostID != 2 || rev.Title != "t2" || rev.EditorUserID != 3 {
+		t.Errorf("got edit revision %+v, want title t2 by user 3", rev)
+	}
+
+	// Later edits only record the new version, and changing only tags
+	// doesn't record one.
+	if err := apiClient.WithAuthToken(newAuthToken(3)).Posts.Update(2, &thesrc.Post{Title: "t3", Body: "b", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiClient.WithAuthToken(newAuthToken(3)).Posts.Update(2, &thesrc.Post{Title: "t", Body: "b", Tags: []string{"go"}, Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 {
+		t.Errorf("got %d revisions, want 3", len(revisions))
+	}
+}
+
+func TestPost_Revisions(t *testing.T) {
+	setup()
+
+	mockModerator(1)
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id}, nil
+	}
+	Store.Posts.(*thesrc.MockPostsService).Revisions_ = func(id int) ([]*thesrc.PostRevision, error) {
+		return []*thesrc.PostRevision{{ID: 1, PostID: id, Title: "t", EditorUserID: 2}}, nil
+	}
+
+	if _, err := apiClient.WithAuthToken(newAuthToken(2)).Posts.Revisions(3); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v for non-moderator, want HTTP %d", err, http.StatusForbidden)
+	}
+
+	revisions, err := apiClient.WithAuthToken(newAuthToken(1)).Posts.Revisions(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 || revisions[0].PostID != 3 || revisions[0].Title != "t" {
+		t.Errorf("got revisions %+v, want 1 of post 3", revisions)
+	}
+}
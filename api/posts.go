@@ -3,66 +3,296 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/classifier"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/spam"
 )
 
+// SpamFilter (if set) scores submitted posts. Posts that it considers spam
+// are hidden and held in the moderation queue.
+var SpamFilter *spam.Filter
+
+// TopicClassifier (if set) tags submitted posts with the topics (such as
+// programming languages) that they're about, in addition to the tags that
+// their submitters gave them.
+var TopicClassifier *classifier.TopicClassifier
+
 func servePost(w http.ResponseWriter, r *http.Request) error {
 	id, err := strconv.Atoi(mux.Vars(r)["ID"])
 	if err != nil {
 		return err
 	}
 
-	post, err := store.Posts.Get(id)
+	post, err := getPost(r, id)
 	if err != nil {
 		return err
 	}
+	if renderBody(r) {
+		renderPostBodies(post)
+	}
+
+	setVersionHeader(w, post.Version)
+	return writeJSON(w, r, post)
+}
+
+// setVersionHeader sets the post's version in the PostVersionHeader header,
+// so that clients can send it back when they update the post (see
+// requestedVersion). It isn't sent as an ETag, because the post's JSON
+// (including its score and other counts) changes without its version
+// changing.
+func setVersionHeader(w http.ResponseWriter, version int) {
+	if version != 0 {
+		w.Header().Set(thesrc.PostVersionHeader, strconv.Itoa(version))
+	}
+}
 
-	return writeJSON(w, post)
+// requestedVersion returns the version of the post that an update (in r)
+// is based on: the version in r's PostVersionHeader header, if any, or else
+// version (from the request body).
+func requestedVersion(r *http.Request, version int) (int, error) {
+	hdr := r.Header.Get(thesrc.PostVersionHeader)
+	if hdr == "" {
+		return version, nil
+	}
+	v, err := strconv.Atoi(hdr)
+	if err != nil || v <= 0 {
+		return 0, &httpError{http.StatusBadRequest, fmt.Errorf("invalid %s header %q (it must be a positive integer)", thesrc.PostVersionHeader, hdr)}
+	}
+	return v, nil
+}
+
+// getPost gets a post as seen by r's authenticated user. Dead posts are only
+// visible to moderators.
+func getPost(r *http.Request, id int) (*thesrc.Post, error) {
+	post, err := store(r).Posts.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if post.Dead {
+		if ok, err := hasRole(r, thesrc.RoleModerator); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, &httpError{http.StatusNotFound, thesrc.ErrPostNotFound}
+		}
+	}
+	if err := markVoted(r, post); err != nil {
+		return nil, err
+	}
+	if err := markSaved(r, post); err != nil {
+		return nil, err
+	}
+	if err := markHiddenByUser(r, post); err != nil {
+		return nil, err
+	}
+	return post, nil
 }
 
 func serveSubmitPost(w http.ResponseWriter, r *http.Request) error {
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		return err
+	}
+
 	var post thesrc.Post
-	err := json.NewDecoder(r.Body).Decode(&post)
+	err = json.NewDecoder(r.Body).Decode(&post)
+	if err != nil {
+		return err
+	}
+	post.IdempotencyKey = r.Header.Get(thesrc.IdempotencyKeyHeader)
+	if len(post.IdempotencyKey) > thesrc.MaxIdempotencyKeyLength {
+		return &httpError{http.StatusBadRequest, fmt.Errorf("%s header is longer than %d bytes", thesrc.IdempotencyKeyHeader, thesrc.MaxIdempotencyKeyLength)}
+	}
+
+	created, err := submitPost(r, &post, userID)
+	if err != nil {
+		return err
+	}
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	return writeJSON(w, r, post)
+}
+
+func servePreviewPost(w http.ResponseWriter, r *http.Request) error {
+	userID, err := authenticatedUserID(r)
 	if err != nil {
 		return err
 	}
 
+	var post thesrc.Post
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		return &httpError{http.StatusBadRequest, err}
+	}
+
+	if err := preparePost(r, &post, userID); err != nil {
+		return err
+	}
+	renderPostBodies(&post)
+
+	preview := &thesrc.PostPreview{Post: &post}
 	if post.LinkURL != "" {
-		linkURL, err := url.Parse(post.LinkURL)
+		existing, err := store(r).Posts.List(&thesrc.PostListOptions{LinkURL: post.LinkURL, ListOptions: thesrc.ListOptions{PerPage: 1}})
 		if err != nil {
 			return err
 		}
-		if linkURL.Scheme != "http" && linkURL.Scheme != "https" {
-			return errors.New("link URL scheme must be http or https")
+		if len(existing) > 0 {
+			preview.Existing = existing[0]
+		}
+	}
+
+	return writeJSON(w, r, preview)
+}
+
+// submitPost submits post (in r) on behalf of the user with ID userID (see
+// thesrc.PostsService.Submit).
+func submitPost(r *http.Request, post *thesrc.Post, userID int) (created bool, err error) {
+	if err := prepareSubmittedPost(r, post, userID); err != nil {
+		return false, err
+	}
+
+	created, err = store(r).Posts.Submit(post)
+	if err != nil {
+		return false, err
+	}
+	if created {
+		postListCache.invalidate()
+		logNotifyError(r, notifyPost(r, post))
+		queueUnfurl(r, post)
+		recordClient(r, thesrc.ClientActionSubmit, userID, post.ID)
+	}
+	return created, nil
+}
+
+func serveCreatePostBatch(w http.ResponseWriter, r *http.Request) error {
+	userID, err := authenticatedUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var posts []*thesrc.Post
+	if err := json.NewDecoder(r.Body).Decode(&posts); err != nil {
+		return &httpError{http.StatusBadRequest, err}
+	}
+	if len(posts) > thesrc.MaxBatchSize {
+		return &httpError{http.StatusBadRequest, fmt.Errorf("batch of %d posts exceeds maximum of %d", len(posts), thesrc.MaxBatchSize)}
+	}
+
+	// Reject invalid posts individually, and submit the rest.
+	results := make([]*thesrc.PostBatchResult, len(posts))
+	var valid []*thesrc.Post
+	var validIdx []int
+	for i, post := range posts {
+		if post == nil {
+			results[i] = &thesrc.PostBatchResult{Error: "missing post"}
+			continue
+		}
+		if err := prepareSubmittedPost(r, post, userID); err != nil {
+			results[i] = &thesrc.PostBatchResult{Error: err.Error()}
+			continue
 		}
-		if host, port, err := net.SplitHostPort(linkURL.Host); err != nil {
-			if !strings.HasPrefix(err.Error(), "missing port") {
-				return err
+		valid = append(valid, post)
+		validIdx = append(validIdx, i)
+	}
+
+	if len(valid) > 0 {
+		submitted, err := store(r).Posts.CreateBatch(valid)
+		if err != nil {
+			return err
+		}
+		var created bool
+		for j, res := range submitted {
+			results[validIdx[j]] = res
+			created = created || res.Created
+			if res.Created {
+				queueUnfurl(r, res.Post)
+				recordClient(r, thesrc.ClientActionSubmit, userID, res.Post.ID)
 			}
-		} else if port != "" {
-			return errors.New("non-standard link URL port is not allowed")
-		} else if !strings.Contains(host, ".") {
-			return errors.New("invalid hostname (must contain dot)")
 		}
-		// TODO(sqs): check for IP addresses or localhost aliases
+		if created {
+			postListCache.invalidate()
+		}
 	}
 
-	created, err := store.Posts.Submit(&post)
-	if err != nil {
+	return writeJSON(w, r, results)
+}
+
+// prepareSubmittedPost validates a post submitted (in r) by the user with ID
+// userID and fills it in (see preparePost), and tags it with its topics (see
+// TopicClassifier). If the spam filter considers it spam, it is hidden and
+// held for moderation.
+func prepareSubmittedPost(r *http.Request, post *thesrc.Post, userID int) error {
+	if err := preparePost(r, post, userID); err != nil {
 		return err
 	}
-	if created {
-		w.WriteHeader(http.StatusCreated)
+
+	if TopicClassifier != nil {
+		TopicClassifier.Tag(post)
+	}
+
+	if SpamFilter != nil {
+		res := SpamFilter.Check(&spam.Submission{Post: post, UserIP: endUserIP(r), UserAgent: r.UserAgent()})
+		if res.Spam {
+			logging.FromContext(r.Context()).Printf("Holding post with URL %q for moderation (spam score %.2f): %s", post.LinkURL, res.Score, strings.Join(res.Reasons, "; "))
+			post.Hidden = true
+			post.SpamScore = res.Score
+		}
+	}
+	return nil
+}
+
+// preparePost validates a post (in r) by the user with ID userID and fills
+// in its author, canonical link URL, normalized tags, and (if it has no
+// title) its link metadata.
+func preparePost(r *http.Request, post *thesrc.Post, userID int) error {
+	post.AuthorUserID = userID
+
+	// Only moderators (and the spam filter) may set a post's moderation
+	// status.
+	post.Flags, post.Reports, post.Hidden, post.Dead, post.SpamScore, post.Queued = 0, 0, false, false, 0, false
+
+	// Only the dead link checker may set a post's link status.
+	post.LinkStatus, post.LinkDead, post.LinkArchiveURL = 0, false, ""
+
+	var err error
+	post.Tags, err = thesrc.NormalizeTags(post.Tags)
+	if err != nil {
+		return invalidField("Tags", err)
+	}
+
+	if post.LinkURL == "" {
+		// A self-post's body is its content.
+		if strings.TrimSpace(post.Body) == "" {
+			return invalidField("Body", errors.New("body is required for posts without a link URL"))
+		}
+	} else {
+		post.LinkURL = thesrc.CanonicalLinkURL(post.LinkURL)
+		if err := checkLinkURL(post.LinkURL); err != nil {
+			return err
+		}
+		if post.Title == "" {
+			if meta, err := unfurlLink(post.LinkURL); err != nil {
+				logging.FromContext(r.Context()).Printf("Unfurling link %q: %s", post.LinkURL, err)
+			} else {
+				post.Title = meta.Title
+				post.LinkDescription = meta.Description
+				post.LinkImageURL = meta.ImageURL
+				post.LinkFaviconURL = meta.FaviconURL
+			}
+		}
 	}
 
-	return writeJSON(w, post)
+	return nil
 }
 
 func servePosts(w http.ResponseWriter, r *http.Request) error {
@@ -71,13 +301,339 @@ func servePosts(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	posts, err := store.Posts.List(&opt)
+	posts, err := listPosts(r, &opt)
+	if err != nil {
+		return err
+	}
+	if opt.RenderBody {
+		renderPostBodies(posts...)
+	}
+	if posts == nil {
+		posts = []*thesrc.Post{}
+	}
+
+	// Lists that can be paginated with cursors include the next page's
+	// cursor, so that clients needn't compute it (see
+	// thesrc.PostCursorAfter).
+	var next string
+	if len(posts) >= opt.PerPageOrDefault() {
+		next = thesrc.PostCursorAfter(opt.Sort, posts[len(posts)-1])
+	}
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+	if opt.After != "" {
+		writeCursorLink(w, r, next)
+	} else {
+		writePaginationLinks(w, r, opt.ListOptions, len(posts))
+	}
+	return writeCacheableJSON(w, r, posts, PostListCacheTTL)
+}
+
+// listPosts lists posts as seen by r's authenticated user.
+func listPosts(r *http.Request, opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+	if !thesrc.ValidSort(opt.Sort) {
+		return nil, invalidField("Sort", fmt.Errorf("invalid sort order %q", opt.Sort))
+	}
+	if !thesrc.ValidPeriod(opt.Period) {
+		return nil, invalidField("Period", fmt.Errorf("invalid period %q", opt.Period))
+	}
+	if _, err := opt.Cursor(); err != nil {
+		return nil, invalidField("After", err)
+	}
+	if opt.Tag != "" {
+		tag, err := thesrc.NormalizeTag(opt.Tag)
+		if err != nil {
+			return nil, invalidField("Tag", err)
+		}
+		opt.Tag = tag
+	}
+	if opt.Query != "" {
+		if found, err := applyPostQuery(r, opt); err != nil {
+			return nil, err
+		} else if !found {
+			return nil, nil
+		}
+	}
+	if opt.Saved {
+		userID, err := requireUserID(r)
+		if err != nil {
+			return nil, err
+		}
+		opt.SavedByUserID = userID
+	}
+	if opt.Deleted {
+		if err := checkRole(r, thesrc.RoleAdmin); err != nil {
+			return nil, err
+		}
+	} else if opt.Flagged {
+		if err := checkRole(r, thesrc.RoleModerator); err != nil {
+			return nil, err
+		}
+	} else {
+		user, err := authenticatedUser(r)
+		if err != nil {
+			return nil, err
+		}
+		if user != nil {
+			// Shadow-banned users see their own posts.
+			if user.ShadowBanned {
+				opt.ViewerUserID = user.ID
+			}
+			// Users don't see the posts they've hidden (except among their
+			// saved posts).
+			if !opt.Saved {
+				opt.ExcludeHiddenByUserID = user.ID
+			}
+			if opt.Personalized {
+				opt.PersonalizedForUserID = user.ID
+			}
+		}
+	}
+
+	var posts []*thesrc.Post
+	var err error
+	if opt.SavedByUserID != 0 || opt.ExcludeHiddenByUserID != 0 || opt.PersonalizedForUserID != 0 {
+		// Lists specific to a user aren't cached.
+		posts, err = store(r).Posts.List(opt)
+	} else {
+		posts, err = postListCache.list(r, opt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := markVoted(r, posts...); err != nil {
+		return nil, err
+	}
+	if err := markSaved(r, posts...); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// applyPostQuery parses opt.Query and sets the filters in opt that it
+// specifies. If it can't match any posts (because its author doesn't exist),
+// found is false.
+func applyPostQuery(r *http.Request, opt *thesrc.PostListOptions) (found bool, err error) {
+	q, err := thesrc.ParsePostQuery(opt.Query)
+	if err != nil {
+		return false, invalidField("Query", err)
+	}
+
+	// Qualifiers may repeat, but not contradict, the other filters.
+	setFilter := func(field *string, value, name string) error {
+		if value == "" {
+			return nil
+		}
+		if *field != "" && *field != value {
+			return invalidField("Query", fmt.Errorf("%s: conflicts with the %s filter", strings.ToLower(name), name))
+		}
+		*field = value
+		return nil
+	}
+	if err := setFilter(&opt.Tag, q.Tag, "Tag"); err != nil {
+		return false, err
+	}
+	opt.Domain = thesrc.NormalizeDomain(opt.Domain)
+	if err := setFilter(&opt.Domain, q.Domain, "Domain"); err != nil {
+		return false, err
+	}
+	if q.Author != "" {
+		author, err := store(r).Users.GetByLogin(q.Author)
+		if err == thesrc.ErrUserNotFound {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if opt.AuthorUserID != 0 && opt.AuthorUserID != author.ID {
+			return false, invalidField("Query", errors.New("author: conflicts with the AuthorUserID filter"))
+		}
+		opt.AuthorUserID = author.ID
+	}
+	opt.SearchTerms = q.Terms
+	return true, nil
+}
+
+// editablePost gets the post identified by r's ID route variable and returns
+// an error unless r's authenticated user may edit it.
+func editablePost(r *http.Request) (*thesrc.Post, error) {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := store(r).Users.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	post, err := store(r).Posts.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !thesrc.CanEditPost(user, post, time.Now()) {
+		return nil, &httpError{http.StatusForbidden, errors.New("only the post's author (within the edit window) or an admin may edit or delete it")}
+	}
+	return post, nil
+}
+
+func serveRelatedPosts(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	var opt thesrc.RelatedPostsOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+
+	// Dead posts' related posts are only visible to those who can see the
+	// posts themselves.
+	if _, err := getPost(r, id); err != nil {
+		return err
+	}
+
+	user, err := authenticatedUser(r)
 	if err != nil {
 		return err
 	}
+	if user != nil && user.ShadowBanned {
+		// Shadow-banned users see their own posts.
+		opt.ViewerUserID = user.ID
+	}
+
+	posts, err := store(r).Posts.Related(id, &opt)
+	if err != nil {
+		return err
+	}
+	if err := markVoted(r, posts...); err != nil {
+		return err
+	}
+	if err := markSaved(r, posts...); err != nil {
+		return err
+	}
 	if posts == nil {
 		posts = []*thesrc.Post{}
 	}
 
-	return writeJSON(w, posts)
+	return writeJSON(w, r, posts)
+}
+
+func serveUpdatePost(w http.ResponseWriter, r *http.Request) error {
+	post, err := editablePost(r)
+	if err != nil {
+		return err
+	}
+
+	var update thesrc.Post
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		return err
+	}
+	if update.Tags, err = thesrc.NormalizeTags(update.Tags); err != nil {
+		return invalidField("Tags", err)
+	}
+	if post.LinkURL == "" && strings.TrimSpace(update.Body) == "" {
+		return invalidField("Body", errors.New("body is required for posts without a link URL"))
+	}
+	if update.Version, err = requestedVersion(r, update.Version); err != nil {
+		return err
+	}
+	if update.Version == 0 {
+		return &httpError{http.StatusPreconditionRequired, errors.New("the version of the post being edited is required (in the " + thesrc.PostVersionHeader + " header or the Version field), so that concurrent edits aren't overwritten")}
+	}
+
+	if err := store(r).Posts.Update(post.ID, &update); err != nil {
+		return err
+	}
+	postListCache.invalidate()
+
+	if update.Title != post.Title || update.Body != post.Body || update.LinkURL != post.LinkURL {
+		if err := recordRevision(r, post, &update); err != nil {
+			return err
+		}
+	}
+
+	setVersionHeader(w, update.Version)
+	return writeJSON(w, r, update)
+}
+
+func serveDeletePost(w http.ResponseWriter, r *http.Request) error {
+	post, err := editablePost(r)
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Posts.Delete(post.ID); err != nil {
+		return err
+	}
+	postListCache.invalidate()
+
+	// Authors deleting their own posts isn't a privileged action.
+	if userID, _ := authenticatedUserID(r); userID != post.AuthorUserID {
+		if err := audit(r, thesrc.AuditDeletePost, auditTarget("post", post.ID), post.Title); err != nil {
+			return err
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveUndeletePost(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Posts.Undelete(id); err != nil {
+		switch err {
+		case thesrc.ErrPostNotFound:
+			return &httpError{http.StatusNotFound, err}
+		case thesrc.ErrPostLinkURLTaken:
+			return &httpError{http.StatusConflict, err}
+		}
+		return err
+	}
+	postListCache.invalidate()
+
+	post, err := store(r).Posts.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := audit(r, thesrc.AuditUndeletePost, auditTarget("post", id), post.Title); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// checkLinkURL returns an error if linkURL isn't an http or https URL to a
+// public hostname on the default port.
+func checkLinkURL(linkURL string) error {
+	u, err := url.Parse(linkURL)
+	if err != nil {
+		return invalidField("LinkURL", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return invalidField("LinkURL", errors.New("link URL scheme must be http or https"))
+	}
+	host := u.Host
+	if h, port, err := net.SplitHostPort(u.Host); err != nil {
+		if !strings.Contains(err.Error(), "missing port") {
+			return invalidField("LinkURL", err)
+		}
+	} else if port != "" {
+		return invalidField("LinkURL", errors.New("non-standard link URL port is not allowed"))
+	} else {
+		host = h
+	}
+	if !strings.Contains(host, ".") {
+		return invalidField("LinkURL", errors.New("invalid hostname (must contain dot)"))
+	}
+	return nil
 }
@@ -1,9 +1,18 @@
 package api
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/classifier"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/spam"
 )
 
 func TestPost(t *testing.T) {
@@ -12,7 +21,7 @@ func TestPost(t *testing.T) {
 	wantPost := &thesrc.Post{ID: 1}
 
 	calledGet := false
-	store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
 		if id != wantPost.ID {
 			t.Errorf("wanted request for post %d but got %d", wantPost.ID, id)
 		}
@@ -36,10 +45,10 @@ func TestPost(t *testing.T) {
 func TestPost_Submit(t *testing.T) {
 	setup()
 
-	wantPost := &thesrc.Post{ID: 1}
+	wantPost := &thesrc.Post{ID: 1, Body: "b"}
 
 	calledPost := false
-	store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+	Store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
 		if !normalizeDeepEqual(wantPost, post) {
 			t.Errorf("wanted request for post %+v but got %+v", wantPost, post)
 		}
@@ -60,6 +69,92 @@ func TestPost_Submit(t *testing.T) {
 	}
 }
 
+func TestPost_Submit_selfPost(t *testing.T) {
+	setup()
+
+	var submitted *thesrc.Post
+	Store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		submitted = post
+		return true, nil
+	}
+
+	_, err := apiClient.Posts.Submit(&thesrc.Post{Title: "Ask: how do I test Go?"})
+	if e, ok := err.(*thesrc.ErrorResponse); !ok || e.Code != thesrc.ErrCodeInvalid || len(e.Fields) != 1 || e.Fields[0].Field != "Body" {
+		t.Errorf("got error %#v, want invalid Body field", err)
+	}
+	if submitted != nil {
+		t.Error("submitted self-post without a body")
+	}
+
+	if _, err := apiClient.Posts.Submit(&thesrc.Post{Title: "Ask: how do I test Go?", Body: "With go test."}); err != nil {
+		t.Fatal(err)
+	}
+	if submitted == nil {
+		t.Error("self-post with a body was not submitted")
+	}
+}
+
+func TestPost_Submit_idempotencyKey(t *testing.T) {
+	setup()
+
+	var key string
+	Store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		key = post.IdempotencyKey
+		return true, nil
+	}
+
+	if _, err := apiClient.Posts.Submit(&thesrc.Post{Title: "t", Body: "b", IdempotencyKey: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	if key != "k" {
+		t.Errorf("got idempotency key %q, want %q", key, "k")
+	}
+
+	key = ""
+	long := strings.Repeat("k", thesrc.MaxIdempotencyKeyLength+1)
+	if _, err := apiClient.Posts.Submit(&thesrc.Post{Title: "t", Body: "b", IdempotencyKey: long}); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v for a too-long key, want HTTP %d", err, http.StatusBadRequest)
+	}
+	if key != "" {
+		t.Error("submitted post with a too-long idempotency key")
+	}
+}
+
+func TestPost_Preview(t *testing.T) {
+	setup()
+
+	existing := &thesrc.Post{ID: 7, Title: "t", LinkURL: "http://example.com/a"}
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		if opt.LinkURL != existing.LinkURL {
+			t.Errorf("got LinkURL filter %q, want %q", opt.LinkURL, existing.LinkURL)
+		}
+		return []*thesrc.Post{existing}, nil
+	}
+	Store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		t.Error("preview submitted the post")
+		return false, nil
+	}
+
+	preview, err := apiClient.Posts.Preview(&thesrc.Post{Title: "t", LinkURL: "HTTP://Example.com:80/a?utm_source=x#top", Body: "*b*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Post.LinkURL != existing.LinkURL {
+		t.Errorf("got LinkURL %q, want canonical %q", preview.Post.LinkURL, existing.LinkURL)
+	}
+	if want := "<p><em>b</em></p>\n"; preview.Post.BodyHTML != want {
+		t.Errorf("got BodyHTML %q, want %q", preview.Post.BodyHTML, want)
+	}
+	if preview.Existing == nil || preview.Existing.ID != existing.ID {
+		t.Errorf("got Existing %+v, want post %d", preview.Existing, existing.ID)
+	}
+
+	_, err = apiClient.Posts.Preview(&thesrc.Post{Title: "t"})
+	if e, ok := err.(*thesrc.ErrorResponse); !ok || e.Code != thesrc.ErrCodeInvalid || len(e.Fields) != 1 || e.Fields[0].Field != "Body" {
+		t.Errorf("got error %#v, want invalid Body field", err)
+	}
+}
+
 func TestPosts_List(t *testing.T) {
 	setup()
 
@@ -67,7 +162,7 @@ func TestPosts_List(t *testing.T) {
 	wantOpt := &thesrc.PostListOptions{ListOptions: thesrc.ListOptions{Page: 1, PerPage: 10}}
 
 	calledList := false
-	store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
 		if !normalizeDeepEqual(wantOpt, opt) {
 			t.Errorf("wanted list options %+v but got %+v", wantOpt, opt)
 		}
@@ -88,3 +183,540 @@ func TestPosts_List(t *testing.T) {
 		t.Errorf("got post %+v but wanted post %+v", posts, wantPosts)
 	}
 }
+
+func TestPosts_List_shadowBanned(t *testing.T) {
+	setup()
+
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return &thesrc.User{ID: id, ShadowBanned: id == 1}, nil
+	}
+	var viewerUserID int
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		viewerUserID = opt.ViewerUserID
+		return nil, nil
+	}
+
+	// ViewerUserID is only set for shadow-banned users (so that other users
+	// share cached lists).
+	tests := map[int]int{0: 0, 1: 1, 2: 0}
+	for userID, want := range tests {
+		client := apiClient
+		if userID != 0 {
+			client = apiClient.WithAuthToken(newAuthToken(userID))
+		}
+		// Use a different page each time, so that results aren't cached.
+		if _, err := client.Posts.List(&thesrc.PostListOptions{ListOptions: thesrc.ListOptions{Page: userID + 1}}); err != nil {
+			t.Fatal(err)
+		}
+		if viewerUserID != want {
+			t.Errorf("user %d: got ViewerUserID %d, want %d", userID, viewerUserID, want)
+		}
+	}
+}
+
+func TestPosts_List_renderBody(t *testing.T) {
+	setup()
+
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		return []*thesrc.Post{{ID: 1, Body: "*b*"}}, nil
+	}
+
+	posts, err := apiClient.Posts.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posts[0].BodyHTML != "" {
+		t.Errorf("got BodyHTML %q without RenderBody, want empty", posts[0].BodyHTML)
+	}
+
+	posts, err = apiClient.Posts.List(&thesrc.PostListOptions{RenderBody: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<p><em>b</em></p>\n"; posts[0].BodyHTML != want {
+		t.Errorf("got BodyHTML %q, want %q", posts[0].BodyHTML, want)
+	}
+}
+
+func TestPosts_List_invalidSort(t *testing.T) {
+	setup()
+
+	_, err := apiClient.Posts.List(&thesrc.PostListOptions{Sort: "bogus"})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v, want HTTP %d", err, http.StatusBadRequest)
+	}
+}
+
+func TestPosts_List_invalidPeriod(t *testing.T) {
+	setup()
+
+	_, err := apiClient.Posts.List(&thesrc.PostListOptions{Period: "decade"})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v, want HTTP %d", err, http.StatusBadRequest)
+	}
+}
+
+func TestPosts_List_query(t *testing.T) {
+	setup()
+
+	Store.Users.(*datastore.MockUsersStore).GetByLogin_ = func(login string) (*thesrc.User, error) {
+		if login != "alice" {
+			return nil, thesrc.ErrUserNotFound
+		}
+		return &thesrc.User{ID: 2, Login: "alice"}, nil
+	}
+	var gotOpt *thesrc.PostListOptions
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		gotOpt = opt
+		return []*thesrc.Post{{ID: 1}}, nil
+	}
+
+	if _, err := apiClient.Posts.List(&thesrc.PostListOptions{Query: `author:alice domain:Golang.org tag:Go rust "error handling"`}); err != nil {
+		t.Fatal(err)
+	}
+	if gotOpt.AuthorUserID != 2 || gotOpt.Domain != "golang.org" || gotOpt.Tag != "go" || !reflect.DeepEqual(gotOpt.SearchTerms, []string{"rust", "error handling"}) {
+		t.Errorf("got list options %+v, want the query's filters", gotOpt)
+	}
+
+	// A nonexistent author matches no posts.
+	gotOpt = nil
+	posts, err := apiClient.Posts.List(&thesrc.PostListOptions{Query: "author:bob rust"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 0 || gotOpt != nil {
+		t.Errorf("got posts %+v (listed with %+v), want none", posts, gotOpt)
+	}
+
+	for _, opt := range []*thesrc.PostListOptions{{Query: "tag:go tag:rust"}, {Query: "tag:go", Tag: "rust"}} {
+		_, err := apiClient.Posts.List(opt)
+		if e, ok := err.(*thesrc.ErrorResponse); !ok || e.Code != thesrc.ErrCodeInvalid || len(e.Fields) != 1 || e.Fields[0].Field != "Query" {
+			t.Errorf("%+v: got error %#v, want invalid Query field", opt, err)
+		}
+	}
+}
+
+func TestPosts_Related(t *testing.T) {
+	setup()
+
+	wantPosts := []*thesrc.Post{{ID: 2}}
+	wantOpt := &thesrc.RelatedPostsOptions{ListOptions: thesrc.ListOptions{PerPage: 5}}
+
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id}, nil
+	}
+	calledRelated := false
+	Store.Posts.(*thesrc.MockPostsService).Related_ = func(id int, opt *thesrc.RelatedPostsOptions) ([]*thesrc.Post, error) {
+		if id != 1 {
+			t.Errorf("wanted related posts of post 1 but got %d", id)
+		}
+		if !normalizeDeepEqual(wantOpt, opt) {
+			t.Errorf("wanted related posts options %+v but got %+v", wantOpt, opt)
+		}
+		calledRelated = true
+		return wantPosts, nil
+	}
+
+	posts, err := apiClient.Posts.Related(1, wantOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledRelated {
+		t.Error("!calledRelated")
+	}
+	if !normalizeDeepEqual(&wantPosts, &posts) {
+		t.Errorf("got posts %+v but wanted posts %+v", posts, wantPosts)
+	}
+
+	// Dead posts' related posts are hidden, like the posts themselves.
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, Dead: true}, nil
+	}
+	if _, err := apiClient.Posts.Related(1, nil); err == nil {
+		t.Error("got no error listing related posts of a dead post, want not found")
+	}
+}
+
+func TestPost_Submit_tags(t *testing.T) {
+	setup()
+
+	calledPost := false
+	Store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		if want := []string{"golang", "c++"}; !reflect.DeepEqual(post.Tags, want) {
+			t.Errorf("got tags %q, want normalized tags %q", post.Tags, want)
+		}
+		calledPost = true
+		return true, nil
+	}
+
+	if _, err := apiClient.Posts.Submit(&thesrc.Post{Body: "b", Tags: []string{"GoLang", "c++", "golang"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !calledPost {
+		t.Error("!calledPost")
+	}
+
+	_, err := apiClient.Posts.Submit(&thesrc.Post{Tags: []string{"not a tag"}})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v, want HTTP %d", err, http.StatusBadRequest)
+	}
+}
+
+func TestPost_Submit_spam(t *testing.T) {
+	setup()
+	SpamFilter = &spam.Filter{Checks: []spam.Check{&spam.Blocklist{Domains: []string{"spam.com"}}}}
+	defer func() { SpamFilter = nil }()
+
+	var submitted []*thesrc.Post
+	Store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		submitted = append(submitted, post)
+		return true, nil
+	}
+
+	for _, linkURL := range []string{"http://spam.com/a", "http://example.com/a"} {
+		if _, err := apiClient.Posts.Submit(&thesrc.Post{Title: "t", LinkURL: linkURL, Hidden: true, SpamScore: 5}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(submitted) != 2 {
+		t.Fatalf("got %d submitted posts, want 2", len(submitted))
+	}
+	if p := submitted[0]; !p.Hidden || p.SpamScore != 1 {
+		t.Errorf("got spam post %+v, want it hidden with spam score 1", p)
+	}
+	if p := submitted[1]; p.Hidden || p.SpamScore != 0 {
+		t.Errorf("got post %+v, want it visible with no spam score (moderation fields can't be set by submitters)", p)
+	}
+}
+
+func TestPost_Submit_topics(t *testing.T) {
+	setup()
+	TopicClassifier = &classifier.TopicClassifier{}
+	defer func() { TopicClassifier = nil }()
+
+	var submitted *thesrc.Post
+	Store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		submitted = post
+		return true, nil
+	}
+
+	if _, err := apiClient.Posts.Submit(&thesrc.Post{Title: "Faster SQL queries in Go", LinkURL: "http://example.com/a", Tags: []string{"performance"}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"performance", "golang", "databases"}; !reflect.DeepEqual(submitted.Tags, want) {
+		t.Errorf("got tags %q, want %q", submitted.Tags, want)
+	}
+}
+
+func TestPosts_CreateBatch(t *testing.T) {
+	setup()
+
+	calledCreateBatch := false
+	Store.Posts.(*thesrc.MockPostsService).CreateBatch_ = func(posts []*thesrc.Post) ([]*thesrc.PostBatchResult, error) {
+		if len(posts) != 2 {
+			t.Fatalf("got %d posts, want the 2 valid posts", len(posts))
+		}
+		if want := []string{"golang"}; !reflect.DeepEqual(posts[1].Tags, want) {
+			t.Errorf("got tags %q, want normalized tags %q", posts[1].Tags, want)
+		}
+		calledCreateBatch = true
+		return []*thesrc.PostBatchResult{
+			{Post: &thesrc.Post{ID: 1, Title: "a"}, Created: true},
+			{Post: &thesrc.Post{ID: 2, Title: "c"}},
+		}, nil
+	}
+
+	results, err := apiClient.Posts.CreateBatch([]*thesrc.Post{
+		{Title: "a", Body: "a"},
+		{Title: "b", Body: "b", Tags: []string{"not a tag"}},
+		{Title: "c", Body: "c", Tags: []string{"GoLang"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !calledCreateBatch {
+		t.Error("!calledCreateBatch")
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if res := results[0]; res.Post == nil || res.Post.ID != 1 || !res.Created {
+		t.Errorf("got result %+v, want created post 1", res)
+	}
+	if res := results[1]; res.Post != nil || res.Error == "" {
+		t.Errorf("got result %+v, want error", res)
+	}
+	if res := results[2]; res.Post == nil || res.Post.ID != 2 || res.Created {
+		t.Errorf("got result %+v, want existing post 2", res)
+	}
+}
+
+func TestPosts_CreateBatch_tooLarge(t *testing.T) {
+	setup()
+
+	posts := make([]*thesrc.Post, thesrc.MaxBatchSize+1)
+	for i := range posts {
+		posts[i] = &thesrc.Post{}
+	}
+	_, err := apiClient.Posts.CreateBatch(posts)
+	if !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v, want HTTP %d", err, http.StatusBadRequest)
+	}
+}
+
+func TestPosts_List_paginationLinks(t *testing.T) {
+	setup()
+
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		return []*thesrc.Post{{ID: 1}, {ID: 2}}, nil
+	}
+
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"/api/posts?PerPage=2", `</api/posts?Page=2&PerPage=2>; rel="next"`},
+		{"/api/posts?PerPage=2&Page=3", `</api/posts?Page=4&PerPage=2>; rel="next", </api/posts?Page=2&PerPage=2>; rel="prev"`},
+		{"/api/posts?PerPage=5&Page=2", `</api/posts?Page=1&PerPage=5>; rel="prev"`},
+		{"/api/posts?PerPage=5", ""},
+	}
+	for _, test := range tests {
+		rw := httptest.NewRecorder()
+		serveMux.ServeHTTP(rw, httptest.NewRequest("GET", test.uri, nil))
+		if got := rw.Header().Get("Link"); got != test.want {
+			t.Errorf("%s: got Link %q, want %q", test.uri, got, test.want)
+		}
+	}
+}
+
+func TestPosts_List_after(t *testing.T) {
+	setup()
+
+	submittedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var gotOpt *thesrc.PostListOptions
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		gotOpt = opt
+		return []*thesrc.Post{{ID: 3, SubmittedAt: submittedAt.Add(time.Hour)}, {ID: 2, SubmittedAt: submittedAt}}, nil
+	}
+	next := thesrc.PostCursorAfter(thesrc.SortNew, &thesrc.Post{ID: 2, SubmittedAt: submittedAt})
+
+	rw := httptest.NewRecorder()
+	serveMux.ServeHTTP(rw, httptest.NewRequest("GET", "/api/posts?PerPage=2", nil))
+	if got := rw.Header().Get("X-Next-Cursor"); got != next {
+		t.Errorf("got X-Next-Cursor %q, want %q", got, next)
+	}
+
+	rw = httptest.NewRecorder()
+	serveMux.ServeHTTP(rw, httptest.NewRequest("GET", "/api/posts?PerPage=2&Page=5&After=abc", nil))
+	if want := http.StatusBadRequest; rw.Code != want {
+		t.Errorf("got HTTP status %d for invalid cursor, want %d", rw.Code, want)
+	}
+
+	rw = httptest.NewRecorder()
+	serveMux.ServeHTTP(rw, httptest.NewRequest("GET", "/api/posts?PerPage=2&After="+next, nil))
+	if gotOpt.After != next {
+		t.Errorf("got After %q, want %q", gotOpt.After, next)
+	}
+	if want := `</api/posts?After=` + next + `&PerPage=2>; rel="next"`; rw.Header().Get("Link") != want {
+		t.Errorf("got Link %q, want %q", rw.Header().Get("Link"), want)
+	}
+
+	// Top-ranked lists can't be paginated with cursors.
+	_, err := apiClient.Posts.List(&thesrc.PostListOptions{Sort: thesrc.SortTop, After: next})
+	if e, ok := err.(*thesrc.ErrorResponse); !ok || e.Code != thesrc.ErrCodeInvalid || len(e.Fields) != 1 || e.Fields[0].Field != "After" {
+		t.Errorf("got error %#v, want invalid After field", err)
+	}
+}
+
+func TestPost_Update(t *testing.T) {
+	setup()
+
+	users := map[int]*thesrc.User{1: {ID: 1}, 2: {ID: 2}, 3: {ID: 3, Role: thesrc.RoleAdmin}}
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return users[id], nil
+	}
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, AuthorUserID: 1, SubmittedAt: time.Now()}, nil
+	}
+	var updated bool
+	Store.Posts.(*thesrc.MockPostsService).Update_ = func(id int, post *thesrc.Post) error {
+		if want := []string{"golang"}; !reflect.DeepEqual(post.Tags, want) {
+			t.Errorf("got tags %q, want %q", post.Tags, want)
+		}
+		updated = true
+		return nil
+	}
+
+	tests := []struct {
+		userID     int
+		wantStatus int
+	}{
+		{0, http.StatusUnauthorized},
+		{2, http.StatusForbidden},
+		{1, 0},
+		{3, 0},
+	}
+	for _, test := range tests {
+		updated = false
+		c := apiClient
+		if test.userID != 0 {
+			c = apiClient.WithAuthToken(newAuthToken(test.userID))
+		}
+		err := c.Posts.Update(1, &thesrc.Post{Title: "t", Body: "b", Tags: []string{"Golang"}, Version: 1})
+		if test.wantStatus == 0 {
+			if err != nil {
+				t.Errorf("user %d: %s", test.userID, err)
+			}
+			if !updated {
+				t.Errorf("user %d: !updated", test.userID)
+			}
+		} else {
+			if !thesrc.IsHTTPErrorCode(err, test.wantStatus) {
+				t.Errorf("user %d: got error %v, want HTTP status %d", test.userID, err, test.wantStatus)
+			}
+			if updated {
+				t.Errorf("user %d: updated, but wanted no update", test.userID)
+			}
+		}
+	}
+}
+
+func TestPost_Update_version(t *testing.T) {
+	setup()
+
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return &thesrc.User{ID: id}, nil
+	}
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, AuthorUserID: 1, SubmittedAt: time.Now(), Version: 2}, nil
+	}
+	Store.Posts.(*thesrc.MockPostsService).Update_ = func(id int, post *thesrc.Post) error {
+		if post.Version != 2 {
+			return thesrc.ErrPostVersionConflict
+		}
+		post.Version++
+		return nil
+	}
+	c := apiClient.WithAuthToken(newAuthToken(1))
+
+	// The version is required.
+	err := c.Posts.Update(1, &thesrc.Post{Title: "t", Body: "b"})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusPreconditionRequired) {
+		t.Errorf("got error %v, want HTTP status %d", err, http.StatusPreconditionRequired)
+	}
+
+	// Updates based on a stale version fail.
+	err = c.Posts.Update(1, &thesrc.Post{Title: "t", Body: "b", Version: 1})
+	if !errors.Is(err, thesrc.ErrConflict) {
+		t.Errorf("got error %v, want a conflict", err)
+	}
+
+	post := &thesrc.Post{Title: "t", Body: "b", Version: 2}
+	if err := c.Posts.Update(1, post); err != nil {
+		t.Fatal(err)
+	}
+	if post.Version != 3 {
+		t.Errorf("got version %d, want 3", post.Version)
+	}
+
+	// The version can be given in the Post-Version header instead.
+	tests := []struct {
+		version     string
+		wantStatus  int
+		wantVersion string
+	}{
+		{"2", http.StatusOK, "3"},
+		{"1", http.StatusConflict, ""},
+		{`"2"`, http.StatusBadRequest, ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("PUT", "/api/posts/1", strings.NewReader(`{"Title":"t","Body":"b"}`))
+		req.Header.Set("Authorization", "Bearer "+newAuthToken(1))
+		req.Header.Set(thesrc.PostVersionHeader, test.version)
+		rw := httptest.NewRecorder()
+		serveMux.ServeHTTP(rw, req)
+
+		if rw.Code != test.wantStatus {
+			t.Errorf("version %s: got HTTP %d, want %d", test.version, rw.Code, test.wantStatus)
+		}
+		if got := rw.Header().Get(thesrc.PostVersionHeader); got != test.wantVersion {
+			t.Errorf("version %s: got %s header %q, want %q", test.version, thesrc.PostVersionHeader, got, test.wantVersion)
+		}
+		if got := rw.Header().Get("ETag"); got != "" {
+			t.Errorf("version %s: got ETag %q, want none", test.version, got)
+		}
+	}
+}
+
+func TestPost_Delete(t *testing.T) {
+	setup()
+
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return &thesrc.User{ID: id}, nil
+	}
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, AuthorUserID: 1, SubmittedAt: time.Now().Add(-thesrc.PostEditWindow)}, nil
+	}
+	var deleted bool
+	Store.Posts.(*thesrc.MockPostsService).Delete_ = func(id int) error {
+		deleted = true
+		return nil
+	}
+
+	// The edit window has passed, so the author may no longer delete the post.
+	err := apiClient.WithAuthToken(newAuthToken(1)).Posts.Delete(1)
+	if !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v, want HTTP status %d", err, http.StatusForbidden)
+	}
+	if deleted {
+		t.Error("deleted, but wanted no deletion")
+	}
+}
+
+func TestPost_Undelete(t *testing.T) {
+	setup()
+
+	mockAdmin(1)
+	var undeleted int
+	Store.Posts.(*thesrc.MockPostsService).Undelete_ = func(id int) error {
+		if id == 4 {
+			return thesrc.ErrPostLinkURLTaken
+		}
+		undeleted = id
+		return nil
+	}
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, Title: "t"}, nil
+	}
+	var audited []*thesrc.AuditEntry
+	Store.AuditLog.(*datastore.MockAuditLogStore).Create_ = func(entry *thesrc.AuditEntry) error {
+		audited = append(audited, entry)
+		return nil
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(2)).Posts.Undelete(3); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v for non-admin, want HTTP %d", err, http.StatusForbidden)
+	}
+	if _, err := apiClient.WithAuthToken(newAuthToken(2)).Posts.List(&thesrc.PostListOptions{Deleted: true}); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v listing deleted posts as non-admin, want HTTP %d", err, http.StatusForbidden)
+	}
+	if undeleted != 0 {
+		t.Fatal("non-admin undeleted post")
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Posts.Undelete(3); err != nil {
+		t.Fatal(err)
+	}
+	if undeleted != 3 {
+		t.Errorf("got post %d undeleted, want 3", undeleted)
+	}
+	if len(audited) != 1 || audited[0].Action != thesrc.AuditUndeletePost || audited[0].Target != "post:3" {
+		t.Errorf("got audit entries %+v, want an undelete of post:3", audited)
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Posts.Undelete(4); !thesrc.IsHTTPErrorCode(err, http.StatusConflict) {
+		t.Errorf("got error %v undeleting a post whose link URL was resubmitted, want HTTP %d", err, http.StatusConflict)
+	}
+}
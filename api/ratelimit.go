@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/redis"
+)
+
+var (
+	// RateLimit is the number of API requests per minute that each client may
+	// make (on average). Clients are identified by the user their API token
+	// authenticates as, or (for anonymous requests) by IP address. If
+	// RateLimit is 0, requests are not rate limited.
+	RateLimit = 0
+
+	// RateLimitBurst is the maximum number of API requests that a client may
+	// make in a burst, before being limited to RateLimit.
+	RateLimitBurst = 60
+
+	// TrustProxyHeaders is whether to use the X-Forwarded-For header (instead
+	// of the connection's remote address) as the client IP address when rate
+	// limiting. Only enable it when the API is served behind a proxy that sets
+	// it.
+	TrustProxyHeaders = false
+
+	// RateLimitExempt lists the IP addresses whose anonymous requests are not
+	// rate limited, such as those of the servers running the app (which makes
+	// API requests on behalf of all of its users).
+	RateLimitExempt = []string{"127.0.0.1", "::1"}
+)
+
+// limiter holds the rate limit token buckets. It is in memory unless
+// UseRedis is called, so that servers sharing a Redis server share limits.
+var limiter tokenBuckets = newRateLimiter()
+
+// tokenBuckets is a set of token buckets, one per client.
+type tokenBuckets interface {
+	// take takes a token from key's bucket, if one is available. It returns
+	// the number of tokens remaining and the time until the bucket is full
+	// (or, if ok is false, until a token is available).
+	take(key string, now time.Time) (remaining int, reset time.Duration, ok bool, err error)
+}
+
+var errRateLimited = errors.New("rate limit exceeded")
+
+// limitRate checks whether the client that made r has exceeded the rate
+// limit. It sets the X-RateLimit-* headers on w and returns an HTTP 429 error
+// if the limit has been exceeded.
+func limitRate(w http.ResponseWriter, r *http.Request) error {
+	if RateLimit <= 0 {
+		return nil
+	}
+
+	key := rateLimitKey(r)
+	for _, ip := range RateLimitExempt {
+		if key == "ip:"+ip {
+			return nil
+		}
+	}
+
+	remaining, reset, ok, err := limiter.take(key, time.Now())
+	if err != nil {
+		// Don't make the API unavailable when the limiter is.
+		logging.FromContext(r.Context()).Log("Rate limiting failed", "error", err)
+		return nil
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(RateLimit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(reset).Unix(), 10))
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(reset.Seconds()))))
+		return &httpError{http.StatusTooManyRequests, errRateLimited}
+	}
+	return nil
+}
+
+// rateLimitKey identifies the client that made r for rate limiting.
+func rateLimitKey(r *http.Request) string {
+	if userID, err := authenticatedUserID(r); err == nil && userID != 0 {
+		return "user:" + strconv.Itoa(userID)
+	}
+
+	return "ip:" + clientIP(r)
+}
+
+// clientIP returns the IP address of the client that made r (using the
+// X-Forwarded-For header if TrustProxyHeaders is set).
+func clientIP(r *http.Request) string {
+	if TrustProxyHeaders {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			// The last address was added by our proxy; earlier ones can be
+			// spoofed by the client.
+			addrs := strings.Split(fwd, ",")
+			return strings.TrimSpace(addrs[len(addrs)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimiter is a set of token buckets in memory.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: map[string]*bucket{}}
+}
+
+func (l *rateLimiter) take(key string, now time.Time) (remaining int, reset time.Duration, ok bool, err error) {
+	perSec, burst := bucketParams()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.buckets[key]
+	if b == nil {
+		l.sweep(now, perSec, burst)
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*perSec)
+	b.last = now
+
+	ok = b.tokens >= 1
+	if ok {
+		b.tokens--
+	}
+	remaining, reset = bucketState(b.tokens, ok, perSec, burst)
+	return remaining, reset, ok, nil
+}
+
+// bucketParams returns the rate (in tokens per second) at which buckets are
+// refilled, and their capacity.
+func bucketParams() (perSec, burst float64) {
+	perSec = float64(RateLimit) / 60
+	burst = float64(RateLimitBurst)
+	if burst < 1 {
+		burst = 1
+	}
+	return perSec, burst
+}
+
+// bucketState returns the values that take returns for a bucket holding
+// tokens (after taking one, if ok).
+func bucketState(tokens float64, ok bool, perSec, burst float64) (remaining int, reset time.Duration) {
+	if !ok {
+		return 0, secondsDuration((1 - tokens) / perSec)
+	}
+	return int(tokens), secondsDuration((burst - tokens) / perSec)
+}
+
+// maxBuckets is the number of buckets above which full buckets (which are
+// equivalent to absent ones) are removed.
+const maxBuckets = 10000
+
+func (l *rateLimiter) sweep(now time.Time, perSec, burst float64) {
+	if len(l.buckets) < maxBuckets {
+		return
+	}
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*perSec >= burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// redisRateLimiter is a set of token buckets in Redis, each a hash holding
+// the bucket's tokens and the time it was last updated.
+type redisRateLimiter struct {
+	c *redis.Client
+}
+
+// redisTakeScript updates and takes a token from the bucket in KEYS[1],
+// given the time in milliseconds (ARGV[1]), the refill rate per millisecond
+// (ARGV[2]), and the capacity (ARGV[3]). It returns whether a token was
+// taken (as 0 or 1) and the tokens remaining, as a string because Redis
+// truncates numbers returned from scripts to integers.
+//
+// It runs as a script so that concurrent requests from the same client to
+// different servers are counted correctly. Buckets expire once they would
+// be full, since full buckets are equivalent to absent ones.
+const redisTakeScript = `
+local now, perMs, burst = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens, last = tonumber(b[1]) or burst, tonumber(b[2]) or now
+if now > last then
+  tokens = math.min(burst, tokens + (now - last) * perMs)
+  last = now
+end
+local ok = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  ok = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(last))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / perMs) + 1)
+return {ok, tostring(tokens)}
+`
+
+func (l *redisRateLimiter) take(key string, now time.Time) (remaining int, reset time.Duration, ok bool, err error) {
+	perSec, burst := bucketParams()
+	reply, err := l.c.Do(context.Background(), "EVAL", redisTakeScript, 1, "thesrc:ratelimit:"+key,
+		now.UnixNano()/int64(time.Millisecond), perSec/1000, burst)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	v, _ := reply.([]interface{})
+	if len(v) != 2 {
+		return 0, 0, false, errors.New("unexpected reply from rate limit script")
+	}
+	taken, err := redis.Int64(v[0], nil)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	s, err := redis.String(v[1], nil)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	ok = taken == 1
+	remaining, reset = bucketState(tokens, ok, perSec, burst)
+	return remaining, reset, ok, nil
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	setup()
+	RateLimit, RateLimitBurst = 60, 2
+	defer func() { RateLimitBurst = 60 }()
+
+	get := func(remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/posts", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rw := httptest.NewRecorder()
+		serveMux.ServeHTTP(rw, req)
+		return rw
+	}
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		rw := get("1.2.3.4:1234", "")
+		if rw.Code != http.StatusOK {
+			t.Fatalf("request %d: got HTTP %d, want %d", i, rw.Code, http.StatusOK)
+		}
+		if got := rw.Header().Get("X-RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("request %d: got X-RateLimit-Remaining %q, want %q", i, got, wantRemaining)
+		}
+	}
+
+	rw := get("1.2.3.4:5678", "")
+	if rw.Code != http.StatusTooManyRequests {
+		t.Errorf("got HTTP %d, want %d", rw.Code, http.StatusTooManyRequests)
+	}
+	if got := rw.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("got Retry-After %q, want %q", got, "1")
+	}
+
+	if rw := get("127.0.0.1:1234", ""); rw.Code != http.StatusOK || rw.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("exempt IP: got HTTP %d and X-RateLimit-Limit %q, want %d and no header", rw.Code, rw.Header().Get("X-RateLimit-Limit"), http.StatusOK)
+	}
+
+	// Other clients have their own limits.
+	if rw := get("5.6.7.8:1234", ""); rw.Code != http.StatusOK {
+		t.Errorf("other IP: got HTTP %d, want %d", rw.Code, http.StatusOK)
+	}
+	if rw := get("1.2.3.4:1234", newAuthToken(1)); rw.Code != http.StatusOK {
+		t.Errorf("authenticated: got HTTP %d, want %d", rw.Code, http.StatusOK)
+	}
+}
+
+func TestRateLimiter_refill(t *testing.T) {
+	setup()
+	RateLimit, RateLimitBurst = 60, 1
+	defer func() { RateLimitBurst = 60 }()
+
+	l := newRateLimiter()
+	now := time.Now()
+	if _, _, ok, _ := l.take("k", now); !ok {
+		t.Fatal("first take: !ok")
+	}
+	if _, reset, ok, _ := l.take("k", now); ok || reset != time.Second {
+		t.Errorf("second take: got ok %v and reset %s, want false and %s", ok, reset, time.Second)
+	}
+	if _, _, ok, _ := l.take("k", now.Add(time.Second)); !ok {
+		t.Error("take after refill: !ok")
+	}
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/events"
+	"sourcegraph.com/sourcegraph/thesrc/redis"
+)
+
+// UseRedis makes the API keep the state that is otherwise held in each
+// server's memory in Redis, so that all servers using the same Redis server
+// share it: sessions, rate limit counters, the post list cache, the events
+// sent to live updates clients, and read-only mode. It must be called after
+// Store, SessionIdleTimeout, and the initial read-only mode (see
+// SetReadOnly) are set.
+//
+// Sessions that were stored in Store.Sessions are not moved, so users who
+// were logged in must log in again.
+func UseRedis(c *redis.Client) {
+	Store.Sessions = datastore.NewRedisSessionsStore(c, SessionIdleTimeout)
+	limiter = &redisRateLimiter{c}
+	postListCache = &redisListCache{c}
+	liveEventHub = events.Relay(Store.Events, c, "thesrc:events")
+	shareReadOnly(c)
+}
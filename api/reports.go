@@ -0,0 +1,83 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// ReportRateLimit is the number of abuse reports that each client (by IP
+// address) may make per hour. If it is 0, reports are not rate limited.
+var ReportRateLimit = 5
+
+func serveReportPost(w http.ResponseWriter, r *http.Request) error {
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	var report thesrc.Report
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		return err
+	}
+	report.ID, report.PostID, report.CreatedAt = 0, postID, time.Time{}
+	if !thesrc.ValidReportCategory(report.Category) {
+		return invalidField("Category", fmt.Errorf("unknown report category %q", report.Category))
+	}
+	if len(report.Details) > thesrc.MaxReportDetailsLength {
+		return invalidField("Details", fmt.Errorf("details must be at most %d bytes", thesrc.MaxReportDetailsLength))
+	}
+
+	// Visitors may not report posts that they can't see.
+	if _, err := getPost(r, postID); err != nil {
+		return err
+	}
+
+	// Key the hash with AuthSecret (not the rotating client record salt), so
+	// that a client's reports are counted across salt periods.
+	mac := hmac.New(sha256.New, AuthSecret)
+	mac.Write([]byte("report:" + endUserIP(r)))
+	report.ClientHash = hex.EncodeToString(mac.Sum(nil))
+
+	if err := store(r).Reports.Create(&report, ReportRateLimit, time.Now().Add(-time.Hour)); err == thesrc.ErrReportRateLimited {
+		// Overwrite the API rate limit's X-RateLimit-* headers (which
+		// checkRateLimit may have set), so clients don't retry as soon as
+		// the API rate limit resets.
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(ReportRateLimit))
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Hour.Seconds())))
+		return &httpError{http.StatusTooManyRequests, err}
+	} else if err != nil {
+		return err
+	}
+	postListCache.invalidate()
+
+	w.WriteHeader(http.StatusCreated)
+	return writeJSON(w, r, report)
+}
+
+func serveReports(w http.ResponseWriter, r *http.Request) error {
+	var opt thesrc.ReportListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+
+	reports, err := store(r).Reports.List(&opt)
+	if err != nil {
+		return err
+	}
+	if reports == nil {
+		reports = []*thesrc.Report{}
+	}
+
+	return writeJSON(w, r, reports)
+}
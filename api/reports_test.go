@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestReportPost(t *testing.T) {
+	setup()
+	ReportRateLimit = 2
+	defer func() { ReportRateLimit = 5 }()
+	// The report limit's 429 must override the API rate limit's headers.
+	RateLimit = 60
+
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		if id == 3 {
+			return &thesrc.Post{ID: id, Dead: true}, nil
+		}
+		return &thesrc.Post{ID: id}, nil
+	}
+	var reports []*thesrc.Report
+	Store.Reports.(*datastore.MockReportsStore).Create_ = func(report *thesrc.Report, limit int, since time.Time) error {
+		if limit != 2 {
+			t.Errorf("got limit %d, want 2", limit)
+		}
+		var n int
+		for _, r := range reports {
+			if r.ClientHash == report.ClientHash {
+				n++
+			}
+		}
+		if n >= limit {
+			return thesrc.ErrReportRateLimited
+		}
+		report.ID = len(reports) + 1
+		reports = append(reports, report)
+		return nil
+	}
+
+	// Reporting doesn't require an account.
+	report := &thesrc.Report{PostID: 2, Category: thesrc.ReportSpam, Details: "ads"}
+	if err := apiClient.Reports.Create(report); err != nil {
+		t.Fatal(err)
+	}
+	if report.ID != 1 {
+		t.Errorf("got report ID %d, want 1", report.ID)
+	}
+	if len(reports) != 1 || reports[0].PostID != 2 || reports[0].Details != "ads" {
+		t.Fatalf("got reports %+v, want the report of post 2", reports)
+	}
+	if reports[0].ClientHash == "" {
+		t.Error("got empty client hash, want the reporter's")
+	}
+
+	invalid := []*thesrc.Report{
+		{PostID: 2, Category: "boring"},
+		{PostID: 2, Category: thesrc.ReportOther, Details: strings.Repeat("x", thesrc.MaxReportDetailsLength+1)},
+	}
+	for _, report := range invalid {
+		if err := apiClient.Reports.Create(report); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+			t.Errorf("%+v: got error %v, want HTTP %d", report, err, http.StatusBadRequest)
+		}
+	}
+	if err := apiClient.Reports.Create(&thesrc.Report{PostID: 3, Category: thesrc.ReportSpam}); !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v reporting dead post, want HTTP %d", err, http.StatusNotFound)
+	}
+
+	if err := apiClient.Reports.Create(&thesrc.Report{PostID: 4, Category: thesrc.ReportHarassment}); err != nil {
+		t.Fatal(err)
+	}
+	err := apiClient.Reports.Create(&thesrc.Report{PostID: 5, Category: thesrc.ReportSpam})
+	var rateLimitErr *thesrc.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("got error %v after exceeding the report limit, want HTTP %d", err, http.StatusTooManyRequests)
+	}
+	if rateLimitErr.Limit != 2 {
+		t.Errorf("got rate limit %d, want the report limit (2)", rateLimitErr.Limit)
+	}
+	if d := time.Until(rateLimitErr.Reset); d < 59*time.Minute {
+		t.Errorf("got rate limit reset in %s, want in an hour", d)
+	}
+	if len(reports) != 2 {
+		t.Errorf("got %d reports, want 2", len(reports))
+	}
+}
+
+func TestReports(t *testing.T) {
+	setup()
+
+	mockModerator(1)
+	want := []*thesrc.Report{{ID: 1, PostID: 2, Category: thesrc.ReportSpam}}
+	Store.Reports.(*datastore.MockReportsStore).List_ = func(opt *thesrc.ReportListOptions) ([]*thesrc.Report, error) {
+		if opt.PostID != 2 {
+			t.Errorf("got PostID %d, want 2", opt.PostID)
+		}
+		return want, nil
+	}
+
+	if _, err := apiClient.WithAuthToken(newAuthToken(2)).Reports.List(&thesrc.ReportListOptions{PostID: 2}); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v for non-moderator, want HTTP %d", err, http.StatusForbidden)
+	}
+
+	reports, err := apiClient.WithAuthToken(newAuthToken(1)).Reports.List(&thesrc.ReportListOptions{PostID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].ID != 1 || reports[0].Category != thesrc.ReportSpam {
+		t.Errorf("got reports %+v, want %+v", reports, want)
+	}
+}
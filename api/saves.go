@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveSavePost(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Saves.Save(userID, postID); err != nil {
+		if err == thesrc.ErrPostNotFound {
+			return &httpError{http.StatusNotFound, err}
+		}
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveUnsavePost(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Saves.Unsave(userID, postID); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// markSaved sets the Saved field on each post that the user r is
+// authenticated as has saved.
+func markSaved(r *http.Request, posts ...*thesrc.Post) error {
+	userID, err := authenticatedUserID(r)
+	if err != nil || userID == 0 {
+		return err
+	}
+
+	postIDs := make([]int, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+	saved, err := store(r).Saves.Saved(userID, postIDs)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		post.Saved = saved[post.ID]
+	}
+	return nil
+}
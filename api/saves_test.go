@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestPost_Save(t *testing.T) {
+	setup()
+
+	calledSave := false
+	Store.Saves.(*datastore.MockSavesStore).Save_ = func(userID, postID int) error {
+		if userID != 1 || postID != 2 {
+			t.Errorf("got save by user %d of post %d, want user %d of post %d", userID, postID, 1, 2)
+		}
+		calledSave = true
+		return nil
+	}
+
+	if err := apiClient.Posts.Save(2); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for unauthenticated save, want HTTP %d", err, http.StatusUnauthorized)
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Posts.Save(2); err != nil {
+		t.Fatal(err)
+	}
+	if !calledSave {
+		t.Error("!calledSave")
+	}
+}
+
+func TestPost_Unsave(t *testing.T) {
+	setup()
+
+	calledUnsave := false
+	Store.Saves.(*datastore.MockSavesStore).Unsave_ = func(userID, postID int) error {
+		if userID != 1 || postID != 2 {
+			t.Errorf("got unsave by user %d of post %d, want user %d of post %d", userID, postID, 1, 2)
+		}
+		calledUnsave = true
+		return nil
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Posts.Unsave(2); err != nil {
+		t.Fatal(err)
+	}
+	if !calledUnsave {
+		t.Error("!calledUnsave")
+	}
+}
+
+func TestPosts_List_saved(t *testing.T) {
+	setup()
+
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		if opt.SavedByUserID != 1 {
+			t.Errorf("got SavedByUserID %d, want 1", opt.SavedByUserID)
+		}
+		return []*thesrc.Post{{ID: 2}, {ID: 3}}, nil
+	}
+	Store.Saves.(*datastore.MockSavesStore).Saved_ = func(userID int, postIDs []int) (map[int]bool, error) {
+		return map[int]bool{2: true, 3: true}, nil
+	}
+
+	if _, err := apiClient.Posts.List(&thesrc.PostListOptions{Saved: true}); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for unauthenticated request, want HTTP %d", err, http.StatusUnauthorized)
+	}
+
+	posts, err := apiClient.WithAuthToken(newAuthToken(1)).Posts.List(&thesrc.PostListOptions{Saved: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 || !posts[0].Saved || !posts[1].Saved {
+		t.Errorf("got posts %+v, want 2 saved posts", posts)
+	}
+}
@@ -11,7 +11,7 @@ import (
 )
 
 func init() {
-	serveMux.Handle("/", http.StripPrefix("/api", Handler()))
+	serveMux.Handle("/", http.StripPrefix("/api", VersionedHandler()))
 }
 
 var (
@@ -21,7 +21,36 @@ var (
 )
 
 func setup() {
-	store = datastore.NewMockDatastore()
+	Store = datastore.NewMockDatastore()
+	Store.Sessions = datastore.NewMemoryDatastore().Sessions
+	AuthSecret = []byte("test secret")
+	RateLimit = 0
+	limiter = newRateLimiter()
+	postListCache = newListCache()
+	QueueJobs = false
+	RecordClients = false
+	clientSalts.salt = nil
+	unfurlLink = func(string) (*thesrc.LinkMetadata, error) { return &thesrc.LinkMetadata{}, nil }
+}
+
+// newAuthToken returns the token of a new session for the user with the
+// given ID. It must be called after setup.
+func newAuthToken(userID int) string {
+	token, err := newSession(httptest.NewRequest("POST", "/api/auth", nil), userID)
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+// sessionUserID returns the ID of the user that the session token
+// authenticates as.
+func sessionUserID(token string) (int, error) {
+	session, err := tokenSession(httptest.NewRequest("GET", "/api/user", nil), token)
+	if err != nil {
+		return 0, err
+	}
+	return session.UserID, nil
 }
 
 type muxTransport http.ServeMux
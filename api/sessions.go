@@ -0,0 +1,160 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// SessionIdleTimeout is how long a session (see thesrc.Session) remains
+// valid after it was last used. Each use extends it, so users who visit at
+// least this often stay logged in.
+var SessionIdleTimeout = 30 * 24 * time.Hour
+
+// sessionTouchInterval is how stale a session's LastUsedAt may get before a
+// request updates it, so that not every authenticated request writes to the
+// datastore.
+const sessionTouchInterval = 5 * time.Minute
+
+// maxUserAgentLength is the length at which a session's UserAgent is
+// truncated.
+const maxUserAgentLength = 255
+
+var errNotSession = &httpError{http.StatusBadRequest, errors.New("not authenticated with a session token")}
+
+// newSession creates a session for the user with the given ID, who is
+// logging in with r, and returns its token. Only the token's hash is stored.
+func newSession(r *http.Request, userID int) (token string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	session := &thesrc.Session{UserID: userID, Hash: hashSessionToken(token), UserAgent: userAgent}
+	if err := store(r).Sessions.Create(session); err != nil {
+		return "", err
+	}
+
+	// Expired sessions can't be used, so clean them up now and then.
+	if err := store(r).Sessions.DeleteUnused(time.Now().Add(-SessionIdleTimeout)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func hashSessionToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// tokenSession returns the session whose token is token, unless it has
+// expired, and extends its expiration.
+func tokenSession(r *http.Request, token string) (*thesrc.Session, error) {
+	session, err := store(r).Sessions.GetByHash(hashSessionToken(token))
+	if err == thesrc.ErrSessionNotFound {
+		return nil, errInvalidAuthToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	idle := time.Since(session.LastUsedAt)
+	if idle > SessionIdleTimeout {
+		return nil, errInvalidAuthToken
+	}
+	if idle > sessionTouchInterval {
+		session.LastUsedAt = time.Now()
+		if err := store(r).Sessions.Touch(session.ID, session.LastUsedAt); err != nil {
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// currentSession returns the session that r is authenticated with. If r is
+// authenticated with a personal API token instead, it returns errNotSession.
+func currentSession(r *http.Request) (*thesrc.Session, error) {
+	if _, err := requireUserID(r); err != nil {
+		return nil, err
+	}
+	token, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(token, thesrc.PersonalTokenPrefix) {
+		return nil, errNotSession
+	}
+	return tokenSession(r, token)
+}
+
+func serveSessions(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := store(r).Sessions.List(userID, time.Now().Add(-SessionIdleTimeout))
+	if err != nil {
+		return err
+	}
+	if sessions == nil {
+		sessions = []*thesrc.Session{}
+	}
+
+	current, err := currentSession(r)
+	if err != nil && err != errNotSession {
+		return err
+	}
+	for _, session := range sessions {
+		session.Current = current != nil && session.ID == current.ID
+	}
+
+	return writeJSON(w, r, sessions)
+}
+
+func serveRevokeSession(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Sessions.Delete(userID, id); err == thesrc.ErrSessionNotFound {
+		return &httpError{http.StatusNotFound, err}
+	} else if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveRevokeCurrentSession(w http.ResponseWriter, r *http.Request) error {
+	session, err := currentSession(r)
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Sessions.Delete(session.UserID, session.ID); err != nil && err != thesrc.ErrSessionNotFound {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
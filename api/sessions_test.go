@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestSessions(t *testing.T) {
+	setup()
+
+	token, other, bobs := newAuthToken(1), newAuthToken(1), newAuthToken(2)
+	c := apiClient.WithAuthToken(token)
+
+	sessions, err := c.Sessions.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+	var current, otherID int
+	for _, session := range sessions {
+		if session.Current {
+			current = session.ID
+		} else {
+			otherID = session.ID
+		}
+	}
+	if current == 0 {
+		t.Fatalf("got sessions %+v, want one to be current", sessions)
+	}
+
+	// Users may only revoke their own sessions.
+	bobSessions, err := apiClient.WithAuthToken(bobs).Sessions.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Sessions.Revoke(bobSessions[0].ID); !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v revoking another user's session, want HTTP %d", err, http.StatusNotFound)
+	}
+
+	if err := c.Sessions.Revoke(otherID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessionUserID(other); err != errInvalidAuthToken {
+		t.Errorf("got error %v using revoked session, want %v", err, errInvalidAuthToken)
+	}
+
+	if err := c.Sessions.RevokeCurrent(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Users.Current(); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v after logging out, want HTTP %d", err, http.StatusUnauthorized)
+	}
+	if _, err := sessionUserID(bobs); err != nil {
+		t.Errorf("got error %v using another user's session, want it to remain valid", err)
+	}
+}
+
+func TestSessions_idleTimeout(t *testing.T) {
+	setup()
+
+	token := newAuthToken(1)
+	session, err := Store.Sessions.GetByHash(hashSessionToken(token))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Using a session extends it.
+	if err := Store.Sessions.Touch(session.ID, time.Now().Add(-SessionIdleTimeout+time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessionUserID(token); err != nil {
+		t.Fatal(err)
+	}
+	if session, _ := Store.Sessions.GetByHash(hashSessionToken(token)); time.Since(session.LastUsedAt) > time.Minute {
+		t.Errorf("got LastUsedAt %v, want it to have been updated", session.LastUsedAt)
+	}
+
+	if err := Store.Sessions.Touch(session.ID, time.Now().Add(-SessionIdleTimeout-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessionUserID(token); err != errInvalidAuthToken {
+		t.Errorf("got error %v using expired session, want %v", err, errInvalidAuthToken)
+	}
+}
+
+func TestRevokeCurrentSession_personalToken(t *testing.T) {
+	setup()
+
+	Store.Tokens.(*datastore.MockTokensStore).GetByHash_ = func(hash []byte) (*thesrc.Token, error) {
+		return &thesrc.Token{ID: 1, UserID: 1}, nil
+	}
+
+	if err := apiClient.WithAuthToken(thesrc.PersonalTokenPrefix + "x").Sessions.RevokeCurrent(); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v, want HTTP %d", err, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/redis"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// readOnly is 1 if the API is in read-only mode (see SetReadOnly).
+var readOnly int32
+
+// SetReadOnly turns read-only mode on or off for this server. In read-only
+// mode, requests that would change data (except the admin request to leave
+// read-only mode) fail with HTTP 503. (When the admin request changes the
+// mode, it changes it for all servers sharing a Redis server; see
+// UseRedis.)
+func SetReadOnly(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&readOnly, v)
+}
+
+// ReadOnly reports whether the API is in read-only mode (see SetReadOnly).
+func ReadOnly() bool { return atomic.LoadInt32(&readOnly) == 1 }
+
+// readOnlyRedis, if set, holds read-only mode in redisReadOnlyKey, so that
+// it's shared by all servers using it (see UseRedis).
+var readOnlyRedis *redis.Client
+
+const redisReadOnlyKey = "thesrc:read-only"
+
+// readOnlyPollInterval is how often servers sharing read-only mode through
+// Redis check whether it was changed on another server.
+const readOnlyPollInterval = 5 * time.Second
+
+// setSharedReadOnly sets read-only mode for this server and (if
+// readOnlyRedis is set) all others.
+func setSharedReadOnly(ctx context.Context, on bool) error {
+	if readOnlyRedis != nil {
+		v := "0"
+		if on {
+			v = "1"
+		}
+		if _, err := readOnlyRedis.Do(ctx, "SET", redisReadOnlyKey, v); err != nil {
+			return err
+		}
+	}
+	SetReadOnly(on)
+	return nil
+}
+
+// shareReadOnly makes servers using c share read-only mode. If this server
+// was started in read-only mode, it puts the others in read-only mode too;
+// otherwise it adopts their mode.
+func shareReadOnly(c *redis.Client) {
+	readOnlyRedis = c
+	if ReadOnly() {
+		if err := setSharedReadOnly(context.Background(), true); err != nil {
+			logging.Default.Log("Sharing read-only mode failed", "error", err)
+		}
+	}
+	go func() {
+		for {
+			if v, err := redis.String(c.Do(context.Background(), "GET", redisReadOnlyKey)); err == nil {
+				SetReadOnly(v == "1")
+			} else if err != redis.ErrNil {
+				logging.Default.Log("Getting read-only mode failed", "error", err)
+			}
+			time.Sleep(readOnlyPollInterval)
+		}
+	}()
+}
+
+var errReadOnly = &httpError{http.StatusServiceUnavailable, errors.New("thesrc is in read-only mode; try again later")}
+
+// readOnlyExemptRoutes are the routes of requests that don't change data
+// despite their method, or that must work in read-only mode. (GraphQL
+// requests may only run queries in read-only mode; see serveGraphQL.)
+var readOnlyExemptRoutes = map[string]bool{
+	router.PreviewPost:      true,
+	router.GraphQL:          true,
+	router.UpdateSiteStatus: true,
+}
+
+// checkReadOnly returns errReadOnly if the API is in read-only mode and r
+// may change data.
+func checkReadOnly(r *http.Request) error {
+	if !ReadOnly() {
+		return nil
+	}
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return nil
+	}
+	if route := mux.CurrentRoute(r); route != nil && readOnlyExemptRoutes[route.GetName()] {
+		return nil
+	}
+	return errReadOnly
+}
+
+func serveSiteStatus(w http.ResponseWriter, r *http.Request) error {
+	return writeJSON(w, r, &thesrc.SiteStatus{ReadOnly: ReadOnly()})
+}
+
+func serveUpdateSiteStatus(w http.ResponseWriter, r *http.Request) error {
+	var status thesrc.SiteStatus
+	if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+		return &httpError{http.StatusBadRequest, err}
+	}
+
+	// Set the mode even if it's unchanged on this server, in case this
+	// server hasn't seen another server's change yet.
+	changed := status.ReadOnly != ReadOnly()
+	if err := setSharedReadOnly(r.Context(), status.ReadOnly); err != nil {
+		return err
+	}
+	if changed {
+		logging.FromContext(r.Context()).Log("Read-only mode changed", "read_only", status.ReadOnly)
+		if err := audit(r, thesrc.AuditUpdateSiteStatus, "site", "read-only: "+strconv.FormatBool(status.ReadOnly)); err != nil {
+			return err
+		}
+	}
+	return writeJSON(w, r, &status)
+}
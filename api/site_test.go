@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestSite_readOnly(t *testing.T) {
+	setup()
+	mockAdmin(1)
+	defer SetReadOnly(false)
+
+	Store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		t.Error("post was submitted in read-only mode")
+		return false, nil
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(2)).Site.UpdateStatus(&thesrc.SiteStatus{ReadOnly: true}); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v updating site status as non-admin, want HTTP %d", err, http.StatusForbidden)
+	}
+
+	admin := apiClient.WithAuthToken(newAuthToken(1))
+	if err := admin.Site.UpdateStatus(&thesrc.SiteStatus{ReadOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := apiClient.Site.Status(); err != nil {
+		t.Fatal(err)
+	} else if !status.ReadOnly {
+		t.Error("got ReadOnly == false, want true")
+	}
+
+	_, err := admin.Posts.Submit(&thesrc.Post{Title: "t", LinkURL: "http://example.com"})
+	if e, ok := err.(*thesrc.ErrorResponse); !ok || e.Code != thesrc.ErrCodeUnavailable {
+		t.Errorf("got error %v submitting post in read-only mode, want code %q", err, thesrc.ErrCodeUnavailable)
+	}
+
+	// Previews don't change data.
+	if _, err := apiClient.Posts.Preview(&thesrc.Post{Title: "t", LinkURL: "http://example.com"}); err != nil {
+		t.Errorf("got error %v previewing post in read-only mode, want nil", err)
+	}
+
+	if err := admin.Site.UpdateStatus(&thesrc.SiteStatus{ReadOnly: false}); err != nil {
+		t.Fatal(err)
+	}
+	if ReadOnly() {
+		t.Error("got ReadOnly() == true after leaving read-only mode, want false")
+	}
+}
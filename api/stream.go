@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+const (
+	// streamReplayLimit is the maximum number of missed posts that are sent
+	// to a client that resumes a posts stream. A client that missed more
+	// than this receives only the most recent ones.
+	streamReplayLimit = 100
+
+	// streamHeartbeatInterval is how often a comment is sent on posts
+	// streams to keep idle connections open through proxies.
+	streamHeartbeatInterval = 30 * time.Second
+)
+
+// servePostsStream sends posts as they are created, as server-sent events
+// (text/event-stream). Each event's data is the JSON-encoded post and its ID
+// is the post's ID, so a client that reconnects with a Last-Event-ID header
+// first receives the posts it missed (up to streamReplayLimit).
+func servePostsStream(w http.ResponseWriter, r *http.Request) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return errors.New("streaming responses are not supported")
+	}
+
+	var lastID int
+	if s := r.Header.Get("Last-Event-ID"); s != "" {
+		var err error
+		lastID, err = strconv.Atoi(s)
+		if err != nil {
+			return &httpError{http.StatusBadRequest, fmt.Errorf("invalid Last-Event-ID %q", s)}
+		}
+	}
+
+	// Subscribe before listing missed posts so that none are created in
+	// between without being sent.
+	events, cancel := liveEvents().Subscribe()
+	defer cancel()
+
+	var missed []*thesrc.Post
+	if lastID != 0 {
+		var err error
+		missed, err = store(r).Posts.List(&thesrc.PostListOptions{
+			SinceID:     lastID,
+			ListOptions: thesrc.ListOptions{PerPage: streamReplayLimit},
+		})
+		if err != nil {
+			return err
+		}
+		sort.Slice(missed, func(i, j int) bool { return missed[i].ID < missed[j].ID })
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// After the response has started, errors can't be reported to the
+	// client, so they just end the stream.
+	sent := make(map[int]bool, len(missed))
+	for _, post := range missed {
+		if err := writePostEvent(w, post); err != nil {
+			return nil
+		}
+		sent[post.ID] = true
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if e.Type != thesrc.EventPostCreated || sent[e.Post.ID] || e.Post.Hidden || e.Post.Dead {
+				continue
+			}
+			if banned, err := authorShadowBanned(r, e.Post); err != nil {
+				return nil
+			} else if banned {
+				continue
+			}
+			if err := writePostEvent(w, e.Post); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return nil
+		}
+	}
+}
+
+// writePostEvent writes a server-sent event whose ID is the post's ID and
+// whose data is the JSON-encoded post.
+func writePostEvent(w io.Writer, post *thesrc.Post) error {
+	data, err := json.Marshal(post)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", post.ID, data)
+	return err
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestPostsStream(t *testing.T) {
+	setup()
+
+	var calledList bool
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		if opt.SinceID != 3 {
+			t.Errorf("got SinceID %d, want 3", opt.SinceID)
+		}
+		calledList = true
+		return []*thesrc.Post{{ID: 5, Title: "b"}, {ID: 4, Title: "a"}}, nil
+	}
+
+	s := httptest.NewServer(serveMux)
+	defer s.Close()
+
+	req, _ := http.NewRequest("GET", s.URL+"/api/posts/stream", nil)
+	req.Header.Set("Last-Event-ID", "3")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got, want := resp.Header.Get("Content-Type"), "text/event-stream"; got != want {
+		t.Errorf("got Content-Type %q, want %q", got, want)
+	}
+	if !calledList {
+		t.Error("!calledList")
+	}
+
+	// Already-sent, hidden, and non-creation events should be skipped.
+	Store.Events.Publish(&thesrc.Event{Type: thesrc.EventPostCreated, Post: &thesrc.Post{ID: 5}})
+	Store.Events.Publish(&thesrc.Event{Type: thesrc.EventPostCreated, Post: &thesrc.Post{ID: 6, Hidden: true}})
+	Store.Events.Publish(&thesrc.Event{Type: thesrc.EventPostScore, Post: &thesrc.Post{ID: 4}})
+	Store.Events.Publish(&thesrc.Event{Type: thesrc.EventPostCreated, Post: &thesrc.Post{ID: 7, Title: "c"}})
+
+	br := bufio.NewReader(resp.Body)
+	var ids []string
+	for len(ids) < 3 {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimSpace(strings.TrimPrefix(line, "id: ")))
+		}
+	}
+	if got, want := strings.Join(ids, ","), "4,5,7"; got != want {
+		t.Errorf("got event IDs %s, want %s", got, want)
+	}
+	if line, _ := br.ReadString('\n'); !strings.Contains(line, `"Title":"c"`) {
+		t.Errorf("got data line %q, want JSON-encoded post", line)
+	}
+}
+
+func TestPostsStream_invalidLastEventID(t *testing.T) {
+	setup()
+
+	req, _ := http.NewRequest("GET", "/api/posts/stream", nil)
+	req.Header.Set("Last-Event-ID", "x")
+	rw := httptest.NewRecorder()
+	serveMux.ServeHTTP(rw, req)
+	if rw.Code != http.StatusBadRequest {
+		t.Errorf("got HTTP status %d, want %d", rw.Code, http.StatusBadRequest)
+	}
+}
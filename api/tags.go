@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveTags(w http.ResponseWriter, r *http.Request) error {
+	var opt thesrc.TagListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+
+	tags, err := store(r).Tags.List(&opt)
+	if err != nil {
+		return err
+	}
+	if tags == nil {
+		tags = []*thesrc.Tag{}
+	}
+
+	writePaginationLinks(w, r, opt.ListOptions, len(tags))
+	return writeJSON(w, r, tags)
+}
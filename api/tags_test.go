@@ -0,0 +1,34 @@
+package api
+
+import (
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestTags(t *testing.T) {
+	setup()
+
+	wantTags := []*thesrc.Tag{{Name: "golang", NumPosts: 2}}
+
+	calledList := false
+	Store.Tags.(*thesrc.MockTagsService).List_ = func(opt *thesrc.TagListOptions) ([]*thesrc.Tag, error) {
+		if opt.Prefix != "go" {
+			t.Errorf("got prefix %q, want %q", opt.Prefix, "go")
+		}
+		calledList = true
+		return wantTags, nil
+	}
+
+	tags, err := apiClient.Tags.List(&thesrc.TagListOptions{Prefix: "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledList {
+		t.Error("!calledList")
+	}
+	if !normalizeDeepEqual(&wantTags, &tags) {
+		t.Errorf("got tags %+v but wanted tags %+v", tags, wantTags)
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// maxTokenNameLength is the maximum length of a personal API token's name.
+const maxTokenNameLength = 100
+
+// newPersonalToken returns a new random personal API token and its hash
+// (which is all that is stored).
+func newPersonalToken() (value string, hash []byte, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	value = thesrc.PersonalTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return value, hashPersonalToken(value), nil
+}
+
+func hashPersonalToken(value string) []byte {
+	sum := sha256.Sum256([]byte(value))
+	return sum[:]
+}
+
+// personalTokenUserID returns the ID of the user that the personal API
+// token authenticates.
+func personalTokenUserID(r *http.Request, value string) (int, error) {
+	token, err := store(r).Tokens.GetByHash(hashPersonalToken(value))
+	if err == thesrc.ErrTokenNotFound {
+		return 0, errInvalidAuthToken
+	} else if err != nil {
+		return 0, err
+	}
+	return token.UserID, nil
+}
+
+func serveTokens(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := store(r).Tokens.List(userID)
+	if err != nil {
+		return err
+	}
+	if tokens == nil {
+		tokens = []*thesrc.Token{}
+	}
+
+	return writeJSON(w, r, tokens)
+}
+
+func serveCreateToken(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var token thesrc.Token
+	if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+		return err
+	}
+	token.Name = strings.TrimSpace(token.Name)
+	if token.Name == "" || len(token.Name) > maxTokenNameLength {
+		return invalidField("Name", errors.New("token name must be 1-100 characters long"))
+	}
+
+	token.ID = 0
+	token.UserID = userID
+	token.Value, token.Hash, err = newPersonalToken()
+	if err != nil {
+		return err
+	}
+	if err := store(r).Tokens.Create(&token); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	return writeJSON(w, r, token)
+}
+
+func serveRevokeToken(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Tokens.Delete(userID, id); err == thesrc.ErrTokenNotFound {
+		return &httpError{http.StatusNotFound, err}
+	} else if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestTokens_Create(t *testing.T) {
+	setup()
+
+	var created *thesrc.Token
+	Store.Tokens.(*datastore.MockTokensStore).Create_ = func(token *thesrc.Token) error {
+		token.ID = 3
+		created = token
+		return nil
+	}
+
+	if _, err := apiClient.Tokens.Create("n"); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for unauthenticated request, want HTTP %d", err, http.StatusUnauthorized)
+	}
+	if _, err := apiClient.WithAuthToken(newAuthToken(1)).Tokens.Create(" "); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v for blank name, want HTTP %d", err, http.StatusBadRequest)
+	}
+
+	token, err := apiClient.WithAuthToken(newAuthToken(1)).Tokens.Create("n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created == nil || created.UserID != 1 || created.Name != "n" {
+		t.Fatalf("got created token %+v, want token named %q for user 1", created, "n")
+	}
+	if token.ID != 3 || !strings.HasPrefix(token.Value, thesrc.PersonalTokenPrefix) {
+		t.Errorf("got token %+v, want ID 3 and a value", token)
+	}
+	if !bytes.Equal(created.Hash, hashPersonalToken(token.Value)) {
+		t.Error("stored hash doesn't match the token's value")
+	}
+}
+
+func TestTokens_authenticate(t *testing.T) {
+	setup()
+
+	value, hash, err := newPersonalToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Store.Tokens.(*datastore.MockTokensStore).GetByHash_ = func(h []byte) (*thesrc.Token, error) {
+		if !bytes.Equal(h, hash) {
+			return nil, thesrc.ErrTokenNotFound
+		}
+		return &thesrc.Token{ID: 1, UserID: 2, Hash: hash}, nil
+	}
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return &thesrc.User{ID: id}, nil
+	}
+
+	user, err := apiClient.WithAuthToken(value).Users.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 2 {
+		t.Errorf("got user ID %d, want 2", user.ID)
+	}
+
+	if _, err := apiClient.WithAuthToken(thesrc.PersonalTokenPrefix + "bad").Users.Current(); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for revoked token, want HTTP %d", err, http.StatusUnauthorized)
+	}
+}
+
+func TestTokens_Revoke(t *testing.T) {
+	setup()
+
+	Store.Tokens.(*datastore.MockTokensStore).Delete_ = func(userID, id int) error {
+		if userID != 1 || id != 3 {
+			return thesrc.ErrTokenNotFound
+		}
+		return nil
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Tokens.Revoke(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiClient.WithAuthToken(newAuthToken(2)).Tokens.Revoke(3); !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v revoking another user's token, want HTTP %d", err, http.StatusNotFound)
+	}
+}
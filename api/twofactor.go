@@ -0,0 +1,253 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/totp"
+)
+
+// RequireTwoFactorRole (if set) is the least privileged role whose users
+// must enable two-factor authentication before they may use the privileges
+// of their role. For example, if it is thesrc.RoleModerator, moderators and
+// admins without two-factor authentication are treated as members.
+var RequireTwoFactorRole string
+
+// twoFactorIssuer names thesrc in users' authenticator apps.
+const twoFactorIssuer = "thesrc"
+
+// twoFactorTokenLifetime is how long a user has to enter a two-factor
+// authentication code after entering their password.
+const twoFactorTokenLifetime = 10 * time.Minute
+
+var (
+	errInvalidTwoFactorToken = errors.New("invalid or expired two-factor authentication token (log in again)")
+	errInvalidTwoFactorCode  = errors.New("invalid authentication code")
+	errTwoFactorEnabled      = &httpError{http.StatusConflict, errors.New("two-factor authentication is already enabled")}
+	errTwoFactorNotEnabled   = &httpError{http.StatusConflict, errors.New("two-factor authentication is not enabled")}
+)
+
+// needsTwoFactor returns whether user must enable two-factor authentication
+// to use role (see RequireTwoFactorRole).
+func needsTwoFactor(user *thesrc.User, role string) bool {
+	if RequireTwoFactorRole == "" || user == nil || user.TOTPSecret != "" {
+		return false
+	}
+	return (&thesrc.User{Role: role}).HasRole(RequireTwoFactorRole)
+}
+
+// passwordAuth returns the result of authenticating as user with their
+// password: a new session, or (if the user has enabled two-factor
+// authentication) a token to finish authenticating with a code.
+func passwordAuth(r *http.Request, user *thesrc.User) (*thesrc.Auth, error) {
+	if user.TOTPSecret != "" {
+		return &thesrc.Auth{TwoFactorToken: newUserToken("two-factor", user, twoFactorTokenLifetime)}, nil
+	}
+	token, err := newSession(r, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Shadow-banned users aren't told that they are.
+	user.ShadowBanned = false
+	return &thesrc.Auth{User: user, Token: token}, nil
+}
+
+// newRecoveryCodes returns thesrc.NumRecoveryCodes new recovery codes, and
+// their hashes (as stored in thesrc.User.RecoveryCodeHashes).
+func newRecoveryCodes() (codes []string, hashes string, err error) {
+	hashList := make([]string, thesrc.NumRecoveryCodes)
+	for i := range hashList {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, "", err
+		}
+		s := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		code := s[:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:]
+		codes = append(codes, code)
+		hashList[i] = hashRecoveryCode(code)
+	}
+	return codes, strings.Join(hashList, " "), nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkTwoFactorCode returns whether code is a current code from user's
+// authenticator app or one of their unused recovery codes. Checking a
+// recovery code uses it up.
+func checkTwoFactorCode(r *http.Request, user *thesrc.User, code string) (bool, error) {
+	if user.TOTPSecret == "" {
+		return false, nil
+	}
+	if totp.Validate(user.TOTPSecret, code, time.Now()) {
+		return true, nil
+	}
+
+	hash := hashRecoveryCode(code)
+	hashes := strings.Fields(user.RecoveryCodeHashes)
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			remaining := append(hashes[:i:i], hashes[i+1:]...)
+			user.RecoveryCodeHashes = strings.Join(remaining, " ")
+			if err := store(r).Users.SetTwoFactor(user.ID, user.TOTPSecret, user.RecoveryCodeHashes); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// twoFactorUser returns r's authenticated user, who must have two-factor
+// authentication enabled if enabled is true, or disabled otherwise.
+func twoFactorUser(r *http.Request, enabled bool) (*thesrc.User, error) {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return nil, err
+	}
+	user, err := store(r).Users.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled && user.TOTPSecret == "" {
+		return nil, errTwoFactorNotEnabled
+	} else if !enabled && user.TOTPSecret != "" {
+		return nil, errTwoFactorEnabled
+	}
+	return user, nil
+}
+
+func serveSetUpTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	user, err := twoFactorUser(r, false)
+	if err != nil {
+		return err
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, r, &thesrc.TwoFactorSetup{
+		Secret: secret,
+		URL:    totp.URL(twoFactorIssuer, user.Login, secret),
+	})
+}
+
+func serveEnableTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	user, err := twoFactorUser(r, false)
+	if err != nil {
+		return err
+	}
+
+	var enable thesrc.TwoFactorEnable
+	if err := json.NewDecoder(r.Body).Decode(&enable); err != nil {
+		return err
+	}
+	if _, err := totp.Code(enable.Secret, time.Now()); err != nil {
+		return invalidField("Secret", errors.New("invalid secret"))
+	}
+	if !totp.Validate(enable.Secret, enable.Code, time.Now()) {
+		return invalidField("Code", errInvalidTwoFactorCode)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return err
+	}
+	if err := store(r).Users.SetTwoFactor(user.ID, enable.Secret, hashes); err != nil {
+		return err
+	}
+	return writeJSON(w, r, codes)
+}
+
+func serveDisableTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	user, err := twoFactorUser(r, true)
+	if err != nil {
+		return err
+	}
+
+	var req struct{ Code string }
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+	if ok, err := checkTwoFactorCode(r, user, req.Code); err != nil {
+		return err
+	} else if !ok {
+		return invalidField("Code", errInvalidTwoFactorCode)
+	}
+
+	if err := store(r).Users.SetTwoFactor(user.ID, "", ""); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) error {
+	user, err := twoFactorUser(r, true)
+	if err != nil {
+		return err
+	}
+
+	var req struct{ Code string }
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+	if ok, err := checkTwoFactorCode(r, user, req.Code); err != nil {
+		return err
+	} else if !ok {
+		return invalidField("Code", errInvalidTwoFactorCode)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return err
+	}
+	if err := store(r).Users.SetTwoFactor(user.ID, user.TOTPSecret, hashes); err != nil {
+		return err
+	}
+	return writeJSON(w, r, codes)
+}
+
+func serveAuthenticateTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	var req struct{ Token, Code string }
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	user, err := userTokenUser(r, "two-factor", req.Token)
+	if err == errInvalidUserToken {
+		return invalidField("Token", errInvalidTwoFactorToken)
+	} else if err != nil {
+		return err
+	}
+	// If the user disabled two-factor authentication after entering their
+	// password, the password is enough.
+	if user.TOTPSecret != "" {
+		if ok, err := checkTwoFactorCode(r, user, req.Code); err != nil {
+			return err
+		} else if !ok {
+			return invalidField("Code", errInvalidTwoFactorCode)
+		}
+	}
+
+	token, err := newSession(r, user.ID)
+	if err != nil {
+		return err
+	}
+
+	user.ShadowBanned = false
+	return writeJSON(w, r, &thesrc.Auth{User: user, Token: token})
+}
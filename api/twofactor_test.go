@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/totp"
+)
+
+func TestTwoFactor(t *testing.T) {
+	setup()
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	alice := &thesrc.User{ID: 1, Login: "alice", PasswordHash: hash}
+	users := Store.Users.(*datastore.MockUsersStore)
+	users.Get_ = func(id int) (*thesrc.User, error) {
+		u := *alice
+		return &u, nil
+	}
+	users.GetByLogin_ = func(login string) (*thesrc.User, error) {
+		u := *alice
+		return &u, nil
+	}
+	users.SetTwoFactor_ = func(userID int, secret, recoveryCodeHashes string) error {
+		alice.TOTPSecret, alice.RecoveryCodeHashes = secret, recoveryCodeHashes
+		return nil
+	}
+
+	c := apiClient.WithAuthToken(newAuthToken(1))
+	tfSetup, err := c.TwoFactor.Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TwoFactor.Enable(&thesrc.TwoFactorEnable{Secret: tfSetup.Secret, Code: "000000"}); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v enabling with wrong code, want HTTP %d", err, http.StatusBadRequest)
+	}
+	code, _ := totp.Code(tfSetup.Secret, time.Now())
+	recoveryCodes, err := c.TwoFactor.Enable(&thesrc.TwoFactorEnable{Secret: tfSetup.Secret, Code: code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recoveryCodes) != thesrc.NumRecoveryCodes {
+		t.Errorf("got %d recovery codes, want %d", len(recoveryCodes), thesrc.NumRecoveryCodes)
+	}
+
+	// Logging in now requires a code.
+	auth, err := apiClient.Users.Authenticate("alice", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Token != "" || auth.User != nil || auth.TwoFactorToken == "" {
+		t.Fatalf("got auth %+v, want only a TwoFactorToken", auth)
+	}
+	if _, err := apiClient.TwoFactor.Authenticate(auth.TwoFactorToken, "000000"); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v for wrong code, want HTTP %d", err, http.StatusBadRequest)
+	}
+	if auth2, err := apiClient.TwoFactor.Authenticate(auth.TwoFactorToken, code); err != nil {
+		t.Fatal(err)
+	} else if userID, err := sessionUserID(auth2.Token); err != nil || userID != 1 {
+		t.Errorf("got token for user %d (error %v), want user 1", userID, err)
+	}
+
+	// Recovery codes may only be used once.
+	if _, err := apiClient.TwoFactor.Authenticate(auth.TwoFactorToken, recoveryCodes[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apiClient.TwoFactor.Authenticate(auth.TwoFactorToken, recoveryCodes[0]); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v reusing recovery code, want HTTP %d", err, http.StatusBadRequest)
+	}
+
+	if err := c.TwoFactor.Disable(recoveryCodes[1]); err != nil {
+		t.Fatal(err)
+	}
+	if alice.TOTPSecret != "" || alice.RecoveryCodeHashes != "" {
+		t.Errorf("got TOTP secret %q and recovery code hashes %q after disabling, want none", alice.TOTPSecret, alice.RecoveryCodeHashes)
+	}
+}
+
+func TestTwoFactor_invalidToken(t *testing.T) {
+	setup()
+
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return &thesrc.User{ID: id, PasswordHash: []byte("h"), TOTPSecret: "JBSWY3DPEHPK3PXP"}, nil
+	}
+
+	for _, token := range []string{"", "x.y", newAuthToken(1), newPasswordResetToken(&thesrc.User{ID: 1, PasswordHash: []byte("h")})} {
+		if _, err := apiClient.TwoFactor.Authenticate(token, "123456"); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+			t.Errorf("%q: got error %v, want HTTP %d", token, err, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestRequireTwoFactorRole(t *testing.T) {
+	setup()
+	RequireTwoFactorRole = thesrc.RoleModerator
+	defer func() { RequireTwoFactorRole = "" }()
+
+	mod := &thesrc.User{ID: 1, Login: "mod", Role: thesrc.RoleModerator}
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		u := *mod
+		return &u, nil
+	}
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+newAuthToken(1))
+
+	if err := checkRole(req, thesrc.RoleModerator); err == nil {
+		t.Error("moderator without two-factor authentication may use moderator role")
+	}
+	if err := checkRole(req, thesrc.RoleMember); err != nil {
+		t.Errorf("moderator without two-factor authentication may not use member role: %s", err)
+	}
+
+	mod.TOTPSecret = "JBSWY3DPEHPK3PXP"
+	if err := checkRole(req, thesrc.RoleModerator); err != nil {
+		t.Errorf("moderator with two-factor authentication may not use moderator role: %s", err)
+	}
+}
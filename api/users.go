@@ -0,0 +1,250 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+var errBadLogin = &httpError{http.StatusUnauthorized, errors.New("invalid login or password")}
+
+func serveSignup(w http.ResponseWriter, r *http.Request) error {
+	var newUser thesrc.NewUser
+	err := json.NewDecoder(r.Body).Decode(&newUser)
+	if err != nil {
+		return err
+	}
+
+	if err := newUser.Validate(); err != nil {
+		return &httpError{http.StatusBadRequest, err}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newUser.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	user := &thesrc.User{
+		Login:        newUser.Login,
+		Email:        newUser.Email,
+		PasswordHash: hash,
+	}
+	if err := store(r).Users.Create(user); err != nil {
+		if err == datastore.ErrLoginTaken {
+			return &httpError{http.StatusConflict, err}
+		}
+		return err
+	}
+
+	token, err := newSession(r, user.ID)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	return writeJSON(w, r, &thesrc.Auth{User: user, Token: token})
+}
+
+func serveAuthenticate(w http.ResponseWriter, r *http.Request) error {
+	var creds struct{ Login, Password string }
+	err := json.NewDecoder(r.Body).Decode(&creds)
+	if err != nil {
+		return err
+	}
+
+	user, err := store(r).Users.GetByLogin(creds.Login)
+	if err == thesrc.ErrUserNotFound {
+		return errBadLogin
+	} else if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(creds.Password)); err != nil {
+		return errBadLogin
+	}
+
+	auth, err := passwordAuth(r, user)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, r, auth)
+}
+
+func serveCurrentUser(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := store(r).Users.Get(userID)
+	if err == thesrc.ErrUserNotFound {
+		return errInvalidAuthToken
+	} else if err != nil {
+		return err
+	}
+
+	user.ShadowBanned = false
+	user.TwoFactorEnabled = user.TOTPSecret != ""
+	user.UnreadNotifications, err = store(r).Notifications.UnreadCount(user.ID)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, r, user)
+}
+
+func serveUpdateUserSettings(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var settings thesrc.UserSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		return err
+	}
+	settings.DisplayName = strings.TrimSpace(settings.DisplayName)
+	settings.Email = strings.TrimSpace(settings.Email)
+	if err := settings.Validate(); err != nil {
+		return &httpError{http.StatusBadRequest, err}
+	}
+
+	if err := store(r).Users.UpdateSettings(userID, &settings); err != nil {
+		return err
+	}
+
+	user, err := store(r).Users.Get(userID)
+	if err != nil {
+		return err
+	}
+	user.ShadowBanned = false
+	return writeJSON(w, r, user)
+}
+
+func serveChangePassword(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var change thesrc.PasswordChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		return err
+	}
+
+	user, err := store(r).Users.Get(userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(change.CurrentPassword)); err != nil {
+		return invalidField("CurrentPassword", errors.New("incorrect password"))
+	}
+	if len(change.NewPassword) < thesrc.MinPasswordLength {
+		return invalidField("NewPassword", fmt.Errorf("password must be at least %d characters long", thesrc.MinPasswordLength))
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(change.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := store(r).Users.SetPasswordHash(userID, hash); err != nil {
+		return err
+	}
+
+	// Log out all of the user's sessions, including the client's, and issue
+	// a new one.
+	if err := store(r).Sessions.DeleteAll(userID, 0); err != nil {
+		return err
+	}
+	token, err := newSession(r, userID)
+	if err != nil {
+		return err
+	}
+
+	user.ShadowBanned = false
+	return writeJSON(w, r, &thesrc.Auth{User: user, Token: token})
+}
+
+func serveUser(w http.ResponseWriter, r *http.Request) error {
+	user, err := store(r).Users.GetByLogin(mux.Vars(r)["Login"])
+	if err == thesrc.ErrUserNotFound {
+		return &httpError{http.StatusNotFound, err}
+	} else if err != nil {
+		return err
+	}
+
+	user.Karma, err = store(r).Users.Karma(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := hidePrivateUserFields(r, user); err != nil {
+		return err
+	}
+	return writeJSON(w, r, user)
+}
+
+// hidePrivateUserFields clears the fields of user that r's authenticated
+// user may not see. Email addresses and settings are private, and only
+// moderators may see who is shadow-banned.
+func hidePrivateUserFields(r *http.Request, user *thesrc.User) error {
+	user.Email = ""
+	user.DigestSubscribed = false
+	user.Theme = ""
+	user.DefaultSort = ""
+	if isMod, err := hasRole(r, thesrc.RoleModerator); err != nil {
+		return err
+	} else if !isMod {
+		user.ShadowBanned = false
+	}
+	return nil
+}
+
+func serveShadowBanUser(w http.ResponseWriter, r *http.Request) error {
+	user, err := store(r).Users.GetByLogin(mux.Vars(r)["Login"])
+	if err == thesrc.ErrUserNotFound {
+		return &httpError{http.StatusNotFound, err}
+	} else if err != nil {
+		return err
+	}
+
+	var ban thesrc.UserShadowBan
+	if err := json.NewDecoder(r.Body).Decode(&ban); err != nil {
+		return err
+	}
+
+	if err := store(r).Users.SetShadowBanned(user.ID, ban.ShadowBanned); err != nil {
+		return err
+	}
+	postListCache.invalidate()
+
+	action := thesrc.AuditShadowBan
+	if !ban.ShadowBanned {
+		action = thesrc.AuditUnban
+	}
+	if err := audit(r, action, auditTarget("user", user.Login), ""); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// authorShadowBanned returns whether post's author is shadow-banned.
+func authorShadowBanned(r *http.Request, post *thesrc.Post) (bool, error) {
+	if post.AuthorUserID == 0 {
+		return false, nil
+	}
+	user, err := store(r).Users.Get(post.AuthorUserID)
+	if err == thesrc.ErrUserNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return user != nil && user.ShadowBanned, nil
+}
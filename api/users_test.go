@@ -0,0 +1,293 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestUser_Signup(t *testing.T) {
+	setup()
+
+	calledCreate := false
+	Store.Users.(*datastore.MockUsersStore).Create_ = func(user *thesrc.User) error {
+		if user.Login != "alice" {
+			t.Errorf("got login %q, want %q", user.Login, "alice")
+		}
+		if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte("password")); err != nil {
+			t.Errorf("password hash does not match password: %s", err)
+		}
+		calledCreate = true
+		user.ID = 1
+		return nil
+	}
+
+	auth, err := apiClient.Users.Signup(&thesrc.NewUser{Login: "alice", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledCreate {
+		t.Error("!calledCreate")
+	}
+	if auth.User.ID != 1 {
+		t.Errorf("got user ID %d, want %d", auth.User.ID, 1)
+	}
+	if userID, err := sessionUserID(auth.Token); err != nil || userID != 1 {
+		t.Errorf("got token for user ID %d (error %v), want %d", userID, err, 1)
+	}
+}
+
+func TestUser_Signup_invalid(t *testing.T) {
+	setup()
+
+	_, err := apiClient.Users.Signup(&thesrc.NewUser{Login: "a", Password: "short"})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v, want HTTP %d", err, http.StatusBadRequest)
+	}
+	if code := thesrc.ErrorCode(err); code != thesrc.ErrCodeInvalid {
+		t.Errorf("got error code %q, want %q", code, thesrc.ErrCodeInvalid)
+	}
+	if e, ok := err.(*thesrc.ErrorResponse); !ok || len(e.Fields) != 2 || e.Fields[0].Field != "Login" || e.Fields[1].Field != "Password" {
+		t.Errorf("got error %#v, want invalid Login and Password fields", err)
+	}
+}
+
+func TestUser_Signup_loginTaken(t *testing.T) {
+	setup()
+
+	Store.Users.(*datastore.MockUsersStore).Create_ = func(user *thesrc.User) error {
+		return datastore.ErrLoginTaken
+	}
+
+	_, err := apiClient.Users.Signup(&thesrc.NewUser{Login: "alice", Password: "password"})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusConflict) {
+		t.Errorf("got error %v, want HTTP %d", err, http.StatusConflict)
+	}
+}
+
+func TestUser_Authenticate(t *testing.T) {
+	setup()
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	Store.Users.(*datastore.MockUsersStore).GetByLogin_ = func(login string) (*thesrc.User, error) {
+		if login != "alice" {
+			return nil, thesrc.ErrUserNotFound
+		}
+		return &thesrc.User{ID: 1, Login: "alice", PasswordHash: hash}, nil
+	}
+
+	auth, err := apiClient.Users.Authenticate("alice", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID, err := sessionUserID(auth.Token); err != nil || userID != 1 {
+		t.Errorf("got token for user ID %d (error %v), want %d", userID, err, 1)
+	}
+
+	if _, err := apiClient.Users.Authenticate("alice", "wrong"); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for wrong password, want HTTP %d", err, http.StatusUnauthorized)
+	}
+	if _, err := apiClient.Users.Authenticate("bob", "password"); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for nonexistent user, want HTTP %d", err, http.StatusUnauthorized)
+	}
+}
+
+func TestUser_Current(t *testing.T) {
+	setup()
+
+	wantUser := &thesrc.User{ID: 1, Login: "alice"}
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		if id != wantUser.ID {
+			t.Errorf("wanted request for user %d but got %d", wantUser.ID, id)
+		}
+		return wantUser, nil
+	}
+
+	if _, err := apiClient.Users.Current(); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for unauthenticated request, want HTTP %d", err, http.StatusUnauthorized)
+	}
+
+	user, err := apiClient.WithAuthToken(newAuthToken(1)).Users.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !normalizeDeepEqual(wantUser, user) {
+		t.Errorf("got user %+v but wanted user %+v", user, wantUser)
+	}
+}
+
+func TestUser(t *testing.T) {
+	setup()
+
+	Store.Users.(*datastore.MockUsersStore).GetByLogin_ = func(login string) (*thesrc.User, error) {
+		if login != "alice" {
+			return nil, thesrc.ErrUserNotFound
+		}
+		return &thesrc.User{ID: 1, Login: "alice", Email: "alice@example.com"}, nil
+	}
+	Store.Users.(*datastore.MockUsersStore).Karma_ = func(userID int) (int, error) {
+		return 7, nil
+	}
+
+	user, err := apiClient.Users.Get("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&thesrc.User{ID: 1, Login: "alice", Karma: 7}); !normalizeDeepEqual(want, user) {
+		t.Errorf("got user %+v, want %+v (without email)", user, want)
+	}
+
+	if _, err := apiClient.Users.Get("bob"); !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v for nonexistent user, want HTTP %d", err, http.StatusNotFound)
+	}
+}
+
+func TestUser_shadowBanned(t *testing.T) {
+	setup()
+
+	Store.Users.(*datastore.MockUsersStore).GetByLogin_ = func(login string) (*thesrc.User, error) {
+		return &thesrc.User{ID: 1, Login: "alice", ShadowBanned: true}, nil
+	}
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		if id == 2 {
+			return &thesrc.User{ID: id, Role: thesrc.RoleModerator}, nil
+		}
+		return &thesrc.User{ID: id, ShadowBanned: true}, nil
+	}
+
+	// Only moderators may see who is shadow-banned, and shadow-banned users
+	// aren't told that they are.
+	if user, err := apiClient.Users.Get("alice"); err != nil {
+		t.Fatal(err)
+	} else if user.ShadowBanned {
+		t.Error("got ShadowBanned for anonymous request, want it omitted")
+	}
+	if user, err := apiClient.WithAuthToken(newAuthToken(1)).Users.Current(); err != nil {
+		t.Fatal(err)
+	} else if user.ShadowBanned {
+		t.Error("got ShadowBanned for current user, want it omitted")
+	}
+	if user, err := apiClient.WithAuthToken(newAuthToken(2)).Users.Get("alice"); err != nil {
+		t.Fatal(err)
+	} else if !user.ShadowBanned {
+		t.Error("got !ShadowBanned for moderator's request, want it included")
+	}
+}
+
+func TestUser_SetShadowBanned(t *testing.T) {
+	setup()
+	mockAdmin(1)
+
+	Store.Users.(*datastore.MockUsersStore).GetByLogin_ = func(login string) (*thesrc.User, error) {
+		if login != "alice" {
+			return nil, thesrc.ErrUserNotFound
+		}
+		return &thesrc.User{ID: 3, Login: "alice"}, nil
+	}
+	var called bool
+	Store.Users.(*datastore.MockUsersStore).SetShadowBanned_ = func(userID int, banned bool) error {
+		called = true
+		if userID != 3 || !banned {
+			t.Errorf("got SetShadowBanned(%d, %v), want (3, true)", userID, banned)
+		}
+		return nil
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(2)).Users.SetShadowBanned("alice", true); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v shadow-banning as non-admin, want HTTP %d", err, http.StatusForbidden)
+	}
+	if called {
+		t.Fatal("called SetShadowBanned for non-admin")
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Users.SetShadowBanned("alice", true); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Error("!called")
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Users.SetShadowBanned("bob", true); !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v for nonexistent user, want HTTP %d", err, http.StatusNotFound)
+	}
+}
+
+func TestUser_UpdateSettings(t *testing.T) {
+	setup()
+
+	var updated *thesrc.UserSettings
+	Store.Users.(*datastore.MockUsersStore).UpdateSettings_ = func(userID int, settings *thesrc.UserSettings) error {
+		if userID != 1 {
+			t.Errorf("got user ID %d, want 1", userID)
+		}
+		updated = settings
+		return nil
+	}
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		user := &thesrc.User{ID: id}
+		if updated != nil {
+			user.DisplayName, user.Theme = updated.DisplayName, updated.Theme
+		}
+		return user, nil
+	}
+
+	if _, err := apiClient.WithAuthToken(newAuthToken(1)).Users.UpdateSettings(&thesrc.UserSettings{Theme: "neon", DefaultSort: "random"}); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v for invalid settings, want HTTP %d", err, http.StatusBadRequest)
+	}
+
+	user, err := apiClient.WithAuthToken(newAuthToken(1)).Users.UpdateSettings(&thesrc.UserSettings{DisplayName: " Alice ", Theme: thesrc.ThemeDark, DefaultSort: thesrc.SortNew})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&thesrc.UserSettings{DisplayName: "Alice", Theme: thesrc.ThemeDark, DefaultSort: thesrc.SortNew}); updated == nil || *updated != *want {
+		t.Errorf("got updated settings %+v, want %+v", updated, want)
+	}
+	if user.DisplayName != "Alice" || user.Theme != thesrc.ThemeDark {
+		t.Errorf("got user %+v, want updated display name and theme", user)
+	}
+}
+
+func TestUser_ChangePassword(t *testing.T) {
+	setup()
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		return &thesrc.User{ID: id, PasswordHash: hash}, nil
+	}
+	var newHash []byte
+	Store.Users.(*datastore.MockUsersStore).SetPasswordHash_ = func(userID int, hash []byte) error {
+		newHash = hash
+		return nil
+	}
+
+	c := apiClient.WithAuthToken(newAuthToken(1))
+	if _, err := c.Users.ChangePassword(&thesrc.PasswordChange{CurrentPassword: "wrong", NewPassword: "password2"}); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v for wrong current password, want HTTP %d", err, http.StatusBadRequest)
+	}
+	if _, err := c.Users.ChangePassword(&thesrc.PasswordChange{CurrentPassword: "password", NewPassword: "short"}); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v for short new password, want HTTP %d", err, http.StatusBadRequest)
+	}
+	if newHash != nil {
+		t.Fatal("password changed by invalid request")
+	}
+
+	auth, err := c.Users.ChangePassword(&thesrc.PasswordChange{CurrentPassword: "password", NewPassword: "password2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bcrypt.CompareHashAndPassword(newHash, []byte("password2")); err != nil {
+		t.Errorf("new password hash does not match new password: %s", err)
+	}
+	if userID, err := sessionUserID(auth.Token); err != nil || userID != 1 {
+		t.Errorf("got new token for user %d (error %v), want user 1", userID, err)
+	}
+
+	// The user's existing sessions are logged out.
+	if _, err := c.Users.Current(); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v with old token, want HTTP %d", err, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Versions of the HTTP API. Each version's endpoints are served under a
+// path prefix with its name (e.g., /api/v1/posts), so that a future version
+// can change the API incompatibly (e.g., its error format or pagination)
+// without breaking clients of the older versions.
+const (
+	// V1 is the first versioned API. It is the same as the original,
+	// unversioned API.
+	V1 = "v1"
+
+	// DefaultVersion is the version that unversioned paths (e.g.,
+	// /api/posts) are aliases for, unless the request's VersionHeader
+	// names another version.
+	DefaultVersion = V1
+)
+
+// VersionHeader is the HTTP header that requests to unversioned paths may
+// set to the version of the API that they want (e.g., "v1" or "1"). It is
+// ignored for versioned paths. Every API response sets it to the version
+// that served the request.
+const VersionHeader = "API-Version"
+
+// VersionedHandler returns a handler that serves each version of the API
+// under "/<version>/" (e.g., "/v1/posts"), and serves unversioned paths
+// (e.g., "/posts") with the version negotiated by the request's
+// VersionHeader. Like Handler, it must be mounted with the "/api" prefix
+// stripped. It allows cross-origin requests from CORSOrigins.
+func VersionedHandler() http.Handler {
+	versions := map[string]http.Handler{
+		V1: Handler(),
+	}
+	return handleCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := pathVersion(r.URL.Path)
+		if version != "" {
+			h, ok := versions[version]
+			if !ok {
+				writeError(w, &httpError{http.StatusNotFound, fmt.Errorf("unsupported API version %q (supported: %s)", version, V1)})
+				return
+			}
+			w.Header().Set(VersionHeader, version)
+			http.StripPrefix("/"+version, h).ServeHTTP(w, r)
+			return
+		}
+
+		version = DefaultVersion
+		if v := r.Header.Get(VersionHeader); v != "" {
+			version = v
+			if !strings.HasPrefix(version, "v") {
+				version = "v" + version
+			}
+		}
+		h, ok := versions[version]
+		if !ok {
+			writeError(w, &httpError{http.StatusBadRequest, fmt.Errorf("unsupported API version %q in %s header (supported: %s)", r.Header.Get(VersionHeader), VersionHeader, V1)})
+			return
+		}
+		w.Header().Set(VersionHeader, version)
+		h.ServeHTTP(w, r)
+	}))
+}
+
+// pathVersion returns the version that path begins with (e.g., "v1" for
+// "/v1/posts"), or "" if path has no version prefix.
+func pathVersion(path string) string {
+	seg := strings.TrimPrefix(path, "/")
+	if i := strings.Index(seg, "/"); i >= 0 {
+		seg = seg[:i]
+	}
+	if len(seg) < 2 || seg[0] != 'v' || strings.Trim(seg[1:], "0123456789") != "" {
+		return ""
+	}
+	return seg
+}
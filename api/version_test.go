@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestVersionedHandler(t *testing.T) {
+	setup()
+
+	Store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id}, nil
+	}
+
+	tests := []struct {
+		path, header string
+		wantStatus   int
+		wantVersion  string
+	}{
+		{"/api/v1/posts/1", "", http.StatusOK, V1},
+		{"/api/posts/1", "", http.StatusOK, DefaultVersion},
+		{"/api/posts/1", "1", http.StatusOK, V1},
+		{"/api/posts/1", "v1", http.StatusOK, V1},
+		{"/api/v1/posts/1", "v9", http.StatusOK, V1}, // header is ignored for versioned paths
+		{"/api/posts/1", "v9", http.StatusBadRequest, ""},
+		{"/api/v9/posts/1", "", http.StatusNotFound, ""},
+		{"/api/v1/nope", "", http.StatusNotFound, V1},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.path, nil)
+		if test.header != "" {
+			req.Header.Set(VersionHeader, test.header)
+		}
+		rw := httptest.NewRecorder()
+		serveMux.ServeHTTP(rw, req)
+		if rw.Code != test.wantStatus {
+			t.Errorf("%s (%s: %q): got HTTP %d, want %d", test.path, VersionHeader, test.header, rw.Code, test.wantStatus)
+		}
+		if got := rw.Header().Get(VersionHeader); got != test.wantVersion {
+			t.Errorf("%s (%s: %q): got %s %q, want %q", test.path, VersionHeader, test.header, VersionHeader, got, test.wantVersion)
+		}
+	}
+}
+
+func TestPathVersion(t *testing.T) {
+	tests := map[string]string{
+		"/v1/posts": "v1",
+		"/v12":      "v12",
+		"/posts":    "",
+		"/v/posts":  "",
+		"/vx/posts": "",
+		"/":         "",
+	}
+	for path, want := range tests {
+		if got := pathVersion(path); got != want {
+			t.Errorf("pathVersion(%q): got %q, want %q", path, got, want)
+		}
+	}
+}
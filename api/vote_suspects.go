@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveVoteSuspects(w http.ResponseWriter, r *http.Request) error {
+	var opt thesrc.ListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+
+	suspects, err := store(r).VoteSuspects.List(&opt)
+	if err != nil {
+		return err
+	}
+	if suspects == nil {
+		suspects = []*thesrc.VoteSuspect{}
+	}
+
+	return writeJSON(w, r, suspects)
+}
+
+func serveNullifyVoteSuspect(w http.ResponseWriter, r *http.Request) error {
+	user, err := store(r).Users.GetByLogin(mux.Vars(r)["Login"])
+	if err != nil {
+		return err
+	}
+
+	var n thesrc.VoteSuspectNullification
+	if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+		return err
+	}
+
+	if err := store(r).VoteSuspects.SetNullified(user.ID, n.Nullified); err != nil {
+		return err
+	}
+	postListCache.invalidate()
+
+	action := thesrc.AuditNullifyVotes
+	if !n.Nullified {
+		action = thesrc.AuditRestoreVotes
+	}
+	if err := audit(r, action, auditTarget("user", user.Login), ""); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveDismissVoteSuspect(w http.ResponseWriter, r *http.Request) error {
+	user, err := store(r).Users.GetByLogin(mux.Vars(r)["Login"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).VoteSuspects.Dismiss(user.ID); err != nil {
+		return err
+	}
+	postListCache.invalidate()
+
+	if err := audit(r, thesrc.AuditDismissVoteSuspect, auditTarget("user", user.Login), ""); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
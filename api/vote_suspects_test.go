@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestVoteSuspects_List(t *testing.T) {
+	setup()
+	mockModerator(1)
+
+	Store.VoteSuspects.(*datastore.MockVoteSuspectsStore).List_ = func(opt *thesrc.ListOptions) ([]*thesrc.VoteSuspect, error) {
+		return []*thesrc.VoteSuspect{{UserID: 3, Login: "carol", Reason: "r"}}, nil
+	}
+
+	if _, err := apiClient.WithAuthToken(newAuthToken(2)).VoteSuspects.List(nil); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v listing vote suspects as non-moderator, want HTTP %d", err, http.StatusForbidden)
+	}
+
+	suspects, err := apiClient.WithAuthToken(newAuthToken(1)).VoteSuspects.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suspects) != 1 || suspects[0].Login != "carol" {
+		t.Errorf("got suspects %+v, want carol", suspects)
+	}
+}
+
+func TestVoteSuspects_SetNullifiedAndDismiss(t *testing.T) {
+	setup()
+	mockModerator(1)
+
+	Store.Users.(*datastore.MockUsersStore).GetByLogin_ = func(login string) (*thesrc.User, error) {
+		if login != "carol" {
+			return nil, thesrc.ErrUserNotFound
+		}
+		return &thesrc.User{ID: 3, Login: login}, nil
+	}
+	var nullified, dismissed bool
+	Store.VoteSuspects.(*datastore.MockVoteSuspectsStore).SetNullified_ = func(userID int, n bool) error {
+		if userID != 3 {
+			t.Errorf("got user ID %d, want 3", userID)
+		}
+		nullified = n
+		return nil
+	}
+	Store.VoteSuspects.(*datastore.MockVoteSuspectsStore).Dismiss_ = func(userID int) error {
+		dismissed = userID == 3
+		return nil
+	}
+
+	client := apiClient.WithAuthToken(newAuthToken(1))
+	if err := client.VoteSuspects.SetNullified("carol", true); err != nil {
+		t.Fatal(err)
+	}
+	if !nullified {
+		t.Error("!nullified")
+	}
+	if err := client.VoteSuspects.Dismiss("carol"); err != nil {
+		t.Fatal(err)
+	}
+	if !dismissed {
+		t.Error("!dismissed")
+	}
+	if err := client.VoteSuspects.Dismiss("dave"); !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v dismissing nonexistent user, want HTTP %d", err, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveUpvote(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Votes.Upvote(userID, postID); err != nil {
+		return err
+	}
+	postListCache.invalidate()
+	recordClient(r, thesrc.ClientActionVote, userID, postID)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveUnvote(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Votes.Unvote(userID, postID); err != nil {
+		return err
+	}
+	postListCache.invalidate()
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// markVoted sets the Voted field on each post that the user r is
+// authenticated as has upvoted.
+func markVoted(r *http.Request, posts ...*thesrc.Post) error {
+	userID, err := authenticatedUserID(r)
+	if err != nil || userID == 0 {
+		return err
+	}
+
+	postIDs := make([]int, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+	voted, err := store(r).Votes.Voted(userID, postIDs)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		post.Voted = voted[post.ID]
+	}
+	return nil
+}
+
+func serveUpvoteComment(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	commentID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Votes.UpvoteComment(userID, commentID); err != nil {
+		if err == thesrc.ErrCommentNotFound {
+			return &httpError{http.StatusNotFound, err}
+		}
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveUnvoteComment(w http.ResponseWriter, r *http.Request) error {
+	userID, err := requireUserID(r)
+	if err != nil {
+		return err
+	}
+
+	commentID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Votes.UnvoteComment(userID, commentID); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// markCommentsVoted sets the Voted field on each comment that the user r is
+// authenticated as has upvoted.
+func markCommentsVoted(r *http.Request, comments ...*thesrc.Comment) error {
+	userID, err := authenticatedUserID(r)
+	if err != nil || userID == 0 {
+		return err
+	}
+
+	commentIDs := make([]int, len(comments))
+	for i, comment := range comments {
+		commentIDs[i] = comment.ID
+	}
+	voted, err := store(r).Votes.VotedComments(userID, commentIDs)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		comment.Voted = voted[comment.ID]
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestVote_Upvote(t *testing.T) {
+	setup()
+
+	calledUpvote := false
+	Store.Votes.(*datastore.MockVotesStore).Upvote_ = func(userID, postID int) error {
+		if userID != 1 || postID != 2 {
+			t.Errorf("got upvote by user %d on post %d, want user %d on post %d", userID, postID, 1, 2)
+		}
+		calledUpvote = true
+		return nil
+	}
+
+	if err := apiClient.Votes.Upvote(2); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for unauthenticated upvote, want HTTP %d", err, http.StatusUnauthorized)
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Votes.Upvote(2); err != nil {
+		t.Fatal(err)
+	}
+	if !calledUpvote {
+		t.Error("!calledUpvote")
+	}
+}
+
+func TestVote_Unvote(t *testing.T) {
+	setup()
+
+	calledUnvote := false
+	Store.Votes.(*datastore.MockVotesStore).Unvote_ = func(userID, postID int) error {
+		if userID != 1 || postID != 2 {
+			t.Errorf("got unvote by user %d on post %d, want user %d on post %d", userID, postID, 1, 2)
+		}
+		calledUnvote = true
+		return nil
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Votes.Unvote(2); err != nil {
+		t.Fatal(err)
+	}
+	if !calledUnvote {
+		t.Error("!calledUnvote")
+	}
+}
+
+func TestPosts_List_voted(t *testing.T) {
+	setup()
+
+	Store.Posts.(*thesrc.MockPostsService).List_ = func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+		return []*thesrc.Post{{ID: 1}, {ID: 2}}, nil
+	}
+	Store.Votes.(*datastore.MockVotesStore).Voted_ = func(userID int, postIDs []int) (map[int]bool, error) {
+		return map[int]bool{2: true}, nil
+	}
+
+	posts, err := apiClient.WithAuthToken(newAuthToken(1)).Posts.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posts[0].Voted || !posts[1].Voted {
+		t.Errorf("got Voted == %v, %v; want false, true", posts[0].Voted, posts[1].Voted)
+	}
+}
+
+func TestVote_UpvoteComment(t *testing.T) {
+	setup()
+
+	calledUpvote := false
+	Store.Votes.(*datastore.MockVotesStore).UpvoteComment_ = func(userID, commentID int) error {
+		if userID != 1 || commentID != 2 {
+			t.Errorf("got upvote by user %d on comment %d, want user %d on comment %d", userID, commentID, 1, 2)
+		}
+		calledUpvote = true
+		return nil
+	}
+
+	if err := apiClient.Votes.UpvoteComment(2); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("got error %v for unauthenticated upvote, want HTTP %d", err, http.StatusUnauthorized)
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Votes.UpvoteComment(2); err != nil {
+		t.Fatal(err)
+	}
+	if !calledUpvote {
+		t.Error("!calledUpvote")
+	}
+
+	Store.Votes.(*datastore.MockVotesStore).UpvoteComment_ = func(userID, commentID int) error {
+		return thesrc.ErrCommentNotFound
+	}
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Votes.UpvoteComment(3); !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v upvoting nonexistent comment, want HTTP %d", err, http.StatusNotFound)
+	}
+}
+
+func TestVote_UnvoteComment(t *testing.T) {
+	setup()
+
+	calledUnvote := false
+	Store.Votes.(*datastore.MockVotesStore).UnvoteComment_ = func(userID, commentID int) error {
+		if userID != 1 || commentID != 2 {
+			t.Errorf("got unvote by user %d on comment %d, want user %d on comment %d", userID, commentID, 1, 2)
+		}
+		calledUnvote = true
+		return nil
+	}
+
+	if err := apiClient.WithAuthToken(newAuthToken(1)).Votes.UnvoteComment(2); err != nil {
+		t.Fatal(err)
+	}
+	if !calledUnvote {
+		t.Error("!calledUnvote")
+	}
+}
+
+func TestPostComments_voted(t *testing.T) {
+	setup()
+
+	Store.Comments.(*thesrc.MockCommentsService).ListForPost_ = func(postID int) ([]*thesrc.Comment, error) {
+		return []*thesrc.Comment{{ID: 1, PostID: postID}, {ID: 2, PostID: postID}}, nil
+	}
+	Store.Votes.(*datastore.MockVotesStore).VotedComments_ = func(userID int, commentIDs []int) (map[int]bool, error) {
+		return map[int]bool{2: true}, nil
+	}
+
+	comments, err := apiClient.WithAuthToken(newAuthToken(1)).Comments.ListForPost(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comments[0].Voted || !comments[1].Voted {
+		t.Errorf("got Voted == %v, %v; want false, true", comments[0].Voted, comments[1].Voted)
+	}
+}
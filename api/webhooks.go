@@ -0,0 +1,99 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveWebhooks(w http.ResponseWriter, r *http.Request) error {
+	hooks, err := store(r).Webhooks.List()
+	if err != nil {
+		return err
+	}
+	if hooks == nil {
+		hooks = []*thesrc.Webhook{}
+	}
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+
+	return writeJSON(w, r, hooks)
+}
+
+func serveCreateWebhook(w http.ResponseWriter, r *http.Request) error {
+	var hook thesrc.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		return err
+	}
+	if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return invalidField("URL", errors.New("webhook URL must be an absolute http or https URL"))
+	}
+
+	if hook.Secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		hook.Secret = hex.EncodeToString(b)
+	}
+
+	hook.ID = 0
+	if err := store(r).Webhooks.Create(&hook); err != nil {
+		return err
+	}
+	if err := audit(r, thesrc.AuditCreateWebhook, auditTarget("webhook", hook.ID), hook.URL); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	return writeJSON(w, r, hook)
+}
+
+func serveDeleteWebhook(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := store(r).Webhooks.Delete(id); err == thesrc.ErrWebhookNotFound {
+		return &httpError{http.StatusNotFound, err}
+	} else if err != nil {
+		return err
+	}
+	if err := audit(r, thesrc.AuditDeleteWebhook, auditTarget("webhook", id), ""); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveWebhookDeliveries(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	var opt thesrc.ListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+
+	deliveries, err := store(r).Webhooks.ListDeliveries(id, &opt)
+	if err != nil {
+		return err
+	}
+	if deliveries == nil {
+		deliveries = []*thesrc.WebhookDelivery{}
+	}
+
+	return writeJSON(w, r, deliveries)
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func mockAdmin(adminID int) {
+	Store.Users.(*datastore.MockUsersStore).Get_ = func(id int) (*thesrc.User, error) {
+		if id == adminID {
+			return &thesrc.User{ID: id, Role: thesrc.RoleAdmin}, nil
+		}
+		return &thesrc.User{ID: id, Role: thesrc.RoleModerator}, nil
+	}
+}
+
+func TestWebhooks_List(t *testing.T) {
+	setup()
+	mockAdmin(1)
+
+	Store.Webhooks.(*datastore.MockWebhooksStore).List_ = func() ([]*thesrc.Webhook, error) {
+		return []*thesrc.Webhook{{ID: 1, URL: "http://example.com", Secret: "s"}}, nil
+	}
+
+	if _, err := apiClient.WithAuthToken(newAuthToken(2)).Webhooks.List(); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("got error %v listing webhooks as non-admin, want HTTP %d", err, http.StatusForbidden)
+	}
+
+	hooks, err := apiClient.WithAuthToken(newAuthToken(1)).Webhooks.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 1 || hooks[0].Secret != "" {
+		t.Errorf("got webhooks %+v, want 1 webhook without its secret", hooks)
+	}
+}
+
+func TestWebhooks_Create(t *testing.T) {
+	setup()
+	mockAdmin(1)
+
+	var created *thesrc.Webhook
+	Store.Webhooks.(*datastore.MockWebhooksStore).Create_ = func(hook *thesrc.Webhook) error {
+		hook.ID = 2
+		created = hook
+		return nil
+	}
+
+	client := apiClient.WithAuthToken(newAuthToken(1))
+	if err := client.Webhooks.Create(&thesrc.Webhook{URL: "ftp://example.com"}); !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Errorf("got error %v for invalid URL, want HTTP %d", err, http.StatusBadRequest)
+	}
+
+	hook := &thesrc.Webhook{URL: "https://example.com/hook"}
+	if err := client.Webhooks.Create(hook); err != nil {
+		t.Fatal(err)
+	}
+	if created == nil {
+		t.Fatal("webhook was not created")
+	}
+	if hook.ID != 2 || len(hook.Secret) != 64 || hook.Secret != created.Secret {
+		t.Errorf("got webhook %+v, want ID 2 and generated secret", hook)
+	}
+}
+
+func TestWebhooks_Delete(t *testing.T) {
+	setup()
+	mockAdmin(1)
+
+	Store.Webhooks.(*datastore.MockWebhooksStore).Delete_ = func(id int) error {
+		if id != 2 {
+			return thesrc.ErrWebhookNotFound
+		}
+		return nil
+	}
+
+	client := apiClient.WithAuthToken(newAuthToken(1))
+	if err := client.Webhooks.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Webhooks.Delete(3); !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v deleting nonexistent webhook, want HTTP %d", err, http.StatusNotFound)
+	}
+}
+
+func TestWebhooks_ListDeliveries(t *testing.T) {
+	setup()
+	mockAdmin(1)
+
+	want := []*thesrc.WebhookDelivery{{ID: 3, WebhookID: 2, Event: thesrc.EventPostCreated, Attempt: 1, StatusCode: 200}}
+	Store.Webhooks.(*datastore.MockWebhooksStore).ListDeliveries_ = func(webhookID int, opt *thesrc.ListOptions) ([]*thesrc.WebhookDelivery, error) {
+		if webhookID != 2 || opt.Page != 2 {
+			t.Errorf("got webhook ID %d and page %d, want 2 and 2", webhookID, opt.Page)
+		}
+		return want, nil
+	}
+
+	deliveries, err := apiClient.WithAuthToken(newAuthToken(1)).Webhooks.ListDeliveries(2, &thesrc.ListOptions{Page: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].ID != 3 || deliveries[0].StatusCode != 200 {
+		t.Errorf("got deliveries %+v, want %+v", deliveries, want)
+	}
+}
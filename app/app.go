@@ -1,27 +1,50 @@
 package app
 
 import (
-	"go/build"
-	"log"
+	"embed"
+	"errors"
+	"io/fs"
 	"os"
-	"path/filepath"
 )
 
-func defaultBase(path string) string {
-	p, err := build.Default.Import(path, "", build.FindOnly)
-	if err != nil {
-		log.Fatal(err)
-	}
+// embedded holds the default templates and static assets, so that the
+// binary is self-contained.
+//
+//go:embed tmpl static
+var embedded embed.FS
 
-	cwd, err := os.Getwd()
+// themeFS returns a file system that serves files from dir (a theme, which
+// may override only some files), falling back to the embedded defaults in
+// the embedded directory named base for files that aren't in dir. If dir is
+// "", it serves only the embedded defaults.
+func themeFS(dir, base string) fs.FS {
+	defaults, err := fs.Sub(embedded, base)
 	if err != nil {
-		log.Fatal(err)
+		panic(err)
 	}
-
-	p.Dir, err = filepath.Rel(cwd, p.Dir)
-	if err != nil {
-		log.Fatal(err)
+	if dir == "" {
+		return defaults
 	}
+	return overlayFS{os.DirFS(dir), defaults}
+}
 
-	return p.Dir
+// overlayFS opens files from fs, or from fallback if they don't exist in fs.
+type overlayFS struct {
+	fs, fallback fs.FS
 }
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.fs.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.fallback.Open(name)
+	}
+	return f, err
+}
+
+// templateFS returns the file system that templates are loaded from (see
+// TemplateDir).
+func templateFS() fs.FS { return themeFS(TemplateDir, "tmpl") }
+
+// staticFS returns the file system that static assets are served from (see
+// StaticDir).
+func staticFS() fs.FS { return themeFS(StaticDir, "static") }
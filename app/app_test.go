@@ -2,9 +2,14 @@ package app
 
 import (
 	"bytes"
+	"errors"
+	"io/fs"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/PuerkitoBio/goquery"
@@ -42,3 +47,70 @@ func getHTML(t *testing.T, uri *url.URL) (*goquery.Document, *httptest.ResponseR
 	}
 	return doc, rw
 }
+
+func doRequest(req *http.Request) *httptest.ResponseRecorder {
+	rw := httptest.NewRecorder()
+	rw.Body = new(bytes.Buffer)
+	testMux.ServeHTTP(rw, req)
+	return rw
+}
+
+// addCSRFToken adds a CSRF token to req's header and cookie, so that it
+// passes the app's CSRF check.
+func addCSRFToken(req *http.Request) {
+	req.Header.Set(csrfHeaderName, "csrf")
+	req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: "csrf"})
+}
+
+func TestThemeFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thesrc-theme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "css"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "css", "main.css"), []byte("themed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := themeFS(dir, "static")
+	if data, err := fs.ReadFile(fsys, "css/main.css"); err != nil || string(data) != "themed" {
+		t.Errorf("got overridden file %q (error %v), want %q", data, err, "themed")
+	}
+	want, err := fs.ReadFile(embedded, "static/js/live.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := fs.ReadFile(fsys, "js/live.js"); err != nil || !bytes.Equal(data, want) {
+		t.Errorf("got default file of length %d (error %v), want the embedded default", len(data), err)
+	}
+	if _, err := fs.ReadFile(fsys, "js/missing.js"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v for missing file, want fs.ErrNotExist", err)
+	}
+}
+
+func TestLoadTemplates_embedded(t *testing.T) {
+	// The embedded templates must load regardless of the working directory.
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "thesrc-cwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	if err := parseHTMLTemplates(templateSets); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckTemplates(); err != nil {
+		t.Error(err)
+	}
+}
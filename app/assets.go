@@ -0,0 +1,117 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// fingerprintLen is the number of hex digits of a static asset's content
+// hash that are included in its URL.
+const fingerprintLen = 10
+
+// assetHashes caches the fingerprints of static assets, keyed by their
+// path. RunTemplateWatcher clears it when static assets change.
+var assetHashes = struct {
+	sync.Mutex
+	m map[string]string
+}{m: map[string]string{}}
+
+// resetAssetHashes clears the cached fingerprints of static assets, so that
+// they are recomputed from the assets' current contents.
+func resetAssetHashes() {
+	assetHashes.Lock()
+	defer assetHashes.Unlock()
+	assetHashes.m = map[string]string{}
+}
+
+// assetFingerprint returns the fingerprint (a prefix of the content hash)
+// of the static asset at name (such as "css/main.css").
+func assetFingerprint(name string) (string, error) {
+	assetHashes.Lock()
+	fp, ok := assetHashes.m[name]
+	assetHashes.Unlock()
+	if ok {
+		return fp, nil
+	}
+
+	f, err := staticFS().Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	fp = hex.EncodeToString(h.Sum(nil))[:fingerprintLen]
+
+	assetHashes.Lock()
+	assetHashes.m[name] = fp
+	assetHashes.Unlock()
+	return fp, nil
+}
+
+// assetURL returns the URL path of the static asset at name, with the
+// asset's fingerprint inserted before its extension (e.g., "css/main.css"
+// becomes "/static/css/main.0123456789.css"). Because the URL changes
+// whenever the asset does, it can be cached forever (see serveStatic).
+func assetURL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	fp, err := assetFingerprint(name)
+	if err != nil {
+		log.Printf("Static asset %q: %s", name, err)
+		return "/static/" + name
+	}
+	ext := path.Ext(name)
+	return "/static/" + strings.TrimSuffix(name, ext) + "." + fp + ext
+}
+
+// splitFingerprint returns name without the fingerprint that assetURL
+// inserted, and the fingerprint, or name and "" if it has no fingerprint.
+func splitFingerprint(name string) (unfingerprinted, fp string) {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	i := strings.LastIndex(base, ".")
+	if i == -1 || len(base)-i-1 != fingerprintLen {
+		return name, ""
+	}
+	fp = base[i+1:]
+	if _, err := hex.DecodeString(fp); err != nil {
+		return name, ""
+	}
+	return base[:i] + ext, fp
+}
+
+// serveStatic serves static assets from StaticDir, falling back to the
+// embedded defaults. Requests for an asset's current fingerprinted URL (see
+// assetURL) are cached forever; requests for other URLs (including an
+// asset's previous fingerprinted URLs, which old pages may still refer to
+// during a deploy) get the current version of the asset, which caches must
+// revalidate.
+func serveStatic() http.Handler {
+	fs := http.FileServer(http.FS(staticFS()))
+	return http.StripPrefix("/static/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, fp := splitFingerprint(r.URL.Path)
+		if fp != "" {
+			if current, err := assetFingerprint(name); err == nil && current == fp {
+				w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			} else {
+				w.Header().Set("Cache-Control", "no-cache")
+			}
+			r2 := *r
+			u := *r.URL
+			u.Path = name
+			r2.URL = &u
+			r = &r2
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		fs.ServeHTTP(w, r)
+	}))
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitFingerprint(t *testing.T) {
+	tests := map[string][2]string{
+		"css/main.css":            {"css/main.css", ""},
+		"css/main.0123456789.css": {"css/main.css", "0123456789"},
+		"css/main.012345678.css":  {"css/main.012345678.css", ""},
+		"css/main.ghijklmnop.css": {"css/main.ghijklmnop.css", ""},
+		"js/a.b.0123abcdef.js":    {"js/a.b.js", "0123abcdef"},
+		"LICENSE":                 {"LICENSE", ""},
+	}
+	for name, want := range tests {
+		name0, fp := splitFingerprint(name)
+		if got := [2]string{name0, fp}; got != want {
+			t.Errorf("%q: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func withTestStaticDir(t *testing.T, files map[string]string) func() {
+	dir, err := ioutil.TempDir("", "thesrc-static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	origStaticDir := StaticDir
+	StaticDir = dir
+	resetAssetHashes()
+	return func() {
+		StaticDir = origStaticDir
+		resetAssetHashes()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAssetURL(t *testing.T) {
+	defer withTestStaticDir(t, map[string]string{"css/main.css": "body {}"})()
+
+	url := assetURL("css/main.css")
+	if name, fp := splitFingerprint(url); name != "/static/css/main.css" || fp == "" {
+		t.Errorf("got asset URL %q, want fingerprinted /static/css/main.css", url)
+	}
+
+	if got, want := assetURL("css/missing.css"), "/static/css/missing.css"; got != want {
+		t.Errorf("got asset URL %q for missing asset, want %q", got, want)
+	}
+}
+
+func TestServeStatic(t *testing.T) {
+	defer withTestStaticDir(t, map[string]string{"css/main.css": "body {}"})()
+	setup()
+	defer teardown()
+
+	tests := []struct {
+		path             string
+		wantCacheControl string
+	}{
+		{assetURL("css/main.css"), "public, max-age=31536000, immutable"},
+		{"/static/css/main.0000000000.css", "no-cache"},
+		{"/static/css/main.css", "no-cache"},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", test.path, nil)
+		rw := doRequest(req)
+		if rw.Code != http.StatusOK {
+			t.Errorf("%s: got HTTP %d, want %d", test.path, rw.Code, http.StatusOK)
+			continue
+		}
+		if got := rw.Header().Get("Cache-Control"); got != test.wantCacheControl {
+			t.Errorf("%s: got Cache-Control %q, want %q", test.path, got, test.wantCacheControl)
+		}
+		if body := rw.Body.String(); body != "body {}" {
+			t.Errorf("%s: got body %q, want %q", test.path, body, "body {}")
+		}
+	}
+}
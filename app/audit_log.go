@@ -0,0 +1,45 @@
+package app
+
+import (
+	"net/http"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveAuditLog(w http.ResponseWriter, r *http.Request) error {
+	// Admins filter by login, which the API doesn't filter entries by.
+	q := r.URL.Query()
+	login := q.Get("Login")
+	q.Del("Login")
+
+	var opt thesrc.AuditLogListOptions
+	if err := schemaDecoder.Decode(&opt, q); err != nil {
+		return err
+	}
+	if opt.PerPage == 0 {
+		opt.PerPage = 100
+	}
+	if login != "" {
+		user, err := apiClient(r).Users.Get(login)
+		if err != nil {
+			return err
+		}
+		opt.ActorUserID = user.ID
+	}
+
+	entries, err := apiClient(r).AuditLog.List(&opt)
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "admin/audit_log.html", http.StatusOK, &struct {
+		Entries []*thesrc.AuditEntry
+		Login   string
+		Options thesrc.AuditLogListOptions
+		templateCommon
+	}{
+		Entries: entries,
+		Login:   login,
+		Options: opt,
+	})
+}
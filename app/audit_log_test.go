@@ -0,0 +1,61 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestAuditLog(t *testing.T) {
+	setup()
+	defer teardown()
+
+	role := thesrc.RoleAdmin
+	APIClient = &thesrc.Client{
+		AuditLog: &thesrc.MockAuditLogService{
+			List_: func(opt *thesrc.AuditLogListOptions) ([]*thesrc.AuditEntry, error) {
+				if opt.ActorUserID != 3 || opt.Action != thesrc.AuditKillPost {
+					t.Errorf("got options %+v, want carol's kill-post entries", opt)
+				}
+				return []*thesrc.AuditEntry{{ID: 1, ActorUserID: 3, ActorLogin: "carol", Action: thesrc.AuditKillPost, Target: "post:2", Reason: "spam"}}, nil
+			},
+		},
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", Role: role}, nil
+			},
+			Get_: func(login string) (*thesrc.User, error) {
+				return &thesrc.User{ID: 3, Login: login}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.AuditLog).URL()
+	url.RawQuery = "Login=carol&Action=kill-post"
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	resp := doRequest(req)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := html.Find(".audit-reason").Text(), "spam"; got != want {
+		t.Errorf("got reason %q, want %q", got, want)
+	}
+
+	// Moderators may not view the audit log.
+	role = thesrc.RoleModerator
+	req, _ = http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	if resp := doRequest(req); resp.Code != http.StatusForbidden {
+		t.Errorf("got HTTP status %d for moderator, want %d", resp.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"net/http"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveClientRecords(w http.ResponseWriter, r *http.Request) error {
+	// Admins filter by login, which the API doesn't filter records by.
+	q := r.URL.Query()
+	login := q.Get("Login")
+	q.Del("Login")
+
+	var opt thesrc.ClientRecordListOptions
+	if err := schemaDecoder.Decode(&opt, q); err != nil {
+		return err
+	}
+	if opt.PerPage == 0 {
+		opt.PerPage = 100
+	}
+	if login != "" {
+		user, err := apiClient(r).Users.Get(login)
+		if err != nil {
+			return err
+		}
+		opt.UserID = user.ID
+	}
+
+	records, err := apiClient(r).ClientRecords.List(&opt)
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "admin/clients.html", http.StatusOK, &struct {
+		Records []*thesrc.ClientRecord
+		Login   string
+		Options thesrc.ClientRecordListOptions
+		templateCommon
+	}{
+		Records: records,
+		Login:   login,
+		Options: opt,
+	})
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestClientRecords(t *testing.T) {
+	setup()
+	defer teardown()
+
+	role := thesrc.RoleAdmin
+	APIClient = &thesrc.Client{
+		ClientRecords: &thesrc.MockClientRecordsService{
+			List_: func(opt *thesrc.ClientRecordListOptions) ([]*thesrc.ClientRecord, error) {
+				if opt.UserID != 3 || opt.IPHash != "x" {
+					t.Errorf("got options %+v, want carol's records from IP hash x", opt)
+				}
+				return []*thesrc.ClientRecord{{ID: 1, Action: thesrc.ClientActionVote, UserID: 3, Login: "carol", PostID: 2, IPHash: "x"}}, nil
+			},
+		},
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", Role: role}, nil
+			},
+			Get_: func(login string) (*thesrc.User, error) {
+				return &thesrc.User{ID: 3, Login: login}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.ClientRecords).URL()
+	url.RawQuery = "Login=carol&IPHash=x"
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	resp := doRequest(req)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := html.Find(".client-record-ip").Text(), "x"; got != want {
+		t.Errorf("got IP hash %q, want %q", got, want)
+	}
+
+	// Moderators may not view client records.
+	role = thesrc.RoleModerator
+	req, _ = http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	if resp := doRequest(req); resp.Code != http.StatusForbidden {
+		t.Errorf("got HTTP status %d for moderator, want %d", resp.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func serveCreateComment(w http.ResponseWriter, r *http.Request) error {
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	var comment thesrc.Comment
+	if err := schemaDecoder.Decode(&comment, r.PostForm); err != nil {
+		return err
+	}
+	comment.PostID = postID
+
+	if err := apiClient(r).Comments.Create(&comment); err != nil {
+		return err
+	}
+
+	postURL := urlTo(router.Post, "ID", strconv.Itoa(postID))
+	postURL.Fragment = "c" + strconv.Itoa(comment.ID)
+	http.Redirect(w, r, postURL.String(), http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestCreateComment(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Comments: &thesrc.MockCommentsService{
+			Create_: func(comment *thesrc.Comment) error {
+				if want := (thesrc.Comment{PostID: 1, ParentID: 2, Body: "b"}); *comment != want {
+					t.Errorf("got comment %+v, want %+v", comment, want)
+				}
+				called = true
+				comment.ID = 3
+				return nil
+			},
+		},
+	}
+
+	v := url.Values{
+		"ParentID": []string{"2"},
+		"Body":     []string{"b"},
+	}
+
+	url, _ := router.App().Get(router.CreateComment).URL("ID", "1")
+	req, err := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp := httptest.NewRecorder()
+	resp.Body = new(bytes.Buffer)
+	addCSRFToken(req)
+	testMux.ServeHTTP(resp, req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+
+	if !called {
+		t.Error("!called")
+	}
+
+	if loc, want := resp.Header().Get("location"), urlTo(router.Post, "ID", "1").String()+"#c3"; loc != want {
+		t.Errorf("got Location %q, want %q", loc, want)
+	}
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	htmpl "html/template"
+	"net/http"
+)
+
+// csrfCookieName is the name of the cookie that holds the browser's CSRF
+// token. State-changing requests must include the same token, in the
+// csrfFieldName form field or the csrfHeaderName header, which another site
+// can't read from the cookie and so can't include in a forged request.
+const csrfCookieName = "thesrc-csrf"
+
+const (
+	csrfFieldName  = "CSRFToken"    // form field that holds the CSRF token
+	csrfHeaderName = "X-CSRF-Token" // header that holds the CSRF token (for scripts)
+)
+
+var errCSRFToken = errors.New("invalid or missing CSRF token (reload the page and try again)")
+
+// csrfToken returns the CSRF token in r's cookie. If r has none, it
+// generates one and sets it in the cookie (on w).
+func csrfToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if c, err := r.Cookie(csrfCookieName); err == nil && c.Value != "" {
+		return c.Value, nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token, nil
+}
+
+// checkCSRFToken returns errCSRFToken if r changes state (i.e., its method
+// isn't GET, HEAD, or OPTIONS) and doesn't have the token in its CSRF
+// cookie. The token's form field is removed from r's form, so that handlers
+// can decode the form without it.
+func checkCSRFToken(r *http.Request) error {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return nil
+	}
+
+	token := r.Header.Get(csrfHeaderName)
+	if token == "" {
+		if err := r.ParseForm(); err != nil {
+			return err
+		}
+		token = r.PostForm.Get(csrfFieldName)
+		r.PostForm.Del(csrfFieldName)
+		r.Form.Del(csrfFieldName)
+	}
+
+	c, err := r.Cookie(csrfCookieName)
+	if err != nil || c.Value == "" || subtle.ConstantTimeCompare([]byte(c.Value), []byte(token)) != 1 {
+		return errCSRFToken
+	}
+	return nil
+}
+
+// csrfFuncs returns the template functions that inject token into pages:
+// {{csrfField}}, a hidden form field holding token that templates include in
+// each form that is POSTed to the app, and {{csrfToken}}, token itself (for
+// scripts that create such forms).
+func csrfFuncs(token string) htmpl.FuncMap {
+	return htmpl.FuncMap{
+		"csrfField": func() htmpl.HTML {
+			return htmpl.HTML(`<input type="hidden" name="` + csrfFieldName + `" value="` + htmpl.HTMLEscapeString(token) + `">`)
+		},
+		"csrfToken": func() string { return token },
+	}
+}
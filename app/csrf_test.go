@@ -0,0 +1,50 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCheckCSRFToken(t *testing.T) {
+	tests := []struct {
+		method, cookie, header, field string
+		wantOK                        bool
+	}{
+		{method: "GET", wantOK: true},
+		{method: "HEAD", wantOK: true},
+		{method: "POST"},
+		{method: "POST", field: "csrf"},
+		{method: "POST", cookie: "csrf"},
+		{method: "POST", cookie: "csrf", field: "other"},
+		{method: "POST", cookie: "csrf", header: "other"},
+		{method: "POST", cookie: "csrf", field: "csrf", wantOK: true},
+		{method: "POST", cookie: "csrf", header: "csrf", wantOK: true},
+		{method: "DELETE", cookie: "csrf"},
+	}
+	for _, test := range tests {
+		v := url.Values{"Title": []string{"t"}}
+		if test.field != "" {
+			v.Set(csrfFieldName, test.field)
+		}
+		req, _ := http.NewRequest(test.method, "/", strings.NewReader(v.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if test.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: test.cookie})
+		}
+		if test.header != "" {
+			req.Header.Set(csrfHeaderName, test.header)
+		}
+
+		err := checkCSRFToken(req)
+		if ok := err == nil; ok != test.wantOK {
+			t.Errorf("%+v: got error %v, want ok %v", test, err, test.wantOK)
+		}
+		if test.wantOK && test.field != "" {
+			if _, present := req.PostForm[csrfFieldName]; present || req.PostForm.Get("Title") != "t" {
+				t.Errorf("%+v: got form %v, want the token field removed", test, req.PostForm)
+			}
+		}
+	}
+}
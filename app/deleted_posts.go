@@ -0,0 +1,41 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func serveDeletedPosts(w http.ResponseWriter, r *http.Request) error {
+	posts, err := apiClient(r).Posts.List(&thesrc.PostListOptions{
+		Deleted:     true,
+		ListOptions: thesrc.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "admin/deleted_posts.html", http.StatusOK, &struct {
+		Posts []*thesrc.Post
+		templateCommon
+	}{
+		Posts: posts,
+	})
+}
+
+func serveUndeletePost(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := auditClient(r).Posts.Undelete(id); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, urlTo(router.Post, "ID", strconv.Itoa(id)).String(), http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestDeletedPosts(t *testing.T) {
+	setup()
+	defer teardown()
+
+	deletedAt := time.Now()
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if !opt.Deleted {
+					t.Error("!opt.Deleted")
+				}
+				return []*thesrc.Post{{ID: 1, Title: "t", LinkURL: "http://example.com", Domain: "example.com", DeletedAt: &deletedAt}}, nil
+			},
+		},
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", Role: thesrc.RoleAdmin}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.DeletedPosts).URL()
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	resp := doRequest(req)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := html.Find(".deleted-post-title").Text(), "t"; got != want {
+		t.Errorf("got title %q, want %q", got, want)
+	}
+}
+
+func TestUndeletePost(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", Role: thesrc.RoleAdmin}, nil
+			},
+		},
+		Posts: &thesrc.MockPostsService{
+			Undelete_: func(id int) error {
+				if id != 1 {
+					t.Errorf("got undelete of post %d, want 1", id)
+				}
+				called = true
+				return nil
+			},
+		},
+	}
+
+	v := url.Values{"Reason": []string{"deleted by mistake"}}
+	url, _ := router.App().Get(router.UndeletePost).URL("ID", "1")
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+}
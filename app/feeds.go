@@ -0,0 +1,220 @@
+package app
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// BaseURL is the absolute URL of the app's root, used to construct absolute
+// URLs in feeds.
+var BaseURL = &url.URL{Scheme: "http", Host: "thesrc.org", Path: "/"}
+
+// feedLength is the number of posts included in feeds.
+const feedLength = 30
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description,omitempty"`
+	Comments    string  `xml:"comments"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Links   []atomLink  `xml:"link"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title   string     `xml:"title"`
+	ID      string     `xml:"id"`
+	Links   []atomLink `xml:"link"`
+	Updated string     `xml:"updated"`
+	Summary string     `xml:"summary,omitempty"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+// feedPosts returns the posts to include in a feed, newest first unless the
+// "Sort" query parameter says otherwise, and only those submitted within the
+// "Period" query parameter's period (if any).
+func feedPosts(r *http.Request) ([]*thesrc.Post, string, error) {
+	sort := r.URL.Query().Get("Sort")
+	if sort == "" {
+		sort = thesrc.SortNew
+	}
+	if !thesrc.ValidSort(sort) {
+		return nil, "", fmt.Errorf("invalid sort order %q", sort)
+	}
+	period := r.URL.Query().Get("Period")
+	if !thesrc.ValidPeriod(period) {
+		return nil, "", fmt.Errorf("invalid period %q", period)
+	}
+
+	posts, err := APIClient.Posts.List(&thesrc.PostListOptions{
+		CodeOnly:    true,
+		Sort:        sort,
+		Period:      period,
+		ListOptions: thesrc.ListOptions{PerPage: feedLength},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var qualifiers []string
+	switch sort {
+	case thesrc.SortTop:
+		qualifiers = append(qualifiers, "top")
+	case thesrc.SortTrending:
+		qualifiers = append(qualifiers, "trending")
+	}
+	if thesrc.PeriodDuration(period) != 0 {
+		qualifiers = append(qualifiers, "past "+period)
+	}
+	title := "thesrc"
+	if len(qualifiers) > 0 {
+		title += " (" + strings.Join(qualifiers, ", ") + ")"
+	}
+	return posts, title, nil
+}
+
+// absURL returns the absolute URL to the named app route.
+func absURL(routeName string, params ...string) string {
+	return BaseURL.ResolveReference(urlTo(routeName, params...)).String()
+}
+
+// postLinkURL returns the URL that a post's title should link to: its link
+// URL if it has one, and otherwise its discussion page.
+func postLinkURL(post *thesrc.Post) string {
+	if post.LinkURL != "" {
+		return post.LinkURL
+	}
+	return absURL(router.Post, "ID", strconv.Itoa(post.ID))
+}
+
+// lastUpdated returns the most recent submission time of posts.
+func lastUpdated(posts []*thesrc.Post) time.Time {
+	var t time.Time
+	for _, post := range posts {
+		if post.SubmittedAt.After(t) {
+			t = post.SubmittedAt
+		}
+	}
+	return t
+}
+
+func serveRSSFeed(w http.ResponseWriter, r *http.Request) error {
+	posts, title, err := feedPosts(r)
+	if err != nil {
+		return err
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        BaseURL.String(),
+			Description: "Links for programmers",
+		},
+	}
+	if len(posts) > 0 {
+		feed.Channel.LastBuildDate = lastUpdated(posts).Format(time.RFC1123Z)
+	}
+	for _, post := range posts {
+		permalink := absURL(router.Post, "ID", strconv.Itoa(post.ID))
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       post.Title,
+			Link:        postLinkURL(post),
+			Description: post.Body,
+			Comments:    permalink,
+			GUID:        rssGUID{IsPermaLink: true, Value: permalink},
+			PubDate:     post.SubmittedAt.Format(time.RFC1123Z),
+		})
+	}
+
+	return writeXML(w, "application/rss+xml; charset=utf-8", feed)
+}
+
+func serveAtomFeed(w http.ResponseWriter, r *http.Request) error {
+	posts, title, err := feedPosts(r)
+	if err != nil {
+		return err
+	}
+
+	selfURL := BaseURL.ResolveReference(&url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery}).String()
+	feed := atomFeed{
+		Title: title,
+		ID:    selfURL,
+		Links: []atomLink{
+			{Href: BaseURL.String(), Rel: "alternate", Type: "text/html"},
+			{Href: selfURL, Rel: "self", Type: "application/atom+xml"},
+		},
+		Updated: lastUpdated(posts).UTC().Format(time.RFC3339),
+	}
+	for _, post := range posts {
+		permalink := absURL(router.Post, "ID", strconv.Itoa(post.ID))
+		entry := atomEntry{
+			Title:   post.Title,
+			ID:      permalink,
+			Links:   []atomLink{{Href: postLinkURL(post), Rel: "alternate"}},
+			Updated: post.SubmittedAt.UTC().Format(time.RFC3339),
+			Summary: post.Body,
+		}
+		if post.LinkURL != "" {
+			entry.Links = append(entry.Links, atomLink{Href: permalink, Rel: "replies", Type: "text/html"})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return writeXML(w, "application/atom+xml; charset=utf-8", feed)
+}
+
+func writeXML(w http.ResponseWriter, contentType string, v interface{}) error {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("content-type", contentType)
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+var feedTestPosts = []*thesrc.Post{
+	{ID: 1, Title: "t1", LinkURL: "http://example.com/1", Body: "b", SubmittedAt: time.Date(2014, 6, 1, 12, 0, 0, 0, time.UTC)},
+	{ID: 2, Title: "t2", SubmittedAt: time.Date(2014, 6, 2, 12, 0, 0, 0, time.UTC)},
+}
+
+func getFeed(t *testing.T, routeName, query string) *httptest.ResponseRecorder {
+	var gotOpt *thesrc.PostListOptions
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				gotOpt = opt
+				return feedTestPosts, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(routeName).URL()
+	url.RawQuery = query
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	resp := doRequest(req)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if gotOpt == nil {
+		t.Fatal("Posts.List not called")
+	}
+	return resp
+}
+
+func TestRSSFeed(t *testing.T) {
+	setup()
+	defer teardown()
+
+	resp := getFeed(t, router.RSSFeed, "")
+
+	var feed rssFeed
+	if err := xml.Unmarshal(resp.Body.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Channel.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Channel.Items))
+	}
+
+	item := feed.Channel.Items[0]
+	if want := "http://example.com/1"; item.Link != want {
+		t.Errorf("got item link %q, want %q", item.Link, want)
+	}
+	if want := "http://thesrc.org/p/1"; item.GUID.Value != want || !item.GUID.IsPermaLink {
+		t.Errorf("got item GUID %+v, want permalink %q", item.GUID, want)
+	}
+	if want := "Sun, 01 Jun 2014 12:00:00 +0000"; item.PubDate != want {
+		t.Errorf("got item pubDate %q, want %q", item.PubDate, want)
+	}
+	if want := "http://thesrc.org/p/2"; feed.Channel.Items[1].Link != want {
+		t.Errorf("got link of post without link URL %q, want %q", feed.Channel.Items[1].Link, want)
+	}
+}
+
+func TestAtomFeed(t *testing.T) {
+	setup()
+	defer teardown()
+
+	resp := getFeed(t, router.AtomFeed, "Sort=top")
+
+	var feed atomFeed
+	if err := xml.Unmarshal(resp.Body.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if want := "thesrc (top)"; feed.Title != want {
+		t.Errorf("got title %q, want %q", feed.Title, want)
+	}
+	if want := "2014-06-02T12:00:00Z"; feed.Updated != want {
+		t.Errorf("got updated %q, want %q", feed.Updated, want)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(feed.Entries))
+	}
+	if want := "http://thesrc.org/p/1"; feed.Entries[0].ID != want {
+		t.Errorf("got entry ID %q, want %q", feed.Entries[0].ID, want)
+	}
+	if want := "http://example.com/1"; feed.Entries[0].Links[0].Href != want {
+		t.Errorf("got entry link %q, want %q", feed.Entries[0].Links[0].Href, want)
+	}
+}
+
+func TestAtomFeed_period(t *testing.T) {
+	setup()
+	defer teardown()
+
+	resp := getFeed(t, router.AtomFeed, "Sort=top&Period=week")
+
+	var feed atomFeed
+	if err := xml.Unmarshal(resp.Body.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if want := "thesrc (top, past week)"; feed.Title != want {
+		t.Errorf("got title %q, want %q", feed.Title, want)
+	}
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"net/http"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func serveFollows(w http.ResponseWriter, r *http.Request) error {
+	return renderFollows(w, r, http.StatusOK, "")
+}
+
+// renderFollows renders the page listing the topics that the user follows
+// and hides.
+func renderFollows(w http.ResponseWriter, r *http.Request, status int, errMsg string) error {
+	follows, err := apiClient(r).Follows.List()
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "users/follows.html", status, &struct {
+		Follows []*thesrc.Follow
+		Error   string
+		templateCommon
+	}{
+		Follows: follows,
+		Error:   errMsg,
+	})
+}
+
+func serveFollow(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	follow := &thesrc.Follow{
+		Kind: r.PostForm.Get("Kind"),
+		Name: r.PostForm.Get("Name"),
+		Hide: r.PostForm.Get("Hide") == "true",
+	}
+	err := apiClient(r).Follows.Follow(follow)
+	if thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		return renderFollows(w, r, http.StatusBadRequest, "Enter a valid tag or domain.")
+	} else if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, localReferer(r, urlTo(router.Follows)).String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveUnfollow(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	if err := apiClient(r).Follows.Unfollow(r.PostForm.Get("Kind"), r.PostForm.Get("Name")); err != nil && !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		return err
+	}
+
+	http.Redirect(w, r, localReferer(r, urlTo(router.Follows)).String(), http.StatusSeeOther)
+	return nil
+}
+
+// topicFollow returns the user's follow of the tag or domain (whichever is
+// non-empty) being listed, or nil if the user doesn't follow or hide it (or
+// isn't logged in).
+func topicFollow(r *http.Request, tag, domain string) (*thesrc.Follow, error) {
+	if sessionToken(r) == "" || (tag == "" && domain == "") {
+		return nil, nil
+	}
+
+	follows, err := apiClient(r).Follows.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range follows {
+		if f.Kind == thesrc.FollowTag && f.Name == tag || f.Kind == thesrc.FollowDomain && f.Name == domain {
+			return f, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestFollow(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var followed *thesrc.Follow
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+		},
+		Follows: &thesrc.MockFollowsService{
+			Follow_: func(follow *thesrc.Follow) error {
+				followed = follow
+				return nil
+			},
+		},
+	}
+
+	v := url.Values{"Kind": []string{"domain"}, "Name": []string{"example.com"}, "Hide": []string{"true"}}
+	url, _ := router.App().Get(router.Follow).URL()
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if want := (&thesrc.Follow{Kind: thesrc.FollowDomain, Name: "example.com", Hide: true}); followed == nil || *followed != *want {
+		t.Errorf("got followed %+v, want %+v", followed, want)
+	}
+}
+
+func TestTagPosts_followed(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+		},
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				return []*thesrc.Post{{ID: 1, Title: "t", Tags: []string{"golang"}}}, nil
+			},
+		},
+		Follows: &thesrc.MockFollowsService{
+			List_: func() ([]*thesrc.Follow, error) {
+				return []*thesrc.Follow{{Kind: thesrc.FollowDomain, Name: "golang"}, {Kind: thesrc.FollowTag, Name: "golang", Hide: true}}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.TagPosts).URL("Tag", "golang")
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	resp := doRequest(req)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := html.Find(".follow-topic button").Text(), "unhide"; got != want {
+		t.Errorf("got follow button %q, want %q", got, want)
+	}
+}
@@ -1,14 +1,12 @@
 package app
 
 import (
-	"net/url"
-	"strings"
+	htmpl "html/template"
+
+	"sourcegraph.com/sourcegraph/thesrc/markdown"
 )
 
-func urlDomain(urlStr string) string {
-	url, err := url.Parse(urlStr)
-	if err != nil {
-		return "invalid URL"
-	}
-	return strings.TrimPrefix(url.Host, "www.")
+// renderMarkdown renders a post or comment body as sanitized HTML.
+func renderMarkdown(src string) htmpl.HTML {
+	return htmpl.HTML(markdown.HTML(src))
 }
@@ -1,26 +1,38 @@
 package app
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"path/filepath"
 	"runtime/debug"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
 	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
 	"sourcegraph.com/sourcegraph/thesrc/router"
+	"sourcegraph.com/sourcegraph/thesrc/tracing"
 )
 
 var (
-	// ReloadTemplates is whether to reload templates on each request.
-	ReloadTemplates bool
+	// StaticDir is a directory of static assets (a theme) that override the
+	// embedded default static assets with the same paths. If it is "", only
+	// the default static assets are served.
+	StaticDir string
 
-	// StaticDir is the directory containing static assets.
-	StaticDir = filepath.Join(defaultBase("sourcegraph.com/sourcegraph/thesrc/app"), "static")
+	// TrustProxyHeaders is whether to use the X-Forwarded-For header (instead
+	// of the connection's remote address) as the IP address of the end user
+	// that the app forwards to the API (see thesrc.Client.WithForwardedClient).
+	// Only enable it when the app is served behind a proxy that sets it.
+	TrustProxyHeaders = false
+)
+
+var (
+	// ReadOnly reports whether the site is in read-only mode (see
+	// api.SetReadOnly), in which the app shows a banner and rejects form
+	// posts. If nil, the site is never read-only.
+	ReadOnly func() bool
 )
 
 var (
@@ -31,25 +43,101 @@ var (
 
 func Handler() *mux.Router {
 	m := appRouter
-	m.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir(StaticDir))))
+	m.PathPrefix("/static/").Handler(serveStatic())
 	// TODO(sqs): add handlers for /favicon.ico and /robots.txt
 	m.Get(router.Post).Handler(handler(servePost))
 	m.Get(router.Posts).Handler(handler(servePosts))
+	m.Get(router.NewPosts).Handler(handler(servePosts))
+	m.Get(router.TopPosts).Handler(handler(servePosts))
+	m.Get(router.BestPosts).Handler(handler(servePosts))
+	m.Get(router.TrendingPosts).Handler(handler(servePosts))
+	m.Get(router.ShowPosts).Handler(handler(servePosts))
+	m.Get(router.SearchPosts).Handler(handler(servePosts))
+	m.Get(router.TagPosts).Handler(handler(servePosts))
+	m.Get(router.DomainPosts).Handler(handler(servePosts))
 	m.Get(router.SubmitPostForm).Handler(handler(serveSubmitPostForm))
 	m.Get(router.SubmitPost).Handler(handler(serveSubmitPost))
+	m.Get(router.EditPostForm).Handler(handler(serveEditPostForm))
+	m.Get(router.UpdatePost).Handler(handler(serveUpdatePost))
+	m.Get(router.DeletePost).Handler(handler(serveDeletePost))
+	m.Get(router.FlagPost).Handler(handler(serveFlagPost))
+	m.Get(router.ReportPostForm).Handler(handler(serveReportPostForm))
+	m.Get(router.ReportPost).Handler(handler(serveReportPost))
+	m.Get(router.PostReported).Handler(handler(servePostReported))
+	m.Get(router.ModeratePost).Handler(requireRole(thesrc.RoleModerator, serveModeratePost))
+	m.Get(router.PostRevisions).Handler(requireRole(thesrc.RoleModerator, servePostRevisions))
+	m.Get(router.Moderation).Handler(requireRole(thesrc.RoleModerator, serveModeration))
+	m.Get(router.BulkModerate).Handler(requireRole(thesrc.RoleModerator, serveBulkModerate))
+	m.Get(router.NullifyVoteSuspect).Handler(requireRole(thesrc.RoleModerator, serveNullifyVoteSuspect))
+	m.Get(router.DismissVoteSuspect).Handler(requireRole(thesrc.RoleModerator, serveDismissVoteSuspect))
+	m.Get(router.SavePost).Handler(handler(serveSavePost))
+	m.Get(router.UnsavePost).Handler(handler(serveUnsavePost))
+	m.Get(router.HidePost).Handler(handler(serveHidePost))
+	m.Get(router.UnhidePost).Handler(handler(serveUnhidePost))
+	m.Get(router.SavedPosts).Handler(requireRole(thesrc.RoleMember, serveSavedPosts))
+	m.Get(router.CreateComment).Handler(handler(serveCreateComment))
+	m.Get(router.UpvoteComment).Handler(handler(serveUpvoteComment))
+	m.Get(router.UnvoteComment).Handler(handler(serveUnvoteComment))
+	m.Get(router.Upvote).Handler(handler(serveUpvote))
+	m.Get(router.Unvote).Handler(handler(serveUnvote))
+	m.Get(router.SignupForm).Handler(handler(serveSignupForm))
+	m.Get(router.Signup).Handler(handler(serveSignup))
+	m.Get(router.LogInForm).Handler(handler(serveLogInForm))
+	m.Get(router.LogIn).Handler(handler(serveLogIn))
+	m.Get(router.LogOut).Handler(handler(serveLogOut))
+	m.Get(router.ForgotPasswordForm).Handler(handler(serveForgotPasswordForm))
+	m.Get(router.RequestPasswordReset).Handler(handler(serveRequestPasswordReset))
+	m.Get(router.ResetPasswordForm).Handler(handler(serveResetPasswordForm))
+	m.Get(router.ResetPassword).Handler(handler(serveResetPassword))
+	m.Get(router.AuthenticateTwoFactor).Handler(handler(serveAuthenticateTwoFactor))
+	m.Get(router.RSSFeed).Handler(handler(serveRSSFeed))
+	m.Get(router.AtomFeed).Handler(handler(serveAtomFeed))
+	m.Get(router.Sitemap).Handler(handler(serveSitemap))
+	m.Get(router.SitemapPage).Handler(handler(serveSitemapPage))
+	m.Get(router.User).Handler(handler(serveUser))
+	m.Get(router.ShadowBanUser).Handler(requireRole(thesrc.RoleAdmin, serveShadowBanUser))
+	m.Get(router.Jobs).Handler(requireRole(thesrc.RoleAdmin, serveJobs))
+	m.Get(router.ClientRecords).Handler(requireRole(thesrc.RoleAdmin, serveClientRecords))
+	m.Get(router.AuditLog).Handler(requireRole(thesrc.RoleAdmin, serveAuditLog))
+	m.Get(router.DeletedPosts).Handler(requireRole(thesrc.RoleAdmin, serveDeletedPosts))
+	m.Get(router.UndeletePost).Handler(requireRole(thesrc.RoleAdmin, serveUndeletePost))
+	m.Get(router.RetryJob).Handler(requireRole(thesrc.RoleAdmin, serveRetryJob))
+	m.Get(router.Notifications).Handler(requireRole(thesrc.RoleMember, serveNotifications))
+	m.Get(router.MarkNotificationRead).Handler(requireRole(thesrc.RoleMember, serveMarkNotificationRead))
+	m.Get(router.MarkAllNotificationsRead).Handler(requireRole(thesrc.RoleMember, serveMarkAllNotificationsRead))
+	m.Get(router.TwoFactor).Handler(requireRole(thesrc.RoleMember, serveTwoFactor))
+	m.Get(router.SetUpTwoFactor).Handler(requireRole(thesrc.RoleMember, serveSetUpTwoFactor))
+	m.Get(router.EnableTwoFactor).Handler(requireRole(thesrc.RoleMember, serveEnableTwoFactor))
+	m.Get(router.DisableTwoFactor).Handler(requireRole(thesrc.RoleMember, serveDisableTwoFactor))
+	m.Get(router.RegenerateRecoveryCodes).Handler(requireRole(thesrc.RoleMember, serveRegenerateRecoveryCodes))
+	m.Get(router.Sessions).Handler(requireRole(thesrc.RoleMember, serveSessions))
+	m.Get(router.RevokeSession).Handler(requireRole(thesrc.RoleMember, serveRevokeSession))
+	m.Get(router.Tokens).Handler(requireRole(thesrc.RoleMember, serveTokens))
+	m.Get(router.CreateToken).Handler(requireRole(thesrc.RoleMember, serveCreateToken))
+	m.Get(router.RevokeToken).Handler(requireRole(thesrc.RoleMember, serveRevokeToken))
+	m.Get(router.Follows).Handler(requireRole(thesrc.RoleMember, serveFollows))
+	m.Get(router.Follow).Handler(requireRole(thesrc.RoleMember, serveFollow))
+	m.Get(router.Unfollow).Handler(requireRole(thesrc.RoleMember, serveUnfollow))
+	m.Get(router.SetTheme).Handler(handler(serveSetTheme))
+	m.Get(router.Settings).Handler(requireRole(thesrc.RoleMember, serveSettings))
+	m.Get(router.UpdateUserSettings).Handler(requireRole(thesrc.RoleMember, serveUpdateUserSettings))
+	m.Get(router.ChangePassword).Handler(requireRole(thesrc.RoleMember, serveChangePassword))
+	metrics.InstrumentRoutes("app", m)
 	return m
 }
 
 type handler func(resp http.ResponseWriter, req *http.Request) error
 
 func (h handler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
-	if ReloadTemplates {
-		LoadTemplates()
-	}
 	runHandler(resp, req, h)
 }
 
 func runHandler(w http.ResponseWriter, r *http.Request, fn func(http.ResponseWriter, *http.Request) error) {
+	if route := mux.CurrentRoute(r); route != nil {
+		logging.SetRoute(r, route.GetName())
+		tracing.SetRoute(r, route.GetName())
+	}
+
 	var err error
 
 	defer func() {
@@ -60,6 +148,18 @@ func runHandler(w http.ResponseWriter, r *http.Request, fn func(http.ResponseWri
 		}
 	}()
 
+	// Reject forged form posts (and other state-changing requests) from
+	// other sites.
+	if err := checkCSRFToken(r); err != nil {
+		handleError(w, r, http.StatusForbidden, err)
+		return
+	}
+
+	if isReadOnly() && r.Method != "GET" && r.Method != "HEAD" {
+		handleError(w, r, http.StatusServiceUnavailable, errReadOnly)
+		return
+	}
+
 	err = fn(w, r)
 	if err != nil {
 		logError(r, err, nil)
@@ -67,6 +167,10 @@ func runHandler(w http.ResponseWriter, r *http.Request, fn func(http.ResponseWri
 	}
 }
 
+var errReadOnly = errors.New("thesrc is in read-only mode for maintenance, so changes can't be saved right now; try again later")
+
+func isReadOnly() bool { return ReadOnly != nil && ReadOnly() }
+
 func handleError(w http.ResponseWriter, r *http.Request, status int, err error) {
 	w.Header().Set("cache-control", "no-cache")
 	err2 := renderTemplate(w, r, "error.html", status, &struct {
@@ -86,12 +190,10 @@ func handleError(w http.ResponseWriter, r *http.Request, status int, err error)
 
 func logError(req *http.Request, err error, rv interface{}) {
 	if err != nil {
-		var buf bytes.Buffer
-		fmt.Fprintf(&buf, "Error serving %s (route %s): %s\n", req.URL, mux.CurrentRoute(req).GetName(), err)
+		keyvals := []interface{}{"url", req.URL.String(), "route", mux.CurrentRoute(req).GetName(), "error", err}
 		if rv != nil {
-			fmt.Fprintln(&buf, rv)
-			buf.Write(debug.Stack())
+			keyvals = append(keyvals, "panic", fmt.Sprint(rv), "stack", string(debug.Stack()))
 		}
-		log.Print(buf.String())
+		logging.FromContext(req.Context()).Log("app error", keyvals...)
 	}
 }
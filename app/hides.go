@@ -0,0 +1,42 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func serveHidePost(w http.ResponseWriter, r *http.Request) error {
+	return serveHide(w, r, true)
+}
+
+func serveUnhidePost(w http.ResponseWriter, r *http.Request) error {
+	return serveHide(w, r, false)
+}
+
+func serveHide(w http.ResponseWriter, r *http.Request, hide bool) error {
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if sessionToken(r) == "" {
+		http.Redirect(w, r, urlTo(router.LogInForm).String(), http.StatusSeeOther)
+		return nil
+	}
+
+	posts := apiClient(r).Posts
+	if hide {
+		err = posts.Hide(postID)
+	} else {
+		err = posts.Unhide(postID)
+	}
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, localReferer(r, urlTo(router.Post, "ID", strconv.Itoa(postID))).String(), http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestHidePost(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var hidden, unhidden bool
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Hide_:   func(id int) error { hidden = id == 1; return nil },
+			Unhide_: func(id int) error { unhidden = id == 1; return nil },
+		},
+	}
+
+	for _, route := range []string{router.HidePost, router.UnhidePost} {
+		url, _ := router.App().Get(route).URL("ID", "1")
+		req, _ := http.NewRequest("POST", url.String(), nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+		addCSRFToken(req)
+		if resp := doRequest(req); resp.Code != http.StatusSeeOther {
+			t.Errorf("%s: got HTTP status %d, want %d", route, resp.Code, http.StatusSeeOther)
+		}
+	}
+	if !hidden || !unhidden {
+		t.Errorf("got hidden %v and unhidden %v, want both", hidden, unhidden)
+	}
+}
+
+func TestHidePost_readOnly(t *testing.T) {
+	setup()
+	defer teardown()
+	ReadOnly = func() bool { return true }
+	defer func() { ReadOnly = nil }()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) { return nil, nil },
+			Hide_: func(id int) error {
+				t.Error("post was hidden in read-only mode")
+				return nil
+			},
+		},
+	}
+
+	url_, _ := router.App().Get(router.Posts).URL()
+	html, resp := getHTML(t, url_)
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if html.Find(".read-only-banner").Length() != 1 {
+		t.Error("no read-only banner on home page")
+	}
+
+	url_, _ = router.App().Get(router.HidePost).URL("ID", "1")
+	req, _ := http.NewRequest("POST", url_.String(), nil)
+	addCSRFToken(req)
+	if resp := doRequest(req); resp.Code != http.StatusServiceUnavailable {
+		t.Errorf("got HTTP status %d hiding post in read-only mode, want %d", resp.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// jobStates are the job states that the jobs page can filter by.
+var jobStates = []string{thesrc.JobFailed, thesrc.JobPending, thesrc.JobRunning, thesrc.JobDone}
+
+func serveJobs(w http.ResponseWriter, r *http.Request) error {
+	// Show failed jobs (which may need to be retried) by default.
+	state := r.URL.Query().Get("State")
+	if state == "" {
+		state = thesrc.JobFailed
+	}
+
+	jobs, err := apiClient(r).Jobs.List(&thesrc.JobListOptions{
+		State:       state,
+		ListOptions: thesrc.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "admin/jobs.html", http.StatusOK, &struct {
+		Jobs   []*thesrc.Job
+		State  string
+		States []string
+		templateCommon
+	}{
+		Jobs:   jobs,
+		State:  state,
+		States: jobStates,
+	})
+}
+
+func serveRetryJob(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := auditClient(r).Jobs.Retry(id); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, localReferer(r, urlTo(router.Jobs)).String(), http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestJobs(t *testing.T) {
+	setup()
+	defer teardown()
+
+	role := thesrc.RoleAdmin
+	APIClient = &thesrc.Client{
+		Jobs: &thesrc.MockJobsService{
+			List_: func(opt *thesrc.JobListOptions) ([]*thesrc.Job, error) {
+				if opt.State != thesrc.JobFailed {
+					t.Errorf("got State %q, want %q", opt.State, thesrc.JobFailed)
+				}
+				return []*thesrc.Job{{ID: 1, Kind: "webhook", State: thesrc.JobFailed, Attempts: 5, MaxAttempts: 5, LastError: "webhook responded with HTTP 500"}}, nil
+			},
+		},
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", Role: role}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.Jobs).URL()
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	resp := doRequest(req)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := html.Find(".job-error").Text(), "webhook responded with HTTP 500"; got != want {
+		t.Errorf("got job error %q, want %q", got, want)
+	}
+	if html.Find(".jobs form button").Length() != 1 {
+		t.Error("want a retry button for the failed job")
+	}
+
+	// Moderators may not view jobs.
+	role = thesrc.RoleModerator
+	req, _ = http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	if resp := doRequest(req); resp.Code != http.StatusForbidden {
+		t.Errorf("got HTTP status %d for moderator, want %d", resp.Code, http.StatusForbidden)
+	}
+}
+
+func TestRetryJob(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", Role: thesrc.RoleAdmin}, nil
+			},
+		},
+		Jobs: &thesrc.MockJobsService{
+			Retry_: func(id int) error {
+				if id != 1 {
+					t.Errorf("got retry of job %d, want 1", id)
+				}
+				called = true
+				return nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.RetryJob).URL("ID", "1")
+	req, _ := http.NewRequest("POST", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func serveFlagPost(w http.ResponseWriter, r *http.Request) error {
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if sessionToken(r) == "" {
+		http.Redirect(w, r, urlTo(router.LogInForm).String(), http.StatusSeeOther)
+		return nil
+	}
+
+	if err := apiClient(r).Posts.Flag(postID); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, localReferer(r, urlTo(router.Post, "ID", strconv.Itoa(postID))).String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveModeratePost(w http.ResponseWriter, r *http.Request) error {
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	client := auditClient(r)
+	var mod thesrc.PostModeration
+	if err := schemaDecoder.Decode(&mod, r.PostForm); err != nil {
+		return err
+	}
+
+	if err := client.Posts.Moderate(postID, &mod); errors.Is(err, thesrc.ErrConflict) {
+		handleError(w, r, http.StatusConflict, errors.New("someone else changed this post since you loaded the page; go back, reload it, and try again"))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, localReferer(r, urlTo(router.Post, "ID", strconv.Itoa(postID))).String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveBulkModerate(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	client := auditClient(r)
+	tags := r.PostForm.Get("Tags")
+	r.PostForm.Del("Tags")
+	var mod thesrc.BulkModeration
+	if err := schemaDecoder.Decode(&mod, r.PostForm); err != nil {
+		return err
+	}
+	mod.Tags = thesrc.SplitTags(tags)
+
+	if len(mod.PostIDs) > 0 || len(mod.CommentIDs) > 0 {
+		if err := client.Moderation.Bulk(&mod); err != nil {
+			return err
+		}
+	}
+
+	http.Redirect(w, r, localReferer(r, urlTo(router.Moderation)).String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveModeration(w http.ResponseWriter, r *http.Request) error {
+	posts, err := apiClient(r).Posts.List(&thesrc.PostListOptions{
+		Flagged:     true,
+		Sort:        thesrc.SortNew,
+		ListOptions: thesrc.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Show each post's recent reports under it.
+	reports, err := apiClient(r).Reports.List(&thesrc.ReportListOptions{ListOptions: thesrc.ListOptions{PerPage: 100}})
+	if err != nil {
+		return err
+	}
+	reportsByPost := map[int][]*thesrc.Report{}
+	for _, report := range reports {
+		reportsByPost[report.PostID] = append(reportsByPost[report.PostID], report)
+	}
+
+	suspects, err := apiClient(r).VoteSuspects.List(&thesrc.ListOptions{PerPage: 100})
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "posts/moderation.html", http.StatusOK, &struct {
+		Posts        []*thesrc.Post
+		Reports      map[int][]*thesrc.Report // keyed by post ID
+		VoteSuspects []*thesrc.VoteSuspect
+		templateCommon
+	}{
+		Posts:        posts,
+		Reports:      reportsByPost,
+		VoteSuspects: suspects,
+	})
+}
+
+func serveNullifyVoteSuspect(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	client := auditClient(r)
+	var n thesrc.VoteSuspectNullification
+	if err := schemaDecoder.Decode(&n, r.PostForm); err != nil {
+		return err
+	}
+
+	if err := client.VoteSuspects.SetNullified(mux.Vars(r)["Login"], n.Nullified); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, localReferer(r, urlTo(router.Moderation)).String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveDismissVoteSuspect(w http.ResponseWriter, r *http.Request) error {
+	if err := auditClient(r).VoteSuspects.Dismiss(mux.Vars(r)["Login"]); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, localReferer(r, urlTo(router.Moderation)).String(), http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,266 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestFlagPost(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Flag_: func(id int) error {
+				if id != 1 {
+					t.Errorf("got flag of post %d, want 1", id)
+				}
+				called = true
+				return nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.FlagPost).URL("ID", "1")
+	req, _ := http.NewRequest("POST", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+}
+
+func TestModeratePost(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", Role: thesrc.RoleModerator}, nil
+			},
+		},
+		Posts: &thesrc.MockPostsService{
+			Moderate_: func(id int, mod *thesrc.PostModeration) error {
+				if want := (thesrc.PostModeration{Hidden: true}); id != 1 || *mod != want {
+					t.Errorf("got moderation of post %d to %+v, want post 1 and %+v", id, mod, want)
+				}
+				called = true
+				return nil
+			},
+		},
+	}
+
+	// The reason is sent to the API for the audit log, not decoded with the
+	// moderation.
+	v := url.Values{"Hidden": []string{"true"}, "Dead": []string{"false"}, "Reason": []string{"off-topic"}}
+	url, _ := router.App().Get(router.ModeratePost).URL("ID", "1")
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+}
+
+func TestBulkModerate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", Role: thesrc.RoleModerator}, nil
+			},
+		},
+		Moderation: &thesrc.MockModerationService{
+			Bulk_: func(mod *thesrc.BulkModeration) error {
+				want := &thesrc.BulkModeration{Action: thesrc.BulkRetag, PostIDs: []int{1, 2}, Tags: []string{"go", "rust"}}
+				if !reflect.DeepEqual(mod, want) {
+					t.Errorf("got bulk moderation %+v, want %+v", mod, want)
+				}
+				called = true
+				return nil
+			},
+		},
+	}
+
+	v := url.Values{"Action": []string{"retag"}, "PostIDs": []string{"1", "2"}, "Tags": []string{"go, rust"}, "Reason": []string{"mistagged"}}
+	url, _ := router.App().Get(router.BulkModerate).URL()
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	if loc, want := resp.Header().Get("location"), urlTo(router.Moderation).String(); loc != want {
+		t.Errorf("got Location %q, want %q", loc, want)
+	}
+}
+
+func TestModeration(t *testing.T) {
+	setup()
+	defer teardown()
+
+	role := thesrc.RoleModerator
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if !opt.Flagged {
+					t.Error("!opt.Flagged")
+				}
+				return []*thesrc.Post{{ID: 1, Title: "t", LinkURL: "http://example.com", Flags: 3, Reports: 1, Hidden: true}}, nil
+			},
+		},
+		VoteSuspects: &thesrc.MockVoteSuspectsService{
+			List_: func(opt *thesrc.ListOptions) ([]*thesrc.VoteSuspect, error) {
+				return []*thesrc.VoteSuspect{{UserID: 2, Login: "bob", Reason: "ring: voted for 3 posts by carol, who voted for 3 of theirs", Nullified: true}}, nil
+			},
+		},
+		Reports: &thesrc.MockReportsService{
+			List_: func(opt *thesrc.ReportListOptions) ([]*thesrc.Report, error) {
+				return []*thesrc.Report{{ID: 2, PostID: 1, Category: thesrc.ReportSpam, Details: "ads"}}, nil
+			},
+		},
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", Role: role}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.Moderation).URL()
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	resp := doRequest(req)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := html.Find(".post-status").Text(), "[hidden]"; got != want {
+		t.Errorf("got post status %q, want %q", got, want)
+	}
+	if got, want := html.Find(".flag-count").Text(), "3 flags"; got != want {
+		t.Errorf("got flag count %q, want %q", got, want)
+	}
+	if got, want := html.Find("input.bulk-select[form=bulk-moderation]").AttrOr("value", ""), "1"; got != want {
+		t.Errorf("got bulk moderation checkbox value %q, want %q", got, want)
+	}
+	if got, want := html.Find(".report-count").Text(), "1 report"; got != want {
+		t.Errorf("got report count %q, want %q", got, want)
+	}
+	if got, want := html.Find("ul.reports .report-category").Text(), thesrc.ReportSpam; got != want {
+		t.Errorf("got report category %q, want %q", got, want)
+	}
+	if got, want := html.Find(".vote-suspect-reason").Text(), "ring: voted for 3 posts by carol, who voted for 3 of theirs"; got != want {
+		t.Errorf("got vote suspect reason %q, want %q", got, want)
+	}
+	if got, want := html.Find(".vote-suspects input[name=Nullified]").AttrOr("value", ""), "false"; got != want {
+		t.Errorf("got Nullified form value %q, want %q (to restore the votes)", got, want)
+	}
+
+	// Members may not view the moderation queue.
+	role = thesrc.RoleMember
+	req, _ = http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	if resp := doRequest(req); resp.Code != http.StatusForbidden {
+		t.Errorf("got HTTP status %d for member, want %d", resp.Code, http.StatusForbidden)
+	}
+}
+
+func TestModeratePost_member(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", Role: thesrc.RoleMember}, nil
+			},
+		},
+		Posts: &thesrc.MockPostsService{
+			Moderate_: func(id int, mod *thesrc.PostModeration) error {
+				t.Error("unexpected call to Moderate")
+				return nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.ModeratePost).URL("ID", "1")
+	req, _ := http.NewRequest("POST", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	if resp := doRequest(req); resp.Code != http.StatusForbidden {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, http.StatusForbidden)
+	}
+}
+
+func TestNullifyVoteSuspect(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", Role: thesrc.RoleModerator}, nil
+			},
+		},
+		VoteSuspects: &thesrc.MockVoteSuspectsService{
+			SetNullified_: func(login string, nullified bool) error {
+				if login != "bob" || !nullified {
+					t.Errorf("got SetNullified(%q, %v), want bob and true", login, nullified)
+				}
+				called = true
+				return nil
+			},
+		},
+	}
+
+	v := url.Values{"Nullified": []string{"true"}}
+	url, _ := router.App().Get(router.NullifyVoteSuspect).URL("Login", "bob")
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func serveNotifications(w http.ResponseWriter, r *http.Request) error {
+	var opt thesrc.NotificationListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+	if opt.PerPage == 0 {
+		opt.PerPage = 60
+	}
+
+	notifications, err := apiClient(r).Notifications.List(&opt)
+	if err != nil {
+		return err
+	}
+
+	var nextPageURL *url.URL
+	if len(notifications) >= opt.PerPage {
+		nextPageURL = &url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		q := nextPageURL.Query()
+		q.Set("Page", strconv.Itoa(opt.PageOrDefault()+1))
+		nextPageURL.RawQuery = q.Encode()
+	}
+
+	return renderTemplate(w, r, "users/notifications.html", http.StatusOK, &struct {
+		Notifications []*thesrc.Notification
+		NextPageURL   *url.URL
+		templateCommon
+	}{
+		Notifications: notifications,
+		NextPageURL:   nextPageURL,
+	})
+}
+
+func serveMarkNotificationRead(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := apiClient(r).Notifications.MarkRead(id); err != nil && !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		return err
+	}
+
+	http.Redirect(w, r, localReferer(r, urlTo(router.Notifications)).String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveMarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) error {
+	if err := apiClient(r).Notifications.MarkAllRead(); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, urlTo(router.Notifications).String(), http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestNotifications(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", UnreadNotifications: 1}, nil
+			},
+		},
+		Notifications: &thesrc.MockNotificationsService{
+			List_: func(opt *thesrc.NotificationListOptions) ([]*thesrc.Notification, error) {
+				return []*thesrc.Notification{
+					{ID: 3, Type: thesrc.NotificationReply, ActorLogin: "bob", PostID: 2, PostTitle: "t", CommentID: 4},
+					{ID: 5, Type: thesrc.NotificationMention, ActorLogin: "carol", PostID: 2, PostTitle: "t", Read: true},
+				}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.Notifications).URL()
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	resp := doRequest(req)
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := html.Find("li.notification").Length(); n != 2 {
+		t.Errorf("got %d notifications, want 2", n)
+	}
+	if n := html.Find("li.notification.unread").Length(); n != 1 {
+		t.Errorf("got %d unread notifications, want 1", n)
+	}
+	if href, _ := html.Find("li.notification.unread a").Eq(1).Attr("href"); href != "/p/2#c4" {
+		t.Errorf("got reply link %q, want %q", href, "/p/2#c4")
+	}
+	if count := html.Find("nav .unread-count").Text(); count != "1" {
+		t.Errorf("got unread count %q in header, want %q", count, "1")
+	}
+}
+
+func TestMarkNotificationsRead(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var marked, markedAll bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+		},
+		Notifications: &thesrc.MockNotificationsService{
+			MarkRead_:    func(id int) error { marked = id == 3; return nil },
+			MarkAllRead_: func() error { markedAll = true; return nil },
+		},
+	}
+
+	for _, u := range []string{urlTo(router.MarkNotificationRead, "ID", "3").String(), urlTo(router.MarkAllNotificationsRead).String()} {
+		req, _ := http.NewRequest("POST", u, nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+		addCSRFToken(req)
+		if resp := doRequest(req); resp.Code != http.StatusSeeOther {
+			t.Errorf("%s: got HTTP status %d, want %d", u, resp.Code, http.StatusSeeOther)
+		}
+	}
+	if !marked || !markedAll {
+		t.Errorf("got marked %v and marked all %v, want both", marked, markedAll)
+	}
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"net/http"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func serveForgotPasswordForm(w http.ResponseWriter, r *http.Request) error {
+	return renderForgotPasswordForm(w, r, http.StatusOK, "", false, "")
+}
+
+// renderForgotPasswordForm renders the form for requesting a password reset
+// email, or (if sent) a page saying that it was sent.
+func renderForgotPasswordForm(w http.ResponseWriter, r *http.Request, status int, login string, sent bool, errMsg string) error {
+	return renderTemplate(w, r, "users/forgot_password_form.html", status, &struct {
+		Login string
+		Sent  bool
+		Error string
+		templateCommon
+	}{
+		Login: login,
+		Sent:  sent,
+		Error: errMsg,
+	})
+}
+
+func serveRequestPasswordReset(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	login := r.PostForm.Get("Login")
+
+	err := APIClient.Users.RequestPasswordReset(login)
+	if thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		return renderForgotPasswordForm(w, r, http.StatusBadRequest, login, false, "Enter your login or email address.")
+	} else if thesrc.IsHTTPErrorCode(err, http.StatusNotImplemented) {
+		return renderForgotPasswordForm(w, r, http.StatusNotImplemented, login, false, "Passwords can't be reset by email on this site.")
+	} else if err != nil {
+		return err
+	}
+
+	return renderForgotPasswordForm(w, r, http.StatusOK, login, true, "")
+}
+
+func serveResetPasswordForm(w http.ResponseWriter, r *http.Request) error {
+	return renderResetPasswordForm(w, r, http.StatusOK, r.URL.Query().Get("Token"), "")
+}
+
+func renderResetPasswordForm(w http.ResponseWriter, r *http.Request, status int, token, errMsg string) error {
+	return renderTemplate(w, r, "users/reset_password_form.html", status, &struct {
+		Token string
+		Error string
+		templateCommon
+	}{
+		Token: token,
+		Error: errMsg,
+	})
+}
+
+func serveResetPassword(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	token := r.PostForm.Get("Token")
+
+	if r.PostForm.Get("NewPassword") != r.PostForm.Get("ConfirmPassword") {
+		return renderResetPasswordForm(w, r, http.StatusBadRequest, token, "The new passwords don't match.")
+	}
+
+	auth, err := loginClient(r).Users.ResetPassword(&thesrc.PasswordReset{
+		Token:       token,
+		NewPassword: r.PostForm.Get("NewPassword"),
+	})
+	if e, ok := err.(*thesrc.ErrorResponse); ok && e.HTTPStatusCode() == http.StatusBadRequest {
+		return renderResetPasswordForm(w, r, http.StatusBadRequest, token, "Couldn't reset your password: "+e.Message+".")
+	} else if err != nil {
+		return err
+	}
+	if auth.TwoFactorToken != "" {
+		return renderTwoFactorForm(w, r, http.StatusOK, auth.TwoFactorToken, "")
+	}
+
+	setSessionToken(w, r, auth.Token)
+	http.Redirect(w, r, urlTo(router.Posts).String(), http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestRequestPasswordReset(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			RequestPasswordReset_: func(login string) error {
+				if want := "alice@example.com"; login != want {
+					t.Errorf("got login %q, want %q", login, want)
+				}
+				called = true
+				return nil
+			},
+		},
+	}
+
+	resp := postForm(t, router.RequestPasswordReset, url.Values{"Login": {"alice@example.com"}})
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			ResetPassword_: func(reset *thesrc.PasswordReset) (*thesrc.Auth, error) {
+				if want := (thesrc.PasswordReset{Token: "reset-tok", NewPassword: "password2"}); *reset != want {
+					t.Errorf("got reset %+v, want %+v", reset, want)
+				}
+				called = true
+				return &thesrc.Auth{User: &thesrc.User{ID: 1, Login: "alice"}, Token: "tok"}, nil
+			},
+		},
+	}
+
+	resp := postForm(t, router.ResetPassword, url.Values{"Token": {"reset-tok"}, "NewPassword": {"password2"}, "ConfirmPassword": {"password2"}})
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	if c := sessionCookie(resp); c == nil || c.Value != "tok" {
+		t.Errorf("got session cookie %v, want value %q", c, "tok")
+	}
+}
+
+func TestResetPassword_mismatch(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{Users: &thesrc.MockUsersService{}}
+
+	resp := postForm(t, router.ResetPassword, url.Values{"Token": {"reset-tok"}, "NewPassword": {"password2"}, "ConfirmPassword": {"password3"}})
+
+	if want := http.StatusBadRequest; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if c := sessionCookie(resp); c != nil {
+		t.Errorf("got session cookie %v, want none", c)
+	}
+}
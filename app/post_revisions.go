@@ -0,0 +1,125 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// A revisionDiff is a post revision and how it differs from the revision
+// before it (if any).
+type revisionDiff struct {
+	*thesrc.PostRevision
+
+	// Previous is the revision before this one, or nil if this is the
+	// post's original version.
+	Previous *thesrc.PostRevision
+
+	// Body is the line diff of Previous's body and this revision's body.
+	Body []diffLine
+}
+
+// A diffLine is a line of a line diff.
+type diffLine struct {
+	Op   string // "+" (added), "-" (removed), or " " (unchanged)
+	Text string
+}
+
+// diffLines returns the line diff of a and b: the lines of their longest
+// common subsequence of lines, with the lines removed from a and added in b
+// in between.
+func diffLines(a, b string) []diffLine {
+	as, bs := splitLines(a), splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of as[i:]
+	// and bs[j:].
+	lcs := make([][]int, len(as)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bs)+1)
+	}
+	for i := len(as) - 1; i >= 0; i-- {
+		for j := len(bs) - 1; j >= 0; j-- {
+			if as[i] == bs[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(as) && j < len(bs) {
+		switch {
+		case as[i] == bs[j]:
+			lines = append(lines, diffLine{" ", as[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{"-", as[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{"+", bs[j]})
+			j++
+		}
+	}
+	for ; i < len(as); i++ {
+		lines = append(lines, diffLine{"-", as[i]})
+	}
+	for ; j < len(bs); j++ {
+		lines = append(lines, diffLine{"+", bs[j]})
+	}
+	return lines
+}
+
+// splitLines splits s into lines (without their line endings).
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(strings.Replace(s, "\r\n", "\n", -1), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func servePostRevisions(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	post, err := apiClient(r).Posts.Get(id)
+	if thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		handleError(w, r, http.StatusNotFound, err)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	revisions, err := apiClient(r).Posts.Revisions(id)
+	if err != nil {
+		return err
+	}
+	diffs := make([]*revisionDiff, len(revisions))
+	for i, rev := range revisions {
+		d := &revisionDiff{PostRevision: rev}
+		if i > 0 {
+			d.Previous = revisions[i-1]
+			d.Body = diffLines(d.Previous.Body, rev.Body)
+		}
+		diffs[i] = d
+	}
+
+	return renderTemplate(w, r, "posts/revisions.html", http.StatusOK, &struct {
+		Post      *thesrc.Post
+		Revisions []*revisionDiff
+		templateCommon
+	}{
+		Post:      post,
+		Revisions: diffs,
+	})
+}
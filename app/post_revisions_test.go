@@ -0,0 +1,85 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []diffLine
+	}{
+		{"", "", nil},
+		{"", "a", []diffLine{{"+", "a"}}},
+		{"a\nb\nc\n", "a\nx\nc\r\nd", []diffLine{{" ", "a"}, {"-", "b"}, {"+", "x"}, {" ", "c"}, {"+", "d"}}},
+	}
+	for _, test := range tests {
+		if got := diffLines(test.a, test.b); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("diffLines(%q, %q): got %q, want %q", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestPostRevisions(t *testing.T) {
+	setup()
+	defer teardown()
+
+	role := thesrc.RoleModerator
+	now := time.Now()
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Get_: func(id int) (*thesrc.Post, error) {
+				return &thesrc.Post{ID: id, Title: "t2", Body: "a\nc", EditedAt: &now}, nil
+			},
+			Revisions_: func(id int) ([]*thesrc.PostRevision, error) {
+				return []*thesrc.PostRevision{
+					{ID: 1, PostID: id, Title: "t", Body: "a\nb", EditorLogin: "alice", CreatedAt: now.Add(-time.Hour)},
+					{ID: 2, PostID: id, Title: "t2", Body: "a\nc", EditorLogin: "bob", CreatedAt: now},
+				}, nil
+			},
+		},
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "bob", Role: role}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.PostRevisions).URL("ID", "1")
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	resp := doRequest(req)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := html.Find(".revision-title del").Text(), "t"; got != want {
+		t.Errorf("got old title %q, want %q", got, want)
+	}
+	if got, want := html.Find(".diff-removed").Text(), "- b"; got != want {
+		t.Errorf("got removed lines %q, want %q", got, want)
+	}
+	if got, want := html.Find(".diff-added").Text(), "+ c"; got != want {
+		t.Errorf("got added lines %q, want %q", got, want)
+	}
+
+	// Members may not view revisions.
+	role = thesrc.RoleMember
+	req, _ = http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	if resp := doRequest(req); resp.Code != http.StatusForbidden {
+		t.Errorf("got HTTP status %d for member, want %d", resp.Code, http.StatusForbidden)
+	}
+}
@@ -1,55 +1,189 @@
 package app
 
 import (
+	"errors"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"sourcegraph.com/sourcegraph/thesrc"
 	"sourcegraph.com/sourcegraph/thesrc/router"
 )
 
+// relatedPostsLength is the number of related posts shown on a post's page.
+const relatedPostsLength = 5
+
 func servePost(w http.ResponseWriter, r *http.Request) error {
 	id, err := strconv.Atoi(mux.Vars(r)["ID"])
 	if err != nil {
 		return err
 	}
 
-	post, err := APIClient.Posts.Get(id)
+	// Use the user's credentials so that moderators can see dead posts.
+	post, err := apiClient(r).Posts.Get(id)
+	if thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		handleError(w, r, http.StatusNotFound, err)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	comments, err := apiClient(r).Comments.ListForPost(id)
+	if err != nil {
+		return err
+	}
+
+	related, err := apiClient(r).Posts.Related(id, &thesrc.RelatedPostsOptions{ListOptions: thesrc.ListOptions{PerPage: relatedPostsLength}})
+	if err != nil {
+		return err
+	}
+
+	replyTo, _ := strconv.Atoi(r.URL.Query().Get("replyto"))
+
+	user, err := currentUser(r)
 	if err != nil {
 		return err
 	}
 
-	return renderTemplate(w, r, "posts/show.html", http.StatusOK, struct {
-		Post *thesrc.Post
+	return renderTemplate(w, r, "posts/show.html", http.StatusOK, &struct {
+		Post     *thesrc.Post
+		Comments []*thesrc.CommentThread
+		Related  []*thesrc.Post
+		ReplyTo  int
+		CanEdit  bool
+		templateCommon
 	}{
-		Post: post,
+		Post:     post,
+		Comments: thesrc.ThreadComments(comments),
+		Related:  related,
+		ReplyTo:  replyTo,
+		CanEdit:  thesrc.CanEditPost(user, post, time.Now()),
 	})
 }
 
+// postSections are the default list options of the front-page sections,
+// keyed by route name. Query parameters override them.
+var postSections = map[string]thesrc.PostListOptions{
+	// The front page is personalized for logged-in users.
+	router.Posts: {Sort: thesrc.SortTop, Personalized: true},
+
+	router.NewPosts:      {Sort: thesrc.SortNew},
+	router.TopPosts:      {Sort: thesrc.SortTop},
+	router.BestPosts:     {Sort: thesrc.SortBest, Period: thesrc.PeriodWeek},
+	router.TrendingPosts: {Sort: thesrc.SortTrending},
+	router.ShowPosts:     {Sort: thesrc.SortTop, Show: true},
+
+	// Search results are listed newest first.
+	router.SearchPosts: {Sort: thesrc.SortNew},
+}
+
 func servePosts(w http.ResponseWriter, r *http.Request) error {
-	var opt thesrc.PostListOptions
+	var section string
+	if route := mux.CurrentRoute(r); route != nil {
+		section = route.GetName()
+	}
+	opt := postSections[section]
 	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
 		return err
 	}
 
+	// The front page is listed in the user's preferred order unless the query
+	// says otherwise.
+	if section == router.Posts && r.URL.Query().Get("Sort") == "" {
+		user, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+		if user != nil && user.DefaultSort != "" {
+			opt.Sort = user.DefaultSort
+		}
+	}
+
 	opt.CodeOnly = true
+	opt.Tag = mux.Vars(r)["Tag"]
+	opt.Domain = thesrc.NormalizeDomain(mux.Vars(r)["Domain"])
+
+	if opt.Sort == "" {
+		opt.Sort = thesrc.SortTop
+	}
 
 	if opt.PerPage == 0 {
 		opt.PerPage = 60
 	}
 
-	posts, err := APIClient.Posts.List(&opt)
+	posts, err := apiClient(r).Posts.List(&opt)
+	if thesrc.ErrorCode(err) == thesrc.ErrCodeInvalid && opt.Query != "" {
+		handleError(w, r, http.StatusBadRequest, err)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var period string
+	if thesrc.PeriodDuration(opt.Period) != 0 {
+		period = opt.Period
+	}
+
+	var domainStats *thesrc.DomainStats
+	if opt.Domain != "" {
+		domainStats, err = APIClient.Domains.Get(opt.Domain)
+		if err != nil {
+			return err
+		}
+	}
+
+	follow, err := topicFollow(r, opt.Tag, opt.Domain)
 	if err != nil {
 		return err
 	}
 
-	return renderTemplate(w, r, "posts/list.html", http.StatusOK, struct {
-		Posts []*thesrc.Post
+	var nextPageURL *url.URL
+	if len(posts) >= opt.PerPage {
+		nextPageURL = &url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		q := nextPageURL.Query()
+		q.Set("Page", strconv.Itoa(opt.PageOrDefault()+1))
+		nextPageURL.RawQuery = q.Encode()
+	}
+
+	return renderTemplate(w, r, "posts/list.html", http.StatusOK, &struct {
+		Posts       []*thesrc.Post
+		Tag         string
+		Domain      string
+		Query       string
+		DomainStats *thesrc.DomainStats
+		NextPageURL *url.URL
+
+		// Follow is the user's follow of the tag or domain being listed, if
+		// they follow or hide it.
+		Follow *thesrc.Follow
+
+		// Period is the period that posts were submitted within, or "" if
+		// posts of any age are listed.
+		Period string
+
+		// Section is the route name of the front-page section being listed
+		// (such as router.NewPosts), or "" if it isn't a section.
+		Section string
+
+		// PrependNew is whether newly submitted posts belong at the top of
+		// this page, so that the live updates script should add them there.
+		PrependNew bool
+
+		templateCommon
 	}{
-		Posts: posts,
+		Posts:       posts,
+		Tag:         opt.Tag,
+		Domain:      opt.Domain,
+		Query:       opt.Query,
+		DomainStats: domainStats,
+		NextPageURL: nextPageURL,
+		Follow:      follow,
+		Period:      period,
+		Section:     section,
+		PrependNew:  opt.Sort == thesrc.SortNew && opt.PageOrDefault() == 1 && opt.Query == "",
 	})
 }
 
@@ -60,13 +194,31 @@ func serveSubmitPostForm(w http.ResponseWriter, r *http.Request) error {
 		Title:   getCaseOrLowerCaseQuery(q, "Title"),
 		LinkURL: getCaseOrLowerCaseQuery(q, "LinkURL") + getCaseOrLowerCaseQuery(q, "URL"), // support both
 		Body:    getCaseOrLowerCaseQuery(q, "Body"),
+		Tags:    thesrc.SplitTags(getCaseOrLowerCaseQuery(q, "Tags")),
 	}
 
-	return renderTemplate(w, r, "posts/submit_form.html", http.StatusOK, struct {
-		Post *thesrc.Post
+	return renderSubmitPostForm(w, r, http.StatusOK, post, nil)
+}
+
+// renderSubmitPostForm renders the submit form for post, showing the
+// validation errors in err (if any).
+func renderSubmitPostForm(w http.ResponseWriter, r *http.Request, status int, post *thesrc.Post, err *thesrc.ErrorResponse) error {
+	data := &struct {
+		Post        *thesrc.Post
+		Error       string
+		FieldErrors map[string]string
+		templateCommon
 	}{
 		Post: post,
-	})
+	}
+	if err != nil {
+		data.Error = "Invalid post: " + err.Message + "."
+		data.FieldErrors = make(map[string]string, len(err.Fields))
+		for _, f := range err.Fields {
+			data.FieldErrors[f.Field] = f.Message
+		}
+	}
+	return renderTemplate(w, r, "posts/submit_form.html", status, data)
 }
 
 func serveSubmitPost(w http.ResponseWriter, r *http.Request) error {
@@ -75,11 +227,15 @@ func serveSubmitPost(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	var post thesrc.Post
-	if err := schemaDecoder.Decode(&post, r.Form); err != nil {
+	if err := schemaDecoder.Decode(&post, r.PostForm); err != nil {
 		return err
 	}
+	post.Tags = thesrc.SplitTags(r.PostForm.Get("Tags"))
 
-	if _, err := APIClient.Posts.Submit(&post); err != nil {
+	_, err := apiClient(r).Posts.Submit(&post)
+	if e, ok := err.(*thesrc.ErrorResponse); ok && e.HTTPStatusCode() == http.StatusBadRequest {
+		return renderSubmitPostForm(w, r, http.StatusBadRequest, &post, e)
+	} else if err != nil {
 		return err
 	}
 
@@ -88,6 +244,93 @@ func serveSubmitPost(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func serveEditPostForm(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if sessionToken(r) == "" {
+		http.Redirect(w, r, urlTo(router.LogInForm).String(), http.StatusSeeOther)
+		return nil
+	}
+
+	post, err := APIClient.Posts.Get(id)
+	if err != nil {
+		return err
+	}
+
+	return renderEditPostForm(w, r, http.StatusOK, post, "")
+}
+
+func renderEditPostForm(w http.ResponseWriter, r *http.Request, status int, post *thesrc.Post, errMsg string) error {
+	return renderTemplate(w, r, "posts/edit_form.html", status, &struct {
+		Post  *thesrc.Post
+		Error string
+		templateCommon
+	}{
+		Post:  post,
+		Error: errMsg,
+	})
+}
+
+func serveUpdatePost(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if sessionToken(r) == "" {
+		http.Redirect(w, r, urlTo(router.LogInForm).String(), http.StatusSeeOther)
+		return nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	version, _ := strconv.Atoi(r.Form.Get("Version"))
+	post := &thesrc.Post{
+		Title:   r.Form.Get("Title"),
+		Body:    r.Form.Get("Body"),
+		Tags:    thesrc.SplitTags(r.Form.Get("Tags")),
+		Version: version,
+	}
+	if err := apiClient(r).Posts.Update(id, post); errors.Is(err, thesrc.ErrConflict) {
+		// Show the form again with the user's changes, so that they can
+		// save them over the current version (after checking it).
+		current, err := apiClient(r).Posts.Get(id)
+		if err != nil {
+			return err
+		}
+		post.ID, post.LinkURL, post.Version = current.ID, current.LinkURL, current.Version
+		return renderEditPostForm(w, r, http.StatusConflict, post, "Someone else changed this post while you were editing it. Check the current post, then save your changes again to replace it.")
+	} else if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, urlTo(router.Post, "ID", strconv.Itoa(id)).String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveDeletePost(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if sessionToken(r) == "" {
+		http.Redirect(w, r, urlTo(router.LogInForm).String(), http.StatusSeeOther)
+		return nil
+	}
+
+	if err := auditClient(r).Posts.Delete(id); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, urlTo(router.Posts).String(), http.StatusSeeOther)
+	return nil
+}
+
 func getCaseOrLowerCaseQuery(q url.Values, name string) string {
 	if v, present := q[name]; present {
 		return v[0]
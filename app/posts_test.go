@@ -5,10 +5,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
 
+	"github.com/PuerkitoBio/goquery"
 	"sourcegraph.com/sourcegraph/thesrc"
 	"sourcegraph.com/sourcegraph/thesrc/router"
 )
@@ -18,6 +20,7 @@ func TestPost(t *testing.T) {
 	defer teardown()
 
 	post := &thesrc.Post{ID: 1, Title: "t", LinkURL: "http://example.com", Body: "b"}
+	comments := []*thesrc.Comment{{ID: 1, PostID: 1, Body: "c1"}, {ID: 2, PostID: 1, ParentID: 1, Body: "c2", Score: 3, Voted: true}}
 
 	var called bool
 	APIClient = &thesrc.Client{
@@ -29,6 +32,14 @@ func TestPost(t *testing.T) {
 				called = true
 				return post, nil
 			},
+			Related_: func(id int, opt *thesrc.RelatedPostsOptions) ([]*thesrc.Post, error) {
+				return []*thesrc.Post{{ID: 2, Title: "related"}}, nil
+			},
+		},
+		Comments: &thesrc.MockCommentsService{
+			ListForPost_: func(postID int) ([]*thesrc.Comment, error) {
+				return comments, nil
+			},
 		},
 	}
 
@@ -50,10 +61,69 @@ func TestPost(t *testing.T) {
 	if got, _ := a.Attr("href"); got != post.LinkURL {
 		t.Errorf("got link href %q, want %q", got, post.LinkURL)
 	}
-	body := html.Find("p.post-body")
+	body := html.Find(".post-body p")
 	if body.Text() != post.Body {
 		t.Errorf("got post body %q, want %q", body.Text(), post.Body)
 	}
+	if reply := html.Find("#c1 #c2 .comment-body p").Text(); reply != comments[1].Body {
+		t.Errorf("got threaded reply body %q, want %q", reply, comments[1].Body)
+	}
+	if score := html.Find("#c2 .comment-score").Text(); score != "3 points" {
+		t.Errorf("got comment score %q, want %q", score, "3 points")
+	}
+	if html.Find("#c2 .comment-info button.voted").Length() != 1 {
+		t.Error("voted comment's vote button isn't marked as voted")
+	}
+	if related := html.Find(".related-posts a.post-link").Text(); related != "related" {
+		t.Errorf("got related post title %q, want %q", related, "related")
+	}
+}
+
+func TestPosts_selfPost(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				return []*thesrc.Post{{ID: 7, Title: "Ask: t", Body: "b"}}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.Posts).URL()
+	html, _ := getHTML(t, url)
+
+	if got, _ := html.Find("a.post-link").Attr("href"); got != "/p/7" {
+		t.Errorf("got self-post link href %q, want its discussion page %q", got, "/p/7")
+	}
+	if body := html.Find(".post-body p").Text(); body != "b" {
+		t.Errorf("got self-post body %q, want %q", body, "b")
+	}
+}
+
+func TestPost_markdown(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Get_: func(id int) (*thesrc.Post, error) {
+				return &thesrc.Post{ID: 1, Body: "*hi* <script>alert(1)</script>"}, nil
+			},
+		},
+		Comments: &thesrc.MockCommentsService{},
+	}
+
+	url, _ := router.App().Get(router.Post).URL("ID", "1")
+	html, _ := getHTML(t, url)
+
+	if em := html.Find(".post-body em").Text(); em != "hi" {
+		t.Errorf("got emphasized text %q, want %q", em, "hi")
+	}
+	if n := html.Find(".post-body script").Length(); n != 0 {
+		t.Errorf("got %d script elements in post body, want 0", n)
+	}
 }
 
 func TestPosts(t *testing.T) {
@@ -66,6 +136,12 @@ func TestPosts(t *testing.T) {
 	APIClient = &thesrc.Client{
 		Posts: &thesrc.MockPostsService{
 			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if opt.Sort != thesrc.SortTop {
+					t.Errorf("got sort %q, want %q", opt.Sort, thesrc.SortTop)
+				}
+				if !opt.Personalized {
+					t.Error("got unpersonalized front page, want personalized")
+				}
 				called = true
 				return posts, nil
 			},
@@ -91,7 +167,7 @@ func TestPosts(t *testing.T) {
 		if got, _ := a.Attr("href"); got != post.LinkURL {
 			t.Errorf("got link href %q, want %q", got, post.LinkURL)
 		}
-		body := html.Find("p.post-body")
+		body := html.Find(".post-body p")
 		if body.Text() != post.Body {
 			t.Errorf("got post body %q, want %q", body.Text(), post.Body)
 		}
@@ -125,6 +201,11 @@ func TestSubmitPostForm(t *testing.T) {
 	if body := html.Find("textarea[name=Body]").Text(); body != want.Body {
 		t.Errorf("got post body %q, want %q", body, want.Body)
 	}
+
+	token, _ := html.Find("input[name=CSRFToken]").Attr("value")
+	if cookies := resp.Result().Cookies(); token == "" || len(cookies) != 1 || cookies[0].Name != csrfCookieName || cookies[0].Value != token {
+		t.Errorf("got CSRF token %q and cookies %v, want a token matching the CSRF cookie", token, cookies)
+	}
 }
 
 func TestSubmitPosts(t *testing.T) {
@@ -137,6 +218,9 @@ func TestSubmitPosts(t *testing.T) {
 	APIClient = &thesrc.Client{
 		Posts: &thesrc.MockPostsService{
 			Submit_: func(post *thesrc.Post) (bool, error) {
+				if want := []string{"golang", "sql"}; !reflect.DeepEqual(post.Tags, want) {
+					t.Errorf("got tags %q, want %q", post.Tags, want)
+				}
 				called = true
 				post.ID = 1
 				return true, nil
@@ -145,9 +229,11 @@ func TestSubmitPosts(t *testing.T) {
 	}
 
 	v := url.Values{
-		"Title":   []string{post.Title},
-		"LinkURL": []string{post.LinkURL},
-		"Body":    []string{post.Body},
+		"Title":     []string{post.Title},
+		"LinkURL":   []string{post.LinkURL},
+		"Body":      []string{post.Body},
+		"Tags":      []string{"golang, sql"},
+		"CSRFToken": []string{"csrf"},
 	}
 
 	url, _ := router.App().Get(router.SubmitPost).URL()
@@ -155,6 +241,8 @@ func TestSubmitPosts(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: "csrf"})
 
 	resp := httptest.NewRecorder()
 	resp.Body = new(bytes.Buffer)
@@ -172,3 +260,394 @@ func TestSubmitPosts(t *testing.T) {
 		t.Errorf("got Location %q, want %q", loc, want)
 	}
 }
+
+func TestSubmitPosts_csrf(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Submit_: func(post *thesrc.Post) (bool, error) {
+				t.Error("submitted post without a valid CSRF token")
+				return true, nil
+			},
+		},
+	}
+
+	u, _ := router.App().Get(router.SubmitPost).URL()
+	for _, token := range []string{"", "wrong"} {
+		v := url.Values{"Title": []string{"t"}, "CSRFToken": []string{token}}
+		req, _ := http.NewRequest("POST", u.String(), strings.NewReader(v.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: "csrf"})
+		if resp := doRequest(req); resp.Code != http.StatusForbidden {
+			t.Errorf("token %q: got HTTP status %d, want %d", token, resp.Code, http.StatusForbidden)
+		}
+	}
+}
+
+func TestSubmitPosts_invalid(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Submit_: func(post *thesrc.Post) (bool, error) {
+				resp := &http.Response{StatusCode: http.StatusBadRequest, Request: httptest.NewRequest("POST", "/api/posts", nil)}
+				return false, &thesrc.ErrorResponse{
+					Response: resp,
+					Code:     thesrc.ErrCodeInvalid,
+					Message:  "body is required",
+					Fields:   []*thesrc.FieldError{{Field: "Body", Message: "body is required"}},
+				}
+			},
+		},
+	}
+
+	v := url.Values{"Title": []string{"t"}, "Tags": []string{"golang"}, "CSRFToken": []string{"csrf"}}
+	url, _ := router.App().Get(router.SubmitPost).URL()
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: "csrf"})
+	resp := doRequest(req)
+
+	if want := http.StatusBadRequest; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := html.Find("input[name=Title]").Attr("value"); got != "t" {
+		t.Errorf("got title %q, want the submitted title", got)
+	}
+	if got, want := html.Find(".form-error").Text(), "Invalid post: body is required."; got != want {
+		t.Errorf("got error %q, want %q", got, want)
+	}
+	if got, want := html.Find("textarea[name=Body] + .field-error").Text(), "body is required"; got != want {
+		t.Errorf("got Body field error %q, want %q", got, want)
+	}
+	if got, _ := html.Find("input[name=CSRFToken]").Attr("value"); got != "csrf" {
+		t.Errorf("got CSRF token %q, want the cookie's token", got)
+	}
+}
+
+func TestPosts_nextPage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if opt.PerPage != 1 || opt.Page != 2 {
+					t.Errorf("got PerPage %d and Page %d, want %d and %d", opt.PerPage, opt.Page, 1, 2)
+				}
+				return []*thesrc.Post{{ID: 1}}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.Posts).URL()
+	url.RawQuery = "PerPage=1&Page=2"
+	html, _ := getHTML(t, url)
+
+	if got, _ := html.Find("a.more").Attr("href"); got != "/?Page=3&PerPage=1" {
+		t.Errorf("got next page link %q, want %q", got, "/?Page=3&PerPage=1")
+	}
+}
+
+func TestPosts_live(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				return []*thesrc.Post{{ID: 7}}, nil
+			},
+		},
+	}
+
+	tests := []struct {
+		query      string
+		prependNew bool
+	}{
+		{"", false},
+		{"Sort=new", true},
+		{"Sort=new&Page=2", false},
+	}
+	for _, test := range tests {
+		url, _ := router.App().Get(router.Posts).URL()
+		url.RawQuery = test.query
+		html, _ := getHTML(t, url)
+
+		list := html.Find("ol.posts[data-live]")
+		if list.Length() != 1 {
+			t.Fatalf("%q: want live posts list", test.query)
+		}
+		if _, prependNew := list.Attr("data-prepend-new"); prependNew != test.prependNew {
+			t.Errorf("%q: got data-prepend-new %v, want %v", test.query, prependNew, test.prependNew)
+		}
+		if got, _ := list.Find("li.post-container").Attr("data-post-id"); got != "7" {
+			t.Errorf("%q: got data-post-id %q, want %q", test.query, got, "7")
+		}
+	}
+}
+
+func TestPostSections(t *testing.T) {
+	setup()
+	defer teardown()
+
+	tests := []struct {
+		route string
+		query string
+		want  thesrc.PostListOptions
+	}{
+		{router.NewPosts, "", thesrc.PostListOptions{Sort: thesrc.SortNew}},
+		{router.TopPosts, "", thesrc.PostListOptions{Sort: thesrc.SortTop}},
+		{router.BestPosts, "", thesrc.PostListOptions{Sort: thesrc.SortBest, Period: thesrc.PeriodWeek}},
+		{router.BestPosts, "Period=month", thesrc.PostListOptions{Sort: thesrc.SortBest, Period: thesrc.PeriodMonth}},
+		{router.TrendingPosts, "", thesrc.PostListOptions{Sort: thesrc.SortTrending}},
+		{router.ShowPosts, "", thesrc.PostListOptions{Sort: thesrc.SortTop, Show: true}},
+	}
+	for _, test := range tests {
+		var gotOpt *thesrc.PostListOptions
+		APIClient = &thesrc.Client{
+			Posts: &thesrc.MockPostsService{
+				List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+					gotOpt = opt
+					return nil, nil
+				},
+			},
+		}
+
+		url, _ := router.App().Get(test.route).URL()
+		url.RawQuery = test.query
+		_, resp := getHTML(t, url)
+		if want := http.StatusOK; resp.Code != want {
+			t.Errorf("%s: got HTTP status %d, want %d", test.route, resp.Code, want)
+			continue
+		}
+		if gotOpt == nil {
+			t.Errorf("%s: Posts.List not called", test.route)
+			continue
+		}
+		if gotOpt.Sort != test.want.Sort || gotOpt.Period != test.want.Period || gotOpt.Show != test.want.Show {
+			t.Errorf("%s: got Sort %q, Period %q, Show %v; want %q, %q, %v", test.route, gotOpt.Sort, gotOpt.Period, gotOpt.Show, test.want.Sort, test.want.Period, test.want.Show)
+		}
+	}
+}
+
+func TestSearchPosts(t *testing.T) {
+	setup()
+	defer teardown()
+
+	const query = "tag:go rust"
+	var gotOpt *thesrc.PostListOptions
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				gotOpt = opt
+				return []*thesrc.Post{{ID: 1, Title: "Rust for Gophers"}}, nil
+			},
+		},
+	}
+
+	url_, _ := router.App().Get(router.SearchPosts).URL()
+	url_.RawQuery = url.Values{"Query": []string{query}}.Encode()
+	html, resp := getHTML(t, url_)
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+
+	if gotOpt == nil || gotOpt.Query != query || gotOpt.Sort != thesrc.SortNew {
+		t.Errorf("got list options %+v, want Query %q sorted by new", gotOpt, query)
+	}
+	if got := html.Find("h1.tag-title em").Text(); got != query {
+		t.Errorf("got search heading %q, want %q", got, query)
+	}
+	if got, _ := html.Find("form.search input[name=Query]").Attr("value"); got != query {
+		t.Errorf("got search box value %q, want %q", got, query)
+	}
+	if n := html.Find("li.post-container").Length(); n != 1 {
+		t.Errorf("got %d results, want 1", n)
+	}
+}
+
+func TestTagPosts(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if opt.Tag != "golang" {
+					t.Errorf("got tag %q, want %q", opt.Tag, "golang")
+				}
+				called = true
+				return []*thesrc.Post{{ID: 1, Title: "t", Tags: []string{"golang"}}}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.TagPosts).URL("Tag", "golang")
+	html, resp := getHTML(t, url)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+
+	if !called {
+		t.Error("!called")
+	}
+
+	if got, _ := html.Find(".tags a").Attr("href"); got != url.String() {
+		t.Errorf("got tag link %q, want %q", got, url.String())
+	}
+}
+
+func TestDomainPosts(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if opt.Domain != "example.com" {
+					t.Errorf("got domain %q, want %q", opt.Domain, "example.com")
+				}
+				called = true
+				return []*thesrc.Post{{ID: 1, Title: "t", LinkURL: "http://example.com/a", Domain: "example.com"}}, nil
+			},
+		},
+		Domains: &thesrc.MockDomainsService{
+			Get_: func(domain string) (*thesrc.DomainStats, error) {
+				return &thesrc.DomainStats{Domain: domain, NumPosts: 3, AverageScore: 2.5}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.DomainPosts).URL("Domain", "example.com")
+	html, resp := getHTML(t, url)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+
+	if !called {
+		t.Error("!called")
+	}
+
+	if got, _ := html.Find(".domain a").Attr("href"); got != url.String() {
+		t.Errorf("got domain link %q, want %q", got, url.String())
+	}
+	if got, want := html.Find(".domain-stats").Text(), "3 posts, average score 2.5"; got != want {
+		t.Errorf("got domain stats %q, want %q", got, want)
+	}
+}
+
+func TestUpdatePost(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Update_: func(id int, post *thesrc.Post) error {
+				want := &thesrc.Post{Title: "t2", Body: "b2", Tags: []string{"golang"}, Version: 3}
+				if id != 1 || !reflect.DeepEqual(post, want) {
+					t.Errorf("got update of post %d to %+v, want post 1 and %+v", id, post, want)
+				}
+				called = true
+				return nil
+			},
+		},
+	}
+
+	v := url.Values{"Title": []string{"t2"}, "Body": []string{"b2"}, "Tags": []string{"golang"}, "Version": []string{"3"}}
+	url, _ := router.App().Get(router.UpdatePost).URL("ID", "1")
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	if loc, want := resp.Header().Get("location"), urlTo(router.Post, "ID", "1").String(); loc != want {
+		t.Errorf("got Location %q, want %q", loc, want)
+	}
+}
+
+func TestUpdatePost_conflict(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Update_: func(id int, post *thesrc.Post) error {
+				return &thesrc.ErrorResponse{Response: &http.Response{StatusCode: http.StatusConflict, Request: &http.Request{Method: "PUT", URL: &url.URL{}}}}
+			},
+			Get_: func(id int) (*thesrc.Post, error) {
+				return &thesrc.Post{ID: id, Title: "t3", LinkURL: "http://example.com", Version: 4}, nil
+			},
+		},
+	}
+
+	v := url.Values{"Title": []string{"t2"}, "Body": []string{"b2"}, "Version": []string{"3"}}
+	url, _ := router.App().Get(router.UpdatePost).URL("ID", "1")
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusConflict; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The form keeps the user's changes, but it is now based on the
+	// current version of the post.
+	if title, _ := html.Find("#Title").Attr("value"); title != "t2" {
+		t.Errorf("got title %q, want %q", title, "t2")
+	}
+	if version, _ := html.Find(`input[name="Version"]`).Attr("value"); version != "4" {
+		t.Errorf("got version %q, want %q", version, "4")
+	}
+	if html.Find(".form-error").Length() != 1 {
+		t.Error("no error message")
+	}
+}
+
+func TestDeletePost_notLoggedIn(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{Posts: &thesrc.MockPostsService{
+		Delete_: func(id int) error {
+			t.Error("unexpected call to Delete")
+			return nil
+		},
+	}}
+
+	url, _ := router.App().Get(router.DeletePost).URL("ID", "1")
+	req, _ := http.NewRequest("POST", url.String(), nil)
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if loc, want := resp.Header().Get("location"), urlTo(router.LogInForm).String(); loc != want {
+		t.Errorf("got Location %q, want %q", loc, want)
+	}
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func serveReportPostForm(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	post, err := apiClient(r).Posts.Get(id)
+	if err != nil {
+		return err
+	}
+
+	return renderReportPostForm(w, r, http.StatusOK, post, &thesrc.Report{PostID: id}, "")
+}
+
+func renderReportPostForm(w http.ResponseWriter, r *http.Request, status int, post *thesrc.Post, report *thesrc.Report, errMsg string) error {
+	return renderTemplate(w, r, "posts/report_form.html", status, &struct {
+		Post       *thesrc.Post
+		Report     *thesrc.Report
+		Categories []string
+		Error      string
+		templateCommon
+	}{
+		Post:       post,
+		Report:     report,
+		Categories: thesrc.ReportCategories,
+		Error:      errMsg,
+	})
+}
+
+func serveReportPost(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	report := &thesrc.Report{
+		PostID:   id,
+		Category: r.PostForm.Get("Category"),
+		Details:  r.PostForm.Get("Details"),
+	}
+
+	// apiClient forwards the visitor's IP address, which the API limits
+	// reports by.
+	if err := apiClient(r).Reports.Create(report); thesrc.IsHTTPErrorCode(err, http.StatusTooManyRequests) {
+		handleError(w, r, http.StatusTooManyRequests, errors.New("you've reported too many posts recently; try again later"))
+		return nil
+	} else if thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		post, err := apiClient(r).Posts.Get(id)
+		if err != nil {
+			return err
+		}
+		return renderReportPostForm(w, r, http.StatusBadRequest, post, report, "Choose what you're reporting this post for, and keep the details short.")
+	} else if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, urlTo(router.PostReported, "ID", strconv.Itoa(id)).String(), http.StatusSeeOther)
+	return nil
+}
+
+func servePostReported(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	post, err := apiClient(r).Posts.Get(id)
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "posts/reported.html", http.StatusOK, &struct {
+		Post *thesrc.Post
+		templateCommon
+	}{
+		Post: post,
+	})
+}
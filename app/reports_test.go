@@ -0,0 +1,118 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestReportPostForm(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Get_: func(id int) (*thesrc.Post, error) {
+				return &thesrc.Post{ID: id, Title: "t"}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.ReportPostForm).URL("ID", "1")
+	html, resp := getHTML(t, url)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if got, want := html.Find("input[name=Category]").Length(), len(thesrc.ReportCategories); got != want {
+		t.Errorf("got %d category choices, want %d", got, want)
+	}
+	if action, want := html.Find("form.report-post").AttrOr("action", ""), urlTo(router.ReportPost, "ID", "1").String(); action != want {
+		t.Errorf("got form action %q, want %q", action, want)
+	}
+}
+
+func TestReportPost(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var reported *thesrc.Report
+	APIClient = &thesrc.Client{
+		Reports: &thesrc.MockReportsService{
+			Create_: func(report *thesrc.Report) error {
+				reported = report
+				return nil
+			},
+		},
+	}
+
+	// Visitors don't need to log in to report posts.
+	v := url.Values{"Category": []string{thesrc.ReportSpam}, "Details": []string{"ads"}}
+	req, _ := http.NewRequest("POST", urlTo(router.ReportPost, "ID", "1").String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if reported == nil || reported.PostID != 1 || reported.Category != thesrc.ReportSpam || reported.Details != "ads" {
+		t.Errorf("got report %+v, want a spam report of post 1", reported)
+	}
+	if loc, want := resp.Header().Get("location"), urlTo(router.PostReported, "ID", "1").String(); loc != want {
+		t.Errorf("got Location %q, want %q", loc, want)
+	}
+}
+
+func TestReportPost_rateLimited(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Reports: &thesrc.MockReportsService{
+			Create_: func(report *thesrc.Report) error {
+				return &thesrc.RateLimitError{ErrorResponse: &thesrc.ErrorResponse{Response: &http.Response{StatusCode: http.StatusTooManyRequests, Request: &http.Request{Method: "POST", URL: &url.URL{}}}}}
+			},
+		},
+	}
+
+	v := url.Values{"Category": []string{thesrc.ReportSpam}}
+	req, _ := http.NewRequest("POST", urlTo(router.ReportPost, "ID", "1").String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusTooManyRequests; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if body := resp.Body.String(); !strings.Contains(body, "reported too many posts") {
+		t.Errorf("got body %q, want it to explain the report limit", body)
+	}
+}
+
+func TestPostReported(t *testing.T) {
+	setup()
+	defer teardown()
+
+	post := &thesrc.Post{ID: 1, Title: "t"}
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Get_: func(id int) (*thesrc.Post, error) { return post, nil },
+		},
+	}
+
+	url, _ := router.App().Get(router.PostReported).URL("ID", strconv.Itoa(post.ID))
+	html, resp := getHTML(t, url)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if got := html.Find(".post-reported a").First().Text(); got != post.Title {
+		t.Errorf("got post link %q, want %q", got, post.Title)
+	}
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func serveSavePost(w http.ResponseWriter, r *http.Request) error {
+	return serveSave(w, r, true)
+}
+
+func serveUnsavePost(w http.ResponseWriter, r *http.Request) error {
+	return serveSave(w, r, false)
+}
+
+func serveSave(w http.ResponseWriter, r *http.Request, save bool) error {
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if sessionToken(r) == "" {
+		http.Redirect(w, r, urlTo(router.LogInForm).String(), http.StatusSeeOther)
+		return nil
+	}
+
+	posts := apiClient(r).Posts
+	if save {
+		err = posts.Save(postID)
+	} else {
+		err = posts.Unsave(postID)
+	}
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, localReferer(r, urlTo(router.Post, "ID", strconv.Itoa(postID))).String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveSavedPosts(w http.ResponseWriter, r *http.Request) error {
+	var opt thesrc.PostListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+	opt.Saved = true
+	opt.Sort = thesrc.SortNew
+	if opt.PerPage == 0 {
+		opt.PerPage = 60
+	}
+
+	posts, err := apiClient(r).Posts.List(&opt)
+	if err != nil {
+		return err
+	}
+
+	var nextPageURL *url.URL
+	if len(posts) >= opt.PerPage {
+		nextPageURL = &url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		q := nextPageURL.Query()
+		q.Set("Page", strconv.Itoa(opt.PageOrDefault()+1))
+		nextPageURL.RawQuery = q.Encode()
+	}
+
+	return renderTemplate(w, r, "posts/saved.html", http.StatusOK, &struct {
+		Posts       []*thesrc.Post
+		NextPageURL *url.URL
+		templateCommon
+	}{
+		Posts:       posts,
+		NextPageURL: nextPageURL,
+	})
+}
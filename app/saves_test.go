@@ -0,0 +1,80 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestSavePost(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var saved, unsaved bool
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Save_:   func(id int) error { saved = id == 1; return nil },
+			Unsave_: func(id int) error { unsaved = id == 1; return nil },
+		},
+	}
+
+	for _, route := range []string{router.SavePost, router.UnsavePost} {
+		url, _ := router.App().Get(route).URL("ID", "1")
+		req, _ := http.NewRequest("POST", url.String(), nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+		addCSRFToken(req)
+		if resp := doRequest(req); resp.Code != http.StatusSeeOther {
+			t.Errorf("%s: got HTTP status %d, want %d", route, resp.Code, http.StatusSeeOther)
+		}
+	}
+	if !saved || !unsaved {
+		t.Errorf("got saved %v and unsaved %v, want both", saved, unsaved)
+	}
+}
+
+func TestSavedPosts(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if !opt.Saved {
+					t.Error("!opt.Saved")
+				}
+				return []*thesrc.Post{{ID: 1, Title: "t", LinkURL: "http://example.com", Saved: true}}, nil
+			},
+		},
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.SavedPosts).URL()
+
+	// Users who aren't logged in are redirected to the login page.
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	if resp := doRequest(req); resp.Code != http.StatusSeeOther {
+		t.Errorf("got HTTP status %d when not logged in, want %d", resp.Code, http.StatusSeeOther)
+	}
+
+	req, _ = http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	resp := doRequest(req)
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := html.Find(".post-link").Text(), "t"; got != want {
+		t.Errorf("got post link %q, want %q", got, want)
+	}
+}
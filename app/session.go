@@ -0,0 +1,138 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// sessionCookieName is the name of the cookie that holds the API token of
+// the logged-in user.
+const sessionCookieName = "thesrc-session"
+
+// sessionCookieLifetime is how long the session cookie is kept. The session
+// itself expires once it goes unused for too long (see
+// api.SessionIdleTimeout), so the cookie may outlive it.
+const sessionCookieLifetime = 365 * 24 * time.Hour
+
+// sessionToken returns the API token stored in r's session cookie, or "" if
+// there is no logged-in user.
+func sessionToken(r *http.Request) string {
+	c, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+// setSessionToken sets the session cookie to token, logging in the user
+// that token authenticates as.
+func setSessionToken(w http.ResponseWriter, r *http.Request, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().Add(sessionCookieLifetime),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+	})
+}
+
+// clearSession deletes the session cookie, logging out the user.
+func clearSession(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+// apiClient returns the API client to use when handling r. If a user is
+// logged in, the client is authenticated as that user. Its requests are made
+// with r's context, so the API logs them with r's request ID, and are sent
+// with r's client IP address and User-Agent, so that the API can record them
+// (see thesrc.ClientRecord).
+func apiClient(r *http.Request) *thesrc.Client {
+	c := APIClient.WithContext(r.Context()).WithForwardedClient(clientIP(r), r.UserAgent())
+	if token := sessionToken(r); token != "" {
+		return c.WithAuthToken(token)
+	}
+	return c
+}
+
+// loginClient returns the API client to use to log in when handling r. It
+// is not authenticated (unlike apiClient), and its requests are sent with
+// r's client IP address and User-Agent, so that the API records the
+// User-Agent with the sessions that they create (see thesrc.Session).
+func loginClient(r *http.Request) *thesrc.Client {
+	return APIClient.WithContext(r.Context()).WithForwardedClient(clientIP(r), r.UserAgent())
+}
+
+// auditClient returns the API client to use when handling r (see
+// apiClient), which gives the reason in r's Reason form field (if any) for
+// the privileged actions that it takes (see thesrc.Client.WithAuditReason).
+// The field is removed from r.PostForm, so that it isn't decoded with the
+// rest of the form.
+func auditClient(r *http.Request) *thesrc.Client {
+	reason := strings.TrimSpace(r.PostFormValue("Reason"))
+	r.PostForm.Del("Reason")
+	return apiClient(r).WithAuditReason(reason)
+}
+
+// clientIP returns the IP address of the client that made r (using the
+// X-Forwarded-For header if TrustProxyHeaders is set).
+func clientIP(r *http.Request) string {
+	if TrustProxyHeaders {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			// The last address was added by our proxy; earlier ones can be
+			// spoofed by the client.
+			addrs := strings.Split(fwd, ",")
+			return strings.TrimSpace(addrs[len(addrs)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host
+}
+
+// currentUser returns the logged-in user, or nil if no user is logged in
+// (or the session is no longer valid).
+func currentUser(r *http.Request) (*thesrc.User, error) {
+	if sessionToken(r) == "" {
+		return nil, nil
+	}
+	user, err := apiClient(r).Users.Current()
+	if thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		return nil, nil
+	}
+	return user, err
+}
+
+// requireRole wraps h so that it is only called for requests from logged-in
+// users with role. Users who aren't logged in are redirected to the login
+// page.
+func requireRole(role string, h handler) handler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		user, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			http.Redirect(w, r, urlTo(router.LogInForm).String(), http.StatusSeeOther)
+			return nil
+		}
+		if !user.HasRole(role) {
+			handleError(w, r, http.StatusForbidden, fmt.Errorf("%s role required", role))
+			return nil
+		}
+		return h(w, r)
+	}
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func serveSessions(w http.ResponseWriter, r *http.Request) error {
+	sessions, err := apiClient(r).Sessions.List()
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "users/sessions.html", http.StatusOK, &struct {
+		Sessions []*thesrc.Session
+		templateCommon
+	}{
+		Sessions: sessions,
+	})
+}
+
+func serveRevokeSession(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := apiClient(r).Sessions.Revoke(id); err != nil && !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		return err
+	}
+
+	http.Redirect(w, r, urlTo(router.Sessions).String(), http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestSessions(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+		},
+		Sessions: &thesrc.MockSessionsService{
+			List_: func() ([]*thesrc.Session, error) {
+				return []*thesrc.Session{{ID: 1, UserAgent: "Firefox", Current: true}, {ID: 2, UserAgent: "Safari"}}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.Sessions).URL()
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	resp := doRequest(req)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := html.Find(".session-user-agent").Length(); n != 2 {
+		t.Errorf("got %d sessions listed, want 2", n)
+	}
+	// Only other sessions can be revoked.
+	if n := html.Find(".sessions form").Length(); n != 1 {
+		t.Errorf("got %d revoke buttons, want 1", n)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+		},
+		Sessions: &thesrc.MockSessionsService{
+			Revoke_: func(id int) error {
+				if id != 2 {
+					t.Errorf("got revoke of session %d, want 2", id)
+				}
+				called = true
+				return nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.RevokeSession).URL("ID", "2")
+	req, _ := http.NewRequest("POST", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"net/http"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// settingsPage is the data of the settings page.
+type settingsPage struct {
+	Settings *thesrc.UserSettings
+
+	// Sorts are the orders that the user may choose for their front page.
+	Sorts []string
+
+	// Notice (if set) tells the user that a change was made.
+	Notice string
+
+	// Error and FieldErrors describe why the settings form was invalid, and
+	// PasswordError why the password form was.
+	Error         string
+	FieldErrors   map[string]string
+	PasswordError string
+
+	templateCommon
+}
+
+func serveSettings(w http.ResponseWriter, r *http.Request) error {
+	user, err := currentUser(r)
+	if err != nil {
+		return err
+	}
+
+	page := &settingsPage{Settings: settingsOf(user)}
+	if r.URL.Query().Get("saved") != "" {
+		page.Notice = "Your settings were saved."
+	}
+	return renderSettings(w, r, http.StatusOK, page)
+}
+
+// settingsOf returns user's current settings.
+func settingsOf(user *thesrc.User) *thesrc.UserSettings {
+	return &thesrc.UserSettings{
+		DisplayName:      user.DisplayName,
+		Email:            user.Email,
+		DigestSubscribed: user.DigestSubscribed,
+		Theme:            user.Theme,
+		DefaultSort:      user.DefaultSort,
+	}
+}
+
+func renderSettings(w http.ResponseWriter, r *http.Request, status int, page *settingsPage) error {
+	page.Sorts = []string{thesrc.SortTop, thesrc.SortNew, thesrc.SortBest, thesrc.SortTrending}
+	return renderTemplate(w, r, "users/settings.html", status, page)
+}
+
+func serveUpdateUserSettings(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	settings := &thesrc.UserSettings{
+		DisplayName:      r.PostForm.Get("DisplayName"),
+		Email:            r.PostForm.Get("Email"),
+		DigestSubscribed: r.PostForm.Get("DigestSubscribed") == "true",
+		Theme:            r.PostForm.Get("Theme"),
+		DefaultSort:      r.PostForm.Get("DefaultSort"),
+	}
+	_, err := apiClient(r).Users.UpdateSettings(settings)
+	if e, ok := err.(*thesrc.ErrorResponse); ok && e.HTTPStatusCode() == http.StatusBadRequest {
+		page := &settingsPage{
+			Settings:    settings,
+			Error:       "Invalid settings: " + e.Message + ".",
+			FieldErrors: make(map[string]string, len(e.Fields)),
+		}
+		for _, f := range e.Fields {
+			page.FieldErrors[f.Field] = f.Message
+		}
+		return renderSettings(w, r, http.StatusBadRequest, page)
+	} else if err != nil {
+		return err
+	}
+
+	u := urlTo(router.Settings)
+	u.RawQuery = "saved=1"
+	http.Redirect(w, r, u.String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveChangePassword(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	user, err := currentUser(r)
+	if err != nil {
+		return err
+	}
+	page := &settingsPage{Settings: settingsOf(user)}
+
+	if r.PostForm.Get("NewPassword") != r.PostForm.Get("ConfirmPassword") {
+		page.PasswordError = "The new passwords don't match."
+		return renderSettings(w, r, http.StatusBadRequest, page)
+	}
+
+	auth, err := loginClient(r).WithAuthToken(sessionToken(r)).Users.ChangePassword(&thesrc.PasswordChange{
+		CurrentPassword: r.PostForm.Get("CurrentPassword"),
+		NewPassword:     r.PostForm.Get("NewPassword"),
+	})
+	if e, ok := err.(*thesrc.ErrorResponse); ok && e.HTTPStatusCode() == http.StatusBadRequest {
+		page.PasswordError = "Couldn't change your password: " + e.Message + "."
+		return renderSettings(w, r, http.StatusBadRequest, page)
+	} else if err != nil {
+		return err
+	}
+
+	// Changing the password revoked the session, so use the new one.
+	setSessionToken(w, r, auth.Token)
+	page.Notice = "Your password was changed, and you were logged out everywhere else."
+	return renderSettings(w, r, http.StatusOK, page)
+}
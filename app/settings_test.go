@@ -0,0 +1,153 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestUpdateUserSettings(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var updated *thesrc.UserSettings
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+			UpdateSettings_: func(settings *thesrc.UserSettings) (*thesrc.User, error) {
+				updated = settings
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+		},
+	}
+
+	v := url.Values{"DisplayName": []string{"Alice"}, "Email": []string{"a@example.com"}, "DigestSubscribed": []string{"true"}, "Theme": []string{"dark"}, "DefaultSort": []string{"new"}}
+	url, _ := router.App().Get(router.UpdateUserSettings).URL()
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	want := &thesrc.UserSettings{DisplayName: "Alice", Email: "a@example.com", DigestSubscribed: true, Theme: thesrc.ThemeDark, DefaultSort: thesrc.SortNew}
+	if updated == nil || *updated != *want {
+		t.Errorf("got settings %+v, want %+v", updated, want)
+	}
+}
+
+func TestUpdateUserSettings_invalid(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+			UpdateSettings_: func(settings *thesrc.UserSettings) (*thesrc.User, error) {
+				resp := &http.Response{StatusCode: http.StatusBadRequest, Request: httptest.NewRequest("PUT", "/api/user", nil)}
+				return nil, &thesrc.ErrorResponse{
+					Response: resp,
+					Code:     thesrc.ErrCodeInvalid,
+					Message:  "invalid theme",
+					Fields:   []*thesrc.FieldError{{Field: "Theme", Message: "invalid theme"}},
+				}
+			},
+		},
+	}
+
+	v := url.Values{"DisplayName": []string{"Alice"}, "Theme": []string{"purple"}}
+	url, _ := router.App().Get(router.UpdateUserSettings).URL()
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusBadRequest; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := html.Find("input[name=DisplayName]").Attr("value"); got != "Alice" {
+		t.Errorf("got display name %q, want the submitted display name", got)
+	}
+	if got, want := html.Find("select[name=Theme] + .field-error").Text(), "invalid theme"; got != want {
+		t.Errorf("got Theme field error %q, want %q", got, want)
+	}
+}
+
+func TestChangePassword_mismatch(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+			ChangePassword_: func(change *thesrc.PasswordChange) (*thesrc.Auth, error) {
+				t.Error("ChangePassword called")
+				return nil, nil
+			},
+		},
+	}
+
+	v := url.Values{"CurrentPassword": []string{"old"}, "NewPassword": []string{"new-password"}, "ConfirmPassword": []string{"other-password"}}
+	url, _ := router.App().Get(router.ChangePassword).URL()
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusBadRequest; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+}
+
+func TestPosts_defaultSort(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var gotOpt *thesrc.PostListOptions
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", DefaultSort: thesrc.SortNew}, nil
+			},
+		},
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				gotOpt = opt
+				return nil, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.Posts).URL()
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	resp := doRequest(req)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if gotOpt == nil || gotOpt.Sort != thesrc.SortNew {
+		t.Errorf("got list options %+v, want Sort %q", gotOpt, thesrc.SortNew)
+	}
+}
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// sitemapPageSize is the maximum number of URLs in each page of the sitemap.
+// (The sitemaps protocol allows up to 50,000.) If there are more posts than
+// fit in one page, /sitemap.xml is a sitemap index that links to each page.
+var sitemapPageSize = 50000
+
+// sitemapListPerPage is the number of posts to fetch from the API at once
+// when generating the sitemap.
+const sitemapListPerPage = 1000
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// A sitemapPage is one page of the sitemap.
+type sitemapPage struct {
+	urls    []sitemapURL
+	lastMod time.Time
+}
+
+// sitemap caches the generated sitemap pages.
+var sitemap sitemapCache
+
+type sitemapCache struct {
+	mu        sync.Mutex
+	pages     []*sitemapPage
+	generated bool
+
+	// genMu is held while generating the sitemap, so that concurrent
+	// requests don't all generate it at once.
+	genMu sync.Mutex
+}
+
+// get returns the cached sitemap pages, generating them if they haven't been
+// generated yet.
+func (c *sitemapCache) get() ([]*sitemapPage, error) {
+	c.mu.Lock()
+	pages, generated := c.pages, c.generated
+	c.mu.Unlock()
+	if generated {
+		return pages, nil
+	}
+
+	c.genMu.Lock()
+	defer c.genMu.Unlock()
+	c.mu.Lock()
+	pages, generated = c.pages, c.generated
+	c.mu.Unlock()
+	if generated {
+		return pages, nil
+	}
+	return c.generateLocked()
+}
+
+// regenerate generates the sitemap pages and replaces the cached pages.
+func (c *sitemapCache) regenerate() ([]*sitemapPage, error) {
+	c.genMu.Lock()
+	defer c.genMu.Unlock()
+	return c.generateLocked()
+}
+
+// generateLocked generates the sitemap pages. c.genMu must be held.
+func (c *sitemapCache) generateLocked() ([]*sitemapPage, error) {
+	pages, err := generateSitemap()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pages, c.generated = pages, true
+	return pages, nil
+}
+
+// generateSitemap lists the permalinks of all posts, newest first, split
+// into pages of up to sitemapPageSize URLs. It always returns at least one
+// (possibly empty) page.
+func generateSitemap() ([]*sitemapPage, error) {
+	page := &sitemapPage{}
+	pages := []*sitemapPage{page}
+	opt := &thesrc.PostListOptions{
+		CodeOnly:    true,
+		Sort:        thesrc.SortNew,
+		ListOptions: thesrc.ListOptions{PerPage: sitemapListPerPage, Page: 1},
+	}
+	for {
+		posts, err := APIClient.Posts.List(opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range posts {
+			if len(page.urls) == sitemapPageSize {
+				page = &sitemapPage{}
+				pages = append(pages, page)
+			}
+			page.urls = append(page.urls, sitemapURL{
+				Loc:     absURL(router.Post, "ID", strconv.Itoa(post.ID)),
+				LastMod: post.SubmittedAt.UTC().Format(time.RFC3339),
+			})
+			if post.SubmittedAt.After(page.lastMod) {
+				page.lastMod = post.SubmittedAt
+			}
+		}
+		if len(posts) < opt.PerPage {
+			return pages, nil
+		}
+		opt.Page++
+	}
+}
+
+// RegenerateSitemap regenerates the sitemap. It should be run periodically.
+// (Until it is first regenerated, the sitemap is generated on the first
+// request for it.)
+func RegenerateSitemap() error {
+	_, err := sitemap.regenerate()
+	return err
+}
+
+// serveSitemap serves the sitemap if it has only one page, and otherwise a
+// sitemap index that links to each page.
+func serveSitemap(w http.ResponseWriter, r *http.Request) error {
+	pages, err := sitemap.get()
+	if err != nil {
+		return err
+	}
+
+	if len(pages) == 1 {
+		return writeXML(w, "application/xml; charset=utf-8", sitemapURLSet{URLs: pages[0].urls})
+	}
+
+	var index sitemapIndex
+	for i, page := range pages {
+		index.Sitemaps = append(index.Sitemaps, sitemapURL{
+			Loc:     absURL(router.SitemapPage, "Page", strconv.Itoa(i+1)),
+			LastMod: page.lastMod.UTC().Format(time.RFC3339),
+		})
+	}
+	return writeXML(w, "application/xml; charset=utf-8", index)
+}
+
+func serveSitemapPage(w http.ResponseWriter, r *http.Request) error {
+	pages, err := sitemap.get()
+	if err != nil {
+		return err
+	}
+
+	n, err := strconv.Atoi(mux.Vars(r)["Page"])
+	if err != nil || n < 1 || n > len(pages) {
+		handleError(w, r, http.StatusNotFound, errors.New("sitemap page not found"))
+		return nil
+	}
+	return writeXML(w, "application/xml; charset=utf-8", sitemapURLSet{URLs: pages[n-1].urls})
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"encoding/xml"
+	"net/http"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func setupSitemap(t *testing.T, posts []*thesrc.Post) (listCalls *int) {
+	listCalls = new(int)
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				*listCalls++
+				if !opt.CodeOnly {
+					t.Error("!opt.CodeOnly")
+				}
+				if opt.Page > 1 {
+					return nil, nil
+				}
+				return posts, nil
+			},
+		},
+	}
+	sitemap = sitemapCache{}
+	return listCalls
+}
+
+func getSitemap(t *testing.T, routeName string, v interface{}, params ...string) int {
+	url, _ := router.App().Get(routeName).URL(params...)
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	resp := doRequest(req)
+	if resp.Code == http.StatusOK {
+		if err := xml.Unmarshal(resp.Body.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.Code
+}
+
+func TestSitemap(t *testing.T) {
+	setup()
+	defer teardown()
+
+	listCalls := setupSitemap(t, feedTestPosts)
+
+	var urlset sitemapURLSet
+	if code := getSitemap(t, router.Sitemap, &urlset); code != http.StatusOK {
+		t.Fatalf("got HTTP status %d, want %d", code, http.StatusOK)
+	}
+	if len(urlset.URLs) != 2 {
+		t.Fatalf("got %d URLs, want 2", len(urlset.URLs))
+	}
+	if want := "http://thesrc.org/p/1"; urlset.URLs[0].Loc != want {
+		t.Errorf("got loc %q, want %q", urlset.URLs[0].Loc, want)
+	}
+	if want := "2014-06-01T12:00:00Z"; urlset.URLs[0].LastMod != want {
+		t.Errorf("got lastmod %q, want %q", urlset.URLs[0].LastMod, want)
+	}
+
+	// The sitemap should be cached.
+	getSitemap(t, router.Sitemap, &urlset)
+	if *listCalls != 1 {
+		t.Errorf("got %d Posts.List calls, want 1 (cached)", *listCalls)
+	}
+}
+
+func TestSitemap_index(t *testing.T) {
+	setup()
+	defer teardown()
+
+	defer func(n int) { sitemapPageSize = n }(sitemapPageSize)
+	sitemapPageSize = 1
+	setupSitemap(t, feedTestPosts)
+
+	var index sitemapIndex
+	if code := getSitemap(t, router.Sitemap, &index); code != http.StatusOK {
+		t.Fatalf("got HTTP status %d, want %d", code, http.StatusOK)
+	}
+	if len(index.Sitemaps) != 2 {
+		t.Fatalf("got %d sitemaps in index, want 2", len(index.Sitemaps))
+	}
+	if want := "http://thesrc.org/sitemap-2.xml"; index.Sitemaps[1].Loc != want {
+		t.Errorf("got loc %q, want %q", index.Sitemaps[1].Loc, want)
+	}
+	if want := feedTestPosts[1].SubmittedAt.Format(time.RFC3339); index.Sitemaps[1].LastMod != want {
+		t.Errorf("got lastmod %q, want %q", index.Sitemaps[1].LastMod, want)
+	}
+
+	var urlset sitemapURLSet
+	if code := getSitemap(t, router.SitemapPage, &urlset, "Page", "2"); code != http.StatusOK {
+		t.Fatalf("got HTTP status %d, want %d", code, http.StatusOK)
+	}
+	if len(urlset.URLs) != 1 || urlset.URLs[0].Loc != "http://thesrc.org/p/2" {
+		t.Errorf("got URLs %+v, want post 2", urlset.URLs)
+	}
+
+	if code := getSitemap(t, router.SitemapPage, &urlset, "Page", "3"); code != http.StatusNotFound {
+		t.Errorf("got HTTP status %d for nonexistent page, want %d", code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,33 @@
+// comments.js adds a toggle to each comment that collapses (or expands) it
+// and its replies. Without JavaScript, all comment threads are expanded.
+(function() {
+  "use strict";
+
+  var comments = document.querySelectorAll("li.comment");
+  Array.prototype.forEach.call(comments, function(comment) {
+    var info = comment.querySelector(".comment-info");
+    if (!info) return;
+
+    // Count the comment itself and all of its replies, to show how many
+    // comments a collapsed thread hides.
+    var n = comment.querySelectorAll("li.comment").length + 1;
+
+    var toggle = document.createElement("button");
+    toggle.type = "button";
+    toggle.className = "comment-toggle";
+    function update() {
+      var collapsed = comment.classList.contains("collapsed");
+      toggle.textContent = collapsed ? "[+" + n + "]" : "[–]";
+      toggle.title = collapsed ? "Expand" : "Collapse";
+    }
+    toggle.addEventListener("click", function() {
+      comment.classList.toggle("collapsed");
+      update();
+    });
+    update();
+
+    var item = document.createElement("li");
+    item.appendChild(toggle);
+    info.insertBefore(item, info.firstChild);
+  });
+})();
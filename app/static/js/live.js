@@ -0,0 +1,103 @@
+// live.js keeps post listings up to date without reloading. It connects to
+// the API's live updates WebSocket (/api/live), updates the scores of listed
+// posts as they change, and (on listings of the newest posts) prepends newly
+// submitted posts.
+(function() {
+  "use strict";
+
+  var list = document.querySelector("ol.posts[data-live]");
+  if (!list || !window.WebSocket) return;
+
+  var minRetryDelay = 1000, maxRetryDelay = 60000;
+  var retryDelay = minRetryDelay;
+
+  function postItem(id) {
+    return list.querySelector('li.post-container[data-post-id="' + id + '"]');
+  }
+
+  // wouldList reports whether the listing would include the post if it were
+  // reloaded. Like the server, it only lists posts classified as code.
+  function wouldList(post) {
+    if (!list.hasAttribute("data-prepend-new")) return false;
+    if (!post.Classification || post.Classification.indexOf("CODE") !== 0) return false;
+    var tag = list.getAttribute("data-tag"), domain = list.getAttribute("data-domain");
+    if (domain && post.Domain !== domain) return false;
+    return !tag || (post.Tags || []).indexOf(tag) !== -1;
+  }
+
+  // postForm creates a form that POSTs to action (with the page's CSRF
+  // token) when its button is clicked.
+  function postForm(action, button) {
+    var token = document.querySelector('meta[name="csrf-token"]');
+    return el("form", {"action": action, "method": "post"}, [
+      el("input", {"type": "hidden", "name": "CSRFToken", "value": token ? token.content : ""}),
+      button
+    ]);
+  }
+
+  function el(tag, attrs, children) {
+    var e = document.createElement(tag);
+    for (var k in attrs) e.setAttribute(k, attrs[k]);
+    (children || []).forEach(function(c) {
+      e.appendChild(typeof c === "string" ? document.createTextNode(c) : c);
+    });
+    return e;
+  }
+
+  // renderPost creates a list item for a post, mirroring the
+  // PostContainerInner template (and the hide link that listings show to
+  // logged-in users).
+  function renderPost(post) {
+    var postURL = "/p/" + post.ID;
+    var actions = [];
+    if (list.hasAttribute("data-can-hide")) {
+      actions.push(el("ul", {"class": "post-actions"}, [
+        el("li", {}, [postForm(postURL + "/hide", el("button", {"type": "submit"}, ["hide"]))])
+      ]));
+    }
+    return el("li", {"class": "post-container", "data-post-id": post.ID}, [
+      el("ul", {"class": "post-info"}, [
+        el("li", {"class": "star", "title": post.Classification}, [
+          el("a", {"href": postURL}, [el("span", {"class": "score-number"}, [String(post.Score)]), " ", el("span", {"class": "icon"}, ["★"])])
+        ]),
+        el("li", {"class": "vote"}, [
+          postForm(postURL + "/vote", el("button", {"type": "submit", "title": "Upvote"}, ["▲"]))
+        ])
+      ]),
+      el("div", {"class": "post"}, [
+        el("header", {}, [el("a", {"class": "post-link", "href": post.LinkURL}, [post.Title || post.LinkURL])].concat(post.Domain ? [
+          " ", el("span", {"class": "domain"}, ["(", el("a", {"href": "/from/" + encodeURIComponent(post.Domain)}, [post.Domain]), ")"])
+        ] : []))
+      ])
+    ].concat(actions));
+  }
+
+  function handleEvent(e) {
+    if (!e.Post) return;
+    var item = postItem(e.Post.ID);
+    switch (e.Type) {
+    case "post:created":
+      if (!item && wouldList(e.Post)) list.insertBefore(renderPost(e.Post), list.firstChild);
+      break;
+    case "post:score":
+      if (item) {
+        var score = item.querySelector(".score-number");
+        if (score) score.textContent = String(e.Post.Score);
+      }
+      break;
+    }
+  }
+
+  function connect() {
+    var ws = new WebSocket((location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/api/live");
+    ws.onopen = function() { retryDelay = minRetryDelay; };
+    ws.onmessage = function(msg) {
+      try { handleEvent(JSON.parse(msg.data)); } catch (e) {}
+    };
+    ws.onclose = function() {
+      setTimeout(connect, retryDelay);
+      retryDelay = Math.min(retryDelay * 2, maxRetryDelay);
+    };
+  }
+  connect();
+})();
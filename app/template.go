@@ -8,48 +8,123 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/tracing"
 )
 
 var (
-	// TemplateDir is the directory containing the html/template template files.
-	TemplateDir = filepath.Join(defaultBase("sourcegraph.com/sourcegraph/thesrc/app"), "tmpl")
+	// TemplateDir is a directory of html/template template files (a theme)
+	// that override the embedded default templates with the same paths. If
+	// it is "", only the default templates are used.
+	TemplateDir string
 )
 
+// templateSets lists the template files of each page template. The first
+// file of each set is the name of the page template.
+var templateSets = [][]string{
+	{"posts/show.html", "posts/common.html", "comments/common.html", "common.html", "layout.html"},
+	{"posts/list.html", "posts/common.html", "common.html", "layout.html"},
+	{"posts/submit_form.html", "common.html", "layout.html"},
+	{"posts/edit_form.html", "common.html", "layout.html"},
+	{"posts/report_form.html", "common.html", "layout.html"},
+	{"posts/reported.html", "common.html", "layout.html"},
+	{"posts/moderation.html", "posts/common.html", "common.html", "layout.html"},
+	{"posts/saved.html", "posts/common.html", "common.html", "layout.html"},
+	{"posts/revisions.html", "common.html", "layout.html"},
+	{"users/signup_form.html", "common.html", "layout.html"},
+	{"users/show.html", "posts/common.html", "common.html", "layout.html"},
+	{"users/login_form.html", "common.html", "layout.html"},
+	{"users/forgot_password_form.html", "common.html", "layout.html"},
+	{"users/reset_password_form.html", "common.html", "layout.html"},
+	{"users/two_factor_form.html", "common.html", "layout.html"},
+	{"users/two_factor.html", "common.html", "layout.html"},
+	{"users/tokens.html", "common.html", "layout.html"},
+	{"users/sessions.html", "common.html", "layout.html"},
+	{"users/follows.html", "common.html", "layout.html"},
+	{"users/settings.html", "common.html", "layout.html"},
+	{"users/notifications.html", "common.html", "layout.html"},
+	{"admin/jobs.html", "common.html", "layout.html"},
+	{"admin/clients.html", "common.html", "layout.html"},
+	{"admin/audit_log.html", "common.html", "layout.html"},
+	{"admin/deleted_posts.html", "common.html", "layout.html"},
+	{"error.html", "common.html", "layout.html"},
+}
+
+// LoadTemplates parses all page templates, exiting if any fails to parse,
+// so that a server with broken templates fails at startup instead of on
+// requests. To reload templates when they change, use RunTemplateWatcher.
 func LoadTemplates() {
-	err := parseHTMLTemplates([][]string{
-		{"posts/show.html", "posts/common.html", "common.html", "layout.html"},
-		{"posts/list.html", "posts/common.html", "common.html", "layout.html"},
-		{"posts/submit_form.html", "common.html", "layout.html"},
-		{"error.html", "common.html", "layout.html"},
-	})
+	err := parseHTMLTemplates(templateSets)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
 // templateCommon is data that is passed to (and available to) all templates.
+// Template data structs should embed it and be passed to renderTemplate as
+// pointers so that renderTemplate can fill it in.
 type templateCommon struct {
+	CurrentUser        *thesrc.User
 	CurrentURL         *url.URL
+	Theme              string
+	ReadOnly           bool
 	PageGenerationTime time.Duration
 }
 
+func (c *templateCommon) setTemplateCommon(tc templateCommon) { *c = tc }
+
 func renderTemplate(w http.ResponseWriter, r *http.Request, name string, status int, data interface{}) error {
-	w.WriteHeader(status)
+	if data, ok := data.(interface {
+		setTemplateCommon(templateCommon)
+	}); ok {
+		user, err := currentUser(r)
+		if err != nil {
+			// Render the page anyway (as though no user is logged in),
+			// because this is called when rendering error pages, too.
+			logError(r, fmt.Errorf("getting current user: %s", err), nil)
+		}
+		data.setTemplateCommon(templateCommon{
+			CurrentUser: user,
+			CurrentURL:  r.URL,
+			Theme:       currentTheme(r, user),
+			ReadOnly:    isReadOnly(),
+		})
+	}
+
+	token, err := csrfToken(w, r)
+	if err != nil {
+		return err
+	}
+
 	if ct := w.Header().Get("content-type"); ct == "" {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	}
+	w.WriteHeader(status)
 
-	t := templates[name]
+	t := lookupTemplate(name)
 	if t == nil {
 		return fmt.Errorf("Template %s not found", name)
 	}
 
+	// Bind the CSRF functions to this request's CSRF token. The loaded templates
+	// themselves are never executed, so that they can be cloned.
+	t, err = t.Clone()
+	if err != nil {
+		return err
+	}
+	t.Funcs(csrfFuncs(token))
+
+	_, span := tracing.Start(r.Context(), tracing.KindInternal, "render "+name)
+	defer span.Finish()
+
 	// Write to a buffer to properly catch errors and avoid partial output written to the http.ResponseWriter
 	var buf bytes.Buffer
-	err := t.Execute(&buf, data)
+	err = t.Execute(&buf, data)
 	if err != nil {
 		return err
 	}
@@ -57,20 +132,49 @@ func renderTemplate(w http.ResponseWriter, r *http.Request, name string, status
 	return err
 }
 
-var templates = map[string]*htmpl.Template{}
+var (
+	templatesMu sync.RWMutex
+	templates   = map[string]*htmpl.Template{}
+)
+
+func lookupTemplate(name string) *htmpl.Template {
+	templatesMu.RLock()
+	defer templatesMu.RUnlock()
+	return templates[name]
+}
+
+// CheckTemplates returns an error if any page template hasn't been loaded
+// (see LoadTemplates).
+func CheckTemplates() error {
+	for _, set := range templateSets {
+		if lookupTemplate(set[0]) == nil {
+			return fmt.Errorf("template %s not loaded", set[0])
+		}
+	}
+	return nil
+}
 
+// parseHTMLTemplates parses the template sets and, only if they all parse,
+// replaces the loaded templates with them.
 func parseHTMLTemplates(sets [][]string) error {
+	tfs := templateFS()
+	parsed := make(map[string]*htmpl.Template, len(sets))
 	for _, set := range sets {
 		t := htmpl.New("")
+		// Placeholders for the CSRF functions, which renderTemplate binds to
+		// each request's CSRF token.
+		t.Funcs(csrfFuncs(""))
 		t.Funcs(htmpl.FuncMap{
-			"urlDomain": urlDomain,
-			"urlTo":     urlTo,
-			"itoa":      strconv.Itoa,
+			"urlTo":    urlTo,
+			"asset":    assetURL,
+			"itoa":     strconv.Itoa,
+			"join":     strings.Join,
+			"markdown": renderMarkdown,
 
 			"googleAnalyticsID": func() string { return os.Getenv("GOOGLE_ANALYTICS_ID") },
 		})
 
-		_, err := t.ParseFiles(joinTemplateDir(TemplateDir, set)...)
+		_, err := t.ParseFS(tfs, set...)
 		if err != nil {
 			return fmt.Errorf("template %v: %s", set, err)
 		}
@@ -79,17 +183,15 @@ func parseHTMLTemplates(sets [][]string) error {
 		if t == nil {
 			return fmt.Errorf("ROOT template not found in %v", set)
 		}
-		templates[set[0]] = t
+		parsed[set[0]] = t
 	}
-	return nil
-}
 
-func joinTemplateDir(base string, files []string) []string {
-	result := make([]string, len(files))
-	for i := range files {
-		result[i] = filepath.Join(base, files[i])
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	for name, t := range parsed {
+		templates[name] = t
 	}
-	return result
+	return nil
 }
 
 func urlTo(routeName string, params ...string) *url.URL {
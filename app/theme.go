@@ -0,0 +1,71 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// themeCookieName is the name of the cookie that holds the color theme
+// chosen by a visitor who isn't logged in.
+const themeCookieName = "thesrc-theme"
+
+// themeCookieLifetime is how long a visitor's choice of theme is remembered.
+const themeCookieLifetime = 365 * 24 * time.Hour
+
+// currentTheme returns the color theme to render r's pages in (see
+// thesrc.User.Theme): the logged-in user's preference, or else the theme
+// cookie's.
+//
+// Because the theme is chosen on the server, pages are never shown in the
+// wrong theme before a script can switch them.
+func currentTheme(r *http.Request, user *thesrc.User) string {
+	if user != nil && user.Theme != "" {
+		return user.Theme
+	}
+	if c, err := r.Cookie(themeCookieName); err == nil && thesrc.ValidTheme(c.Value) {
+		return c.Value
+	}
+	return ""
+}
+
+// serveSetTheme sets the color theme, in the user's settings if they are
+// logged in or else in the theme cookie.
+func serveSetTheme(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	theme := r.PostForm.Get("Theme")
+	if !thesrc.ValidTheme(theme) {
+		handleError(w, r, http.StatusBadRequest, fmt.Errorf("invalid theme %q", theme))
+		return nil
+	}
+
+	user, err := currentUser(r)
+	if err != nil {
+		return err
+	}
+	if user != nil {
+		settings := settingsOf(user)
+		settings.Theme = theme
+		if _, err := apiClient(r).Users.UpdateSettings(settings); err != nil {
+			return err
+		}
+	} else {
+		http.SetCookie(w, &http.Cookie{
+			Name:     themeCookieName,
+			Value:    theme,
+			Path:     "/",
+			Expires:  time.Now().Add(themeCookieLifetime),
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+		})
+	}
+
+	http.Redirect(w, r, localReferer(r, urlTo(router.Posts)).String(), http.StatusSeeOther)
+	return nil
+}
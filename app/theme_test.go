@@ -0,0 +1,100 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestSetTheme_anonymous(t *testing.T) {
+	setup()
+	defer teardown()
+
+	v := url.Values{"Theme": []string{"dark"}}
+	url, _ := router.App().Get(router.SetTheme).URL()
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	var theme string
+	for _, c := range resp.Result().Cookies() {
+		if c.Name == themeCookieName {
+			theme = c.Value
+		}
+	}
+	if want := thesrc.ThemeDark; theme != want {
+		t.Errorf("got theme cookie %q, want %q", theme, want)
+	}
+}
+
+func TestSetTheme_loggedIn(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var updated *thesrc.UserSettings
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice", DisplayName: "Alice", DefaultSort: thesrc.SortNew}, nil
+			},
+			UpdateSettings_: func(settings *thesrc.UserSettings) (*thesrc.User, error) {
+				updated = settings
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+		},
+	}
+
+	v := url.Values{"Theme": []string{"dark"}}
+	url, _ := router.App().Get(router.SetTheme).URL()
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	// The user's other settings must be kept.
+	want := &thesrc.UserSettings{DisplayName: "Alice", Theme: thesrc.ThemeDark, DefaultSort: thesrc.SortNew}
+	if updated == nil || *updated != *want {
+		t.Errorf("got settings %+v, want %+v", updated, want)
+	}
+}
+
+func TestTheme_rendered(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				return nil, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.Posts).URL()
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: themeCookieName, Value: thesrc.ThemeDark})
+	resp := doRequest(req)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	body := resp.Body.String()
+	if !strings.Contains(body, `<html lang="en" class="theme-dark">`) {
+		t.Error("page isn't rendered with the dark theme class")
+	}
+	if !strings.Contains(body, "/static/css/dark.") {
+		t.Error("page doesn't link to the dark theme stylesheet")
+	}
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func serveTokens(w http.ResponseWriter, r *http.Request) error {
+	return renderTokens(w, r, http.StatusOK, nil, "")
+}
+
+// renderTokens renders the page listing the user's personal API tokens. If
+// newToken is non-nil, its value is shown (once) so that the user can copy
+// it.
+func renderTokens(w http.ResponseWriter, r *http.Request, status int, newToken *thesrc.Token, errMsg string) error {
+	tokens, err := apiClient(r).Tokens.List()
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "users/tokens.html", status, &struct {
+		Tokens   []*thesrc.Token
+		NewToken *thesrc.Token
+		Error    string
+		templateCommon
+	}{
+		Tokens:   tokens,
+		NewToken: newToken,
+		Error:    errMsg,
+	})
+}
+
+func serveCreateToken(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	token, err := apiClient(r).Tokens.Create(r.PostForm.Get("Name"))
+	if thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		return renderTokens(w, r, http.StatusBadRequest, nil, "Token name must be 1-100 characters long.")
+	} else if err != nil {
+		return err
+	}
+
+	return renderTokens(w, r, http.StatusOK, token, "")
+}
+
+func serveRevokeToken(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := apiClient(r).Tokens.Revoke(id); err != nil && !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		return err
+	}
+
+	http.Redirect(w, r, urlTo(router.Tokens).String(), http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestTokens_notLoggedIn(t *testing.T) {
+	setup()
+	defer teardown()
+
+	url, _ := router.App().Get(router.Tokens).URL()
+	_, resp := getHTML(t, url)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if loc, want := resp.Header().Get("location"), urlTo(router.LogInForm).String(); loc != want {
+		t.Errorf("got Location %q, want %q", loc, want)
+	}
+}
+
+func TestCreateToken(t *testing.T) {
+	setup()
+	defer teardown()
+
+	newToken := &thesrc.Token{ID: 2, Name: "laptop", Value: thesrc.PersonalTokenPrefix + "secret"}
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+		},
+		Tokens: &thesrc.MockTokensService{
+			Create_: func(name string) (*thesrc.Token, error) {
+				if name != newToken.Name {
+					t.Errorf("got token name %q, want %q", name, newToken.Name)
+				}
+				return newToken, nil
+			},
+			List_: func() ([]*thesrc.Token, error) {
+				return []*thesrc.Token{newToken, {ID: 1, Name: "ci"}}, nil
+			},
+		},
+	}
+
+	v := url.Values{"Name": []string{"laptop"}}
+	url, _ := router.App().Get(router.CreateToken).URL()
+	req, _ := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := html.Find(".new-token input").Attr("value"); got != newToken.Value {
+		t.Errorf("got new token value %q, want %q", got, newToken.Value)
+	}
+	if n := html.Find(".token-name").Length(); n != 2 {
+		t.Errorf("got %d tokens listed, want 2", n)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+		},
+		Tokens: &thesrc.MockTokensService{
+			Revoke_: func(id int) error {
+				if id != 2 {
+					t.Errorf("got revoke of token %d, want 2", id)
+				}
+				called = true
+				return nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.RevokeToken).URL("ID", "2")
+	req, _ := http.NewRequest("POST", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"encoding/base64"
+	"html/template"
+	"net/http"
+
+	qrcode "github.com/skip2/go-qrcode"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// twoFactorPage is the page for managing two-factor authentication.
+type twoFactorPage struct {
+	Enabled bool
+
+	// Setup and QRCode are set while the user is enabling two-factor
+	// authentication.
+	Setup  *thesrc.TwoFactorSetup
+	QRCode template.URL
+
+	// RecoveryCodes are set (once) after the user enables two-factor
+	// authentication or regenerates their recovery codes.
+	RecoveryCodes []string
+
+	Error string
+	templateCommon
+}
+
+func serveTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	user, err := currentUser(r)
+	if err != nil {
+		return err
+	}
+	return renderTwoFactor(w, r, http.StatusOK, &twoFactorPage{Enabled: user.TwoFactorEnabled})
+}
+
+func renderTwoFactor(w http.ResponseWriter, r *http.Request, status int, page *twoFactorPage) error {
+	if page.Setup != nil {
+		png, err := qrcode.Encode(page.Setup.URL, qrcode.Medium, 256)
+		if err != nil {
+			return err
+		}
+		page.QRCode = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(png))
+	}
+	return renderTemplate(w, r, "users/two_factor.html", status, page)
+}
+
+func serveSetUpTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	setup, err := apiClient(r).TwoFactor.Setup()
+	if thesrc.IsHTTPErrorCode(err, http.StatusConflict) {
+		return renderTwoFactor(w, r, http.StatusConflict, &twoFactorPage{Enabled: true, Error: "Two-factor authentication is already enabled."})
+	} else if err != nil {
+		return err
+	}
+	return renderTwoFactor(w, r, http.StatusOK, &twoFactorPage{Setup: setup})
+}
+
+func serveEnableTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	// The setup is resubmitted with the code, so that the form can be shown
+	// again if the code is wrong.
+	setup := &thesrc.TwoFactorSetup{Secret: r.PostForm.Get("Secret"), URL: r.PostForm.Get("URL")}
+
+	codes, err := apiClient(r).TwoFactor.Enable(&thesrc.TwoFactorEnable{Secret: setup.Secret, Code: r.PostForm.Get("Code")})
+	if thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		return renderTwoFactor(w, r, http.StatusBadRequest, &twoFactorPage{Setup: setup, Error: "That code is incorrect. Enter the code currently shown in your authenticator app."})
+	} else if err != nil {
+		return err
+	}
+	return renderTwoFactor(w, r, http.StatusOK, &twoFactorPage{Enabled: true, RecoveryCodes: codes})
+}
+
+func serveDisableTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	err := apiClient(r).TwoFactor.Disable(r.PostForm.Get("Code"))
+	if thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		return renderTwoFactor(w, r, http.StatusBadRequest, &twoFactorPage{Enabled: true, Error: "That code is incorrect."})
+	} else if err != nil && !thesrc.IsHTTPErrorCode(err, http.StatusConflict) {
+		return err
+	}
+
+	http.Redirect(w, r, urlTo(router.TwoFactor).String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	codes, err := apiClient(r).TwoFactor.RegenerateRecoveryCodes(r.PostForm.Get("Code"))
+	if thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		return renderTwoFactor(w, r, http.StatusBadRequest, &twoFactorPage{Enabled: true, Error: "That code is incorrect."})
+	} else if err != nil {
+		return err
+	}
+	return renderTwoFactor(w, r, http.StatusOK, &twoFactorPage{Enabled: true, RecoveryCodes: codes})
+}
+
+// renderTwoFactorForm renders the second step of logging in, for users with
+// two-factor authentication enabled: entering a code.
+func renderTwoFactorForm(w http.ResponseWriter, r *http.Request, status int, token, errMsg string) error {
+	return renderTemplate(w, r, "users/two_factor_form.html", status, &struct {
+		Token string
+		Error string
+		templateCommon
+	}{
+		Token: token,
+		Error: errMsg,
+	})
+}
+
+func serveAuthenticateTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	token := r.PostForm.Get("Token")
+
+	auth, err := loginClient(r).TwoFactor.Authenticate(token, r.PostForm.Get("Code"))
+	if e, ok := err.(*thesrc.ErrorResponse); ok && e.HTTPStatusCode() == http.StatusBadRequest {
+		if len(e.Fields) == 1 && e.Fields[0].Field == "Token" {
+			http.Redirect(w, r, urlTo(router.LogInForm).String(), http.StatusSeeOther)
+			return nil
+		}
+		return renderTwoFactorForm(w, r, http.StatusBadRequest, token, "That code is incorrect.")
+	} else if err != nil {
+		return err
+	}
+
+	setSessionToken(w, r, auth.Token)
+	http.Redirect(w, r, urlTo(router.Posts).String(), http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestLogIn_twoFactor(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Authenticate_: func(login, password string) (*thesrc.Auth, error) {
+				return &thesrc.Auth{TwoFactorToken: "tf"}, nil
+			},
+		},
+	}
+
+	resp := postForm(t, router.LogIn, url.Values{"Login": {"alice"}, "Password": {"password"}})
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if c := sessionCookie(resp); c != nil {
+		t.Errorf("got session cookie %v before two-factor authentication, want none", c)
+	}
+	html, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := html.Find("input[name=Token]").Attr("value"); got != "tf" {
+		t.Errorf("got Token %q, want %q", got, "tf")
+	}
+}
+
+func TestAuthenticateTwoFactor(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		TwoFactor: &thesrc.MockTwoFactorService{
+			Authenticate_: func(token, code string) (*thesrc.Auth, error) {
+				if token != "tf" || code != "123456" {
+					t.Errorf("got token %q and code %q, want %q and %q", token, code, "tf", "123456")
+				}
+				called = true
+				return &thesrc.Auth{User: &thesrc.User{ID: 1, Login: "alice"}, Token: "tok"}, nil
+			},
+		},
+	}
+
+	resp := postForm(t, router.AuthenticateTwoFactor, url.Values{"Token": {"tf"}, "Code": {"123456"}})
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	if c := sessionCookie(resp); c == nil || c.Value != "tok" {
+		t.Errorf("got session cookie %v, want value %q", c, "tok")
+	}
+}
+
+func TestSetUpTwoFactor(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+		},
+		TwoFactor: &thesrc.MockTwoFactorService{
+			Setup_: func() (*thesrc.TwoFactorSetup, error) {
+				return &thesrc.TwoFactorSetup{Secret: "JBSWY3DPEHPK3PXP", URL: "otpauth://totp/thesrc:alice?issuer=thesrc&secret=JBSWY3DPEHPK3PXP"}, nil
+			},
+		},
+	}
+
+	resp := postForm(t, router.SetUpTwoFactor, nil, &http.Cookie{Name: sessionCookieName, Value: "tok"})
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Fatalf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	html, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := html.Find("input[name=Secret]").Attr("value"); got != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("got Secret %q, want the new secret", got)
+	}
+	if src, _ := html.Find("img.qr-code").Attr("src"); len(src) < 100 {
+		t.Errorf("got QR code src %q, want a data URL", src)
+	}
+}
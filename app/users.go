@@ -0,0 +1,162 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func serveSignupForm(w http.ResponseWriter, r *http.Request) error {
+	return renderTemplate(w, r, "users/signup_form.html", http.StatusOK, &struct {
+		NewUser *thesrc.NewUser
+		Error   string
+		templateCommon
+	}{
+		NewUser: &thesrc.NewUser{},
+	})
+}
+
+func serveSignup(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	var newUser thesrc.NewUser
+	if err := schemaDecoder.Decode(&newUser, r.PostForm); err != nil {
+		return err
+	}
+
+	renderForm := func(status int, msg string) error {
+		newUser.Password = ""
+		return renderTemplate(w, r, "users/signup_form.html", status, &struct {
+			NewUser *thesrc.NewUser
+			Error   string
+			templateCommon
+		}{
+			NewUser: &newUser,
+			Error:   msg,
+		})
+	}
+
+	if err := newUser.Validate(); err != nil {
+		return renderForm(http.StatusBadRequest, "Invalid signup: "+err.Error()+".")
+	}
+
+	auth, err := loginClient(r).Users.Signup(&newUser)
+	if thesrc.IsHTTPErrorCode(err, http.StatusConflict) {
+		return renderForm(http.StatusConflict, "That login is already taken.")
+	} else if err != nil {
+		return err
+	}
+
+	setSessionToken(w, r, auth.Token)
+	http.Redirect(w, r, urlTo(router.Posts).String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveLogInForm(w http.ResponseWriter, r *http.Request) error {
+	return renderTemplate(w, r, "users/login_form.html", http.StatusOK, &struct {
+		Login string
+		Error string
+		templateCommon
+	}{})
+}
+
+func serveLogIn(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	login := r.PostForm.Get("Login")
+
+	auth, err := loginClient(r).Users.Authenticate(login, r.PostForm.Get("Password"))
+	if thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		return renderTemplate(w, r, "users/login_form.html", http.StatusUnauthorized, &struct {
+			Login string
+			Error string
+			templateCommon
+		}{
+			Login: login,
+			Error: "Invalid login or password.",
+		})
+	} else if err != nil {
+		return err
+	}
+	if auth.TwoFactorToken != "" {
+		return renderTwoFactorForm(w, r, http.StatusOK, auth.TwoFactorToken, "")
+	}
+
+	setSessionToken(w, r, auth.Token)
+	http.Redirect(w, r, urlTo(router.Posts).String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveLogOut(w http.ResponseWriter, r *http.Request) error {
+	if sessionToken(r) != "" {
+		// If the session is already invalid, there's nothing to revoke.
+		if err := apiClient(r).Sessions.RevokeCurrent(); err != nil && !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+			return err
+		}
+	}
+	clearSession(w)
+	http.Redirect(w, r, urlTo(router.Posts).String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveUser(w http.ResponseWriter, r *http.Request) error {
+	user, err := apiClient(r).Users.Get(mux.Vars(r)["Login"])
+	if thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		handleError(w, r, http.StatusNotFound, err)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	posts, err := apiClient(r).Posts.List(&thesrc.PostListOptions{
+		AuthorUserID: user.ID,
+		Sort:         thesrc.SortNew,
+		ListOptions:  thesrc.ListOptions{PerPage: 30},
+	})
+	if err != nil {
+		return err
+	}
+
+	comments, err := APIClient.Comments.List(&thesrc.CommentListOptions{
+		AuthorUserID: user.ID,
+		ListOptions:  thesrc.ListOptions{PerPage: 30},
+	})
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "users/show.html", http.StatusOK, &struct {
+		User     *thesrc.User
+		Posts    []*thesrc.Post
+		Comments []*thesrc.Comment
+		templateCommon
+	}{
+		User:     user,
+		Posts:    posts,
+		Comments: comments,
+	})
+}
+
+func serveShadowBanUser(w http.ResponseWriter, r *http.Request) error {
+	login := mux.Vars(r)["Login"]
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	client := auditClient(r)
+	var ban thesrc.UserShadowBan
+	if err := schemaDecoder.Decode(&ban, r.PostForm); err != nil {
+		return err
+	}
+
+	if err := client.Users.SetShadowBanned(login, ban.ShadowBanned); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, urlTo(router.User, "Login", login).String(), http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,222 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func postForm(t *testing.T, routeName string, v url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	url, _ := router.App().Get(routeName).URL()
+	req, err := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+
+	resp := httptest.NewRecorder()
+	resp.Body = new(bytes.Buffer)
+	addCSRFToken(req)
+	testMux.ServeHTTP(resp, req)
+	return resp
+}
+
+func sessionCookie(resp *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range (&http.Response{Header: resp.Header()}).Cookies() {
+		if c.Name == sessionCookieName {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestSignup(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Signup_: func(user *thesrc.NewUser) (*thesrc.Auth, error) {
+				if want := (thesrc.NewUser{Login: "alice", Password: "password"}); *user != want {
+					t.Errorf("got new user %+v, want %+v", user, want)
+				}
+				called = true
+				return &thesrc.Auth{User: &thesrc.User{ID: 1, Login: "alice"}, Token: "tok"}, nil
+			},
+		},
+	}
+
+	resp := postForm(t, router.Signup, url.Values{"Login": {"alice"}, "Password": {"password"}})
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	if c := sessionCookie(resp); c == nil || c.Value != "tok" {
+		t.Errorf("got session cookie %v, want value %q", c, "tok")
+	}
+}
+
+func TestSignup_invalid(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{Users: &thesrc.MockUsersService{}}
+
+	resp := postForm(t, router.Signup, url.Values{"Login": {"alice"}, "Password": {"short"}})
+
+	if want := http.StatusBadRequest; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if c := sessionCookie(resp); c != nil {
+		t.Errorf("got session cookie %v, want none", c)
+	}
+}
+
+func TestLogIn(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Authenticate_: func(login, password string) (*thesrc.Auth, error) {
+				if login != "alice" || password != "password" {
+					t.Errorf("got login %q and password %q, want %q and %q", login, password, "alice", "password")
+				}
+				called = true
+				return &thesrc.Auth{User: &thesrc.User{ID: 1, Login: "alice"}, Token: "tok"}, nil
+			},
+		},
+	}
+
+	resp := postForm(t, router.LogIn, url.Values{"Login": {"alice"}, "Password": {"password"}})
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	if c := sessionCookie(resp); c == nil || c.Value != "tok" {
+		t.Errorf("got session cookie %v, want value %q", c, "tok")
+	}
+}
+
+func TestLogOut(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Sessions: &thesrc.MockSessionsService{
+			RevokeCurrent_: func() error {
+				called = true
+				return nil
+			},
+		},
+	}
+
+	resp := postForm(t, router.LogOut, nil, &http.Cookie{Name: sessionCookieName, Value: "tok"})
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	if c := sessionCookie(resp); c == nil || c.MaxAge >= 0 {
+		t.Errorf("got session cookie %v, want it to be deleted", c)
+	}
+}
+
+func TestCurrentUserInHeader(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{},
+		Users: &thesrc.MockUsersService{
+			Current_: func() (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "alice"}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.Posts).URL()
+	req, _ := http.NewRequest("GET", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	resp := httptest.NewRecorder()
+	testMux.ServeHTTP(resp, req)
+
+	if !strings.Contains(resp.Body.String(), `<li class="current-user"><a href="/users/alice">alice</a></li>`) {
+		t.Errorf("current user not shown in page header:\n%s", resp.Body.String())
+	}
+}
+
+func TestUser(t *testing.T) {
+	setup()
+	defer teardown()
+
+	user := &thesrc.User{ID: 1, Login: "alice", Karma: 12}
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Get_: func(login string) (*thesrc.User, error) {
+				if login != user.Login {
+					resp := &http.Response{StatusCode: http.StatusNotFound, Request: httptest.NewRequest("GET", "/api/users/"+login, nil)}
+					return nil, &thesrc.ErrorResponse{Response: resp}
+				}
+				return user, nil
+			},
+		},
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if opt.AuthorUserID != user.ID {
+					t.Errorf("got posts by user %d, want %d", opt.AuthorUserID, user.ID)
+				}
+				return []*thesrc.Post{{ID: 2, Title: "p"}}, nil
+			},
+		},
+		Comments: &thesrc.MockCommentsService{
+			List_: func(opt *thesrc.CommentListOptions) ([]*thesrc.Comment, error) {
+				if opt.AuthorUserID != user.ID {
+					t.Errorf("got comments by user %d, want %d", opt.AuthorUserID, user.ID)
+				}
+				return []*thesrc.Comment{{ID: 3, PostID: 2, Body: "c"}}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.User).URL("Login", "alice")
+	html, resp := getHTML(t, url)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if karma := html.Find(".karma").Text(); karma != "12" {
+		t.Errorf("got karma %q, want %q", karma, "12")
+	}
+	if title := html.Find("a.post-link").Text(); title != "p" {
+		t.Errorf("got post title %q, want %q", title, "p")
+	}
+	if href, _ := html.Find(".user-comments .comment-info a").Attr("href"); href != "/p/2#c3" {
+		t.Errorf("got comment link %q, want %q", href, "/p/2#c3")
+	}
+
+	url, _ = router.App().Get(router.User).URL("Login", "bob")
+	if _, resp := getHTML(t, url); resp.Code != http.StatusNotFound {
+		t.Errorf("got HTTP status %d for nonexistent user, want %d", resp.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func serveUpvote(w http.ResponseWriter, r *http.Request) error {
+	return serveVote(w, r, true)
+}
+
+func serveUnvote(w http.ResponseWriter, r *http.Request) error {
+	return serveVote(w, r, false)
+}
+
+func serveVote(w http.ResponseWriter, r *http.Request, up bool) error {
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if sessionToken(r) == "" {
+		http.Redirect(w, r, urlTo(router.LogInForm).String(), http.StatusSeeOther)
+		return nil
+	}
+
+	votes := apiClient(r).Votes
+	if up {
+		err = votes.Upvote(postID)
+	} else {
+		err = votes.Unvote(postID)
+	}
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, localReferer(r, urlTo(router.Post, "ID", strconv.Itoa(postID))).String(), http.StatusSeeOther)
+	return nil
+}
+
+func serveUpvoteComment(w http.ResponseWriter, r *http.Request) error {
+	return serveCommentVote(w, r, true)
+}
+
+func serveUnvoteComment(w http.ResponseWriter, r *http.Request) error {
+	return serveCommentVote(w, r, false)
+}
+
+func serveCommentVote(w http.ResponseWriter, r *http.Request, up bool) error {
+	commentID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if sessionToken(r) == "" {
+		http.Redirect(w, r, urlTo(router.LogInForm).String(), http.StatusSeeOther)
+		return nil
+	}
+
+	comment, err := APIClient.Comments.Get(commentID)
+	if thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		handleError(w, r, http.StatusNotFound, err)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	votes := apiClient(r).Votes
+	if up {
+		err = votes.UpvoteComment(commentID)
+	} else {
+		err = votes.UnvoteComment(commentID)
+	}
+	if err != nil {
+		return err
+	}
+
+	dest := localReferer(r, urlTo(router.Post, "ID", strconv.Itoa(comment.PostID)))
+	dest.Fragment = "c" + strconv.Itoa(commentID)
+	http.Redirect(w, r, dest.String(), http.StatusSeeOther)
+	return nil
+}
+
+// localReferer returns the path (and query) of r's referer, so that
+// handlers can redirect users back to the page they came from. If there is
+// no referer or it is on another host, fallback is returned.
+func localReferer(r *http.Request, fallback *url.URL) *url.URL {
+	ref, err := url.Parse(r.Referer())
+	if err != nil || ref.Path == "" || (ref.Host != "" && ref.Host != r.Host) {
+		return fallback
+	}
+	return &url.URL{Path: ref.Path, RawQuery: ref.RawQuery}
+}
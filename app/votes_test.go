@@ -0,0 +1,137 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestUpvote(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Votes: &thesrc.MockVotesService{
+			Upvote_: func(postID int) error {
+				if postID != 1 {
+					t.Errorf("got post ID %d, want %d", postID, 1)
+				}
+				called = true
+				return nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.Upvote).URL("ID", "1")
+	req, _ := http.NewRequest("POST", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	req.Header.Set("Referer", "http://example.com/?page=2")
+	req.Host = "example.com"
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	if loc, want := resp.Header().Get("location"), "/?page=2"; loc != want {
+		t.Errorf("got Location %q, want %q", loc, want)
+	}
+}
+
+func TestUpvote_notLoggedIn(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{Votes: &thesrc.MockVotesService{
+		Upvote_: func(postID int) error {
+			t.Error("Upvote called")
+			return nil
+		},
+	}}
+
+	url, _ := router.App().Get(router.Upvote).URL("ID", "1")
+	req, _ := http.NewRequest("POST", url.String(), nil)
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if loc, want := resp.Header().Get("location"), urlTo(router.LogInForm).String(); loc != want {
+		t.Errorf("got Location %q, want %q", loc, want)
+	}
+}
+
+func TestUnvote(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Votes: &thesrc.MockVotesService{
+			Unvote_: func(postID int) error {
+				called = true
+				return nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.Unvote).URL("ID", "1")
+	req, _ := http.NewRequest("POST", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if !called {
+		t.Error("!called")
+	}
+	if loc, want := resp.Header().Get("location"), urlTo(router.Post, "ID", "1").String(); loc != want {
+		t.Errorf("got Location %q, want %q", loc, want)
+	}
+}
+
+func TestUpvoteComment(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Comments: &thesrc.MockCommentsService{
+			Get_: func(id int) (*thesrc.Comment, error) {
+				return &thesrc.Comment{ID: id, PostID: 2}, nil
+			},
+		},
+		Votes: &thesrc.MockVotesService{
+			UpvoteComment_: func(commentID int) error {
+				if commentID != 1 {
+					t.Errorf("got comment ID %d, want %d", commentID, 1)
+				}
+				called = true
+				return nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.UpvoteComment).URL("ID", "1")
+	req, _ := http.NewRequest("POST", url.String(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "tok"})
+	addCSRFToken(req)
+	resp := doRequest(req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	// Without a referer, the user is sent to the comment on the post's page.
+	if loc, want := resp.Header().Get("location"), urlTo(router.Post, "ID", "2").String()+"#c1"; loc != want {
+		t.Errorf("got Location %q, want %q", loc, want)
+	}
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RunTemplateWatcher checks TemplateDir and StaticDir for changed, added,
+// and removed files every interval, until stop is closed. When templates
+// change, it reloads them; if they fail to parse, it logs the error and
+// keeps serving the previously loaded templates. When static assets change,
+// it clears their cached fingerprints (see assetURL). It is meant for
+// development; in production, templates are loaded once at startup (see
+// LoadTemplates).
+func RunTemplateWatcher(interval time.Duration, stop <-chan struct{}) {
+	tmpls, static := snapshotDir(TemplateDir), snapshotDir(StaticDir)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if s := snapshotDir(TemplateDir); !s.equal(tmpls) {
+				tmpls = s
+				if err := parseHTMLTemplates(templateSets); err != nil {
+					log.Printf("Reloading templates: %s", err)
+				} else {
+					log.Print("Reloaded templates")
+				}
+			}
+			if s := snapshotDir(StaticDir); !s.equal(static) {
+				static = s
+				resetAssetHashes()
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// A dirSnapshot records the size and modification time of each file in a
+// directory tree, keyed by path.
+type dirSnapshot map[string]fileStamp
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshotDir returns a snapshot of the files in the directory tree rooted
+// at dir. Files that can't be read are omitted.
+func snapshotDir(dir string) dirSnapshot {
+	s := dirSnapshot{}
+	filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err == nil && fi.Mode().IsRegular() {
+			s[path] = fileStamp{size: fi.Size(), modTime: fi.ModTime()}
+		}
+		return nil
+	})
+	return s
+}
+
+func (s dirSnapshot) equal(t dirSnapshot) bool {
+	if len(s) != len(t) {
+		return false
+	}
+	for path, stamp := range s {
+		if other, ok := t[path]; !ok || other.size != stamp.size || !other.modTime.Equal(stamp.modTime) {
+			return false
+		}
+	}
+	return true
+}
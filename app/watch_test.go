@@ -0,0 +1,51 @@
+package app
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunTemplateWatcher_staticAssets(t *testing.T) {
+	defer withTestStaticDir(t, map[string]string{"css/main.css": "body {}"})()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go RunTemplateWatcher(10*time.Millisecond, stop)
+
+	before := assetURL("css/main.css")
+	time.Sleep(50 * time.Millisecond)
+	if err := ioutil.WriteFile(filepath.Join(StaticDir, "css", "main.css"), []byte("body { color: red; }"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for assetURL("css/main.css") == before {
+		if time.Now().After(deadline) {
+			t.Fatalf("asset URL is still %q after the asset changed", before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDirSnapshot_equal(t *testing.T) {
+	now := time.Now()
+	a := dirSnapshot{"a": {size: 1, modTime: now}}
+	tests := []struct {
+		b    dirSnapshot
+		want bool
+	}{
+		{dirSnapshot{"a": {size: 1, modTime: now}}, true},
+		{dirSnapshot{"a": {size: 2, modTime: now}}, false},
+		{dirSnapshot{"a": {size: 1, modTime: now.Add(time.Second)}}, false},
+		{dirSnapshot{"b": {size: 1, modTime: now}}, false},
+		{dirSnapshot{"a": {size: 1, modTime: now}, "b": {}}, false},
+		{dirSnapshot{}, false},
+	}
+	for _, test := range tests {
+		if got := a.equal(test.b); got != test.want {
+			t.Errorf("%v == %v: got %v, want %v", a, test.b, got, test.want)
+		}
+	}
+}
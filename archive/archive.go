@@ -0,0 +1,85 @@
+// Package archive saves and finds snapshots of pages in the Internet
+// Archive's Wayback Machine, so that readers can reach posts' linked pages
+// after the links die.
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// waybackURL is the base URL of the Wayback Machine's API, and saveURL is the
+// URL prefix of its Save Page Now service.
+var (
+	waybackURL = "https://archive.org/wayback/"
+	saveURL    = "https://web.archive.org/save/"
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// saveClient is used for Save Page Now requests, which wait for the page to
+// be captured and may take much longer than API requests.
+var saveClient = &http.Client{Timeout: 2 * time.Minute}
+
+// Save asks the Wayback Machine to capture linkURL's page now, and returns
+// the URL of the snapshot.
+func Save(linkURL string) (string, error) {
+	resp, err := saveClient.Get(saveURL + linkURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("non-200 HTTP response status: %d", resp.StatusCode)
+	}
+
+	// The snapshot's URL is either given in the Content-Location header or
+	// redirected to.
+	if loc := resp.Header.Get("Content-Location"); loc != "" {
+		u, err := resp.Request.URL.Parse(loc)
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+	if strings.HasPrefix(resp.Request.URL.Path, "/web/") {
+		return resp.Request.URL.String(), nil
+	}
+	return "", fmt.Errorf("no snapshot URL in response to saving %s", linkURL)
+}
+
+// Snapshot returns the URL of the Wayback Machine's most recent successful
+// snapshot of linkURL, or "" if it has none.
+func Snapshot(linkURL string) (string, error) {
+	resp, err := httpClient.Get(waybackURL + "available?url=" + url.QueryEscape(linkURL))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("non-200 HTTP response status: %d", resp.StatusCode)
+	}
+
+	var res struct {
+		ArchivedSnapshots struct {
+			Closest *struct {
+				Available bool
+				URL       string
+				Status    string
+			}
+		} `json:"archived_snapshots"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+	if c := res.ArchivedSnapshots.Closest; c != nil && c.Available && c.Status == "200" {
+		return c.URL, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,90 @@
+package archive
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/available", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("url") {
+		case "http://example.com/a?b=c":
+			fmt.Fprint(w, `{"archived_snapshots": {"closest": {"available": true, "url": "http://web.archive.org/web/20140513165320/http://example.com/a?b=c", "timestamp": "20140513165320", "status": "200"}}}`)
+		case "http://example.com/404":
+			fmt.Fprint(w, `{"archived_snapshots": {"closest": {"available": true, "url": "http://web.archive.org/web/20140513165320/http://example.com/404", "timestamp": "20140513165320", "status": "404"}}}`)
+		default:
+			fmt.Fprint(w, `{"archived_snapshots": {}}`)
+		}
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	defer func(orig string) { waybackURL = orig }(waybackURL)
+	waybackURL = s.URL + "/"
+
+	tests := map[string]string{
+		"http://example.com/a?b=c": "http://web.archive.org/web/20140513165320/http://example.com/a?b=c",
+		"http://example.com/404":   "",
+		"http://example.com/none":  "",
+	}
+	for linkURL, want := range tests {
+		got, err := Snapshot(linkURL)
+		if err != nil {
+			t.Errorf("%s: %s", linkURL, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: got snapshot %q, want %q", linkURL, got, want)
+		}
+	}
+}
+
+func TestSave(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/save/") {
+			return // a snapshot
+		}
+		switch linkURL := strings.TrimPrefix(r.URL.Path, "/save/"); linkURL {
+		case "http://example.com/a":
+			w.Header().Set("Content-Location", "/web/20140513165320/"+linkURL)
+		case "http://example.com/b":
+			w.Header().Set("Location", "/web/20140513165321/"+linkURL)
+			w.WriteHeader(http.StatusFound)
+		case "http://example.com/c":
+			// No snapshot URL.
+		default:
+			http.Error(w, "blocked", http.StatusForbidden)
+		}
+	}))
+	defer s.Close()
+
+	defer func(orig string) { saveURL = orig }(saveURL)
+	saveURL = s.URL + "/save/"
+
+	tests := map[string]string{
+		"http://example.com/a": s.URL + "/web/20140513165320/http://example.com/a",
+		"http://example.com/b": s.URL + "/web/20140513165321/http://example.com/b",
+		"http://example.com/c": "",
+		"http://example.com/d": "",
+	}
+	for linkURL, want := range tests {
+		got, err := Save(linkURL)
+		if want == "" {
+			if err == nil {
+				t.Errorf("%s: got snapshot %q, want error", linkURL, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", linkURL, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: got snapshot %q, want %q", linkURL, got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package archive
+
+import (
+	"log"
+	"sync"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
+)
+
+// DefaultConcurrency is the default value of Archiver.Concurrency.
+const DefaultConcurrency = 2
+
+// save captures pages. It is a variable so that tests can replace it.
+var save = Save
+
+var saves = metrics.NewCounterVec("thesrc_archive_saves_total",
+	"Wayback Machine captures of new posts' linked pages, by result (success or error).", "result")
+
+// An Archiver asks the Wayback Machine to capture the linked pages of new
+// posts, and records the snapshots' URLs as the posts' LinkArchiveURLs.
+type Archiver struct {
+	Store datastore.LinkChecksStore
+
+	// Concurrency is the maximum number of captures in progress at once. If
+	// 0, DefaultConcurrency is used.
+	Concurrency int
+
+	wg   sync.WaitGroup
+	once sync.Once
+	sem  chan struct{}
+}
+
+// Run captures the linked pages of the posts created by the events received
+// on c, until c is closed. Captures happen in the background; call Wait to
+// wait for them to finish.
+func (a *Archiver) Run(c <-chan *thesrc.Event) {
+	a.once.Do(func() {
+		n := a.Concurrency
+		if n == 0 {
+			n = DefaultConcurrency
+		}
+		a.sem = make(chan struct{}, n)
+	})
+
+	for e := range c {
+		// Don't archive pages linked by posts held for moderation, which are
+		// likely spam.
+		if e.Type != thesrc.EventPostCreated || e.Post.LinkURL == "" || e.Post.Hidden {
+			continue
+		}
+
+		a.wg.Add(1)
+		go func(post *thesrc.Post) {
+			defer a.wg.Done()
+			a.sem <- struct{}{}
+			defer func() { <-a.sem }()
+			a.archive(post)
+		}(e.Post)
+	}
+}
+
+// Wait waits for all captures started by Run to finish.
+func (a *Archiver) Wait() { a.wg.Wait() }
+
+func (a *Archiver) archive(post *thesrc.Post) {
+	archiveURL, err := save(post.LinkURL)
+	if err == nil {
+		err = a.Store.SetArchiveURL(post.ID, archiveURL)
+	}
+	if err != nil {
+		log.Printf("Archiving post %d (%s): %s", post.ID, post.LinkURL, err)
+		saves.Inc("error")
+		return
+	}
+	saves.Inc("success")
+}
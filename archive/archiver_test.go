@@ -0,0 +1,49 @@
+package archive
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestArchiver(t *testing.T) {
+	defer func(orig func(string) (string, error)) { save = orig }(save)
+	save = func(linkURL string) (string, error) {
+		if linkURL == "http://example.com/error" {
+			return "", errors.New("capture failed")
+		}
+		return "http://web.archive.org/web/2014/" + linkURL, nil
+	}
+
+	var mu sync.Mutex
+	archiveURLs := map[int]string{}
+	store := &datastore.MockLinkChecksStore{
+		SetArchiveURL_: func(postID int, archiveURL string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			archiveURLs[postID] = archiveURL
+			return nil
+		},
+	}
+
+	c := make(chan *thesrc.Event, 5)
+	c <- &thesrc.Event{Type: thesrc.EventPostCreated, Post: &thesrc.Post{ID: 1, LinkURL: "http://example.com/1"}}
+	c <- &thesrc.Event{Type: thesrc.EventPostCreated, Post: &thesrc.Post{ID: 2, LinkURL: "http://example.com/error"}}
+	c <- &thesrc.Event{Type: thesrc.EventPostCreated, Post: &thesrc.Post{ID: 3, Body: "self-post"}}
+	c <- &thesrc.Event{Type: thesrc.EventPostCreated, Post: &thesrc.Post{ID: 4, LinkURL: "http://example.com/spam", Hidden: true}}
+	c <- &thesrc.Event{Type: thesrc.EventPostUpdated, Post: &thesrc.Post{ID: 5, LinkURL: "http://example.com/5"}}
+	close(c)
+
+	a := &Archiver{Store: store}
+	a.Run(c)
+	a.Wait()
+
+	want := map[int]string{1: "http://web.archive.org/web/2014/http://example.com/1"}
+	if !reflect.DeepEqual(archiveURLs, want) {
+		t.Errorf("got archive URLs %v, want %v", archiveURLs, want)
+	}
+}
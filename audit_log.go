@@ -0,0 +1,125 @@
+package thesrc
+
+import (
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// An AuditEntry records a privileged action that an admin or moderator took
+// (or that was taken from the command line, such as with "thesrc
+// grant-role"). The audit log is append-only: entries are never changed or
+// deleted.
+type AuditEntry struct {
+	// ID a unique identifier for this entry.
+	ID int
+
+	// ActorUserID is the ID of the user that took the action, or 0 if it was
+	// taken from the command line.
+	ActorUserID int
+
+	// ActorLogin is the login of the user with ID ActorUserID. It is only set
+	// by AuditLogService.List.
+	ActorLogin string `db:"-" json:",omitempty"`
+
+	// Action is the action that was taken (one of the Audit* constants).
+	Action string
+
+	// Target is what the action was taken on, such as "post:123" or
+	// "user:alice".
+	Target string
+
+	// Details describes the action further (such as the role that a user was
+	// given), if needed.
+	Details string `json:",omitempty"`
+
+	// Reason is the reason that the actor gave for the action (see
+	// Client.WithAuditReason), if any.
+	Reason string `json:",omitempty"`
+
+	// CreatedAt is when the action was taken.
+	CreatedAt time.Time
+}
+
+// The actions that are recorded in the audit log.
+const (
+	AuditDeletePost         = "delete-post" // deleted another user's post
+	AuditUndeletePost       = "undelete-post"
+	AuditHidePost           = "hide-post"
+	AuditUnhidePost         = "unhide-post"
+	AuditKillPost           = "kill-post"
+	AuditUnkillPost         = "unkill-post"
+	AuditKillComment        = "kill-comment"
+	AuditUntagPost          = "untag-post"
+	AuditRetagPost          = "retag-post"
+	AuditQueuePost          = "queue-post"
+	AuditShadowBan          = "shadow-ban"
+	AuditUnban              = "unban"
+	AuditSetRole            = "set-role"
+	AuditNullifyVotes       = "nullify-votes"
+	AuditRestoreVotes       = "restore-votes"
+	AuditDismissVoteSuspect = "dismiss-vote-suspect"
+	AuditCreateWebhook      = "create-webhook"
+	AuditDeleteWebhook      = "delete-webhook"
+	AuditRetryJob           = "retry-job"
+	AuditUpdateSiteStatus   = "update-site-status"
+)
+
+// AuditReasonHeader is the HTTP header in which a client sends the reason
+// for the privileged action that it requests, to be recorded in the audit
+// log (see Client.WithAuditReason). The reason is query-escaped (see
+// url.QueryEscape).
+const AuditReasonHeader = "X-Thesrc-Audit-Reason"
+
+// AuditLogService interacts with the audit log endpoints in thesrc's API.
+// Only admins may use it.
+type AuditLogService interface {
+	// List audit log entries, newest first.
+	List(opt *AuditLogListOptions) ([]*AuditEntry, error)
+}
+
+type AuditLogListOptions struct {
+	// ActorUserID, Action, and Target (if set) filter the result set to
+	// entries of actions taken by the user, of the action, and on the
+	// target.
+	ActorUserID int    `url:",omitempty" json:",omitempty"`
+	Action      string `url:",omitempty" json:",omitempty"`
+	Target      string `url:",omitempty" json:",omitempty"`
+
+	ListOptions
+}
+
+type auditLogService struct{ client *Client }
+
+func (s *auditLogService) List(opt *AuditLogListOptions) ([]*AuditEntry, error) {
+	url, err := s.client.url(router.AuditLog, nil, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*AuditEntry
+	_, err = s.client.Do(req, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+type MockAuditLogService struct {
+	List_ func(opt *AuditLogListOptions) ([]*AuditEntry, error)
+}
+
+var _ AuditLogService = &MockAuditLogService{}
+
+func (s *MockAuditLogService) List(opt *AuditLogListOptions) ([]*AuditEntry, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
@@ -0,0 +1,63 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestAuditLogService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*AuditEntry{{ID: 1, ActorUserID: 2, ActorLogin: "bob", Action: AuditKillPost, Target: "post:3", Reason: "spam"}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.AuditLog, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"Action": AuditKillPost, "PerPage": "5"})
+
+		writeJSON(w, want)
+	})
+
+	entries, err := client.AuditLog.List(&AuditLogListOptions{Action: AuditKillPost, ListOptions: ListOptions{PerPage: 5}})
+	if err != nil {
+		t.Errorf("AuditLog.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, e := range want {
+		normalizeTime(&e.CreatedAt)
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("AuditLog.List returned %+v, want %+v", entries, want)
+	}
+}
+
+func TestClient_WithAuditReason(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.ShadowBanUser, map[string]string{"Login": "alice"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		// The client escapes the reason (and the API unescapes it).
+		if got, want := r.Header.Get(AuditReasonHeader), "spam+ring"; got != want {
+			t.Errorf("got audit reason %q, want %q", got, want)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.WithAuditReason("spam ring").Users.SetShadowBanned("alice", true); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Fatal("!called")
+	}
+}
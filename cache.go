@@ -0,0 +1,108 @@
+package thesrc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// A ResponseCache stores API responses so that a Client (see Client.Cache)
+// can make conditional requests for them, and reuse a stored response when
+// the API reports that it hasn't changed. Implementations must be safe for
+// concurrent use.
+type ResponseCache interface {
+	// Get returns the response stored under key, if any.
+	Get(key string) (*CachedResponse, bool)
+
+	// Set stores resp under key.
+	Set(key string, resp *CachedResponse)
+}
+
+// A CachedResponse is an API response stored in a ResponseCache.
+type CachedResponse struct {
+	ETag         string // the response's ETag header
+	LastModified string // the response's Last-Modified header
+	Body         []byte // the response body
+}
+
+// CacheOption returns a ClientOption that makes conditional requests using
+// cache. See Client.Cache.
+func CacheOption(cache ResponseCache) ClientOption {
+	return func(c *Client) { c.Cache = cache }
+}
+
+// NewMemoryResponseCache returns a ResponseCache that stores up to
+// maxEntries responses in memory. When it is full, it is cleared.
+func NewMemoryResponseCache(maxEntries int) ResponseCache {
+	return &memoryResponseCache{maxEntries: maxEntries, entries: map[string]*CachedResponse{}}
+}
+
+type memoryResponseCache struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*CachedResponse
+}
+
+func (c *memoryResponseCache) Get(key string) (*CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resp, ok := c.entries[key]
+	return resp, ok
+}
+
+func (c *memoryResponseCache) Set(key string, resp *CachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.entries = map[string]*CachedResponse{}
+	}
+	c.entries[key] = resp
+}
+
+// responseCacheKey returns the key under which the response to req is
+// cached, or "" if it isn't cacheable. API responses vary by the user that
+// the request is authenticated as, so the key includes (a hash of) the
+// Authorization header.
+func responseCacheKey(req *http.Request) string {
+	if req.Method != "GET" {
+		return ""
+	}
+	key := req.URL.String()
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		sum := sha256.Sum256([]byte(auth))
+		key += " " + hex.EncodeToString(sum[:])
+	}
+	return key
+}
+
+// addConditionalHeaders adds the headers to req that make it conditional on
+// the cached response having changed.
+func addConditionalHeaders(req *http.Request, cached *CachedResponse) {
+	if cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+}
+
+// cacheResponse stores resp (a successful response) in cache under key, if
+// resp has an ETag or Last-Modified header. It reads resp's body, so it
+// replaces the body with a reader of the data it read.
+func cacheResponse(cache ResponseCache, key string, resp *http.Response) error {
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	cache.Set(key, &CachedResponse{ETag: etag, LastModified: lastModified, Body: body})
+	return nil
+}
@@ -0,0 +1,235 @@
+package classifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// A Topic is a subject that posts may be about, such as a programming
+// language, and the heuristics that detect it.
+type Topic struct {
+	// Tag is the tag that posts about the topic are tagged with.
+	Tag string
+
+	// Keywords are words or phrases in a post's title that indicate that
+	// it's about the topic. They match whole words, ignoring case, unless
+	// they contain an uppercase letter (so "Go" matches the language, but
+	// not "go").
+	Keywords []string
+
+	// Domains are link domains whose posts are about the topic. A domain
+	// also matches its subdomains.
+	Domains []string
+}
+
+// DefaultTopics are the topics that a TopicClassifier detects if its Topics
+// are not set.
+var DefaultTopics = []*Topic{
+	{
+		Tag:      "golang",
+		Keywords: []string{"Go", "golang", "goroutine", "goroutines", "gopher", "gophers", "gophercon"},
+		Domains:  []string{"golang.org", "go.dev"},
+	},
+	{
+		Tag:      "rust",
+		Keywords: []string{"Rust", "rustlang", "rustc", "rustacean", "rustaceans", "crates.io"},
+		Domains:  []string{"rust-lang.org", "this-week-in-rust.org", "docs.rs", "crates.io"},
+	},
+	{
+		Tag:      "databases",
+		Keywords: []string{"database", "databases", "sql", "postgres", "postgresql", "mysql", "sqlite", "mongodb", "redis", "cassandra", "clickhouse"},
+		Domains:  []string{"postgresql.org", "sqlite.org", "mysql.com", "use-the-index-luke.com"},
+	},
+	{
+		Tag:      "security",
+		Keywords: []string{"security", "vulnerability", "vulnerabilities", "exploit", "exploits", "CVE", "xss", "csrf", "malware", "ransomware", "cryptography", "infosec"},
+		Domains:  []string{"krebsonsecurity.com", "schneier.com", "portswigger.net"},
+	},
+	{
+		Tag:      "ml",
+		Keywords: []string{"machine learning", "deep learning", "neural network", "neural networks", "LLM", "LLMs", "pytorch", "tensorflow"},
+		Domains:  []string{"huggingface.co", "distill.pub"},
+	},
+}
+
+// Matches returns whether post's link domain or title indicates that it's
+// about the topic.
+func (t *Topic) Matches(post *thesrc.Post) bool {
+	return matchesDomain(thesrc.LinkDomain(post.LinkURL), t.Domains) || matchesKeyword(titleWords(post.Title), t.Keywords)
+}
+
+// An ExternalClassifier detects the topics of posts using some other
+// classifier (such as a machine learning model).
+type ExternalClassifier interface {
+	// Topics returns the tags of the topics that post is about.
+	Topics(post *thesrc.Post) ([]string, error)
+}
+
+// A TopicClassifier tags posts with the topics that they're about.
+type TopicClassifier struct {
+	// Topics are the topics that are detected. If nil, DefaultTopics are
+	// used.
+	Topics []*Topic
+
+	// External (if set) is consulted in addition to the heuristics of
+	// Topics. If it fails, the error is logged and only the heuristics are
+	// used, so that an unavailable service doesn't prevent posts from being
+	// submitted.
+	External ExternalClassifier
+}
+
+// Classify returns the tags of the topics that post is about.
+func (c *TopicClassifier) Classify(post *thesrc.Post) []string {
+	topics := c.Topics
+	if topics == nil {
+		topics = DefaultTopics
+	}
+
+	var tags []string
+	seen := map[string]bool{}
+	add := func(tag string) {
+		if tag, err := thesrc.NormalizeTag(tag); err == nil && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	for _, t := range topics {
+		if t.Matches(post) {
+			add(t.Tag)
+		}
+	}
+
+	if c.External != nil {
+		ext, err := c.External.Topics(post)
+		if err != nil {
+			log.Printf("External topic classifier on post with URL %q: %s", post.LinkURL, err)
+		}
+		for _, tag := range ext {
+			add(tag)
+		}
+	}
+	return tags
+}
+
+// Tag adds the tags of the topics that post is about to post.Tags (as long
+// as it has fewer than thesrc.MaxTagsPerPost tags), and returns whether any
+// tags were added.
+func (c *TopicClassifier) Tag(post *thesrc.Post) bool {
+	var added bool
+	for _, tag := range c.Classify(post) {
+		if len(post.Tags) >= thesrc.MaxTagsPerPost {
+			break
+		}
+		if !hasTag(post.Tags, tag) {
+			post.Tags = append(post.Tags, tag)
+			added = true
+		}
+	}
+	return added
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// titleWords splits title into words. Characters that commonly appear in
+// names (such as the "+" in "C++" or the "." in "crates.io") are part of
+// words, except for a "." at the end of a word (which is usually a period).
+func titleWords(title string) []string {
+	words := strings.FieldsFunc(title, func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune("+#.", c)
+	})
+	for i, w := range words {
+		words[i] = strings.TrimRight(w, ".")
+	}
+	return words
+}
+
+func matchesKeyword(words []string, keywords []string) bool {
+	for _, kw := range keywords {
+		kwWords := strings.Fields(kw)
+		ignoreCase := kw == strings.ToLower(kw)
+		for i := 0; i+len(kwWords) <= len(words); i++ {
+			match := true
+			for j, kwWord := range kwWords {
+				w := words[i+j]
+				if ignoreCase {
+					w = strings.ToLower(w)
+				}
+				if w != kwWord {
+					match = false
+					break
+				}
+			}
+			if match {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesDomain(domain string, domains []string) bool {
+	if domain == "" {
+		return false
+	}
+	for _, d := range domains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// An HTTPClassifier is an ExternalClassifier that POSTs posts (as JSON) to
+// a URL, which responds with a JSON object whose Topics field lists the
+// post's topics' tags (e.g., {"Topics": ["golang"]}).
+type HTTPClassifier struct {
+	URL string
+
+	// Client is the HTTP client used to make requests. If nil, a client
+	// with a short timeout is used.
+	Client *http.Client
+}
+
+func (c *HTTPClassifier) Topics(post *thesrc.Post) ([]string, error) {
+	client := c.Client
+	if client == nil {
+		client = httpClient
+	}
+
+	body, err := json.Marshal(post)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Post(c.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-200 HTTP response status: %d", resp.StatusCode)
+	}
+
+	var res struct{ Topics []string }
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return res.Topics, nil
+}
+
+var _ ExternalClassifier = &HTTPClassifier{}
@@ -0,0 +1,69 @@
+package classifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestTopicClassifier_Classify(t *testing.T) {
+	tests := []struct {
+		post *thesrc.Post
+		want []string
+	}{
+		{&thesrc.Post{Title: "Go 1.3 is released", LinkURL: "http://example.com/a"}, []string{"golang"}},
+		{&thesrc.Post{Title: "Let's go shopping", LinkURL: "http://example.com/a"}, nil},
+		{&thesrc.Post{Title: "Release notes", LinkURL: "https://blog.rust-lang.org/a"}, []string{"rust"}},
+		{&thesrc.Post{Title: "A PostgreSQL exploit (CVE-2014-0001)."}, []string{"databases", "security"}},
+		{&thesrc.Post{Title: "Machine learning with PyTorch"}, []string{"ml"}},
+		{&thesrc.Post{Title: "Learning machines"}, nil},
+	}
+	c := &TopicClassifier{}
+	for _, test := range tests {
+		if got := c.Classify(test.post); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got topics %q, want %q", test.post.Title, got, test.want)
+		}
+	}
+}
+
+func TestTopicClassifier_Tag(t *testing.T) {
+	c := &TopicClassifier{}
+
+	post := &thesrc.Post{Title: "Go and Rust", Tags: []string{"rust"}}
+	if !c.Tag(post) {
+		t.Error("!added")
+	}
+	if want := []string{"rust", "golang"}; !reflect.DeepEqual(post.Tags, want) {
+		t.Errorf("got tags %q, want %q", post.Tags, want)
+	}
+
+	// Posts with the maximum number of tags are left alone.
+	post = &thesrc.Post{Title: "Go", Tags: []string{"a", "b", "c", "d", "e"}}
+	if c.Tag(post) {
+		t.Error("added tag to post with the maximum number of tags")
+	}
+}
+
+func TestHTTPClassifier(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var post thesrc.Post
+		if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+			t.Fatal(err)
+		}
+		if post.Title != "Erlang in production" {
+			t.Errorf("got title %q", post.Title)
+		}
+		w.Write([]byte(`{"Topics": ["erlang", "not a tag"]}`))
+	}))
+	defer s.Close()
+
+	c := &TopicClassifier{External: &HTTPClassifier{URL: s.URL}}
+	got := c.Classify(&thesrc.Post{Title: "Erlang in production"})
+	if want := []string{"erlang"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got topics %q, want %q", got, want)
+	}
+}
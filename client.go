@@ -2,21 +2,47 @@ package thesrc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/go-querystring/query"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
 	"sourcegraph.com/sourcegraph/thesrc/router"
+	"sourcegraph.com/sourcegraph/thesrc/tracing"
 )
 
 // A Client communicates with thesrc's HTTP API.
 type Client struct {
-	Posts PostsService
+	Posts         PostsService
+	Comments      CommentsService
+	Users         UsersService
+	Votes         VotesService
+	Tags          TagsService
+	Domains       DomainsService
+	Links         LinksService
+	Tokens        TokensService
+	TwoFactor     TwoFactorService
+	Sessions      SessionsService
+	Follows       FollowsService
+	Webhooks      WebhooksService
+	Jobs          JobsService
+	VoteSuspects  VoteSuspectsService
+	ClientRecords ClientRecordsService
+	AuditLog      AuditLogService
+	Moderation    ModerationService
+	Reports       ReportsService
+	Site          SiteService
+	Notifications NotificationsService
+	GraphQL       GraphQLService
 
 	// BaseURL for HTTP requests to thesrc's API.
 	BaseURL *url.URL
@@ -24,30 +50,286 @@ type Client struct {
 	//UserAgent used for HTTP requests to thesrc's API.
 	UserAgent string
 
+	// AuthToken (if set) authenticates HTTP requests to thesrc's API as the
+	// user it was issued to. See UsersService.Authenticate.
+	AuthToken string
+
+	// MaxRetries is the number of times a request is retried if it fails
+	// with a network error or a 5xx or 429 (Too Many Requests) response.
+	// Requests that aren't idempotent (POSTs without an Idempotency-Key
+	// header) are only retried after responses that mean the server didn't
+	// act on them: 429, or 503 with a Retry-After header.
+	MaxRetries int
+
+	// RetryBackoff is how long to wait before the first retry of a failed
+	// request. It doubles after each retry. If the failed response has a
+	// Retry-After header, that is used instead. If RetryBackoff is 0,
+	// DefaultRetryBackoff is used.
+	RetryBackoff time.Duration
+
+	// Cache (if set) stores the responses to GET requests, so that the
+	// requests can be repeated conditionally (with If-None-Match or
+	// If-Modified-Since). When the API responds that a response hasn't
+	// changed (304 Not Modified), its cached body is used, which makes
+	// polling cheap. See NewMemoryResponseCache.
+	Cache ResponseCache
+
+	// ForwardingKey (if set) signs the IP addresses of the end users on
+	// whose behalf requests are made (see WithForwardedClient), so that an
+	// API server with the same key trusts them. It must be kept secret.
+	ForwardingKey []byte
+
+	// forwardedIP (if set) is the IP address of the end user on whose
+	// behalf requests are made. See WithForwardedClient.
+	forwardedIP string
+
+	// auditReason (if set) is the reason given for the privileged actions
+	// that requests take. See WithAuditReason.
+	auditReason string
+
+	// ctx (if set) is the context that requests are made with. See
+	// WithContext.
+	ctx context.Context
+
 	httpClient *http.Client
+
+	// middleware is applied to httpClient's transport by NewClient. See
+	// MiddlewareOption.
+	middleware []Middleware
 }
 
+// DefaultRetryBackoff is the default value of Client.RetryBackoff.
+const DefaultRetryBackoff = 500 * time.Millisecond
+
+// maxRetryWait is the longest that a client waits before retrying a
+// request, even if the server's Retry-After header asks for longer.
+const maxRetryWait = time.Minute
+
+// APIVersion is the version of thesrc's HTTP API that the client targets.
+// The default BaseURL includes it, so a client with a custom BaseURL should
+// include it too (e.g., "https://example.com/api/v1/").
+const APIVersion = "v1"
+
 const (
 	libraryVersion = "0.0.1"
 	userAgent      = "thesrc-client/" + libraryVersion
 )
 
-// NewClient creates a new HTTP API client for thesrc. If httpClient == nil,
-// then http.DefaultClient is used.
-func NewClient(httpClient *http.Client) *Client {
+// NewClient creates a new HTTP API client for thesrc, configured by opts. If
+// httpClient == nil, then http.DefaultClient is used.
+func NewClient(httpClient *http.Client, opts ...ClientOption) *Client {
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
 
 	c := &Client{
-		BaseURL:    &url.URL{Scheme: "http", Host: "thesrc.org", Path: "/api/"},
+		BaseURL:    &url.URL{Scheme: "http", Host: "thesrc.org", Path: "/api/" + APIVersion + "/"},
 		UserAgent:  userAgent,
 		httpClient: httpClient,
 	}
 	c.Posts = &postsService{c}
+	c.Comments = &commentsService{c}
+	c.Users = &usersService{c}
+	c.Votes = &votesService{c}
+	c.Tags = &tagsService{c}
+	c.Domains = &domainsService{c}
+	c.Links = &linksService{c}
+	c.Tokens = &tokensService{c}
+	c.TwoFactor = &twoFactorService{c}
+	c.Sessions = &sessionsService{c}
+	c.Follows = &followsService{c}
+	c.Webhooks = &webhooksService{c}
+	c.Jobs = &jobsService{c}
+	c.VoteSuspects = &voteSuspectsService{c}
+	c.ClientRecords = &clientRecordsService{c}
+	c.AuditLog = &auditLogService{c}
+	c.Moderation = &moderationService{c}
+	c.Reports = &reportsService{c}
+	c.Site = &siteService{c}
+	c.Notifications = &notificationsService{c}
+	c.GraphQL = &graphQLService{c}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if len(c.middleware) > 0 {
+		// Copy httpClient (which may be shared, such as
+		// http.DefaultClient) instead of modifying it.
+		hc := *c.httpClient
+		transport := hc.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		for i := len(c.middleware) - 1; i >= 0; i-- {
+			transport = c.middleware[i](transport)
+		}
+		hc.Transport = transport
+		c.httpClient = &hc
+	}
 	return c
 }
 
+// A ClientOption configures a Client created by NewClient.
+type ClientOption func(*Client)
+
+// AuthTokenOption returns a ClientOption that authenticates the client's
+// requests with token (such as a personal API token; see TokensService).
+func AuthTokenOption(token string) ClientOption {
+	return func(c *Client) { c.AuthToken = token }
+}
+
+// RetryOption returns a ClientOption that retries failed requests up to
+// maxRetries times, waiting backoff before the first retry and doubling the
+// wait after each. See Client.MaxRetries and Client.RetryBackoff.
+func RetryOption(maxRetries int, backoff time.Duration) ClientOption {
+	return func(c *Client) {
+		c.MaxRetries = maxRetries
+		c.RetryBackoff = backoff
+	}
+}
+
+// UserAgentOption returns a ClientOption that sends userAgent as the
+// User-Agent of the client's requests.
+func UserAgentOption(userAgent string) ClientOption {
+	return func(c *Client) { c.UserAgent = userAgent }
+}
+
+// A Middleware wraps the http.RoundTripper that sends a client's requests,
+// returning one that may inspect or modify each request and response (for
+// example, to log requests, record metrics, or add headers). Each retry of
+// a request (see Client.MaxRetries) passes through it again.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// MiddlewareOption returns a ClientOption that sends the client's requests
+// through middleware, in order: the first wraps the second, and so on, and
+// the last wraps the http.Client's transport. It may be given more than
+// once, in which case the middleware of the first is outermost.
+func MiddlewareOption(middleware ...Middleware) ClientOption {
+	return func(c *Client) { c.middleware = append(c.middleware, middleware...) }
+}
+
+// RoundTripperFunc is an http.RoundTripper implemented by a function, for
+// use in Middleware.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// WithAuthToken returns a copy of c that authenticates its requests with
+// token. Services on c that were not created by NewClient (such as mocks) are
+// shared with the copy.
+func (c *Client) WithAuthToken(token string) *Client {
+	c2 := c.clone()
+	c2.AuthToken = token
+	return c2
+}
+
+// WithContext returns a copy of c whose requests are made with ctx, so
+// that they (and any waits before retrying them) are abandoned when ctx is
+// canceled. If ctx carries a request ID (see logging.RequestID), the requests
+// are sent with it, so the API logs them under the same ID. Services on c
+// that were not created by NewClient (such as mocks) are shared with the
+// copy.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	c2 := c.clone()
+	c2.ctx = ctx
+	return c2
+}
+
+// WithForwardedClient returns a copy of c whose requests are made on behalf
+// of an end user with the given IP address and User-Agent, for servers
+// (such as the app) that call the API for their users. The User-Agent
+// replaces c.UserAgent (unless it's empty), and the IP address is only sent
+// if c.ForwardingKey is set. Services on c that were not created by
+// NewClient (such as mocks) are shared with the copy.
+func (c *Client) WithForwardedClient(ip, userAgent string) *Client {
+	c2 := c.clone()
+	c2.forwardedIP = ip
+	if userAgent != "" {
+		c2.UserAgent = userAgent
+	}
+	return c2
+}
+
+// WithAuditReason returns a copy of c whose requests give reason as the
+// reason for the privileged actions that they take, which the API records in
+// the audit log (see AuditEntry). Services on c that were not created by
+// NewClient (such as mocks) are shared with the copy.
+func (c *Client) WithAuditReason(reason string) *Client {
+	c2 := c.clone()
+	c2.auditReason = reason
+	return c2
+}
+
+// clone returns a copy of c whose services that were created by NewClient
+// use the copy.
+func (c *Client) clone() *Client {
+	c2 := *c
+	if _, ok := c.Posts.(*postsService); ok {
+		c2.Posts = &postsService{&c2}
+	}
+	if _, ok := c.Comments.(*commentsService); ok {
+		c2.Comments = &commentsService{&c2}
+	}
+	if _, ok := c.Users.(*usersService); ok {
+		c2.Users = &usersService{&c2}
+	}
+	if _, ok := c.Votes.(*votesService); ok {
+		c2.Votes = &votesService{&c2}
+	}
+	if _, ok := c.Tags.(*tagsService); ok {
+		c2.Tags = &tagsService{&c2}
+	}
+	if _, ok := c.Domains.(*domainsService); ok {
+		c2.Domains = &domainsService{&c2}
+	}
+	if _, ok := c.Links.(*linksService); ok {
+		c2.Links = &linksService{&c2}
+	}
+	if _, ok := c.Tokens.(*tokensService); ok {
+		c2.Tokens = &tokensService{&c2}
+	}
+	if _, ok := c.TwoFactor.(*twoFactorService); ok {
+		c2.TwoFactor = &twoFactorService{&c2}
+	}
+	if _, ok := c.Sessions.(*sessionsService); ok {
+		c2.Sessions = &sessionsService{&c2}
+	}
+	if _, ok := c.Follows.(*followsService); ok {
+		c2.Follows = &followsService{&c2}
+	}
+	if _, ok := c.Webhooks.(*webhooksService); ok {
+		c2.Webhooks = &webhooksService{&c2}
+	}
+	if _, ok := c.Jobs.(*jobsService); ok {
+		c2.Jobs = &jobsService{&c2}
+	}
+	if _, ok := c.VoteSuspects.(*voteSuspectsService); ok {
+		c2.VoteSuspects = &voteSuspectsService{&c2}
+	}
+	if _, ok := c.ClientRecords.(*clientRecordsService); ok {
+		c2.ClientRecords = &clientRecordsService{&c2}
+	}
+	if _, ok := c.AuditLog.(*auditLogService); ok {
+		c2.AuditLog = &auditLogService{&c2}
+	}
+	if _, ok := c.Moderation.(*moderationService); ok {
+		c2.Moderation = &moderationService{&c2}
+	}
+	if _, ok := c.Reports.(*reportsService); ok {
+		c2.Reports = &reportsService{&c2}
+	}
+	if _, ok := c.Site.(*siteService); ok {
+		c2.Site = &siteService{&c2}
+	}
+	if _, ok := c.Notifications.(*notificationsService); ok {
+		c2.Notifications = &notificationsService{&c2}
+	}
+	if _, ok := c.GraphQL.(*graphQLService); ok {
+		c2.GraphQL = &graphQLService{&c2}
+	}
+	return &c2
+}
+
 // ListOptions specifies general pagination options for fetching a list of
 // results.
 type ListOptions struct {
@@ -138,26 +420,78 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Requ
 		return nil, err
 	}
 
+	if c.ctx != nil {
+		req = req.WithContext(c.ctx)
+		if id := logging.RequestID(c.ctx); id != "" {
+			req.Header.Set(logging.RequestIDHeader, id)
+		}
+	}
+
 	req.Header.Add("User-Agent", c.UserAgent)
+	if c.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.AuthToken)
+	}
+	if c.forwardedIP != "" && len(c.ForwardingKey) > 0 {
+		req.Header.Set(ForwardedIPHeader, SignForwardedIP(c.ForwardingKey, c.forwardedIP))
+	}
+	if c.auditReason != "" {
+		// Escape the reason, which may contain characters (such as
+		// newlines) that header values can't.
+		req.Header.Set(AuditReasonHeader, url.QueryEscape(c.auditReason))
+	}
 	return req, nil
 }
 
 // Do sends an API request and returns the API response. The API response is
 // JSON-decoded and stored in the value pointed to by v, or returned as an error
-// if an API error has occurred.
+// if an API error has occurred. Failed requests are retried as configured by
+// c.MaxRetries.
 func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
-	resp, err := c.httpClient.Do(req)
+	ctx, span := tracing.Start(req.Context(), tracing.KindClient, "API "+req.Method,
+		"http.method", req.Method,
+		"http.url", req.URL.String(),
+	)
+	defer span.Finish()
+	if span != nil {
+		req = req.WithContext(ctx)
+		tracing.Inject(ctx, req.Header)
+	}
+
+	var key string
+	var cached *CachedResponse
+	if c.Cache != nil {
+		if key = responseCacheKey(req); key != "" {
+			if cached, _ = c.Cache.Get(key); cached != nil {
+				addConditionalHeaders(req, cached)
+			}
+		}
+	}
+
+	resp, err := c.send(req)
 	if err != nil {
+		span.SetError(err)
 		return nil, err
 	}
+	span.SetAttributes("http.status_code", resp.StatusCode)
 
 	defer resp.Body.Close()
 
-	err = CheckResponse(resp)
-	if err != nil {
-		// even though there was an error, we still return the response
-		// in case the caller wants to inspect it further
-		return resp, err
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		// The returned response keeps its 304 status, so callers can tell
+		// that the cached body was used.
+		resp.Body = ioutil.NopCloser(bytes.NewReader(cached.Body))
+	} else {
+		err = CheckResponse(resp)
+		if err != nil {
+			// even though there was an error, we still return the response
+			// in case the caller wants to inspect it further
+			return resp, err
+		}
+		if key != "" {
+			if err := cacheResponse(c.Cache, key, resp); err != nil {
+				return nil, fmt.Errorf("error reading response from %s %s: %s", req.Method, req.URL.RequestURI(), err)
+			}
+		}
 	}
 
 	if v != nil {
@@ -173,6 +507,92 @@ func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
 	return resp, nil
 }
 
+// send sends req, retrying it (up to c.MaxRetries times, with exponential
+// backoff) if it fails in a way that shouldRetry allows. It stops waiting to
+// retry when req's context is done.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	backoff := c.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+
+	for retry := 0; ; retry++ {
+		resp, err := c.httpClient.Do(req)
+		if retry >= c.MaxRetries || !shouldRetry(req, resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		wait := backoff << uint(retry)
+		if resp != nil {
+			if d, ok := retryAfter(resp); ok {
+				wait = d
+			}
+			ioutil.ReadAll(io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		if wait > maxRetryWait {
+			wait = maxRetryWait
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, req.Context().Err()
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// shouldRetry reports whether req, which got resp and err, should be
+// retried. Any request may be retried after a 429 response, or a 503
+// response with a Retry-After header, since the server didn't act on it.
+// After a network error or another 5xx response, the server may have acted
+// on req, so it is only retried if doing so again is harmless (see
+// idempotent).
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err == nil {
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			return true
+		case resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") != "":
+			return true
+		case resp.StatusCode < 500:
+			return false
+		}
+	}
+	return idempotent(req)
+}
+
+// idempotent reports whether sending req more than once has the same effect
+// as sending it once: if its method is idempotent, or it has an
+// IdempotencyKeyHeader.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// retryAfter returns the delay requested by resp's Retry-After header (if it
+// has one, in seconds).
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+	return time.Duration(secs) * time.Second, true
+}
+
 // addOptions adds the parameters in opt as URL query parameters to u. opt
 // must be a struct whose fields may contain "url" tags.
 func addOptions(u *url.URL, opt interface{}) error {
@@ -0,0 +1,99 @@
+package thesrc
+
+import (
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A ClientRecord identifies the client that submitted or voted on a post,
+// for abuse detection (see the "thesrc serve -record-clients" flag). The
+// client's IP address and User-Agent are only kept as hashes, salted with a
+// salt that is replaced every period and then deleted: equal hashes from the
+// same period mean the same IP address (or User-Agent), but the address
+// can't be recovered once its period's salt is deleted. Records are deleted
+// after a retention period.
+type ClientRecord struct {
+	// ID a unique identifier for this record.
+	ID int
+
+	// Action is what the client did (one of the ClientAction* constants).
+	Action string
+
+	// UserID is the ID of the user that the client was authenticated as.
+	UserID int
+
+	// Login is the login of the user with ID UserID. It is only set by
+	// ClientRecordsService.List.
+	Login string `db:"-" json:",omitempty"`
+
+	// PostID is the ID of the post that was submitted or voted on.
+	PostID int
+
+	// IPHash and UserAgentHash are the salted hashes of the client's IP
+	// address and User-Agent header.
+	IPHash        string
+	UserAgentHash string
+
+	// CreatedAt is when the client acted.
+	CreatedAt time.Time
+}
+
+// The actions that client records are kept for.
+const (
+	ClientActionSubmit = "submit" // submitted a post
+	ClientActionVote   = "vote"   // upvoted a post
+)
+
+// ClientRecordsService interacts with the client record endpoints in thesrc's
+// API. Only admins may use it.
+type ClientRecordsService interface {
+	// List client records, newest first.
+	List(opt *ClientRecordListOptions) ([]*ClientRecord, error)
+}
+
+type ClientRecordListOptions struct {
+	// UserID, PostID, and IPHash (if set) filter the result set to records
+	// of the user, of actions on the post, and from clients whose IP
+	// addresses had the hash.
+	UserID int    `url:",omitempty" json:",omitempty"`
+	PostID int    `url:",omitempty" json:",omitempty"`
+	IPHash string `url:",omitempty" json:",omitempty"`
+
+	ListOptions
+}
+
+type clientRecordsService struct{ client *Client }
+
+func (s *clientRecordsService) List(opt *ClientRecordListOptions) ([]*ClientRecord, error) {
+	url, err := s.client.url(router.ClientRecords, nil, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*ClientRecord
+	_, err = s.client.Do(req, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+type MockClientRecordsService struct {
+	List_ func(opt *ClientRecordListOptions) ([]*ClientRecord, error)
+}
+
+var _ ClientRecordsService = &MockClientRecordsService{}
+
+func (s *MockClientRecordsService) List(opt *ClientRecordListOptions) ([]*ClientRecord, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
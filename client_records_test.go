@@ -0,0 +1,41 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestClientRecordsService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*ClientRecord{{ID: 1, Action: ClientActionVote, UserID: 2, Login: "bob", PostID: 3, IPHash: "h", UserAgentHash: "u"}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.ClientRecords, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"IPHash": "h", "PerPage": "5"})
+
+		writeJSON(w, want)
+	})
+
+	records, err := client.ClientRecords.List(&ClientRecordListOptions{IPHash: "h", ListOptions: ListOptions{PerPage: 5}})
+	if err != nil {
+		t.Errorf("ClientRecords.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, r := range want {
+		normalizeTime(&r.CreatedAt)
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("ClientRecords.List returned %+v, want %+v", records, want)
+	}
+}
@@ -1,6 +1,7 @@
 package thesrc
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -9,6 +10,9 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/router"
 )
 
 var (
@@ -91,3 +95,245 @@ func testBody(t *testing.T, r *http.Request, want string) {
 func normalizeTime(t *time.Time) {
 	*t = t.In(time.UTC)
 }
+
+func TestClient_retry(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var requests int
+	mux.HandleFunc(urlPath(t, router.SubmitPost, nil), func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		testBody(t, r, `{"Title":"t","LinkURL":"","Body":"","SubmittedAt":"0001-01-01T00:00:00Z","AuthorUserID":0,"Score":0,"Classification":""}`+"\n")
+		switch requests {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusCreated)
+			writeJSON(w, &Post{ID: 1})
+		}
+	})
+
+	post := &Post{Title: "t"}
+	if _, err := client.Posts.Submit(post); !IsHTTPErrorCode(err, http.StatusServiceUnavailable) {
+		t.Errorf("got error %v without retries, want HTTP %d", err, http.StatusServiceUnavailable)
+	}
+
+	requests = 0
+	c := client.WithContext(context.Background())
+	c.MaxRetries, c.RetryBackoff = 2, time.Millisecond
+	created, err := c.Posts.Submit(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || post.ID != 1 {
+		t.Errorf("got created %v and post %+v, want created post 1", created, post)
+	}
+	if requests != 3 {
+		t.Errorf("got %d requests, want 3", requests)
+	}
+}
+
+func TestClient_retryNonIdempotent(t *testing.T) {
+	setup()
+	defer teardown()
+
+	type retryTest struct {
+		status     int
+		retryAfter string
+		wantRetry  bool
+	}
+	tests := []retryTest{
+		{http.StatusBadGateway, "", false},
+		{http.StatusServiceUnavailable, "", false},
+		{http.StatusServiceUnavailable, "0", true},
+		{http.StatusTooManyRequests, "0", true},
+	}
+	var (
+		requests int
+		test     retryTest
+	)
+	mux.HandleFunc(urlPath(t, router.CreateComment, nil), func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			if test.retryAfter != "" {
+				w.Header().Set("Retry-After", test.retryAfter)
+			}
+			w.WriteHeader(test.status)
+			return
+		}
+		writeJSON(w, &Comment{ID: 1})
+	})
+
+	c := NewClient(nil, RetryOption(3, time.Millisecond))
+	c.BaseURL = client.BaseURL
+	for _, test = range tests {
+		requests = 0
+		err := c.Comments.Create(&Comment{PostID: 1, Body: "b"})
+		if test.wantRetry && (err != nil || requests != 2) {
+			t.Errorf("%+v: got error %v after %d requests, want success after a retry", test, err, requests)
+		}
+		if !test.wantRetry && (!IsHTTPErrorCode(err, test.status) || requests != 1) {
+			t.Errorf("%+v: got error %v after %d requests, want HTTP %d without retrying", test, err, requests, test.status)
+		}
+	}
+}
+
+func TestClient_retryNotFound(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var requests int
+	mux.HandleFunc(urlPath(t, router.Post, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	c := NewClient(nil, RetryOption(3, time.Millisecond))
+	c.BaseURL = client.BaseURL
+	if _, err := c.Posts.Get(1); !IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Errorf("got error %v, want HTTP %d", err, http.StatusNotFound)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1 (4xx errors other than 429 should not be retried)", requests)
+	}
+}
+
+func TestClient_WithContext_canceled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mux.HandleFunc(urlPath(t, router.Post, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	c := client.WithContext(ctx)
+	c.MaxRetries, c.RetryBackoff = 5, time.Hour
+	done := make(chan error)
+	go func() {
+		_, err := c.Posts.Get(1)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("got nil error, want error after context was canceled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request was not abandoned after its context was canceled")
+	}
+}
+
+func TestClient_WithContext_requestID(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var gotID string
+	mux.HandleFunc(urlPath(t, router.Post, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		gotID = r.Header.Get(logging.RequestIDHeader)
+		writeJSON(w, &Post{ID: 1})
+	})
+
+	// Make the API request while serving a request with ID "abc".
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(logging.RequestIDHeader, "abc")
+	logging.Handler(logging.New(ioutil.Discard), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := client.WithContext(r.Context()).Posts.Get(1); err != nil {
+			t.Fatal(err)
+		}
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotID != "abc" {
+		t.Errorf("got %s %q, want %q", logging.RequestIDHeader, gotID, "abc")
+	}
+}
+
+func TestClient_cache(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var requests, notModified int
+	mux.HandleFunc(urlPath(t, router.Post, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		writeJSON(w, &Post{ID: 1, Title: "t"})
+	})
+
+	c := client.WithContext(context.Background())
+	c.Cache = NewMemoryResponseCache(10)
+	for i := 0; i < 2; i++ {
+		post, err := c.Posts.Get(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.ID != 1 || post.Title != "t" {
+			t.Errorf("request %d: got post %+v, want post 1", i+1, post)
+		}
+	}
+	if requests != 2 || notModified != 1 {
+		t.Errorf("got %d requests (%d not modified), want 2 (1 not modified)", requests, notModified)
+	}
+
+	// Responses to other users aren't reused.
+	if _, err := c.WithAuthToken("tok").Posts.Get(1); err != nil {
+		t.Fatal(err)
+	}
+	if notModified != 1 {
+		t.Errorf("got %d not modified responses after request with a different token, want 1", notModified)
+	}
+}
+
+func TestClient_middleware(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc(urlPath(t, router.Post, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("User-Agent"), "bot/1.0"; got != want {
+			t.Errorf("got User-Agent %q, want %q", got, want)
+		}
+		if got, want := r.Header.Get("X-Trace"), "ab"; got != want {
+			t.Errorf("got X-Trace %q, want %q (middleware applied in order)", got, want)
+		}
+		writeJSON(w, &Post{ID: 1})
+	})
+
+	var statuses []int
+	addTrace := func(s string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				req.Header.Set("X-Trace", req.Header.Get("X-Trace")+s)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	recordStatus := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err == nil {
+				statuses = append(statuses, resp.StatusCode)
+			}
+			return resp, err
+		})
+	}
+
+	c := NewClient(nil, UserAgentOption("bot/1.0"), MiddlewareOption(addTrace("a"), recordStatus), MiddlewareOption(addTrace("b")))
+	c.BaseURL = client.BaseURL
+	if _, err := c.Posts.Get(1); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(statuses, []int{http.StatusOK}) {
+		t.Errorf("got statuses %v, want [200]", statuses)
+	}
+	if http.DefaultClient.Transport != nil {
+		t.Error("middleware modified http.DefaultClient")
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+var configFile = flag.String("config", os.Getenv("THESRC_CONFIG"), "path to TOML config file (defaults to $THESRC_CONFIG)")
+
+// config holds the contents of the config file. Top-level keys set global
+// flags, and each table sets the flags of the subcommand with the same name.
+// Flags given on the command line override values in the config file. For
+// example:
+//
+//	url = "http://localhost:5000"
+//	db = "dbname=thesrc sslmode=disable"
+//
+//	[serve]
+//	http = ":5000"
+//	tmpl-dir = "/srv/thesrc/tmpl"
+//
+//	[import]
+//	interval = "10m"
+//	subreddits = ["golang", "rust"]
+var config map[string]interface{}
+
+func loadConfig() {
+	if *configFile == "" {
+		return
+	}
+	if _, err := toml.DecodeFile(*configFile, &config); err != nil {
+		log.Fatalf("Error reading config file %s: %s", *configFile, err)
+	}
+	applyConfig(flag.CommandLine, config)
+}
+
+// parseFlags parses a subcommand's flags from args, and then sets the flags
+// that were not given in args to the values in the subcommand's config file
+// table (if any).
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+	if table, ok := config[fs.Name()].(map[string]interface{}); ok {
+		applyConfig(fs, table)
+	}
+}
+
+// applyConfig sets each flag in fs that was not explicitly set to its value
+// in values (if any). Tables in values (i.e., subcommand configs) are
+// ignored.
+func applyConfig(fs *flag.FlagSet, values map[string]interface{}) {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var s string
+		switch v := values[name].(type) {
+		case map[string]interface{}:
+			continue
+		case []interface{}:
+			elems := make([]string, len(v))
+			for i, e := range v {
+				elems[i] = fmt.Sprint(e)
+			}
+			s = strings.Join(elems, ",")
+		default:
+			s = fmt.Sprint(v)
+		}
+
+		if set[name] {
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			log.Fatalf("Error in config file %s: unknown option %q for %s.", *configFile, name, configSection(fs))
+		}
+		if err := fs.Set(name, s); err != nil {
+			log.Fatalf("Error in config file %s: option %q for %s: %s.", *configFile, name, configSection(fs), err)
+		}
+	}
+}
+
+func configSection(fs *flag.FlagSet) string {
+	if fs == flag.CommandLine {
+		return "thesrc"
+	}
+	return "thesrc " + fs.Name()
+}
+
+// A profile is a named server in the user's profiles file: the base URL of
+// the site and the user's personal API token on it.
+type profile struct {
+	URL   string
+	Token string
+}
+
+var profileName = flag.String("profile", os.Getenv("THESRC_PROFILE"), "name of the profile (in ~/.config/thesrc/config) whose URL and token to use (defaults to $THESRC_PROFILE, or the file's default profile)")
+
+// profilesFile returns the path of the user's profiles file,
+// $XDG_CONFIG_HOME/thesrc/config (or ~/.config/thesrc/config).
+func profilesFile() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "thesrc", "config"), nil
+}
+
+// readProfiles reads the profiles in the TOML file at path, and the name of
+// the default profile (if any). Each table is a profile, and the top-level
+// default key names the profile to use when -profile isn't set. For example:
+//
+//	default = "production"
+//
+//	[production]
+//	url = "https://thesrc.org"
+//	token = "..."
+//
+//	[staging]
+//	url = "https://staging.thesrc.org"
+//	token = "..."
+func readProfiles(path string) (profiles map[string]profile, defaultName string, err error) {
+	var values map[string]interface{}
+	if _, err := toml.DecodeFile(path, &values); err != nil {
+		return nil, "", err
+	}
+
+	profiles = map[string]profile{}
+	for name, v := range values {
+		if name == "default" {
+			s, ok := v.(string)
+			if !ok {
+				return nil, "", fmt.Errorf("default must be the name of a profile")
+			}
+			defaultName = s
+			continue
+		}
+		table, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("unknown option %q (profiles are tables, such as [%s])", name, name)
+		}
+		var p profile
+		for key, v := range table {
+			s, ok := v.(string)
+			switch {
+			case !ok:
+				return nil, "", fmt.Errorf("profile %s: option %q must be a string", name, key)
+			case key == "url":
+				p.URL = s
+			case key == "token":
+				p.Token = s
+			default:
+				return nil, "", fmt.Errorf("profile %s: unknown option %q (profiles may set url and token)", name, key)
+			}
+		}
+		profiles[name] = p
+	}
+	if _, present := profiles[defaultName]; defaultName != "" && !present {
+		return nil, "", fmt.Errorf("default profile %q is not defined", defaultName)
+	}
+	return profiles, defaultName, nil
+}
+
+// loadProfile sets the -url and -token flags to the values in the selected
+// profile (-profile, or else the profiles file's default profile), unless
+// they were set otherwise (see applyProfile). It must be called after
+// loadConfig, and only for client subcommands (see subcmd.client).
+func loadProfile() {
+	path, err := profilesFile()
+	if err != nil {
+		if *profileName != "" {
+			log.Fatalf("Error finding profiles file: %s", err)
+		}
+		return
+	}
+	profiles, defaultName, err := readProfiles(path)
+	if os.IsNotExist(err) && *profileName == "" {
+		return
+	} else if err != nil {
+		log.Fatalf("Error reading profiles file %s: %s", path, err)
+	}
+
+	name := *profileName
+	if name == "" {
+		name = defaultName
+	}
+	if name == "" {
+		return
+	}
+	p, present := profiles[name]
+	if !present {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		log.Fatalf("Unknown profile %q. The profiles in %s are: %s.", name, path, strings.Join(names, ", "))
+	}
+
+	applyProfile(flag.CommandLine, p)
+}
+
+// applyProfile sets the url and token flags in fs to p's URL and token,
+// unless they were set on the command line or in the config file (or, for
+// the token, in $THESRC_TOKEN), which take precedence over profiles.
+func applyProfile(fs *flag.FlagSet, p profile) {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if p.URL != "" && !set["url"] {
+		fs.Set("url", p.URL)
+	}
+	if p.Token != "" && !set["token"] && os.Getenv("THESRC_TOKEN") == "" {
+		fs.Set("token", p.Token)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+func TestApplyProfile(t *testing.T) {
+	defer os.Setenv("THESRC_TOKEN", os.Getenv("THESRC_TOKEN"))
+
+	p := profile{URL: "https://profile.example.com", Token: "profile-token"}
+	tests := []struct {
+		args      []string
+		config    map[string]interface{}
+		envToken  string
+		wantURL   string
+		wantToken string
+	}{
+		{wantURL: p.URL, wantToken: p.Token},
+		{args: []string{"-url", "https://flag.example.com", "-token", "flag-token"}, wantURL: "https://flag.example.com", wantToken: "flag-token"},
+		{config: map[string]interface{}{"url": "https://config.example.com", "token": "config-token"}, wantURL: "https://config.example.com", wantToken: "config-token"},
+		{envToken: "env-token", wantURL: p.URL, wantToken: "env-token"},
+	}
+	for _, test := range tests {
+		os.Setenv("THESRC_TOKEN", test.envToken)
+		fs := flag.NewFlagSet("thesrc", flag.ContinueOnError)
+		url := fs.String("url", "http://thesrc.org", "")
+		token := fs.String("token", os.Getenv("THESRC_TOKEN"), "")
+		if err := fs.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		applyConfig(fs, test.config)
+		applyProfile(fs, p)
+
+		if *url != test.wantURL {
+			t.Errorf("%+v: got url %q, want %q", test, *url, test.wantURL)
+		}
+		if *token != test.wantToken {
+			t.Errorf("%+v: got token %q, want %q", test, *token, test.wantToken)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// postListFlags adds the flags of the list and search subcommands to fs, and
+// returns a func that returns the post list options and output format that
+// they set (after fs is parsed).
+func postListFlags(fs *flag.FlagSet, defaultSort string) (opts func() (*thesrc.PostListOptions, string)) {
+	sort := fs.String("sort", defaultSort, "order to list posts in: new, top, best, or trending")
+	tag := fs.String("tag", "", "only list posts with this tag")
+	domain := fs.String("domain", "", "only list posts whose links are on this domain")
+	period := fs.String("period", "", "only list posts submitted in the past day, week, month, or year")
+	n := fs.Int("n", 30, "number of posts to list")
+	page := fs.Int("page", 1, "page of results to list (of -n posts each)")
+	format := fs.String("format", "table", "output format: table, json, or tsv")
+	return func() (*thesrc.PostListOptions, string) {
+		switch *format {
+		case "table", "json", "tsv":
+		default:
+			log.Fatalf(`Unknown -format %q. See "thesrc %s -h" for usage.`, *format, fs.Name())
+		}
+		return &thesrc.PostListOptions{
+			Sort:        *sort,
+			Tag:         *tag,
+			Domain:      *domain,
+			Period:      *period,
+			ListOptions: thesrc.ListOptions{PerPage: *n, Page: *page},
+		}, *format
+	}
+}
+
+func listCmd(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	opts := postListFlags(fs, thesrc.SortTop)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc list [options]
+
+Lists posts on the site at -url, as on its front page. Set -token (or
+THESRC_TOKEN) to list them as yourself (e.g., without posts you've hidden).
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	parseFlags(fs, args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+	}
+
+	opt, format := opts()
+	listPosts(opt, format)
+}
+
+func searchCmd(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	opts := postListFlags(fs, thesrc.SortNew)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc search [options] query...
+
+Lists the posts on the site at -url that match the query, newest first. The
+query may include filters, as in the site's search box: for example,
+"author:alice tag:go generics" or "domain:go.dev release".
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	parseFlags(fs, args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+	}
+
+	opt, format := opts()
+	opt.Query = strings.Join(fs.Args(), " ")
+	listPosts(opt, format)
+}
+
+// listPosts lists the posts that opt selects to stdout, in format.
+func listPosts(opt *thesrc.PostListOptions, format string) {
+	posts, err := userAPIClient().Posts.List(opt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := writePosts(os.Stdout, posts, format); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writePosts writes posts to w in format: a table for people to read, a
+// JSON array, or tab-separated values with a header row (for scripts).
+func writePosts(w io.Writer, posts []*thesrc.Post, format string) error {
+	switch format {
+	case "json":
+		if posts == nil {
+			posts = []*thesrc.Post{}
+		}
+		data, err := json.MarshalIndent(posts, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+
+	case "tsv":
+		fmt.Fprintln(w, "id\tscore\tsubmitted\ttitle\tlink\tdomain\ttags\turl")
+		for _, p := range posts {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", p.ID, p.Score, p.SubmittedAt.UTC().Format(time.RFC3339), tsvField(p.Title), tsvField(p.LinkURL), p.Domain, strings.Join(p.Tags, ","), postURL(p))
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSCORE\tAGE\tTITLE\tDOMAIN")
+	for _, p := range posts {
+		domain := p.Domain
+		if p.LinkURL == "" {
+			domain = "(self)"
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\n", p.ID, p.Score, age(time.Since(p.SubmittedAt)), tsvField(p.Title), domain)
+	}
+	return tw.Flush()
+}
+
+// tsvField replaces the tabs and newlines in s with spaces, so that it can
+// be a field of a tab-separated line.
+func tsvField(s string) string {
+	return strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
+
+// postURL returns the absolute URL of the post's page on the site.
+func postURL(p *thesrc.Post) string {
+	url, err := router.App().Get(router.Post).URL("ID", strconv.Itoa(p.ID))
+	if err != nil {
+		return ""
+	}
+	return baseURL.ResolveReference(url).String()
+}
+
+// age formats d briefly, in its largest whole unit (e.g., "5m", "3h", or
+// "2d").
+func age(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
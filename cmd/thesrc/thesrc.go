@@ -1,28 +1,63 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
+	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc"
 	"sourcegraph.com/sourcegraph/thesrc"
 	"sourcegraph.com/sourcegraph/thesrc/api"
 	"sourcegraph.com/sourcegraph/thesrc/app"
+	"sourcegraph.com/sourcegraph/thesrc/archive"
 	"sourcegraph.com/sourcegraph/thesrc/classifier"
+	"sourcegraph.com/sourcegraph/thesrc/compress"
+	"sourcegraph.com/sourcegraph/thesrc/cron"
 	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/health"
 	"sourcegraph.com/sourcegraph/thesrc/importer"
+	"sourcegraph.com/sourcegraph/thesrc/jobs"
+	"sourcegraph.com/sourcegraph/thesrc/linkcheck"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/mail"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
+	"sourcegraph.com/sourcegraph/thesrc/redis"
 	"sourcegraph.com/sourcegraph/thesrc/router"
+	"sourcegraph.com/sourcegraph/thesrc/rpc"
+	"sourcegraph.com/sourcegraph/thesrc/spam"
+	"sourcegraph.com/sourcegraph/thesrc/thumbnail"
+	"sourcegraph.com/sourcegraph/thesrc/tracing"
+	"sourcegraph.com/sourcegraph/thesrc/trending"
+	"sourcegraph.com/sourcegraph/thesrc/votefraud"
+	"sourcegraph.com/sourcegraph/thesrc/webhooks"
 )
 
 var (
-	baseURLStr = flag.String("url", "http://thesrc.org", "base URL of thesrc")
-	baseURL    *url.URL
+	baseURLStr   = flag.String("url", "http://thesrc.org", "base URL of thesrc")
+	dbSource     = flag.String("db", "", "PostgreSQL data source name (if empty, the PG* environment variables are used), or sqlite:///path/to/file.db to use SQLite")
+	dbMaxOpen    = flag.Int("db-max-open-conns", datastore.MaxOpenConns, "maximum number of open database connections (0 for no limit)")
+	dbMaxIdle    = flag.Int("db-max-idle-conns", datastore.MaxIdleConns, "maximum number of idle database connections to keep open")
+	dbLifetime   = flag.Duration("db-conn-max-lifetime", datastore.ConnMaxLifetime, "how long a database connection may be reused before it is closed (0 for no limit)")
+	dbTimeout    = flag.Duration("db-statement-timeout", datastore.StatementTimeout, "how long a PostgreSQL statement may run before it is canceled (0 to use the server's statement_timeout)")
+	dbSlowQuery  = flag.Duration("db-slow-query-threshold", datastore.SlowQueryThreshold, "log SQL statements that take longer than this (0 to disable)")
+	authToken    = flag.String("token", os.Getenv("THESRC_TOKEN"), "personal API token to authenticate client commands such as post (defaults to $THESRC_TOKEN)")
+	retries      = flag.Int("retries", 3, "number of times to retry API requests that fail with a network error or a 5xx or 429 response (non-idempotent requests only if the server didn't act on them)")
+	retryBackoff = flag.Duration("retry-backoff", thesrc.DefaultRetryBackoff, "how long to wait before retrying a failed API request (doubled after each retry)")
+	baseURL      *url.URL
 )
 
 func init() {
@@ -55,61 +90,106 @@ func main() {
 		flag.Usage()
 	}
 	log.SetFlags(0)
+	loadConfig()
+
+	name := flag.Arg(0)
+	var cmd *subcmd
+	for i := range subcmds {
+		if subcmds[i].name == name {
+			cmd = &subcmds[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown subcmd %q\n", name)
+		fmt.Fprintln(os.Stderr, `Run "thesrc -h" for usage.`)
+		os.Exit(1)
+	}
+	if cmd.client {
+		loadProfile()
+	}
+
+	datastore.DataSource = *dbSource
+	datastore.MaxOpenConns = *dbMaxOpen
+	datastore.MaxIdleConns = *dbMaxIdle
+	datastore.ConnMaxLifetime = *dbLifetime
+	datastore.StatementTimeout = *dbTimeout
+	datastore.SlowQueryThreshold = *dbSlowQuery
 
 	var err error
 	baseURL, err = url.Parse(*baseURLStr)
 	if err != nil {
 		log.Fatal(err)
 	}
-	apiclient.BaseURL = baseURL.ResolveReference(&url.URL{Path: "/api/"})
+	apiclient.BaseURL = baseURL.ResolveReference(&url.URL{Path: "/api/" + thesrc.APIVersion + "/"})
+	apiclient.MaxRetries = *retries
+	apiclient.RetryBackoff = *retryBackoff
 	app.APIClient = apiclient
-	importer.Store = apiclient
+	app.BaseURL = baseURL
+	importer.Posts = apiclient.Posts
 
-	subcmd := flag.Arg(0)
-	for _, c := range subcmds {
-		if c.name == subcmd {
-			c.run(flag.Args()[1:])
-			return
-		}
-	}
-
-	fmt.Fprintf(os.Stderr, "unknown subcmd %q\n", subcmd)
-	fmt.Fprintln(os.Stderr, `Run "thesrc -h" for usage.`)
-	os.Exit(1)
+	cmd.run(flag.Args()[1:])
 }
 
 type subcmd struct {
 	name        string
 	description string
 	run         func(args []string)
+
+	// client is whether the subcommand is a client of a server's API (as a
+	// user, with -token), and so uses the selected profile (see
+	// loadProfile). Servers and importers never do, so that a user's
+	// profiles can't point them at another server.
+	client bool
 }
 
 var subcmds = []subcmd{
-	{"post", "submit a post", postCmd},
-	{"import", "import posts from other sites", importCmd},
-	{"classify", "classify posts", classifyCmd},
-	{"serve", "start web server", serveCmd},
-	{"createdb", "create the database schema", createDBCmd},
+	{"post", "submit a post", postCmd, true},
+	{"list", "list posts", listCmd, true},
+	{"search", "search posts", searchCmd, true},
+	{"tui", "read the site in an interactive terminal UI", tuiCmd, true},
+	{"import", "import posts from other sites", importCmd, false},
+	{"crawl", "continuously import posts from other sites (crawler daemon)", crawlCmd, false},
+	{"classify", "classify posts", classifyCmd, false},
+	{"check-links", "check posts' links and mark dead ones", checkLinksCmd, false},
+	{"serve", "start web server", serveCmd, false},
+	{"worker", "run background jobs from the job queue", workerCmd, false},
+	{"migrate", "migrate the database schema", migrateCmd, false},
+	{"grant-role", "set a user's role (e.g., to make the first admin)", grantRoleCmd, false},
+	{"read-only", "show or set the server's read-only mode", readOnlyCmd, true},
+	{"export", "export all posts as JSON Lines", exportCmd, false},
+	{"import-dump", "import posts exported by export", importDumpCmd, false},
 }
 
 var apiclient = thesrc.NewClient(nil)
 
+// userAPIClient returns an API client that is authenticated with the -token
+// flag (if set). Unlike apiclient, it must not be used by the app server,
+// which makes requests on behalf of its visitors.
+func userAPIClient() *thesrc.Client {
+	if *authToken == "" {
+		return apiclient
+	}
+	return apiclient.WithAuthToken(*authToken)
+}
+
 func postCmd(args []string) {
 	fs := flag.NewFlagSet("post", flag.ExitOnError)
 	title := fs.String("title", "", "title of post")
-	linkURL := fs.String("link", "", "link URL")
+	linkURL := fs.String("link", "", "link URL (if empty, the post is a self-post and -body is required)")
 	body := fs.String("body", "", "body of post")
 	fs.Usage = func() {
 		fmt.Fprintln(os.Stderr, `usage: thesrc post [options]
 
-Submits a post.
+Submits a post. To submit it as yourself, set the -token flag or the
+THESRC_TOKEN environment variable to a personal API token (which you can
+create at /settings/tokens).
 
 The options are:
 `)
 		fs.PrintDefaults()
 		os.Exit(1)
 	}
-	fs.Parse(args)
+	parseFlags(fs, args)
 
 	if fs.NArg() != 0 {
 		fs.Usage()
@@ -118,8 +198,8 @@ The options are:
 	if *title == "" {
 		log.Fatal(`Title must not be empty. See "thesrc post -h" for usage.`)
 	}
-	if *linkURL == "" {
-		log.Fatal(`Link URL must not be empty. See "thesrc post -h" for usage.`)
+	if *linkURL == "" && *body == "" {
+		log.Fatal(`Either a link URL or a body must be given. See "thesrc post -h" for usage.`)
 	}
 
 	post := &thesrc.Post{
@@ -127,7 +207,7 @@ The options are:
 		LinkURL: *linkURL,
 		Body:    *body,
 	}
-	created, err := apiclient.Posts.Submit(post)
+	created, err := userAPIClient().Posts.Submit(post)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -147,10 +227,21 @@ The options are:
 
 func importCmd(args []string) {
 	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	interval := fs.Duration("interval", 0, "if nonzero, keep importing (polling each site) at this interval")
+	subreddits := fs.String("subreddits", strings.Join(importer.DefaultSubreddits, ","), "comma-separated list of subreddits to import from")
+	redditDomains := fs.String("reddit-domains", "", "comma-separated allowlist of link domains to import from Reddit (default: all)")
+	direct := fs.Bool("direct", false, "submit posts directly to the datastore (given by -db) instead of through the API")
+	setFilter := relevanceFilterFlags(fs)
 	fs.Usage = func() {
-		fmt.Fprintln(os.Stderr, `usage: thesrc import [options]
+		fmt.Fprintln(os.Stderr, `usage: thesrc import [options] [site...]
 
-Imports posts from other sites.
+Imports posts from other sites. If sites are given, only posts from those sites
+are imported; a site name such as "hackernews" matches all of its lists (e.g.,
+"hackernews/top" and "hackernews/new"). To keep importing in the background,
+use "thesrc crawl".
+
+Posts are submitted through the API (at -url), unless -direct is set, in which
+case they are submitted in-process to the datastore, without an API server.
 
 The available sites are:
 `)
@@ -164,10 +255,13 @@ The options are:
 		fs.PrintDefaults()
 		os.Exit(1)
 	}
-	fs.Parse(args)
+	parseFlags(fs, args)
 
-	if fs.NArg() != 0 {
-		fs.Usage()
+	importer.RedditDomains = splitList(*redditDomains)
+	setFilter()
+	fetchers := selectFetchers(splitList(*subreddits), nil, fs.Args())
+	if len(fetchers) == 0 {
+		log.Fatalf(`No sites match %q. See "thesrc import -h" for usage.`, fs.Args())
 	}
 
 	var numTotal, numCreated int
@@ -183,33 +277,225 @@ The options are:
 		numCreated++
 	}
 
-	datastore.Connect()
-	var failed bool
-	var wg sync.WaitGroup
-	for _, f_ := range importer.Fetchers {
-		f := f_
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if err := importer.Import(f); err != nil {
-				log.Printf("Error fetching from %s: %s.", f.Site(), err)
-				mu.Lock()
-				failed = true
-				mu.Unlock()
+	if *direct {
+		datastore.Connect()
+		importer.Posts = datastore.NewDatastore(nil).Posts
+	}
+	for {
+		var failed bool
+		var wg sync.WaitGroup
+		for _, f_ := range fetchers {
+			f := f_
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := importer.Import(f); err != nil {
+					log.Printf("Error fetching from %s: %s.", f.Site(), err)
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		log.Printf("# import: %d new posts, %d already existed", numCreated, numTotal-numCreated)
+		if *interval == 0 {
+			if failed {
+				os.Exit(1)
 			}
-		}()
+			return
+		}
+		numTotal, numCreated = 0, 0
+		time.Sleep(*interval)
 	}
-	wg.Wait()
+}
+
+func crawlCmd(args []string) {
+	fs := flag.NewFlagSet("crawl", flag.ExitOnError)
+	interval := fs.Duration("interval", 10*time.Minute, "default interval between crawls of each site")
+	intervals := fs.String("intervals", "", "comma-separated site=interval overrides of -interval (e.g., hackernews=5m,reddit=30m)")
+	jitter := fs.Float64("jitter", 0.1, "fraction by which each interval is randomly lengthened or shortened")
+	stateFile := fs.String("state", "thesrc-crawl.json", "file to persist the time of each site's last crawl and the link URLs last seen there (empty to not persist)")
+	subreddits := fs.String("subreddits", strings.Join(importer.DefaultSubreddits, ","), "comma-separated list of subreddits to crawl")
+	redditDomains := fs.String("reddit-domains", "", "comma-separated allowlist of link domains to import from Reddit (default: all)")
+	rssFeeds := fs.String("rss", "", "comma-separated list of RSS or Atom feed URLs to crawl")
+	direct := fs.Bool("direct", false, "submit posts directly to the datastore (given by -db) instead of through the API")
+	setFilter := relevanceFilterFlags(fs)
+	metricsAddr := fs.String("metrics-addr", "", "if set, serve Prometheus metrics at /metrics on this address (e.g., :5003)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc crawl [options] [site...]
 
-	log.Printf("# import: %d new posts, %d already existed", numCreated, numTotal-numCreated)
-	if failed {
+Continuously imports posts from other sites, crawling each site at its own
+interval until interrupted. If sites are given, only those sites are crawled;
+a site name such as "hackernews" matches all of its lists (e.g.,
+"hackernews/top" and "hackernews/new"). Use "thesrc import" to import once.
+
+The available sites are:
+`)
+		for _, f := range importer.Fetchers {
+			fmt.Fprintln(os.Stderr, "  ", f.Site())
+		}
+		fmt.Fprintln(os.Stderr, `   rss/... (feeds given by -rss)
+
+The options are:
+`)
+		fs.PrintDefaults()
 		os.Exit(1)
 	}
+	parseFlags(fs, args)
+
+	siteIntervals := map[string]time.Duration{}
+	for _, kv := range splitList(*intervals) {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			log.Fatalf(`Invalid -intervals entry %q (want site=interval). See "thesrc crawl -h" for usage.`, kv)
+		}
+		d, err := time.ParseDuration(parts[1])
+		if err != nil {
+			log.Fatalf("Invalid -intervals entry %q: %s.", kv, err)
+		}
+		siteIntervals[parts[0]] = d
+	}
+
+	importer.RedditDomains = splitList(*redditDomains)
+	setFilter()
+	fetchers := selectFetchers(splitList(*subreddits), splitList(*rssFeeds), fs.Args())
+	if len(fetchers) == 0 {
+		log.Fatalf(`No sites match %q. See "thesrc crawl -h" for usage.`, fs.Args())
+	}
+	crawler := &importer.Crawler{Jitter: *jitter, StateFile: *stateFile}
+	for _, f := range fetchers {
+		// The most specific matching override applies (e.g., "reddit/golang"
+		// rather than "reddit").
+		src := &importer.Source{Fetcher: f, Interval: *interval}
+		var matched string
+		for site, d := range siteIntervals {
+			if len(site) > len(matched) && matchSite(f.Site(), []string{site}) {
+				src.Interval, matched = d, site
+			}
+		}
+		crawler.Sources = append(crawler.Sources, src)
+		log.Printf("Crawling %s every %s", f.Site(), src.Interval)
+	}
+
+	importer.Imported = func(site string, post *thesrc.Post, created bool) {
+		if created {
+			log.Printf("%-12s  %s (%s)", site, post.Title, post.LinkURL)
+		}
+	}
+
+	if *direct {
+		datastore.Connect()
+		importer.Posts = datastore.NewDatastore(nil).Posts
+	}
+
+	if *metricsAddr != "" {
+		mm := http.NewServeMux()
+		mm.Handle("/metrics", metrics.Handler())
+		go func() {
+			log.Print("Serving metrics on ", *metricsAddr)
+			log.Fatal("ListenAndServe (metrics): ", http.ListenAndServe(*metricsAddr, mm))
+		}()
+	}
+
+	// Stop crawling on SIGINT or SIGTERM. Crawls in progress are finished
+	// (and the crawler's state saved) before exiting.
+	stop := make(chan struct{})
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		log.Printf("Received %s; stopping after crawls in progress finish...", <-sig)
+		close(stop)
+	}()
+
+	if err := crawler.Run(stop); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// relevanceFilterFlags adds the flags that configure the importer's relevance
+// filter to fs, and returns a func that sets importer.Filter according to
+// them (after fs is parsed).
+func relevanceFilterFlags(fs *flag.FlagSet) (setFilter func()) {
+	enabled := fs.Bool("relevance-filter", true, "drop imported posts that don't look like they're about code (e.g., politics or general news)")
+	minRelevance := fs.Float64("min-relevance", importer.DefaultMinRelevance, "relevance score (from 0 to 1) below which imported posts are dropped")
+	allowDomains := fs.String("allow-domains", "", "comma-separated link domains (including their subdomains) whose posts are always imported")
+	denyDomains := fs.String("deny-domains", "", "comma-separated link domains (including their subdomains) whose posts are never imported")
+	rejectLog := fs.String("reject-log", "", "if set, append a line of JSON describing each dropped post to this file (for tuning the filter)")
+	return func() {
+		if !*enabled {
+			return
+		}
+		f := &importer.RelevanceFilter{
+			MinRelevance: *minRelevance,
+			AllowDomains: splitList(*allowDomains),
+			DenyDomains:  splitList(*denyDomains),
+		}
+		if *rejectLog != "" {
+			w, err := os.OpenFile(*rejectLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+			if err != nil {
+				log.Fatal(err)
+			}
+			f.RejectLog = w
+		}
+		importer.Filter = f
+	}
+}
+
+// selectFetchers returns the fetchers of the built-in sites (except
+// subreddits), the given subreddits, and the given RSS or Atom feeds, that
+// are named by names (see matchSite). If names is empty, all of them are
+// returned.
+func selectFetchers(subreddits, rssFeeds, names []string) []importer.Fetcher {
+	all := make([]importer.Fetcher, 0, len(importer.Fetchers))
+	for _, f := range importer.Fetchers {
+		if !strings.HasPrefix(f.Site(), "reddit/") {
+			all = append(all, f)
+		}
+	}
+	for _, name := range subreddits {
+		all = append(all, importer.Subreddit(name))
+	}
+	for _, feedURL := range rssFeeds {
+		all = append(all, importer.RSS(feedURL))
+	}
+
+	var fetchers []importer.Fetcher
+	for _, f := range all {
+		if len(names) == 0 || matchSite(f.Site(), names) {
+			fetchers = append(fetchers, f)
+		}
+	}
+	return fetchers
+}
+
+// matchSite returns whether site is named by any of names, either exactly or
+// as a prefix followed by a "/" (so "hackernews" matches "hackernews/top").
+func matchSite(site string, names []string) bool {
+	for _, name := range names {
+		if site == name || strings.HasPrefix(site, strings.TrimSuffix(name, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma-separated list, omitting empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
 }
 
 func classifyCmd(args []string) {
 	fs := flag.NewFlagSet("classify", flag.ExitOnError)
 	concurrency := fs.Int("c", 10, "concurrent classifiers")
+	topics := fs.Bool("topics", false, "also tag posts with the topics that they're about (as \"thesrc serve -topics\" does for new posts)")
 	fs.Usage = func() {
 		fmt.Fprintln(os.Stderr, `usage: thesrc classify [options]
 
@@ -220,7 +506,7 @@ The options are:
 		fs.PrintDefaults()
 		os.Exit(1)
 	}
-	fs.Parse(args)
+	parseFlags(fs, args)
 
 	if fs.NArg() != 0 {
 		fs.Usage()
@@ -229,6 +515,13 @@ The options are:
 	var mu sync.Mutex
 	summary := map[string]int{}
 
+	var topicClassifier *classifier.TopicClassifier
+	if *topics {
+		topicClassifier = &classifier.TopicClassifier{}
+	}
+	datastore.Connect()
+	store := datastore.NewDatastore(nil)
+
 	workChan := make(chan *thesrc.Post)
 	quitChan := make(chan struct{})
 	for i := 0; i < *concurrency; i++ {
@@ -236,6 +529,18 @@ The options are:
 			for {
 				select {
 				case post := <-workChan:
+					if topicClassifier != nil && topicClassifier.Tag(post) {
+						if err := store.Posts.Update(post.ID, post); err == thesrc.ErrPostVersionConflict {
+							// Someone edited the post since it was listed; don't
+							// overwrite their edit. The next run will see it.
+							log.Printf("Skipping %q, which changed while classifying. (Continuing...)", post.LinkURL)
+							continue
+						} else if err != nil {
+							log.Fatal(err)
+						}
+						fmt.Printf("tagged %-20s %s\n", strings.Join(post.Tags, ","), post.LinkURL)
+					}
+
 					c, err := classifier.Classify(post)
 					if err != nil {
 						log.Printf("Error classifying %q: %s. (Continuing...)", post.LinkURL, err)
@@ -261,7 +566,6 @@ The options are:
 		}()
 	}
 
-	datastore.Connect()
 	perPage := 100
 	for pg := 1; true; pg++ {
 		log.Println("Fetching more posts...")
@@ -283,6 +587,49 @@ The options are:
 	fmt.Fprintf(os.Stderr, "# classified posts: %v\n", summary)
 }
 
+func checkLinksCmd(args []string) {
+	fs := flag.NewFlagSet("check-links", flag.ExitOnError)
+	maxAge := fs.Duration("max-age", linkcheck.DefaultMaxAge, "check links that haven't been checked in this long")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc check-links [options]
+
+Checks the link URLs of posts whose links are due to be checked, and
+marks posts whose links are dead (404 Not Found, 410 Gone, or a
+nonexistent domain), recording the Internet Archive's snapshot of
+each dead link's page (if any). To check links periodically instead,
+run "thesrc serve -check-links".
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	parseFlags(fs, args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+	}
+
+	datastore.Connect()
+	c := &linkcheck.Checker{Store: datastore.NewDatastore(nil).LinkChecks, MaxAge: *maxAge}
+
+	var total, totalDead int
+	for {
+		checked, dead, err := c.RunOnce()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if checked == 0 {
+			break
+		}
+		total += checked
+		totalDead += dead
+		log.Printf("Checked %d links (%d dead)", total, totalDead)
+	}
+
+	fmt.Fprintf(os.Stderr, "# checked links: %d (%d dead)\n", total, totalDead)
+}
+
 func firstWord(s string) string {
 	i := strings.Index(s, " ")
 	if i == -1 {
@@ -293,10 +640,81 @@ func firstWord(s string) string {
 
 func serveCmd(args []string) {
 	fs := flag.NewFlagSet("serve", flag.ExitOnError)
-	httpAddr := fs.String("http", ":5000", "HTTP service address")
-	templateDir := fs.String("tmpl-dir", app.TemplateDir, "template directory")
-	staticDir := fs.String("static-dir", app.StaticDir, "static assets directory")
-	reload := flag.Bool("reload", true, "reload templates on each request (dev mode)")
+	httpAddr := fs.String("http", ":5000", "HTTP service address (HTTPS if -tls-cert or -autocert-domain is set)")
+	tlsCert := fs.String("tls-cert", "", "if set, serve HTTPS using this TLS certificate file (PEM, with any intermediate certificates; requires -tls-key)")
+	tlsKey := fs.String("tls-key", "", "TLS private key file (PEM) for -tls-cert")
+	autocertDomains := fs.String("autocert-domain", "", "if set, serve HTTPS using certificates obtained automatically from Let's Encrypt (via ACME) for these comma-separated domains")
+	autocertDir := fs.String("autocert-cache-dir", "autocert-cache", "directory to cache -autocert-domain certificates and account keys in")
+	autocertEmail := fs.String("autocert-email", "", "contact email address for the -autocert-domain Let's Encrypt account (optional)")
+	redirectHTTPAddr := fs.String("redirect-http-addr", "", "when serving HTTPS, also listen on this address (e.g., :80) to redirect HTTP requests to HTTPS (and answer ACME HTTP challenges)")
+	templateDir := fs.String("tmpl-dir", "", "directory of templates that override the built-in templates (a theme)")
+	staticDir := fs.String("static-dir", "", "directory of static assets that override the built-in static assets")
+	reload := fs.Bool("reload", true, "reload templates and static assets when they change (dev mode)")
+	authSecret := fs.String("auth-secret", os.Getenv("THESRC_AUTH_SECRET"), "secret key for signing password reset and two-factor authentication tokens (defaults to $THESRC_AUTH_SECRET)")
+	sessionIdleTimeout := fs.Duration("session-idle-timeout", api.SessionIdleTimeout, "how long a login session lasts after it was last used")
+	requireTwoFactorRole := fs.String("require-2fa-role", "", "if set (e.g., to moderator), users with this role or a more privileged one may only use their role's privileges once they've enabled two-factor authentication")
+	rateLimit := fs.Int("rate-limit", 0, "max API requests per minute per client (user or IP address); 0 means unlimited")
+	rateLimitBurst := fs.Int("rate-limit-burst", api.RateLimitBurst, "max API requests per client in a burst")
+	rateLimitExempt := fs.String("rate-limit-exempt", strings.Join(api.RateLimitExempt, ","), "comma-separated IP addresses exempt from rate limiting (e.g., of app servers)")
+	corsOrigins := fs.String("cors-origins", "", "comma-separated origins (e.g., https://example.com, or * for any) of web pages allowed to call the API from browsers")
+	corsMethods := fs.String("cors-methods", strings.Join(api.CORSMethods, ","), "comma-separated HTTP methods that cross-origin API requests may use (with -cors-origins)")
+	corsCredentials := fs.Bool("cors-credentials", false, "allow cross-origin API requests to include cookies and HTTP authentication (with -cors-origins, other than *)")
+	trustProxyHeaders := fs.Bool("trust-proxy-headers", false, "use X-Forwarded-For to identify clients (only if behind a proxy that sets it)")
+	storeType := fs.String("store", "postgres", "datastore backend: postgres (the SQL database given by -db, which may be SQLite), or memory (for demos; data is lost on exit)")
+	listCacheTTL := fs.Duration("list-cache-ttl", api.PostListCacheTTL, "how long to cache post lists in memory, or in Redis if -redis-url is set (0 to disable)")
+	redisURL := fs.String("redis-url", os.Getenv("THESRC_REDIS_URL"), "if set, keep sessions, rate limit counters, and cached post lists in this Redis server (redis://[:password@]host[:port][/db]), and relay live updates through it, so that multiple servers share them (defaults to $THESRC_REDIS_URL)")
+	replicaSafe := fs.Bool("replica-safe", false, "refuse to start with options that break when several servers serve the site (behind a load balancer): requires -redis-url, -auth-secret, and -reload=false, and disallows -store=memory, -autocert-domain, and -thumbnails without -thumbnail-s3-bucket")
+	flagHideThreshold := fs.Int("flag-hide-threshold", api.FlagHideThreshold, "number of flags after which a post is automatically hidden (0 to disable)")
+	reportRateLimit := fs.Int("report-rate-limit", api.ReportRateLimit, "max abuse reports per hour per client IP address (0 means unlimited)")
+	readOnly := fs.Bool("read-only", false, "start in read-only mode, rejecting requests that would change data (e.g., during migrations); admins can turn it off with \"thesrc read-only off\"")
+	metricsAddr := fs.String("metrics-addr", "", "if set, serve Prometheus metrics at /metrics on this address (e.g., :5001)")
+	otlpEndpoint := fs.String("otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"), "if set, export OpenTelemetry traces to this OTLP/HTTP (JSON) endpoint, e.g., http://localhost:4318/v1/traces (defaults to $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT)")
+	otlpHeaders := fs.String("otlp-headers", os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), "comma-separated key=value headers to send to the -otlp-endpoint (defaults to $OTEL_EXPORTER_OTLP_HEADERS)")
+	traceServiceName := fs.String("trace-service-name", "thesrc", "service name of exported traces")
+	traceSampleRatio := fs.Float64("trace-sample-ratio", tracing.SampleRatio, "fraction of requests to trace (requests continuing a caller's trace follow the caller's decision)")
+	grpcAddr := fs.String("grpc-addr", "", "if set, serve the gRPC Posts service (which calls the API at -url) on this address (e.g., :5002)")
+	compressResponses := fs.Bool("compress", true, "compress HTML, JSON, and feed responses (with gzip) for clients that accept it")
+	drainTimeout := fs.Duration("drain-timeout", 30*time.Second, "on SIGINT or SIGTERM, max time to wait for in-flight requests to finish before exiting")
+	thumbnails := fs.Bool("thumbnails", false, "generate thumbnails of posts' linked pages in the background")
+	thumbnailInterval := fs.Duration("thumbnail-interval", time.Minute, "how often to check for posts that need thumbnails")
+	thumbnailDir := fs.String("thumbnail-dir", "thumbnails", "directory to store thumbnails in (served at /thumbnails/), if -thumbnail-s3-bucket is not set")
+	thumbnailS3Bucket := fs.String("thumbnail-s3-bucket", "", "if set, store thumbnails in this S3 bucket (using the credentials in $AWS_ACCESS_KEY_ID and $AWS_SECRET_ACCESS_KEY)")
+	thumbnailS3Region := fs.String("thumbnail-s3-region", "us-east-1", "region of the -thumbnail-s3-bucket")
+	thumbnailS3URL := fs.String("thumbnail-s3-url", "", "public URL prefix of thumbnails in S3, such as a CDN (defaults to the bucket's URL)")
+	checkLinks := fs.Bool("check-links", false, "periodically check posts' links in the background, and mark posts whose links are dead")
+	checkLinksInterval := fs.Duration("check-links-interval", time.Minute, "how often to check for posts whose links are due to be checked")
+	checkLinksMaxAge := fs.Duration("check-links-max-age", linkcheck.DefaultMaxAge, "how long after a post's link is checked that it is checked again")
+	trendingInterval := fs.Duration("trending-interval", 5*time.Minute, "how often to update posts' velocities for /trending (0 to disable)")
+	trendingWindow := fs.Duration("trending-window", trending.DefaultWindow, "period over which votes and comments are counted in posts' velocities")
+	voteAnalysis := fs.Bool("vote-analysis", false, "periodically analyze recent votes for voting rings and other patterns of vote fraud, and list the users involved in the moderation queue")
+	voteAnalysisInterval := fs.Duration("vote-analysis-interval", time.Hour, "how often to analyze recent votes (with -vote-analysis)")
+	voteAnalysisWindow := fs.Duration("vote-analysis-window", votefraud.DefaultWindow, "period whose votes are analyzed (with -vote-analysis)")
+	recordClients := fs.Bool("record-clients", false, "record hashes of the IP addresses and User-Agents that posts are submitted and voted on from, for abuse detection (viewable only by admins)")
+	clientRecordRetention := fs.Duration("client-record-retention", 30*24*time.Hour, "how long client records are kept before they are purged")
+	deletedPostRetention := fs.Duration("deleted-post-retention", 30*24*time.Hour, "how long deleted posts are kept (and may be undeleted by admins) before they are purged")
+	clientSaltPeriod := fs.Duration("client-salt-period", api.ClientSaltPeriod, "how often the salt that client IP addresses and User-Agents are hashed with is replaced (hashes only match within a period, and old salts are deleted)")
+	nullifySuspectVotes := fs.Bool("nullify-suspect-votes", false, "nullify the votes of users flagged by -vote-analysis immediately, instead of counting them until a moderator nullifies them")
+	archiveLinks := fs.Bool("archive-links", false, "ask the Internet Archive's Wayback Machine to capture new posts' linked pages, and link to the snapshots")
+	spamFilter := fs.Bool("spam-filter", false, "score submitted posts for spam, and hold likely spam for moderation")
+	spamThreshold := fs.Float64("spam-threshold", spam.DefaultThreshold, "spam score at or above which posts are held for moderation")
+	spamBlockedDomains := fs.String("spam-blocked-domains", "", "comma-separated domains (including their subdomains) whose links are considered spam")
+	spamBlockedURLs := fs.String("spam-blocked-urls", "", "comma-separated substrings of link URLs that are considered spam")
+	spamMaxPosts := fs.Int("spam-max-posts", spam.DefaultMaxPosts, "number of posts a user may submit in -spam-window before further posts are considered spam")
+	spamWindow := fs.Duration("spam-window", spam.DefaultWindow, "period over which -spam-max-posts is counted")
+	topics := fs.Bool("topics", true, "tag submitted posts with the topics (such as Go, Rust, databases, security, and ML) that they're about")
+	topicClassifierURL := fs.String("topic-classifier-url", "", "if set, also tag submitted posts with the topics returned by this external classifier (which is POSTed each post as JSON and responds with {\"Topics\": [...]}; requires -topics)")
+	akismetKey := fs.String("akismet-key", os.Getenv("AKISMET_KEY"), "if set, also check submitted posts with Akismet using this API key (defaults to $AKISMET_KEY; requires -spam-filter)")
+	sitemapInterval := fs.Duration("sitemap-interval", time.Hour, "how often to regenerate /sitemap.xml")
+	schedules := fs.String("schedule", "", "semicolon-separated task=schedule pairs that override when periodic tasks (sitemap, prune-sessions, thumbnails, check-links, trending, vote-analysis, purge-client-records, and purge-deleted-posts) run, where a schedule is \"@every 10m\", \"@hourly\", \"@daily\", \"@weekly\", \"@monthly\", or a 5-field cron expression in UTC, e.g.: trending=*/10 * * * *;prune-sessions=0 4 * * *")
+	webhookMaxAttempts := fs.Int("webhook-max-attempts", webhooks.DefaultMaxAttempts, "number of times to attempt delivering an event to a webhook")
+	webhookBackoff := fs.Duration("webhook-backoff", webhooks.DefaultBackoff, "how long to wait before retrying a failed webhook delivery (doubled after each retry)")
+	queueJobs := fs.Bool("jobs", false, "queue webhook deliveries and the unfurling of submitted links on the job queue, to be run by \"thesrc worker\" processes (or -job-workers), instead of delivering webhooks in this server")
+	jobWorkers := fs.Int("job-workers", 0, "number of jobs from the job queue to run at the same time in this server (with -jobs; 0 to leave them to \"thesrc worker\" processes)")
+	smtpAddr := fs.String("smtp-addr", "", "if set, send email (such as password reset links) through this SMTP server (e.g., smtp.example.com:587)")
+	smtpUsername := fs.String("smtp-username", "", "username for the -smtp-addr server (if it requires authentication)")
+	smtpPassword := fs.String("smtp-password", os.Getenv("THESRC_SMTP_PASSWORD"), "password for -smtp-username (defaults to $THESRC_SMTP_PASSWORD)")
+	mailFrom := fs.String("mail-from", "thesrc <noreply@thesrc.org>", "From address of email sent by thesrc")
+	screenshotCmd := fs.String("screenshot-cmd", "", "command to screenshot pages with no og:image ({{url}} and {{file}} are replaced by the page URL and PNG file to write), e.g.: chromium --headless --screenshot={{file}} {{url}}")
 	fs.Usage = func() {
 		fmt.Fprintln(os.Stderr, `usage: thesrc serve [options] 
 
@@ -307,52 +725,819 @@ The options are:
 		fs.PrintDefaults()
 		os.Exit(1)
 	}
-	fs.Parse(args)
+	parseFlags(fs, args)
 
 	if fs.NArg() != 0 {
 		fs.Usage()
 	}
 
+	if *replicaSafe {
+		var problems []string
+		if *storeType == "memory" {
+			problems = append(problems, "-store=memory keeps data in this server's memory")
+		}
+		if *redisURL == "" {
+			problems = append(problems, "-redis-url is required to share sessions, rate limits, caches, live updates, and read-only mode")
+		}
+		if *authSecret == "" {
+			problems = append(problems, "-auth-secret is required so that all servers accept the same signed tokens")
+		}
+		if *reload {
+			problems = append(problems, "-reload=false is required so that all servers serve the same templates")
+		}
+		if *autocertDomains != "" {
+			problems = append(problems, "-autocert-domain keeps certificates on this server; terminate TLS at the load balancer instead")
+		}
+		if *thumbnails && *thumbnailS3Bucket == "" {
+			problems = append(problems, "-thumbnails requires -thumbnail-s3-bucket, since thumbnails in -thumbnail-dir are only served by this server")
+		}
+		if len(problems) > 0 {
+			log.Fatalf("Incompatible options for -replica-safe:\n\t%s", strings.Join(problems, "\n\t"))
+		}
+	}
+
 	app.StaticDir = *staticDir
 	app.TemplateDir = *templateDir
-	app.ReloadTemplates = *reload
 	app.LoadTemplates()
 
-	datastore.Connect()
+	if *authSecret == "" {
+		log.Print("Warning: no -auth-secret set; using a random secret, so password reset links will not work across restarts.")
+		api.AuthSecret = make([]byte, 32)
+		if _, err := rand.Read(api.AuthSecret); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		api.AuthSecret = []byte(*authSecret)
+	}
+
+	if *requireTwoFactorRole != "" && !thesrc.ValidRole(*requireTwoFactorRole) {
+		log.Fatalf(`Unknown -require-2fa-role %q. See "thesrc serve -h" for usage.`, *requireTwoFactorRole)
+	}
+	api.RequireTwoFactorRole = *requireTwoFactorRole
+	api.SessionIdleTimeout = *sessionIdleTimeout
+
+	api.RateLimit = *rateLimit
+	api.RateLimitBurst = *rateLimitBurst
+	api.RateLimitExempt = splitList(*rateLimitExempt)
+	api.CORSOrigins = splitList(*corsOrigins)
+	api.CORSMethods = splitList(*corsMethods)
+	if *corsCredentials {
+		for _, o := range api.CORSOrigins {
+			if o == "*" {
+				log.Fatal(`-cors-credentials may not be used with -cors-origins=*, which would let any web page make requests with the browser's credentials. See "thesrc serve -h" for usage.`)
+			}
+		}
+	}
+	api.CORSCredentials = *corsCredentials
+	api.TrustProxyHeaders = *trustProxyHeaders
+	app.TrustProxyHeaders = *trustProxyHeaders
+	api.RecordClients = *recordClients
+	if *clientSaltPeriod <= 0 {
+		log.Fatal(`-client-salt-period must be positive. See "thesrc serve -h" for usage.`)
+	}
+	api.ClientSaltPeriod = *clientSaltPeriod
+	apiclient.ForwardingKey = api.AuthSecret
+	api.PostListCacheTTL = *listCacheTTL
+	api.FlagHideThreshold = *flagHideThreshold
+	api.ReportRateLimit = *reportRateLimit
+	api.SetReadOnly(*readOnly)
+	app.ReadOnly = api.ReadOnly
+
+	if *smtpAddr != "" {
+		api.Mailer = &mail.SMTPSender{Addr: *smtpAddr, From: *mailFrom, Username: *smtpUsername, Password: *smtpPassword}
+	}
+	if u, err := router.App().Get(router.ResetPasswordForm).URL(); err != nil {
+		log.Fatal(err)
+	} else {
+		api.PasswordResetURL = baseURL.ResolveReference(u)
+	}
+
+	switch *storeType {
+	case "postgres":
+		datastore.Connect()
+	case "memory":
+		api.Store = datastore.NewMemoryDatastore()
+	default:
+		log.Fatalf(`Unknown -store %q. See "thesrc serve -h" for usage.`, *storeType)
+	}
+	if *storeType == "memory" && *queueJobs && *jobWorkers == 0 {
+		log.Fatal(`-jobs with -store=memory requires -job-workers, because "thesrc worker" processes can't see this server's job queue.`)
+	}
+
+	var redisClient *redis.Client
+	if *redisURL != "" {
+		var err error
+		if redisClient, err = redis.New(*redisURL); err != nil {
+			log.Fatalf("Invalid -redis-url: %s", err)
+		}
+		api.UseRedis(redisClient)
+	}
+
+	if *spamFilter {
+		f := &spam.Filter{
+			Checks: []spam.Check{
+				&spam.Blocklist{Domains: splitList(*spamBlockedDomains), Substrings: splitList(*spamBlockedURLs)},
+				&spam.DomainReputation{Domains: api.Store.Domains},
+				&spam.SubmissionRate{Posts: api.Store.Posts, MaxPosts: *spamMaxPosts, Window: *spamWindow},
+			},
+			Threshold: *spamThreshold,
+		}
+		if *akismetKey != "" {
+			f.Checks = append(f.Checks, &spam.Akismet{Key: *akismetKey, Site: baseURL.String()})
+		}
+		api.SpamFilter = f
+	}
+
+	if *topics {
+		api.TopicClassifier = &classifier.TopicClassifier{}
+		if *topicClassifierURL != "" {
+			api.TopicClassifier.External = &classifier.HTTPClassifier{URL: *topicClassifierURL}
+		}
+	}
+
+	readyChecks := []health.Check{{Name: "templates", Check: app.CheckTemplates}}
+	if *storeType == "postgres" {
+		readyChecks = append(readyChecks,
+			health.Check{Name: "database", Check: datastore.Ping},
+			health.Check{Name: "migrations", Check: checkMigrations},
+		)
+	}
+	if redisClient != nil {
+		readyChecks = append(readyChecks, health.Check{Name: "redis", Check: func() error {
+			_, err := redisClient.Do(context.Background(), "PING")
+			return err
+		}})
+	}
 
 	m := http.NewServeMux()
-	m.Handle("/api/", http.StripPrefix("/api", api.Handler()))
+	m.Handle("/api/", http.StripPrefix("/api", api.VersionedHandler()))
+	m.Handle("/healthz", health.LiveHandler())
+	m.Handle("/readyz", health.ReadyHandler(readyChecks...))
 	m.Handle("/", app.Handler())
 
-	log.Print("Listening on ", *httpAddr)
-	err := http.ListenAndServe(*httpAddr, m)
-	if err != nil {
+	// Periodic tasks run on every replica, but each occurrence of a task
+	// (other than regenerating this replica's sitemap) runs on only one.
+	tasks := []*cron.Task{
+		{Name: "sitemap", Schedule: cron.Every(*sitemapInterval), Run: app.RegenerateSitemap, Local: true},
+		{Name: "prune-sessions", Schedule: cron.Every(24 * time.Hour), Run: func() error {
+			return api.Store.Sessions.DeleteUnused(time.Now().Add(-api.SessionIdleTimeout))
+		}},
+		{Name: "purge-client-records", Schedule: cron.Every(time.Hour), Run: func() error {
+			now := time.Now()
+			if err := api.Store.ClientRecords.DeleteBefore(now.Add(-*clientRecordRetention)); err != nil {
+				return err
+			}
+			// Only the current period's salt is needed to hash new records.
+			return api.Store.ClientRecords.DeleteSaltsBefore(now.Truncate(api.ClientSaltPeriod))
+		}},
+		{Name: "purge-deleted-posts", Schedule: cron.Every(time.Hour), Run: func() error {
+			_, err := api.Store.DeletedPosts.Purge(time.Now().Add(-*deletedPostRetention))
+			return err
+		}},
+	}
+	if *thumbnails {
+		storage := thumbnailStorage(*thumbnailDir, *thumbnailS3Bucket, *thumbnailS3Region, *thumbnailS3URL)
+		if *thumbnailS3Bucket == "" {
+			m.Handle("/thumbnails/", http.StripPrefix("/thumbnails/", http.FileServer(http.Dir(*thumbnailDir))))
+		}
+		thumbnail.ScreenshotCommand = strings.Fields(*screenshotCmd)
+
+		w := &thumbnail.Worker{Store: api.Store.Thumbnails, Storage: storage}
+		tasks = append(tasks, &cron.Task{Name: "thumbnails", Schedule: cron.Every(*thumbnailInterval), Run: func() error {
+			_, err := w.RunOnce()
+			return err
+		}})
+	}
+	if *checkLinks {
+		c := &linkcheck.Checker{Store: api.Store.LinkChecks, MaxAge: *checkLinksMaxAge}
+		tasks = append(tasks, &cron.Task{Name: "check-links", Schedule: cron.Every(*checkLinksInterval), Run: func() error {
+			_, _, err := c.RunOnce()
+			return err
+		}})
+	}
+	if *trendingInterval != 0 {
+		w := &trending.Worker{Store: api.Store.Trending, Window: *trendingWindow}
+		tasks = append(tasks, &cron.Task{Name: "trending", Schedule: cron.Every(*trendingInterval), Run: func() error {
+			_, err := w.RunOnce()
+			return err
+		}})
+	}
+	if *voteAnalysis {
+		a := &votefraud.Analyzer{Store: api.Store.VoteSuspects, Window: *voteAnalysisWindow, Nullify: *nullifySuspectVotes}
+		tasks = append(tasks, &cron.Task{Name: "vote-analysis", Schedule: cron.Every(*voteAnalysisInterval), Run: func() error {
+			_, err := a.RunOnce()
+			return err
+		}})
+	}
+	if err := applySchedules(tasks, *schedules); err != nil {
+		log.Fatalf(`Invalid -schedule: %s. See "thesrc serve -h" for usage.`, err)
+	}
+	stopCron := make(chan struct{})
+	cronDone := make(chan struct{})
+	go func() {
+		(&cron.Scheduler{Locks: api.Store.TaskLocks, Tasks: tasks}).Run(stopCron)
+		close(cronDone)
+	}()
+
+	if *archiveLinks {
+		archiveEvents, _ := api.Store.Events.Subscribe()
+		go (&archive.Archiver{Store: api.Store.LinkChecks}).Run(archiveEvents)
+	}
+
+	stopTemplateWatcher := make(chan struct{})
+	if *reload {
+		go app.RunTemplateWatcher(500*time.Millisecond, stopTemplateWatcher)
+	}
+
+	dispatcher := &webhooks.Dispatcher{Store: api.Store.Webhooks, MaxAttempts: *webhookMaxAttempts, Backoff: *webhookBackoff}
+	if *queueJobs {
+		dispatcher.Jobs = api.Store.Jobs
+		api.QueueJobs = true
+	}
+	hookEvents, _ := api.Store.Events.Subscribe()
+	go dispatcher.Run(hookEvents)
+
+	stopJobWorkers := make(chan struct{})
+	jobWorkersDone := make(chan struct{})
+	if *queueJobs && *jobWorkers > 0 {
+		w := newJobWorker(api.Store, *jobWorkers)
+		go func() {
+			w.Run(stopJobWorkers)
+			close(jobWorkersDone)
+		}()
+	} else {
+		close(jobWorkersDone)
+	}
+
+	if *metricsAddr != "" {
+		mm := http.NewServeMux()
+		mm.Handle("/metrics", metrics.Handler())
+		go func() {
+			log.Print("Serving metrics on ", *metricsAddr)
+			log.Fatal("ListenAndServe (metrics): ", http.ListenAndServe(*metricsAddr, mm))
+		}()
+	}
+
+	var traceExporter *tracing.OTLPExporter
+	stopTracing := make(chan struct{})
+	if *otlpEndpoint != "" {
+		traceExporter = &tracing.OTLPExporter{URL: *otlpEndpoint, ServiceName: *traceServiceName, Headers: http.Header{}}
+		for _, kv := range strings.Split(*otlpHeaders, ",") {
+			if kv := strings.SplitN(kv, "=", 2); len(kv) == 2 {
+				traceExporter.Headers.Set(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+			}
+		}
+		tracing.Exporter = traceExporter
+		tracing.SampleRatio = *traceSampleRatio
+		go traceExporter.Run(5*time.Second, stopTracing)
+	}
+
+	var grpcSrv *grpc.Server
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatal("Listen (gRPC): ", err)
+		}
+		grpcSrv = grpc.NewServer()
+		rpc.RegisterPostsServer(grpcSrv, &rpc.Server{Client: apiclient})
+		go func() {
+			log.Print("Serving gRPC on ", *grpcAddr)
+			if err := grpcSrv.Serve(lis); err != nil {
+				log.Fatal("Serve (gRPC): ", err)
+			}
+		}()
+	}
+
+	var h http.Handler = m
+	if *compressResponses {
+		h = compress.Handler(h)
+	}
+	srv := &http.Server{Addr: *httpAddr, Handler: logging.Handler(logging.Default, tracing.Handler(h))}
+
+	redirectHandler := redirectToHTTPS(*httpAddr)
+	switch {
+	case *autocertDomains != "" && *tlsCert != "":
+		log.Fatal(`Only one of -autocert-domain and -tls-cert may be set. See "thesrc serve -h" for usage.`)
+	case *autocertDomains != "":
+		mgr := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(splitList(*autocertDomains)...),
+			Cache:      autocert.DirCache(*autocertDir),
+			Email:      *autocertEmail,
+		}
+		srv.TLSConfig = mgr.TLSConfig()
+		redirectHandler = mgr.HTTPHandler(redirectHandler)
+	case *tlsCert != "" || *tlsKey != "":
+		if *tlsCert == "" || *tlsKey == "" {
+			log.Fatal(`Both -tls-cert and -tls-key must be set. See "thesrc serve -h" for usage.`)
+		}
+		cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+		if err != nil {
+			log.Fatal("Loading TLS certificate: ", err)
+		}
+		srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	var redirectSrv *http.Server
+	if *redirectHTTPAddr != "" {
+		if srv.TLSConfig == nil {
+			log.Fatal(`-redirect-http-addr requires -tls-cert or -autocert-domain. See "thesrc serve -h" for usage.`)
+		}
+		redirectSrv = &http.Server{Addr: *redirectHTTPAddr, Handler: redirectHandler}
+		go func() {
+			log.Print("Redirecting HTTP to HTTPS on ", *redirectHTTPAddr)
+			if err := redirectSrv.ListenAndServe(); err != http.ErrServerClosed {
+				log.Fatal("ListenAndServe (HTTP redirect): ", err)
+			}
+		}()
+	}
+
+	// Stop accepting new connections on SIGINT or SIGTERM, and give in-flight
+	// requests up to drainTimeout to finish.
+	done := make(chan struct{})
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		log.Printf("Received %s; shutting down (waiting up to %s for in-flight requests)...", <-sig, *drainTimeout)
+
+		ctx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Print("Shutdown: ", err)
+		}
+		if redirectSrv != nil {
+			redirectSrv.Shutdown(ctx)
+		}
+		if grpcSrv != nil {
+			grpcSrv.GracefulStop()
+		}
+		close(stopCron)
+		<-cronDone
+		close(stopTemplateWatcher)
+		close(stopTracing)
+		close(stopJobWorkers)
+		<-jobWorkersDone
+		close(done)
+	}()
+
+	var err error
+	if srv.TLSConfig != nil {
+		log.Print("Listening for HTTPS on ", *httpAddr)
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		log.Print("Listening on ", *httpAddr)
+		err = srv.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
 		log.Fatal("ListenAndServe:", err)
 	}
+	<-done
+
+	if traceExporter != nil {
+		if err := traceExporter.Flush(); err != nil {
+			log.Print("Exporting traces: ", err)
+		}
+	}
+	if err := datastore.Close(); err != nil {
+		log.Fatal("Closing datastore: ", err)
+	}
+	log.Print("Shut down.")
 }
 
-func createDBCmd(args []string) {
-	fs := flag.NewFlagSet("createdb", flag.ExitOnError)
-	drop := fs.Bool("drop", false, "drop DB before creating")
+// Kinds of the periodic jobs that "thesrc worker" queues.
+const (
+	thumbnailsJobKind = "thumbnails"
+	checkLinksJobKind = "check-links"
+	pruneJobsJobKind  = "prune-jobs"
+)
+
+func workerCmd(args []string) {
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	concurrency := fs.Int("concurrency", 4, "number of jobs to run at the same time")
+	pollInterval := fs.Duration("poll-interval", jobs.DefaultPollInterval, "how long to wait before checking for jobs again when none are ready to run")
+	backoff := fs.Duration("backoff", jobs.DefaultBackoff, "how long to wait before retrying a failed job (doubled after each failed attempt)")
+	keepDone := fs.Duration("keep-done", 7*24*time.Hour, "how long to keep finished jobs before deleting them")
+	thumbnails := fs.Bool("thumbnails", false, "periodically generate thumbnails of posts' linked pages")
+	thumbnailInterval := fs.Duration("thumbnail-interval", time.Minute, "how often to check for posts that need thumbnails")
+	thumbnailDir := fs.String("thumbnail-dir", "thumbnails", "directory to store thumbnails in (which \"thesrc serve\" must serve from its -thumbnail-dir), if -thumbnail-s3-bucket is not set")
+	thumbnailS3Bucket := fs.String("thumbnail-s3-bucket", "", "if set, store thumbnails in this S3 bucket (using the credentials in $AWS_ACCESS_KEY_ID and $AWS_SECRET_ACCESS_KEY)")
+	thumbnailS3Region := fs.String("thumbnail-s3-region", "us-east-1", "region of the -thumbnail-s3-bucket")
+	thumbnailS3URL := fs.String("thumbnail-s3-url", "", "public URL prefix of thumbnails in S3, such as a CDN (defaults to the bucket's URL)")
+	screenshotCmd := fs.String("screenshot-cmd", "", "command to screenshot pages with no og:image (see \"thesrc serve -h\")")
+	checkLinks := fs.Bool("check-links", false, "periodically check posts' links, and mark posts whose links are dead")
+	checkLinksInterval := fs.Duration("check-links-interval", time.Minute, "how often to check for posts whose links are due to be checked")
+	checkLinksMaxAge := fs.Duration("check-links-max-age", linkcheck.DefaultMaxAge, "how long after a post's link is checked that it is checked again")
+	metricsAddr := fs.String("metrics-addr", "", "if set, serve Prometheus metrics at /metrics on this address (e.g., :5001)")
 	fs.Usage = func() {
-		fmt.Fprintln(os.Stderr, `usage: thesrc createdb [options] 
+		fmt.Fprintln(os.Stderr, `usage: thesrc worker [options]
+
+Runs background jobs from the job queue in the database: the webhook
+deliveries and link unfurling queued by "thesrc serve -jobs", and
+(with -thumbnails and -check-links) periodic thumbnail generation and
+link checks. Failed jobs are retried with exponential backoff, and
+jobs that fail on every attempt are listed for admins at /admin/jobs.
 
-Creates the necessary DB tables and indexes.
+Any number of workers may run at once; each job is run by only one of
+them at a time.
 
 The options are:
 `)
 		fs.PrintDefaults()
 		os.Exit(1)
 	}
-	fs.Parse(args)
+	parseFlags(fs, args)
 
 	if fs.NArg() != 0 {
 		fs.Usage()
 	}
 
 	datastore.Connect()
-	if *drop {
-		datastore.Drop()
+	store := datastore.NewDatastore(nil)
+
+	w := newJobWorker(store, *concurrency)
+	w.PollInterval = *pollInterval
+	w.Backoff = *backoff
+
+	stop := make(chan struct{})
+	w.Handlers[pruneJobsJobKind] = func(*thesrc.Job) error {
+		return store.Jobs.DeleteDone(time.Now().Add(-*keepDone))
+	}
+	go jobs.Periodic(store.Jobs, pruneJobsJobKind, time.Hour, stop)
+	if *thumbnails {
+		thumbnail.ScreenshotCommand = strings.Fields(*screenshotCmd)
+		tw := &thumbnail.Worker{Store: store.Thumbnails, Storage: thumbnailStorage(*thumbnailDir, *thumbnailS3Bucket, *thumbnailS3Region, *thumbnailS3URL)}
+		w.Handlers[thumbnailsJobKind] = func(*thesrc.Job) error {
+			_, err := tw.RunOnce()
+			return err
+		}
+		go jobs.Periodic(store.Jobs, thumbnailsJobKind, *thumbnailInterval, stop)
+	}
+	if *checkLinks {
+		c := &linkcheck.Checker{Store: store.LinkChecks, MaxAge: *checkLinksMaxAge}
+		w.Handlers[checkLinksJobKind] = func(*thesrc.Job) error {
+			_, _, err := c.RunOnce()
+			return err
+		}
+		go jobs.Periodic(store.Jobs, checkLinksJobKind, *checkLinksInterval, stop)
+	}
+
+	if *metricsAddr != "" {
+		mm := http.NewServeMux()
+		mm.Handle("/metrics", metrics.Handler())
+		go func() {
+			log.Print("Serving metrics on ", *metricsAddr)
+			log.Fatal("ListenAndServe (metrics): ", http.ListenAndServe(*metricsAddr, mm))
+		}()
+	}
+
+	// Stop claiming jobs on SIGINT or SIGTERM, and let running jobs finish.
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		log.Printf("Received %s; waiting for running jobs to finish...", <-sig)
+		close(stop)
+	}()
+
+	log.Printf("Running jobs (concurrency %d)", *concurrency)
+	w.Run(stop)
+
+	if err := datastore.Close(); err != nil {
+		log.Fatal("Closing datastore: ", err)
+	}
+	log.Print("Shut down.")
+}
+
+// applySchedules parses schedules (as given to "thesrc serve -schedule")
+// and sets the schedules of the named tasks.
+func applySchedules(tasks []*cron.Task, schedules string) error {
+	for _, pair := range strings.Split(schedules, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("%q is not task=schedule", pair)
+		}
+		name := strings.TrimSpace(kv[0])
+		var task *cron.Task
+		for _, t := range tasks {
+			if t.Name == name {
+				task = t
+			}
+		}
+		if task == nil {
+			return fmt.Errorf("no task named %q (is it enabled?)", name)
+		}
+		schedule, err := cron.Parse(kv[1])
+		if err != nil {
+			return err
+		}
+		task.Schedule = schedule
+	}
+	return nil
+}
+
+// newJobWorker returns a job queue worker that runs the jobs queued by the
+// server (webhook deliveries and link unfurling).
+func newJobWorker(store *datastore.Datastore, concurrency int) *jobs.Worker {
+	dispatcher := &webhooks.Dispatcher{Store: store.Webhooks}
+	return &jobs.Worker{
+		Store: store.Jobs,
+		Handlers: map[string]jobs.Handler{
+			webhooks.JobKind: dispatcher.RunJob,
+			jobs.UnfurlKind:  jobs.Unfurl(store.LinkChecks),
+		},
+		Concurrency: concurrency,
+	}
+}
+
+// thumbnailStorage returns the storage for thumbnails: the S3 bucket (if
+// set), or else dir (served at /thumbnails/).
+func thumbnailStorage(dir, s3Bucket, s3Region, s3URL string) thumbnail.Storage {
+	if s3Bucket != "" {
+		return &thumbnail.S3Storage{
+			Bucket:          s3Bucket,
+			Region:          s3Region,
+			Prefix:          "thumbnails/",
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			BaseURL:         s3URL,
+		}
+	}
+	return &thumbnail.DirStorage{Dir: dir, BaseURL: "/thumbnails/"}
+}
+
+// redirectToHTTPS returns a handler that redirects requests to the same URL
+// on the HTTPS server listening on httpsAddr.
+func redirectToHTTPS(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		u := *r.URL
+		u.Scheme = "https"
+		u.Host = host
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+	})
+}
+
+// checkMigrations returns an error if the database has pending migrations.
+func checkMigrations() error {
+	pending, err := datastore.PendingMigrations()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending migrations (run \"thesrc migrate up\")", len(pending))
+	}
+	return nil
+}
+
+func migrateCmd(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc migrate [options] up|down|status
+
+Migrates the database schema.
+
+The commands are:
+
+    up       apply all pending migrations (creating the schema if needed)
+    down     revert the most recently applied migration
+    status   list migrations and whether each has been applied
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+	}
+
+	datastore.Connect()
+	switch fs.Arg(0) {
+	case "up":
+		applied, err := datastore.MigrateUp()
+		for _, m := range applied {
+			fmt.Printf("applied:  %3d  %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("# no pending migrations")
+		}
+
+	case "down":
+		m, err := datastore.MigrateDown()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if m == nil {
+			fmt.Println("# no applied migrations")
+			return
+		}
+		fmt.Printf("reverted: %3d  %s\n", m.Version, m.Name)
+
+	case "status":
+		statuses, err := datastore.MigrationStatuses()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, st := range statuses {
+			status := "pending"
+			if st.AppliedAt != nil {
+				status = "applied " + st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%3d  %-40s %s\n", st.Version, st.Name, status)
+		}
+
+	default:
+		fs.Usage()
+	}
+}
+
+func grantRoleCmd(args []string) {
+	fs := flag.NewFlagSet("grant-role", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `usage: thesrc grant-role [options] login role
+
+Sets the role of the user with the given login, directly in the database.
+Use it to make the first admin, who can then moderate and edit any post.
+The change is recorded in the audit log.
+
+The roles are: %s, %s, and %s.
+
+The options are:
+`, thesrc.RoleMember, thesrc.RoleModerator, thesrc.RoleAdmin)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	reason := fs.String("reason", "", "reason for the change, recorded in the audit log")
+	parseFlags(fs, args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+	}
+	login, role := fs.Arg(0), fs.Arg(1)
+	if !thesrc.ValidRole(role) {
+		log.Fatalf(`Invalid role %q. See "thesrc grant-role -h" for usage.`, role)
+	}
+
+	datastore.Connect()
+	store := datastore.NewDatastore(nil)
+	user, err := store.Users.GetByLogin(login)
+	if err != nil {
+		log.Fatalf("Getting user %q: %s", login, err)
+	}
+	if err := store.Users.SetRole(user.ID, role); err != nil {
+		log.Fatal(err)
+	}
+	entry := &thesrc.AuditEntry{
+		Action:  thesrc.AuditSetRole,
+		Target:  "user:" + user.Login,
+		Details: fmt.Sprintf("%s (was %s)", role, user.Role),
+		Reason:  *reason,
+	}
+	if err := store.AuditLog.Create(entry); err != nil {
+		log.Fatalf("Recording the change in the audit log: %s", err)
+	}
+	fmt.Printf("%s is now a %s (was %s)\n", user.Login, role, user.Role)
+}
+
+func readOnlyCmd(args []string) {
+	fs := flag.NewFlagSet("read-only", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc read-only [options] [on|off]
+
+Shows whether the server at -url is in read-only mode, in which it rejects
+requests that would change data, or (given on or off) turns read-only mode
+on or off. Turning it on or off requires an admin's API token (see -token).
+Read-only mode is not persisted: a restarted server starts in read-only mode
+only if it was started with "thesrc serve -read-only".
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	parseFlags(fs, args)
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+	}
+
+	c := userAPIClient()
+	switch fs.Arg(0) {
+	case "":
+		status, err := c.Site.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if status.ReadOnly {
+			fmt.Println("read-only: on")
+		} else {
+			fmt.Println("read-only: off")
+		}
+
+	case "on", "off":
+		status := &thesrc.SiteStatus{ReadOnly: fs.Arg(0) == "on"}
+		if err := c.Site.UpdateStatus(status); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("read-only:", fs.Arg(0))
+
+	default:
+		fs.Usage()
+	}
+}
+
+func exportCmd(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	output := fs.String("o", "", "write the dump to this file (default: stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc export [options]
+
+Exports all posts (including hidden and dead posts), directly from the
+database, as JSON Lines. Use "thesrc import-dump" to import the dump into
+another database.
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	parseFlags(fs, args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+	}
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w = f
+	}
+
+	datastore.Connect()
+	n, err := datastore.NewDatastore(nil).Export(w)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *output != "" {
+		if err := w.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	log.Printf("# exported %d records", n)
+}
+
+func importDumpCmd(args []string) {
+	fs := flag.NewFlagSet("import-dump", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc import-dump [options] [file]
+
+Imports posts from a dump written by "thesrc export" (or from stdin, if no file
+is given), directly into the database. Imported posts are given new IDs, and
+posts whose link URL was already submitted are skipped, so it is safe to import
+a dump more than once.
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	parseFlags(fs, args)
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+	}
+
+	r := os.Stdin
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	datastore.Connect()
+	n, err := datastore.NewDatastore(nil).Import(r)
+	if err != nil {
+		log.Fatalf("Importing dump (after creating %d posts): %s", n, err)
 	}
-	datastore.Create()
+	log.Printf("# imported %d new posts", n)
 }
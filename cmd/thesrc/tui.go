@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"golang.org/x/term"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/tui"
+)
+
+func tuiCmd(args []string) {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	sort := fs.String("sort", thesrc.SortTop, "order to list posts in: new, top, best, or trending")
+	tag := fs.String("tag", "", "only list posts with this tag")
+	domain := fs.String("domain", "", "only list posts whose links are on this domain")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc tui [options]
+
+Reads the site at -url in an interactive terminal UI. Move through the list
+of posts with j and k (or the arrow keys), open a post's link in your web
+browser with o, read its comments with c, and upvote it with u. Press ? for
+the other keys, and q to quit.
+
+Set -token (or THESRC_TOKEN) to vote and to list posts as yourself.
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	parseFlags(fs, args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+	}
+
+	in := int(os.Stdin.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		log.Fatal("thesrc tui must be run in a terminal.")
+	}
+
+	r := &tui.Reader{
+		Client: userAPIClient(),
+		Options: thesrc.PostListOptions{
+			Sort:   *sort,
+			Tag:    *tag,
+			Domain: *domain,
+		},
+		OpenURL: openBrowser,
+		PostURL: postURL,
+	}
+
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = r.Run(os.Stdin, os.Stdout, func() (int, int, error) { return term.GetSize(in) })
+	term.Restore(in, state)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// openBrowser opens url in the user's web browser, without waiting for it
+// to exit.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,224 @@
+package thesrc
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A Comment is a reply to a post (or to another comment on a post).
+type Comment struct {
+	// ID a unique identifier for this comment.
+	ID int `json:",omitempty"`
+
+	// PostID is the ID of the post that this comment is on.
+	PostID int
+
+	// ParentID is the ID of the comment that this comment is a reply to, or 0
+	// if this is a top-level comment on the post.
+	ParentID int `json:",omitempty"`
+
+	// Body of the comment, in Markdown.
+	Body string
+
+	// BodyHTML is Body rendered as sanitized HTML. It is only set in API
+	// responses to requests with the RenderBody query parameter.
+	BodyHTML string `db:"-" json:",omitempty"`
+
+	// SubmittedAt is when the comment was submitted.
+	SubmittedAt time.Time
+
+	// AuthorUserID is the user ID of this comment's author.
+	AuthorUserID int
+
+	// Score is the number of upvotes the comment has received.
+	Score int
+
+	// Voted is whether the authenticated user has upvoted this comment. It
+	// is only set in API responses.
+	Voted bool `db:"-" json:",omitempty"`
+
+	// Dead is whether this comment has been killed by a moderator (see
+	// BulkKill). Only moderators see the bodies of dead comments; others see
+	// them with an empty body, so that replies to them stay in place.
+	Dead bool `json:",omitempty"`
+}
+
+// CommentsService interacts with the comment-related endpoints in thesrc's
+// API. Like PostsService, it is implemented by both the API client and the
+// datastore.
+type CommentsService interface {
+	// Get a comment.
+	Get(id int) (*Comment, error)
+
+	// ListForPost lists all comments on a post, highest-scored first (and
+	// oldest first among comments with the same score).
+	ListForPost(postID int) ([]*Comment, error)
+
+	// List comments on all posts, newest first.
+	List(opt *CommentListOptions) ([]*Comment, error)
+
+	// Create a comment. If successful, comment.ID will be the new comment's
+	// ID.
+	Create(comment *Comment) error
+}
+
+type CommentListOptions struct {
+	// AuthorUserID filters the result set to only those comments by the
+	// user with this ID.
+	AuthorUserID int `url:",omitempty" json:",omitempty"`
+
+	// RenderBody is whether to set the BodyHTML field of each comment.
+	RenderBody bool `url:",omitempty" json:",omitempty"`
+
+	ListOptions
+}
+
+var (
+	ErrCommentNotFound = errors.New("comment not found")
+)
+
+type commentsService struct{ client *Client }
+
+func (s *commentsService) Get(id int) (*Comment, error) {
+	url, err := s.client.url(router.Comment, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var comment *Comment
+	_, err = s.client.Do(req, &comment)
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (s *commentsService) ListForPost(postID int) ([]*Comment, error) {
+	url, err := s.client.url(router.PostComments, map[string]string{"ID": strconv.Itoa(postID)}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []*Comment
+	_, err = s.client.Do(req, &comments)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func (s *commentsService) List(opt *CommentListOptions) ([]*Comment, error) {
+	url, err := s.client.url(router.Comments, nil, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []*Comment
+	_, err = s.client.Do(req, &comments)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func (s *commentsService) Create(comment *Comment) error {
+	url, err := s.client.url(router.CreateComment, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), comment)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, &comment)
+	return err
+}
+
+// A CommentThread is a comment and its replies, which are themselves
+// threaded.
+type CommentThread struct {
+	*Comment
+	Replies []*CommentThread
+}
+
+// ThreadComments arranges comments into threads according to their ParentID
+// fields, preserving the original order among siblings (so that threads
+// from ListForPost are ordered by score at each level). Comments whose parent
+// is not in comments are treated as top-level comments.
+func ThreadComments(comments []*Comment) []*CommentThread {
+	threads := make(map[int]*CommentThread, len(comments))
+	for _, c := range comments {
+		threads[c.ID] = &CommentThread{Comment: c}
+	}
+
+	var top []*CommentThread
+	for _, c := range comments {
+		t := threads[c.ID]
+		if parent, present := threads[c.ParentID]; present && c.ParentID != 0 && parent != t {
+			parent.Replies = append(parent.Replies, t)
+		} else {
+			top = append(top, t)
+		}
+	}
+	return top
+}
+
+type MockCommentsService struct {
+	Get_         func(id int) (*Comment, error)
+	ListForPost_ func(postID int) ([]*Comment, error)
+	List_        func(opt *CommentListOptions) ([]*Comment, error)
+	Create_      func(comment *Comment) error
+}
+
+var _ CommentsService = &MockCommentsService{}
+
+func (s *MockCommentsService) Get(id int) (*Comment, error) {
+	if s.Get_ == nil {
+		return nil, nil
+	}
+	return s.Get_(id)
+}
+
+func (s *MockCommentsService) ListForPost(postID int) ([]*Comment, error) {
+	if s.ListForPost_ == nil {
+		return nil, nil
+	}
+	return s.ListForPost_(postID)
+}
+
+func (s *MockCommentsService) List(opt *CommentListOptions) ([]*Comment, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
+
+func (s *MockCommentsService) Create(comment *Comment) error {
+	if s.Create_ == nil {
+		return nil
+	}
+	return s.Create_(comment)
+}
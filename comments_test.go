@@ -0,0 +1,156 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestCommentsService_Get(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Comment{ID: 1, PostID: 2}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Comment, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, want)
+	})
+
+	comment, err := client.Comments.Get(1)
+	if err != nil {
+		t.Errorf("Comments.Get returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.SubmittedAt)
+	if !reflect.DeepEqual(comment, want) {
+		t.Errorf("Comments.Get returned %+v, want %+v", comment, want)
+	}
+}
+
+func TestCommentsService_ListForPost(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*Comment{{ID: 1, PostID: 2}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.PostComments, map[string]string{"ID": "2"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, want)
+	})
+
+	comments, err := client.Comments.ListForPost(2)
+	if err != nil {
+		t.Errorf("Comments.ListForPost returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, c := range want {
+		normalizeTime(&c.SubmittedAt)
+	}
+	if !reflect.DeepEqual(comments, want) {
+		t.Errorf("Comments.ListForPost returned %+v, want %+v", comments, want)
+	}
+}
+
+func TestCommentsService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*Comment{{ID: 1, AuthorUserID: 2}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Comments, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"AuthorUserID": "2"})
+
+		writeJSON(w, want)
+	})
+
+	comments, err := client.Comments.List(&CommentListOptions{AuthorUserID: 2})
+	if err != nil {
+		t.Errorf("Comments.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, c := range want {
+		normalizeTime(&c.SubmittedAt)
+	}
+	if !reflect.DeepEqual(comments, want) {
+		t.Errorf("Comments.List returned %+v, want %+v", comments, want)
+	}
+}
+
+func TestCommentsService_Create(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Comment{ID: 1, PostID: 2, Body: "b"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.CreateComment, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"PostID":2,"Body":"b","SubmittedAt":"0001-01-01T00:00:00Z","AuthorUserID":0,"Score":0}`+"\n")
+
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, want)
+	})
+
+	comment := &Comment{PostID: 2, Body: "b"}
+	err := client.Comments.Create(comment)
+	if err != nil {
+		t.Errorf("Comments.Create returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.SubmittedAt)
+	if !reflect.DeepEqual(comment, want) {
+		t.Errorf("Comments.Create returned %+v, want %+v", comment, want)
+	}
+}
+
+func TestThreadComments(t *testing.T) {
+	c1 := &Comment{ID: 1}
+	c2 := &Comment{ID: 2, ParentID: 1}
+	c3 := &Comment{ID: 3}
+	c4 := &Comment{ID: 4, ParentID: 2}
+	c5 := &Comment{ID: 5, ParentID: 1}
+	orphan := &Comment{ID: 6, ParentID: 99}
+
+	threads := ThreadComments([]*Comment{c1, c2, c3, c4, c5, orphan})
+
+	want := []*CommentThread{
+		{Comment: c1, Replies: []*CommentThread{
+			{Comment: c2, Replies: []*CommentThread{{Comment: c4}}},
+			{Comment: c5},
+		}},
+		{Comment: c3},
+		{Comment: orphan},
+	}
+	if !reflect.DeepEqual(threads, want) {
+		t.Errorf("got threads %+v, want %+v", threads, want)
+	}
+}
@@ -0,0 +1,264 @@
+// Package compress compresses HTTP responses (such as HTML pages, JSON API
+// responses, and feeds) for clients that accept compressed responses.
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A Writer compresses the data written to it. *gzip.Writer is a Writer, as
+// are the writers of most other compression packages (such as
+// github.com/andybalholm/brotli).
+type Writer interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// An Encoding is a content coding (such as gzip) that responses may be
+// compressed with.
+type Encoding struct {
+	// Name is the coding's name in the Accept-Encoding and
+	// Content-Encoding headers.
+	Name string
+
+	// NewWriter returns a Writer that compresses data written to it and
+	// writes the compressed data to w.
+	NewWriter func(w io.Writer) Writer
+
+	pool sync.Pool
+}
+
+func (e *Encoding) getWriter(w io.Writer) Writer {
+	if zw, ok := e.pool.Get().(Writer); ok {
+		zw.Reset(w)
+		return zw
+	}
+	return e.NewWriter(w)
+}
+
+func (e *Encoding) putWriter(zw Writer) {
+	zw.Reset(nil)
+	e.pool.Put(zw)
+}
+
+// Gzip is the gzip content coding.
+var Gzip = &Encoding{
+	Name:      "gzip",
+	NewWriter: func(w io.Writer) Writer { return gzip.NewWriter(w) },
+}
+
+var (
+	// Encodings are the content codings that responses may be compressed
+	// with, in order of preference. To also compress responses with
+	// brotli, add an Encoding named "br" before Gzip.
+	Encodings = []*Encoding{Gzip}
+
+	// MinSize is the size (in bytes) below which responses aren't
+	// compressed, because compressing them would hardly save anything.
+	MinSize = 1024
+)
+
+// compressibleTypes are the media types of responses that are compressed.
+// Other types (such as images) are usually already compressed.
+var compressibleTypes = map[string]bool{
+	"application/atom+xml":   true,
+	"application/javascript": true,
+	"application/json":       true,
+	"application/rss+xml":    true,
+	"application/xml":        true,
+	"image/svg+xml":          true,
+	"text/css":               true,
+	"text/html":              true,
+	"text/javascript":        true,
+	"text/plain":             true,
+	"text/xml":               true,
+}
+
+// compressible reports whether responses with the Content-Type contentType
+// should be compressed.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && compressibleTypes[mediaType]
+}
+
+// Handler wraps h so that responses of compressible types (such as HTML,
+// JSON, and feeds) are compressed with the most preferred encoding in
+// Encodings that the client accepts. Responses to requests with Range
+// headers, responses that are already encoded, and responses smaller than
+// MinSize are not compressed. Compressible responses have a "Vary:
+// Accept-Encoding" header, so that caches keep their compressed and
+// uncompressed variants apart.
+func Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		cw := &responseWriter{ResponseWriter: w, encoding: negotiate(r.Header.Get("Accept-Encoding")), head: r.Method == "HEAD"}
+		defer cw.Close()
+		h.ServeHTTP(cw, r)
+	})
+}
+
+// negotiate returns the most preferred encoding in Encodings that is
+// acceptable according to the Accept-Encoding header value accept, or nil
+// if there is none.
+func negotiate(accept string) *Encoding {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[len("q="):], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[name] = q > 0
+	}
+	for _, e := range Encodings {
+		if ok, present := accepted[e.Name]; ok || (!present && accepted["*"]) {
+			return e
+		}
+	}
+	return nil
+}
+
+// responseWriter compresses a response if it is compressible and at least
+// MinSize bytes long. Until it has decided whether to compress, it buffers
+// the status code and body.
+type responseWriter struct {
+	http.ResponseWriter
+	encoding *Encoding // nil if the client accepts no encodings
+	head     bool      // whether the request is a HEAD request
+
+	status  int    // status code passed to WriteHeader (or 0)
+	buf     []byte // body written before deciding whether to compress
+	decided bool   // whether the headers have been written
+	zw      Writer // if non-nil, compresses the body
+	err     error  // error that writing the buffered body failed with
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if status >= 100 && status < 200 {
+		// Informational responses precede the real response.
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.decided || w.status != 0 {
+		return
+	}
+	w.status = status
+	// Responses without bodies are never compressed.
+	if status == http.StatusNoContent || status == http.StatusNotModified || status == http.StatusPartialContent {
+		w.decide(false)
+	}
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < MinSize {
+			return len(p), nil
+		}
+		w.decide(true)
+		return len(p), w.err
+	}
+	if w.zw != nil {
+		return w.zw.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// decide writes the headers, compressing the response if it is
+// compressible and (if large is true) big enough, and then writes the
+// buffered body.
+func (w *responseWriter) decide(large bool) {
+	w.decided = true
+	hdr := w.Header()
+	if hdr.Get("Content-Type") == "" && len(w.buf) > 0 {
+		hdr.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if compressible(hdr.Get("Content-Type")) && hdr.Get("Content-Encoding") == "" && hdr.Get("Content-Range") == "" {
+		hdr.Add("Vary", "Accept-Encoding")
+		if large && w.encoding != nil {
+			hdr.Set("Content-Encoding", w.encoding.Name)
+			hdr.Del("Content-Length")
+			if etag := hdr.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				hdr.Set("ETag", "W/"+etag)
+			}
+			if !w.head {
+				w.zw = w.encoding.getWriter(w.ResponseWriter)
+			}
+		}
+	}
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buf) > 0 {
+		buf := w.buf
+		w.buf = nil
+		if w.zw != nil {
+			_, w.err = w.zw.Write(buf)
+		} else {
+			_, w.err = w.ResponseWriter.Write(buf)
+		}
+	}
+}
+
+// Close writes any buffered body and finishes compressing the response.
+func (w *responseWriter) Close() error {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.zw == nil {
+		return w.err
+	}
+	err := w.zw.Close()
+	w.encoding.putWriter(w.zw)
+	w.zw = nil
+	return err
+}
+
+// Flush writes the response so far (compressing it if it is compressible,
+// regardless of its size), so that handlers can stream responses.
+func (w *responseWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.zw != nil {
+		w.zw.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets handlers take over the connection (for example, to upgrade it
+// to a WebSocket), if nothing has been written yet.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("compress: underlying ResponseWriter does not support hijacking")
+	}
+	if w.decided || w.status != 0 || len(w.buf) > 0 {
+		return nil, nil, errors.New("compress: cannot hijack after writing the response")
+	}
+	w.decided = true
+	return h.Hijack()
+}
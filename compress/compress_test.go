@@ -0,0 +1,123 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]*Encoding{
+		"":                    nil,
+		"gzip":                Gzip,
+		"deflate, gzip;q=1.0": Gzip,
+		"GZIP":                Gzip,
+		"gzip;q=0":            nil,
+		"*":                   Gzip,
+		"*, gzip;q=0":         nil,
+		"br":                  nil,
+		"identity":            nil,
+	}
+	for accept, want := range tests {
+		if got := negotiate(accept); got != want {
+			t.Errorf("%q: got %v, want %v", accept, got, want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	large := strings.Repeat("<p>hello</p>", 200)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		reqHeader   http.Header
+		wantGzip    bool
+		wantVary    bool
+	}{
+		{name: "large html", contentType: "text/html; charset=utf-8", body: large, wantGzip: true, wantVary: true},
+		{name: "large json with error status", contentType: "application/json", body: large, status: http.StatusNotFound, wantGzip: true, wantVary: true},
+		{name: "sniffed html", body: "<html>" + large, wantGzip: true, wantVary: true},
+		{name: "small html", contentType: "text/html", body: "<p>hi</p>", wantVary: true},
+		{name: "image", contentType: "image/png", body: large},
+		{name: "no accept-encoding", contentType: "text/html", body: large, reqHeader: http.Header{}, wantVary: true},
+		{name: "range request", contentType: "text/html", body: large, reqHeader: http.Header{"Accept-Encoding": {"gzip"}, "Range": {"bytes=0-1"}}},
+		{name: "no content", status: http.StatusNoContent},
+	}
+	for _, test := range tests {
+		h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if test.contentType != "" {
+				w.Header().Set("Content-Type", test.contentType)
+			}
+			if test.status != 0 {
+				w.WriteHeader(test.status)
+			}
+			// Write in small pieces to exercise buffering.
+			for i := 0; i < len(test.body); i += 100 {
+				end := i + 100
+				if end > len(test.body) {
+					end = len(test.body)
+				}
+				w.Write([]byte(test.body[i:end]))
+			}
+		}))
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header = test.reqHeader
+		if req.Header == nil {
+			req.Header = http.Header{"Accept-Encoding": {"gzip, deflate"}}
+		}
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, req)
+
+		wantStatus := test.status
+		if wantStatus == 0 {
+			wantStatus = http.StatusOK
+		}
+		if rw.Code != wantStatus {
+			t.Errorf("%s: got HTTP %d, want %d", test.name, rw.Code, wantStatus)
+		}
+		if gotVary := rw.Header().Get("Vary") == "Accept-Encoding"; gotVary != test.wantVary {
+			t.Errorf("%s: got Vary %q, want Accept-Encoding == %v", test.name, rw.Header().Get("Vary"), test.wantVary)
+		}
+
+		body := rw.Body.String()
+		if gotGzip := rw.Header().Get("Content-Encoding") == "gzip"; gotGzip != test.wantGzip {
+			t.Errorf("%s: got Content-Encoding %q, want gzip == %v", test.name, rw.Header().Get("Content-Encoding"), test.wantGzip)
+		} else if gotGzip {
+			zr, err := gzip.NewReader(rw.Body)
+			if err != nil {
+				t.Fatalf("%s: %s", test.name, err)
+			}
+			data, err := ioutil.ReadAll(zr)
+			if err != nil {
+				t.Fatalf("%s: %s", test.name, err)
+			}
+			body = string(data)
+		}
+		if body != test.body {
+			t.Errorf("%s: got body of length %d, want %d", test.name, len(body), len(test.body))
+		}
+	}
+}
+
+func TestHandler_flush(t *testing.T) {
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+	}))
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+
+	if !rw.Flushed || rw.Body.String() != "data: 1\n\n" || rw.Header().Get("Content-Encoding") != "" {
+		t.Errorf("got flushed %v, body %q, Content-Encoding %q; want uncompressed event stream to be flushed", rw.Flushed, rw.Body.String(), rw.Header().Get("Content-Encoding"))
+	}
+}
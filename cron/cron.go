@@ -0,0 +1,108 @@
+// Package cron runs periodic tasks (such as updating trending posts) on
+// cron-like schedules. When several processes run the same tasks, each
+// occurrence of a task is run by only one of them, using locks in the
+// datastore.
+package cron
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
+)
+
+var runs = metrics.NewCounterVec("thesrc_cron_runs_total",
+	"Scheduled task occurrences, by task and result (ok, error, or skipped because another process ran it).", "task", "result")
+
+// A Task is work that is run on a schedule.
+type Task struct {
+	// Name identifies the task (in locks, logs, and metrics).
+	Name string
+
+	Schedule Schedule
+
+	Run func() error
+
+	// Local is whether every process runs the task (such as one that
+	// regenerates a cache in memory), instead of only one process per
+	// occurrence.
+	Local bool
+}
+
+// A Scheduler runs tasks on their schedules.
+type Scheduler struct {
+	// Locks ensures that only one process runs each occurrence of a task
+	// (unless it is Local). A task's lock lasts until its next occurrence,
+	// so if a process stops while running a task, the task isn't run again
+	// until then.
+	Locks datastore.TaskLocksStore
+
+	Tasks []*Task
+
+	// Owner identifies this process in locks. If "", the host name and
+	// process ID are used.
+	Owner string
+}
+
+// Run runs the tasks on their schedules until stop is closed, and then waits
+// for the running tasks to finish.
+func (s *Scheduler) Run(stop <-chan struct{}) {
+	if s.Owner == "" {
+		host, _ := os.Hostname()
+		s.Owner = fmt.Sprintf("%s:%d", host, os.Getpid())
+	}
+
+	var wg sync.WaitGroup
+	for _, task := range s.Tasks {
+		wg.Add(1)
+		go func(task *Task) {
+			defer wg.Done()
+			for {
+				now := time.Now()
+				next := task.Schedule.Next(now)
+				if !next.After(now) {
+					logging.Default.Log("Scheduled task never runs", "task", task.Name)
+					return
+				}
+				select {
+				case <-time.After(time.Until(next)):
+					s.RunOccurrence(task, next)
+				case <-stop:
+					return
+				}
+			}
+		}(task)
+	}
+	wg.Wait()
+}
+
+// RunOccurrence runs the occurrence of task scheduled at at, unless it isn't
+// Local and another process has already locked it. It reports whether it ran
+// the task.
+func (s *Scheduler) RunOccurrence(task *Task, at time.Time) bool {
+	if !task.Local {
+		ok, err := s.Locks.Acquire(task.Name, s.Owner, time.Now(), task.Schedule.Next(at))
+		if err != nil {
+			logging.Default.Log("Locking scheduled task failed", "task", task.Name, "error", err)
+			runs.Inc(task.Name, "error")
+			return false
+		}
+		if !ok {
+			runs.Inc(task.Name, "skipped")
+			return false
+		}
+	}
+
+	start := time.Now()
+	if err := task.Run(); err != nil {
+		logging.Default.Log("Scheduled task failed", "task", task.Name, "error", err, "duration_ms", logging.Milliseconds(time.Since(start)))
+		runs.Inc(task.Name, "error")
+	} else {
+		runs.Inc(task.Name, "ok")
+	}
+	return true
+}
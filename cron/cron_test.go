@@ -0,0 +1,68 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestScheduler_RunOccurrence(t *testing.T) {
+	locks := datastore.NewMemoryDatastore().TaskLocks
+	s1 := &Scheduler{Locks: locks, Owner: "p1"}
+	s2 := &Scheduler{Locks: locks, Owner: "p2"}
+
+	var runs int
+	task := &Task{Name: "t", Schedule: Every(time.Hour), Run: func() error { runs++; return nil }}
+	at := time.Now().Truncate(time.Hour)
+	if !s1.RunOccurrence(task, at) {
+		t.Error("p1 didn't run the task")
+	}
+	if s2.RunOccurrence(task, at) {
+		t.Error("p2 ran the task's occurrence that p1 already ran")
+	}
+	if runs != 1 {
+		t.Errorf("got %d runs, want 1", runs)
+	}
+
+	// Local tasks run in every process.
+	task.Local = true
+	if !s2.RunOccurrence(task, at) {
+		t.Error("p2 didn't run the local task")
+	}
+
+	// Failed tasks count as run.
+	failing := &Task{Name: "f", Schedule: Every(time.Hour), Run: func() error { return errors.New("x") }}
+	if !s1.RunOccurrence(failing, at) {
+		t.Error("p1 didn't run the failing task")
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	ran := make(chan struct{}, 1)
+	s := &Scheduler{
+		Locks: datastore.NewMemoryDatastore().TaskLocks,
+		Tasks: []*Task{{Name: "t", Schedule: Every(10 * time.Millisecond), Run: func() error {
+			select {
+			case ran <- struct{}{}:
+			default:
+			}
+			return nil
+		}}},
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		s.Run(stop)
+		close(done)
+	}()
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the task to run")
+	}
+	close(stop)
+	<-done
+}
@@ -0,0 +1,46 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	base := time.Date(2015, time.January, 30, 10, 17, 30, 0, time.UTC) // a Friday
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"@every 5m", time.Date(2015, time.January, 30, 10, 20, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2015, time.January, 30, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2015, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2015, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2015, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"* * * * *", time.Date(2015, time.January, 30, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2015, time.January, 30, 10, 30, 0, 0, time.UTC)},
+		{"5,10 9-11 * * *", time.Date(2015, time.January, 30, 11, 5, 0, 0, time.UTC)},
+		{"30 4 * * 1-5", time.Date(2015, time.February, 2, 4, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2015, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2016, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 5", time.Date(2015, time.February, 1, 0, 0, 0, 0, time.UTC)}, // day of month or week
+		{"0 0 31 2 *", time.Time{}}, // never
+	}
+	for _, test := range tests {
+		s, err := Parse(test.spec)
+		if err != nil {
+			t.Errorf("%q: %s", test.spec, err)
+			continue
+		}
+		if got := s.Next(base); !got.Equal(test.want) {
+			t.Errorf("%q: got next %s, want %s", test.spec, got, test.want)
+		}
+	}
+}
+
+func TestParse_invalid(t *testing.T) {
+	for _, spec := range []string{"", "@every", "@every 1ms", "@yearly", "* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("%q: got no error", spec)
+		}
+	}
+}
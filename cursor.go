@@ -0,0 +1,115 @@
+package thesrc
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A PostCursor is a position in a list of posts: the sort keys of the last
+// post on a page. Listing posts after a cursor (see PostListOptions.After)
+// returns the next page, even if posts were submitted since the previous
+// page was fetched, and without the database skipping over the earlier
+// pages.
+//
+// Only lists sorted by SortNew or SortBest can be paginated with cursors.
+// SortTop and SortTrending ranks change continuously as posts age and
+// velocities are updated, so lists sorted by them must be paginated by page
+// number.
+type PostCursor struct {
+	Sort        string    // the list's sort order (SortNew or SortBest)
+	Score       int       // the post's score (for SortBest)
+	SubmittedAt time.Time // when the post was submitted
+	ID          int       // the post's ID
+}
+
+var errInvalidPostCursor = errors.New("invalid cursor")
+
+// PostCursorAfter returns the cursor, for use in PostListOptions.After, of
+// the position just after post in a list sorted by sort (or SortNew, if
+// empty). It returns "" if lists sorted by sort can't be paginated with
+// cursors.
+func PostCursorAfter(sort string, post *Post) string {
+	c := &PostCursor{Sort: sort, Score: post.Score, SubmittedAt: post.SubmittedAt, ID: post.ID}
+	switch sort {
+	case "", SortNew:
+		c.Sort, c.Score = SortNew, 0
+	case SortBest:
+	default:
+		return ""
+	}
+	return c.String()
+}
+
+// String returns the cursor's opaque encoding.
+func (c *PostCursor) String() string {
+	s := c.Sort + ":" + strconv.FormatInt(c.SubmittedAt.UnixNano(), 10) + ":" + strconv.Itoa(c.ID)
+	if c.Sort == SortBest {
+		s += ":" + strconv.Itoa(c.Score)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// ParsePostCursor parses a cursor returned by PostCursorAfter.
+func ParsePostCursor(s string) (*PostCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidPostCursor
+	}
+	parts := strings.Split(string(b), ":")
+
+	var c PostCursor
+	switch c.Sort = parts[0]; {
+	case c.Sort == SortNew && len(parts) == 3:
+	case c.Sort == SortBest && len(parts) == 4:
+		if c.Score, err = strconv.Atoi(parts[3]); err != nil {
+			return nil, errInvalidPostCursor
+		}
+	default:
+		return nil, errInvalidPostCursor
+	}
+	nsec, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, errInvalidPostCursor
+	}
+	c.SubmittedAt = time.Unix(0, nsec).UTC()
+	if c.ID, err = strconv.Atoi(parts[2]); err != nil {
+		return nil, errInvalidPostCursor
+	}
+	return &c, nil
+}
+
+// After reports whether post comes after the cursor's position in the list
+// (and so belongs on the pages after it).
+func (c *PostCursor) After(post *Post) bool {
+	if c.Sort == SortBest && post.Score != c.Score {
+		return post.Score < c.Score
+	}
+	if !post.SubmittedAt.Equal(c.SubmittedAt) {
+		return post.SubmittedAt.Before(c.SubmittedAt)
+	}
+	return post.ID < c.ID
+}
+
+// Cursor parses o.After. It returns nil if o.After is empty, and an error if
+// o.After is invalid or isn't a cursor for a list sorted by o.Sort.
+func (o *PostListOptions) Cursor() (*PostCursor, error) {
+	if o.After == "" {
+		return nil, nil
+	}
+	c, err := ParsePostCursor(o.After)
+	if err != nil {
+		return nil, err
+	}
+	sort := o.Sort
+	if sort == "" {
+		sort = SortNew
+	}
+	if sort != c.Sort {
+		return nil, fmt.Errorf("cursor is for a list sorted by %s, not %s", c.Sort, sort)
+	}
+	return c, nil
+}
@@ -0,0 +1,63 @@
+package thesrc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPostCursor(t *testing.T) {
+	post := &Post{ID: 7, Score: 3, SubmittedAt: time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)}
+
+	tests := []struct {
+		sort string
+		want *PostCursor
+	}{
+		{"", &PostCursor{Sort: SortNew, SubmittedAt: post.SubmittedAt, ID: 7}},
+		{SortNew, &PostCursor{Sort: SortNew, SubmittedAt: post.SubmittedAt, ID: 7}},
+		{SortBest, &PostCursor{Sort: SortBest, Score: 3, SubmittedAt: post.SubmittedAt, ID: 7}},
+	}
+	for _, test := range tests {
+		s := PostCursorAfter(test.sort, post)
+		c, err := (&PostListOptions{Sort: test.sort, After: s}).Cursor()
+		if err != nil {
+			t.Errorf("%q: %s", test.sort, err)
+			continue
+		}
+		if !reflect.DeepEqual(c, test.want) {
+			t.Errorf("%q: got cursor %+v, want %+v", test.sort, c, test.want)
+		}
+	}
+
+	if s := PostCursorAfter(SortTop, post); s != "" {
+		t.Errorf("got cursor %q for top-ranked list, want none", s)
+	}
+	if _, err := (&PostListOptions{Sort: SortBest, After: PostCursorAfter(SortNew, post)}).Cursor(); err == nil {
+		t.Error("got no error using a SortNew cursor for a SortBest list")
+	}
+	for _, s := range []string{"x", "bmV3", "bmV3OjE6Mg:x", "top:1:2"} {
+		if _, err := ParsePostCursor(s); err == nil {
+			t.Errorf("%q: got no error parsing invalid cursor", s)
+		}
+	}
+}
+
+func TestPostCursor_After(t *testing.T) {
+	t0 := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	c := &PostCursor{Sort: SortBest, Score: 2, SubmittedAt: t0, ID: 5}
+	tests := []struct {
+		post *Post
+		want bool
+	}{
+		{&Post{Score: 1, SubmittedAt: t0.Add(time.Hour), ID: 9}, true},
+		{&Post{Score: 3, SubmittedAt: t0.Add(-time.Hour), ID: 1}, false},
+		{&Post{Score: 2, SubmittedAt: t0.Add(-time.Hour), ID: 9}, true},
+		{&Post{Score: 2, SubmittedAt: t0, ID: 4}, true},
+		{&Post{Score: 2, SubmittedAt: t0, ID: 5}, false},
+	}
+	for _, test := range tests {
+		if got := c.After(test.post); got != test.want {
+			t.Errorf("%+v: got After %v, want %v", test.post, got, test.want)
+		}
+	}
+}
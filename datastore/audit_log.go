@@ -0,0 +1,66 @@
+package datastore
+
+import (
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.AuditEntry{}, "audit_log").SetKeys(true, "ID")
+}
+
+// AuditLogStore accesses the audit log (see thesrc.AuditEntry) in the
+// datastore. It has no methods to change or delete entries, because the
+// audit log is append-only.
+type AuditLogStore interface {
+	// Create an audit log entry. If successful, entry.ID will be the new
+	// entry's ID.
+	Create(entry *thesrc.AuditEntry) error
+
+	// List audit log entries, newest first.
+	List(opt *thesrc.AuditLogListOptions) ([]*thesrc.AuditEntry, error)
+}
+
+type auditLogStore struct{ *Datastore }
+
+func (s *auditLogStore) Create(entry *thesrc.AuditEntry) error {
+	defer s.observe(time.Now(), "AuditLog.Create")
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	return s.dbh.Insert(entry)
+}
+
+func (s *auditLogStore) List(opt *thesrc.AuditLogListOptions) ([]*thesrc.AuditEntry, error) {
+	defer s.observe(time.Now(), "AuditLog.List")
+	if opt == nil {
+		opt = &thesrc.AuditLogListOptions{}
+	}
+	var entries []*thesrc.AuditEntry
+	if err := s.dbh.Select(&entries, `SELECT * FROM audit_log WHERE ($1 = 0 OR actoruserid=$1) AND ($2 = '' OR action=$2) AND ($3 = '' OR target=$3) ORDER BY createdat DESC, id DESC LIMIT $4 OFFSET $5;`, opt.ActorUserID, opt.Action, opt.Target, opt.PerPageOrDefault(), opt.Offset()); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+type MockAuditLogStore struct {
+	Create_ func(entry *thesrc.AuditEntry) error
+	List_   func(opt *thesrc.AuditLogListOptions) ([]*thesrc.AuditEntry, error)
+}
+
+var _ AuditLogStore = &MockAuditLogStore{}
+
+func (s *MockAuditLogStore) Create(entry *thesrc.AuditEntry) error {
+	if s.Create_ == nil {
+		return nil
+	}
+	return s.Create_(entry)
+}
+
+func (s *MockAuditLogStore) List(opt *thesrc.AuditLogListOptions) ([]*thesrc.AuditEntry, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
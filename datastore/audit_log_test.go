@@ -0,0 +1,60 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestAuditLogStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM audit_log;`) // test on a clean DB
+
+	testAuditLogStore(t, NewDatastore(tx).AuditLog)
+}
+
+// testAuditLogStore tests s. s must be empty.
+func testAuditLogStore(t *testing.T, s AuditLogStore) {
+	now := time.Now().Truncate(time.Second)
+	entries := []*thesrc.AuditEntry{
+		{ActorUserID: 1, Action: thesrc.AuditKillPost, Target: "post:10", Reason: "spam", CreatedAt: now.Add(-time.Hour)},
+		{ActorUserID: 2, Action: thesrc.AuditShadowBan, Target: "user:carol", CreatedAt: now.Add(-time.Minute)},
+		{Action: thesrc.AuditSetRole, Target: "user:carol", Details: "admin (was member)", CreatedAt: now},
+	}
+	for _, e := range entries {
+		if err := s.Create(e); err != nil {
+			t.Fatal(err)
+		}
+		if e.ID == 0 {
+			t.Error("got ID == 0, want non-zero")
+		}
+	}
+
+	tests := []struct {
+		opt  *thesrc.AuditLogListOptions
+		want []*thesrc.AuditEntry
+	}{
+		{nil, []*thesrc.AuditEntry{entries[2], entries[1], entries[0]}},
+		{&thesrc.AuditLogListOptions{ActorUserID: 1}, []*thesrc.AuditEntry{entries[0]}},
+		{&thesrc.AuditLogListOptions{Action: thesrc.AuditShadowBan}, []*thesrc.AuditEntry{entries[1]}},
+		{&thesrc.AuditLogListOptions{Target: "user:carol"}, []*thesrc.AuditEntry{entries[2], entries[1]}},
+		{&thesrc.AuditLogListOptions{ListOptions: thesrc.ListOptions{PerPage: 1, Page: 3}}, []*thesrc.AuditEntry{entries[0]}},
+	}
+	for _, test := range tests {
+		got, err := s.List(test.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%+v: got %d entries, want %d", test.opt, len(got), len(test.want))
+			continue
+		}
+		for i, e := range got {
+			if e.ID != test.want[i].ID || e.Reason != test.want[i].Reason || e.Details != test.want[i].Details {
+				t.Errorf("%+v: got entry %d %+v, want %+v", test.opt, i, e, test.want[i])
+			}
+		}
+	}
+}
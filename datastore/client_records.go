@@ -0,0 +1,149 @@
+package datastore
+
+import (
+	"crypto/rand"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.ClientRecord{}, "client_record").SetKeys(true, "ID")
+}
+
+// clientSalt is the salt with which clients' IP addresses and User-Agents
+// are hashed in the period that starts at Period (in Unix seconds).
+type clientSalt struct {
+	Period int64
+	Salt   []byte
+}
+
+// ClientRecordsStore accesses client records (see thesrc.ClientRecord) and
+// the salts that their hashes are made with in the datastore.
+type ClientRecordsStore interface {
+	// Create a client record. If successful, record.ID will be the new
+	// record's ID.
+	Create(record *thesrc.ClientRecord) error
+
+	// List client records, newest first.
+	List(opt *thesrc.ClientRecordListOptions) ([]*thesrc.ClientRecord, error)
+
+	// DeleteBefore deletes the records created before before.
+	DeleteBefore(before time.Time) error
+
+	// Salt returns the salt for hashing clients' IP addresses and
+	// User-Agents in the period that starts at start, creating a random
+	// salt if the period has none yet.
+	Salt(start time.Time) ([]byte, error)
+
+	// DeleteSaltsBefore deletes the salts of the periods that started
+	// before before, so that the hashes made with them can't be matched
+	// against IP addresses anymore.
+	DeleteSaltsBefore(before time.Time) error
+}
+
+type clientRecordsStore struct{ *Datastore }
+
+func (s *clientRecordsStore) Create(record *thesrc.ClientRecord) error {
+	defer s.observe(time.Now(), "ClientRecords.Create")
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	return s.dbh.Insert(record)
+}
+
+func (s *clientRecordsStore) List(opt *thesrc.ClientRecordListOptions) ([]*thesrc.ClientRecord, error) {
+	defer s.observe(time.Now(), "ClientRecords.List")
+	if opt == nil {
+		opt = &thesrc.ClientRecordListOptions{}
+	}
+	var records []*thesrc.ClientRecord
+	if err := s.dbh.Select(&records, `SELECT * FROM client_record WHERE ($1 = 0 OR userid=$1) AND ($2 = 0 OR postid=$2) AND ($3 = '' OR iphash=$3) ORDER BY createdat DESC, id DESC LIMIT $4 OFFSET $5;`, opt.UserID, opt.PostID, opt.IPHash, opt.PerPageOrDefault(), opt.Offset()); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (s *clientRecordsStore) DeleteBefore(before time.Time) error {
+	defer s.observe(time.Now(), "ClientRecords.DeleteBefore")
+	_, err := s.dbh.Exec(`DELETE FROM client_record WHERE createdat<$1;`, before)
+	return err
+}
+
+func (s *clientRecordsStore) Salt(start time.Time) ([]byte, error) {
+	defer s.observe(time.Now(), "ClientRecords.Salt")
+	get := func() ([]byte, error) {
+		var salts []*clientSalt
+		if err := s.dbh.Select(&salts, `SELECT * FROM client_salt WHERE period=$1;`, start.Unix()); err != nil || len(salts) == 0 {
+			return nil, err
+		}
+		return salts[0].Salt, nil
+	}
+	if salt, err := get(); err != nil || salt != nil {
+		return salt, err
+	}
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := s.dbh.Exec(`INSERT INTO client_salt(period, salt) VALUES($1, $2);`, start.Unix(), salt); err != nil {
+		// Another process created the period's salt first.
+		if isUniqueViolation(err, "client_salt_pkey", "client_salt.period") {
+			return get()
+		}
+		return nil, err
+	}
+	return salt, nil
+}
+
+func (s *clientRecordsStore) DeleteSaltsBefore(before time.Time) error {
+	defer s.observe(time.Now(), "ClientRecords.DeleteSaltsBefore")
+	_, err := s.dbh.Exec(`DELETE FROM client_salt WHERE period<$1;`, before.Unix())
+	return err
+}
+
+type MockClientRecordsStore struct {
+	Create_            func(record *thesrc.ClientRecord) error
+	List_              func(opt *thesrc.ClientRecordListOptions) ([]*thesrc.ClientRecord, error)
+	DeleteBefore_      func(before time.Time) error
+	Salt_              func(start time.Time) ([]byte, error)
+	DeleteSaltsBefore_ func(before time.Time) error
+}
+
+var _ ClientRecordsStore = &MockClientRecordsStore{}
+
+func (s *MockClientRecordsStore) Create(record *thesrc.ClientRecord) error {
+	if s.Create_ == nil {
+		return nil
+	}
+	return s.Create_(record)
+}
+
+func (s *MockClientRecordsStore) List(opt *thesrc.ClientRecordListOptions) ([]*thesrc.ClientRecord, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
+
+func (s *MockClientRecordsStore) DeleteBefore(before time.Time) error {
+	if s.DeleteBefore_ == nil {
+		return nil
+	}
+	return s.DeleteBefore_(before)
+}
+
+func (s *MockClientRecordsStore) Salt(start time.Time) ([]byte, error) {
+	if s.Salt_ == nil {
+		return nil, nil
+	}
+	return s.Salt_(start)
+}
+
+func (s *MockClientRecordsStore) DeleteSaltsBefore(before time.Time) error {
+	if s.DeleteSaltsBefore_ == nil {
+		return nil
+	}
+	return s.DeleteSaltsBefore_(before)
+}
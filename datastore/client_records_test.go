@@ -0,0 +1,91 @@
+package datastore
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestClientRecordsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM client_record;`) // test on a clean DB
+	tx.Exec(`DELETE FROM client_salt;`)
+
+	testClientRecordsStore(t, NewDatastore(tx).ClientRecords)
+}
+
+// testClientRecordsStore tests s. s must be empty.
+func testClientRecordsStore(t *testing.T, s ClientRecordsStore) {
+	now := time.Now().Truncate(time.Second)
+	records := []*thesrc.ClientRecord{
+		{Action: thesrc.ClientActionSubmit, UserID: 1, PostID: 10, IPHash: "x", UserAgentHash: "u", CreatedAt: now.Add(-48 * time.Hour)},
+		{Action: thesrc.ClientActionVote, UserID: 2, PostID: 10, IPHash: "x", UserAgentHash: "v", CreatedAt: now.Add(-time.Hour)},
+		{Action: thesrc.ClientActionVote, UserID: 2, PostID: 20, IPHash: "y", UserAgentHash: "v", CreatedAt: now},
+	}
+	for _, r := range records {
+		if err := s.Create(r); err != nil {
+			t.Fatal(err)
+		}
+		if r.ID == 0 {
+			t.Error("got ID == 0, want non-zero")
+		}
+	}
+
+	tests := []struct {
+		opt  *thesrc.ClientRecordListOptions
+		want []*thesrc.ClientRecord
+	}{
+		{nil, []*thesrc.ClientRecord{records[2], records[1], records[0]}},
+		{&thesrc.ClientRecordListOptions{UserID: 2}, []*thesrc.ClientRecord{records[2], records[1]}},
+		{&thesrc.ClientRecordListOptions{PostID: 10}, []*thesrc.ClientRecord{records[1], records[0]}},
+		{&thesrc.ClientRecordListOptions{IPHash: "x", UserID: 1}, []*thesrc.ClientRecord{records[0]}},
+		{&thesrc.ClientRecordListOptions{ListOptions: thesrc.ListOptions{PerPage: 1, Page: 2}}, []*thesrc.ClientRecord{records[1]}},
+	}
+	for _, test := range tests {
+		got, err := s.List(test.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%+v: got %d records, want %d", test.opt, len(got), len(test.want))
+			continue
+		}
+		for i, r := range got {
+			if r.ID != test.want[i].ID {
+				t.Errorf("%+v: got record %d ID %d, want %d", test.opt, i, r.ID, test.want[i].ID)
+			}
+		}
+	}
+
+	if err := s.DeleteBefore(now.Add(-24 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.List(nil); len(got) != 2 {
+		t.Errorf("got %d records after deleting old records, want 2", len(got))
+	}
+
+	// Each period gets its own salt, which is kept until it's deleted.
+	period := now.Truncate(time.Hour)
+	salt, err := s.Salt(period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(salt) == 0 {
+		t.Fatal("got empty salt")
+	}
+	if salt2, _ := s.Salt(period); !bytes.Equal(salt2, salt) {
+		t.Errorf("got salt %x the second time, want %x", salt2, salt)
+	}
+	if next, _ := s.Salt(period.Add(time.Hour)); bytes.Equal(next, salt) {
+		t.Error("got the same salt for the next period")
+	}
+	if err := s.DeleteSaltsBefore(period.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if salt2, _ := s.Salt(period); bytes.Equal(salt2, salt) {
+		t.Error("got the same salt after deleting it")
+	}
+}
@@ -0,0 +1,87 @@
+package datastore
+
+import (
+	"errors"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.Comment{}, "comment").SetKeys(true, "ID")
+}
+
+var errParentOnOtherPost = errors.New("parent comment is on a different post")
+
+type commentsStore struct{ *Datastore }
+
+var _ thesrc.CommentsService = &commentsStore{}
+
+// listCommentsForPostQuery is prepared because it runs on every post page.
+// Deleted posts have no comments (until they're undeleted).
+var listCommentsForPostQuery = prepared(`SELECT * FROM comment WHERE postid=$1 AND postid NOT IN (SELECT id FROM post WHERE deletedat IS NOT NULL) ORDER BY score DESC, submittedat ASC, id ASC;`)
+
+func (s *commentsStore) Get(id int) (*thesrc.Comment, error) {
+	defer s.observe(time.Now(), "Comments.Get")
+	var comments []*thesrc.Comment
+	if err := s.dbh.Select(&comments, `SELECT * FROM comment WHERE id=$1;`, id); err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 {
+		return nil, thesrc.ErrCommentNotFound
+	}
+	return comments[0], nil
+}
+
+func (s *commentsStore) ListForPost(postID int) ([]*thesrc.Comment, error) {
+	defer s.observe(time.Now(), "Comments.ListForPost")
+	var comments []*thesrc.Comment
+	err := listCommentsForPostQuery.Select(s.dbh, &comments, postID)
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (s *commentsStore) List(opt *thesrc.CommentListOptions) ([]*thesrc.Comment, error) {
+	defer s.observe(time.Now(), "Comments.List")
+	if opt == nil {
+		opt = &thesrc.CommentListOptions{}
+	}
+
+	// Omit comments on deleted posts.
+	sql := `SELECT * FROM comment WHERE postid NOT IN (SELECT id FROM post WHERE deletedat IS NOT NULL)`
+	args := []interface{}{opt.PerPageOrDefault(), opt.Offset()}
+	if opt.AuthorUserID != 0 {
+		sql += ` AND authoruserid=$3`
+		args = append(args, opt.AuthorUserID)
+	}
+	sql += ` ORDER BY submittedat DESC, id DESC LIMIT $1 OFFSET $2;`
+
+	var comments []*thesrc.Comment
+	if err := s.dbh.Select(&comments, sql, args...); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (s *commentsStore) Create(comment *thesrc.Comment) error {
+	defer s.observe(time.Now(), "Comments.Create")
+	if _, err := s.Posts.Get(comment.PostID); err != nil {
+		return err
+	}
+	if comment.ParentID != 0 {
+		parent, err := s.Get(comment.ParentID)
+		if err != nil {
+			return err
+		}
+		if parent.PostID != comment.PostID {
+			return errParentOnOtherPost
+		}
+	}
+
+	if comment.SubmittedAt.IsZero() {
+		comment.SubmittedAt = time.Now()
+	}
+	return s.dbh.Insert(comment)
+}
@@ -0,0 +1,121 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestCommentsStore_Get_db(t *testing.T) {
+	want := &thesrc.Comment{ID: 1, PostID: 1}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM comment;`) // test on a clean DB
+	if err := tx.Insert(want); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	comment, err := d.Comments.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	normalizeTime(&want.SubmittedAt)
+	if !reflect.DeepEqual(comment, want) {
+		t.Errorf("got comment %+v, want %+v", comment, want)
+	}
+}
+
+func TestCommentsStore_ListForPost_db(t *testing.T) {
+	want := []*thesrc.Comment{{ID: 1, PostID: 1}, {ID: 2, PostID: 1, ParentID: 1}}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM comment;`) // test on a clean DB
+	for _, c := range want {
+		if err := tx.Insert(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Insert(&thesrc.Comment{ID: 3, PostID: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	comments, err := d.Comments.ListForPost(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range want {
+		normalizeTime(&c.SubmittedAt)
+	}
+	if !reflect.DeepEqual(comments, want) {
+		t.Errorf("got comments %+v, want %+v", comments, want)
+	}
+}
+
+func TestCommentsStore_List_db(t *testing.T) {
+	now := time.Now()
+	older := &thesrc.Comment{ID: 1, PostID: 1, AuthorUserID: 1, SubmittedAt: now.Add(-time.Hour)}
+	newer := &thesrc.Comment{ID: 2, PostID: 2, AuthorUserID: 1, SubmittedAt: now}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM comment;`) // test on a clean DB
+	for _, c := range []*thesrc.Comment{older, newer, {ID: 3, PostID: 1, AuthorUserID: 2, SubmittedAt: now}} {
+		if err := tx.Insert(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDatastore(tx)
+	comments, err := d.Comments.List(&thesrc.CommentListOptions{AuthorUserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	for _, c := range comments {
+		ids = append(ids, c.ID)
+	}
+	if want := []int{newer.ID, older.ID}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got comment IDs %v, want %v", ids, want)
+	}
+}
+
+func TestCommentsStore_Create_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM comment;`)
+	post := &thesrc.Post{ID: 1, LinkURL: "http://example.com"}
+	if err := tx.Insert(post); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	comment := &thesrc.Comment{PostID: post.ID, Body: "b"}
+	if err := d.Comments.Create(comment); err != nil {
+		t.Fatal(err)
+	}
+	if comment.ID == 0 {
+		t.Error("want nonzero comment.ID after creating")
+	}
+	if comment.SubmittedAt.IsZero() {
+		t.Error("want nonzero comment.SubmittedAt after creating")
+	}
+
+	reply := &thesrc.Comment{PostID: post.ID, ParentID: comment.ID, Body: "r"}
+	if err := d.Comments.Create(reply); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Comments.Create(&thesrc.Comment{PostID: 2, Body: "b"}); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v creating comment on nonexistent post, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
@@ -1,17 +1,60 @@
 package datastore
 
 import (
+	"context"
+	"time"
+
 	"github.com/jmoiron/modl"
 	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/events"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/tracing"
 )
 
 // A Datastore accesses the datastore (in PostgreSQL).
 type Datastore struct {
-	Posts thesrc.PostsService
+	Posts         thesrc.PostsService
+	Comments      thesrc.CommentsService
+	Users         UsersStore
+	Votes         VotesStore
+	Flags         FlagsStore
+	Saves         SavesStore
+	Hides         HidesStore
+	Tags          thesrc.TagsService
+	Domains       thesrc.DomainsService
+	Thumbnails    ThumbnailsStore
+	LinkChecks    LinkChecksStore
+	Trending      TrendingStore
+	Tokens        TokensStore
+	Sessions      SessionsStore
+	Follows       FollowsStore
+	Webhooks      WebhooksStore
+	Notifications NotificationsStore
+	Jobs          JobsStore
+	TaskLocks     TaskLocksStore
+	VoteSuspects  VoteSuspectsStore
+	ClientRecords ClientRecordsStore
+	AuditLog      AuditLogStore
+	Moderation    ModerationStore
+	DeletedPosts  DeletedPostsStore
+	PostRevisions PostRevisionsStore
+	Reports       ReportsStore
+
+	// Events receives an event whenever a post is created, updated, or
+	// flagged, or its score changes.
+	Events *events.Hub
 
 	dbh modl.SqlExecutor
+
+	// ctx (if set) is the context of the request that the datastore is
+	// being used for. See WithContext.
+	ctx context.Context
 }
 
+// SlowOperationThreshold is how long a datastore operation may take before
+// it is logged as slow. If 0, slow operations are not logged.
+var SlowOperationThreshold = 250 * time.Millisecond
+
 // NewDatastore creates a new client for accessing the datastore (in
 // PostgreSQL). If dbh is nil, it uses the global DB handle.
 func NewDatastore(dbh modl.SqlExecutor) *Datastore {
@@ -19,13 +62,172 @@ func NewDatastore(dbh modl.SqlExecutor) *Datastore {
 		dbh = DBH
 	}
 
-	d := &Datastore{dbh: dbh}
+	d := &Datastore{dbh: dbh, Events: events.NewHub()}
 	d.Posts = &postsStore{d}
+	d.Comments = &commentsStore{d}
+	d.Users = &usersStore{d}
+	d.Votes = &votesStore{d}
+	d.Flags = &flagsStore{d}
+	d.Saves = &savesStore{d}
+	d.Hides = &hidesStore{d}
+	d.Tags = &tagsStore{d}
+	d.Domains = &domainsStore{d}
+	d.Thumbnails = &thumbnailsStore{d}
+	d.LinkChecks = &linkChecksStore{d}
+	d.Trending = &trendingStore{d}
+	d.Tokens = &tokensStore{d}
+	d.Sessions = &sessionsStore{d}
+	d.Follows = &followsStore{d}
+	d.Webhooks = &webhooksStore{d}
+	d.Notifications = &notificationsStore{d}
+	d.Jobs = &jobsStore{d}
+	d.TaskLocks = &taskLocksStore{d}
+	d.VoteSuspects = &voteSuspectsStore{d}
+	d.ClientRecords = &clientRecordsStore{d}
+	d.AuditLog = &auditLogStore{d}
+	d.Moderation = &moderationStore{d}
+	d.DeletedPosts = &deletedPostsStore{d}
+	d.PostRevisions = &postRevisionsStore{d}
+	d.Reports = &reportsStore{d}
 	return d
 }
 
+// WithContext returns a copy of d whose operations are on behalf of the
+// request with context ctx, so that they are logged with the request's
+// logger (see logging.FromContext). Stores on d that were not created by
+// NewDatastore (such as mocks) are shared with the copy.
+func (d *Datastore) WithContext(ctx context.Context) *Datastore {
+	d2 := *d
+	d2.ctx = ctx
+	if _, ok := d.Posts.(*postsStore); ok {
+		d2.Posts = &postsStore{&d2}
+	}
+	if _, ok := d.Comments.(*commentsStore); ok {
+		d2.Comments = &commentsStore{&d2}
+	}
+	if _, ok := d.Users.(*usersStore); ok {
+		d2.Users = &usersStore{&d2}
+	}
+	if _, ok := d.Votes.(*votesStore); ok {
+		d2.Votes = &votesStore{&d2}
+	}
+	if _, ok := d.Flags.(*flagsStore); ok {
+		d2.Flags = &flagsStore{&d2}
+	}
+	if _, ok := d.Saves.(*savesStore); ok {
+		d2.Saves = &savesStore{&d2}
+	}
+	if _, ok := d.Hides.(*hidesStore); ok {
+		d2.Hides = &hidesStore{&d2}
+	}
+	if _, ok := d.Tags.(*tagsStore); ok {
+		d2.Tags = &tagsStore{&d2}
+	}
+	if _, ok := d.Domains.(*domainsStore); ok {
+		d2.Domains = &domainsStore{&d2}
+	}
+	if _, ok := d.Thumbnails.(*thumbnailsStore); ok {
+		d2.Thumbnails = &thumbnailsStore{&d2}
+	}
+	if _, ok := d.LinkChecks.(*linkChecksStore); ok {
+		d2.LinkChecks = &linkChecksStore{&d2}
+	}
+	if _, ok := d.Trending.(*trendingStore); ok {
+		d2.Trending = &trendingStore{&d2}
+	}
+	if _, ok := d.Tokens.(*tokensStore); ok {
+		d2.Tokens = &tokensStore{&d2}
+	}
+	if _, ok := d.Sessions.(*sessionsStore); ok {
+		d2.Sessions = &sessionsStore{&d2}
+	}
+	if _, ok := d.Follows.(*followsStore); ok {
+		d2.Follows = &followsStore{&d2}
+	}
+	if _, ok := d.Webhooks.(*webhooksStore); ok {
+		d2.Webhooks = &webhooksStore{&d2}
+	}
+	if _, ok := d.Notifications.(*notificationsStore); ok {
+		d2.Notifications = &notificationsStore{&d2}
+	}
+	if _, ok := d.Jobs.(*jobsStore); ok {
+		d2.Jobs = &jobsStore{&d2}
+	}
+	if _, ok := d.TaskLocks.(*taskLocksStore); ok {
+		d2.TaskLocks = &taskLocksStore{&d2}
+	}
+	if _, ok := d.VoteSuspects.(*voteSuspectsStore); ok {
+		d2.VoteSuspects = &voteSuspectsStore{&d2}
+	}
+	if _, ok := d.ClientRecords.(*clientRecordsStore); ok {
+		d2.ClientRecords = &clientRecordsStore{&d2}
+	}
+	if _, ok := d.AuditLog.(*auditLogStore); ok {
+		d2.AuditLog = &auditLogStore{&d2}
+	}
+	if _, ok := d.Moderation.(*moderationStore); ok {
+		d2.Moderation = &moderationStore{&d2}
+	}
+	if _, ok := d.DeletedPosts.(*deletedPostsStore); ok {
+		d2.DeletedPosts = &deletedPostsStore{&d2}
+	}
+	if _, ok := d.PostRevisions.(*postRevisionsStore); ok {
+		d2.PostRevisions = &postRevisionsStore{&d2}
+	}
+	if _, ok := d.Reports.(*reportsStore); ok {
+		d2.Reports = &reportsStore{&d2}
+	}
+	return &d2
+}
+
+// observe records the latency of the datastore operation op, which started
+// at start, traces it, and logs the operation if it was slow (see
+// SlowOperationThreshold).
+func (d *Datastore) observe(start time.Time, op string) {
+	queryDuration.ObserveSince(start, op)
+	tracing.Record(d.ctx, tracing.KindClient, "datastore "+op, start, "db.operation", op)
+	if elapsed := time.Since(start); SlowOperationThreshold > 0 && elapsed > SlowOperationThreshold {
+		logging.FromContext(d.ctx).Log("slow datastore operation", "op", op, "latency_ms", logging.Milliseconds(elapsed))
+	}
+}
+
 func NewMockDatastore() *Datastore {
 	return &Datastore{
-		Posts: &thesrc.MockPostsService{},
+		Posts:         &thesrc.MockPostsService{},
+		Comments:      &thesrc.MockCommentsService{},
+		Users:         &MockUsersStore{},
+		Votes:         &MockVotesStore{},
+		Flags:         &MockFlagsStore{},
+		Saves:         &MockSavesStore{},
+		Hides:         &MockHidesStore{},
+		Tags:          &thesrc.MockTagsService{},
+		Domains:       &thesrc.MockDomainsService{},
+		Thumbnails:    &MockThumbnailsStore{},
+		LinkChecks:    &MockLinkChecksStore{},
+		Trending:      &MockTrendingStore{},
+		Tokens:        &MockTokensStore{},
+		Sessions:      &MockSessionsStore{},
+		Follows:       &MockFollowsStore{},
+		Webhooks:      &MockWebhooksStore{},
+		Notifications: &MockNotificationsStore{},
+		Jobs:          &MockJobsStore{},
+		TaskLocks:     &MockTaskLocksStore{},
+		VoteSuspects:  &MockVoteSuspectsStore{},
+		ClientRecords: &MockClientRecordsStore{},
+		AuditLog:      &MockAuditLogStore{},
+		Moderation:    &MockModerationStore{},
+		DeletedPosts:  &MockDeletedPostsStore{},
+		PostRevisions: &MockPostRevisionsStore{},
+		Reports:       &MockReportsStore{},
+		Events:        events.NewHub(),
+	}
+}
+
+// publishPost broadcasts an event of type typ with the post's current state.
+// The change has already been made, so if the post can't be fetched, no
+// event is sent rather than failing the change.
+func (d *Datastore) publishPost(typ string, postID int) {
+	if post, err := d.Posts.Get(postID); err == nil {
+		d.Events.Publish(&thesrc.Event{Type: typ, Post: post})
 	}
 }
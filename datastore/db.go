@@ -1,13 +1,20 @@
 package datastore
 
 import (
+	"database/sql"
+	"errors"
 	"log"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/jmoiron/modl"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
 )
 
 // DB is the global database.
@@ -18,35 +25,146 @@ var DB = &modl.DbMap{Dialect: modl.PostgresDialect{}}
 // could not later be wrapped in a transaction.
 var DBH modl.SqlExecutor = DB
 
+// DataSource is the PostgreSQL data source name (a connection string or URL)
+// that Connect uses. If empty, the PG* environment variables are used. If it
+// begins with "sqlite://", the rest is the path to a SQLite database file to
+// use instead.
+var DataSource string
+
+// Connection pool settings that Connect applies to the database. The
+// database/sql defaults (no limit on open connections, and connections that
+// are never closed for age) let a traffic spike exhaust the server's
+// connection slots.
+var (
+	// MaxOpenConns is the maximum number of open connections to the
+	// database. If <= 0, there is no limit.
+	MaxOpenConns = 25
+
+	// MaxIdleConns is the maximum number of idle connections kept in the
+	// pool. If <= 0, no idle connections are kept.
+	MaxIdleConns = 10
+
+	// ConnMaxLifetime is the maximum amount of time a connection may be
+	// reused. If <= 0, connections are reused forever.
+	ConnMaxLifetime = 30 * time.Minute
+
+	// StatementTimeout is the maximum amount of time a statement may run
+	// before PostgreSQL cancels it (overriding any statement_timeout in
+	// DataSource). If <= 0, the server's setting is used. It has no effect
+	// on SQLite.
+	StatementTimeout = 30 * time.Second
+)
+
 var connectOnce sync.Once
 
-// Connect connects to the PostgreSQL database specified by the PG* environment
-// variables. It calls log.Fatal if it encounters an error.
+// postgresDriver is the name of the database/sql driver for PostgreSQL, with
+// instrumentation (see QueryHooks).
+const postgresDriver = "postgres_thesrc"
+
+func init() {
+	sql.Register(postgresDriver, instrumentedDriver{&pq.Driver{}})
+}
+
+var queryDuration = metrics.NewHistogramVec("thesrc_datastore_query_duration_seconds",
+	"Datastore operation latencies, by operation.",
+	metrics.DefaultBuckets, "op")
+
+func init() {
+	gauge := func(name, help string, fn func(sql.DBStats) float64) {
+		metrics.NewGaugeFunc(name, help, func() float64 { return fn(dbStats()) })
+	}
+	counter := func(name, help string, fn func(sql.DBStats) float64) {
+		metrics.NewCounterFunc(name, help, func() float64 { return fn(dbStats()) })
+	}
+	gauge("thesrc_datastore_max_open_connections", "Maximum number of open database connections (0 means unlimited).",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	gauge("thesrc_datastore_open_connections", "Number of open database connections, in use or idle.",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	gauge("thesrc_datastore_in_use_connections", "Number of database connections in use.",
+		func(s sql.DBStats) float64 { return float64(s.InUse) })
+	gauge("thesrc_datastore_idle_connections", "Number of idle database connections.",
+		func(s sql.DBStats) float64 { return float64(s.Idle) })
+	counter("thesrc_datastore_wait_count_total", "Number of times a query waited for a free database connection.",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	counter("thesrc_datastore_wait_duration_seconds_total", "Total time queries spent waiting for a free database connection.",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+	counter("thesrc_datastore_max_idle_closed_total", "Number of database connections closed because the pool had too many idle connections.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) })
+	counter("thesrc_datastore_max_lifetime_closed_total", "Number of database connections closed because they reached their maximum lifetime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) })
+}
+
+// dbStats returns the connection pool statistics of the global database (or
+// zero statistics if it isn't connected).
+func dbStats() sql.DBStats {
+	if DB.Db == nil {
+		return sql.DBStats{}
+	}
+	return DB.Db.Stats()
+}
+
+// Connect connects to the PostgreSQL database specified by DataSource (or the
+// PG* environment variables). It calls log.Fatal if it encounters an error.
 func Connect() {
 	connectOnce.Do(func() {
 		setDBCredentialsFromRDSEnv()
 
-		var err error
-		DB.Dbx, err = sqlx.Open("postgres", "")
+		if strings.HasPrefix(DataSource, sqliteScheme) {
+			var err error
+			DB.Dbx, err = sqlx.Open(sqliteDriver, strings.TrimPrefix(DataSource, sqliteScheme))
+			if err != nil {
+				log.Fatal("Error opening SQLite database: ", err)
+			}
+			// SQLite allows only one writer at a time.
+			DB.Dbx.SetMaxOpenConns(1)
+			DB.Dialect = modl.SqliteDialect{}
+			DB.Db = DB.Dbx.DB
+			return
+		}
+
+		db, err := sql.Open(postgresDriver, withStatementTimeout(DataSource, StatementTimeout))
 		if err != nil {
-			log.Fatal("Error connecting to PostgreSQL database (using PG* environment variables): ", err)
+			log.Fatal("Error connecting to PostgreSQL database (using DataSource or PG* environment variables): ", err)
 		}
+		DB.Dbx = sqlx.NewDb(db, "postgres")
+		DB.Dbx.SetMaxOpenConns(MaxOpenConns)
+		DB.Dbx.SetMaxIdleConns(MaxIdleConns)
+		DB.Dbx.SetConnMaxLifetime(ConnMaxLifetime)
 		DB.Db = DB.Dbx.DB
 	})
 }
 
-var createSQL []string
+// withStatementTimeout returns the PostgreSQL data source ds (a URL or
+// key=value connection string) with its statement_timeout parameter set to
+// d. If d <= 0, ds is returned unchanged.
+func withStatementTimeout(ds string, d time.Duration) string {
+	if d <= 0 {
+		return ds
+	}
+	ms := strconv.FormatInt(int64(d/time.Millisecond), 10)
+	if u, err := url.Parse(ds); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		q := u.Query()
+		q.Set("statement_timeout", ms)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return strings.TrimSpace(ds + " statement_timeout=" + ms)
+}
 
-// Create the database schema. It calls log.Fatal if it encounters an error.
-func Create() {
-	if err := DB.CreateTablesIfNotExists(); err != nil {
-		log.Fatal("Error creating tables: ", err)
+// Close closes the connection to the database (if connected).
+func Close() error {
+	if DB.Db == nil {
+		return nil
 	}
-	for _, query := range createSQL {
-		if _, err := DB.Exec(query); err != nil {
-			log.Fatalf("Error running query %q: %s", query, err)
-		}
+	return DB.Db.Close()
+}
+
+// Ping checks that the database is reachable.
+func Ping() error {
+	if DB.Db == nil {
+		return errors.New("not connected to the database")
 	}
+	return DB.Db.Ping()
 }
 
 // Drop the database schema.
@@ -57,34 +175,28 @@ func Drop() {
 
 // transact calls fn in a DB transaction. If dbh is a transaction, then it just
 // calls the function. Otherwise, it begins a transaction, rolling back on
-// failure and committing on success.
-func transact(dbh modl.SqlExecutor, fn func(dbh modl.SqlExecutor) error) error {
-	var sharedTx bool
-	tx, sharedTx := dbh.(*modl.Transaction)
-	if !sharedTx {
-		var err error
-		tx, err = dbh.(*modl.DbMap).Begin()
-		if err != nil {
-			return err
-		}
-		defer func() {
-			if err != nil {
-				tx.Rollback()
-			}
-		}()
+// failure (of fn or the commit) and committing on success.
+func transact(dbh modl.SqlExecutor, fn func(dbh modl.SqlExecutor) error) (err error) {
+	if tx, ok := dbh.(*modl.Transaction); ok {
+		return fn(tx)
 	}
 
-	if err := fn(tx); err != nil {
+	tx, err := dbh.(*modl.DbMap).Begin()
+	if err != nil {
 		return err
 	}
-
-	if !sharedTx {
-		if err := tx.Commit(); err != nil {
-			return err
+	defer func() {
+		// Roll back on any error, so that the transaction doesn't hold its
+		// connection (SQLite's only one) open forever.
+		if err != nil {
+			tx.Rollback()
 		}
-	}
+	}()
 
-	return nil
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // setDBCredentialsFromRDSEnv copies RDS env vars (RDS_*) to PostgreSQL env vars
@@ -1,26 +1,84 @@
 package datastore
 
 import (
+	"errors"
 	"log"
 	"os"
 	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/modl"
 )
 
 func init() {
-	// Make sure we don't run the tests, which clobber data, on the main DB.
-	dbname := os.Getenv("PGDATABASE")
-	if dbname == "" {
-		dbname = "thesrctest"
-	}
-	if !strings.HasSuffix(dbname, "test") {
-		dbname += "test"
-	}
-	if err := os.Setenv("PGDATABASE", dbname); err != nil {
-		log.Fatal(err)
+	// To run the tests against SQLite instead of PostgreSQL, set
+	// THESRC_TEST_DB=sqlite:///path/to/test.db.
+	if ds := os.Getenv("THESRC_TEST_DB"); strings.HasPrefix(ds, sqliteScheme) {
+		DataSource = ds
+	} else {
+		// Make sure we don't run the tests, which clobber data, on the main DB.
+		dbname := os.Getenv("PGDATABASE")
+		if dbname == "" {
+			dbname = "thesrctest"
+		}
+		if !strings.HasSuffix(dbname, "test") {
+			dbname += "test"
+		}
+		if err := os.Setenv("PGDATABASE", dbname); err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	// Reset DB.
 	Connect()
 	Drop()
-	Create()
+	if _, err := MigrateUp(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestTransact_rollback(t *testing.T) {
+	errTest := errors.New("test")
+	err := transact(DBH, func(tx modl.SqlExecutor) error {
+		if err := tx.Insert(&tag{Name: "transact-rollback"}); err != nil {
+			t.Fatal(err)
+		}
+		return errTest
+	})
+	if err != errTest {
+		t.Fatalf("got error %v, want %v", err, errTest)
+	}
+
+	// The failed transaction must have released its connection (or, on
+	// SQLite, this query would block forever) and its changes.
+	if n := DB.Db.Stats().InUse; n != 0 {
+		t.Errorf("got %d connections in use after rollback, want 0", n)
+	}
+	var tags []*tag
+	if err := DBH.Select(&tags, `SELECT * FROM tag WHERE name='transact-rollback';`); err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 0 {
+		t.Errorf("got %d tags after rollback, want 0", len(tags))
+	}
+}
+
+func TestWithStatementTimeout(t *testing.T) {
+	tests := []struct {
+		ds      string
+		timeout time.Duration
+		want    string
+	}{
+		{"dbname=thesrc", 0, "dbname=thesrc"},
+		{"", 5 * time.Second, "statement_timeout=5000"},
+		{"dbname=thesrc sslmode=disable", 1500 * time.Millisecond, "dbname=thesrc sslmode=disable statement_timeout=1500"},
+		{"postgres://u@localhost/thesrc?sslmode=disable", time.Second, "postgres://u@localhost/thesrc?sslmode=disable&statement_timeout=1000"},
+		{"postgresql://localhost/thesrc?statement_timeout=1", time.Minute, "postgresql://localhost/thesrc?statement_timeout=60000"},
+	}
+	for _, test := range tests {
+		if got := withStatementTimeout(test.ds, test.timeout); got != test.want {
+			t.Errorf("withStatementTimeout(%q, %s): got %q, want %q", test.ds, test.timeout, got, test.want)
+		}
+	}
 }
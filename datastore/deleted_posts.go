@@ -0,0 +1,68 @@
+package datastore
+
+import (
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// DeletedPostsStore purges deleted posts (see thesrc.PostsService.Delete)
+// from the datastore.
+type DeletedPostsStore interface {
+	// Purge permanently deletes the posts that were deleted before before,
+	// and their comments, votes, flags, tags, and revisions. It returns the
+	// number of posts purged.
+	Purge(before time.Time) (int, error)
+}
+
+type deletedPostsStore struct{ *Datastore }
+
+func (s *deletedPostsStore) Purge(before time.Time) (int, error) {
+	defer s.observe(time.Now(), "DeletedPosts.Purge")
+	var posts []*thesrc.Post
+	if err := s.dbh.Select(&posts, `SELECT * FROM post WHERE deletedat < $1;`, before); err != nil {
+		return 0, err
+	}
+	if len(posts) == 0 {
+		return 0, nil
+	}
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	err := transact(s.dbh, func(tx modl.SqlExecutor) error {
+		in, args := inList(1, ids)
+		if _, err := tx.Exec(`DELETE FROM comment_vote WHERE commentid IN (SELECT id FROM comment WHERE postid IN `+in+`);`, args...); err != nil {
+			return err
+		}
+		for _, table := range []string{"post_tag", "vote", "flag", "saved_posts", "hidden_posts", "notification", "comment", "thumbnail_attempt", "link_check", "post_revision", "report"} {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE postid IN `+in+`;`, args...); err != nil {
+				return err
+			}
+		}
+		if err := deleteUnusedTags(tx); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM post WHERE id IN `+in+`;`, args...)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(posts), nil
+}
+
+type MockDeletedPostsStore struct {
+	Purge_ func(before time.Time) (int, error)
+}
+
+var _ DeletedPostsStore = &MockDeletedPostsStore{}
+
+func (s *MockDeletedPostsStore) Purge(before time.Time) (int, error) {
+	if s.Purge_ == nil {
+		return 0, nil
+	}
+	return s.Purge_(before)
+}
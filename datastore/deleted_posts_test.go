@@ -0,0 +1,96 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestDeletedPostsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM comment;`)
+
+	testDeletedPostsStore(t, NewDatastore(tx))
+}
+
+// testDeletedPostsStore tests deleting, undeleting, and purging posts. d
+// must be empty.
+func testDeletedPostsStore(t *testing.T, d *Datastore) {
+	post := &thesrc.Post{Title: "t", LinkURL: "http://example.com"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Comments.Create(&thesrc.Comment{PostID: post.ID, Body: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	listDeleted := func() []*thesrc.Post {
+		posts, err := d.Posts.List(&thesrc.PostListOptions{Deleted: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return posts
+	}
+
+	if err := d.Posts.Delete(post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Posts.Get(post.ID); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v getting deleted post, want %v", err, thesrc.ErrPostNotFound)
+	}
+	if posts, _ := d.Posts.List(nil); len(posts) != 0 {
+		t.Errorf("got %d posts listed after deleting, want 0", len(posts))
+	}
+	if comments, _ := d.Comments.List(nil); len(comments) != 0 {
+		t.Errorf("got %d comments listed after deleting their post, want 0", len(comments))
+	}
+	if deleted := listDeleted(); len(deleted) != 1 || deleted[0].ID != post.ID || deleted[0].DeletedAt == nil {
+		t.Errorf("got deleted posts %+v, want only %d with DeletedAt", deleted, post.ID)
+	}
+
+	// Undeleting restores the post and its comments.
+	if err := d.Posts.Undelete(post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := d.Posts.Get(post.ID); err != nil || p.DeletedAt != nil {
+		t.Errorf("got post %+v and error %v after undeleting, want it without DeletedAt", p, err)
+	}
+	if comments, _ := d.Comments.ListForPost(post.ID); len(comments) != 1 {
+		t.Errorf("got %d comments after undeleting, want 1", len(comments))
+	}
+	if err := d.Posts.Undelete(post.ID); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v undeleting a post that isn't deleted, want %v", err, thesrc.ErrPostNotFound)
+	}
+
+	// A deleted post's link URL may be submitted again, after which the
+	// deleted post can't be undeleted.
+	if err := d.Posts.Delete(post.ID); err != nil {
+		t.Fatal(err)
+	}
+	resubmitted := &thesrc.Post{Title: "t2", LinkURL: post.LinkURL}
+	if created, err := d.Posts.Submit(resubmitted); err != nil || !created {
+		t.Fatalf("got created %v and error %v resubmitting a deleted post's link URL, want true and nil", created, err)
+	}
+	if err := d.Posts.Undelete(post.ID); err != thesrc.ErrPostLinkURLTaken {
+		t.Errorf("got error %v undeleting a post whose link URL was resubmitted, want %v", err, thesrc.ErrPostLinkURLTaken)
+	}
+
+	// Purging only purges posts deleted before the given time.
+	if n, err := d.DeletedPosts.Purge(time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("got %d purged and error %v, want 0 and nil", n, err)
+	}
+	if n, err := d.DeletedPosts.Purge(time.Now().Add(time.Minute)); err != nil || n != 1 {
+		t.Errorf("got %d purged and error %v, want 1 and nil", n, err)
+	}
+	if deleted := listDeleted(); len(deleted) != 0 {
+		t.Errorf("got %d deleted posts after purging, want 0", len(deleted))
+	}
+	if err := d.Posts.Undelete(post.ID); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v undeleting a purged post, want %v", err, thesrc.ErrPostNotFound)
+	}
+	if _, err := d.Posts.Get(resubmitted.ID); err != nil {
+		t.Errorf("got error %v getting the resubmitted post after purging, want nil", err)
+	}
+}
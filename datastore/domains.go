@@ -0,0 +1,46 @@
+package datastore
+
+import (
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+type domainsStore struct{ *Datastore }
+
+var _ thesrc.DomainsService = &domainsStore{}
+
+func (s *domainsStore) Get(domain string) (*thesrc.DomainStats, error) {
+	defer s.observe(time.Now(), "Domains.Get")
+	domain = thesrc.NormalizeDomain(domain)
+
+	var stats []*thesrc.DomainStats
+	if err := s.dbh.Select(&stats, `SELECT coalesce(sum(CASE WHEN hidden OR dead THEN 0 ELSE 1 END), 0) AS numposts, coalesce(avg(CASE WHEN hidden OR dead THEN NULL ELSE score END), 0) AS averagescore, coalesce(sum(CASE WHEN hidden OR dead THEN 1 ELSE 0 END), 0) AS numhidden FROM post WHERE domain=$1 AND deletedat IS NULL;`, domain); err != nil {
+		return nil, err
+	}
+	st := &thesrc.DomainStats{}
+	if len(stats) > 0 {
+		st = stats[0]
+	}
+	st.Domain = domain
+	return st, nil
+}
+
+// setPostDomains sets the domain of every post, for posts submitted before
+// post.domain existed.
+func setPostDomains(tx modl.SqlExecutor) error {
+	var posts []*struct {
+		ID      int
+		LinkURL string
+	}
+	if err := tx.Select(&posts, `SELECT id, linkurl FROM post WHERE linkurl != '';`); err != nil {
+		return err
+	}
+	for _, p := range posts {
+		if _, err := tx.Exec(`UPDATE post SET domain=$1 WHERE id=$2;`, thesrc.LinkDomain(p.LinkURL), p.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
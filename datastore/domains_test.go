@@ -0,0 +1,41 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestDomainsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+
+	d := NewDatastore(tx)
+	for _, p := range []*thesrc.Post{
+		{LinkURL: "http://example.com/a", Score: 1},
+		{LinkURL: "https://www.example.com/b", Score: 4},
+		{LinkURL: "http://other.com/", Score: 1},
+	} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	posts, err := d.Posts.List(&thesrc.PostListOptions{Domain: "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 {
+		t.Errorf("got %d posts from example.com, want 2", len(posts))
+	}
+
+	stats, err := d.Domains.Get("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&thesrc.DomainStats{Domain: "example.com", NumPosts: 2, AverageScore: 2.5}); !reflect.DeepEqual(stats, want) {
+		t.Errorf("got stats %+v, want %+v", stats, want)
+	}
+}
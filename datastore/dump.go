@@ -0,0 +1,154 @@
+package datastore
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// A DumpRecord is one line of a dump written by Export and read by Import.
+// Dumps are in JSON Lines format: each line is a JSON-encoded DumpRecord.
+type DumpRecord struct {
+	// Type is the type of the record (DumpPost).
+	Type string
+
+	// Post is set if Type is DumpPost.
+	Post *thesrc.Post `json:",omitempty"`
+}
+
+// Dump record types.
+const (
+	DumpPost = "post"
+)
+
+// dumpPageSize is the number of posts that Export reads from the datastore
+// at once.
+const dumpPageSize = 500
+
+// postDumper is implemented by posts stores that support Export.
+type postDumper interface {
+	// dumpPosts lists up to n posts (including hidden and dead posts, but
+	// not deleted posts) whose ID is greater than afterID, in order of ID.
+	dumpPosts(afterID, n int) ([]*thesrc.Post, error)
+}
+
+// Export writes all posts in the datastore to w, as a dump in JSON Lines
+// format (see DumpRecord). It returns the number of records written.
+func (d *Datastore) Export(w io.Writer) (int, error) {
+	dumper, ok := d.Posts.(postDumper)
+	if !ok {
+		return 0, errors.New("datastore does not support export")
+	}
+
+	enc := json.NewEncoder(w)
+	var n, afterID int
+	for {
+		posts, err := dumper.dumpPosts(afterID, dumpPageSize)
+		if err != nil {
+			return n, err
+		}
+		for _, post := range posts {
+			if err := enc.Encode(&DumpRecord{Type: DumpPost, Post: post}); err != nil {
+				return n, err
+			}
+			n++
+			afterID = post.ID
+		}
+		if len(posts) < dumpPageSize {
+			return n, nil
+		}
+	}
+}
+
+// Import reads a dump written by Export from r and submits its posts to the
+// datastore, in batches of up to thesrc.MaxBatchSize posts. Posts are given
+// new IDs, and posts whose link URL was already submitted are skipped, so a
+// dump may be imported more than once (although self-posts, which have no
+// link URL, are imported again each time). It returns the number of posts
+// that were created.
+func (d *Datastore) Import(r io.Reader) (int, error) {
+	var created int
+	var batch []*thesrc.Post
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := d.Posts.CreateBatch(batch)
+		if err != nil {
+			return err
+		}
+		for _, res := range results {
+			if res.Created {
+				created++
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 16*1024*1024)
+	for line := 1; s.Scan(); line++ {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		var rec DumpRecord
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			return created, fmt.Errorf("line %d: %s", line, err)
+		}
+		switch rec.Type {
+		case DumpPost:
+			if rec.Post == nil {
+				return created, fmt.Errorf("line %d: post record has no post", line)
+			}
+			rec.Post.ID = 0
+			batch = append(batch, rec.Post)
+			if len(batch) == thesrc.MaxBatchSize {
+				if err := flush(); err != nil {
+					return created, err
+				}
+			}
+		default:
+			return created, fmt.Errorf("line %d: unknown record type %q", line, rec.Type)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return created, err
+	}
+	return created, flush()
+}
+
+func (s *postsStore) dumpPosts(afterID, n int) ([]*thesrc.Post, error) {
+	defer s.observe(time.Now(), "Posts.dumpPosts")
+	var posts []*thesrc.Post
+	if err := s.dbh.Select(&posts, `SELECT * FROM post WHERE id > $1 AND deletedat IS NULL ORDER BY id LIMIT $2;`, afterID, n); err != nil {
+		return nil, err
+	}
+	if err := loadPostTags(s.dbh, posts...); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (s *memoryPostsStore) dumpPosts(afterID, n int) ([]*thesrc.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var posts []*thesrc.Post
+	for _, p := range s.posts {
+		if p.ID > afterID {
+			posts = append(posts, copyPost(p))
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool { return posts[i].ID < posts[j].ID })
+	if len(posts) > n {
+		posts = posts[:n]
+	}
+	return posts, nil
+}
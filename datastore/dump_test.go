@@ -0,0 +1,91 @@
+package datastore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestDatastore_Export_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	for _, p := range []*thesrc.Post{
+		{ID: 1, LinkURL: "http://example.com/1"},
+		{ID: 2, LinkURL: "http://example.com/2", Dead: true},
+	} {
+		if err := tx.Insert(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDatastore(tx)
+	var buf bytes.Buffer
+	n, err := d.Export(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("got %d records, want 2 (including the dead post)", n)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Errorf("got %d lines, want 2", lines)
+	}
+}
+
+func TestMemoryDatastore_ExportImport(t *testing.T) {
+	src := NewMemoryDatastore()
+	posts := []*thesrc.Post{
+		{Title: "a", LinkURL: "http://example.com/1", Tags: []string{"golang"}, Score: 3},
+		{Title: "b", LinkURL: "http://example.com/2", Hidden: true},
+	}
+	for _, p := range posts {
+		if _, err := src.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if n, err := src.Export(&buf); err != nil {
+		t.Fatal(err)
+	} else if n != len(posts) {
+		t.Errorf("exported %d records, want %d", n, len(posts))
+	}
+	dump := buf.String()
+
+	dst := NewMemoryDatastore()
+	dst.Posts.Submit(&thesrc.Post{LinkURL: "http://example.com/other"})
+	if n, err := dst.Import(strings.NewReader(dump)); err != nil {
+		t.Fatal(err)
+	} else if n != len(posts) {
+		t.Errorf("imported %d posts, want %d", n, len(posts))
+	}
+
+	// Importing the same dump again should skip the existing posts.
+	if n, err := dst.Import(strings.NewReader(dump)); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Errorf("imported %d posts again, want 0", n)
+	}
+
+	a, err := dst.Posts.Get(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Title != "a" || a.Score != 3 || len(a.Tags) != 1 {
+		t.Errorf("got imported post %+v, want post a with its score and tags", a)
+	}
+	b, err := dst.Posts.Get(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Title != "b" || !b.Hidden {
+		t.Errorf("got imported post %+v, want hidden post b", b)
+	}
+
+	if _, err := dst.Import(strings.NewReader(`{"Type":"widget"}` + "\n")); err == nil {
+		t.Error("got nil error for unknown record type")
+	}
+}
@@ -0,0 +1,72 @@
+package datastore
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// A flag is a user's report that a post is inappropriate.
+type flag struct {
+	UserID    int
+	PostID    int
+	FlaggedAt time.Time
+}
+
+func init() {
+	DB.AddTableWithName(flag{}, "flag").SetKeys(false, "UserID", "PostID")
+}
+
+// FlagsStore accesses flags in the datastore. Flags are recorded on behalf of
+// a specific user (unlike thesrc.PostsService.Flag, which flags as the
+// authenticated user).
+type FlagsStore interface {
+	// Flag a post as a user, incrementing the post's flag count if the user
+	// had not already flagged it.
+	Flag(userID, postID int) error
+}
+
+// errFlagWithoutUser is returned by the datastore's PostsService.Flag, which
+// can't know which user is flagging the post.
+var errFlagWithoutUser = errors.New("datastore: posts must be flagged by a user (use Datastore.Flags)")
+
+type flagsStore struct{ *Datastore }
+
+func (s *flagsStore) Flag(userID, postID int) error {
+	defer s.observe(time.Now(), "Flags.Flag")
+	if _, err := s.Posts.Get(postID); err != nil {
+		return err
+	}
+	var changed bool
+	err := transact(s.dbh, func(tx modl.SqlExecutor) error {
+		res, err := tx.Exec(`INSERT INTO flag(userid, postid, flaggedat) SELECT $1, $2, $3 WHERE NOT EXISTS (SELECT 1 FROM flag WHERE userid=$1 AND postid=$2);`, userID, postID, time.Now())
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE post SET flags=flags+1 WHERE id=$1;`, postID)
+		changed = err == nil
+		return err
+	})
+	if err == nil && changed {
+		s.publishPost(thesrc.EventPostFlagged, postID)
+	}
+	return err
+}
+
+type MockFlagsStore struct {
+	Flag_ func(userID, postID int) error
+}
+
+var _ FlagsStore = &MockFlagsStore{}
+
+func (s *MockFlagsStore) Flag(userID, postID int) error {
+	if s.Flag_ == nil {
+		return nil
+	}
+	return s.Flag_(userID, postID)
+}
@@ -0,0 +1,49 @@
+package datastore
+
+import (
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestFlagsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM flag;`)
+	post := &thesrc.Post{ID: 1, LinkURL: "http://example.com"}
+	if err := tx.Insert(post); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	checkFlags := func(want int) {
+		post, err := d.Posts.Get(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.Flags != want {
+			t.Errorf("got %d flags, want %d", post.Flags, want)
+		}
+	}
+
+	if err := d.Flags.Flag(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	checkFlags(1)
+
+	// Flagging again should have no effect.
+	if err := d.Flags.Flag(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	checkFlags(1)
+
+	if err := d.Flags.Flag(2, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	checkFlags(2)
+
+	if err := d.Flags.Flag(1, 2); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v flagging nonexistent post, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
@@ -0,0 +1,99 @@
+package datastore
+
+import (
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.Follow{}, "follow").SetKeys(false, "UserID", "Kind", "Name")
+}
+
+// FollowsStore accesses the topics that users follow and hide in the
+// datastore. Follows are managed on behalf of a specific user (unlike
+// thesrc.FollowsService, which manages the authenticated user's follows).
+type FollowsStore interface {
+	// List a user's followed and hidden topics, most recently followed
+	// first.
+	List(userID int) ([]*thesrc.Follow, error)
+
+	// Follow (or, if follow.Hide is set, hide) a topic for follow.UserID,
+	// replacing any previous follow of the same topic. follow must already
+	// be normalized (see thesrc.NormalizeFollow).
+	Follow(follow *thesrc.Follow) error
+
+	// Unfollow stops a user following (or hiding) a topic.
+	Unfollow(userID int, kind, name string) error
+}
+
+type followsStore struct{ *Datastore }
+
+func (s *followsStore) List(userID int) ([]*thesrc.Follow, error) {
+	defer s.observe(time.Now(), "Follows.List")
+	var follows []*thesrc.Follow
+	if err := s.dbh.Select(&follows, `SELECT * FROM follow WHERE userid=$1 ORDER BY createdat DESC, kind, name;`, userID); err != nil {
+		return nil, err
+	}
+	return follows, nil
+}
+
+func (s *followsStore) Follow(follow *thesrc.Follow) error {
+	defer s.observe(time.Now(), "Follows.Follow")
+	if follow.CreatedAt.IsZero() {
+		follow.CreatedAt = time.Now()
+	}
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		if _, err := tx.Exec(`DELETE FROM follow WHERE userid=$1 AND kind=$2 AND name=$3;`, follow.UserID, follow.Kind, follow.Name); err != nil {
+			return err
+		}
+		return tx.Insert(follow)
+	})
+}
+
+func (s *followsStore) Unfollow(userID int, kind, name string) error {
+	defer s.observe(time.Now(), "Follows.Unfollow")
+	_, err := s.dbh.Exec(`DELETE FROM follow WHERE userid=$1 AND kind=$2 AND name=$3;`, userID, kind, name)
+	return err
+}
+
+// followedTopicCond returns an SQL condition that is true for posts about
+// a topic that the user (whose ID is the SQL expression userID) follows or,
+// if hide is true, hides.
+func followedTopicCond(userID string, hide bool) string {
+	hidden := "f.hide"
+	if !hide {
+		hidden = "NOT f.hide"
+	}
+	return "EXISTS (SELECT 1 FROM follow f WHERE f.userid=" + userID + " AND " + hidden + " AND (f.kind='" + thesrc.FollowDomain + "' AND f.name=post.domain OR f.kind='" + thesrc.FollowTag + "' AND f.name IN (SELECT t.name FROM post_tag pt INNER JOIN tag t ON t.id=pt.tagid WHERE pt.postid=post.id)))"
+}
+
+type MockFollowsStore struct {
+	List_     func(userID int) ([]*thesrc.Follow, error)
+	Follow_   func(follow *thesrc.Follow) error
+	Unfollow_ func(userID int, kind, name string) error
+}
+
+var _ FollowsStore = &MockFollowsStore{}
+
+func (s *MockFollowsStore) List(userID int) ([]*thesrc.Follow, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(userID)
+}
+
+func (s *MockFollowsStore) Follow(follow *thesrc.Follow) error {
+	if s.Follow_ == nil {
+		return nil
+	}
+	return s.Follow_(follow)
+}
+
+func (s *MockFollowsStore) Unfollow(userID int, kind, name string) error {
+	if s.Unfollow_ == nil {
+		return nil
+	}
+	return s.Unfollow_(userID, kind, name)
+}
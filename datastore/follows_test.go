@@ -0,0 +1,86 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestFollowsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM post_tag;`)
+	tx.Exec(`DELETE FROM follow;`)
+
+	testFollowsStore(t, NewDatastore(tx))
+}
+
+func TestMemoryDatastore_Follows(t *testing.T) {
+	testFollowsStore(t, NewMemoryDatastore())
+}
+
+// testFollowsStore tests d.Follows and personalized post lists, given an
+// empty datastore.
+func testFollowsStore(t *testing.T, d *Datastore) {
+	posts := []*thesrc.Post{
+		{Title: "a", LinkURL: "http://example.com/a", Score: 3},
+		{Title: "b", LinkURL: "http://example.com/b", Score: 2, Tags: []string{"golang"}},
+		{Title: "c", LinkURL: "http://spam.example.org/c", Score: 4},
+	}
+	for _, p := range posts {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Hiding a followed topic replaces the follow.
+	for _, f := range []*thesrc.Follow{
+		{UserID: 1, Kind: thesrc.FollowTag, Name: "golang", Hide: true},
+		{UserID: 1, Kind: thesrc.FollowDomain, Name: "spam.example.org", Hide: true},
+		{UserID: 1, Kind: thesrc.FollowTag, Name: "golang"},
+		{UserID: 2, Kind: thesrc.FollowDomain, Name: "example.com"},
+	} {
+		if err := d.Follows.Follow(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	follows, err := d.Follows.List(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hides := map[string]bool{}
+	for _, f := range follows {
+		hides[f.Kind+":"+f.Name] = f.Hide
+	}
+	if want := map[string]bool{"tag:golang": false, "domain:spam.example.org": true}; len(follows) != 2 || !reflect.DeepEqual(hides, want) {
+		t.Errorf("got follows %+v, want %v (topic: hidden)", follows, want)
+	}
+
+	listIDs := func(opt *thesrc.PostListOptions) []int {
+		posts, err := d.Posts.List(opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, p := range posts {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+	if got, want := listIDs(&thesrc.PostListOptions{Sort: thesrc.SortTop}), []int{posts[2].ID, posts[0].ID, posts[1].ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got top post IDs %v, want %v", got, want)
+	}
+	if got, want := listIDs(&thesrc.PostListOptions{Sort: thesrc.SortTop, PersonalizedForUserID: 1}), []int{posts[1].ID, posts[0].ID, posts[2].ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got personalized top post IDs %v, want %v", got, want)
+	}
+
+	if err := d.Follows.Unfollow(1, thesrc.FollowTag, "golang"); err != nil {
+		t.Fatal(err)
+	}
+	if follows, _ := d.Follows.List(1); len(follows) != 1 || follows[0].Name != "spam.example.org" {
+		t.Errorf("got follows %+v after unfollowing, want only spam.example.org", follows)
+	}
+}
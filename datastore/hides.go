@@ -0,0 +1,102 @@
+package datastore
+
+import (
+	"errors"
+	"time"
+)
+
+// A hiddenPost is a user's hiding of a post from their post listings.
+type hiddenPost struct {
+	UserID   int
+	PostID   int
+	HiddenAt time.Time
+}
+
+func init() {
+	DB.AddTableWithName(hiddenPost{}, "hidden_posts").SetKeys(false, "UserID", "PostID")
+}
+
+// HidesStore accesses the posts that users have hidden from their post
+// listings (see thesrc.PostListOptions.ExcludeHiddenByUserID). Posts are
+// hidden on behalf of a specific user (unlike thesrc.PostsService.Hide,
+// which hides as the authenticated user).
+type HidesStore interface {
+	// Hide a post from a user's post listings. Hiding a post that the user
+	// has already hidden has no effect.
+	Hide(userID, postID int) error
+
+	// Unhide shows a post that the user hid in their post listings again.
+	Unhide(userID, postID int) error
+
+	// Hidden returns the subset of postIDs that the user has hidden.
+	Hidden(userID int, postIDs []int) (map[int]bool, error)
+}
+
+// errHideWithoutUser is returned by the datastore's PostsService.Hide and
+// Unhide, which can't know which user is hiding the post.
+var errHideWithoutUser = errors.New("datastore: posts must be hidden by a user (use Datastore.Hides)")
+
+type hidesStore struct{ *Datastore }
+
+func (s *hidesStore) Hide(userID, postID int) error {
+	defer s.observe(time.Now(), "Hides.Hide")
+	if _, err := s.Posts.Get(postID); err != nil {
+		return err
+	}
+	_, err := s.dbh.Exec(`INSERT INTO hidden_posts(userid, postid, hiddenat) SELECT $1, $2, $3 WHERE NOT EXISTS (SELECT 1 FROM hidden_posts WHERE userid=$1 AND postid=$2);`, userID, postID, time.Now())
+	return err
+}
+
+func (s *hidesStore) Unhide(userID, postID int) error {
+	defer s.observe(time.Now(), "Hides.Unhide")
+	_, err := s.dbh.Exec(`DELETE FROM hidden_posts WHERE userid=$1 AND postid=$2;`, userID, postID)
+	return err
+}
+
+func (s *hidesStore) Hidden(userID int, postIDs []int) (map[int]bool, error) {
+	defer s.observe(time.Now(), "Hides.Hidden")
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	in, args := inList(2, postIDs)
+	var hides []*hiddenPost
+	if err := s.dbh.Select(&hides, `SELECT * FROM hidden_posts WHERE userid=$1 AND postid IN `+in+`;`, append([]interface{}{userID}, args...)...); err != nil {
+		return nil, err
+	}
+
+	hidden := make(map[int]bool, len(hides))
+	for _, h := range hides {
+		hidden[h.PostID] = true
+	}
+	return hidden, nil
+}
+
+type MockHidesStore struct {
+	Hide_   func(userID, postID int) error
+	Unhide_ func(userID, postID int) error
+	Hidden_ func(userID int, postIDs []int) (map[int]bool, error)
+}
+
+var _ HidesStore = &MockHidesStore{}
+
+func (s *MockHidesStore) Hide(userID, postID int) error {
+	if s.Hide_ == nil {
+		return nil
+	}
+	return s.Hide_(userID, postID)
+}
+
+func (s *MockHidesStore) Unhide(userID, postID int) error {
+	if s.Unhide_ == nil {
+		return nil
+	}
+	return s.Unhide_(userID, postID)
+}
+
+func (s *MockHidesStore) Hidden(userID int, postIDs []int) (map[int]bool, error) {
+	if s.Hidden_ == nil {
+		return nil, nil
+	}
+	return s.Hidden_(userID, postIDs)
+}
@@ -0,0 +1,58 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestHidesStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM hidden_posts;`)
+	for _, p := range []*thesrc.Post{
+		{ID: 1, LinkURL: "http://example.com/1"},
+		{ID: 2, LinkURL: "http://example.com/2"},
+	} {
+		if err := tx.Insert(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDatastore(tx)
+	// Hiding again should have no effect.
+	for i := 0; i < 2; i++ {
+		if err := d.Hides.Hide(1, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hidden, err := d.Hides.Hidden(1, []int{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]bool{1: true}; !reflect.DeepEqual(hidden, want) {
+		t.Errorf("got hidden %v, want %v", hidden, want)
+	}
+
+	posts, err := d.Posts.List(&thesrc.PostListOptions{ExcludeHiddenByUserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != 2 {
+		t.Errorf("got posts %+v excluding hidden posts, want post 2", posts)
+	}
+
+	if err := d.Hides.Unhide(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if hidden, _ := d.Hides.Hidden(1, []int{1}); hidden[1] {
+		t.Error("post is still hidden after Unhide")
+	}
+
+	if err := d.Hides.Hide(1, 3); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v hiding nonexistent post, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
@@ -0,0 +1,263 @@
+package datastore
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
+)
+
+// A QueryEvent describes a SQL statement that was run on the database.
+type QueryEvent struct {
+	// Query is the statement's SQL text.
+	Query string
+
+	// Duration is how long the statement took to run (for queries, until
+	// the database began returning rows).
+	Duration time.Duration
+
+	// Err is the error that the statement returned, if any.
+	Err error
+}
+
+// QueryHooks are called, in order, after each SQL statement is run on the
+// database, including the statements that modl generates. They must be safe
+// for concurrent use. The default hook records the
+// thesrc_datastore_statement_* metrics and logs slow statements (see
+// SlowQueryThreshold).
+var QueryHooks = []func(*QueryEvent){observeQuery}
+
+// SlowQueryThreshold is how long a single SQL statement may take before it
+// is logged as slow. If 0, slow statements are not logged. (A slow datastore
+// operation that runs many fast statements is logged according to
+// SlowOperationThreshold instead.)
+var SlowQueryThreshold = 100 * time.Millisecond
+
+var (
+	statementDuration = metrics.NewHistogramVec("thesrc_datastore_statement_duration_seconds",
+		"SQL statement latencies, by statement type (SELECT, INSERT, etc.).",
+		metrics.DefaultBuckets, "statement")
+	statementErrors = metrics.NewCounterVec("thesrc_datastore_statement_errors_total",
+		"SQL statements that returned an error, by statement type (SELECT, INSERT, etc.).",
+		"statement")
+)
+
+func observeQuery(e *QueryEvent) {
+	typ := statementType(e.Query)
+	statementDuration.Observe(e.Duration.Seconds(), typ)
+	if e.Err != nil {
+		statementErrors.Inc(typ)
+	}
+	if SlowQueryThreshold > 0 && e.Duration > SlowQueryThreshold {
+		logging.Default.Log("slow SQL statement", "statement", typ, "query", compactQuery(e.Query), "latency_ms", logging.Milliseconds(e.Duration))
+	}
+}
+
+// statementType returns the SQL command that query begins with (e.g.,
+// "SELECT"), or "OTHER".
+func statementType(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "OTHER"
+	}
+	switch cmd := strings.ToUpper(strings.TrimSuffix(fields[0], ";")); cmd {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH", "BEGIN", "COMMIT", "ROLLBACK":
+		return cmd
+	}
+	return "OTHER"
+}
+
+// compactQuery collapses the whitespace in query and truncates it, for
+// logging.
+func compactQuery(query string) string {
+	const max = 500
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > max {
+		query = query[:max] + "..."
+	}
+	return query
+}
+
+// runQueryHooks calls the QueryHooks for query, which started at start and
+// returned err. It does nothing if err is driver.ErrSkip, which means that
+// database/sql will run query another way (and call runQueryHooks then).
+func runQueryHooks(query string, start time.Time, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	e := &QueryEvent{Query: query, Duration: time.Since(start), Err: err}
+	for _, hook := range QueryHooks {
+		hook(e)
+	}
+}
+
+// instrumentedDriver wraps a database/sql driver so that the QueryHooks are
+// called for each statement run on its connections.
+type instrumentedDriver struct{ driver.Driver }
+
+func (d instrumentedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{conn}, nil
+}
+
+// instrumentedConn is a connection opened by instrumentedDriver. It
+// implements the optional driver interfaces by delegating to the underlying
+// connection when it implements them, and otherwise by doing what
+// database/sql would do without them.
+type instrumentedConn struct{ driver.Conn }
+
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{stmt, query, c}, nil
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	runQueryHooks(query, start, err)
+	return res, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	runQueryHooks(query, start, err)
+	return rows, err
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *instrumentedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// instrumentedStmt is a prepared statement on an instrumentedConn.
+type instrumentedStmt struct {
+	driver.Stmt
+	query string
+	conn  *instrumentedConn
+}
+
+func (s *instrumentedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	start := time.Now()
+	res, err := s.Stmt.Exec(args)
+	runQueryHooks(s.query, start, err)
+	return res, err
+}
+
+func (s *instrumentedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := s.Stmt.Query(args)
+	runQueryHooks(s.query, start, err)
+	return rows, err
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := s.Stmt.(driver.StmtExecContext)
+	if !ok {
+		values, err := namedValuesToValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Exec(values)
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, args)
+	runQueryHooks(s.query, start, err)
+	return res, err
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := s.Stmt.(driver.StmtQueryContext)
+	if !ok {
+		values, err := namedValuesToValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Query(values)
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, args)
+	runQueryHooks(s.query, start, err)
+	return rows, err
+}
+
+// CheckNamedValue uses the statement's checker or else the connection's,
+// because database/sql only consults the connection's if the statement
+// doesn't have one.
+func (s *instrumentedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+// namedValuesToValues converts args for a driver that doesn't support named
+// arguments.
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("datastore: driver does not support named arguments")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
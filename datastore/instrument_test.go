@@ -0,0 +1,72 @@
+package datastore
+
+import (
+	"database/sql"
+	"sync"
+	"testing"
+)
+
+func TestQueryHooks(t *testing.T) {
+	var mu sync.Mutex
+	var events []*QueryEvent
+	orig := QueryHooks
+	QueryHooks = []func(*QueryEvent){func(e *QueryEvent) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}}
+	defer func() { QueryHooks = orig }()
+
+	db, err := sql.Open(sqliteDriver, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE t (x integer);`); err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := db.Prepare(`SELECT x FROM t WHERE x=$1;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	var x int
+	if err := stmt.QueryRow(1).Scan(&x); err != sql.ErrNoRows {
+		t.Fatalf("got error %v, want sql.ErrNoRows", err)
+	}
+	if _, err := db.Exec(`INSERT INTO nonexistent VALUES (1);`); err == nil {
+		t.Fatal("got no error inserting into nonexistent table")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{`CREATE TABLE t (x integer);`, `SELECT x FROM t WHERE x=$1;`, `INSERT INTO nonexistent VALUES (1);`}
+	if len(events) != len(want) {
+		t.Fatalf("got %d query events, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if e.Query != want[i] {
+			t.Errorf("event %d: got query %q, want %q", i, e.Query, want[i])
+		}
+		if wantErr := i == 2; (e.Err != nil) != wantErr {
+			t.Errorf("event %d: got error %v, want error: %v", i, e.Err, wantErr)
+		}
+	}
+}
+
+func TestStatementType(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM post;":    "SELECT",
+		"\n  insert INTO post":   "INSERT",
+		"BEGIN;":                 "BEGIN",
+		"CREATE TABLE t (x int)": "OTHER",
+		"":                       "OTHER",
+	}
+	for query, want := range tests {
+		if got := statementType(query); got != want {
+			t.Errorf("statementType(%q): got %q, want %q", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package datastore
+
+import (
+	"database/sql"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.Job{}, "job").SetKeys(true, "ID")
+}
+
+// DefaultJobMaxAttempts is the MaxAttempts of jobs that are queued without
+// one.
+const DefaultJobMaxAttempts = 5
+
+// JobsStore accesses the job queue in the datastore. Workers claim pending
+// jobs, and then complete or fail them.
+type JobsStore interface {
+	// Enqueue queues a job to run at job.RunAt (or now, if it's zero). If
+	// job.Key is set and a pending or running job already has the same key,
+	// the job isn't queued (and job.ID is 0). Otherwise job.ID will be the
+	// new job's ID.
+	Enqueue(job *thesrc.Job) error
+
+	// Claim marks the pending job that has been waiting longest to run (or
+	// a running job whose lease expired) as running, and returns it. The
+	// job's lease expires at now plus lease, after which another worker may
+	// claim it. If no job is ready to run, Claim returns nil.
+	Claim(now time.Time, lease time.Duration) (*thesrc.Job, error)
+
+	// Complete marks job, as returned by Claim, as done. If job's lease has
+	// expired (even if no other worker has claimed it since),
+	// thesrc.ErrJobLeaseExpired is returned and the job is left as is.
+	Complete(job *thesrc.Job) error
+
+	// Fail records that job, as returned by Claim, failed with errMsg. If the
+	// job has been attempted MaxAttempts times, it is marked as failed;
+	// otherwise it is queued to run again at retryAt. Like Complete, Fail
+	// returns thesrc.ErrJobLeaseExpired if job's lease has expired.
+	Fail(job *thesrc.Job, errMsg string, retryAt time.Time) error
+
+	// List jobs, most recently updated first.
+	List(opt *thesrc.JobListOptions) ([]*thesrc.Job, error)
+
+	// Retry queues a failed job to run again, with its attempts reset. If
+	// there is no failed job with the given ID, thesrc.ErrJobNotFound is
+	// returned.
+	Retry(id int) error
+
+	// DeleteDone deletes jobs that were done before before.
+	DeleteDone(before time.Time) error
+}
+
+type jobsStore struct{ *Datastore }
+
+func (s *jobsStore) Enqueue(job *thesrc.Job) error {
+	defer s.observe(time.Now(), "Jobs.Enqueue")
+	now := time.Now()
+	job.ID, job.State, job.Attempts, job.LastError = 0, thesrc.JobPending, 0, ""
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = DefaultJobMaxAttempts
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	job.CreatedAt, job.UpdatedAt = now, now
+
+	// Check for a queued job with the same key first, because a unique
+	// violation aborts the transaction (if any) in PostgreSQL. The unique
+	// index catches concurrent enqueues.
+	if job.Key != "" {
+		var rows []*struct{ Count int }
+		if err := s.dbh.Select(&rows, `SELECT COUNT(*) AS count FROM job WHERE key=$1 AND state IN ('pending', 'running');`, job.Key); err != nil {
+			return err
+		}
+		if rows[0].Count > 0 {
+			return nil
+		}
+	}
+	if err := s.dbh.Insert(job); err != nil {
+		if job.Key != "" && isUniqueViolation(err, "job_key", "job.key") {
+			job.ID = 0
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *jobsStore) Claim(now time.Time, lease time.Duration) (*thesrc.Job, error) {
+	defer s.observe(time.Now(), "Jobs.Claim")
+	var jobs []*thesrc.Job
+	if err := s.dbh.Select(&jobs, `SELECT * FROM job WHERE state IN ('pending', 'running') AND runat<=$1 ORDER BY runat, id LIMIT 1;`, now); err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	job := jobs[0]
+
+	// If another worker claimed the job first, its runat is in the future,
+	// so this update doesn't match it.
+	res, err := s.dbh.Exec(`UPDATE job SET state='running', attempts=attempts+1, runat=$1, updatedat=$2 WHERE id=$3 AND state IN ('pending', 'running') AND runat<=$2;`, now.Add(lease), now, job.ID)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, nil
+	}
+	job.State, job.Attempts, job.RunAt, job.UpdatedAt = thesrc.JobRunning, job.Attempts+1, now.Add(lease), now
+	return job, nil
+}
+
+func (s *jobsStore) Complete(job *thesrc.Job) error {
+	defer s.observe(time.Now(), "Jobs.Complete")
+	// Each claim increments attempts, so it identifies job's lease (which
+	// expires at runat). Fail checks the lease the same way.
+	res, err := s.dbh.Exec(`UPDATE job SET state='done', lasterror='', updatedat=$1 WHERE id=$2 AND state='running' AND attempts=$3 AND runat>$1;`, time.Now(), job.ID, job.Attempts)
+	if err != nil {
+		return err
+	}
+	return checkJobLease(res)
+}
+
+func (s *jobsStore) Fail(job *thesrc.Job, errMsg string, retryAt time.Time) error {
+	defer s.observe(time.Now(), "Jobs.Fail")
+	res, err := s.dbh.Exec(`UPDATE job SET state=CASE WHEN attempts>=maxattempts THEN 'failed' ELSE 'pending' END, lasterror=$1, runat=$2, updatedat=$3 WHERE id=$4 AND state='running' AND attempts=$5 AND runat>$3;`, errMsg, retryAt, time.Now(), job.ID, job.Attempts)
+	if err != nil {
+		return err
+	}
+	return checkJobLease(res)
+}
+
+// checkJobLease returns thesrc.ErrJobLeaseExpired if res, the result of
+// updating a claimed job, shows that the job's lease had expired.
+func checkJobLease(res sql.Result) error {
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return thesrc.ErrJobLeaseExpired
+	}
+	return nil
+}
+
+func (s *jobsStore) List(opt *thesrc.JobListOptions) ([]*thesrc.Job, error) {
+	defer s.observe(time.Now(), "Jobs.List")
+	if opt == nil {
+		opt = &thesrc.JobListOptions{}
+	}
+	var jobs []*thesrc.Job
+	if err := s.dbh.Select(&jobs, `SELECT * FROM job WHERE ($1 = '' OR state=$1) ORDER BY updatedat DESC, id DESC LIMIT $2 OFFSET $3;`, opt.State, opt.PerPageOrDefault(), opt.Offset()); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (s *jobsStore) Retry(id int) error {
+	defer s.observe(time.Now(), "Jobs.Retry")
+	now := time.Now()
+	res, err := s.dbh.Exec(`UPDATE job SET state='pending', attempts=0, runat=$1, updatedat=$1 WHERE id=$2 AND state='failed';`, now, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return thesrc.ErrJobNotFound
+	}
+	return nil
+}
+
+func (s *jobsStore) DeleteDone(before time.Time) error {
+	defer s.observe(time.Now(), "Jobs.DeleteDone")
+	_, err := s.dbh.Exec(`DELETE FROM job WHERE state='done' AND updatedat<$1;`, before)
+	return err
+}
+
+type MockJobsStore struct {
+	Enqueue_    func(job *thesrc.Job) error
+	Claim_      func(now time.Time, lease time.Duration) (*thesrc.Job, error)
+	Complete_   func(job *thesrc.Job) error
+	Fail_       func(job *thesrc.Job, errMsg string, retryAt time.Time) error
+	List_       func(opt *thesrc.JobListOptions) ([]*thesrc.Job, error)
+	Retry_      func(id int) error
+	DeleteDone_ func(before time.Time) error
+}
+
+var _ JobsStore = &MockJobsStore{}
+
+func (s *MockJobsStore) Enqueue(job *thesrc.Job) error {
+	if s.Enqueue_ == nil {
+		return nil
+	}
+	return s.Enqueue_(job)
+}
+
+func (s *MockJobsStore) Claim(now time.Time, lease time.Duration) (*thesrc.Job, error) {
+	if s.Claim_ == nil {
+		return nil, nil
+	}
+	return s.Claim_(now, lease)
+}
+
+func (s *MockJobsStore) Complete(job *thesrc.Job) error {
+	if s.Complete_ == nil {
+		return nil
+	}
+	return s.Complete_(job)
+}
+
+func (s *MockJobsStore) Fail(job *thesrc.Job, errMsg string, retryAt time.Time) error {
+	if s.Fail_ == nil {
+		return nil
+	}
+	return s.Fail_(job, errMsg, retryAt)
+}
+
+func (s *MockJobsStore) List(opt *thesrc.JobListOptions) ([]*thesrc.Job, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
+
+func (s *MockJobsStore) Retry(id int) error {
+	if s.Retry_ == nil {
+		return nil
+	}
+	return s.Retry_(id)
+}
+
+func (s *MockJobsStore) DeleteDone(before time.Time) error {
+	if s.DeleteDone_ == nil {
+		return nil
+	}
+	return s.DeleteDone_(before)
+}
@@ -0,0 +1,136 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestJobsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM job;`) // test on a clean DB
+
+	testJobsStore(t, NewDatastore(tx).Jobs)
+}
+
+// testJobsStore tests a JobsStore implementation, which must be empty.
+func testJobsStore(t *testing.T, s JobsStore) {
+	job := &thesrc.Job{Kind: "test", Payload: `{}`, Key: "k", MaxAttempts: 2}
+	if err := s.Enqueue(job); err != nil {
+		t.Fatal(err)
+	}
+	if job.ID == 0 {
+		t.Fatal("want nonzero job.ID after enqueueing")
+	}
+	dup := &thesrc.Job{Kind: "test", Key: "k"}
+	if err := s.Enqueue(dup); err != nil {
+		t.Fatal(err)
+	}
+	if dup.ID != 0 {
+		t.Errorf("got job ID %d for a job with the same key as a pending job, want 0 (not queued)", dup.ID)
+	}
+
+	now := time.Now().Add(time.Second)
+	claimed, err := s.Claim(now, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed == nil || claimed.ID != job.ID || claimed.State != thesrc.JobRunning || claimed.Attempts != 1 {
+		t.Fatalf("got claimed job %+v, want job %d running on attempt 1", claimed, job.ID)
+	}
+	if claimed, err := s.Claim(now, time.Minute); err != nil {
+		t.Fatal(err)
+	} else if claimed != nil {
+		t.Errorf("got claimed job %+v while its lease hadn't expired, want none", claimed)
+	}
+
+	// The first failure queues a retry; the second is the last attempt.
+	if err := s.Fail(claimed, "x", now); err != nil {
+		t.Fatal(err)
+	}
+	claimed, err = s.Claim(now, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed == nil || claimed.Attempts != 2 || claimed.LastError != "x" {
+		t.Fatalf("got claimed job %+v, want the retried job on attempt 2", claimed)
+	}
+	if err := s.Fail(claimed, "y", now); err != nil {
+		t.Fatal(err)
+	}
+	failed, err := s.List(&thesrc.JobListOptions{State: thesrc.JobFailed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].ID != job.ID || failed[0].LastError != "y" {
+		t.Fatalf("got failed jobs %+v, want job %d", failed, job.ID)
+	}
+
+	if err := s.Retry(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Retry(job.ID); err != thesrc.ErrJobNotFound {
+		t.Errorf("got error %v retrying a job that isn't failed, want %v", err, thesrc.ErrJobNotFound)
+	}
+	claimed, err = s.Claim(now, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed == nil || claimed.Attempts != 1 {
+		t.Fatalf("got claimed job %+v, want the retried job on attempt 1", claimed)
+	}
+	if err := s.Complete(claimed); err != nil {
+		t.Fatal(err)
+	}
+	if done, _ := s.List(&thesrc.JobListOptions{State: thesrc.JobDone}); len(done) != 1 {
+		t.Errorf("got %d done jobs, want 1", len(done))
+	}
+
+	// A done job doesn't prevent queueing the same work again.
+	if err := s.Enqueue(dup); err != nil {
+		t.Fatal(err)
+	}
+	if dup.ID == 0 {
+		t.Error("want nonzero job.ID after enqueueing a job with the same key as a done job")
+	}
+
+	if err := s.DeleteDone(time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if jobs, _ := s.List(nil); len(jobs) != 1 || jobs[0].ID != dup.ID {
+		t.Errorf("got jobs %+v after deleting done jobs, want only job %d", jobs, dup.ID)
+	}
+
+	// A worker whose lease expired can't complete or fail its job, whether
+	// or not another worker has claimed the job since.
+	hourAgo := time.Now().Add(-time.Hour)
+	stale := &thesrc.Job{Kind: "test", Payload: `{}`, RunAt: hourAgo}
+	if err := s.Enqueue(stale); err != nil {
+		t.Fatal(err)
+	}
+	expired, err := s.Claim(hourAgo, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired == nil || expired.ID != stale.ID {
+		t.Fatalf("got claimed job %+v, want job %d", expired, stale.ID)
+	}
+	if err := s.Complete(expired); err != thesrc.ErrJobLeaseExpired {
+		t.Errorf("got error %v completing a job whose lease expired, want %v", err, thesrc.ErrJobLeaseExpired)
+	}
+	reclaimed, err := s.Claim(now, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reclaimed == nil || reclaimed.ID != stale.ID || reclaimed.Attempts != 2 {
+		t.Fatalf("got claimed job %+v, want job %d on attempt 2", reclaimed, stale.ID)
+	}
+	if err := s.Fail(expired, "x", now); err != thesrc.ErrJobLeaseExpired {
+		t.Errorf("got error %v failing a job that another worker claimed, want %v", err, thesrc.ErrJobLeaseExpired)
+	}
+	if err := s.Complete(reclaimed); err != nil {
+		t.Fatal(err)
+	}
+}
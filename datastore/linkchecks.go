@@ -0,0 +1,128 @@
+package datastore
+
+import (
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// LinkChecksStore tracks the dead link checker's checks of posts' link URLs,
+// and the archived snapshots of their pages. It is used by the link checker
+// and the archiver and is not exposed in the API (although the results are,
+// as fields of posts).
+type LinkChecksStore interface {
+	// ListDue lists up to n posts with link URLs that haven't been checked
+	// since before (or ever), those never checked first (newest first), and
+	// then the least recently checked.
+	ListDue(before time.Time, n int) ([]*thesrc.Post, error)
+
+	// Set records that a post's link URL was checked, setting its
+	// LinkStatus and LinkDead, and its LinkArchiveURL (if archiveURL is
+	// non-empty). The post won't be listed by ListDue until it is due again.
+	Set(postID, status int, dead bool, archiveURL string) error
+
+	// SetArchiveURL sets a post's LinkArchiveURL without recording a check.
+	SetArchiveURL(postID int, archiveURL string) error
+
+	// SetMetadata sets those of a post's LinkDescription, LinkImageURL, and
+	// LinkFaviconURL that are empty to the values in meta.
+	SetMetadata(postID int, meta *thesrc.LinkMetadata) error
+}
+
+type linkChecksStore struct{ *Datastore }
+
+func (s *linkChecksStore) ListDue(before time.Time, n int) ([]*thesrc.Post, error) {
+	defer s.observe(time.Now(), "LinkChecks.ListDue")
+	var posts []*thesrc.Post
+	err := s.dbh.Select(&posts, `SELECT post.* FROM post LEFT JOIN link_check ON link_check.postid=post.id WHERE post.linkurl <> '' AND post.deletedat IS NULL AND (link_check.checkedat IS NULL OR link_check.checkedat < $1) ORDER BY link_check.checkedat IS NOT NULL, link_check.checkedat, post.id DESC LIMIT $2;`, before, n)
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (s *linkChecksStore) Set(postID, status int, dead bool, archiveURL string) error {
+	defer s.observe(time.Now(), "LinkChecks.Set")
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		res, err := tx.Exec(`UPDATE post SET linkstatus=$1, linkdead=$2, linkarchiveurl=COALESCE(NULLIF($3, ''), linkarchiveurl) WHERE id=$4;`, status, dead, archiveURL, postID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return thesrc.ErrPostNotFound
+		}
+		if _, err := tx.Exec(`DELETE FROM link_check WHERE postid=$1;`, postID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO link_check(postid, checkedat) VALUES($1, $2);`, postID, time.Now())
+		return err
+	})
+}
+
+func (s *linkChecksStore) SetArchiveURL(postID int, archiveURL string) error {
+	defer s.observe(time.Now(), "LinkChecks.SetArchiveURL")
+	res, err := s.dbh.Exec(`UPDATE post SET linkarchiveurl=$1 WHERE id=$2;`, archiveURL, postID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return thesrc.ErrPostNotFound
+	}
+	return nil
+}
+
+func (s *linkChecksStore) SetMetadata(postID int, meta *thesrc.LinkMetadata) error {
+	defer s.observe(time.Now(), "LinkChecks.SetMetadata")
+	res, err := s.dbh.Exec(`UPDATE post SET linkdescription=COALESCE(NULLIF(linkdescription, ''), $1), linkimageurl=COALESCE(NULLIF(linkimageurl, ''), $2), linkfaviconurl=COALESCE(NULLIF(linkfaviconurl, ''), $3) WHERE id=$4;`, meta.Description, meta.ImageURL, meta.FaviconURL, postID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return thesrc.ErrPostNotFound
+	}
+	return nil
+}
+
+type MockLinkChecksStore struct {
+	ListDue_       func(before time.Time, n int) ([]*thesrc.Post, error)
+	Set_           func(postID, status int, dead bool, archiveURL string) error
+	SetArchiveURL_ func(postID int, archiveURL string) error
+	SetMetadata_   func(postID int, meta *thesrc.LinkMetadata) error
+}
+
+var _ LinkChecksStore = &MockLinkChecksStore{}
+
+func (s *MockLinkChecksStore) ListDue(before time.Time, n int) ([]*thesrc.Post, error) {
+	if s.ListDue_ == nil {
+		return nil, nil
+	}
+	return s.ListDue_(before, n)
+}
+
+func (s *MockLinkChecksStore) Set(postID, status int, dead bool, archiveURL string) error {
+	if s.Set_ == nil {
+		return nil
+	}
+	return s.Set_(postID, status, dead, archiveURL)
+}
+
+func (s *MockLinkChecksStore) SetArchiveURL(postID int, archiveURL string) error {
+	if s.SetArchiveURL_ == nil {
+		return nil
+	}
+	return s.SetArchiveURL_(postID, archiveURL)
+}
+
+func (s *MockLinkChecksStore) SetMetadata(postID int, meta *thesrc.LinkMetadata) error {
+	if s.SetMetadata_ == nil {
+		return nil
+	}
+	return s.SetMetadata_(postID, meta)
+}
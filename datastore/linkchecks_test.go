@@ -0,0 +1,115 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestLinkChecksStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM link_check;`)
+	for _, p := range []*thesrc.Post{{ID: 1, LinkURL: "http://example.com/1"}, {ID: 2, LinkURL: "http://example.com/2"}, {ID: 3}} {
+		if err := tx.Insert(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testLinkChecksStore(t, NewDatastore(tx))
+}
+
+func TestMemoryDatastore_LinkChecks(t *testing.T) {
+	d := NewMemoryDatastore()
+	for _, p := range []*thesrc.Post{{LinkURL: "http://example.com/1"}, {LinkURL: "http://example.com/2"}, {}} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testLinkChecksStore(t, d)
+}
+
+// testLinkChecksStore tests d.LinkChecks, given posts 1 and 2 with link URLs
+// and post 3 without one.
+func testLinkChecksStore(t *testing.T, d *Datastore) {
+	dueIDs := func(before time.Time) []int {
+		posts, err := d.LinkChecks.ListDue(before, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, p := range posts {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	if got, want := dueIDs(time.Now()), []int{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got due post IDs %v, want %v", got, want)
+	}
+
+	if err := d.LinkChecks.Set(1, 404, true, "http://web.archive.org/web/2014/http://example.com/1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.LinkChecks.Set(2, 200, false, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := dueIDs(time.Now().Add(-time.Hour)); len(got) != 0 {
+		t.Errorf("got due post IDs %v after checking links, want none", got)
+	}
+	if got, want := dueIDs(time.Now().Add(time.Hour)), []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got due post IDs %v (least recently checked first), want %v", got, want)
+	}
+
+	// An empty archive URL leaves the previous one.
+	if err := d.LinkChecks.Set(1, 410, true, ""); err != nil {
+		t.Fatal(err)
+	}
+	p, err := d.Posts.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.LinkStatus != 410 || !p.LinkDead || p.LinkArchiveURL != "http://web.archive.org/web/2014/http://example.com/1" {
+		t.Errorf("got post %+v, want dead link with status 410 and archive URL", p)
+	}
+
+	if err := d.LinkChecks.Set(123, 200, false, ""); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+
+	// Setting the archive URL doesn't record a check.
+	if err := d.LinkChecks.SetArchiveURL(2, "http://web.archive.org/web/2014/http://example.com/2"); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := d.Posts.Get(2); err != nil {
+		t.Fatal(err)
+	} else if p.LinkArchiveURL != "http://web.archive.org/web/2014/http://example.com/2" {
+		t.Errorf("got LinkArchiveURL %q, want the archive URL", p.LinkArchiveURL)
+	}
+	if got, want := dueIDs(time.Now().Add(time.Hour)), []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got due post IDs %v after setting archive URL, want %v", got, want)
+	}
+	if err := d.LinkChecks.SetArchiveURL(123, ""); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+
+	// Setting metadata fills in only the empty fields.
+	if err := d.LinkChecks.SetMetadata(2, &thesrc.LinkMetadata{Description: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.LinkChecks.SetMetadata(2, &thesrc.LinkMetadata{Description: "d2", ImageURL: "http://example.com/i.png"}); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := d.Posts.Get(2); err != nil {
+		t.Fatal(err)
+	} else if p.LinkDescription != "d" || p.LinkImageURL != "http://example.com/i.png" {
+		t.Errorf("got LinkDescription %q and LinkImageURL %q, want %q and the image URL", p.LinkDescription, p.LinkImageURL, "d")
+	}
+	if err := d.LinkChecks.SetMetadata(123, &thesrc.LinkMetadata{}); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
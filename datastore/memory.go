@@ -0,0 +1,2177 @@
+package datastore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/events"
+)
+
+// NewMemoryDatastore creates a new datastore that keeps all data in memory,
+// for demos and tests that shouldn't require a PostgreSQL database. Its
+// contents are lost when the process exits.
+func NewMemoryDatastore() *Datastore {
+	db := &memoryDB{
+		posts:        map[int]*thesrc.Post{},
+		deletedPosts: map[int]*thesrc.Post{},
+		comments:     map[int]*thesrc.Comment{},
+		users:        map[int]*thesrc.User{},
+		votes:        map[[2]int]*memoryVote{},
+		commentVotes: map[[2]int]*memoryVote{},
+		flags:        map[[2]int]bool{},
+		saves:        map[[2]int]bool{},
+		hides:        map[[2]int]bool{},
+
+		thumbnailAttempts: map[int]bool{},
+		linkChecks:        map[int]time.Time{},
+		tokens:            map[int]*thesrc.Token{},
+		sessions:          map[int]*thesrc.Session{},
+		follows:           map[int][]*thesrc.Follow{},
+		webhooks:          map[int]*thesrc.Webhook{},
+		notifications:     map[int]*thesrc.Notification{},
+		jobs:              map[int]*thesrc.Job{},
+		taskLocks:         map[string]time.Time{},
+		voteSuspects:      map[int]*thesrc.VoteSuspect{},
+		clientRecords:     map[int]*thesrc.ClientRecord{},
+		clientSalts:       map[int64][]byte{},
+		auditLog:          map[int]*thesrc.AuditEntry{},
+		postRevisions:     map[int]*thesrc.PostRevision{},
+		reports:           map[int]*thesrc.Report{},
+
+		events: events.NewHub(),
+	}
+	return &Datastore{
+		Posts:         &memoryPostsStore{db},
+		Comments:      &memoryCommentsStore{db},
+		Users:         &memoryUsersStore{db},
+		Votes:         &memoryVotesStore{db},
+		Flags:         &memoryFlagsStore{db},
+		Saves:         &memorySavesStore{db},
+		Hides:         &memoryHidesStore{db},
+		Tags:          &memoryTagsStore{db},
+		Domains:       &memoryDomainsStore{db},
+		Thumbnails:    &memoryThumbnailsStore{db},
+		LinkChecks:    &memoryLinkChecksStore{db},
+		Trending:      &memoryTrendingStore{db},
+		Tokens:        &memoryTokensStore{db},
+		Sessions:      &memorySessionsStore{db},
+		Follows:       &memoryFollowsStore{db},
+		Webhooks:      &memoryWebhooksStore{db},
+		Notifications: &memoryNotificationsStore{db},
+		Jobs:          &memoryJobsStore{db},
+		TaskLocks:     &memoryTaskLocksStore{db},
+		VoteSuspects:  &memoryVoteSuspectsStore{db},
+		ClientRecords: &memoryClientRecordsStore{db},
+		AuditLog:      &memoryAuditLogStore{db},
+		Moderation:    &memoryModerationStore{db},
+		DeletedPosts:  &memoryDeletedPostsStore{db},
+		PostRevisions: &memoryPostRevisionsStore{db},
+		Reports:       &memoryReportsStore{db},
+		Events:        db.events,
+	}
+}
+
+// memoryDB holds the data of an in-memory datastore. Stored values are
+// copies, so callers may modify values that they pass in or get back.
+type memoryDB struct {
+	mu sync.Mutex
+
+	posts        map[int]*thesrc.Post
+	deletedPosts map[int]*thesrc.Post // deleted posts, which aren't in posts
+	comments     map[int]*thesrc.Comment
+	users        map[int]*thesrc.User
+	votes        map[[2]int]*memoryVote // keyed by {userID, postID}
+	commentVotes map[[2]int]*memoryVote // keyed by {userID, commentID}
+	flags        map[[2]int]bool        // keyed by {userID, postID}
+	saves        map[[2]int]bool        // keyed by {userID, postID}
+	hides        map[[2]int]bool        // keyed by {userID, postID}
+
+	thumbnailAttempts map[int]bool      // keyed by post ID
+	linkChecks        map[int]time.Time // keyed by post ID
+	tokens            map[int]*thesrc.Token
+	sessions          map[int]*thesrc.Session
+	follows           map[int][]*thesrc.Follow // keyed by user ID, oldest first
+	webhooks          map[int]*thesrc.Webhook
+	webhookDeliveries []*thesrc.WebhookDelivery // oldest first
+	notifications     map[int]*thesrc.Notification
+	jobs              map[int]*thesrc.Job
+	taskLocks         map[string]time.Time        // expiration, keyed by task name
+	voteSuspects      map[int]*thesrc.VoteSuspect // keyed by user ID
+	clientRecords     map[int]*thesrc.ClientRecord
+	clientSalts       map[int64][]byte // keyed by period start (Unix seconds)
+	auditLog          map[int]*thesrc.AuditEntry
+	postRevisions     map[int]*thesrc.PostRevision
+	reports           map[int]*thesrc.Report
+
+	lastID int // shared by all tables
+
+	events *events.Hub
+}
+
+func (db *memoryDB) nextID() int {
+	db.lastID++
+	return db.lastID
+}
+
+// shadowBanned returns whether the user with the given ID is shadow-banned.
+// db.mu must be held.
+func (db *memoryDB) shadowBanned(userID int) bool {
+	user, present := db.users[userID]
+	return present && user.ShadowBanned
+}
+
+// votesNullified returns whether the user with the given ID is a vote
+// suspect whose votes are nullified. db.mu must be held.
+func (db *memoryDB) votesNullified(userID int) bool {
+	suspect, present := db.voteSuspects[userID]
+	return present && suspect.Nullified
+}
+
+type memoryPostsStore struct{ *memoryDB }
+
+var _ thesrc.PostsService = &memoryPostsStore{}
+
+func copyPost(p *thesrc.Post) *thesrc.Post {
+	p2 := *p
+	p2.Tags = append([]string(nil), p.Tags...)
+	if len(p2.Tags) == 0 {
+		p2.Tags = nil
+	}
+	return &p2
+}
+
+func (s *memoryPostsStore) Get(id int) (*thesrc.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	post, present := s.posts[id]
+	if !present {
+		return nil, thesrc.ErrPostNotFound
+	}
+	return copyPost(post), nil
+}
+
+func (s *memoryPostsStore) List(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+	if opt == nil {
+		opt = &thesrc.PostListOptions{}
+	}
+
+	if !thesrc.ValidPeriod(opt.Period) {
+		return nil, fmt.Errorf("invalid period %q", opt.Period)
+	}
+	var since time.Time
+	if d := thesrc.PeriodDuration(opt.Period); d != 0 {
+		since = time.Now().Add(-d)
+	}
+	cursor, err := opt.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := s.posts
+	if opt.Deleted {
+		all = s.deletedPosts
+	}
+	var posts []*thesrc.Post
+	for _, p := range all {
+		if opt.CodeOnly && !strings.HasPrefix(p.Classification, "CODE") && p.LinkURL != "" {
+			continue
+		}
+		if opt.Tag != "" && !containsString(p.Tags, opt.Tag) {
+			continue
+		}
+		if opt.Domain != "" && p.Domain != thesrc.NormalizeDomain(opt.Domain) {
+			continue
+		}
+		if opt.LinkURL != "" && p.LinkURL != opt.LinkURL {
+			continue
+		}
+		if opt.AuthorUserID != 0 && p.AuthorUserID != opt.AuthorUserID {
+			continue
+		}
+		if opt.SavedByUserID != 0 && !s.saves[[2]int{opt.SavedByUserID, p.ID}] {
+			continue
+		}
+		if opt.ExcludeHiddenByUserID != 0 && s.hides[[2]int{opt.ExcludeHiddenByUserID, p.ID}] {
+			continue
+		}
+		if p.ID <= opt.SinceID {
+			continue
+		}
+		if cursor != nil && !cursor.After(p) {
+			continue
+		}
+		if p.SubmittedAt.Before(since) {
+			continue
+		}
+		if opt.Show && !thesrc.IsShowPost(p) {
+			continue
+		}
+		if !matchesSearchTerms(p, opt.SearchTerms) {
+			continue
+		}
+		if !opt.Deleted && (opt.Flagged && p.Flags == 0 && p.Reports == 0 && p.SpamScore == 0 && !p.Queued || !opt.Flagged && (p.Hidden || p.Dead)) {
+			continue
+		}
+		if !opt.Flagged && !opt.Deleted && p.AuthorUserID != opt.ViewerUserID && s.shadowBanned(p.AuthorUserID) {
+			continue
+		}
+		posts = append(posts, p)
+	}
+
+	newer := func(i, j int) bool {
+		if !posts[i].SubmittedAt.Equal(posts[j].SubmittedAt) {
+			return posts[i].SubmittedAt.After(posts[j].SubmittedAt)
+		}
+		return posts[i].ID > posts[j].ID
+	}
+	switch opt.Sort {
+	case "", thesrc.SortNew:
+		sort.Slice(posts, newer)
+	case thesrc.SortTop:
+		now := time.Now()
+		follows := s.follows[opt.PersonalizedForUserID]
+		rank := func(p *thesrc.Post) float64 {
+			r := float64(p.Score-1) / math.Pow(now.Sub(p.SubmittedAt).Hours()+2, 1.8)
+			if followsTopicOf(follows, p, true) {
+				r *= hiddenTopicFactor
+			} else if followsTopicOf(follows, p, false) {
+				r *= followedTopicFactor
+			}
+			return r
+		}
+		sort.Slice(posts, func(i, j int) bool {
+			if ri, rj := rank(posts[i]), rank(posts[j]); ri != rj {
+				return ri > rj
+			}
+			return newer(i, j)
+		})
+	case thesrc.SortBest:
+		sort.Slice(posts, func(i, j int) bool {
+			if posts[i].Score != posts[j].Score {
+				return posts[i].Score > posts[j].Score
+			}
+			return newer(i, j)
+		})
+	case thesrc.SortTrending:
+		sort.Slice(posts, func(i, j int) bool {
+			if posts[i].Velocity != posts[j].Velocity {
+				return posts[i].Velocity > posts[j].Velocity
+			}
+			return newer(i, j)
+		})
+	default:
+		return nil, fmt.Errorf("invalid sort order %q", opt.Sort)
+	}
+
+	pageOpt := opt.ListOptions
+	if cursor != nil {
+		pageOpt.Page = 0
+	}
+	start, end := pageBounds(len(posts), pageOpt)
+	posts = posts[start:end]
+	for i, p := range posts {
+		posts[i] = copyPost(p)
+	}
+	return posts, nil
+}
+
+// followsTopicOf reports whether follows include a follow (or, if hide is
+// true, a hide) of one of p's topics, like the SQL store's
+// followedTopicCond.
+func followsTopicOf(follows []*thesrc.Follow, p *thesrc.Post, hide bool) bool {
+	for _, f := range follows {
+		if f.Hide != hide {
+			continue
+		}
+		if f.Kind == thesrc.FollowDomain && f.Name == p.Domain || f.Kind == thesrc.FollowTag && containsString(p.Tags, f.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesSearchTerms reports whether p's title or body contains each of
+// terms (case-insensitively), like the SQL store's search.
+func matchesSearchTerms(p *thesrc.Post, terms []string) bool {
+	title, body := strings.ToLower(p.Title), strings.ToLower(p.Body)
+	for _, term := range terms {
+		term = strings.ToLower(term)
+		if !strings.Contains(title, term) && !strings.Contains(body, term) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *memoryPostsStore) Submit(post *thesrc.Post) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.submit(post), nil
+}
+
+// submit is like Submit, but s.mu must be held.
+func (s *memoryPostsStore) submit(post *thesrc.Post) bool {
+	for _, p := range s.posts {
+		if post.LinkURL != "" && p.LinkURL == post.LinkURL || post.IdempotencyKey != "" && p.AuthorUserID == post.AuthorUserID && p.IdempotencyKey == post.IdempotencyKey {
+			*post = *copyPost(p)
+			return false
+		}
+	}
+
+	post.ID = s.nextID()
+	post.Domain = thesrc.LinkDomain(post.LinkURL)
+	post.Version = 1
+	if post.SubmittedAt.IsZero() {
+		post.SubmittedAt = time.Now()
+	}
+	s.posts[post.ID] = copyPost(post)
+	s.events.Publish(&thesrc.Event{Type: thesrc.EventPostCreated, Post: copyPost(post)})
+	return true
+}
+
+func (s *memoryPostsStore) CreateBatch(posts []*thesrc.Post) ([]*thesrc.PostBatchResult, error) {
+	if len(posts) > thesrc.MaxBatchSize {
+		return nil, fmt.Errorf("batch of %d posts exceeds maximum of %d", len(posts), thesrc.MaxBatchSize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]*thesrc.PostBatchResult, len(posts))
+	for i, post := range posts {
+		results[i] = &thesrc.PostBatchResult{Post: post, Created: s.submit(post)}
+	}
+	return results, nil
+}
+
+func (s *memoryPostsStore) Update(id int, post *thesrc.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, present := s.posts[id]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+	if post.Version != 0 && post.Version != p.Version {
+		return thesrc.ErrPostVersionConflict
+	}
+	p.Version++
+	if p.Title != post.Title || p.Body != post.Body {
+		now := time.Now()
+		p.EditedAt = &now
+	}
+	p.Title = post.Title
+	p.Body = post.Body
+	p.Tags = copyPost(post).Tags
+	*post = *copyPost(p)
+	s.events.Publish(&thesrc.Event{Type: thesrc.EventPostUpdated, Post: copyPost(p)})
+	return nil
+}
+
+func (s *memoryPostsStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, present := s.posts[id]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+	now := time.Now()
+	p.DeletedAt = &now
+	delete(s.posts, id)
+	s.deletedPosts[id] = p
+	return nil
+}
+
+func (s *memoryPostsStore) Undelete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, present := s.deletedPosts[id]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+	for _, p2 := range s.posts {
+		if p.LinkURL != "" && p2.LinkURL == p.LinkURL {
+			return thesrc.ErrPostLinkURLTaken
+		}
+	}
+	for _, p2 := range s.posts {
+		if p.IdempotencyKey != "" && p2.AuthorUserID == p.AuthorUserID && p2.IdempotencyKey == p.IdempotencyKey {
+			p.IdempotencyKey = ""
+		}
+	}
+	p.DeletedAt = nil
+	delete(s.deletedPosts, id)
+	s.posts[id] = p
+	return nil
+}
+
+func (s *memoryPostsStore) Preview(post *thesrc.Post) (*thesrc.PostPreview, error) {
+	return nil, errPreviewNotSupported
+}
+
+func (s *memoryPostsStore) Flag(id int) error {
+	return errFlagWithoutUser
+}
+
+func (s *memoryPostsStore) Save(id int) error {
+	return errSaveWithoutUser
+}
+
+func (s *memoryPostsStore) Unsave(id int) error {
+	return errSaveWithoutUser
+}
+
+func (s *memoryPostsStore) Hide(id int) error {
+	return errHideWithoutUser
+}
+
+func (s *memoryPostsStore) Unhide(id int) error {
+	return errHideWithoutUser
+}
+
+func (s *memoryPostsStore) Related(id int, opt *thesrc.RelatedPostsOptions) ([]*thesrc.Post, error) {
+	if opt == nil {
+		opt = &thesrc.RelatedPostsOptions{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, present := s.posts[id]
+	if !present {
+		return nil, thesrc.ErrPostNotFound
+	}
+
+	// Rank posts as the SQL store does.
+	rank := map[int]float64{}
+	var posts []*thesrc.Post
+	for _, p := range s.posts {
+		if p.ID == id || p.Hidden || p.Dead || (p.AuthorUserID != opt.ViewerUserID && s.shadowBanned(p.AuthorUserID)) {
+			continue
+		}
+		var sharedTags int
+		for _, tag := range p.Tags {
+			if containsString(post.Tags, tag) {
+				sharedTags++
+			}
+		}
+		sameDomain := post.Domain != "" && p.Domain == post.Domain
+		titleSimilarity := similarity(p.Title, post.Title)
+		if sharedTags == 0 && !sameDomain && titleSimilarity < minTitleSimilarity {
+			continue
+		}
+		rank[p.ID] = float64(sharedTags)*relatedTagWeight + titleSimilarity*relatedTitleWeight
+		if sameDomain {
+			rank[p.ID] += relatedDomainWeight
+		}
+		posts = append(posts, p)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if ri, rj := rank[posts[i].ID], rank[posts[j].ID]; ri != rj {
+			return ri > rj
+		}
+		if posts[i].Score != posts[j].Score {
+			return posts[i].Score > posts[j].Score
+		}
+		return posts[i].ID > posts[j].ID
+	})
+
+	start, end := pageBounds(len(posts), opt.ListOptions)
+	posts = posts[start:end]
+	for i, p := range posts {
+		posts[i] = copyPost(p)
+	}
+	return posts, nil
+}
+
+func (s *memoryPostsStore) Revisions(id int) ([]*thesrc.PostRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var revisions []*thesrc.PostRevision
+	for _, rev := range s.postRevisions {
+		if rev.PostID == id {
+			rev2 := *rev
+			revisions = append(revisions, &rev2)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		if !revisions[i].CreatedAt.Equal(revisions[j].CreatedAt) {
+			return revisions[i].CreatedAt.Before(revisions[j].CreatedAt)
+		}
+		return revisions[i].ID < revisions[j].ID
+	})
+	return revisions, nil
+}
+
+func (s *memoryPostsStore) Moderate(id int, mod *thesrc.PostModeration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, present := s.posts[id]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+	if mod.Version != 0 && mod.Version != p.Version {
+		return thesrc.ErrPostVersionConflict
+	}
+	p.Version++
+	p.Hidden = mod.Hidden
+	p.Dead = mod.Dead
+	p.Queued = false
+	p.Reports = 0
+	return nil
+}
+
+type memoryCommentsStore struct{ *memoryDB }
+
+var _ thesrc.CommentsService = &memoryCommentsStore{}
+
+func (s *memoryCommentsStore) Get(id int) (*thesrc.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	comment, present := s.comments[id]
+	if !present {
+		return nil, thesrc.ErrCommentNotFound
+	}
+	c := *comment
+	return &c, nil
+}
+
+func (s *memoryCommentsStore) ListForPost(postID int) ([]*thesrc.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, deleted := s.deletedPosts[postID]; deleted {
+		return nil, nil
+	}
+	var comments []*thesrc.Comment
+	for _, comment := range s.comments {
+		if comment.PostID == postID {
+			c := *comment
+			comments = append(comments, &c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if comments[i].Score != comments[j].Score {
+			return comments[i].Score > comments[j].Score
+		}
+		if !comments[i].SubmittedAt.Equal(comments[j].SubmittedAt) {
+			return comments[i].SubmittedAt.Before(comments[j].SubmittedAt)
+		}
+		return comments[i].ID < comments[j].ID
+	})
+	return comments, nil
+}
+
+func (s *memoryCommentsStore) List(opt *thesrc.CommentListOptions) ([]*thesrc.Comment, error) {
+	if opt == nil {
+		opt = &thesrc.CommentListOptions{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var comments []*thesrc.Comment
+	for _, comment := range s.comments {
+		if _, deleted := s.deletedPosts[comment.PostID]; deleted {
+			continue
+		}
+		if opt.AuthorUserID == 0 || comment.AuthorUserID == opt.AuthorUserID {
+			c := *comment
+			comments = append(comments, &c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].SubmittedAt.Equal(comments[j].SubmittedAt) {
+			return comments[i].SubmittedAt.After(comments[j].SubmittedAt)
+		}
+		return comments[i].ID > comments[j].ID
+	})
+	start, end := pageBounds(len(comments), opt.ListOptions)
+	return comments[start:end], nil
+}
+
+func (s *memoryCommentsStore) Create(comment *thesrc.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, present := s.posts[comment.PostID]; !present {
+		return thesrc.ErrPostNotFound
+	}
+	if comment.ParentID != 0 {
+		parent, present := s.comments[comment.ParentID]
+		if !present {
+			return thesrc.ErrCommentNotFound
+		}
+		if parent.PostID != comment.PostID {
+			return errParentOnOtherPost
+		}
+	}
+
+	comment.ID = s.nextID()
+	if comment.SubmittedAt.IsZero() {
+		comment.SubmittedAt = time.Now()
+	}
+	c := *comment
+	s.comments[c.ID] = &c
+	return nil
+}
+
+type memoryUsersStore struct{ *memoryDB }
+
+func (s *memoryUsersStore) Get(id int) (*thesrc.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, present := s.users[id]
+	if !present {
+		return nil, thesrc.ErrUserNotFound
+	}
+	u := *user
+	return &u, nil
+}
+
+func (s *memoryUsersStore) GetByLogin(login string) (*thesrc.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if strings.EqualFold(user.Login, login) {
+			u := *user
+			return &u, nil
+		}
+	}
+	return nil, thesrc.ErrUserNotFound
+}
+
+func (s *memoryUsersStore) GetByEmail(email string) (*thesrc.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var first *thesrc.User
+	for _, user := range s.users {
+		if strings.EqualFold(user.Email, email) && (first == nil || user.ID < first.ID) {
+			first = user
+		}
+	}
+	if first == nil {
+		return nil, thesrc.ErrUserNotFound
+	}
+	u := *first
+	return &u, nil
+}
+
+func (s *memoryUsersStore) Create(user *thesrc.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if strings.EqualFold(u.Login, user.Login) {
+			return ErrLoginTaken
+		}
+	}
+
+	user.ID = s.nextID()
+	if user.RegisteredAt.IsZero() {
+		user.RegisteredAt = time.Now()
+	}
+	if user.Role == "" {
+		user.Role = thesrc.RoleMember
+	}
+	u := *user
+	s.users[u.ID] = &u
+	return nil
+}
+
+func (s *memoryUsersStore) Karma(userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var karma int
+	for _, p := range s.posts {
+		if p.AuthorUserID == userID {
+			karma += p.Score
+		}
+	}
+	for _, c := range s.comments {
+		if _, deleted := s.deletedPosts[c.PostID]; deleted {
+			continue
+		}
+		if c.AuthorUserID == userID {
+			karma += c.Score
+		}
+	}
+	return karma, nil
+}
+
+func (s *memoryUsersStore) SetRole(userID int, role string) error {
+	if !thesrc.ValidRole(role) {
+		return fmt.Errorf("invalid role %q", role)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, present := s.users[userID]
+	if !present {
+		return thesrc.ErrUserNotFound
+	}
+	user.Role = role
+	return nil
+}
+
+func (s *memoryUsersStore) SetShadowBanned(userID int, banned bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, present := s.users[userID]
+	if !present {
+		return thesrc.ErrUserNotFound
+	}
+	user.ShadowBanned = banned
+	return nil
+}
+
+func (s *memoryUsersStore) UpdateSettings(userID int, settings *thesrc.UserSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, present := s.users[userID]
+	if !present {
+		return thesrc.ErrUserNotFound
+	}
+	user.DisplayName = settings.DisplayName
+	user.Email = settings.Email
+	user.DigestSubscribed = settings.DigestSubscribed
+	user.Theme = settings.Theme
+	user.DefaultSort = settings.DefaultSort
+	return nil
+}
+
+func (s *memoryUsersStore) SetPasswordHash(userID int, hash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, present := s.users[userID]
+	if !present {
+		return thesrc.ErrUserNotFound
+	}
+	user.PasswordHash = append([]byte(nil), hash...)
+	user.PasswordChangedAt = time.Now()
+	return nil
+}
+
+func (s *memoryUsersStore) SetTwoFactor(userID int, secret, recoveryCodeHashes string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, present := s.users[userID]
+	if !present {
+		return thesrc.ErrUserNotFound
+	}
+	user.TOTPSecret = secret
+	user.RecoveryCodeHashes = recoveryCodeHashes
+	return nil
+}
+
+// A memoryVote is a user's upvote of a post or comment.
+type memoryVote struct {
+	// shadow is whether the vote was cast while the user was shadow-banned
+	// (and so wasn't counted in the post's or comment's score).
+	shadow bool
+
+	// nullified is whether the vote (on a post) was nullified as vote fraud
+	// (and so isn't counted in the post's score).
+	nullified bool
+
+	votedAt time.Time
+}
+
+// counted returns whether the vote counts toward its post's or comment's
+// score.
+func (v *memoryVote) counted() bool { return !v.shadow && !v.nullified }
+
+type memoryVotesStore struct{ *memoryDB }
+
+func (s *memoryVotesStore) Upvote(userID, postID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, present := s.posts[postID]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+	if key := [2]int{userID, postID}; s.votes[key] == nil {
+		v := &memoryVote{shadow: s.shadowBanned(userID), nullified: s.votesNullified(userID), votedAt: time.Now()}
+		s.votes[key] = v
+		if v.counted() {
+			post.Score++
+			s.events.Publish(&thesrc.Event{Type: thesrc.EventPostScore, Post: copyPost(post)})
+		}
+	}
+	return nil
+}
+
+func (s *memoryVotesStore) Unvote(userID, postID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key := [2]int{userID, postID}; s.votes[key] != nil {
+		counted := s.votes[key].counted()
+		delete(s.votes, key)
+		if post, present := s.posts[postID]; present && counted {
+			post.Score--
+			s.events.Publish(&thesrc.Event{Type: thesrc.EventPostScore, Post: copyPost(post)})
+		}
+	}
+	return nil
+}
+
+func (s *memoryVotesStore) Voted(userID int, postIDs []int) (map[int]bool, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	voted := map[int]bool{}
+	for _, postID := range postIDs {
+		if s.votes[[2]int{userID, postID}] != nil {
+			voted[postID] = true
+		}
+	}
+	return voted, nil
+}
+
+func (s *memoryVotesStore) UpvoteComment(userID, commentID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, present := s.comments[commentID]
+	if !present {
+		return thesrc.ErrCommentNotFound
+	}
+	if key := [2]int{userID, commentID}; s.commentVotes[key] == nil {
+		shadow := s.shadowBanned(userID)
+		s.commentVotes[key] = &memoryVote{shadow: shadow, votedAt: time.Now()}
+		if !shadow {
+			comment.Score++
+		}
+	}
+	return nil
+}
+
+func (s *memoryVotesStore) UnvoteComment(userID, commentID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key := [2]int{userID, commentID}; s.commentVotes[key] != nil {
+		shadow := s.commentVotes[key].shadow
+		delete(s.commentVotes, key)
+		if comment, present := s.comments[commentID]; present && !shadow {
+			comment.Score--
+		}
+	}
+	return nil
+}
+
+func (s *memoryVotesStore) VotedComments(userID int, commentIDs []int) (map[int]bool, error) {
+	if len(commentIDs) == 0 {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	voted := map[int]bool{}
+	for _, commentID := range commentIDs {
+		if s.commentVotes[[2]int{userID, commentID}] != nil {
+			voted[commentID] = true
+		}
+	}
+	return voted, nil
+}
+
+type memorySavesStore struct{ *memoryDB }
+
+func (s *memorySavesStore) Save(userID, postID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, present := s.posts[postID]; !present {
+		return thesrc.ErrPostNotFound
+	}
+	s.saves[[2]int{userID, postID}] = true
+	return nil
+}
+
+func (s *memorySavesStore) Unsave(userID, postID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.saves, [2]int{userID, postID})
+	return nil
+}
+
+func (s *memorySavesStore) Saved(userID int, postIDs []int) (map[int]bool, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := map[int]bool{}
+	for _, postID := range postIDs {
+		if s.saves[[2]int{userID, postID}] {
+			saved[postID] = true
+		}
+	}
+	return saved, nil
+}
+
+type memoryHidesStore struct{ *memoryDB }
+
+func (s *memoryHidesStore) Hide(userID, postID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, present := s.posts[postID]; !present {
+		return thesrc.ErrPostNotFound
+	}
+	s.hides[[2]int{userID, postID}] = true
+	return nil
+}
+
+func (s *memoryHidesStore) Unhide(userID, postID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.hides, [2]int{userID, postID})
+	return nil
+}
+
+func (s *memoryHidesStore) Hidden(userID int, postIDs []int) (map[int]bool, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hidden := map[int]bool{}
+	for _, postID := range postIDs {
+		if s.hides[[2]int{userID, postID}] {
+			hidden[postID] = true
+		}
+	}
+	return hidden, nil
+}
+
+type memoryFlagsStore struct{ *memoryDB }
+
+func (s *memoryFlagsStore) Flag(userID, postID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, present := s.posts[postID]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+	if key := [2]int{userID, postID}; !s.flags[key] {
+		s.flags[key] = true
+		post.Flags++
+		s.events.Publish(&thesrc.Event{Type: thesrc.EventPostFlagged, Post: copyPost(post)})
+	}
+	return nil
+}
+
+type memoryTagsStore struct{ *memoryDB }
+
+var _ thesrc.TagsService = &memoryTagsStore{}
+
+func (s *memoryTagsStore) List(opt *thesrc.TagListOptions) ([]*thesrc.Tag, error) {
+	if opt == nil {
+		opt = &thesrc.TagListOptions{}
+	}
+	prefix := strings.ToLower(opt.Prefix)
+
+	s.mu.Lock()
+	counts := map[string]int{}
+	for _, p := range s.posts {
+		for _, name := range p.Tags {
+			if strings.HasPrefix(name, prefix) {
+				counts[name]++
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	tags := make([]*thesrc.Tag, 0, len(counts))
+	for name, n := range counts {
+		tags = append(tags, &thesrc.Tag{Name: name, NumPosts: n})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].NumPosts != tags[j].NumPosts {
+			return tags[i].NumPosts > tags[j].NumPosts
+		}
+		return tags[i].Name < tags[j].Name
+	})
+
+	start, end := pageBounds(len(tags), opt.ListOptions)
+	return tags[start:end], nil
+}
+
+type memoryDomainsStore struct{ *memoryDB }
+
+var _ thesrc.DomainsService = &memoryDomainsStore{}
+
+func (s *memoryDomainsStore) Get(domain string) (*thesrc.DomainStats, error) {
+	domain = thesrc.NormalizeDomain(domain)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := &thesrc.DomainStats{Domain: domain}
+	var total int
+	for _, p := range s.posts {
+		if p.Domain != domain {
+			continue
+		}
+		if p.Hidden || p.Dead {
+			st.NumHidden++
+			continue
+		}
+		st.NumPosts++
+		total += p.Score
+	}
+	if st.NumPosts > 0 {
+		st.AverageScore = float64(total) / float64(st.NumPosts)
+	}
+	return st, nil
+}
+
+// pageBounds returns the bounds of the page (specified by opt) of a list of n
+// results.
+func pageBounds(n int, opt thesrc.ListOptions) (start, end int) {
+	start = opt.Offset()
+	if start > n {
+		start = n
+	}
+	end = start + opt.PerPageOrDefault()
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+type memoryThumbnailsStore struct{ *memoryDB }
+
+func (s *memoryThumbnailsStore) ListPending(n int) ([]*thesrc.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var posts []*thesrc.Post
+	for _, p := range s.posts {
+		if p.LinkURL != "" && !s.thumbnailAttempts[p.ID] {
+			posts = append(posts, copyPost(p))
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool { return posts[i].ID > posts[j].ID })
+	if len(posts) > n {
+		posts = posts[:n]
+	}
+	return posts, nil
+}
+
+func (s *memoryThumbnailsStore) Set(postID int, url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, present := s.posts[postID]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+	p.ThumbnailURL = url
+	s.thumbnailAttempts[postID] = true
+	return nil
+}
+
+type memoryLinkChecksStore struct{ *memoryDB }
+
+func (s *memoryLinkChecksStore) ListDue(before time.Time, n int) ([]*thesrc.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var posts []*thesrc.Post
+	for _, p := range s.posts {
+		if checkedAt, checked := s.linkChecks[p.ID]; p.LinkURL != "" && (!checked || checkedAt.Before(before)) {
+			posts = append(posts, copyPost(p))
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		ti, ci := s.linkChecks[posts[i].ID]
+		tj, cj := s.linkChecks[posts[j].ID]
+		if ci != cj {
+			return !ci
+		}
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	if len(posts) > n {
+		posts = posts[:n]
+	}
+	return posts, nil
+}
+
+func (s *memoryLinkChecksStore) Set(postID, status int, dead bool, archiveURL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, present := s.posts[postID]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+	p.LinkStatus, p.LinkDead = status, dead
+	if archiveURL != "" {
+		p.LinkArchiveURL = archiveURL
+	}
+	s.linkChecks[postID] = time.Now()
+	return nil
+}
+
+func (s *memoryLinkChecksStore) SetArchiveURL(postID int, archiveURL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, present := s.posts[postID]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+	p.LinkArchiveURL = archiveURL
+	return nil
+}
+
+func (s *memoryLinkChecksStore) SetMetadata(postID int, meta *thesrc.LinkMetadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, present := s.posts[postID]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+	if p.LinkDescription == "" {
+		p.LinkDescription = meta.Description
+	}
+	if p.LinkImageURL == "" {
+		p.LinkImageURL = meta.ImageURL
+	}
+	if p.LinkFaviconURL == "" {
+		p.LinkFaviconURL = meta.FaviconURL
+	}
+	return nil
+}
+
+type memoryTrendingStore struct{ *memoryDB }
+
+func (s *memoryTrendingStore) UpdateVelocities(window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	since := time.Now().Add(-window)
+	counts := map[int]int{} // keyed by post ID
+	for key, v := range s.votes {
+		if v.counted() && !v.votedAt.Before(since) {
+			counts[key[1]]++
+		}
+	}
+	for _, c := range s.comments {
+		if !c.SubmittedAt.Before(since) {
+			counts[c.PostID] += thesrc.CommentVelocityWeight
+		}
+	}
+
+	var n int
+	for _, p := range s.posts {
+		if counts[p.ID] == 0 && p.Velocity == 0 {
+			continue
+		}
+		p.Velocity = float64(counts[p.ID]) / window.Hours()
+		n++
+	}
+	return n, nil
+}
+
+type memoryTokensStore struct{ *memoryDB }
+
+func (s *memoryTokensStore) Create(token *thesrc.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token.ID = s.nextID()
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	t := *token
+	t.Value = "" // only the hash is stored
+	s.tokens[t.ID] = &t
+	return nil
+}
+
+func (s *memoryTokensStore) List(userID int) ([]*thesrc.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tokens []*thesrc.Token
+	for _, token := range s.tokens {
+		if token.UserID == userID {
+			t := *token
+			tokens = append(tokens, &t)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if !tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+		}
+		return tokens[i].ID > tokens[j].ID
+	})
+	return tokens, nil
+}
+
+func (s *memoryTokensStore) GetByHash(hash []byte) (*thesrc.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if bytes.Equal(token.Hash, hash) {
+			t := *token
+			return &t, nil
+		}
+	}
+	return nil, thesrc.ErrTokenNotFound
+}
+
+func (s *memoryTokensStore) Delete(userID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token, present := s.tokens[id]; !present || token.UserID != userID {
+		return thesrc.ErrTokenNotFound
+	}
+	delete(s.tokens, id)
+	return nil
+}
+
+type memorySessionsStore struct{ *memoryDB }
+
+func (s *memorySessionsStore) Create(session *thesrc.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session.ID = s.nextID()
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	if session.LastUsedAt.IsZero() {
+		session.LastUsedAt = session.CreatedAt
+	}
+	ss := *session
+	ss.Current = false
+	s.sessions[ss.ID] = &ss
+	return nil
+}
+
+func (s *memorySessionsStore) GetByHash(hash []byte) (*thesrc.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, session := range s.sessions {
+		if bytes.Equal(session.Hash, hash) {
+			ss := *session
+			return &ss, nil
+		}
+	}
+	return nil, thesrc.ErrSessionNotFound
+}
+
+func (s *memorySessionsStore) List(userID int, usedSince time.Time) ([]*thesrc.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sessions []*thesrc.Session
+	for _, session := range s.sessions {
+		if session.UserID == userID && !session.LastUsedAt.Before(usedSince) {
+			ss := *session
+			sessions = append(sessions, &ss)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastUsedAt.Equal(sessions[j].LastUsedAt) {
+			return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+func (s *memorySessionsStore) Touch(id int, lastUsedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, present := s.sessions[id]; present {
+		session.LastUsedAt = lastUsedAt
+	}
+	return nil
+}
+
+func (s *memorySessionsStore) Delete(userID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, present := s.sessions[id]; !present || session.UserID != userID {
+		return thesrc.ErrSessionNotFound
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *memorySessionsStore) DeleteAll(userID, exceptID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, session := range s.sessions {
+		if session.UserID == userID && id != exceptID {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (s *memorySessionsStore) DeleteUnused(usedBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, session := range s.sessions {
+		if session.LastUsedAt.Before(usedBefore) {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+type memoryFollowsStore struct{ *memoryDB }
+
+func (s *memoryFollowsStore) List(userID int) ([]*thesrc.Follow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	follows := make([]*thesrc.Follow, 0, len(s.follows[userID]))
+	for i := len(s.follows[userID]) - 1; i >= 0; i-- {
+		f := *s.follows[userID][i]
+		follows = append(follows, &f)
+	}
+	if len(follows) == 0 {
+		return nil, nil
+	}
+	return follows, nil
+}
+
+func (s *memoryFollowsStore) Follow(follow *thesrc.Follow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if follow.CreatedAt.IsZero() {
+		follow.CreatedAt = time.Now()
+	}
+	s.unfollow(follow.UserID, follow.Kind, follow.Name)
+	f := *follow
+	s.follows[f.UserID] = append(s.follows[f.UserID], &f)
+	return nil
+}
+
+func (s *memoryFollowsStore) Unfollow(userID int, kind, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unfollow(userID, kind, name)
+	return nil
+}
+
+// unfollow removes the user's follow of a topic, if any. s.mu must be held.
+func (s *memoryFollowsStore) unfollow(userID int, kind, name string) {
+	follows := s.follows[userID][:0]
+	for _, f := range s.follows[userID] {
+		if f.Kind != kind || f.Name != name {
+			follows = append(follows, f)
+		}
+	}
+	s.follows[userID] = follows
+}
+
+type memoryWebhooksStore struct{ *memoryDB }
+
+func (s *memoryWebhooksStore) Create(hook *thesrc.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hook.ID = s.nextID()
+	if hook.CreatedAt.IsZero() {
+		hook.CreatedAt = time.Now()
+	}
+	h := *hook
+	s.webhooks[h.ID] = &h
+	return nil
+}
+
+func (s *memoryWebhooksStore) List() ([]*thesrc.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var hooks []*thesrc.Webhook
+	for _, hook := range s.webhooks {
+		h := *hook
+		hooks = append(hooks, &h)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+	return hooks, nil
+}
+
+func (s *memoryWebhooksStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, present := s.webhooks[id]; !present {
+		return thesrc.ErrWebhookNotFound
+	}
+	delete(s.webhooks, id)
+
+	var kept []*thesrc.WebhookDelivery
+	for _, d := range s.webhookDeliveries {
+		if d.WebhookID != id {
+			kept = append(kept, d)
+		}
+	}
+	s.webhookDeliveries = kept
+	return nil
+}
+
+func (s *memoryWebhooksStore) AddDelivery(d *thesrc.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d.ID = s.nextID()
+	if d.AttemptedAt.IsZero() {
+		d.AttemptedAt = time.Now()
+	}
+	d2 := *d
+	s.webhookDeliveries = append(s.webhookDeliveries, &d2)
+	return nil
+}
+
+func (s *memoryWebhooksStore) ListDeliveries(webhookID int, opt *thesrc.ListOptions) ([]*thesrc.WebhookDelivery, error) {
+	if opt == nil {
+		opt = &thesrc.ListOptions{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deliveries []*thesrc.WebhookDelivery
+	for i := len(s.webhookDeliveries) - 1; i >= 0; i-- {
+		if d := *s.webhookDeliveries[i]; d.WebhookID == webhookID {
+			deliveries = append(deliveries, &d)
+		}
+	}
+	start, end := pageBounds(len(deliveries), *opt)
+	return deliveries[start:end], nil
+}
+
+type memoryNotificationsStore struct{ *memoryDB }
+
+func (s *memoryNotificationsStore) Create(notification *thesrc.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notification.ID = s.nextID()
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	n := *notification
+	s.notifications[n.ID] = &n
+	return nil
+}
+
+func (s *memoryNotificationsStore) List(userID int, opt *thesrc.NotificationListOptions) ([]*thesrc.Notification, error) {
+	if opt == nil {
+		opt = &thesrc.NotificationListOptions{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var notifications []*thesrc.Notification
+	for _, notification := range s.notifications {
+		if notification.UserID == userID && (!opt.Unread || !notification.Read) {
+			n := *notification
+			notifications = append(notifications, &n)
+		}
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		if !notifications[i].CreatedAt.Equal(notifications[j].CreatedAt) {
+			return notifications[i].CreatedAt.After(notifications[j].CreatedAt)
+		}
+		return notifications[i].ID > notifications[j].ID
+	})
+	start, end := pageBounds(len(notifications), opt.ListOptions)
+	return notifications[start:end], nil
+}
+
+func (s *memoryNotificationsStore) UnreadCount(userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int
+	for _, n := range s.notifications {
+		if n.UserID == userID && !n.Read {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryNotificationsStore) MarkRead(userID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, present := s.notifications[id]
+	if !present || n.UserID != userID {
+		return thesrc.ErrNotificationNotFound
+	}
+	n.Read = true
+	return nil
+}
+
+func (s *memoryNotificationsStore) MarkAllRead(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, n := range s.notifications {
+		if n.UserID == userID {
+			n.Read = true
+		}
+	}
+	return nil
+}
+
+type memoryJobsStore struct{ *memoryDB }
+
+func (s *memoryJobsStore) Enqueue(job *thesrc.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	job.ID, job.State, job.Attempts, job.LastError = 0, thesrc.JobPending, 0, ""
+	if job.Key != "" {
+		for _, j := range s.jobs {
+			if j.Key == job.Key && (j.State == thesrc.JobPending || j.State == thesrc.JobRunning) {
+				return nil
+			}
+		}
+	}
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = DefaultJobMaxAttempts
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	job.ID = s.nextID()
+	job.CreatedAt, job.UpdatedAt = now, now
+	j := *job
+	s.jobs[j.ID] = &j
+	return nil
+}
+
+func (s *memoryJobsStore) Claim(now time.Time, lease time.Duration) (*thesrc.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next *thesrc.Job
+	for _, j := range s.jobs {
+		if (j.State != thesrc.JobPending && j.State != thesrc.JobRunning) || j.RunAt.After(now) {
+			continue
+		}
+		if next == nil || j.RunAt.Before(next.RunAt) || (j.RunAt.Equal(next.RunAt) && j.ID < next.ID) {
+			next = j
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+	next.State, next.Attempts, next.RunAt, next.UpdatedAt = thesrc.JobRunning, next.Attempts+1, now.Add(lease), now
+	j := *next
+	return &j, nil
+}
+
+func (s *memoryJobsStore) Complete(job *thesrc.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, err := s.leasedJob(job, time.Now())
+	if err != nil {
+		return err
+	}
+	j.State, j.LastError, j.UpdatedAt = thesrc.JobDone, "", time.Now()
+	return nil
+}
+
+func (s *memoryJobsStore) Fail(job *thesrc.Job, errMsg string, retryAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, err := s.leasedJob(job, time.Now())
+	if err != nil {
+		return err
+	}
+	j.State = thesrc.JobPending
+	if j.Attempts >= j.MaxAttempts {
+		j.State = thesrc.JobFailed
+	}
+	j.LastError, j.RunAt, j.UpdatedAt = errMsg, retryAt, time.Now()
+	return nil
+}
+
+// leasedJob returns the stored job that job was claimed as, or
+// thesrc.ErrJobLeaseExpired if job's lease has expired at now. The caller
+// must hold s.mu.
+func (s *memoryJobsStore) leasedJob(job *thesrc.Job, now time.Time) (*thesrc.Job, error) {
+	j, present := s.jobs[job.ID]
+	if !present || j.State != thesrc.JobRunning || j.Attempts != job.Attempts || !j.RunAt.After(now) {
+		return nil, thesrc.ErrJobLeaseExpired
+	}
+	return j, nil
+}
+
+func (s *memoryJobsStore) List(opt *thesrc.JobListOptions) ([]*thesrc.Job, error) {
+	if opt == nil {
+		opt = &thesrc.JobListOptions{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var jobs []*thesrc.Job
+	for _, job := range s.jobs {
+		if opt.State == "" || job.State == opt.State {
+			j := *job
+			jobs = append(jobs, &j)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].UpdatedAt.Equal(jobs[j].UpdatedAt) {
+			return jobs[i].UpdatedAt.After(jobs[j].UpdatedAt)
+		}
+		return jobs[i].ID > jobs[j].ID
+	})
+	start, end := pageBounds(len(jobs), opt.ListOptions)
+	return jobs[start:end], nil
+}
+
+func (s *memoryJobsStore) Retry(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, present := s.jobs[id]
+	if !present || j.State != thesrc.JobFailed {
+		return thesrc.ErrJobNotFound
+	}
+	now := time.Now()
+	j.State, j.Attempts, j.RunAt, j.UpdatedAt = thesrc.JobPending, 0, now, now
+	return nil
+}
+
+func (s *memoryJobsStore) DeleteDone(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, j := range s.jobs {
+		if j.State == thesrc.JobDone && j.UpdatedAt.Before(before) {
+			delete(s.jobs, id)
+		}
+	}
+	return nil
+}
+
+type memoryTaskLocksStore struct{ *memoryDB }
+
+func (s *memoryTaskLocksStore) Acquire(name, owner string, now, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if expiresAt, present := s.taskLocks[name]; present && expiresAt.After(now) {
+		return false, nil
+	}
+	s.taskLocks[name] = until
+	return true, nil
+}
+
+type memoryVoteSuspectsStore struct{ *memoryDB }
+
+func (s *memoryVoteSuspectsStore) RecentVotes(since time.Time) ([]*VoteRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var votes []*VoteRecord
+	for key, v := range s.votes {
+		if v.votedAt.Before(since) {
+			continue
+		}
+		user, post := s.users[key[0]], s.posts[key[1]]
+		if user == nil || post == nil {
+			continue
+		}
+		r := &VoteRecord{UserID: user.ID, Login: user.Login, PostID: post.ID, AuthorUserID: post.AuthorUserID, VotedAt: v.votedAt}
+		if author := s.users[post.AuthorUserID]; author != nil {
+			r.AuthorLogin = author.Login
+		}
+		for _, c := range s.clientRecords {
+			if c.Action == thesrc.ClientActionVote && c.UserID == user.ID && c.PostID == post.ID && c.IPHash > r.IPHash {
+				r.IPHash = c.IPHash
+			}
+		}
+		votes = append(votes, r)
+	}
+	sort.Slice(votes, func(i, j int) bool {
+		if !votes[i].VotedAt.Equal(votes[j].VotedAt) {
+			return votes[i].VotedAt.Before(votes[j].VotedAt)
+		}
+		if votes[i].UserID != votes[j].UserID {
+			return votes[i].UserID < votes[j].UserID
+		}
+		return votes[i].PostID < votes[j].PostID
+	})
+	return votes, nil
+}
+
+func (s *memoryVoteSuspectsStore) Flag(suspect *thesrc.VoteSuspect) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if suspect.FlaggedAt.IsZero() {
+		suspect.FlaggedAt = time.Now()
+	}
+	if existing, present := s.voteSuspects[suspect.UserID]; present {
+		if !existing.Dismissed {
+			existing.Reason, existing.FlaggedAt = suspect.Reason, suspect.FlaggedAt
+		}
+		return false, nil
+	}
+	suspect.Nullified, suspect.Dismissed = false, false
+	s2 := *suspect
+	s.voteSuspects[suspect.UserID] = &s2
+	return true, nil
+}
+
+func (s *memoryVoteSuspectsStore) List(opt *thesrc.ListOptions) ([]*thesrc.VoteSuspect, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if opt == nil {
+		opt = &thesrc.ListOptions{}
+	}
+	var suspects []*thesrc.VoteSuspect
+	for _, suspect := range s.voteSuspects {
+		if !suspect.Dismissed {
+			s2 := *suspect
+			suspects = append(suspects, &s2)
+		}
+	}
+	sort.Slice(suspects, func(i, j int) bool {
+		if !suspects[i].FlaggedAt.Equal(suspects[j].FlaggedAt) {
+			return suspects[i].FlaggedAt.After(suspects[j].FlaggedAt)
+		}
+		return suspects[i].UserID < suspects[j].UserID
+	})
+	start, end := pageBounds(len(suspects), *opt)
+	return suspects[start:end], nil
+}
+
+func (s *memoryVoteSuspectsStore) SetNullified(userID int, nullified bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	suspect, present := s.voteSuspects[userID]
+	if !present || suspect.Dismissed {
+		return thesrc.ErrVoteSuspectNotFound
+	}
+	suspect.Nullified = nullified
+	s.setVotesNullified(userID, nullified)
+	return nil
+}
+
+func (s *memoryVoteSuspectsStore) Dismiss(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	suspect, present := s.voteSuspects[userID]
+	if !present || suspect.Dismissed {
+		return thesrc.ErrVoteSuspectNotFound
+	}
+	suspect.Dismissed, suspect.Nullified = true, false
+	s.setVotesNullified(userID, false)
+	return nil
+}
+
+// setVotesNullified nullifies (or restores) all of a user's votes on posts,
+// and updates the scores of the posts whose votes changed. s.mu must be
+// held.
+func (s *memoryVoteSuspectsStore) setVotesNullified(userID int, nullified bool) {
+	for key, v := range s.votes {
+		if key[0] != userID || v.nullified == nullified {
+			continue
+		}
+		v.nullified = nullified
+		post, present := s.posts[key[1]]
+		if !present {
+			// Deleted posts' scores are restored if they're undeleted.
+			post, present = s.deletedPosts[key[1]]
+		}
+		if present && !v.shadow {
+			if nullified {
+				post.Score--
+			} else {
+				post.Score++
+			}
+		}
+	}
+}
+
+type memoryClientRecordsStore struct{ *memoryDB }
+
+func (s *memoryClientRecordsStore) Create(record *thesrc.ClientRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record.ID = s.nextID()
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	r := *record
+	r.Login = ""
+	s.clientRecords[r.ID] = &r
+	return nil
+}
+
+func (s *memoryClientRecordsStore) List(opt *thesrc.ClientRecordListOptions) ([]*thesrc.ClientRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if opt == nil {
+		opt = &thesrc.ClientRecordListOptions{}
+	}
+	var records []*thesrc.ClientRecord
+	for _, r := range s.clientRecords {
+		if (opt.UserID == 0 || r.UserID == opt.UserID) && (opt.PostID == 0 || r.PostID == opt.PostID) && (opt.IPHash == "" || r.IPHash == opt.IPHash) {
+			r2 := *r
+			records = append(records, &r2)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].CreatedAt.After(records[j].CreatedAt)
+		}
+		return records[i].ID > records[j].ID
+	})
+	start, end := pageBounds(len(records), opt.ListOptions)
+	return records[start:end], nil
+}
+
+func (s *memoryClientRecordsStore) DeleteBefore(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, r := range s.clientRecords {
+		if r.CreatedAt.Before(before) {
+			delete(s.clientRecords, id)
+		}
+	}
+	return nil
+}
+
+func (s *memoryClientRecordsStore) Salt(start time.Time) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if salt, present := s.clientSalts[start.Unix()]; present {
+		return salt, nil
+	}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	s.clientSalts[start.Unix()] = salt
+	return salt, nil
+}
+
+func (s *memoryClientRecordsStore) DeleteSaltsBefore(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for period := range s.clientSalts {
+		if period < before.Unix() {
+			delete(s.clientSalts, period)
+		}
+	}
+	return nil
+}
+
+type memoryAuditLogStore struct{ *memoryDB }
+
+func (s *memoryAuditLogStore) Create(entry *thesrc.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.ID = s.nextID()
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	e := *entry
+	e.ActorLogin = ""
+	s.auditLog[e.ID] = &e
+	return nil
+}
+
+func (s *memoryAuditLogStore) List(opt *thesrc.AuditLogListOptions) ([]*thesrc.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if opt == nil {
+		opt = &thesrc.AuditLogListOptions{}
+	}
+	var entries []*thesrc.AuditEntry
+	for _, e := range s.auditLog {
+		if (opt.ActorUserID == 0 || e.ActorUserID == opt.ActorUserID) && (opt.Action == "" || e.Action == opt.Action) && (opt.Target == "" || e.Target == opt.Target) {
+			e2 := *e
+			entries = append(entries, &e2)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.After(entries[j].CreatedAt)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+	start, end := pageBounds(len(entries), opt.ListOptions)
+	return entries[start:end], nil
+}
+
+type memoryModerationStore struct{ *memoryDB }
+
+func (s *memoryModerationStore) Bulk(mod *thesrc.BulkModeration, audit *thesrc.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := mod.Validate(); err != nil {
+		return err
+	}
+	for _, id := range mod.PostIDs {
+		if _, present := s.posts[id]; !present {
+			return thesrc.ErrPostNotFound
+		}
+	}
+	for _, id := range mod.CommentIDs {
+		if _, present := s.comments[id]; !present {
+			return thesrc.ErrCommentNotFound
+		}
+	}
+
+	now := time.Now()
+	record := func(action, target, details string) {
+		e := *audit
+		e.ID, e.Action, e.Target, e.Details, e.CreatedAt, e.ActorLogin = s.nextID(), action, target, details, now, ""
+		s.auditLog[e.ID] = &e
+	}
+	for _, id := range mod.PostIDs {
+		p := s.posts[id]
+		p.Version++
+		switch mod.Action {
+		case thesrc.BulkKill:
+			p.Dead, p.Queued, p.Reports = true, false, 0
+			record(thesrc.AuditKillPost, fmt.Sprintf("post:%d", id), p.Title)
+		case thesrc.BulkQueue:
+			p.Hidden, p.Queued, p.Reports = true, true, 0
+			record(thesrc.AuditQueuePost, fmt.Sprintf("post:%d", id), p.Title)
+		case thesrc.BulkUntag:
+			var tags []string
+			for _, tag := range p.Tags {
+				if !containsString(mod.Tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+			p.Tags = tags
+			record(thesrc.AuditUntagPost, fmt.Sprintf("post:%d", id), strings.Join(mod.Tags, ", "))
+		case thesrc.BulkRetag:
+			p.Tags = append([]string(nil), mod.Tags...)
+			record(thesrc.AuditRetagPost, fmt.Sprintf("post:%d", id), strings.Join(mod.Tags, ", "))
+		}
+	}
+	for _, id := range mod.CommentIDs {
+		s.comments[id].Dead = true
+		record(thesrc.AuditKillComment, fmt.Sprintf("comment:%d", id), "")
+	}
+	return nil
+}
+
+type memoryDeletedPostsStore struct{ *memoryDB }
+
+func (s *memoryDeletedPostsStore) Purge(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int
+	for id, p := range s.deletedPosts {
+		if !p.DeletedAt.Before(before) {
+			continue
+		}
+		delete(s.deletedPosts, id)
+		delete(s.thumbnailAttempts, id)
+		delete(s.linkChecks, id)
+		for cid, c := range s.comments {
+			if c.PostID == id {
+				delete(s.comments, cid)
+				for key := range s.commentVotes {
+					if key[1] == cid {
+						delete(s.commentVotes, key)
+					}
+				}
+			}
+		}
+		for key := range s.votes {
+			if key[1] == id {
+				delete(s.votes, key)
+			}
+		}
+		for key := range s.flags {
+			if key[1] == id {
+				delete(s.flags, key)
+			}
+		}
+		for key := range s.saves {
+			if key[1] == id {
+				delete(s.saves, key)
+			}
+		}
+		for key := range s.hides {
+			if key[1] == id {
+				delete(s.hides, key)
+			}
+		}
+		for nid, n := range s.notifications {
+			if n.PostID == id {
+				delete(s.notifications, nid)
+			}
+		}
+		for rid, rev := range s.postRevisions {
+			if rev.PostID == id {
+				delete(s.postRevisions, rid)
+			}
+		}
+		for rid, r := range s.reports {
+			if r.PostID == id {
+				delete(s.reports, rid)
+			}
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+type memoryPostRevisionsStore struct{ *memoryDB }
+
+func (s *memoryPostRevisionsStore) Create(rev *thesrc.PostRevision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rev.ID = s.nextID()
+	if rev.CreatedAt.IsZero() {
+		rev.CreatedAt = time.Now()
+	}
+	rev2 := *rev
+	rev2.EditorLogin = ""
+	s.postRevisions[rev2.ID] = &rev2
+	return nil
+}
+
+type memoryReportsStore struct{ *memoryDB }
+
+func (s *memoryReportsStore) Create(report *thesrc.Report, limit int, since time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, present := s.posts[report.PostID]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+	if limit > 0 {
+		var count int
+		for _, r := range s.reports {
+			if r.ClientHash == report.ClientHash && !r.CreatedAt.Before(since) {
+				count++
+			}
+		}
+		if count >= limit {
+			return thesrc.ErrReportRateLimited
+		}
+	}
+	p.Reports++
+
+	report.ID = s.nextID()
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = time.Now()
+	}
+	r := *report
+	s.reports[r.ID] = &r
+	return nil
+}
+
+func (s *memoryReportsStore) List(opt *thesrc.ReportListOptions) ([]*thesrc.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if opt == nil {
+		opt = &thesrc.ReportListOptions{}
+	}
+	var reports []*thesrc.Report
+	for _, r := range s.reports {
+		if opt.PostID == 0 || r.PostID == opt.PostID {
+			r2 := *r
+			reports = append(reports, &r2)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if !reports[i].CreatedAt.Equal(reports[j].CreatedAt) {
+			return reports[i].CreatedAt.After(reports[j].CreatedAt)
+		}
+		return reports[i].ID > reports[j].ID
+	})
+	start, end := pageBounds(len(reports), opt.ListOptions)
+	return reports[start:end], nil
+}
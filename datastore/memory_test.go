@@ -0,0 +1,958 @@
+package datastore
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestMemoryDatastore_Posts(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	now := time.Now()
+	old := &thesrc.Post{LinkURL: "http://example.com/1", Score: 50, SubmittedAt: now.Add(-72 * time.Hour), Tags: []string{"golang"}}
+	hot := &thesrc.Post{LinkURL: "http://example.com/2", Score: 10, SubmittedAt: now.Add(-1 * time.Hour)}
+	fresh := &thesrc.Post{Title: "Show thesrc: a thing", LinkURL: "http://example.com/3", Score: 1, SubmittedAt: now, Tags: []string{"golang", "sql"}}
+	for _, p := range []*thesrc.Post{old, hot, fresh} {
+		if created, err := d.Posts.Submit(p); err != nil || !created {
+			t.Fatalf("Submit: got created %v and error %v", created, err)
+		}
+	}
+
+	dup := &thesrc.Post{LinkURL: old.LinkURL}
+	if created, err := d.Posts.Submit(dup); err != nil || created {
+		t.Fatalf("Submit duplicate: got created %v and error %v", created, err)
+	}
+	if !reflect.DeepEqual(dup, old) {
+		t.Errorf("got duplicate post %+v, want existing %+v", dup, old)
+	}
+
+	if _, err := d.Posts.Get(123); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+
+	tests := []struct {
+		opt  *thesrc.PostListOptions
+		want []*thesrc.Post
+	}{
+		{nil, []*thesrc.Post{fresh, hot, old}},
+		{&thesrc.PostListOptions{Sort: thesrc.SortTop}, []*thesrc.Post{hot, old, fresh}},
+		{&thesrc.PostListOptions{Sort: thesrc.SortBest}, []*thesrc.Post{old, hot, fresh}},
+		{&thesrc.PostListOptions{Sort: thesrc.SortBest, Period: thesrc.PeriodDay}, []*thesrc.Post{hot, fresh}},
+		{&thesrc.PostListOptions{Show: true}, []*thesrc.Post{fresh}},
+		{&thesrc.PostListOptions{Tag: "golang"}, []*thesrc.Post{fresh, old}},
+		{&thesrc.PostListOptions{SinceID: old.ID}, []*thesrc.Post{fresh, hot}},
+		{&thesrc.PostListOptions{Period: thesrc.PeriodDay}, []*thesrc.Post{fresh, hot}},
+		{&thesrc.PostListOptions{Period: thesrc.PeriodAll}, []*thesrc.Post{fresh, hot, old}},
+		{&thesrc.PostListOptions{ListOptions: thesrc.ListOptions{PerPage: 2, Page: 2}}, []*thesrc.Post{old}},
+	}
+	for _, test := range tests {
+		posts, err := d.Posts.List(test.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(posts, test.want) {
+			t.Errorf("%+v: got posts %+v, want %+v", test.opt, posts, test.want)
+		}
+	}
+}
+
+func TestMemoryDatastore_Posts_selfPosts(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	link := &thesrc.Post{LinkURL: "http://example.com/1", Classification: "NOTCODE"}
+	self1 := &thesrc.Post{Title: "a", Body: "a"}
+	self2 := &thesrc.Post{Title: "b", Body: "b"}
+	for _, p := range []*thesrc.Post{link, self1, self2} {
+		if created, err := d.Posts.Submit(p); err != nil || !created {
+			t.Fatalf("Submit %+v: got created %v and error %v", p, created, err)
+		}
+	}
+	if self1.ID == self2.ID {
+		t.Errorf("got the same ID %d for 2 self-posts", self1.ID)
+	}
+
+	posts, err := d.Posts.List(&thesrc.PostListOptions{CodeOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 || posts[0].ID == link.ID || posts[1].ID == link.ID {
+		t.Errorf("got CodeOnly posts %+v, want only the 2 self-posts", posts)
+	}
+}
+
+func TestMemoryDatastore_Posts_idempotencyKey(t *testing.T) {
+	testPostsIdempotencyKey(t, NewMemoryDatastore())
+}
+
+func TestMemoryDatastore_Posts_version(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	post := &thesrc.Post{Title: "a", Body: "a"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+	if post.Version != 1 {
+		t.Errorf("got version %d after submitting, want 1", post.Version)
+	}
+
+	if err := d.Posts.Update(post.ID, &thesrc.Post{Title: "b", Body: "b", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Posts.Update(post.ID, &thesrc.Post{Title: "c", Body: "c", Version: 1}); err != thesrc.ErrPostVersionConflict {
+		t.Errorf("got error %v updating a stale version, want ErrPostVersionConflict", err)
+	}
+	if err := d.Posts.Moderate(post.ID, &thesrc.PostModeration{Hidden: true, Version: 1}); err != thesrc.ErrPostVersionConflict {
+		t.Errorf("got error %v moderating a stale version, want ErrPostVersionConflict", err)
+	}
+	if err := d.Posts.Moderate(post.ID, &thesrc.PostModeration{Hidden: true, Version: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	post, err := d.Posts.Get(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Version != 3 {
+		t.Errorf("got version %d, want 3", post.Version)
+	}
+	if post.Title != "b" {
+		t.Errorf("got title %q, want %q", post.Title, "b")
+	}
+}
+
+func TestMemoryDatastore_Posts_searchTerms(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	rust := &thesrc.Post{Title: "Error handling in Rust", Body: "b"}
+	golang := &thesrc.Post{Title: "Go", Body: "Error handling in Go"}
+	for _, p := range []*thesrc.Post{rust, golang} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string][]int{
+		"error handling": {rust.ID, golang.ID},
+		"rust":           {rust.ID},
+		"go":             {golang.ID},
+		"python":         nil,
+	}
+	for terms, want := range tests {
+		posts, err := d.Posts.List(&thesrc.PostListOptions{SearchTerms: strings.Fields(terms)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		for _, p := range posts {
+			got = append(got, p.ID)
+		}
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got posts %v, want %v", terms, got, want)
+		}
+	}
+}
+
+func TestMemoryDatastore_Posts_after(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	for i := 0; i < 5; i++ {
+		if _, err := d.Posts.Submit(&thesrc.Post{Title: "p"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, sort := range []string{thesrc.SortNew, thesrc.SortBest} {
+		all, err := d.Posts.List(&thesrc.PostListOptions{Sort: sort})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		opt := &thesrc.PostListOptions{Sort: sort, ListOptions: thesrc.ListOptions{PerPage: 2}}
+		var paged []*thesrc.Post
+		for {
+			posts, err := d.Posts.List(opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			paged = append(paged, posts...)
+			if len(posts) < 2 {
+				break
+			}
+			opt.After = thesrc.PostCursorAfter(sort, posts[len(posts)-1])
+			opt.Page = 3 // ignored when After is set
+
+			// Posts submitted in the meantime don't shift later pages.
+			if _, err := d.Posts.Submit(&thesrc.Post{Title: "new"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !reflect.DeepEqual(paged, all) {
+			t.Errorf("%s: got posts %v when paginating with cursors, want %v", sort, postIDs(paged), postIDs(all))
+		}
+	}
+}
+
+func postIDs(posts []*thesrc.Post) []int {
+	ids := make([]int, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func TestMemoryDatastore_Posts_CreateBatch(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	existing := &thesrc.Post{LinkURL: "http://example.com/1"}
+	if _, err := d.Posts.Submit(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	posts := []*thesrc.Post{
+		{LinkURL: "http://example.com/1"},
+		{LinkURL: "http://example.com/2"},
+		{LinkURL: "http://example.com/2"},
+	}
+	results, err := d.Posts.CreateBatch(posts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := []bool{results[0].Created, results[1].Created, results[2].Created}, []bool{false, true, false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got created %v, want %v", got, want)
+	}
+	if posts[0].ID != existing.ID || posts[2].ID != posts[1].ID {
+		t.Errorf("got post IDs %d, %d, %d, want %d, N, N", posts[0].ID, posts[1].ID, posts[2].ID, existing.ID)
+	}
+
+	if _, err := d.Posts.CreateBatch(make([]*thesrc.Post, thesrc.MaxBatchSize+1)); err == nil {
+		t.Error("got nil error for batch larger than MaxBatchSize")
+	}
+}
+
+func TestMemoryDatastore_Posts_updateAndDelete(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	post := &thesrc.Post{Title: "t", LinkURL: "http://example.com", Tags: []string{"golang"}}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Comments.Create(&thesrc.Comment{PostID: post.ID, Body: "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	update := &thesrc.Post{Title: "t2", Tags: []string{"sql"}}
+	if err := d.Posts.Update(post.ID, update); err != nil {
+		t.Fatal(err)
+	}
+	if update.LinkURL != post.LinkURL || update.Title != "t2" || !reflect.DeepEqual(update.Tags, []string{"sql"}) {
+		t.Errorf("got updated post %+v", update)
+	}
+
+	if err := d.Posts.Delete(post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Posts.Get(post.ID); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v after deleting, want %v", err, thesrc.ErrPostNotFound)
+	}
+	if comments, _ := d.Comments.ListForPost(post.ID); len(comments) != 0 {
+		t.Errorf("got %d comments on deleted post, want 0", len(comments))
+	}
+	if err := d.Posts.Update(post.ID, update); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v updating deleted post, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
+
+func TestMemoryDatastore_Posts_Related(t *testing.T) {
+	testPostsStoreRelated(t, NewMemoryDatastore())
+}
+
+func TestMemoryDatastore_Votes(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	post := &thesrc.Post{LinkURL: "http://example.com", Score: 1}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := d.Votes.Upvote(1, post.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p, _ := d.Posts.Get(post.ID); p.Score != 2 {
+		t.Errorf("got score %d after upvoting, want 2", p.Score)
+	}
+	if voted, _ := d.Votes.Voted(1, []int{post.ID}); !voted[post.ID] {
+		t.Error("!voted")
+	}
+
+	if err := d.Votes.Unvote(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := d.Posts.Get(post.ID); p.Score != 1 {
+		t.Errorf("got score %d after unvoting, want 1", p.Score)
+	}
+
+	if err := d.Votes.Upvote(1, 123); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v upvoting nonexistent post, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
+
+func TestMemoryDatastore_CommentVotes(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	post := &thesrc.Post{LinkURL: "http://example.com"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+	older := &thesrc.Comment{PostID: post.ID, Body: "a", AuthorUserID: 2}
+	newer := &thesrc.Comment{PostID: post.ID, Body: "b", AuthorUserID: 2}
+	for _, c := range []*thesrc.Comment{older, newer} {
+		if err := d.Comments.Create(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := d.Votes.UpvoteComment(1, newer.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c, _ := d.Comments.Get(newer.ID); c.Score != 1 {
+		t.Errorf("got score %d after upvoting, want 1", c.Score)
+	}
+	if voted, _ := d.Votes.VotedComments(1, []int{older.ID, newer.ID}); voted[older.ID] || !voted[newer.ID] {
+		t.Errorf("got voted %v, want only comment %d", voted, newer.ID)
+	}
+	if karma, _ := d.Users.Karma(2); karma != 1 {
+		t.Errorf("got karma %d, want 1", karma)
+	}
+
+	// Higher-scored comments are listed first.
+	if comments, _ := d.Comments.ListForPost(post.ID); len(comments) != 2 || comments[0].ID != newer.ID {
+		t.Errorf("got comments %+v, want comment %d first", comments, newer.ID)
+	}
+
+	if err := d.Votes.UnvoteComment(1, newer.ID); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := d.Comments.Get(newer.ID); c.Score != 0 {
+		t.Errorf("got score %d after unvoting, want 0", c.Score)
+	}
+	if comments, _ := d.Comments.ListForPost(post.ID); len(comments) != 2 || comments[0].ID != older.ID {
+		t.Errorf("got comments %+v, want comment %d first", comments, older.ID)
+	}
+
+	if err := d.Votes.UpvoteComment(1, 123); err != thesrc.ErrCommentNotFound {
+		t.Errorf("got error %v upvoting nonexistent comment, want %v", err, thesrc.ErrCommentNotFound)
+	}
+}
+
+func TestMemoryDatastore_Saves(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	post := &thesrc.Post{LinkURL: "http://example.com"}
+	other := &thesrc.Post{LinkURL: "http://example.com/other"}
+	for _, p := range []*thesrc.Post{post, other} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := d.Saves.Save(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := d.Saves.Saved(1, []int{post.ID, other.ID}); !saved[post.ID] || saved[other.ID] {
+		t.Errorf("got saved %v, want only post %d", saved, post.ID)
+	}
+	if posts, _ := d.Posts.List(&thesrc.PostListOptions{SavedByUserID: 1}); len(posts) != 1 || posts[0].ID != post.ID {
+		t.Errorf("got saved posts %+v, want post %d", posts, post.ID)
+	}
+	if posts, _ := d.Posts.List(&thesrc.PostListOptions{SavedByUserID: 2}); len(posts) != 0 {
+		t.Errorf("got %d posts saved by another user, want 0", len(posts))
+	}
+
+	if err := d.Saves.Unsave(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := d.Saves.Saved(1, []int{post.ID}); saved[post.ID] {
+		t.Error("post is still saved after Unsave")
+	}
+
+	if err := d.Saves.Save(1, 123); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v saving nonexistent post, want %v", err, thesrc.ErrPostNotFound)
+	}
+	if err := d.Posts.Save(post.ID); err != errSaveWithoutUser {
+		t.Errorf("got error %v from Posts.Save, want %v", err, errSaveWithoutUser)
+	}
+}
+
+func TestMemoryDatastore_Hides(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	post := &thesrc.Post{LinkURL: "http://example.com"}
+	other := &thesrc.Post{LinkURL: "http://example.com/other"}
+	for _, p := range []*thesrc.Post{post, other} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := d.Hides.Hide(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if hidden, _ := d.Hides.Hidden(1, []int{post.ID, other.ID}); !hidden[post.ID] || hidden[other.ID] {
+		t.Errorf("got hidden %v, want only post %d", hidden, post.ID)
+	}
+	if posts, _ := d.Posts.List(&thesrc.PostListOptions{ExcludeHiddenByUserID: 1}); len(posts) != 1 || posts[0].ID != other.ID {
+		t.Errorf("got posts %+v excluding hidden posts, want post %d", posts, other.ID)
+	}
+	if posts, _ := d.Posts.List(&thesrc.PostListOptions{ExcludeHiddenByUserID: 2}); len(posts) != 2 {
+		t.Errorf("got %d posts for another user, want 2", len(posts))
+	}
+
+	if err := d.Hides.Unhide(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if hidden, _ := d.Hides.Hidden(1, []int{post.ID}); hidden[post.ID] {
+		t.Error("post is still hidden after Unhide")
+	}
+
+	if err := d.Hides.Hide(1, 123); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v hiding nonexistent post, want %v", err, thesrc.ErrPostNotFound)
+	}
+	if err := d.Posts.Hide(post.ID); err != errHideWithoutUser {
+		t.Errorf("got error %v from Posts.Hide, want %v", err, errHideWithoutUser)
+	}
+}
+
+func TestMemoryDatastore_Users_UpdateSettings(t *testing.T) {
+	testUsersStoreUpdateSettings(t, NewMemoryDatastore())
+}
+
+func TestMemoryDatastore_ShadowBan(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	alice, bob := &thesrc.User{Login: "alice"}, &thesrc.User{Login: "bob"}
+	for _, u := range []*thesrc.User{alice, bob} {
+		if err := d.Users.Create(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Users.SetShadowBanned(alice.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := d.Users.Get(alice.ID); !u.ShadowBanned {
+		t.Error("!ShadowBanned after SetShadowBanned")
+	}
+	if err := d.Users.SetShadowBanned(123, true); err != thesrc.ErrUserNotFound {
+		t.Errorf("got error %v shadow-banning nonexistent user, want %v", err, thesrc.ErrUserNotFound)
+	}
+
+	alicePost := &thesrc.Post{LinkURL: "http://example.com/a", AuthorUserID: alice.ID}
+	bobPost := &thesrc.Post{LinkURL: "http://example.com/b", AuthorUserID: bob.ID}
+	for _, p := range []*thesrc.Post{alicePost, bobPost} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// alice's posts are only listed for her.
+	for viewerUserID, want := range map[int]int{0: 1, bob.ID: 1, alice.ID: 2} {
+		posts, err := d.Posts.List(&thesrc.PostListOptions{ViewerUserID: viewerUserID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(posts) != want {
+			t.Errorf("viewer %d: got %d posts, want %d", viewerUserID, len(posts), want)
+		}
+	}
+
+	// alice's votes are recorded but not counted, even after she is
+	// unbanned.
+	if err := d.Votes.Upvote(alice.ID, bobPost.ID); err != nil {
+		t.Fatal(err)
+	}
+	if voted, _ := d.Votes.Voted(alice.ID, []int{bobPost.ID}); !voted[bobPost.ID] {
+		t.Error("!voted")
+	}
+	if p, _ := d.Posts.Get(bobPost.ID); p.Score != 0 {
+		t.Errorf("got score %d after shadow-banned upvote, want 0", p.Score)
+	}
+	d.Users.SetShadowBanned(alice.ID, false)
+	if err := d.Votes.Unvote(alice.ID, bobPost.ID); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := d.Posts.Get(bobPost.ID); p.Score != 0 {
+		t.Errorf("got score %d after unvoting shadow-banned upvote, want 0", p.Score)
+	}
+}
+
+func TestMemoryDatastore_Events(t *testing.T) {
+	d := NewMemoryDatastore()
+	events, cancel := d.Events.Subscribe()
+	defer cancel()
+
+	post := &thesrc.Post{LinkURL: "http://example.com", Score: 1}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Posts.Submit(&thesrc.Post{LinkURL: post.LinkURL}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Votes.Upvote(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Votes.Upvote(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Votes.Unvote(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Posts.Update(post.ID, &thesrc.Post{Title: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Flags.Flag(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		typ   string
+		score int
+	}{
+		{thesrc.EventPostCreated, 1},
+		{thesrc.EventPostScore, 2},
+		{thesrc.EventPostScore, 1},
+		{thesrc.EventPostUpdated, 1},
+		{thesrc.EventPostFlagged, 1},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for _, w := range want {
+		e := <-events
+		if e.Type != w.typ || e.Post.ID != post.ID || e.Post.Score != w.score {
+			t.Errorf("got event %s with post %+v, want %s with score %d", e.Type, e.Post, w.typ, w.score)
+		}
+	}
+}
+
+func TestMemoryDatastore_Flags(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	post := &thesrc.Post{LinkURL: "http://example.com"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := d.Flags.Flag(1, post.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p, _ := d.Posts.Get(post.ID); p.Flags != 1 {
+		t.Errorf("got %d flags after flagging, want 1", p.Flags)
+	}
+	if flagged, _ := d.Posts.List(&thesrc.PostListOptions{Flagged: true}); len(flagged) != 1 {
+		t.Errorf("got %d flagged posts, want 1", len(flagged))
+	}
+
+	if err := d.Posts.Moderate(post.ID, &thesrc.PostModeration{Hidden: true}); err != nil {
+		t.Fatal(err)
+	}
+	if posts, _ := d.Posts.List(nil); len(posts) != 0 {
+		t.Errorf("got %d posts after hiding, want 0", len(posts))
+	}
+	if flagged, _ := d.Posts.List(&thesrc.PostListOptions{Flagged: true}); len(flagged) != 1 || !flagged[0].Hidden {
+		t.Errorf("got flagged posts %+v, want 1 hidden post", flagged)
+	}
+
+	held := &thesrc.Post{LinkURL: "http://example.com/spam", Hidden: true, SpamScore: 1.5}
+	if _, err := d.Posts.Submit(held); err != nil {
+		t.Fatal(err)
+	}
+	if flagged, _ := d.Posts.List(&thesrc.PostListOptions{Flagged: true}); len(flagged) != 2 {
+		t.Errorf("got %d flagged posts, want 2 (including the post held as spam)", len(flagged))
+	}
+
+	if err := d.Flags.Flag(1, 123); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v flagging nonexistent post, want %v", err, thesrc.ErrPostNotFound)
+	}
+	if err := d.Posts.Moderate(123, &thesrc.PostModeration{}); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v moderating nonexistent post, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
+
+func TestMemoryDatastore_Comments(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	post := &thesrc.Post{LinkURL: "http://example.com"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+
+	comment := &thesrc.Comment{PostID: post.ID, Body: "b"}
+	if err := d.Comments.Create(comment); err != nil {
+		t.Fatal(err)
+	}
+	reply := &thesrc.Comment{PostID: post.ID, ParentID: comment.ID, Body: "r"}
+	if err := d.Comments.Create(reply); err != nil {
+		t.Fatal(err)
+	}
+
+	comments, err := d.Comments.ListForPost(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*thesrc.Comment{comment, reply}; !reflect.DeepEqual(comments, want) {
+		t.Errorf("got comments %+v, want %+v", comments, want)
+	}
+
+	if err := d.Comments.Create(&thesrc.Comment{PostID: 123}); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v creating comment on nonexistent post, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
+
+func TestMemoryDatastore_authorFilters(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	mine := &thesrc.Post{LinkURL: "http://example.com/1", AuthorUserID: 1, Score: 3}
+	other := &thesrc.Post{LinkURL: "http://example.com/2", AuthorUserID: 2, Score: 5}
+	mine2 := &thesrc.Post{LinkURL: "http://example.com/3", AuthorUserID: 1, Score: 4}
+	for _, p := range []*thesrc.Post{mine, other, mine2} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	myComment := &thesrc.Comment{PostID: other.ID, AuthorUserID: 1}
+	for _, c := range []*thesrc.Comment{myComment, {PostID: other.ID, AuthorUserID: 2}} {
+		if err := d.Comments.Create(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	posts, err := d.Posts.List(&thesrc.PostListOptions{AuthorUserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*thesrc.Post{mine2, mine}; !reflect.DeepEqual(posts, want) {
+		t.Errorf("got posts %+v, want %+v", posts, want)
+	}
+
+	comments, err := d.Comments.List(&thesrc.CommentListOptions{AuthorUserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*thesrc.Comment{myComment}; !reflect.DeepEqual(comments, want) {
+		t.Errorf("got comments %+v, want %+v", comments, want)
+	}
+
+	if karma, err := d.Users.Karma(1); err != nil || karma != 7 {
+		t.Errorf("got karma %d and error %v, want 7 and nil", karma, err)
+	}
+}
+
+func TestMemoryDatastore_Users(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	user := &thesrc.User{Login: "Alice"}
+	if err := d.Users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Users.Create(&thesrc.User{Login: "alice"}); err != ErrLoginTaken {
+		t.Errorf("got error %v, want %v", err, ErrLoginTaken)
+	}
+
+	got, err := d.Users.GetByLogin("ALICE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, user) {
+		t.Errorf("got user %+v, want %+v", got, user)
+	}
+	if got.Role != thesrc.RoleMember {
+		t.Errorf("got role %q for new user, want %q", got.Role, thesrc.RoleMember)
+	}
+
+	if err := d.Users.SetRole(user.ID, thesrc.RoleModerator); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := d.Users.Get(user.ID); got.Role != thesrc.RoleModerator {
+		t.Errorf("got role %q after SetRole, want %q", got.Role, thesrc.RoleModerator)
+	}
+	if err := d.Users.SetRole(user.ID, "superuser"); err == nil {
+		t.Error("got nil error setting invalid role")
+	}
+	if err := d.Users.SetRole(123, thesrc.RoleAdmin); err != thesrc.ErrUserNotFound {
+		t.Errorf("got error %v setting role of nonexistent user, want %v", err, thesrc.ErrUserNotFound)
+	}
+}
+
+func TestMemoryDatastore_Tags(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	for i, tags := range [][]string{{"golang", "git"}, {"golang"}, {"python"}} {
+		post := &thesrc.Post{LinkURL: "http://example.com/" + string(rune('a'+i)), Tags: tags}
+		if _, err := d.Posts.Submit(post); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tags, err := d.Tags.List(&thesrc.TagListOptions{Prefix: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*thesrc.Tag{{Name: "golang", NumPosts: 2}, {Name: "git", NumPosts: 1}}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("got tags %+v, want %+v", tags, want)
+	}
+}
+
+func TestMemoryDatastore_Domains(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	for i, p := range []*thesrc.Post{
+		{LinkURL: "http://example.com/a", Score: 1},
+		{LinkURL: "https://www.Example.com/b", Score: 4},
+		{LinkURL: "http://example.com/c", Score: 100, Dead: true},
+		{LinkURL: "http://other.com/", Score: 1},
+	} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(i, err)
+		}
+	}
+
+	posts, err := d.Posts.List(&thesrc.PostListOptions{Domain: "www.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 {
+		t.Errorf("got %d posts from example.com, want 2", len(posts))
+	}
+	for _, p := range posts {
+		if p.Domain != "example.com" {
+			t.Errorf("got post domain %q, want %q", p.Domain, "example.com")
+		}
+	}
+
+	stats, err := d.Domains.Get("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&thesrc.DomainStats{Domain: "example.com", NumPosts: 2, AverageScore: 2.5, NumHidden: 1}); !reflect.DeepEqual(stats, want) {
+		t.Errorf("got stats %+v, want %+v", stats, want)
+	}
+}
+
+func TestMemoryDatastore_Tokens(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	token := &thesrc.Token{UserID: 1, Name: "n", Hash: []byte("h"), Value: "v"}
+	if err := d.Tokens.Create(token); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := d.Tokens.GetByHash([]byte("h")); err != nil {
+		t.Fatal(err)
+	} else if got.ID != token.ID || got.Value != "" {
+		t.Errorf("got token %+v, want ID %d and no value", got, token.ID)
+	}
+	if tokens, _ := d.Tokens.List(1); len(tokens) != 1 {
+		t.Errorf("got %d tokens, want 1", len(tokens))
+	}
+	if tokens, _ := d.Tokens.List(2); len(tokens) != 0 {
+		t.Errorf("got %d tokens for another user, want 0", len(tokens))
+	}
+
+	if err := d.Tokens.Delete(2, token.ID); err != thesrc.ErrTokenNotFound {
+		t.Errorf("got error %v deleting another user's token, want %v", err, thesrc.ErrTokenNotFound)
+	}
+	if err := d.Tokens.Delete(1, token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Tokens.GetByHash([]byte("h")); err != thesrc.ErrTokenNotFound {
+		t.Errorf("got error %v after deleting, want %v", err, thesrc.ErrTokenNotFound)
+	}
+}
+
+func TestMemoryDatastore_Sessions(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	old := &thesrc.Session{UserID: 1, Hash: []byte("old"), LastUsedAt: time.Now().Add(-time.Hour)}
+	cur := &thesrc.Session{UserID: 1, Hash: []byte("cur")}
+	other := &thesrc.Session{UserID: 2, Hash: []byte("other")}
+	for _, session := range []*thesrc.Session{old, cur, other} {
+		if err := d.Sessions.Create(session); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, err := d.Sessions.GetByHash([]byte("cur")); err != nil {
+		t.Fatal(err)
+	} else if got.ID != cur.ID {
+		t.Errorf("got session %+v, want ID %d", got, cur.ID)
+	}
+	if sessions, _ := d.Sessions.List(1, time.Time{}); len(sessions) != 2 || sessions[0].ID != cur.ID {
+		t.Errorf("got sessions %+v, want the current session and then the old one", sessions)
+	}
+	if sessions, _ := d.Sessions.List(1, time.Now().Add(-time.Minute)); len(sessions) != 1 {
+		t.Errorf("got %d recently used sessions, want 1", len(sessions))
+	}
+
+	if err := d.Sessions.Touch(old.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, _ := d.Sessions.List(1, time.Now().Add(-time.Minute)); len(sessions) != 2 {
+		t.Errorf("got %d recently used sessions after touching, want 2", len(sessions))
+	}
+
+	if err := d.Sessions.Delete(2, cur.ID); err != thesrc.ErrSessionNotFound {
+		t.Errorf("got error %v deleting another user's session, want %v", err, thesrc.ErrSessionNotFound)
+	}
+	if err := d.Sessions.DeleteAll(1, cur.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Sessions.GetByHash([]byte("old")); err != thesrc.ErrSessionNotFound {
+		t.Errorf("got error %v after deleting all other sessions, want %v", err, thesrc.ErrSessionNotFound)
+	}
+	if err := d.Sessions.Delete(1, cur.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Sessions.GetByHash([]byte("cur")); err != thesrc.ErrSessionNotFound {
+		t.Errorf("got error %v after deleting, want %v", err, thesrc.ErrSessionNotFound)
+	}
+
+	if err := d.Sessions.DeleteUnused(time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Sessions.GetByHash([]byte("other")); err != thesrc.ErrSessionNotFound {
+		t.Errorf("got error %v after deleting unused sessions, want %v", err, thesrc.ErrSessionNotFound)
+	}
+}
+
+func TestMemoryDatastore_Webhooks(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	hook := &thesrc.Webhook{URL: "http://example.com/hook", Secret: "s"}
+	if err := d.Webhooks.Create(hook); err != nil {
+		t.Fatal(err)
+	}
+	if hook.ID == 0 {
+		t.Error("want nonzero hook.ID after creating")
+	}
+	if hooks, err := d.Webhooks.List(); err != nil {
+		t.Fatal(err)
+	} else if len(hooks) != 1 || hooks[0].Secret != "s" {
+		t.Errorf("got webhooks %+v, want 1 webhook with its secret", hooks)
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		if err := d.Webhooks.AddDelivery(&thesrc.WebhookDelivery{WebhookID: hook.ID, Event: thesrc.EventPostCreated, PostID: 1, Attempt: attempt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deliveries, err := d.Webhooks.ListDeliveries(hook.ID, &thesrc.ListOptions{PerPage: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 2 || deliveries[0].Attempt != 3 || deliveries[1].Attempt != 2 {
+		t.Errorf("got deliveries %+v, want attempts 3 and 2", deliveries)
+	}
+
+	if err := d.Webhooks.Delete(hook.ID); err != nil {
+		t.Fatal(err)
+	}
+	if deliveries, _ := d.Webhooks.ListDeliveries(hook.ID, nil); len(deliveries) != 0 {
+		t.Errorf("got %d deliveries after deleting webhook, want 0", len(deliveries))
+	}
+	if err := d.Webhooks.Delete(hook.ID); err != thesrc.ErrWebhookNotFound {
+		t.Errorf("got error %v deleting nonexistent webhook, want %v", err, thesrc.ErrWebhookNotFound)
+	}
+}
+
+func TestMemoryDatastore_Notifications(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	post := &thesrc.Post{LinkURL: "http://example.com"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+	n1 := &thesrc.Notification{UserID: 1, Type: thesrc.NotificationReply, ActorUserID: 2, PostID: post.ID}
+	n2 := &thesrc.Notification{UserID: 1, Type: thesrc.NotificationMention, ActorUserID: 2, PostID: post.ID}
+	for _, n := range []*thesrc.Notification{n1, n2} {
+		if err := d.Notifications.Create(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if notifications, _ := d.Notifications.List(1, nil); len(notifications) != 2 || notifications[0].ID != n2.ID {
+		t.Errorf("got notifications %+v, want 2, newest first", notifications)
+	}
+	if err := d.Notifications.MarkRead(1, n1.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notifications.MarkRead(2, n2.ID); err != thesrc.ErrNotificationNotFound {
+		t.Errorf("got error %v marking another user's notification as read, want %v", err, thesrc.ErrNotificationNotFound)
+	}
+	if unread, _ := d.Notifications.List(1, &thesrc.NotificationListOptions{Unread: true}); len(unread) != 1 || unread[0].ID != n2.ID {
+		t.Errorf("got unread notifications %+v, want only %d", unread, n2.ID)
+	}
+	if err := d.Notifications.MarkAllRead(1); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := d.Notifications.UnreadCount(1); n != 0 {
+		t.Errorf("got unread count %d after marking all read, want 0", n)
+	}
+
+	// Purging a deleted post deletes its notifications.
+	if err := d.Posts.Delete(post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.DeletedPosts.Purge(time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if notifications, _ := d.Notifications.List(1, nil); len(notifications) != 0 {
+		t.Errorf("got %d notifications of a deleted post, want 0", len(notifications))
+	}
+}
+
+func TestMemoryDatastore_Jobs(t *testing.T) {
+	testJobsStore(t, NewMemoryDatastore().Jobs)
+}
+
+func TestMemoryDatastore_TaskLocks(t *testing.T) {
+	testTaskLocksStore(t, NewMemoryDatastore().TaskLocks)
+}
+
+func TestMemoryDatastore_VoteSuspects(t *testing.T) {
+	testVoteSuspectsStore(t, NewMemoryDatastore())
+}
+
+func TestMemoryDatastore_ClientRecords(t *testing.T) {
+	testClientRecordsStore(t, NewMemoryDatastore().ClientRecords)
+}
+
+func TestMemoryDatastore_AuditLog(t *testing.T) {
+	testAuditLogStore(t, NewMemoryDatastore().AuditLog)
+}
+
+func TestMemoryDatastore_Moderation(t *testing.T) {
+	testModerationStore(t, NewMemoryDatastore())
+}
+
+func TestMemoryDatastore_DeletedPosts(t *testing.T) {
+	testDeletedPostsStore(t, NewMemoryDatastore())
+}
+
+func TestMemoryDatastore_PostRevisions(t *testing.T) {
+	testPostRevisionsStore(t, NewMemoryDatastore())
+}
+
+func TestMemoryDatastore_Reports(t *testing.T) {
+	testReportsStore(t, NewMemoryDatastore())
+}
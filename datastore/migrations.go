@@ -0,0 +1,647 @@
+package datastore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/modl"
+)
+
+// A Migration is a versioned change to the database schema.
+type Migration struct {
+	// Version is the migration's unique version number. Migrations are
+	// applied in increasing order of Version.
+	Version int
+
+	// Name briefly describes the migration.
+	Name string
+
+	// Up and Down are the SQL statements that apply and revert the
+	// migration. They may contain the placeholders {{serial}} (an
+	// auto-incrementing integer primary key column type), {{timestamp}}, and
+	// {{bytes}}, which are replaced with the corresponding type in the
+	// database's SQL dialect.
+	Up, Down []string
+
+	// UpFunc (if set) is called after the Up statements, in the same
+	// transaction, to make changes that can't be made in SQL alone (such as
+	// filling in a new column with values computed in Go).
+	UpFunc func(tx modl.SqlExecutor) error
+}
+
+// Migrations is the list of all migrations, in order. To change the schema,
+// append a migration (and never modify one that has been released).
+var Migrations = []*Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		// These statements are idempotent so that databases created before
+		// migrations existed can be migrated.
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS post (id {{serial}}, title text NOT NULL DEFAULT '', linkurl text NOT NULL DEFAULT '', body text NOT NULL DEFAULT '', submittedat {{timestamp}} NOT NULL, authoruserid integer NOT NULL DEFAULT 0, score integer NOT NULL DEFAULT 0, classification text NOT NULL DEFAULT '');`,
+			`CREATE INDEX IF NOT EXISTS post_submittedat ON post(submittedat DESC);`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS post_linkurl ON post(linkurl);`,
+			`CREATE TABLE IF NOT EXISTS comment (id {{serial}}, postid integer NOT NULL, parentid integer NOT NULL DEFAULT 0, body text NOT NULL DEFAULT '', submittedat {{timestamp}} NOT NULL, authoruserid integer NOT NULL DEFAULT 0);`,
+			`CREATE INDEX IF NOT EXISTS comment_postid ON comment(postid, submittedat);`,
+			`CREATE TABLE IF NOT EXISTS users (id {{serial}}, login text NOT NULL, email text NOT NULL DEFAULT '', passwordhash {{bytes}}, registeredat {{timestamp}} NOT NULL);`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS users_login ON users(lower(login));`,
+			`CREATE TABLE IF NOT EXISTS vote (userid integer NOT NULL, postid integer NOT NULL, votedat {{timestamp}} NOT NULL, PRIMARY KEY (userid, postid));`,
+			`CREATE INDEX IF NOT EXISTS vote_postid ON vote(postid);`,
+			`CREATE TABLE IF NOT EXISTS tag (id {{serial}}, name text NOT NULL);`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS tag_name ON tag(name);`,
+			`CREATE TABLE IF NOT EXISTS post_tag (postid integer NOT NULL, tagid integer NOT NULL, PRIMARY KEY (postid, tagid));`,
+			`CREATE INDEX IF NOT EXISTS post_tag_tagid ON post_tag(tagid);`,
+		},
+		Down: []string{
+			`DROP TABLE post_tag;`,
+			`DROP TABLE tag;`,
+			`DROP TABLE vote;`,
+			`DROP TABLE users;`,
+			`DROP TABLE comment;`,
+			`DROP TABLE post;`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add users.admin",
+		Up:      []string{`ALTER TABLE users ADD COLUMN admin boolean NOT NULL DEFAULT false;`},
+		Down:    []string{`ALTER TABLE users DROP COLUMN admin;`},
+	},
+	{
+		Version: 3,
+		Name:    "add post link metadata",
+		Up: []string{
+			`ALTER TABLE post ADD COLUMN linkdescription text NOT NULL DEFAULT '';`,
+			`ALTER TABLE post ADD COLUMN linkimageurl text NOT NULL DEFAULT '';`,
+			`ALTER TABLE post ADD COLUMN linkfaviconurl text NOT NULL DEFAULT '';`,
+		},
+		Down: []string{
+			`ALTER TABLE post DROP COLUMN linkfaviconurl;`,
+			`ALTER TABLE post DROP COLUMN linkimageurl;`,
+			`ALTER TABLE post DROP COLUMN linkdescription;`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add post thumbnails",
+		Up: []string{
+			`ALTER TABLE post ADD COLUMN thumbnailurl text NOT NULL DEFAULT '';`,
+			`CREATE TABLE thumbnail_attempt (postid integer PRIMARY KEY, attemptedat {{timestamp}} NOT NULL);`,
+		},
+		Down: []string{
+			`DROP TABLE thumbnail_attempt;`,
+			`ALTER TABLE post DROP COLUMN thumbnailurl;`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add post flags and moderation",
+		Up: []string{
+			`ALTER TABLE post ADD COLUMN flags integer NOT NULL DEFAULT 0;`,
+			`ALTER TABLE post ADD COLUMN hidden boolean NOT NULL DEFAULT false;`,
+			`ALTER TABLE post ADD COLUMN dead boolean NOT NULL DEFAULT false;`,
+			`CREATE TABLE flag (userid integer NOT NULL, postid integer NOT NULL, flaggedat {{timestamp}} NOT NULL, PRIMARY KEY (userid, postid));`,
+			`CREATE INDEX flag_postid ON flag(postid);`,
+		},
+		Down: []string{
+			`DROP TABLE flag;`,
+			`ALTER TABLE post DROP COLUMN dead;`,
+			`ALTER TABLE post DROP COLUMN hidden;`,
+			`ALTER TABLE post DROP COLUMN flags;`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "replace users.admin with users.role",
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN role text NOT NULL DEFAULT 'member';`,
+			`UPDATE users SET role='admin' WHERE admin;`,
+			`ALTER TABLE users DROP COLUMN admin;`,
+		},
+		Down: []string{
+			`ALTER TABLE users ADD COLUMN admin boolean NOT NULL DEFAULT false;`,
+			`UPDATE users SET admin=true WHERE role='admin';`,
+			`ALTER TABLE users DROP COLUMN role;`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add personal API tokens",
+		Up: []string{
+			`CREATE TABLE token (id {{serial}}, userid integer NOT NULL, name text NOT NULL, hash {{bytes}} NOT NULL, createdat {{timestamp}} NOT NULL);`,
+			`CREATE UNIQUE INDEX token_hash ON token(hash);`,
+			`CREATE INDEX token_userid ON token(userid);`,
+		},
+		Down: []string{`DROP TABLE token;`},
+	},
+	{
+		Version: 8,
+		Name:    "add webhooks",
+		Up: []string{
+			`CREATE TABLE webhook (id {{serial}}, url text NOT NULL, secret text NOT NULL, createdat {{timestamp}} NOT NULL);`,
+			`CREATE TABLE webhook_delivery (id {{serial}}, webhookid integer NOT NULL, event text NOT NULL, postid integer NOT NULL, attempt integer NOT NULL, statuscode integer NOT NULL, error text NOT NULL, attemptedat {{timestamp}} NOT NULL);`,
+			`CREATE INDEX webhook_delivery_webhookid ON webhook_delivery(webhookid);`,
+		},
+		Down: []string{`DROP TABLE webhook_delivery;`, `DROP TABLE webhook;`},
+	},
+	{
+		Version: 9,
+		Name:    "add post.domain",
+		Up: []string{
+			`ALTER TABLE post ADD COLUMN domain text NOT NULL DEFAULT '';`,
+			`CREATE INDEX post_domain ON post(domain);`,
+		},
+		UpFunc: setPostDomains,
+		Down: []string{
+			`DROP INDEX post_domain;`,
+			`ALTER TABLE post DROP COLUMN domain;`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "add post.spamscore",
+		Up:      []string{`ALTER TABLE post ADD COLUMN spamscore double precision NOT NULL DEFAULT 0;`},
+		Down:    []string{`ALTER TABLE post DROP COLUMN spamscore;`},
+	},
+	{
+		Version: 11,
+		Name:    "add users.shadowbanned and vote.shadow",
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN shadowbanned boolean NOT NULL DEFAULT false;`,
+			`ALTER TABLE vote ADD COLUMN shadow boolean NOT NULL DEFAULT false;`,
+		},
+		Down: []string{
+			`ALTER TABLE vote DROP COLUMN shadow;`,
+			`ALTER TABLE users DROP COLUMN shadowbanned;`,
+		},
+	},
+	{
+		Version: 12,
+		Name:    "add saved_posts table",
+		Up: []string{
+			`CREATE TABLE saved_posts (userid integer NOT NULL, postid integer NOT NULL, savedat {{timestamp}} NOT NULL, PRIMARY KEY (userid, postid));`,
+			`CREATE INDEX saved_posts_postid ON saved_posts(postid);`,
+		},
+		Down: []string{`DROP TABLE saved_posts;`},
+	},
+	{
+		Version: 13,
+		Name:    "add hidden_posts table",
+		Up: []string{
+			`CREATE TABLE hidden_posts (userid integer NOT NULL, postid integer NOT NULL, hiddenat {{timestamp}} NOT NULL, PRIMARY KEY (userid, postid));`,
+			`CREATE INDEX hidden_posts_postid ON hidden_posts(postid);`,
+		},
+		Down: []string{`DROP TABLE hidden_posts;`},
+	},
+	{
+		Version: 14,
+		Name:    "add comment scores and comment_vote table",
+		Up: []string{
+			`ALTER TABLE comment ADD COLUMN score integer NOT NULL DEFAULT 0;`,
+			`CREATE TABLE comment_vote (userid integer NOT NULL, commentid integer NOT NULL, votedat {{timestamp}} NOT NULL, shadow boolean NOT NULL DEFAULT false, PRIMARY KEY (userid, commentid));`,
+			`CREATE INDEX comment_vote_commentid ON comment_vote(commentid);`,
+		},
+		Down: []string{
+			`DROP TABLE comment_vote;`,
+			`ALTER TABLE comment DROP COLUMN score;`,
+		},
+	},
+	{
+		Version: 15,
+		Name:    "add notification table",
+		Up: []string{
+			`CREATE TABLE notification (id {{serial}}, userid integer NOT NULL, type text NOT NULL, actoruserid integer NOT NULL, postid integer NOT NULL, commentid integer NOT NULL DEFAULT 0, createdat {{timestamp}} NOT NULL, read boolean NOT NULL DEFAULT false);`,
+			`CREATE INDEX notification_userid ON notification(userid, createdat);`,
+		},
+		Down: []string{`DROP TABLE notification;`},
+	},
+	{
+		Version: 16,
+		Name:    "allow multiple self-posts without link URLs",
+		Up: []string{
+			`DROP INDEX post_linkurl;`,
+			`CREATE UNIQUE INDEX post_linkurl ON post(linkurl) WHERE linkurl <> '';`,
+		},
+		Down: []string{
+			`DROP INDEX post_linkurl;`,
+			`CREATE UNIQUE INDEX post_linkurl ON post(linkurl);`,
+		},
+	},
+	{
+		Version: 17,
+		Name:    "add dead link checks",
+		Up: []string{
+			`ALTER TABLE post ADD COLUMN linkstatus integer NOT NULL DEFAULT 0;`,
+			`ALTER TABLE post ADD COLUMN linkdead boolean NOT NULL DEFAULT false;`,
+			`ALTER TABLE post ADD COLUMN linkarchiveurl text NOT NULL DEFAULT '';`,
+			`CREATE TABLE link_check (postid integer PRIMARY KEY, checkedat {{timestamp}} NOT NULL);`,
+			`CREATE INDEX link_check_checkedat ON link_check(checkedat);`,
+		},
+		Down: []string{
+			`DROP TABLE link_check;`,
+			`ALTER TABLE post DROP COLUMN linkarchiveurl;`,
+			`ALTER TABLE post DROP COLUMN linkdead;`,
+			`ALTER TABLE post DROP COLUMN linkstatus;`,
+		},
+	},
+	{
+		Version: 18,
+		Name:    "add trigram index on post.title",
+		UpFunc:  createPostTitleTrigramIndex,
+		Down:    []string{`DROP INDEX IF EXISTS post_title_trgm;`},
+	},
+	{
+		Version: 19,
+		Name:    "add post.velocity",
+		Up: []string{
+			`ALTER TABLE post ADD COLUMN velocity double precision NOT NULL DEFAULT 0;`,
+			`CREATE INDEX post_velocity ON post(velocity);`,
+			`CREATE INDEX vote_votedat ON vote(votedat);`,
+			`CREATE INDEX comment_submittedat ON comment(submittedat);`,
+		},
+		Down: []string{
+			`DROP INDEX comment_submittedat;`,
+			`DROP INDEX vote_votedat;`,
+			`DROP INDEX post_velocity;`,
+			`ALTER TABLE post DROP COLUMN velocity;`,
+		},
+	},
+	{
+		Version: 20,
+		Name:    "add follow table",
+		Up: []string{
+			`CREATE TABLE follow (userid integer NOT NULL, kind text NOT NULL, name text NOT NULL, hide boolean NOT NULL DEFAULT false, createdat {{timestamp}} NOT NULL, PRIMARY KEY (userid, kind, name));`,
+		},
+		Down: []string{`DROP TABLE follow;`},
+	},
+	{
+		Version: 21,
+		Name:    "add user settings",
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN displayname text NOT NULL DEFAULT '';`,
+			`ALTER TABLE users ADD COLUMN digestsubscribed boolean NOT NULL DEFAULT false;`,
+			`ALTER TABLE users ADD COLUMN theme text NOT NULL DEFAULT '';`,
+			`ALTER TABLE users ADD COLUMN defaultsort text NOT NULL DEFAULT '';`,
+		},
+		Down: []string{
+			`ALTER TABLE users DROP COLUMN defaultsort;`,
+			`ALTER TABLE users DROP COLUMN theme;`,
+			`ALTER TABLE users DROP COLUMN digestsubscribed;`,
+			`ALTER TABLE users DROP COLUMN displayname;`,
+		},
+	},
+	{
+		Version: 22,
+		Name:    "add users.passwordchangedat",
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN passwordchangedat {{timestamp}} NOT NULL DEFAULT '1970-01-01 00:00:00';`,
+		},
+		Down: []string{`ALTER TABLE users DROP COLUMN passwordchangedat;`},
+	},
+	{
+		Version: 23,
+		Name:    "add two-factor authentication",
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN totpsecret text NOT NULL DEFAULT '';`,
+			`ALTER TABLE users ADD COLUMN recoverycodehashes text NOT NULL DEFAULT '';`,
+		},
+		Down: []string{
+			`ALTER TABLE users DROP COLUMN recoverycodehashes;`,
+			`ALTER TABLE users DROP COLUMN totpsecret;`,
+		},
+	},
+	{
+		Version: 24,
+		Name:    "add session table",
+		Up: []string{
+			`CREATE TABLE session (id {{serial}}, userid integer NOT NULL, hash {{bytes}} NOT NULL, useragent text NOT NULL, createdat {{timestamp}} NOT NULL, lastusedat {{timestamp}} NOT NULL);`,
+			`CREATE UNIQUE INDEX session_hash ON session(hash);`,
+			`CREATE INDEX session_userid ON session(userid);`,
+			`CREATE INDEX session_lastusedat ON session(lastusedat);`,
+		},
+		Down: []string{`DROP TABLE session;`},
+	},
+	{
+		Version: 25,
+		Name:    "add job table",
+		Up: []string{
+			`CREATE TABLE job (id {{serial}}, kind text NOT NULL, payload text NOT NULL, key text NOT NULL, state text NOT NULL, attempts integer NOT NULL, maxattempts integer NOT NULL, lasterror text NOT NULL, runat {{timestamp}} NOT NULL, createdat {{timestamp}} NOT NULL, updatedat {{timestamp}} NOT NULL);`,
+			`CREATE INDEX job_state_runat ON job(state, runat);`,
+			`CREATE UNIQUE INDEX job_key ON job(key) WHERE key <> '' AND state IN ('pending', 'running');`,
+		},
+		Down: []string{`DROP TABLE job;`},
+	},
+	{
+		Version: 26,
+		Name:    "add task_lock table",
+		Up: []string{
+			`CREATE TABLE task_lock (name text PRIMARY KEY, owner text NOT NULL, expiresat {{timestamp}} NOT NULL);`,
+		},
+		Down: []string{`DROP TABLE task_lock;`},
+	},
+	{
+		Version: 27,
+		Name:    "add vote_suspect table and vote.nullified",
+		Up: []string{
+			`CREATE TABLE vote_suspect (userid integer PRIMARY KEY, login text NOT NULL, reason text NOT NULL, flaggedat {{timestamp}} NOT NULL, nullified boolean NOT NULL DEFAULT false, dismissed boolean NOT NULL DEFAULT false);`,
+			`CREATE INDEX vote_suspect_flaggedat ON vote_suspect(flaggedat);`,
+			`ALTER TABLE vote ADD COLUMN nullified boolean NOT NULL DEFAULT false;`,
+		},
+		Down: []string{
+			`ALTER TABLE vote DROP COLUMN nullified;`,
+			`DROP TABLE vote_suspect;`,
+		},
+	},
+	{
+		Version: 28,
+		Name:    "add client_record and client_salt tables",
+		Up: []string{
+			`CREATE TABLE client_record (id {{serial}}, action text NOT NULL, userid integer NOT NULL, postid integer NOT NULL, iphash text NOT NULL, useragenthash text NOT NULL, createdat {{timestamp}} NOT NULL);`,
+			`CREATE INDEX client_record_createdat ON client_record(createdat);`,
+			`CREATE INDEX client_record_userid_postid ON client_record(userid, postid);`,
+			`CREATE INDEX client_record_postid ON client_record(postid);`,
+			`CREATE INDEX client_record_iphash ON client_record(iphash);`,
+			`CREATE TABLE client_salt (period bigint PRIMARY KEY, salt {{bytes}} NOT NULL);`,
+		},
+		Down: []string{
+			`DROP TABLE client_salt;`,
+			`DROP TABLE client_record;`,
+		},
+	},
+	{
+		Version: 29,
+		Name:    "add audit_log table",
+		Up: []string{
+			`CREATE TABLE audit_log (id {{serial}}, actoruserid integer NOT NULL, action text NOT NULL, target text NOT NULL, details text NOT NULL, reason text NOT NULL, createdat {{timestamp}} NOT NULL);`,
+			`CREATE INDEX audit_log_createdat ON audit_log(createdat);`,
+			`CREATE INDEX audit_log_actoruserid ON audit_log(actoruserid);`,
+			`CREATE INDEX audit_log_target ON audit_log(target);`,
+		},
+		Down: []string{
+			`DROP TABLE audit_log;`,
+		},
+	},
+	{
+		Version: 30,
+		Name:    "add post.deletedat",
+		Up: []string{
+			`ALTER TABLE post ADD COLUMN deletedat {{timestamp}};`,
+			`CREATE INDEX post_deletedat ON post(deletedat) WHERE deletedat IS NOT NULL;`,
+			// Deleted posts' link URLs may be submitted again.
+			`DROP INDEX post_linkurl;`,
+			`CREATE UNIQUE INDEX post_linkurl ON post(linkurl) WHERE linkurl <> '' AND deletedat IS NULL;`,
+		},
+		Down: []string{
+			// Purge deleted posts, which would otherwise reappear.
+			`DELETE FROM comment_vote WHERE commentid IN (SELECT id FROM comment WHERE postid IN (SELECT id FROM post WHERE deletedat IS NOT NULL));`,
+			`DELETE FROM post_tag WHERE postid IN (SELECT id FROM post WHERE deletedat IS NOT NULL);`,
+			`DELETE FROM vote WHERE postid IN (SELECT id FROM post WHERE deletedat IS NOT NULL);`,
+			`DELETE FROM flag WHERE postid IN (SELECT id FROM post WHERE deletedat IS NOT NULL);`,
+			`DELETE FROM saved_posts WHERE postid IN (SELECT id FROM post WHERE deletedat IS NOT NULL);`,
+			`DELETE FROM hidden_posts WHERE postid IN (SELECT id FROM post WHERE deletedat IS NOT NULL);`,
+			`DELETE FROM notification WHERE postid IN (SELECT id FROM post WHERE deletedat IS NOT NULL);`,
+			`DELETE FROM comment WHERE postid IN (SELECT id FROM post WHERE deletedat IS NOT NULL);`,
+			`DELETE FROM thumbnail_attempt WHERE postid IN (SELECT id FROM post WHERE deletedat IS NOT NULL);`,
+			`DELETE FROM link_check WHERE postid IN (SELECT id FROM post WHERE deletedat IS NOT NULL);`,
+			`DELETE FROM post WHERE deletedat IS NOT NULL;`,
+			`DROP INDEX post_linkurl;`,
+			`CREATE UNIQUE INDEX post_linkurl ON post(linkurl) WHERE linkurl <> '';`,
+			`DROP INDEX post_deletedat;`,
+			`ALTER TABLE post DROP COLUMN deletedat;`,
+		},
+	},
+	{
+		Version: 31,
+		Name:    "add post_revision table and post.editedat",
+		Up: []string{
+			`CREATE TABLE post_revision (id {{serial}}, postid integer NOT NULL, title text NOT NULL, linkurl text NOT NULL, body text NOT NULL, editoruserid integer NOT NULL, createdat {{timestamp}} NOT NULL);`,
+			`CREATE INDEX post_revision_postid ON post_revision(postid, createdat);`,
+			`ALTER TABLE post ADD COLUMN editedat {{timestamp}};`,
+		},
+		Down: []string{
+			`ALTER TABLE post DROP COLUMN editedat;`,
+			`DROP TABLE post_revision;`,
+		},
+	},
+	{
+		Version: 32,
+		Name:    "add post.idempotencykey",
+		Up: []string{
+			`ALTER TABLE post ADD COLUMN idempotencykey text NOT NULL DEFAULT '';`,
+			`CREATE UNIQUE INDEX post_idempotencykey ON post(authoruserid, idempotencykey) WHERE idempotencykey <> '';`,
+		},
+		Down: []string{
+			`DROP INDEX post_idempotencykey;`,
+			`ALTER TABLE post DROP COLUMN idempotencykey;`,
+		},
+	},
+	{
+		Version: 33,
+		Name:    "add post.version",
+		Up: []string{
+			`ALTER TABLE post ADD COLUMN version integer NOT NULL DEFAULT 1;`,
+		},
+		Down: []string{
+			`ALTER TABLE post DROP COLUMN version;`,
+		},
+	},
+	{
+		Version: 34,
+		Name:    "add post.queued and comment.dead",
+		Up: []string{
+			`ALTER TABLE post ADD COLUMN queued boolean NOT NULL DEFAULT false;`,
+			`ALTER TABLE comment ADD COLUMN dead boolean NOT NULL DEFAULT false;`,
+		},
+		Down: []string{
+			`ALTER TABLE comment DROP COLUMN dead;`,
+			`ALTER TABLE post DROP COLUMN queued;`,
+		},
+	},
+	{
+		Version: 35,
+		Name:    "add report table and post.reports",
+		Up: []string{
+			`CREATE TABLE report (id {{serial}}, postid integer NOT NULL, category text NOT NULL, details text NOT NULL DEFAULT '', clienthash text NOT NULL DEFAULT '', createdat {{timestamp}} NOT NULL);`,
+			`CREATE INDEX report_postid ON report(postid);`,
+			`CREATE INDEX report_clienthash_createdat ON report(clienthash, createdat);`,
+			`ALTER TABLE post ADD COLUMN reports integer NOT NULL DEFAULT 0;`,
+		},
+		Down: []string{
+			`ALTER TABLE post DROP COLUMN reports;`,
+			`DROP TABLE report;`,
+		},
+	},
+	{
+		Version: 36,
+		Name:    "allow reusing deleted posts' idempotency keys",
+		Up: []string{
+			`DROP INDEX post_idempotencykey;`,
+			`CREATE UNIQUE INDEX post_idempotencykey ON post(authoruserid, idempotencykey) WHERE idempotencykey <> '' AND deletedat IS NULL;`,
+		},
+		Down: []string{
+			// Forget deleted posts' keys, which live posts may have reused.
+			`UPDATE post SET idempotencykey='' WHERE deletedat IS NOT NULL;`,
+			`DROP INDEX post_idempotencykey;`,
+			`CREATE UNIQUE INDEX post_idempotencykey ON post(authoruserid, idempotencykey) WHERE idempotencykey <> '';`,
+		},
+	},
+}
+
+// A MigrationStatus describes whether a migration has been applied.
+type MigrationStatus struct {
+	*Migration
+
+	// AppliedAt is when the migration was applied, or nil if it hasn't been.
+	AppliedAt *time.Time
+}
+
+// appliedMigration is a row in the schema_migrations table.
+type appliedMigration struct {
+	Version   int
+	AppliedAt time.Time
+}
+
+func init() {
+	DB.AddTableWithName(appliedMigration{}, "schema_migrations").SetKeys(false, "Version")
+}
+
+// MigrateUp applies all pending migrations, in order, and returns the
+// migrations that it applied.
+func MigrateUp() ([]*Migration, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []*Migration
+	for _, m := range sortedMigrations() {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := transact(DBH, func(tx modl.SqlExecutor) error {
+			if err := execMigrationSQL(tx, m.Up); err != nil {
+				return err
+			}
+			if m.UpFunc != nil {
+				if err := m.UpFunc(tx); err != nil {
+					return err
+				}
+			}
+			return tx.Insert(&appliedMigration{Version: m.Version, AppliedAt: time.Now()})
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d (%s): %s", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateDown reverts the most recently applied migration and returns it. If
+// no migrations have been applied, it returns nil.
+func MigrateDown() (*Migration, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	ms := sortedMigrations()
+	for i := len(ms) - 1; i >= 0; i-- {
+		m := ms[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		err := transact(DBH, func(tx modl.SqlExecutor) error {
+			if err := execMigrationSQL(tx, m.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version=$1;`, m.Version)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("migration %d (%s): %s", m.Version, m.Name, err)
+		}
+		return m, nil
+	}
+	return nil, nil
+}
+
+// MigrationStatuses returns the status of each migration, in order.
+func MigrationStatuses() ([]*MigrationStatus, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []*MigrationStatus
+	for _, m := range sortedMigrations() {
+		st := &MigrationStatus{Migration: m}
+		if t, ok := applied[m.Version]; ok {
+			st.AppliedAt = &t
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// PendingMigrations returns the migrations that haven't been applied, in
+// order.
+func PendingMigrations() ([]*Migration, error) {
+	statuses, err := MigrationStatuses()
+	if err != nil {
+		return nil, err
+	}
+	var pending []*Migration
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			pending = append(pending, st.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// appliedMigrations returns the times at which each applied migration was
+// applied, keyed by version. It creates the schema_migrations table if it
+// doesn't exist.
+func appliedMigrations() (map[int]time.Time, error) {
+	if err := execMigrationSQL(DBH, []string{`CREATE TABLE IF NOT EXISTS schema_migrations (version integer PRIMARY KEY, appliedat {{timestamp}} NOT NULL);`}); err != nil {
+		return nil, err
+	}
+
+	var rows []*appliedMigration
+	if err := DBH.Select(&rows, `SELECT * FROM schema_migrations;`); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+func sortedMigrations() []*Migration {
+	ms := make([]*Migration, len(Migrations))
+	copy(ms, Migrations)
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+	return ms
+}
+
+var (
+	postgresTypes = strings.NewReplacer("{{serial}}", "serial PRIMARY KEY", "{{timestamp}}", "timestamp with time zone", "{{bytes}}", "bytea")
+	sqliteTypes   = strings.NewReplacer("{{serial}}", "integer PRIMARY KEY AUTOINCREMENT", "{{timestamp}}", "datetime", "{{bytes}}", "blob")
+)
+
+func execMigrationSQL(dbh modl.SqlExecutor, stmts []string) error {
+	types := postgresTypes
+	if isSQLite() {
+		types = sqliteTypes
+	}
+	for _, stmt := range stmts {
+		if _, err := dbh.Exec(types.Replace(stmt)); err != nil {
+			return fmt.Errorf("%s (in query %q)", err, stmt)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package datastore
+
+import (
+	"log"
+	"testing"
+)
+
+func TestMigrations_db(t *testing.T) {
+	// Leave the DB fully migrated for the other tests.
+	defer func() {
+		if _, err := MigrateUp(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	statuses, err := MigrationStatuses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			t.Errorf("migration %d is pending, want all applied", st.Version)
+		}
+	}
+
+	last := Migrations[len(Migrations)-1]
+	m, err := MigrateDown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != last {
+		t.Errorf("reverted migration %d, want %d", m.Version, last.Version)
+	}
+
+	applied, err := MigrateUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || applied[0] != last {
+		t.Errorf("got applied migrations %v, want only %d", applied, last.Version)
+	}
+}
@@ -0,0 +1,121 @@
+package datastore
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// ModerationStore applies bulk moderation actions (see
+// thesrc.BulkModeration) in the datastore.
+type ModerationStore interface {
+	// Bulk applies mod in a single transaction, which also records an
+	// audit log entry for each post and comment that mod acts on, with
+	// audit's ActorUserID and Reason. If any of the posts or comments
+	// doesn't exist, nothing is changed and thesrc.ErrPostNotFound or
+	// thesrc.ErrCommentNotFound is returned.
+	Bulk(mod *thesrc.BulkModeration, audit *thesrc.AuditEntry) error
+}
+
+type moderationStore struct{ *Datastore }
+
+func (s *moderationStore) Bulk(mod *thesrc.BulkModeration, audit *thesrc.AuditEntry) error {
+	defer s.observe(time.Now(), "Moderation.Bulk")
+	if err := mod.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	record := func(tx modl.SqlExecutor, action, target, details string) error {
+		e := *audit
+		e.ID, e.Action, e.Target, e.Details, e.CreatedAt = 0, action, target, details, now
+		return tx.Insert(&e)
+	}
+
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		for _, id := range mod.PostIDs {
+			var posts []*thesrc.Post
+			if err := tx.Select(&posts, `SELECT * FROM post WHERE id=$1 AND deletedat IS NULL;`, id); err != nil {
+				return err
+			}
+			if len(posts) == 0 {
+				return thesrc.ErrPostNotFound
+			}
+
+			var action, details string
+			switch mod.Action {
+			case thesrc.BulkKill:
+				action, details = thesrc.AuditKillPost, posts[0].Title
+				if _, err := tx.Exec(`UPDATE post SET dead=true, queued=false, reports=0, version=version+1 WHERE id=$1;`, id); err != nil {
+					return err
+				}
+			case thesrc.BulkQueue:
+				action, details = thesrc.AuditQueuePost, posts[0].Title
+				if _, err := tx.Exec(`UPDATE post SET hidden=true, queued=true, reports=0, version=version+1 WHERE id=$1;`, id); err != nil {
+					return err
+				}
+			case thesrc.BulkUntag:
+				action, details = thesrc.AuditUntagPost, strings.Join(mod.Tags, ", ")
+				for _, tag := range mod.Tags {
+					if _, err := tx.Exec(`DELETE FROM post_tag WHERE postid=$1 AND tagid IN (SELECT id FROM tag WHERE name=$2);`, id, tag); err != nil {
+						return err
+					}
+				}
+				if _, err := tx.Exec(`UPDATE post SET version=version+1 WHERE id=$1;`, id); err != nil {
+					return err
+				}
+			case thesrc.BulkRetag:
+				action, details = thesrc.AuditRetagPost, strings.Join(mod.Tags, ", ")
+				if _, err := tx.Exec(`DELETE FROM post_tag WHERE postid=$1;`, id); err != nil {
+					return err
+				}
+				if err := setPostTags(tx, id, mod.Tags); err != nil {
+					return err
+				}
+				if _, err := tx.Exec(`UPDATE post SET version=version+1 WHERE id=$1;`, id); err != nil {
+					return err
+				}
+			}
+			if err := record(tx, action, "post:"+strconv.Itoa(id), details); err != nil {
+				return err
+			}
+		}
+		if mod.Action == thesrc.BulkUntag || mod.Action == thesrc.BulkRetag {
+			if err := deleteUnusedTags(tx); err != nil {
+				return err
+			}
+		}
+
+		for _, id := range mod.CommentIDs {
+			res, err := tx.Exec(`UPDATE comment SET dead=true WHERE id=$1;`, id)
+			if err != nil {
+				return err
+			}
+			if n, err := res.RowsAffected(); err != nil {
+				return err
+			} else if n == 0 {
+				return thesrc.ErrCommentNotFound
+			}
+			if err := record(tx, thesrc.AuditKillComment, "comment:"+strconv.Itoa(id), ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+type MockModerationStore struct {
+	Bulk_ func(mod *thesrc.BulkModeration, audit *thesrc.AuditEntry) error
+}
+
+var _ ModerationStore = &MockModerationStore{}
+
+func (s *MockModerationStore) Bulk(mod *thesrc.BulkModeration, audit *thesrc.AuditEntry) error {
+	if s.Bulk_ == nil {
+		return nil
+	}
+	return s.Bulk_(mod, audit)
+}
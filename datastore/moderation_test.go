@@ -0,0 +1,153 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestModerationStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM comment;`)
+	tx.Exec(`DELETE FROM audit_log;`)
+
+	testModerationStore(t, NewDatastore(tx))
+}
+
+func TestModerationStore_Bulk_failure_db(t *testing.T) {
+	// Use the DB itself (not a test transaction), so that Bulk begins and
+	// must end its own transaction.
+	for _, table := range []string{"post", "comment", "audit_log"} {
+		DBH.Exec(`DELETE FROM ` + table + `;`) // test on a clean DB
+		defer DBH.Exec(`DELETE FROM ` + table + `;`)
+	}
+
+	d := NewDatastore(DBH)
+	post := &thesrc.Post{Title: "a", LinkURL: "http://example.com"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+	audit := &thesrc.AuditEntry{ActorUserID: 1}
+
+	// Each bulk action fails after changing the first post.
+	if err := d.Moderation.Bulk(&thesrc.BulkModeration{Action: thesrc.BulkQueue, PostIDs: []int{post.ID, post.ID + 1000}}, audit); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v queueing a nonexistent post, want ErrPostNotFound", err)
+	}
+	if err := d.Moderation.Bulk(&thesrc.BulkModeration{Action: thesrc.BulkKill, PostIDs: []int{post.ID}, CommentIDs: []int{1000}}, audit); err != thesrc.ErrCommentNotFound {
+		t.Errorf("got error %v killing a nonexistent comment, want ErrCommentNotFound", err)
+	}
+
+	// The failures must not leave their transactions (and connections)
+	// open, or any of their changes behind.
+	if n := DB.Db.Stats().InUse; n != 0 {
+		t.Errorf("got %d connections in use after failed bulk moderations, want 0", n)
+	}
+	got, err := d.Posts.Get(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Hidden || got.Queued || got.Dead || got.Version != post.Version {
+		t.Errorf("got post %+v after failed bulk moderations, want it unchanged", got)
+	}
+	if entries, err := d.AuditLog.List(nil); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 0 {
+		t.Errorf("got audit entries %+v after failed bulk moderations, want none", entries)
+	}
+}
+
+// testModerationStore tests bulk moderation. d must be empty.
+func testModerationStore(t *testing.T, d *Datastore) {
+	a := &thesrc.Post{Title: "a", LinkURL: "http://example.com/a", Tags: []string{"go", "rust"}}
+	b := &thesrc.Post{Title: "b", LinkURL: "http://example.com/b", Tags: []string{"go"}}
+	for _, p := range []*thesrc.Post{a, b} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	comment := &thesrc.Comment{PostID: a.ID, Body: "c"}
+	if err := d.Comments.Create(comment); err != nil {
+		t.Fatal(err)
+	}
+	audit := &thesrc.AuditEntry{ActorUserID: 1, Reason: "cleanup"}
+	get := func(id int) *thesrc.Post {
+		post, err := d.Posts.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return post
+	}
+
+	if err := d.Moderation.Bulk(&thesrc.BulkModeration{Action: thesrc.BulkUntag, PostIDs: []int{a.ID, b.ID}, Tags: []string{"go"}}, audit); err != nil {
+		t.Fatal(err)
+	}
+	if tags := get(a.ID).Tags; !reflect.DeepEqual(tags, []string{"rust"}) {
+		t.Errorf("got tags %q after untagging, want [rust]", tags)
+	}
+	if tags := get(b.ID).Tags; len(tags) != 0 {
+		t.Errorf("got tags %q after untagging, want none", tags)
+	}
+
+	if err := d.Moderation.Bulk(&thesrc.BulkModeration{Action: thesrc.BulkRetag, PostIDs: []int{b.ID}, Tags: []string{"python"}}, audit); err != nil {
+		t.Fatal(err)
+	}
+	if tags := get(b.ID).Tags; !reflect.DeepEqual(tags, []string{"python"}) {
+		t.Errorf("got tags %q after retagging, want [python]", tags)
+	}
+
+	if err := d.Moderation.Bulk(&thesrc.BulkModeration{Action: thesrc.BulkQueue, PostIDs: []int{a.ID}}, audit); err != nil {
+		t.Fatal(err)
+	}
+	if post := get(a.ID); !post.Hidden || !post.Queued {
+		t.Errorf("got post %+v after queueing, want it hidden and queued", post)
+	}
+	queue, err := d.Posts.List(&thesrc.PostListOptions{Flagged: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].ID != a.ID {
+		t.Errorf("got moderation queue %+v, want the queued post", queue)
+	}
+
+	// If any post doesn't exist, nothing is changed.
+	if err := d.Moderation.Bulk(&thesrc.BulkModeration{Action: thesrc.BulkKill, PostIDs: []int{b.ID, b.ID + 1000}}, audit); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v killing a nonexistent post, want ErrPostNotFound", err)
+	}
+	if get(b.ID).Dead {
+		t.Error("post was killed although the bulk moderation failed")
+	}
+	if err := d.Moderation.Bulk(&thesrc.BulkModeration{Action: thesrc.BulkQueue, CommentIDs: []int{comment.ID}}, audit); err == nil {
+		t.Error("got no error queueing a comment, want a validation error")
+	}
+
+	if err := d.Moderation.Bulk(&thesrc.BulkModeration{Action: thesrc.BulkKill, PostIDs: []int{a.ID}, CommentIDs: []int{comment.ID}}, audit); err != nil {
+		t.Fatal(err)
+	}
+	if post := get(a.ID); !post.Dead || post.Queued {
+		t.Errorf("got post %+v after killing, want it dead and no longer queued", post)
+	}
+	if c, err := d.Comments.Get(comment.ID); err != nil {
+		t.Fatal(err)
+	} else if !c.Dead {
+		t.Error("comment wasn't killed")
+	}
+
+	entries, err := d.AuditLog.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{thesrc.AuditUntagPost: 2, thesrc.AuditRetagPost: 1, thesrc.AuditQueuePost: 1, thesrc.AuditKillPost: 1, thesrc.AuditKillComment: 1}
+	got := map[string]int{}
+	for _, e := range entries {
+		got[e.Action]++
+		if e.ActorUserID != 1 || e.Reason != "cleanup" {
+			t.Errorf("got audit entry %+v, want one by user 1 with the reason", e)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got audit entries per action %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+package datastore
+
+import (
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.Notification{}, "notification").SetKeys(true, "ID")
+}
+
+// NotificationsStore accesses notifications in the datastore. Notifications
+// are listed and marked as read on behalf of a specific user (unlike
+// thesrc.NotificationsService, which manages the authenticated user's
+// notifications).
+type NotificationsStore interface {
+	// Create a notification. If successful, notification.ID will be the new
+	// notification's ID.
+	Create(notification *thesrc.Notification) error
+
+	// List a user's notifications, newest first.
+	List(userID int, opt *thesrc.NotificationListOptions) ([]*thesrc.Notification, error)
+
+	// UnreadCount returns the number of a user's unread notifications.
+	UnreadCount(userID int) (int, error)
+
+	// MarkRead marks one of a user's notifications as read. If the user has
+	// no notification with the given ID, thesrc.ErrNotificationNotFound is
+	// returned.
+	MarkRead(userID, id int) error
+
+	// MarkAllRead marks all of a user's notifications as read.
+	MarkAllRead(userID int) error
+}
+
+type notificationsStore struct{ *Datastore }
+
+func (s *notificationsStore) Create(notification *thesrc.Notification) error {
+	defer s.observe(time.Now(), "Notifications.Create")
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	return s.dbh.Insert(notification)
+}
+
+func (s *notificationsStore) List(userID int, opt *thesrc.NotificationListOptions) ([]*thesrc.Notification, error) {
+	defer s.observe(time.Now(), "Notifications.List")
+	if opt == nil {
+		opt = &thesrc.NotificationListOptions{}
+	}
+
+	sql := `SELECT * FROM notification WHERE userid=$3`
+	if opt.Unread {
+		sql += ` AND NOT read`
+	}
+	sql += ` ORDER BY createdat DESC, id DESC LIMIT $1 OFFSET $2;`
+
+	var notifications []*thesrc.Notification
+	if err := s.dbh.Select(&notifications, sql, opt.PerPageOrDefault(), opt.Offset(), userID); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (s *notificationsStore) UnreadCount(userID int) (int, error) {
+	defer s.observe(time.Now(), "Notifications.UnreadCount")
+	var rows []*struct{ Count int }
+	if err := s.dbh.Select(&rows, `SELECT COUNT(*) AS count FROM notification WHERE userid=$1 AND NOT read;`, userID); err != nil {
+		return 0, err
+	}
+	return rows[0].Count, nil
+}
+
+func (s *notificationsStore) MarkRead(userID, id int) error {
+	defer s.observe(time.Now(), "Notifications.MarkRead")
+	res, err := s.dbh.Exec(`UPDATE notification SET read=true WHERE id=$1 AND userid=$2;`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return thesrc.ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (s *notificationsStore) MarkAllRead(userID int) error {
+	defer s.observe(time.Now(), "Notifications.MarkAllRead")
+	_, err := s.dbh.Exec(`UPDATE notification SET read=true WHERE userid=$1 AND NOT read;`, userID)
+	return err
+}
+
+type MockNotificationsStore struct {
+	Create_      func(notification *thesrc.Notification) error
+	List_        func(userID int, opt *thesrc.NotificationListOptions) ([]*thesrc.Notification, error)
+	UnreadCount_ func(userID int) (int, error)
+	MarkRead_    func(userID, id int) error
+	MarkAllRead_ func(userID int) error
+}
+
+var _ NotificationsStore = &MockNotificationsStore{}
+
+func (s *MockNotificationsStore) Create(notification *thesrc.Notification) error {
+	if s.Create_ == nil {
+		return nil
+	}
+	return s.Create_(notification)
+}
+
+func (s *MockNotificationsStore) List(userID int, opt *thesrc.NotificationListOptions) ([]*thesrc.Notification, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(userID, opt)
+}
+
+func (s *MockNotificationsStore) UnreadCount(userID int) (int, error) {
+	if s.UnreadCount_ == nil {
+		return 0, nil
+	}
+	return s.UnreadCount_(userID)
+}
+
+func (s *MockNotificationsStore) MarkRead(userID, id int) error {
+	if s.MarkRead_ == nil {
+		return nil
+	}
+	return s.MarkRead_(userID, id)
+}
+
+func (s *MockNotificationsStore) MarkAllRead(userID int) error {
+	if s.MarkAllRead_ == nil {
+		return nil
+	}
+	return s.MarkAllRead_(userID)
+}
@@ -0,0 +1,59 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestNotificationsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM notification;`) // test on a clean DB
+
+	d := NewDatastore(tx)
+	older := &thesrc.Notification{UserID: 1, Type: thesrc.NotificationReply, ActorUserID: 2, PostID: 3, CreatedAt: time.Now().Add(-time.Hour)}
+	newer := &thesrc.Notification{UserID: 1, Type: thesrc.NotificationMention, ActorUserID: 2, PostID: 3, CommentID: 4}
+	other := &thesrc.Notification{UserID: 2, Type: thesrc.NotificationReply, ActorUserID: 1, PostID: 3}
+	for _, n := range []*thesrc.Notification{older, newer, other} {
+		if err := d.Notifications.Create(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notifications, err := d.Notifications.List(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 2 || notifications[0].ID != newer.ID || notifications[1].ID != older.ID {
+		t.Errorf("got notifications %+v, want newest of user 1's first", notifications)
+	}
+	if n, err := d.Notifications.UnreadCount(1); err != nil || n != 2 {
+		t.Errorf("got unread count %d and error %v, want 2 and nil", n, err)
+	}
+
+	if err := d.Notifications.MarkRead(1, older.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notifications.MarkRead(1, other.ID); err != thesrc.ErrNotificationNotFound {
+		t.Errorf("got error %v marking another user's notification as read, want %v", err, thesrc.ErrNotificationNotFound)
+	}
+	unread, err := d.Notifications.List(1, &thesrc.NotificationListOptions{Unread: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unread) != 1 || unread[0].ID != newer.ID {
+		t.Errorf("got unread notifications %+v, want only %d", unread, newer.ID)
+	}
+
+	if err := d.Notifications.MarkAllRead(1); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := d.Notifications.UnreadCount(1); n != 0 {
+		t.Errorf("got unread count %d after marking all read, want 0", n)
+	}
+	if n, _ := d.Notifications.UnreadCount(2); n != 1 {
+		t.Errorf("got unread count %d for another user, want 1", n)
+	}
+}
@@ -0,0 +1,42 @@
+package datastore
+
+import (
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.PostRevision{}, "post_revision").SetKeys(true, "ID")
+}
+
+// PostRevisionsStore records post revisions (see thesrc.PostRevision) in the
+// datastore. They are listed with thesrc.PostsService.Revisions.
+type PostRevisionsStore interface {
+	// Create a post revision. If successful, rev.ID will be the new
+	// revision's ID.
+	Create(rev *thesrc.PostRevision) error
+}
+
+type postRevisionsStore struct{ *Datastore }
+
+func (s *postRevisionsStore) Create(rev *thesrc.PostRevision) error {
+	defer s.observe(time.Now(), "PostRevisions.Create")
+	if rev.CreatedAt.IsZero() {
+		rev.CreatedAt = time.Now()
+	}
+	return s.dbh.Insert(rev)
+}
+
+type MockPostRevisionsStore struct {
+	Create_ func(rev *thesrc.PostRevision) error
+}
+
+var _ PostRevisionsStore = &MockPostRevisionsStore{}
+
+func (s *MockPostRevisionsStore) Create(rev *thesrc.PostRevision) error {
+	if s.Create_ == nil {
+		return nil
+	}
+	return s.Create_(rev)
+}
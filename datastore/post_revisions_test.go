@@ -0,0 +1,68 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestPostRevisionsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM post_revision;`)
+
+	testPostRevisionsStore(t, NewDatastore(tx))
+}
+
+// testPostRevisionsStore tests d.PostRevisions, d.Posts.Revisions, and how
+// d.Posts.Update sets EditedAt. d must be empty.
+func testPostRevisionsStore(t *testing.T, d *Datastore) {
+	post := &thesrc.Post{Title: "t", Body: "b", LinkURL: "http://example.com"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing only tags isn't an edit.
+	update := &thesrc.Post{Title: "t", Body: "b", Tags: []string{"golang"}}
+	if err := d.Posts.Update(post.ID, update); err != nil {
+		t.Fatal(err)
+	}
+	if update.EditedAt != nil {
+		t.Errorf("got EditedAt %v after changing tags, want nil", update.EditedAt)
+	}
+	update = &thesrc.Post{Title: "t2", Body: "b"}
+	if err := d.Posts.Update(post.ID, update); err != nil {
+		t.Fatal(err)
+	}
+	if update.EditedAt == nil {
+		t.Error("got nil EditedAt after editing title")
+	}
+
+	now := time.Now().Truncate(time.Second)
+	revisions := []*thesrc.PostRevision{
+		{PostID: post.ID, Title: "t2", LinkURL: post.LinkURL, Body: "b", EditorUserID: 2, CreatedAt: now},
+		{PostID: post.ID, Title: "t", LinkURL: post.LinkURL, Body: "b", EditorUserID: 1, CreatedAt: now.Add(-time.Hour)},
+		{PostID: post.ID + 1, Title: "x", CreatedAt: now},
+	}
+	for _, rev := range revisions {
+		if err := d.PostRevisions.Create(rev); err != nil {
+			t.Fatal(err)
+		}
+		if rev.ID == 0 {
+			t.Error("got ID == 0, want non-zero")
+		}
+	}
+
+	got, err := d.Posts.Revisions(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != revisions[1].ID || got[1].ID != revisions[0].ID {
+		t.Errorf("got revisions %+v, want %d and %d (oldest first)", got, revisions[1].ID, revisions[0].ID)
+	}
+	if len(got) == 2 && (got[1].Title != "t2" || got[1].EditorUserID != 2) {
+		t.Errorf("got revision %+v, want title t2 by user 2", got[1])
+	}
+}
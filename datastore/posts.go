@@ -1,6 +1,7 @@
 package datastore
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -12,27 +13,33 @@ import (
 
 func init() {
 	DB.AddTableWithName(thesrc.Post{}, "post").SetKeys(true, "ID")
-	createSQL = append(createSQL,
-		`CREATE INDEX post_submittedat ON post(submittedat DESC);`,
-		`CREATE UNIQUE INDEX post_linkurl ON post(linkurl);`,
-	)
-
 }
 
 type postsStore struct{ *Datastore }
 
+var _ thesrc.PostsService = &postsStore{}
+
+// getPostQuery is prepared because posts are fetched by ID on nearly every
+// page. Deleted posts aren't found.
+var getPostQuery = prepared(`SELECT * FROM post WHERE id=$1 AND deletedat IS NULL;`)
+
 func (s *postsStore) Get(id int) (*thesrc.Post, error) {
+	defer s.observe(time.Now(), "Posts.Get")
 	var posts []*thesrc.Post
-	if err := s.dbh.Select(&posts, `SELECT * FROM post WHERE id=$1;`, id); err != nil {
+	if err := getPostQuery.Select(s.dbh, &posts, id); err != nil {
 		return nil, err
 	}
 	if len(posts) == 0 {
 		return nil, thesrc.ErrPostNotFound
 	}
+	if err := loadPostTags(s.dbh, posts[0]); err != nil {
+		return nil, err
+	}
 	return posts[0], nil
 }
 
 func (s *postsStore) List(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+	defer s.observe(time.Now(), "Posts.List")
 	if opt == nil {
 		opt = &thesrc.PostListOptions{}
 	}
@@ -40,24 +47,198 @@ func (s *postsStore) List(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
 	sql := `SELECT * FROM post`
 
 	var conds []string
+	var args []interface{}
+	arg := func(a interface{}) string {
+		args = append(args, a)
+		return fmt.Sprintf("$%d", len(args))
+	}
 	if opt.CodeOnly {
-		conds = append(conds, "classification LIKE 'CODE%'")
+		conds = append(conds, "classification LIKE 'CODE%' OR linkurl = ''")
+	}
+	if opt.Tag != "" {
+		conds = append(conds, "id IN (SELECT pt.postid FROM post_tag pt INNER JOIN tag t ON t.id=pt.tagid WHERE t.name="+arg(opt.Tag)+")")
+	}
+	if opt.Domain != "" {
+		conds = append(conds, "domain="+arg(thesrc.NormalizeDomain(opt.Domain)))
+	}
+	if opt.LinkURL != "" {
+		conds = append(conds, "linkurl="+arg(opt.LinkURL))
+	}
+	if opt.AuthorUserID != 0 {
+		conds = append(conds, "authoruserid="+arg(opt.AuthorUserID))
+	}
+	if opt.SavedByUserID != 0 {
+		conds = append(conds, "id IN (SELECT postid FROM saved_posts WHERE userid="+arg(opt.SavedByUserID)+")")
+	}
+	if opt.ExcludeHiddenByUserID != 0 {
+		conds = append(conds, "id NOT IN (SELECT postid FROM hidden_posts WHERE userid="+arg(opt.ExcludeHiddenByUserID)+")")
+	}
+	if opt.SinceID != 0 {
+		conds = append(conds, "id > "+arg(opt.SinceID))
+	}
+	cursor, err := opt.Cursor()
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		// Posts after the cursor in the sort order (see PostCursor.After).
+		t := cursor.SubmittedAt
+		if isSQLite() {
+			// SQLite compares the times as text, which the driver
+			// formats in the time's location (Local, for posts
+			// submitted with time.Now).
+			t = t.Local()
+		}
+		submittedAt := arg(t)
+		after := "submittedat < " + submittedAt + " OR (submittedat = " + submittedAt + " AND id < " + arg(cursor.ID) + ")"
+		if cursor.Sort == thesrc.SortBest {
+			score := arg(cursor.Score)
+			after = "score < " + score + " OR (score = " + score + " AND (" + after + "))"
+		}
+		conds = append(conds, after)
+	}
+	for _, term := range opt.SearchTerms {
+		pattern := "%" + escapeLike(strings.ToLower(term)) + "%"
+		conds = append(conds, `lower(title) LIKE `+arg(pattern)+` ESCAPE '\' OR lower(body) LIKE `+arg(pattern)+` ESCAPE '\'`)
+	}
+	if opt.Show {
+		conds = append(conds, "lower(title) LIKE "+arg(strings.ToLower(thesrc.ShowPostTitlePrefix)+"%"))
 	}
-	if len(conds) > 0 {
-		sql += " WHERE (" + strings.Join(conds, ") AND (") + ")"
+	if !thesrc.ValidPeriod(opt.Period) {
+		return nil, fmt.Errorf("invalid period %q", opt.Period)
 	}
+	if d := thesrc.PeriodDuration(opt.Period); d != 0 {
+		if isSQLite() {
+			conds = append(conds, "julianday(submittedat) >= julianday('now') - "+arg(d.Hours()/24))
+		} else {
+			conds = append(conds, "submittedat >= now() - CAST("+arg(fmt.Sprintf("%d seconds", int64(d.Seconds())))+" AS interval)")
+		}
+	}
+	if opt.Deleted {
+		conds = append(conds, "deletedat IS NOT NULL")
+	} else if opt.Flagged {
+		conds = append(conds, "deletedat IS NULL", "flags > 0 OR reports > 0 OR spamscore > 0 OR queued")
+	} else {
+		conds = append(conds, "deletedat IS NULL", "NOT hidden AND NOT dead")
+		conds = append(conds, "authoruserid="+arg(opt.ViewerUserID)+" OR authoruserid NOT IN (SELECT id FROM users WHERE shadowbanned)")
+	}
+	sql += " WHERE (" + strings.Join(conds, ") AND (") + ")"
 
-	sql += " ORDER BY submittedat DESC LIMIT $1 OFFSET $2;"
+	switch opt.Sort {
+	case "", thesrc.SortNew:
+		sql += " ORDER BY submittedat DESC, id DESC"
+	case thesrc.SortTop:
+		ageHours := "extract(epoch FROM now() - submittedat) / 3600"
+		if isSQLite() {
+			ageHours = "(julianday('now') - julianday(submittedat)) * 24"
+		}
+		rank := "(score - 1) / power(" + ageHours + " + 2, 1.8)"
+		if opt.PersonalizedForUserID != 0 {
+			userID := arg(opt.PersonalizedForUserID)
+			rank += fmt.Sprintf(" * CASE WHEN %s THEN %v WHEN %s THEN %v ELSE 1 END", followedTopicCond(userID, true), hiddenTopicFactor, followedTopicCond(userID, false), followedTopicFactor)
+		}
+		sql += " ORDER BY " + rank + " DESC, submittedat DESC, id DESC"
+	case thesrc.SortBest:
+		sql += " ORDER BY score DESC, submittedat DESC, id DESC"
+	case thesrc.SortTrending:
+		sql += " ORDER BY velocity DESC, submittedat DESC, id DESC"
+	default:
+		return nil, fmt.Errorf("invalid sort order %q", opt.Sort)
+	}
+
+	offset := opt.Offset()
+	if cursor != nil {
+		offset = 0
+	}
+	sql += " LIMIT " + arg(opt.PerPageOrDefault()) + " OFFSET " + arg(offset) + ";"
 
 	var posts []*thesrc.Post
-	err := s.dbh.Select(&posts, sql, opt.PerPageOrDefault(), opt.Offset())
+	err = s.dbh.Select(&posts, sql, args...)
 	if err != nil {
 		return nil, err
 	}
+	if err := loadPostTags(s.dbh, posts...); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// Factors by which the SortTop ranks of posts about the topics that a user
+// follows or hides are multiplied in their personalized lists (see
+// thesrc.PostListOptions.Personalized). A post about both a followed and a
+// hidden topic is demoted.
+const (
+	followedTopicFactor = 3
+	hiddenTopicFactor   = 0.2
+)
+
+// escapeLike escapes the LIKE wildcards (and the escape character, '\') in s,
+// so that a LIKE pattern matches s literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Weights of the ways in which posts can be related (see Related), and the
+// title similarity (see similarity) above which posts are considered related
+// by their titles. The threshold is pg_trgm's default for its % operator,
+// which can use the post_title_trgm index.
+const (
+	relatedTagWeight    = 1   // per shared tag
+	relatedDomainWeight = 0.5 // for links on the same domain
+	relatedTitleWeight  = 2   // times the similarity of titles
+	minTitleSimilarity  = 0.3
+)
+
+func (s *postsStore) Related(id int, opt *thesrc.RelatedPostsOptions) ([]*thesrc.Post, error) {
+	defer s.observe(time.Now(), "Posts.Related")
+	if opt == nil {
+		opt = &thesrc.RelatedPostsOptions{}
+	}
+
+	post, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []interface{}
+	arg := func(a interface{}) string {
+		args = append(args, a)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	postID, title := arg(id), arg(post.Title)
+
+	sharedTags := "SELECT tagid FROM post_tag WHERE postid=" + postID
+	related := []string{"id IN (SELECT postid FROM post_tag WHERE tagid IN (" + sharedTags + "))"}
+	if isSQLite() {
+		related = append(related, "similarity(title, "+title+") >= "+arg(minTitleSimilarity))
+	} else {
+		related = append(related, "title % "+title)
+	}
+	rank := fmt.Sprintf("(SELECT count(*) FROM post_tag WHERE postid=post.id AND tagid IN (%s)) * %v + similarity(title, %s) * %v", sharedTags, relatedTagWeight, title, relatedTitleWeight)
+	if post.Domain != "" {
+		domain := arg(post.Domain)
+		related = append(related, "domain="+domain)
+		rank += fmt.Sprintf(" + CASE WHEN domain=%s THEN %v ELSE 0 END", domain, relatedDomainWeight)
+	}
+
+	sql := `SELECT * FROM post WHERE id <> ` + postID + ` AND NOT hidden AND NOT dead AND deletedat IS NULL`
+	sql += " AND (authoruserid=" + arg(opt.ViewerUserID) + " OR authoruserid NOT IN (SELECT id FROM users WHERE shadowbanned))"
+	sql += " AND (" + strings.Join(related, " OR ") + ")"
+	sql += " ORDER BY " + rank + " DESC, score DESC, id DESC"
+	sql += " LIMIT " + arg(opt.PerPageOrDefault()) + " OFFSET " + arg(opt.Offset()) + ";"
+
+	var posts []*thesrc.Post
+	if err := s.dbh.Select(&posts, sql, args...); err != nil {
+		return nil, err
+	}
+	if err := loadPostTags(s.dbh, posts...); err != nil {
+		return nil, err
+	}
 	return posts, nil
 }
 
 func (s *postsStore) Submit(post *thesrc.Post) (bool, error) {
+	defer s.observe(time.Now(), "Posts.Submit")
 	retries := 3
 	var wantRetry bool
 
@@ -70,29 +251,270 @@ retry:
 
 	var created bool
 	err := transact(s.dbh, func(tx modl.SqlExecutor) error {
+		var err error
+		created, wantRetry, err = submitPost(tx, post)
+		return err
+	})
+	if wantRetry {
+		goto retry
+	}
+	if err == nil && created {
+		s.Events.Publish(&thesrc.Event{Type: thesrc.EventPostCreated, Post: copyPost(post)})
+	}
+	return created, err
+}
+
+// submitPost inserts post (and its tags) in tx, unless a post with the same
+// (non-empty) link URL or (from the same author) idempotency key already
+// exists, in which case post is set to the existing post. Deleted posts are
+// ignored, so their link URLs and idempotency keys may be reused; the
+// partial unique indexes post_linkurl and post_idempotencykey (the latter
+// since migration 36) enforce the same rule.
+// If the insert failed because another such post was inserted concurrently,
+// wantRetry is true and the caller should retry in a new transaction.
+func submitPost(tx modl.SqlExecutor, post *thesrc.Post) (created, wantRetry bool, err error) {
+	if post.IdempotencyKey != "" {
 		var existing []*thesrc.Post
-		if err := tx.Select(&existing, `SELECT * FROM post WHERE linkurl=$1 LIMIT 1;`, post.LinkURL); err != nil {
-			return err
+		if err := tx.Select(&existing, `SELECT * FROM post WHERE authoruserid=$1 AND idempotencykey=$2 AND deletedat IS NULL LIMIT 1;`, post.AuthorUserID, post.IdempotencyKey); err != nil {
+			return false, false, err
+		}
+		if len(existing) > 0 {
+			*post = *existing[0]
+			return false, false, loadPostTags(tx, post)
+		}
+	}
+	if post.LinkURL != "" {
+		var existing []*thesrc.Post
+		if err := tx.Select(&existing, `SELECT * FROM post WHERE linkurl=$1 AND deletedat IS NULL LIMIT 1;`, post.LinkURL); err != nil {
+			return false, false, err
 		}
 		if len(existing) > 0 {
 			*post = *existing[0]
-			return nil
+			return false, false, loadPostTags(tx, post)
 		}
+	}
+
+	post.Domain = thesrc.LinkDomain(post.LinkURL)
+	post.Version = 1
+	if err := tx.Insert(post); err != nil {
+		if isUniqueViolation(err, "post_linkurl", "post.linkurl") || isUniqueViolation(err, "post_idempotencykey", "post.authoruserid, post.idempotencykey") {
+			time.Sleep(time.Duration(rand.Intn(75)) * time.Millisecond)
+			return false, true, err
+		}
+		return false, false, err
+	}
+	if err := setPostTags(tx, post.ID, post.Tags); err != nil {
+		return false, false, err
+	}
+	return true, false, nil
+}
+
+func (s *postsStore) CreateBatch(posts []*thesrc.Post) ([]*thesrc.PostBatchResult, error) {
+	defer s.observe(time.Now(), "Posts.CreateBatch")
+	if len(posts) > thesrc.MaxBatchSize {
+		return nil, fmt.Errorf("batch of %d posts exceeds maximum of %d", len(posts), thesrc.MaxBatchSize)
+	}
+	retries := 3
+	var wantRetry bool
+	var results []*thesrc.PostBatchResult
 
-		if err := tx.Insert(post); err != nil {
-			if strings.Contains(err.Error(), `violates unique constraint "post_linkurl"`) {
-				time.Sleep(time.Duration(rand.Intn(75)) * time.Millisecond)
-				wantRetry = true
+retry:
+	retries--
+	wantRetry = false
+	if retries == 0 {
+		return nil, fmt.Errorf("failed to submit batch of %d posts after retrying", len(posts))
+	}
+
+	// Submit copies of the posts so that a failed attempt (whose inserts are
+	// rolled back) doesn't leave IDs set on them.
+	results = make([]*thesrc.PostBatchResult, len(posts))
+	err := transact(s.dbh, func(tx modl.SqlExecutor) error {
+		for i, post := range posts {
+			post = copyPost(post)
+			var created bool
+			var err error
+			created, wantRetry, err = submitPost(tx, post)
+			if err != nil {
 				return err
 			}
-			return err
+			results[i] = &thesrc.PostBatchResult{Post: post, Created: created}
 		}
-
-		created = true
 		return nil
 	})
 	if wantRetry {
 		goto retry
 	}
-	return created, err
+	if err != nil {
+		return nil, err
+	}
+
+	for i, res := range results {
+		*posts[i] = *res.Post
+		res.Post = posts[i]
+		if res.Created {
+			s.Events.Publish(&thesrc.Event{Type: thesrc.EventPostCreated, Post: copyPost(res.Post)})
+		}
+	}
+	return results, nil
+}
+
+func (s *postsStore) Update(id int, post *thesrc.Post) error {
+	defer s.observe(time.Now(), "Posts.Update")
+	err := transact(s.dbh, func(tx modl.SqlExecutor) error {
+		res, err := tx.Exec(`UPDATE post SET title=$1, body=$2, editedat=CASE WHEN title<>$1 OR body<>$2 THEN $4 ELSE editedat END, version=version+1 WHERE id=$3 AND deletedat IS NULL AND ($5=0 OR version=$5);`, post.Title, post.Body, id, time.Now(), post.Version)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return postNotUpdated(tx, id)
+		}
+
+		if _, err := tx.Exec(`DELETE FROM post_tag WHERE postid=$1;`, id); err != nil {
+			return err
+		}
+		if err := setPostTags(tx, id, post.Tags); err != nil {
+			return err
+		}
+		if err := deleteUnusedTags(tx); err != nil {
+			return err
+		}
+
+		var posts []*thesrc.Post
+		if err := tx.Select(&posts, `SELECT * FROM post WHERE id=$1;`, id); err != nil {
+			return err
+		}
+		*post = *posts[0]
+		return loadPostTags(tx, post)
+	})
+	if err == nil {
+		s.Events.Publish(&thesrc.Event{Type: thesrc.EventPostUpdated, Post: copyPost(post)})
+	}
+	return err
+}
+
+func (s *postsStore) Delete(id int) error {
+	defer s.observe(time.Now(), "Posts.Delete")
+	res, err := s.dbh.Exec(`UPDATE post SET deletedat=$1 WHERE id=$2 AND deletedat IS NULL;`, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return thesrc.ErrPostNotFound
+	}
+	return nil
+}
+
+func (s *postsStore) Undelete(id int) error {
+	defer s.observe(time.Now(), "Posts.Undelete")
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		var posts []*thesrc.Post
+		if err := tx.Select(&posts, `SELECT * FROM post WHERE id=$1 AND deletedat IS NOT NULL;`, id); err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			return thesrc.ErrPostNotFound
+		}
+
+		// Check for a live post with the same link URL first, because a
+		// unique violation aborts the transaction (if any) in PostgreSQL.
+		if linkURL := posts[0].LinkURL; linkURL != "" {
+			var existing []*thesrc.Post
+			if err := tx.Select(&existing, `SELECT * FROM post WHERE linkurl=$1 AND deletedat IS NULL LIMIT 1;`, linkURL); err != nil {
+				return err
+			}
+			if len(existing) > 0 {
+				return thesrc.ErrPostLinkURLTaken
+			}
+		}
+
+		// If the author has since reused the post's idempotency key, the
+		// key belongs to the newer post.
+		if key := posts[0].IdempotencyKey; key != "" {
+			if _, err := tx.Exec(`UPDATE post SET idempotencykey='' WHERE id=$1 AND EXISTS (SELECT 1 FROM post WHERE authoruserid=$2 AND idempotencykey=$3 AND deletedat IS NULL);`, id, posts[0].AuthorUserID, key); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(`UPDATE post SET deletedat=NULL WHERE id=$1;`, id)
+		return err
+	})
+}
+
+func (s *postsStore) Revisions(id int) ([]*thesrc.PostRevision, error) {
+	defer s.observe(time.Now(), "Posts.Revisions")
+	var revisions []*thesrc.PostRevision
+	if err := s.dbh.Select(&revisions, `SELECT * FROM post_revision WHERE postid=$1 ORDER BY createdat, id;`, id); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// errPreviewNotSupported is returned by the datastore's PostsService.Preview.
+var errPreviewNotSupported = errors.New("datastore: posts are previewed by the API server, not the datastore")
+
+// Preview is not supported by the datastore, because previewing a post
+// (validating it and fetching its link's page) is done by the API server.
+func (s *postsStore) Preview(post *thesrc.Post) (*thesrc.PostPreview, error) {
+	return nil, errPreviewNotSupported
+}
+
+// Flag is not supported by the datastore, because flags are recorded on
+// behalf of a specific user. Use Datastore.Flags instead.
+func (s *postsStore) Flag(id int) error {
+	return errFlagWithoutUser
+}
+
+// Save is not supported by the datastore, because posts are saved on behalf
+// of a specific user. Use Datastore.Saves instead.
+func (s *postsStore) Save(id int) error {
+	return errSaveWithoutUser
+}
+
+// Unsave is not supported by the datastore. Use Datastore.Saves instead.
+func (s *postsStore) Unsave(id int) error {
+	return errSaveWithoutUser
+}
+
+// Hide is not supported by the datastore, because posts are hidden on behalf
+// of a specific user. Use Datastore.Hides instead.
+func (s *postsStore) Hide(id int) error {
+	return errHideWithoutUser
+}
+
+// Unhide is not supported by the datastore. Use Datastore.Hides instead.
+func (s *postsStore) Unhide(id int) error {
+	return errHideWithoutUser
+}
+
+func (s *postsStore) Moderate(id int, mod *thesrc.PostModeration) error {
+	defer s.observe(time.Now(), "Posts.Moderate")
+	res, err := s.dbh.Exec(`UPDATE post SET hidden=$1, dead=$2, queued=false, reports=0, version=version+1 WHERE id=$3 AND deletedat IS NULL AND ($4=0 OR version=$4);`, mod.Hidden, mod.Dead, id, mod.Version)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return postNotUpdated(s.dbh, id)
+	}
+	return nil
+}
+
+// postNotUpdated returns the error for a conditional update of the post with
+// the given ID that updated no rows: thesrc.ErrPostVersionConflict if the
+// post exists (so its version didn't match), or else
+// thesrc.ErrPostNotFound.
+func postNotUpdated(tx modl.SqlExecutor, id int) error {
+	var rows []*struct{ Count int }
+	if err := tx.Select(&rows, `SELECT COUNT(*) AS count FROM post WHERE id=$1 AND deletedat IS NULL;`, id); err != nil {
+		return err
+	}
+	if rows[0].Count == 0 {
+		return thesrc.ErrPostNotFound
+	}
+	return thesrc.ErrPostVersionConflict
 }
@@ -2,7 +2,10 @@ package datastore
 
 import (
 	"reflect"
+	"sort"
+	"strconv"
 	"testing"
+	"time"
 
 	"sourcegraph.com/sourcegraph/thesrc"
 )
@@ -100,3 +103,359 @@ func TestPostsStore_Submit_existing_db(t *testing.T) {
 		t.Error("got post %+v, want %+v", post, want)
 	}
 }
+
+func TestPostsStore_CreateBatch_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	existing := &thesrc.Post{ID: 1, LinkURL: "http://example.com/1"}
+	if err := tx.Insert(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	posts := []*thesrc.Post{
+		{LinkURL: "http://example.com/1"},
+		{LinkURL: "http://example.com/2", Tags: []string{"golang"}},
+		{LinkURL: "http://example.com/2"},
+	}
+	results, err := d.Posts.CreateBatch(posts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != len(posts) {
+		t.Fatalf("got %d results, want %d", len(results), len(posts))
+	}
+	if results[0].Created || posts[0].ID != existing.ID {
+		t.Errorf("got result %+v for existing post, want existing post %d", results[0], existing.ID)
+	}
+	if !results[1].Created || posts[1].ID == 0 {
+		t.Errorf("got result %+v for new post, want created post with nonzero ID", results[1])
+	}
+	if results[2].Created || posts[2].ID != posts[1].ID {
+		t.Errorf("got result %+v for duplicate post in batch, want post %d", results[2], posts[1].ID)
+	}
+
+	post, err := d.Posts.Get(posts[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"golang"}; !reflect.DeepEqual(post.Tags, want) {
+		t.Errorf("got tags %q, want %q", post.Tags, want)
+	}
+}
+
+func TestPostsStore_List_top_db(t *testing.T) {
+	now := time.Now()
+	old := &thesrc.Post{ID: 1, LinkURL: "http://example.com/1", Score: 50, SubmittedAt: now.Add(-72 * time.Hour)}
+	hot := &thesrc.Post{ID: 2, LinkURL: "http://example.com/2", Score: 10, SubmittedAt: now.Add(-1 * time.Hour)}
+	fresh := &thesrc.Post{ID: 3, LinkURL: "http://example.com/3", Score: 1, SubmittedAt: now}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	for _, p := range []*thesrc.Post{old, hot, fresh} {
+		if err := tx.Insert(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDatastore(tx)
+	posts, err := d.Posts.List(&thesrc.PostListOptions{Sort: thesrc.SortTop})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	if want := []int{hot.ID, old.ID, fresh.ID}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got post IDs %v, want %v", ids, want)
+	}
+}
+
+func TestPostsStore_List_period_db(t *testing.T) {
+	now := time.Now()
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	for id, age := range map[int]time.Duration{1: 40 * 24 * time.Hour, 2: 3 * 24 * time.Hour, 3: time.Hour} {
+		if err := tx.Insert(&thesrc.Post{ID: id, LinkURL: "http://example.com/" + strconv.Itoa(id), SubmittedAt: now.Add(-age)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDatastore(tx)
+	tests := map[string][]int{
+		thesrc.PeriodDay:   {3},
+		thesrc.PeriodWeek:  {2, 3},
+		thesrc.PeriodMonth: {2, 3},
+		thesrc.PeriodYear:  {1, 2, 3},
+		thesrc.PeriodAll:   {1, 2, 3},
+	}
+	for period, want := range tests {
+		posts, err := d.Posts.List(&thesrc.PostListOptions{Period: period})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, p := range posts {
+			ids = append(ids, p.ID)
+		}
+		sort.Ints(ids)
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("%s: got post IDs %v, want %v", period, ids, want)
+		}
+	}
+}
+
+func TestPostsStore_List_sinceID_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	for id := 1; id <= 3; id++ {
+		if err := tx.Insert(&thesrc.Post{ID: id, LinkURL: "http://example.com/" + strconv.Itoa(id), SubmittedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDatastore(tx)
+	posts, err := d.Posts.List(&thesrc.PostListOptions{SinceID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	sort.Ints(ids)
+	if want := []int{2, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got post IDs %v, want %v", ids, want)
+	}
+}
+
+func TestPostsStore_Update_db(t *testing.T) {
+	post := &thesrc.Post{ID: 1, Title: "t", LinkURL: "http://example.com"}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	if err := tx.Insert(post); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	update := &thesrc.Post{Title: "t2", Body: "b2", Tags: []string{"golang"}}
+	if err := d.Posts.Update(post.ID, update); err != nil {
+		t.Fatal(err)
+	}
+	if update.ID != post.ID || update.LinkURL != post.LinkURL || update.Title != "t2" {
+		t.Errorf("got updated post %+v", update)
+	}
+
+	got, err := d.Posts.Get(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"golang"}; got.Title != "t2" || got.Body != "b2" || !reflect.DeepEqual(got.Tags, want) {
+		t.Errorf("got post %+v after update", got)
+	}
+
+	if err := d.Posts.Update(123, update); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
+
+func TestPostsStore_Update_versionConflict_db(t *testing.T) {
+	// Use the DB itself (not a test transaction), so that Update begins and
+	// must end its own transaction.
+	DBH.Exec(`DELETE FROM post;`) // test on a clean DB
+	defer DBH.Exec(`DELETE FROM post;`)
+
+	d := NewDatastore(DBH)
+	post := &thesrc.Post{Title: "a", LinkURL: "http://example.com"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Posts.Update(post.ID, &thesrc.Post{Title: "b", Version: post.Version}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Posts.Update(post.ID, &thesrc.Post{Title: "c", Version: post.Version}); err != thesrc.ErrPostVersionConflict {
+		t.Fatalf("got error %v updating a stale version, want %v", err, thesrc.ErrPostVersionConflict)
+	}
+
+	// The conflict must not leave its transaction (and connection) open.
+	if n := DB.Db.Stats().InUse; n != 0 {
+		t.Errorf("got %d connections in use after a conflict, want 0", n)
+	}
+	if err := d.Posts.Update(post.ID, &thesrc.Post{Title: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.Posts.Get(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "c" {
+		t.Errorf("got title %q, want %q", got.Title, "c")
+	}
+}
+
+func TestPostsStore_Submit_idempotencyKey_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+
+	testPostsIdempotencyKey(t, NewDatastore(tx))
+}
+
+// testPostsIdempotencyKey tests submitting posts with idempotency keys. d
+// must be empty.
+func testPostsIdempotencyKey(t *testing.T, d *Datastore) {
+	first := &thesrc.Post{Title: "a", Body: "a", AuthorUserID: 1, IdempotencyKey: "k"}
+	retry := &thesrc.Post{Title: "a", Body: "a", AuthorUserID: 1, IdempotencyKey: "k"}
+	other := &thesrc.Post{Title: "a", Body: "a", AuthorUserID: 2, IdempotencyKey: "k"}
+	for _, p := range []*thesrc.Post{first, retry, other} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if retry.ID != first.ID {
+		t.Errorf("got post %d for a retry of post %d, want the same post", retry.ID, first.ID)
+	}
+	if other.ID == first.ID {
+		t.Error("got the same post for another user's post with the same key")
+	}
+
+	// A deleted post's key may be reused.
+	if err := d.Posts.Delete(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	again := &thesrc.Post{Title: "b", Body: "b", AuthorUserID: 1, IdempotencyKey: "k"}
+	if created, err := d.Posts.Submit(again); err != nil {
+		t.Fatal(err)
+	} else if !created || again.ID == first.ID || again.DeletedAt != nil {
+		t.Errorf("got post %+v (created == %v) reusing a deleted post's key, want a new post", again, created)
+	}
+
+	// Undeleting the post leaves the key with the newer post.
+	if err := d.Posts.Undelete(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	retry = &thesrc.Post{Title: "b", Body: "b", AuthorUserID: 1, IdempotencyKey: "k"}
+	if _, err := d.Posts.Submit(retry); err != nil {
+		t.Fatal(err)
+	}
+	if retry.ID != again.ID {
+		t.Errorf("got post %d for a retry of post %d after undeleting post %d, want post %d", retry.ID, again.ID, first.ID, again.ID)
+	}
+}
+
+func TestPostsStore_Delete_db(t *testing.T) {
+	post := &thesrc.Post{ID: 1, LinkURL: "http://example.com"}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	if err := tx.Insert(post); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	if err := d.Posts.Delete(post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Posts.Get(post.ID); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v after deleting, want %v", err, thesrc.ErrPostNotFound)
+	}
+	if err := d.Posts.Delete(post.ID); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v deleting again, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
+
+func TestPostsStore_Moderate_db(t *testing.T) {
+	visible := &thesrc.Post{ID: 1, LinkURL: "http://example.com/1"}
+	flagged := &thesrc.Post{ID: 2, LinkURL: "http://example.com/2", Flags: 1}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	if err := tx.Insert(visible, flagged); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	if err := d.Posts.Moderate(flagged.ID, &thesrc.PostModeration{Dead: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	posts, err := d.Posts.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != visible.ID {
+		t.Errorf("got posts %+v, want only the visible post", posts)
+	}
+
+	posts, err = d.Posts.List(&thesrc.PostListOptions{Flagged: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != flagged.ID || !posts[0].Dead {
+		t.Errorf("got flagged posts %+v, want only the dead post", posts)
+	}
+
+	if err := d.Posts.Moderate(123, &thesrc.PostModeration{}); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
+
+func TestPostsStore_Related_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM post_tag;`)
+
+	testPostsStoreRelated(t, NewDatastore(tx))
+}
+
+// testPostsStoreRelated tests d.Posts.Related, given an empty datastore.
+func testPostsStoreRelated(t *testing.T, d *Datastore) {
+	posts := []*thesrc.Post{
+		{Title: "Fast JSON parsing in Go", LinkURL: "http://github.com/a", Tags: []string{"golang", "json"}},
+		{Title: "Parsing JSON fast with Go", LinkURL: "http://example.com/2", Tags: []string{"golang"}}, // shared tag and similar title
+		{Title: "Unrelated thing", LinkURL: "http://github.com/b"},                                      // same domain
+		{Title: "Cooking recipes", LinkURL: "http://example.org/4"},                                     // unrelated
+		{Title: "Fast JSON parsing in Rust", LinkURL: "http://example.com/5", Tags: []string{"json"}},   // hidden
+		{Title: "Tabs or spaces", LinkURL: "http://example.net/6", Tags: []string{"json"}},              // shared tag
+	}
+	for _, p := range posts {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Posts.Moderate(posts[4].ID, &thesrc.PostModeration{Hidden: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	related, err := d.Posts.Related(posts[0].ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, p := range related {
+		ids = append(ids, p.ID)
+	}
+	if want := []int{posts[1].ID, posts[5].ID, posts[2].ID}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got related post IDs %v, want %v (most related first)", ids, want)
+	}
+	if len(related) > 0 && !reflect.DeepEqual(related[0].Tags, []string{"golang"}) {
+		t.Errorf("got related post tags %v, want [golang]", related[0].Tags)
+	}
+
+	if _, err := d.Posts.Related(123, nil); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
@@ -0,0 +1,73 @@
+package datastore
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/modl"
+	"github.com/jmoiron/sqlx"
+)
+
+// A preparedQuery is a frequently run query that is prepared once (on each
+// connection, by database/sql) instead of being parsed and planned each time
+// it runs.
+type preparedQuery struct {
+	query string
+
+	mu   sync.Mutex
+	stmt *sqlx.Stmt // prepared on first use
+}
+
+func prepared(query string) *preparedQuery { return &preparedQuery{query: query} }
+
+// Select runs the query with args and stores the results in dest, like
+// dbh.Select. The prepared statement belongs to the global DB, so it is only
+// used if dbh is DBH; otherwise (e.g., in a transaction) the query is run on
+// dbh as usual.
+func (q *preparedQuery) Select(dbh modl.SqlExecutor, dest interface{}, args ...interface{}) error {
+	if dbh != DBH || DB.Dbx == nil {
+		return dbh.Select(dest, q.query, args...)
+	}
+
+	stmt, err := q.statement()
+	if err != nil {
+		return err
+	}
+	err = stmt.Select(dest, args...)
+	if err != nil && strings.Contains(err.Error(), "cached plan must not change result type") {
+		// The table's columns changed (in a migration) since the
+		// statement was prepared, so PostgreSQL refuses to run it.
+		q.reset(stmt)
+		if stmt, err = q.statement(); err != nil {
+			return err
+		}
+		err = stmt.Select(dest, args...)
+	}
+	return err
+}
+
+// statement returns the prepared statement, preparing it if needed. If
+// preparing fails, the next call tries again.
+func (q *preparedQuery) statement() (*sqlx.Stmt, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stmt == nil {
+		stmt, err := DB.Dbx.Preparex(q.query)
+		if err != nil {
+			return nil, err
+		}
+		q.stmt = stmt
+	}
+	return q.stmt, nil
+}
+
+// reset closes stmt so that the next call to statement prepares the query
+// again (unless another caller already did).
+func (q *preparedQuery) reset(stmt *sqlx.Stmt) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stmt == stmt {
+		q.stmt.Close()
+		q.stmt = nil
+	}
+}
@@ -0,0 +1,251 @@
+package datastore
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/redis"
+)
+
+// NewRedisSessionsStore returns a sessions store that keeps sessions in
+// Redis, so that they are shared by all servers that use the same Redis
+// server (and don't add writes to the database on every login). Sessions
+// expire from Redis once they have been unused for idleTimeout, so
+// DeleteUnused does nothing.
+func NewRedisSessionsStore(c *redis.Client, idleTimeout time.Duration) SessionsStore {
+	return &redisSessionsStore{c: c, idleTimeout: idleTimeout}
+}
+
+type redisSessionsStore struct {
+	c           *redis.Client
+	idleTimeout time.Duration
+}
+
+// Sessions are stored under these keys.
+const (
+	redisSessionNextIDKey      = "thesrc:session-next-id"
+	redisSessionKeyPrefix      = "thesrc:session:"       // + ID, holds a JSON redisSession
+	redisSessionHashKeyPrefix  = "thesrc:session-hash:"  // + hex hash, holds the session's ID
+	redisUserSessionsKeyPrefix = "thesrc:user-sessions:" // + user ID, a set of session IDs
+)
+
+// redisSession is how a session is stored. The session's Hash isn't
+// included in its JSON otherwise.
+type redisSession struct {
+	*thesrc.Session
+	Hash []byte
+}
+
+func redisSessionKey(id int) string {
+	return redisSessionKeyPrefix + strconv.Itoa(id)
+}
+
+func redisSessionHashKey(hash []byte) string {
+	return redisSessionHashKeyPrefix + hex.EncodeToString(hash)
+}
+
+func redisUserSessionsKey(userID int) string {
+	return redisUserSessionsKeyPrefix + strconv.Itoa(userID)
+}
+
+// ttl returns how long a session last used at lastUsedAt has until it
+// expires, in milliseconds.
+func (s *redisSessionsStore) ttl(lastUsedAt time.Time) int64 {
+	return int64((s.idleTimeout - time.Since(lastUsedAt)) / time.Millisecond)
+}
+
+func (s *redisSessionsStore) do(args ...interface{}) (interface{}, error) {
+	return s.c.Do(context.Background(), args...)
+}
+
+func (s *redisSessionsStore) Create(session *thesrc.Session) error {
+	id, err := redis.Int64(s.do("INCR", redisSessionNextIDKey))
+	if err != nil {
+		return err
+	}
+	session.ID = int(id)
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	if session.LastUsedAt.IsZero() {
+		session.LastUsedAt = session.CreatedAt
+	}
+	return s.put(session)
+}
+
+// put stores session, and extends the expiration of its keys to match its
+// LastUsedAt. The session's hash key is written last, so that the session
+// can't be found by its token if an earlier write fails.
+func (s *redisSessionsStore) put(session *thesrc.Session) error {
+	ttl := s.ttl(session.LastUsedAt)
+	if ttl <= 0 {
+		return s.delete(session)
+	}
+
+	ss := *session
+	ss.Current = false
+	data, err := json.Marshal(redisSession{Session: &ss, Hash: ss.Hash})
+	if err != nil {
+		return err
+	}
+	if _, err := s.do("SET", redisSessionKey(ss.ID), data, "PX", ttl); err != nil {
+		return err
+	}
+	userKey := redisUserSessionsKey(ss.UserID)
+	if _, err := s.do("SADD", userKey, ss.ID); err != nil {
+		return err
+	}
+	// The set of a user's sessions lives as long as their longest-lived
+	// session.
+	if curTTL, err := redis.Int64(s.do("PTTL", userKey)); err != nil {
+		return err
+	} else if curTTL < ttl {
+		if _, err := s.do("PEXPIRE", userKey, ttl); err != nil {
+			return err
+		}
+	}
+	_, err = s.do("SET", redisSessionHashKey(ss.Hash), ss.ID, "PX", ttl)
+	return err
+}
+
+// get returns the session with the given ID, or thesrc.ErrSessionNotFound.
+func (s *redisSessionsStore) get(id int) (*thesrc.Session, error) {
+	data, err := redis.Bytes(s.do("GET", redisSessionKey(id)))
+	if err == redis.ErrNil {
+		return nil, thesrc.ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return unmarshalRedisSession(data)
+}
+
+func unmarshalRedisSession(data []byte) (*thesrc.Session, error) {
+	rs := redisSession{Session: &thesrc.Session{}}
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return nil, err
+	}
+	rs.Session.Hash = rs.Hash
+	return rs.Session, nil
+}
+
+func (s *redisSessionsStore) delete(session *thesrc.Session) error {
+	if _, err := s.do("DEL", redisSessionHashKey(session.Hash), redisSessionKey(session.ID)); err != nil {
+		return err
+	}
+	_, err := s.do("SREM", redisUserSessionsKey(session.UserID), session.ID)
+	return err
+}
+
+func (s *redisSessionsStore) GetByHash(hash []byte) (*thesrc.Session, error) {
+	id, err := redis.Int64(s.do("GET", redisSessionHashKey(hash)))
+	if err == redis.ErrNil {
+		return nil, thesrc.ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return s.get(int(id))
+}
+
+// userSessions returns all of a user's sessions, and forgets the IDs of any
+// that have expired.
+func (s *redisSessionsStore) userSessions(userID int) ([]*thesrc.Session, error) {
+	userKey := redisUserSessionsKey(userID)
+	ids, err := redis.Strings(s.do("SMEMBERS", userKey))
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = redisSessionKeyPrefix + id
+	}
+	reply, err := s.do(append([]interface{}{"MGET"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	var sessions []*thesrc.Session
+	for i, v := range reply.([]interface{}) {
+		if v == nil {
+			if _, err := s.do("SREM", userKey, ids[i]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		data, err := redis.Bytes(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		session, err := unmarshalRedisSession(data)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func (s *redisSessionsStore) List(userID int, usedSince time.Time) ([]*thesrc.Session, error) {
+	all, err := s.userSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	var sessions []*thesrc.Session
+	for _, session := range all {
+		if !session.LastUsedAt.Before(usedSince) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastUsedAt.Equal(sessions[j].LastUsedAt) {
+			return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+func (s *redisSessionsStore) Touch(id int, lastUsedAt time.Time) error {
+	session, err := s.get(id)
+	if err == thesrc.ErrSessionNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	session.LastUsedAt = lastUsedAt
+	return s.put(session)
+}
+
+func (s *redisSessionsStore) Delete(userID, id int) error {
+	session, err := s.get(id)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return thesrc.ErrSessionNotFound
+	}
+	return s.delete(session)
+}
+
+func (s *redisSessionsStore) DeleteAll(userID, exceptID int) error {
+	sessions, err := s.userSessions(userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID != exceptID {
+			if err := s.delete(session); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *redisSessionsStore) DeleteUnused(usedBefore time.Time) error {
+	return nil
+}
@@ -0,0 +1,80 @@
+package datastore
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/redis"
+)
+
+// To run the Redis sessions store test, set THESRC_TEST_REDIS to the URL of
+// a Redis server whose data may be clobbered (e.g., redis://localhost/15).
+func TestRedisSessionsStore(t *testing.T) {
+	rawurl := os.Getenv("THESRC_TEST_REDIS")
+	if rawurl == "" {
+		t.Skip("THESRC_TEST_REDIS not set")
+	}
+	c, err := redis.New(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(context.Background(), "FLUSHDB"); err != nil {
+		t.Fatal(err)
+	}
+	s := NewRedisSessionsStore(c, time.Hour)
+
+	old := &thesrc.Session{UserID: 1, Hash: []byte("old"), LastUsedAt: time.Now().Add(-30 * time.Minute)}
+	cur := &thesrc.Session{UserID: 1, Hash: []byte("cur"), UserAgent: "ua"}
+	other := &thesrc.Session{UserID: 2, Hash: []byte("other")}
+	for _, session := range []*thesrc.Session{old, cur, other} {
+		if err := s.Create(session); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, err := s.GetByHash([]byte("cur")); err != nil {
+		t.Fatal(err)
+	} else if got.ID != cur.ID || got.UserAgent != "ua" || string(got.Hash) != "cur" {
+		t.Errorf("got session %+v, want %+v", got, cur)
+	}
+	if sessions, _ := s.List(1, time.Time{}); len(sessions) != 2 || sessions[0].ID != cur.ID {
+		t.Errorf("got sessions %+v, want the current session and then the old one", sessions)
+	}
+	if sessions, _ := s.List(1, time.Now().Add(-time.Minute)); len(sessions) != 1 {
+		t.Errorf("got %d recently used sessions, want 1", len(sessions))
+	}
+
+	if err := s.Touch(old.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, _ := s.List(1, time.Now().Add(-time.Minute)); len(sessions) != 2 {
+		t.Errorf("got %d recently used sessions after touching, want 2", len(sessions))
+	}
+
+	// Sessions last used longer ago than the idle timeout are gone.
+	if err := s.Touch(other.ID, time.Now().Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetByHash([]byte("other")); err != thesrc.ErrSessionNotFound {
+		t.Errorf("got error %v after expiring, want %v", err, thesrc.ErrSessionNotFound)
+	}
+
+	if err := s.Delete(2, cur.ID); err != thesrc.ErrSessionNotFound {
+		t.Errorf("got error %v deleting another user's session, want %v", err, thesrc.ErrSessionNotFound)
+	}
+	if err := s.DeleteAll(1, cur.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetByHash([]byte("old")); err != thesrc.ErrSessionNotFound {
+		t.Errorf("got error %v after deleting all other sessions, want %v", err, thesrc.ErrSessionNotFound)
+	}
+	if err := s.Delete(1, cur.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetByHash([]byte("cur")); err != thesrc.ErrSessionNotFound {
+		t.Errorf("got error %v after deleting, want %v", err, thesrc.ErrSessionNotFound)
+	}
+}
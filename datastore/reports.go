@@ -0,0 +1,102 @@
+package datastore
+
+import (
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.Report{}, "report").SetKeys(true, "ID")
+}
+
+// ReportsStore accesses abuse reports (see thesrc.Report) in the datastore.
+type ReportsStore interface {
+	// Create a report and increment the reported post's Reports count. If
+	// successful, report.ID will be the new report's ID. If the post doesn't
+	// exist (or is deleted), thesrc.ErrPostNotFound is returned.
+	//
+	// If limit is positive and the reporting client (report.ClientHash) has
+	// made limit reports since since, the report isn't created and
+	// thesrc.ErrReportRateLimited is returned. The reports are counted in
+	// the same transaction that creates the report, so concurrent reports
+	// can't exceed the limit.
+	Create(report *thesrc.Report, limit int, since time.Time) error
+
+	// List reports, newest first.
+	List(opt *thesrc.ReportListOptions) ([]*thesrc.Report, error)
+}
+
+type reportsStore struct{ *Datastore }
+
+func (s *reportsStore) Create(report *thesrc.Report, limit int, since time.Time) error {
+	defer s.observe(time.Now(), "Reports.Create")
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = time.Now()
+	}
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		if limit > 0 {
+			if !isSQLite() {
+				// Make the client's concurrent reports wait for this
+				// transaction, so that they count this report. (SQLite
+				// databases have only one connection, which already
+				// runs transactions one at a time.)
+				if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1));`, "report:"+report.ClientHash); err != nil {
+					return err
+				}
+			}
+			var rows []*struct{ Count int }
+			if err := tx.Select(&rows, `SELECT COUNT(*) AS count FROM report WHERE clienthash=$1 AND createdat >= $2;`, report.ClientHash, since); err != nil {
+				return err
+			}
+			if rows[0].Count >= limit {
+				return thesrc.ErrReportRateLimited
+			}
+		}
+
+		res, err := tx.Exec(`UPDATE post SET reports=reports+1 WHERE id=$1 AND deletedat IS NULL;`, report.PostID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return thesrc.ErrPostNotFound
+		}
+		return tx.Insert(report)
+	})
+}
+
+func (s *reportsStore) List(opt *thesrc.ReportListOptions) ([]*thesrc.Report, error) {
+	defer s.observe(time.Now(), "Reports.List")
+	if opt == nil {
+		opt = &thesrc.ReportListOptions{}
+	}
+	var reports []*thesrc.Report
+	if err := s.dbh.Select(&reports, `SELECT * FROM report WHERE ($1 = 0 OR postid=$1) ORDER BY createdat DESC, id DESC LIMIT $2 OFFSET $3;`, opt.PostID, opt.PerPageOrDefault(), opt.Offset()); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+type MockReportsStore struct {
+	Create_ func(report *thesrc.Report, limit int, since time.Time) error
+	List_   func(opt *thesrc.ReportListOptions) ([]*thesrc.Report, error)
+}
+
+var _ ReportsStore = &MockReportsStore{}
+
+func (s *MockReportsStore) Create(report *thesrc.Report, limit int, since time.Time) error {
+	if s.Create_ == nil {
+		return nil
+	}
+	return s.Create_(report, limit, since)
+}
+
+func (s *MockReportsStore) List(opt *thesrc.ReportListOptions) ([]*thesrc.Report, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
@@ -0,0 +1,152 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestReportsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM report;`)
+
+	testReportsStore(t, NewDatastore(tx))
+}
+
+// testReportsStore tests d.Reports and how reports put posts in the
+// moderation queue. d must be empty.
+func testReportsStore(t *testing.T, d *Datastore) {
+	a := &thesrc.Post{Title: "a", LinkURL: "http://example.com/a"}
+	b := &thesrc.Post{Title: "b", LinkURL: "http://example.com/b"}
+	for _, p := range []*thesrc.Post{a, b} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := d.Reports.Create(&thesrc.Report{PostID: 12345, Category: thesrc.ReportSpam}, 0, time.Time{}); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v reporting nonexistent post, want %v", err, thesrc.ErrPostNotFound)
+	}
+
+	r1 := &thesrc.Report{PostID: a.ID, Category: thesrc.ReportSpam, ClientHash: "x", CreatedAt: time.Now().Add(-2 * time.Hour)}
+	r2 := &thesrc.Report{PostID: a.ID, Category: thesrc.ReportOther, Details: "d", ClientHash: "x"}
+	r3 := &thesrc.Report{PostID: b.ID, Category: thesrc.ReportHarassment, ClientHash: "y"}
+	for _, r := range []*thesrc.Report{r1, r2, r3} {
+		if err := d.Reports.Create(r, 0, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		if r.ID == 0 {
+			t.Error("got ID == 0, want nonzero")
+		}
+	}
+
+	post, err := d.Posts.Get(a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Reports != 2 {
+		t.Errorf("got Reports %d, want 2", post.Reports)
+	}
+	flagged, err := d.Posts.List(&thesrc.PostListOptions{Flagged: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flagged) != 2 {
+		t.Errorf("got %d flagged posts, want 2 (both reported)", len(flagged))
+	}
+
+	reports, err := d.Reports.List(&thesrc.ReportListOptions{PostID: a.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[0].ID != r2.ID || reports[1].ID != r1.ID {
+		t.Errorf("got reports %+v, want [r2 r1]", reports)
+	}
+	if reports[0].Details != "d" || reports[0].ClientHash != "x" {
+		t.Errorf("got report %+v, want Details and ClientHash to round-trip", reports[0])
+	}
+	if reports, err := d.Reports.List(nil); err != nil {
+		t.Fatal(err)
+	} else if len(reports) != 3 {
+		t.Errorf("got %d reports, want 3", len(reports))
+	}
+
+	// Client x has made 1 report in the last hour (and 1 before), and z
+	// none.
+	since := time.Now().Add(-time.Hour)
+	if err := d.Reports.Create(&thesrc.Report{PostID: a.ID, Category: thesrc.ReportSpam, ClientHash: "x"}, 1, since); err != thesrc.ErrReportRateLimited {
+		t.Errorf("got error %v exceeding the rate limit, want %v", err, thesrc.ErrReportRateLimited)
+	}
+	if post, err := d.Posts.Get(a.ID); err != nil {
+		t.Fatal(err)
+	} else if post.Reports != 2 {
+		t.Errorf("got Reports %d after a rate-limited report, want 2", post.Reports)
+	}
+	if err := d.Reports.Create(&thesrc.Report{PostID: a.ID, Category: thesrc.ReportSpam, ClientHash: "x"}, 2, since); err != nil {
+		t.Errorf("got error %v within the rate limit, want nil", err)
+	}
+	if err := d.Reports.Create(&thesrc.Report{PostID: a.ID, Category: thesrc.ReportSpam, ClientHash: "z"}, 1, since); err != nil {
+		t.Errorf("got error %v for a new client, want nil", err)
+	}
+
+	// Moderating a post acknowledges its reports, taking it out of the
+	// moderation queue.
+	if err := d.Posts.Moderate(a.ID, &thesrc.PostModeration{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Moderation.Bulk(&thesrc.BulkModeration{Action: thesrc.BulkKill, PostIDs: []int{b.ID}}, &thesrc.AuditEntry{ActorUserID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{a.ID, b.ID} {
+		if post, err := d.Posts.Get(id); err != nil {
+			t.Fatal(err)
+		} else if post.Reports != 0 {
+			t.Errorf("post %d: got Reports %d after moderation, want 0", id, post.Reports)
+		}
+	}
+	if flagged, err := d.Posts.List(&thesrc.PostListOptions{Flagged: true}); err != nil {
+		t.Fatal(err)
+	} else if len(flagged) != 0 {
+		t.Errorf("got flagged posts %+v after moderation, want none", flagged)
+	}
+}
+
+func TestReportsStore_Create_failure_db(t *testing.T) {
+	// Use the DB itself (not a test transaction), so that Create begins and
+	// must end its own transaction.
+	DBH.Exec(`DELETE FROM post;`) // test on a clean DB
+	DBH.Exec(`DELETE FROM report;`)
+	defer DBH.Exec(`DELETE FROM post;`)
+	defer DBH.Exec(`DELETE FROM report;`)
+
+	d := NewDatastore(DBH)
+	post := &thesrc.Post{Title: "a", LinkURL: "http://example.com"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+	since := time.Now().Add(-time.Hour)
+	if err := d.Reports.Create(&thesrc.Report{PostID: post.ID, Category: thesrc.ReportSpam, ClientHash: "x"}, 1, since); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Reports.Create(&thesrc.Report{PostID: post.ID + 1000, Category: thesrc.ReportSpam, ClientHash: "y"}, 1, since); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v reporting nonexistent post, want %v", err, thesrc.ErrPostNotFound)
+	}
+	if err := d.Reports.Create(&thesrc.Report{PostID: post.ID, Category: thesrc.ReportSpam, ClientHash: "x"}, 1, since); err != thesrc.ErrReportRateLimited {
+		t.Errorf("got error %v exceeding the rate limit, want %v", err, thesrc.ErrReportRateLimited)
+	}
+
+	// The failures must not leave their transactions (and connections)
+	// open.
+	if n := DB.Db.Stats().InUse; n != 0 {
+		t.Errorf("got %d connections in use after failed reports, want 0", n)
+	}
+	if reports, err := d.Reports.List(nil); err != nil {
+		t.Fatal(err)
+	} else if len(reports) != 1 {
+		t.Errorf("got %d reports, want 1", len(reports))
+	}
+}
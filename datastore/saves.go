@@ -0,0 +1,101 @@
+package datastore
+
+import (
+	"errors"
+	"time"
+)
+
+// A savedPost is a user's bookmark of a post.
+type savedPost struct {
+	UserID  int
+	PostID  int
+	SavedAt time.Time
+}
+
+func init() {
+	DB.AddTableWithName(savedPost{}, "saved_posts").SetKeys(false, "UserID", "PostID")
+}
+
+// SavesStore accesses saved posts in the datastore. Posts are saved on
+// behalf of a specific user (unlike thesrc.PostsService.Save, which saves as
+// the authenticated user).
+type SavesStore interface {
+	// Save a post as a user. Saving a post that the user has already saved
+	// has no effect.
+	Save(userID, postID int) error
+
+	// Unsave removes a post from a user's saved posts (if it is there).
+	Unsave(userID, postID int) error
+
+	// Saved returns the subset of postIDs that the user has saved.
+	Saved(userID int, postIDs []int) (map[int]bool, error)
+}
+
+// errSaveWithoutUser is returned by the datastore's PostsService.Save and
+// Unsave, which can't know which user is saving the post.
+var errSaveWithoutUser = errors.New("datastore: posts must be saved by a user (use Datastore.Saves)")
+
+type savesStore struct{ *Datastore }
+
+func (s *savesStore) Save(userID, postID int) error {
+	defer s.observe(time.Now(), "Saves.Save")
+	if _, err := s.Posts.Get(postID); err != nil {
+		return err
+	}
+	_, err := s.dbh.Exec(`INSERT INTO saved_posts(userid, postid, savedat) SELECT $1, $2, $3 WHERE NOT EXISTS (SELECT 1 FROM saved_posts WHERE userid=$1 AND postid=$2);`, userID, postID, time.Now())
+	return err
+}
+
+func (s *savesStore) Unsave(userID, postID int) error {
+	defer s.observe(time.Now(), "Saves.Unsave")
+	_, err := s.dbh.Exec(`DELETE FROM saved_posts WHERE userid=$1 AND postid=$2;`, userID, postID)
+	return err
+}
+
+func (s *savesStore) Saved(userID int, postIDs []int) (map[int]bool, error) {
+	defer s.observe(time.Now(), "Saves.Saved")
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	in, args := inList(2, postIDs)
+	var saves []*savedPost
+	if err := s.dbh.Select(&saves, `SELECT * FROM saved_posts WHERE userid=$1 AND postid IN `+in+`;`, append([]interface{}{userID}, args...)...); err != nil {
+		return nil, err
+	}
+
+	saved := make(map[int]bool, len(saves))
+	for _, sp := range saves {
+		saved[sp.PostID] = true
+	}
+	return saved, nil
+}
+
+type MockSavesStore struct {
+	Save_   func(userID, postID int) error
+	Unsave_ func(userID, postID int) error
+	Saved_  func(userID int, postIDs []int) (map[int]bool, error)
+}
+
+var _ SavesStore = &MockSavesStore{}
+
+func (s *MockSavesStore) Save(userID, postID int) error {
+	if s.Save_ == nil {
+		return nil
+	}
+	return s.Save_(userID, postID)
+}
+
+func (s *MockSavesStore) Unsave(userID, postID int) error {
+	if s.Unsave_ == nil {
+		return nil
+	}
+	return s.Unsave_(userID, postID)
+}
+
+func (s *MockSavesStore) Saved(userID int, postIDs []int) (map[int]bool, error) {
+	if s.Saved_ == nil {
+		return nil, nil
+	}
+	return s.Saved_(userID, postIDs)
+}
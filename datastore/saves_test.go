@@ -0,0 +1,58 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestSavesStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM saved_posts;`)
+	for _, p := range []*thesrc.Post{
+		{ID: 1, LinkURL: "http://example.com/1"},
+		{ID: 2, LinkURL: "http://example.com/2"},
+	} {
+		if err := tx.Insert(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDatastore(tx)
+	// Saving again should have no effect.
+	for i := 0; i < 2; i++ {
+		if err := d.Saves.Save(1, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	saved, err := d.Saves.Saved(1, []int{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]bool{1: true}; !reflect.DeepEqual(saved, want) {
+		t.Errorf("got saved %v, want %v", saved, want)
+	}
+
+	posts, err := d.Posts.List(&thesrc.PostListOptions{SavedByUserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != 1 {
+		t.Errorf("got saved posts %+v, want post 1", posts)
+	}
+
+	if err := d.Saves.Unsave(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := d.Saves.Saved(1, []int{1}); saved[1] {
+		t.Error("post is still saved after Unsave")
+	}
+
+	if err := d.Saves.Save(1, 3); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v saving nonexistent post, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
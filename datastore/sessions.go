@@ -0,0 +1,173 @@
+package datastore
+
+import (
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.Session{}, "session").SetKeys(true, "ID")
+}
+
+// SessionsStore accesses login sessions in the datastore. Sessions are
+// managed on behalf of a specific user (unlike thesrc.SessionsService, which
+// manages the authenticated user's sessions).
+type SessionsStore interface {
+	// Create a session. If successful, session.ID will be the new session's
+	// ID.
+	Create(session *thesrc.Session) error
+
+	// GetByHash gets the session whose token has the given hash.
+	GetByHash(hash []byte) (*thesrc.Session, error)
+
+	// List a user's sessions that have been used since usedSince, most
+	// recently used first.
+	List(userID int, usedSince time.Time) ([]*thesrc.Session, error)
+
+	// Touch sets a session's LastUsedAt.
+	Touch(id int, lastUsedAt time.Time) error
+
+	// Delete one of a user's sessions. If the user has no session with the
+	// given ID, thesrc.ErrSessionNotFound is returned.
+	Delete(userID, id int) error
+
+	// DeleteAll deletes all of a user's sessions, except the one with ID
+	// exceptID (if nonzero).
+	DeleteAll(userID, exceptID int) error
+
+	// DeleteUnused deletes all users' sessions that have not been used since
+	// usedBefore.
+	DeleteUnused(usedBefore time.Time) error
+}
+
+type sessionsStore struct{ *Datastore }
+
+// getSessionByHashQuery is prepared because it runs on every API request
+// that is authenticated with a session token.
+var getSessionByHashQuery = prepared(`SELECT * FROM session WHERE hash=$1;`)
+
+func (s *sessionsStore) Create(session *thesrc.Session) error {
+	defer s.observe(time.Now(), "Sessions.Create")
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	if session.LastUsedAt.IsZero() {
+		session.LastUsedAt = session.CreatedAt
+	}
+	return s.dbh.Insert(session)
+}
+
+func (s *sessionsStore) GetByHash(hash []byte) (*thesrc.Session, error) {
+	defer s.observe(time.Now(), "Sessions.GetByHash")
+	var sessions []*thesrc.Session
+	if err := getSessionByHashQuery.Select(s.dbh, &sessions, hash); err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, thesrc.ErrSessionNotFound
+	}
+	return sessions[0], nil
+}
+
+func (s *sessionsStore) List(userID int, usedSince time.Time) ([]*thesrc.Session, error) {
+	defer s.observe(time.Now(), "Sessions.List")
+	var sessions []*thesrc.Session
+	if err := s.dbh.Select(&sessions, `SELECT * FROM session WHERE userid=$1 AND lastusedat>=$2 ORDER BY lastusedat DESC, id DESC;`, userID, usedSince); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (s *sessionsStore) Touch(id int, lastUsedAt time.Time) error {
+	defer s.observe(time.Now(), "Sessions.Touch")
+	_, err := s.dbh.Exec(`UPDATE session SET lastusedat=$2 WHERE id=$1;`, id, lastUsedAt)
+	return err
+}
+
+func (s *sessionsStore) Delete(userID, id int) error {
+	defer s.observe(time.Now(), "Sessions.Delete")
+	res, err := s.dbh.Exec(`DELETE FROM session WHERE userid=$1 AND id=$2;`, userID, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return thesrc.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *sessionsStore) DeleteAll(userID, exceptID int) error {
+	defer s.observe(time.Now(), "Sessions.DeleteAll")
+	_, err := s.dbh.Exec(`DELETE FROM session WHERE userid=$1 AND id<>$2;`, userID, exceptID)
+	return err
+}
+
+func (s *sessionsStore) DeleteUnused(usedBefore time.Time) error {
+	defer s.observe(time.Now(), "Sessions.DeleteUnused")
+	_, err := s.dbh.Exec(`DELETE FROM session WHERE lastusedat<$1;`, usedBefore)
+	return err
+}
+
+type MockSessionsStore struct {
+	Create_       func(session *thesrc.Session) error
+	GetByHash_    func(hash []byte) (*thesrc.Session, error)
+	List_         func(userID int, usedSince time.Time) ([]*thesrc.Session, error)
+	Touch_        func(id int, lastUsedAt time.Time) error
+	Delete_       func(userID, id int) error
+	DeleteAll_    func(userID, exceptID int) error
+	DeleteUnused_ func(usedBefore time.Time) error
+}
+
+var _ SessionsStore = &MockSessionsStore{}
+
+func (s *MockSessionsStore) Create(session *thesrc.Session) error {
+	if s.Create_ == nil {
+		return nil
+	}
+	return s.Create_(session)
+}
+
+func (s *MockSessionsStore) GetByHash(hash []byte) (*thesrc.Session, error) {
+	if s.GetByHash_ == nil {
+		return nil, nil
+	}
+	return s.GetByHash_(hash)
+}
+
+func (s *MockSessionsStore) List(userID int, usedSince time.Time) ([]*thesrc.Session, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(userID, usedSince)
+}
+
+func (s *MockSessionsStore) Touch(id int, lastUsedAt time.Time) error {
+	if s.Touch_ == nil {
+		return nil
+	}
+	return s.Touch_(id, lastUsedAt)
+}
+
+func (s *MockSessionsStore) Delete(userID, id int) error {
+	if s.Delete_ == nil {
+		return nil
+	}
+	return s.Delete_(userID, id)
+}
+
+func (s *MockSessionsStore) DeleteAll(userID, exceptID int) error {
+	if s.DeleteAll_ == nil {
+		return nil
+	}
+	return s.DeleteAll_(userID, exceptID)
+}
+
+func (s *MockSessionsStore) DeleteUnused(usedBefore time.Time) error {
+	if s.DeleteUnused_ == nil {
+		return nil
+	}
+	return s.DeleteUnused_(usedBefore)
+}
@@ -0,0 +1,69 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestSessionsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM session;`) // test on a clean DB
+
+	d := NewDatastore(tx)
+	session := &thesrc.Session{UserID: 1, Hash: []byte("h"), UserAgent: "ua"}
+	if err := d.Sessions.Create(session); err != nil {
+		t.Fatal(err)
+	}
+	if session.ID == 0 {
+		t.Error("want nonzero session.ID after creating")
+	}
+	other := &thesrc.Session{UserID: 1, Hash: []byte("o"), LastUsedAt: time.Now().Add(-time.Hour)}
+	if err := d.Sessions.Create(other); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := d.Sessions.GetByHash([]byte("h"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != session.ID || got.UserID != 1 || got.UserAgent != "ua" {
+		t.Errorf("got session %+v, want %+v", got, session)
+	}
+	if _, err := d.Sessions.GetByHash([]byte("x")); err != thesrc.ErrSessionNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrSessionNotFound)
+	}
+
+	if sessions, err := d.Sessions.List(1, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	} else if len(sessions) != 1 {
+		t.Errorf("got %d recently used sessions, want 1", len(sessions))
+	}
+	if err := d.Sessions.Touch(other.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, err := d.Sessions.List(1, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	} else if len(sessions) != 2 {
+		t.Errorf("got %d recently used sessions after touching, want 2", len(sessions))
+	}
+
+	// Users may only delete their own sessions.
+	if err := d.Sessions.Delete(2, session.ID); err != thesrc.ErrSessionNotFound {
+		t.Errorf("got error %v deleting another user's session, want %v", err, thesrc.ErrSessionNotFound)
+	}
+	if err := d.Sessions.DeleteAll(1, session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Sessions.GetByHash([]byte("o")); err != thesrc.ErrSessionNotFound {
+		t.Errorf("got error %v after deleting other sessions, want %v", err, thesrc.ErrSessionNotFound)
+	}
+	if err := d.Sessions.DeleteUnused(time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Sessions.GetByHash([]byte("h")); err != thesrc.ErrSessionNotFound {
+		t.Errorf("got error %v after deleting unused sessions, want %v", err, thesrc.ErrSessionNotFound)
+	}
+}
@@ -0,0 +1,57 @@
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jmoiron/modl"
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteScheme is the DataSource prefix that selects SQLite instead of
+// PostgreSQL (e.g., "sqlite:///var/lib/thesrc.db").
+const sqliteScheme = "sqlite://"
+
+// sqliteDriver is the name of the database/sql driver for SQLite, with the
+// functions that thesrc's queries use (but SQLite lacks) added, and with
+// instrumentation (see QueryHooks).
+const sqliteDriver = "sqlite3_thesrc"
+
+func init() {
+	sql.Register(sqliteDriver, instrumentedDriver{&sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("power", math.Pow, true); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("similarity", similarity, true)
+		},
+	}})
+}
+
+// isSQLite returns whether the global DB is a SQLite database.
+func isSQLite() bool {
+	_, ok := DB.Dialect.(modl.SqliteDialect)
+	return ok
+}
+
+// isUniqueViolation returns whether err is a violation of the unique index
+// named index, on column (given as "table.column").
+func isUniqueViolation(err error, index, column string) bool {
+	msg := err.Error()
+	return strings.Contains(msg, `violates unique constraint "`+index+`"`) || // PostgreSQL
+		strings.Contains(msg, "UNIQUE constraint failed: "+column) || strings.Contains(msg, "UNIQUE constraint failed: index '"+index+"'") // SQLite
+}
+
+// inList returns a parenthesized list of bind variables (starting at
+// $first) for use in an IN condition, and the corresponding args.
+func inList(first int, ids []int) (string, []interface{}) {
+	vars := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		vars[i] = fmt.Sprintf("$%d", first+i)
+		args[i] = id
+	}
+	return "(" + strings.Join(vars, ",") + ")", args
+}
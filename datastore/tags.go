@@ -0,0 +1,106 @@
+package datastore
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// A tag is a row in the tag table, which holds one row per distinct tag name.
+type tag struct {
+	ID   int
+	Name string
+}
+
+// A postTag associates a post with a tag.
+type postTag struct {
+	PostID int
+	TagID  int
+}
+
+func init() {
+	DB.AddTableWithName(tag{}, "tag").SetKeys(true, "ID")
+	DB.AddTableWithName(postTag{}, "post_tag").SetKeys(false, "PostID", "TagID")
+}
+
+type tagsStore struct{ *Datastore }
+
+var _ thesrc.TagsService = &tagsStore{}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (s *tagsStore) List(opt *thesrc.TagListOptions) ([]*thesrc.Tag, error) {
+	defer s.observe(time.Now(), "Tags.List")
+	if opt == nil {
+		opt = &thesrc.TagListOptions{}
+	}
+
+	var tags []*thesrc.Tag
+	err := s.dbh.Select(&tags, `SELECT t.name, count(pt.postid) AS numposts FROM tag t LEFT JOIN post_tag pt ON pt.tagid=t.id WHERE t.name LIKE $1 ESCAPE '\' GROUP BY t.name ORDER BY numposts DESC, t.name LIMIT $2 OFFSET $3;`, likeEscaper.Replace(strings.ToLower(opt.Prefix))+"%", opt.PerPageOrDefault(), opt.Offset())
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// setPostTags tags the post with the given (normalized) tag names, creating
+// tags that don't yet exist.
+func setPostTags(tx modl.SqlExecutor, postID int, names []string) error {
+	for _, name := range names {
+		var tags []*tag
+		if err := tx.Select(&tags, `SELECT * FROM tag WHERE name=$1;`, name); err != nil {
+			return err
+		}
+		var t *tag
+		if len(tags) > 0 {
+			t = tags[0]
+		} else {
+			t = &tag{Name: name}
+			if err := tx.Insert(t); err != nil {
+				return err
+			}
+		}
+		if err := tx.Insert(&postTag{PostID: postID, TagID: t.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteUnusedTags deletes tags that no posts are tagged with.
+func deleteUnusedTags(tx modl.SqlExecutor) error {
+	_, err := tx.Exec(`DELETE FROM tag WHERE id NOT IN (SELECT tagid FROM post_tag);`)
+	return err
+}
+
+// loadPostTags sets the Tags field of each post.
+func loadPostTags(dbh modl.SqlExecutor, posts ...*thesrc.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	in, args := inList(1, ids)
+
+	var rows []*struct {
+		PostID int
+		Name   string
+	}
+	if err := dbh.Select(&rows, `SELECT pt.postid, t.name FROM post_tag pt INNER JOIN tag t ON t.id=pt.tagid WHERE pt.postid IN `+in+` ORDER BY t.name;`, args...); err != nil {
+		return err
+	}
+
+	tags := make(map[int][]string, len(posts))
+	for _, row := range rows {
+		tags[row.PostID] = append(tags[row.PostID], row.Name)
+	}
+	for _, p := range posts {
+		p.Tags = tags[p.ID]
+	}
+	return nil
+}
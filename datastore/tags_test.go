@@ -0,0 +1,68 @@
+package datastore
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestPostsStore_Submit_tags_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM tag;`)
+	tx.Exec(`DELETE FROM post_tag;`)
+
+	d := NewDatastore(tx)
+	post := &thesrc.Post{LinkURL: "http://example.com", Tags: []string{"postgresql", "golang"}}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Posts.Submit(&thesrc.Post{LinkURL: "http://example.com/2", Tags: []string{"golang"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := d.Posts.Get(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"golang", "postgresql"}; !reflect.DeepEqual(got.Tags, want) {
+		t.Errorf("got tags %q, want %q", got.Tags, want)
+	}
+
+	posts, err := d.Posts.List(&thesrc.PostListOptions{Tag: "postgresql"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != post.ID {
+		t.Errorf("got posts %+v tagged postgresql, want only post %d", posts, post.ID)
+	}
+}
+
+func TestTagsStore_List_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM tag;`)
+	tx.Exec(`DELETE FROM post_tag;`)
+
+	d := NewDatastore(tx)
+	for i, tags := range [][]string{{"golang", "git"}, {"golang"}, {"python"}} {
+		post := &thesrc.Post{LinkURL: "http://example.com/" + strconv.Itoa(i), Tags: tags}
+		if _, err := d.Posts.Submit(post); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tags, err := d.Tags.List(&thesrc.TagListOptions{Prefix: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*thesrc.Tag{{Name: "golang", NumPosts: 2}, {Name: "git", NumPosts: 1}}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("got tags %+v, want %+v", tags, want)
+	}
+}
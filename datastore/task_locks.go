@@ -0,0 +1,59 @@
+package datastore
+
+import "time"
+
+// TaskLocksStore holds locks on scheduled tasks, so that when several
+// processes run the same schedule, only one of them runs each occurrence of
+// a task.
+type TaskLocksStore interface {
+	// Acquire locks the task named name for owner until until, unless the
+	// task is already locked (as of now). It reports whether it acquired the
+	// lock. Locks aren't released; they expire.
+	Acquire(name, owner string, now, until time.Time) (bool, error)
+}
+
+type taskLocksStore struct{ *Datastore }
+
+func (s *taskLocksStore) Acquire(name, owner string, now, until time.Time) (bool, error) {
+	defer s.observe(time.Now(), "TaskLocks.Acquire")
+	res, err := s.dbh.Exec(`UPDATE task_lock SET owner=$1, expiresat=$2 WHERE name=$3 AND expiresat<=$4;`, owner, until, name, now)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else if n > 0 {
+		return true, nil
+	}
+
+	// Check whether the task is locked before inserting its first lock,
+	// because a unique violation aborts the transaction (if any) in
+	// PostgreSQL. The primary key catches concurrent inserts.
+	var rows []*struct{ Count int }
+	if err := s.dbh.Select(&rows, `SELECT COUNT(*) AS count FROM task_lock WHERE name=$1;`, name); err != nil {
+		return false, err
+	}
+	if rows[0].Count > 0 {
+		return false, nil
+	}
+	if _, err := s.dbh.Exec(`INSERT INTO task_lock(name, owner, expiresat) VALUES($1, $2, $3);`, name, owner, until); err != nil {
+		if isUniqueViolation(err, "task_lock_pkey", "task_lock.name") {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+type MockTaskLocksStore struct {
+	Acquire_ func(name, owner string, now, until time.Time) (bool, error)
+}
+
+var _ TaskLocksStore = &MockTaskLocksStore{}
+
+func (s *MockTaskLocksStore) Acquire(name, owner string, now, until time.Time) (bool, error) {
+	if s.Acquire_ == nil {
+		return true, nil
+	}
+	return s.Acquire_(name, owner, now, until)
+}
@@ -0,0 +1,40 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskLocksStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM task_lock;`) // test on a clean DB
+
+	testTaskLocksStore(t, NewDatastore(tx).TaskLocks)
+}
+
+// testTaskLocksStore tests a TaskLocksStore implementation, which must be
+// empty.
+func testTaskLocksStore(t *testing.T, s TaskLocksStore) {
+	now := time.Now()
+	acquire := func(name, owner string, now time.Time) bool {
+		ok, err := s.Acquire(name, owner, now, now.Add(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	if !acquire("a", "p1", now) {
+		t.Error("p1 couldn't acquire the first lock on task a")
+	}
+	if acquire("a", "p2", now.Add(time.Second)) {
+		t.Error("p2 acquired the lock on task a before it expired")
+	}
+	if !acquire("b", "p2", now) {
+		t.Error("p2 couldn't acquire the lock on task b")
+	}
+	if !acquire("a", "p2", now.Add(time.Minute)) {
+		t.Error("p2 couldn't acquire the expired lock on task a")
+	}
+}
@@ -0,0 +1,71 @@
+package datastore
+
+import (
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// ThumbnailsStore tracks the generation of post thumbnails. It is used by the
+// thumbnail worker and is not exposed in the API.
+type ThumbnailsStore interface {
+	// ListPending lists up to n posts with link URLs whose thumbnails haven't
+	// been attempted yet, newest first.
+	ListPending(n int) ([]*thesrc.Post, error)
+
+	// Set records that a post's thumbnail was attempted, setting the post's
+	// ThumbnailURL to url (which is empty if the attempt failed). The post
+	// won't be listed by ListPending again.
+	Set(postID int, url string) error
+}
+
+type thumbnailsStore struct{ *Datastore }
+
+func (s *thumbnailsStore) ListPending(n int) ([]*thesrc.Post, error) {
+	defer s.observe(time.Now(), "Thumbnails.ListPending")
+	var posts []*thesrc.Post
+	err := s.dbh.Select(&posts, `SELECT * FROM post WHERE linkurl <> '' AND deletedat IS NULL AND id NOT IN (SELECT postid FROM thumbnail_attempt) ORDER BY id DESC LIMIT $1;`, n)
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (s *thumbnailsStore) Set(postID int, url string) error {
+	defer s.observe(time.Now(), "Thumbnails.Set")
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		res, err := tx.Exec(`UPDATE post SET thumbnailurl=$1 WHERE id=$2;`, url, postID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return thesrc.ErrPostNotFound
+		}
+		_, err = tx.Exec(`INSERT INTO thumbnail_attempt(postid, attemptedat) SELECT $1, $2 WHERE NOT EXISTS (SELECT 1 FROM thumbnail_attempt WHERE postid=$1);`, postID, time.Now())
+		return err
+	})
+}
+
+type MockThumbnailsStore struct {
+	ListPending_ func(n int) ([]*thesrc.Post, error)
+	Set_         func(postID int, url string) error
+}
+
+var _ ThumbnailsStore = &MockThumbnailsStore{}
+
+func (s *MockThumbnailsStore) ListPending(n int) ([]*thesrc.Post, error) {
+	if s.ListPending_ == nil {
+		return nil, nil
+	}
+	return s.ListPending_(n)
+}
+
+func (s *MockThumbnailsStore) Set(postID int, url string) error {
+	if s.Set_ == nil {
+		return nil
+	}
+	return s.Set_(postID, url)
+}
@@ -0,0 +1,71 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestThumbnailsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM thumbnail_attempt;`)
+	for _, p := range []*thesrc.Post{{ID: 1, LinkURL: "http://example.com/1"}, {ID: 2, LinkURL: "http://example.com/2"}, {ID: 3}} {
+		if err := tx.Insert(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testThumbnailsStore(t, NewDatastore(tx))
+}
+
+func TestMemoryDatastore_Thumbnails(t *testing.T) {
+	d := NewMemoryDatastore()
+	for _, p := range []*thesrc.Post{{LinkURL: "http://example.com/1"}, {LinkURL: "http://example.com/2"}, {}} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testThumbnailsStore(t, d)
+}
+
+// testThumbnailsStore tests d.Thumbnails, given posts 1 and 2 with link URLs
+// and post 3 without one.
+func testThumbnailsStore(t *testing.T, d *Datastore) {
+	pendingIDs := func() []int {
+		posts, err := d.Thumbnails.ListPending(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, p := range posts {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	if got, want := pendingIDs(), []int{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got pending post IDs %v, want %v", got, want)
+	}
+
+	if err := d.Thumbnails.Set(2, "http://example.com/2.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Thumbnails.Set(1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := pendingIDs(); len(got) != 0 {
+		t.Errorf("got pending post IDs %v after setting thumbnails, want none", got)
+	}
+
+	if p, _ := d.Posts.Get(2); p.ThumbnailURL != "http://example.com/2.jpg" {
+		t.Errorf("got ThumbnailURL %q, want %q", p.ThumbnailURL, "http://example.com/2.jpg")
+	}
+
+	if err := d.Thumbnails.Set(123, ""); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
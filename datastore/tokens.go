@@ -0,0 +1,115 @@
+package datastore
+
+import (
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.Token{}, "token").SetKeys(true, "ID")
+}
+
+// TokensStore accesses personal API tokens in the datastore. Tokens are
+// managed on behalf of a specific user (unlike thesrc.TokensService, which
+// manages the authenticated user's tokens).
+type TokensStore interface {
+	// Create a token. If successful, token.ID will be the new token's ID.
+	Create(token *thesrc.Token) error
+
+	// List a user's tokens, most recently created first.
+	List(userID int) ([]*thesrc.Token, error)
+
+	// GetByHash gets the token whose value has the given hash.
+	GetByHash(hash []byte) (*thesrc.Token, error)
+
+	// Delete one of a user's tokens. If the user has no token with the given
+	// ID, thesrc.ErrTokenNotFound is returned.
+	Delete(userID, id int) error
+}
+
+type tokensStore struct{ *Datastore }
+
+// getTokenByHashQuery is prepared because it runs on every API request that
+// is authenticated with a token.
+var getTokenByHashQuery = prepared(`SELECT * FROM token WHERE hash=$1;`)
+
+func (s *tokensStore) Create(token *thesrc.Token) error {
+	defer s.observe(time.Now(), "Tokens.Create")
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	return s.dbh.Insert(token)
+}
+
+func (s *tokensStore) List(userID int) ([]*thesrc.Token, error) {
+	defer s.observe(time.Now(), "Tokens.List")
+	var tokens []*thesrc.Token
+	if err := s.dbh.Select(&tokens, `SELECT * FROM token WHERE userid=$1 ORDER BY createdat DESC, id DESC;`, userID); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (s *tokensStore) GetByHash(hash []byte) (*thesrc.Token, error) {
+	defer s.observe(time.Now(), "Tokens.GetByHash")
+	var tokens []*thesrc.Token
+	if err := getTokenByHashQuery.Select(s.dbh, &tokens, hash); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, thesrc.ErrTokenNotFound
+	}
+	return tokens[0], nil
+}
+
+func (s *tokensStore) Delete(userID, id int) error {
+	defer s.observe(time.Now(), "Tokens.Delete")
+	res, err := s.dbh.Exec(`DELETE FROM token WHERE userid=$1 AND id=$2;`, userID, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return thesrc.ErrTokenNotFound
+	}
+	return nil
+}
+
+type MockTokensStore struct {
+	Create_    func(token *thesrc.Token) error
+	List_      func(userID int) ([]*thesrc.Token, error)
+	GetByHash_ func(hash []byte) (*thesrc.Token, error)
+	Delete_    func(userID, id int) error
+}
+
+var _ TokensStore = &MockTokensStore{}
+
+func (s *MockTokensStore) Create(token *thesrc.Token) error {
+	if s.Create_ == nil {
+		return nil
+	}
+	return s.Create_(token)
+}
+
+func (s *MockTokensStore) List(userID int) ([]*thesrc.Token, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(userID)
+}
+
+func (s *MockTokensStore) GetByHash(hash []byte) (*thesrc.Token, error) {
+	if s.GetByHash_ == nil {
+		return nil, nil
+	}
+	return s.GetByHash_(hash)
+}
+
+func (s *MockTokensStore) Delete(userID, id int) error {
+	if s.Delete_ == nil {
+		return nil
+	}
+	return s.Delete_(userID, id)
+}
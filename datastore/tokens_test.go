@@ -0,0 +1,50 @@
+package datastore
+
+import (
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestTokensStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM token;`) // test on a clean DB
+
+	d := NewDatastore(tx)
+	token := &thesrc.Token{UserID: 1, Name: "n", Hash: []byte("h")}
+	if err := d.Tokens.Create(token); err != nil {
+		t.Fatal(err)
+	}
+	if token.ID == 0 {
+		t.Error("want nonzero token.ID after creating")
+	}
+
+	got, err := d.Tokens.GetByHash([]byte("h"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != token.ID || got.UserID != 1 {
+		t.Errorf("got token %+v, want %+v", got, token)
+	}
+	if _, err := d.Tokens.GetByHash([]byte("x")); err != thesrc.ErrTokenNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrTokenNotFound)
+	}
+
+	if tokens, err := d.Tokens.List(1); err != nil {
+		t.Fatal(err)
+	} else if len(tokens) != 1 {
+		t.Errorf("got %d tokens, want 1", len(tokens))
+	}
+
+	// Users may only delete their own tokens.
+	if err := d.Tokens.Delete(2, token.ID); err != thesrc.ErrTokenNotFound {
+		t.Errorf("got error %v deleting another user's token, want %v", err, thesrc.ErrTokenNotFound)
+	}
+	if err := d.Tokens.Delete(1, token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Tokens.GetByHash([]byte("h")); err != thesrc.ErrTokenNotFound {
+		t.Errorf("got error %v after deleting, want %v", err, thesrc.ErrTokenNotFound)
+	}
+}
@@ -0,0 +1,44 @@
+package datastore
+
+import (
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// TrendingStore computes how quickly posts are gaining traction (see
+// thesrc.Post.Velocity). It is used by the trending worker and is not exposed
+// in the API (although posts' velocities are, and they can be listed by
+// them).
+type TrendingStore interface {
+	// UpdateVelocities sets the Velocity of each post to the number of
+	// votes and weighted comments it received per hour in the past window,
+	// and returns the number of posts updated. Only posts with votes or
+	// comments in the window (or whose velocities were nonzero) are updated.
+	UpdateVelocities(window time.Duration) (int, error)
+}
+
+type trendingStore struct{ *Datastore }
+
+func (s *trendingStore) UpdateVelocities(window time.Duration) (int, error) {
+	defer s.observe(time.Now(), "Trending.UpdateVelocities")
+	res, err := s.dbh.Exec(`UPDATE post SET velocity=((SELECT count(*) FROM vote WHERE vote.postid=post.id AND vote.votedat >= $1 AND NOT vote.shadow AND NOT vote.nullified) + $2 * (SELECT count(*) FROM comment WHERE comment.postid=post.id AND comment.submittedat >= $1)) / CAST($3 AS double precision) WHERE velocity <> 0 OR id IN (SELECT postid FROM vote WHERE votedat >= $1) OR id IN (SELECT postid FROM comment WHERE submittedat >= $1);`, time.Now().Add(-window), thesrc.CommentVelocityWeight, window.Hours())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+type MockTrendingStore struct {
+	UpdateVelocities_ func(window time.Duration) (int, error)
+}
+
+var _ TrendingStore = &MockTrendingStore{}
+
+func (s *MockTrendingStore) UpdateVelocities(window time.Duration) (int, error) {
+	if s.UpdateVelocities_ == nil {
+		return 0, nil
+	}
+	return s.UpdateVelocities_(window)
+}
@@ -0,0 +1,73 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestTrendingStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM vote;`)
+	tx.Exec(`DELETE FROM comment;`)
+	for _, p := range []*thesrc.Post{{ID: 1, SubmittedAt: time.Now()}, {ID: 2, SubmittedAt: time.Now()}, {ID: 3, SubmittedAt: time.Now()}} {
+		if err := tx.Insert(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testTrendingStore(t, NewDatastore(tx))
+}
+
+func TestMemoryDatastore_Trending(t *testing.T) {
+	d := NewMemoryDatastore()
+	for _, p := range []*thesrc.Post{{Body: "1"}, {Body: "2"}, {Body: "3"}} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testTrendingStore(t, d)
+}
+
+// testTrendingStore tests d.Trending, given posts 1, 2, and 3 without votes
+// or comments.
+func testTrendingStore(t *testing.T, d *Datastore) {
+	// Post 1 gets 4 votes, and post 2 gets 1 vote and 1 comment (which
+	// counts as thesrc.CommentVelocityWeight votes).
+	for _, v := range [][2]int{{1, 1}, {2, 1}, {3, 1}, {4, 1}, {1, 2}} {
+		if err := d.Votes.Upvote(v[0], v[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Comments.Create(&thesrc.Comment{PostID: 2, Body: "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := d.Trending.UpdateVelocities(2 * time.Hour); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Errorf("updated %d posts, want 2", n)
+	}
+
+	posts, err := d.Posts.List(&thesrc.PostListOptions{Sort: thesrc.SortTrending})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	velocities := map[int]float64{}
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+		velocities[p.ID] = p.Velocity
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got trending post IDs %v, want %v", ids, want)
+	}
+	if want := map[int]float64{1: 2, 2: (1 + thesrc.CommentVelocityWeight) / 2.0, 3: 0}; !reflect.DeepEqual(velocities, want) {
+		t.Errorf("got velocities %v, want %v", velocities, want)
+	}
+}
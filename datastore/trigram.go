@@ -0,0 +1,59 @@
+package datastore
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/modl"
+)
+
+// createPostTitleTrigramIndex creates an index of posts' titles by their
+// trigrams, for finding related posts (see postsStore.Related). It requires
+// PostgreSQL's pg_trgm extension, which it installs if necessary. SQLite
+// databases have no such index, so related posts are found by scanning all
+// posts' titles.
+func createPostTitleTrigramIndex(tx modl.SqlExecutor) error {
+	if isSQLite() {
+		return nil
+	}
+	if _, err := tx.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm;`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`CREATE INDEX post_title_trgm ON post USING gin (title gin_trgm_ops);`)
+	return err
+}
+
+// similarity returns the similarity (from 0 to 1) of a and b, by the
+// trigrams of their words, as PostgreSQL's pg_trgm extension computes it. It
+// is registered as a function for SQLite databases, and used by the memory
+// datastore.
+func similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	var shared int
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// trigrams returns the set of trigrams of the words in s. Like pg_trgm, it
+// lowercases s and pads each word with two spaces before it and one after
+// it, so that words' beginnings count for more than their ends.
+func trigrams(s string) map[string]bool {
+	ts := map[string]bool{}
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		r := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			ts[string(r[i:i+3])] = true
+		}
+	}
+	return ts
+}
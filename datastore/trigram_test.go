@@ -0,0 +1,25 @@
+package datastore
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"word", "word", 1},
+		{"Word", "wORD!", 1},
+		{"word", "", 0},
+		{"", "", 0},
+		{"word", "two words", 4.0 / 11}, // pg_trgm: SELECT similarity('word', 'two words');
+		{"cat", "dog", 0},
+	}
+	for _, test := range tests {
+		if got := similarity(test.a, test.b); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("similarity(%q, %q): got %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
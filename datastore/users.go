@@ -0,0 +1,288 @@
+package datastore
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.User{}, "users").SetKeys(true, "ID")
+}
+
+// UsersStore accesses users in the datastore. Unlike the other stores, it is
+// not shared with the API client, because it deals in password hashes, which
+// never leave the server.
+type UsersStore interface {
+	// Get a user by ID.
+	Get(id int) (*thesrc.User, error)
+
+	// GetByLogin gets a user by login (case-insensitively).
+	GetByLogin(login string) (*thesrc.User, error)
+
+	// GetByEmail gets the first user to register with an email address
+	// (case-insensitively).
+	GetByEmail(email string) (*thesrc.User, error)
+
+	// Create a user. If successful, user.ID will be the new user's ID. If the
+	// login is already taken, ErrLoginTaken is returned.
+	Create(user *thesrc.User) error
+
+	// Karma returns the total score of a user's posts and comments.
+	Karma(userID int) (int, error)
+
+	// SetRole sets a user's role (see thesrc.User.HasRole).
+	SetRole(userID int, role string) error
+
+	// SetShadowBanned sets whether a user is shadow-banned (see
+	// thesrc.User.ShadowBanned).
+	SetShadowBanned(userID int, banned bool) error
+
+	// UpdateSettings sets a user's settings. settings must already be
+	// validated (see thesrc.UserSettings.Validate).
+	UpdateSettings(userID int, settings *thesrc.UserSettings) error
+
+	// SetPasswordHash sets the bcrypt hash of a user's password, and sets
+	// their PasswordChangedAt to now.
+	SetPasswordHash(userID int, hash []byte) error
+
+	// SetTwoFactor sets a user's TOTP secret and the hashes of their
+	// recovery codes (see thesrc.User.TOTPSecret and RecoveryCodeHashes). An
+	// empty secret disables two-factor authentication.
+	SetTwoFactor(userID int, secret, recoveryCodeHashes string) error
+}
+
+var (
+	ErrLoginTaken = errors.New("login is already taken")
+)
+
+type usersStore struct{ *Datastore }
+
+// These are prepared because users are fetched (to authenticate them or
+// show their profiles) on most requests.
+var (
+	getUserQuery        = prepared(`SELECT * FROM users WHERE id=$1;`)
+	getUserByLoginQuery = prepared(`SELECT * FROM users WHERE lower(login)=lower($1);`)
+)
+
+func (s *usersStore) Get(id int) (*thesrc.User, error) {
+	defer s.observe(time.Now(), "Users.Get")
+	var users []*thesrc.User
+	if err := getUserQuery.Select(s.dbh, &users, id); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, thesrc.ErrUserNotFound
+	}
+	return users[0], nil
+}
+
+func (s *usersStore) GetByLogin(login string) (*thesrc.User, error) {
+	defer s.observe(time.Now(), "Users.GetByLogin")
+	var users []*thesrc.User
+	if err := getUserByLoginQuery.Select(s.dbh, &users, login); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, thesrc.ErrUserNotFound
+	}
+	return users[0], nil
+}
+
+func (s *usersStore) GetByEmail(email string) (*thesrc.User, error) {
+	defer s.observe(time.Now(), "Users.GetByEmail")
+	var users []*thesrc.User
+	if err := s.dbh.Select(&users, `SELECT * FROM users WHERE lower(email)=lower($1) ORDER BY id LIMIT 1;`, email); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, thesrc.ErrUserNotFound
+	}
+	return users[0], nil
+}
+
+func (s *usersStore) Create(user *thesrc.User) error {
+	defer s.observe(time.Now(), "Users.Create")
+	if user.RegisteredAt.IsZero() {
+		user.RegisteredAt = time.Now()
+	}
+	if user.Role == "" {
+		user.Role = thesrc.RoleMember
+	}
+	if err := s.dbh.Insert(user); err != nil {
+		if isUniqueViolation(err, "users_login", "users.login") {
+			return ErrLoginTaken
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *usersStore) Karma(userID int) (int, error) {
+	defer s.observe(time.Now(), "Users.Karma")
+	var rows []*struct{ Karma int }
+	if err := s.dbh.Select(&rows, `SELECT (SELECT COALESCE(SUM(score), 0) FROM post WHERE authoruserid=$1 AND deletedat IS NULL) + (SELECT COALESCE(SUM(score), 0) FROM comment WHERE authoruserid=$1 AND postid NOT IN (SELECT id FROM post WHERE deletedat IS NOT NULL)) AS karma;`, userID); err != nil {
+		return 0, err
+	}
+	return rows[0].Karma, nil
+}
+
+func (s *usersStore) SetRole(userID int, role string) error {
+	defer s.observe(time.Now(), "Users.SetRole")
+	if !thesrc.ValidRole(role) {
+		return fmt.Errorf("invalid role %q", role)
+	}
+	res, err := s.dbh.Exec(`UPDATE users SET role=$1 WHERE id=$2;`, role, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return thesrc.ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *usersStore) SetShadowBanned(userID int, banned bool) error {
+	defer s.observe(time.Now(), "Users.SetShadowBanned")
+	res, err := s.dbh.Exec(`UPDATE users SET shadowbanned=$1 WHERE id=$2;`, banned, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return thesrc.ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *usersStore) UpdateSettings(userID int, settings *thesrc.UserSettings) error {
+	defer s.observe(time.Now(), "Users.UpdateSettings")
+	res, err := s.dbh.Exec(`UPDATE users SET displayname=$1, email=$2, digestsubscribed=$3, theme=$4, defaultsort=$5 WHERE id=$6;`, settings.DisplayName, settings.Email, settings.DigestSubscribed, settings.Theme, settings.DefaultSort, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return thesrc.ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *usersStore) SetPasswordHash(userID int, hash []byte) error {
+	defer s.observe(time.Now(), "Users.SetPasswordHash")
+	res, err := s.dbh.Exec(`UPDATE users SET passwordhash=$1, passwordchangedat=$2 WHERE id=$3;`, hash, time.Now(), userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return thesrc.ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *usersStore) SetTwoFactor(userID int, secret, recoveryCodeHashes string) error {
+	defer s.observe(time.Now(), "Users.SetTwoFactor")
+	res, err := s.dbh.Exec(`UPDATE users SET totpsecret=$1, recoverycodehashes=$2 WHERE id=$3;`, secret, recoveryCodeHashes, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return thesrc.ErrUserNotFound
+	}
+	return nil
+}
+
+type MockUsersStore struct {
+	Get_             func(id int) (*thesrc.User, error)
+	GetByLogin_      func(login string) (*thesrc.User, error)
+	GetByEmail_      func(email string) (*thesrc.User, error)
+	Create_          func(user *thesrc.User) error
+	Karma_           func(userID int) (int, error)
+	SetRole_         func(userID int, role string) error
+	SetShadowBanned_ func(userID int, banned bool) error
+	UpdateSettings_  func(userID int, settings *thesrc.UserSettings) error
+	SetPasswordHash_ func(userID int, hash []byte) error
+	SetTwoFactor_    func(userID int, secret, recoveryCodeHashes string) error
+}
+
+var _ UsersStore = &MockUsersStore{}
+
+func (s *MockUsersStore) Get(id int) (*thesrc.User, error) {
+	if s.Get_ == nil {
+		return nil, nil
+	}
+	return s.Get_(id)
+}
+
+func (s *MockUsersStore) GetByLogin(login string) (*thesrc.User, error) {
+	if s.GetByLogin_ == nil {
+		return nil, nil
+	}
+	return s.GetByLogin_(login)
+}
+
+func (s *MockUsersStore) GetByEmail(email string) (*thesrc.User, error) {
+	if s.GetByEmail_ == nil {
+		return nil, nil
+	}
+	return s.GetByEmail_(email)
+}
+
+func (s *MockUsersStore) Create(user *thesrc.User) error {
+	if s.Create_ == nil {
+		return nil
+	}
+	return s.Create_(user)
+}
+
+func (s *MockUsersStore) Karma(userID int) (int, error) {
+	if s.Karma_ == nil {
+		return 0, nil
+	}
+	return s.Karma_(userID)
+}
+
+func (s *MockUsersStore) SetRole(userID int, role string) error {
+	if s.SetRole_ == nil {
+		return nil
+	}
+	return s.SetRole_(userID, role)
+}
+
+func (s *MockUsersStore) SetShadowBanned(userID int, banned bool) error {
+	if s.SetShadowBanned_ == nil {
+		return nil
+	}
+	return s.SetShadowBanned_(userID, banned)
+}
+
+func (s *MockUsersStore) UpdateSettings(userID int, settings *thesrc.UserSettings) error {
+	if s.UpdateSettings_ == nil {
+		return nil
+	}
+	return s.UpdateSettings_(userID, settings)
+}
+
+func (s *MockUsersStore) SetPasswordHash(userID int, hash []byte) error {
+	if s.SetPasswordHash_ == nil {
+		return nil
+	}
+	return s.SetPasswordHash_(userID, hash)
+}
+
+func (s *MockUsersStore) SetTwoFactor(userID int, secret, recoveryCodeHashes string) error {
+	if s.SetTwoFactor_ == nil {
+		return nil
+	}
+	return s.SetTwoFactor_(userID, secret, recoveryCodeHashes)
+}
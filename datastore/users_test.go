@@ -0,0 +1,236 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestUsersStore_Get_db(t *testing.T) {
+	want := &thesrc.User{ID: 1, Login: "alice", PasswordHash: []byte("h")}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM users;`) // test on a clean DB
+	if err := tx.Insert(want); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	user, err := d.Users.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	normalizeTime(&want.RegisteredAt)
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("got user %+v, want %+v", user, want)
+	}
+
+	if _, err := d.Users.Get(2); err != thesrc.ErrUserNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrUserNotFound)
+	}
+}
+
+func TestUsersStore_GetByLogin_db(t *testing.T) {
+	want := &thesrc.User{ID: 1, Login: "Alice", PasswordHash: []byte("h")}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM users;`) // test on a clean DB
+	if err := tx.Insert(want); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	user, err := d.Users.GetByLogin("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	normalizeTime(&want.RegisteredAt)
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("got user %+v, want %+v", user, want)
+	}
+}
+
+func TestUsersStore_Create_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM users;`) // test on a clean DB
+
+	d := NewDatastore(tx)
+	user := &thesrc.User{Login: "alice", PasswordHash: []byte("h")}
+	if err := d.Users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 0 {
+		t.Error("want nonzero user.ID after creating")
+	}
+
+	if err := d.Users.Create(&thesrc.User{Login: "ALICE"}); err != ErrLoginTaken {
+		t.Errorf("got error %v creating user with duplicate login, want %v", err, ErrLoginTaken)
+	}
+}
+
+func TestUsersStore_SetRole_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM users;`) // test on a clean DB
+
+	d := NewDatastore(tx)
+	user := &thesrc.User{Login: "alice"}
+	if err := d.Users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Users.SetRole(user.ID, thesrc.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.Users.Get(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Role != thesrc.RoleAdmin {
+		t.Errorf("got role %q, want %q", got.Role, thesrc.RoleAdmin)
+	}
+
+	if err := d.Users.SetRole(user.ID+1, thesrc.RoleAdmin); err != thesrc.ErrUserNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrUserNotFound)
+	}
+}
+
+func TestUsersStore_SetShadowBanned_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM users;`) // test on a clean DB
+	tx.Exec(`DELETE FROM post;`)
+
+	d := NewDatastore(tx)
+	user := &thesrc.User{Login: "alice"}
+	if err := d.Users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Users.SetShadowBanned(user.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := d.Users.Get(user.ID); err != nil {
+		t.Fatal(err)
+	} else if !got.ShadowBanned {
+		t.Error("!ShadowBanned")
+	}
+
+	if _, err := d.Posts.Submit(&thesrc.Post{LinkURL: "http://example.com", AuthorUserID: user.ID, SubmittedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	for viewerUserID, want := range map[int]int{0: 0, user.ID: 1} {
+		posts, err := d.Posts.List(&thesrc.PostListOptions{ViewerUserID: viewerUserID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(posts) != want {
+			t.Errorf("viewer %d: got %d posts, want %d", viewerUserID, len(posts), want)
+		}
+	}
+
+	if err := d.Users.SetShadowBanned(user.ID+1, true); err != thesrc.ErrUserNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrUserNotFound)
+	}
+}
+
+func TestUsersStore_UpdateSettings_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM users;`) // test on a clean DB
+
+	testUsersStoreUpdateSettings(t, NewDatastore(tx))
+}
+
+// testUsersStoreUpdateSettings tests d.Users.UpdateSettings,
+// SetPasswordHash, SetTwoFactor, and GetByEmail, given a datastore without
+// users.
+func testUsersStoreUpdateSettings(t *testing.T, d *Datastore) {
+	user := &thesrc.User{Login: "alice", PasswordHash: []byte("h1")}
+	if err := d.Users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := &thesrc.UserSettings{DisplayName: "Alice", Email: "alice@example.com", DigestSubscribed: true, Theme: thesrc.ThemeDark, DefaultSort: thesrc.SortNew}
+	if err := d.Users.UpdateSettings(user.ID, settings); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Users.SetPasswordHash(user.ID, []byte("h2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Users.SetTwoFactor(user.ID, "secret", "c1 c2"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := d.Users.Get(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotSettings := &thesrc.UserSettings{DisplayName: got.DisplayName, Email: got.Email, DigestSubscribed: got.DigestSubscribed, Theme: got.Theme, DefaultSort: got.DefaultSort}
+	if !reflect.DeepEqual(gotSettings, settings) {
+		t.Errorf("got settings %+v, want %+v", gotSettings, settings)
+	}
+	if string(got.PasswordHash) != "h2" {
+		t.Errorf("got password hash %q, want %q", got.PasswordHash, "h2")
+	}
+	if got.PasswordChangedAt.IsZero() {
+		t.Error("got zero PasswordChangedAt after setting password hash")
+	}
+	if got.TOTPSecret != "secret" || got.RecoveryCodeHashes != "c1 c2" {
+		t.Errorf("got TOTP secret %q and recovery code hashes %q, want %q and %q", got.TOTPSecret, got.RecoveryCodeHashes, "secret", "c1 c2")
+	}
+
+	byEmail, err := d.Users.GetByEmail("ALICE@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byEmail.ID != user.ID {
+		t.Errorf("got user %d by email, want %d", byEmail.ID, user.ID)
+	}
+	if _, err := d.Users.GetByEmail("bob@example.com"); err != thesrc.ErrUserNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrUserNotFound)
+	}
+
+	if err := d.Users.UpdateSettings(user.ID+1, settings); err != thesrc.ErrUserNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrUserNotFound)
+	}
+	if err := d.Users.SetPasswordHash(user.ID+1, []byte("h")); err != thesrc.ErrUserNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrUserNotFound)
+	}
+	if err := d.Users.SetTwoFactor(user.ID+1, "", ""); err != thesrc.ErrUserNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrUserNotFound)
+	}
+}
+
+func TestUsersStore_Karma_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM comment;`)
+	for _, p := range []*thesrc.Post{{ID: 1, LinkURL: "http://example.com/1", AuthorUserID: 1, Score: 3}, {ID: 2, LinkURL: "http://example.com/2", AuthorUserID: 1, Score: 4}, {ID: 3, LinkURL: "http://example.com/3", AuthorUserID: 2, Score: 5}} {
+		if err := tx.Insert(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Comment scores count toward karma too.
+	if err := tx.Insert(&thesrc.Comment{PostID: 1, Body: "c", AuthorUserID: 2, Score: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	for userID, want := range map[int]int{1: 7, 2: 7, 3: 0} {
+		karma, err := d.Users.Karma(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if karma != want {
+			t.Errorf("user %d: got karma %d, want %d", userID, karma, want)
+		}
+	}
+}
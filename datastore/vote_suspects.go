@@ -0,0 +1,198 @@
+package datastore
+
+import (
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.VoteSuspect{}, "vote_suspect").SetKeys(false, "UserID")
+}
+
+// A VoteRecord is a vote on a post, with the logins of the voter and the
+// post's author, for vote fraud analysis.
+type VoteRecord struct {
+	UserID       int
+	Login        string
+	PostID       int
+	AuthorUserID int
+	AuthorLogin  string // "" if the post has no author
+	VotedAt      time.Time
+
+	// IPHash is the hash of the voter's IP address, from the vote's client
+	// record (see thesrc.ClientRecord), or "" if it has none.
+	IPHash string
+}
+
+// VoteSuspectsStore accesses the users suspected of vote fraud (see
+// thesrc.VoteSuspect) in the datastore, and the votes that they are
+// suspected from.
+type VoteSuspectsStore interface {
+	// RecentVotes returns the votes on posts that were cast at or after
+	// since, oldest first.
+	RecentVotes(since time.Time) ([]*VoteRecord, error)
+
+	// Flag records a user as a vote suspect (or updates the reason and time
+	// for which they are suspected), unless a moderator has dismissed them.
+	// It reports whether the user wasn't already a suspect.
+	Flag(suspect *thesrc.VoteSuspect) (bool, error)
+
+	// List vote suspects that haven't been dismissed, most recently flagged
+	// first.
+	List(opt *thesrc.ListOptions) ([]*thesrc.VoteSuspect, error)
+
+	// SetNullified nullifies (or restores) a suspect's votes on posts,
+	// updating the posts' scores. If the user isn't an undismissed suspect,
+	// thesrc.ErrVoteSuspectNotFound is returned.
+	SetNullified(userID int, nullified bool) error
+
+	// Dismiss clears a suspect, restoring their votes if they were
+	// nullified. If the user isn't an undismissed suspect,
+	// thesrc.ErrVoteSuspectNotFound is returned.
+	Dismiss(userID int) error
+}
+
+type voteSuspectsStore struct{ *Datastore }
+
+func (s *voteSuspectsStore) RecentVotes(since time.Time) ([]*VoteRecord, error) {
+	defer s.observe(time.Now(), "VoteSuspects.RecentVotes")
+	var votes []*VoteRecord
+	if err := s.dbh.Select(&votes, `SELECT v.userid, u.login, v.postid, p.authoruserid, COALESCE(a.login, '') AS authorlogin, v.votedat, COALESCE((SELECT MAX(c.iphash) FROM client_record c WHERE c.action='vote' AND c.userid=v.userid AND c.postid=v.postid), '') AS iphash FROM vote v INNER JOIN post p ON p.id=v.postid INNER JOIN users u ON u.id=v.userid LEFT JOIN users a ON a.id=p.authoruserid WHERE v.votedat >= $1 ORDER BY v.votedat, v.userid, v.postid;`, since); err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+func (s *voteSuspectsStore) Flag(suspect *thesrc.VoteSuspect) (bool, error) {
+	defer s.observe(time.Now(), "VoteSuspects.Flag")
+	if suspect.FlaggedAt.IsZero() {
+		suspect.FlaggedAt = time.Now()
+	}
+
+	var existing []*thesrc.VoteSuspect
+	if err := s.dbh.Select(&existing, `SELECT * FROM vote_suspect WHERE userid=$1;`, suspect.UserID); err != nil {
+		return false, err
+	}
+	if len(existing) > 0 {
+		if existing[0].Dismissed {
+			return false, nil
+		}
+		_, err := s.dbh.Exec(`UPDATE vote_suspect SET reason=$1, flaggedat=$2 WHERE userid=$3 AND NOT dismissed;`, suspect.Reason, suspect.FlaggedAt, suspect.UserID)
+		return false, err
+	}
+
+	suspect.Nullified, suspect.Dismissed = false, false
+	if err := s.dbh.Insert(suspect); err != nil {
+		if isUniqueViolation(err, "vote_suspect_pkey", "vote_suspect.userid") {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *voteSuspectsStore) List(opt *thesrc.ListOptions) ([]*thesrc.VoteSuspect, error) {
+	defer s.observe(time.Now(), "VoteSuspects.List")
+	if opt == nil {
+		opt = &thesrc.ListOptions{}
+	}
+	var suspects []*thesrc.VoteSuspect
+	if err := s.dbh.Select(&suspects, `SELECT * FROM vote_suspect WHERE NOT dismissed ORDER BY flaggedat DESC, userid LIMIT $1 OFFSET $2;`, opt.PerPageOrDefault(), opt.Offset()); err != nil {
+		return nil, err
+	}
+	return suspects, nil
+}
+
+func (s *voteSuspectsStore) SetNullified(userID int, nullified bool) error {
+	defer s.observe(time.Now(), "VoteSuspects.SetNullified")
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		res, err := tx.Exec(`UPDATE vote_suspect SET nullified=$1 WHERE userid=$2 AND NOT dismissed;`, nullified, userID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return thesrc.ErrVoteSuspectNotFound
+		}
+		return setVotesNullified(tx, userID, nullified)
+	})
+}
+
+func (s *voteSuspectsStore) Dismiss(userID int) error {
+	defer s.observe(time.Now(), "VoteSuspects.Dismiss")
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		res, err := tx.Exec(`UPDATE vote_suspect SET dismissed=true, nullified=false WHERE userid=$1 AND NOT dismissed;`, userID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return thesrc.ErrVoteSuspectNotFound
+		}
+		return setVotesNullified(tx, userID, false)
+	})
+}
+
+// setVotesNullified nullifies (or restores) all of a user's votes on posts,
+// and updates the scores of the posts whose votes changed. Votes that are
+// shadow votes don't count either way, so they don't change scores.
+func setVotesNullified(tx modl.SqlExecutor, userID int, nullified bool) error {
+	delta := "-1"
+	if !nullified {
+		delta = "+1"
+	}
+	if _, err := tx.Exec(`UPDATE post SET score=score`+delta+` WHERE id IN (SELECT postid FROM vote WHERE userid=$1 AND NOT shadow AND nullified=$2);`, userID, !nullified); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`UPDATE vote SET nullified=$1 WHERE userid=$2;`, nullified, userID)
+	return err
+}
+
+type MockVoteSuspectsStore struct {
+	RecentVotes_  func(since time.Time) ([]*VoteRecord, error)
+	Flag_         func(suspect *thesrc.VoteSuspect) (bool, error)
+	List_         func(opt *thesrc.ListOptions) ([]*thesrc.VoteSuspect, error)
+	SetNullified_ func(userID int, nullified bool) error
+	Dismiss_      func(userID int) error
+}
+
+var _ VoteSuspectsStore = &MockVoteSuspectsStore{}
+
+func (s *MockVoteSuspectsStore) RecentVotes(since time.Time) ([]*VoteRecord, error) {
+	if s.RecentVotes_ == nil {
+		return nil, nil
+	}
+	return s.RecentVotes_(since)
+}
+
+func (s *MockVoteSuspectsStore) Flag(suspect *thesrc.VoteSuspect) (bool, error) {
+	if s.Flag_ == nil {
+		return false, nil
+	}
+	return s.Flag_(suspect)
+}
+
+func (s *MockVoteSuspectsStore) List(opt *thesrc.ListOptions) ([]*thesrc.VoteSuspect, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
+
+func (s *MockVoteSuspectsStore) SetNullified(userID int, nullified bool) error {
+	if s.SetNullified_ == nil {
+		return nil
+	}
+	return s.SetNullified_(userID, nullified)
+}
+
+func (s *MockVoteSuspectsStore) Dismiss(userID int) error {
+	if s.Dismiss_ == nil {
+		return nil
+	}
+	return s.Dismiss_(userID)
+}
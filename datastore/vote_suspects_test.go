@@ -0,0 +1,106 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestVoteSuspectsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM vote;`)
+	tx.Exec(`DELETE FROM users;`)
+	tx.Exec(`DELETE FROM vote_suspect;`)
+
+	testVoteSuspectsStore(t, NewDatastore(tx))
+}
+
+// testVoteSuspectsStore tests d.VoteSuspects, and how nullified votes are
+// counted by d.Votes. d must be empty.
+func testVoteSuspectsStore(t *testing.T, d *Datastore) {
+	alice, bob := &thesrc.User{Login: "alice"}, &thesrc.User{Login: "bob"}
+	for _, user := range []*thesrc.User{alice, bob} {
+		if err := d.Users.Create(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var posts []*thesrc.Post
+	for _, linkURL := range []string{"http://example.com/1", "http://example.com/2"} {
+		post := &thesrc.Post{LinkURL: linkURL, AuthorUserID: alice.ID}
+		if _, err := d.Posts.Submit(post); err != nil {
+			t.Fatal(err)
+		}
+		posts = append(posts, post)
+	}
+	score := func(post *thesrc.Post) int {
+		p, err := d.Posts.Get(post.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.Score
+	}
+	before := score(posts[0])
+
+	if err := d.Votes.Upvote(bob.ID, posts[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	votes, err := d.VoteSuspects.RecentVotes(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(votes) != 1 || votes[0].Login != "bob" || votes[0].PostID != posts[0].ID || votes[0].AuthorLogin != "alice" {
+		t.Errorf("got recent votes %+v, want bob's vote on alice's post", votes)
+	}
+
+	if created, err := d.VoteSuspects.Flag(&thesrc.VoteSuspect{UserID: bob.ID, Login: "bob", Reason: "a"}); err != nil || !created {
+		t.Fatalf("got created %v and error %v, want true and nil", created, err)
+	}
+	if created, err := d.VoteSuspects.Flag(&thesrc.VoteSuspect{UserID: bob.ID, Login: "bob", Reason: "b"}); err != nil || created {
+		t.Fatalf("got created %v and error %v flagging again, want false and nil", created, err)
+	}
+	suspects, err := d.VoteSuspects.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suspects) != 1 || suspects[0].UserID != bob.ID || suspects[0].Reason != "b" {
+		t.Errorf("got suspects %+v, want bob with the latest reason", suspects)
+	}
+
+	// Nullified votes (and votes cast while nullified) don't count.
+	for i := 0; i < 2; i++ {
+		if err := d.VoteSuspects.SetNullified(bob.ID, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := score(posts[0]); got != before {
+		t.Errorf("got score %d after nullifying, want %d", got, before)
+	}
+	if err := d.Votes.Upvote(bob.ID, posts[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := score(posts[1]); got != before {
+		t.Errorf("got score %d after nullified upvote, want %d", got, before)
+	}
+
+	// Dismissing restores the votes, and the user isn't flagged again.
+	if err := d.VoteSuspects.Dismiss(bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, post := range posts {
+		if got := score(post); got != before+1 {
+			t.Errorf("got score %d after dismissing, want %d", got, before+1)
+		}
+	}
+	if created, err := d.VoteSuspects.Flag(&thesrc.VoteSuspect{UserID: bob.ID, Login: "bob", Reason: "c"}); err != nil || created {
+		t.Errorf("got created %v and error %v flagging dismissed user, want false and nil", created, err)
+	}
+	if suspects, _ := d.VoteSuspects.List(nil); len(suspects) != 0 {
+		t.Errorf("got suspects %+v after dismissing, want none", suspects)
+	}
+	if err := d.VoteSuspects.SetNullified(bob.ID, true); err != thesrc.ErrVoteSuspectNotFound {
+		t.Errorf("got error %v nullifying dismissed user, want %v", err, thesrc.ErrVoteSuspectNotFound)
+	}
+}
@@ -0,0 +1,251 @@
+package datastore
+
+import (
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// A vote is a user's upvote of a post.
+type vote struct {
+	UserID  int
+	PostID  int
+	VotedAt time.Time
+
+	// Shadow is whether the vote was cast while the user was shadow-banned
+	// (and so wasn't counted in the post's score).
+	Shadow bool
+
+	// Nullified is whether the vote is excluded from the post's score
+	// because the user's votes were nullified as vote fraud (see
+	// VoteSuspectsStore.SetNullified).
+	Nullified bool
+}
+
+// A commentVote is a user's upvote of a comment.
+type commentVote struct {
+	UserID    int
+	CommentID int
+	VotedAt   time.Time
+
+	// Shadow is whether the vote was cast while the user was shadow-banned
+	// (and so wasn't counted in the comment's score).
+	Shadow bool
+}
+
+func init() {
+	DB.AddTableWithName(vote{}, "vote").SetKeys(false, "UserID", "PostID")
+	DB.AddTableWithName(commentVote{}, "comment_vote").SetKeys(false, "UserID", "CommentID")
+}
+
+// VotesStore accesses votes in the datastore. Votes are cast on behalf of a
+// specific user (unlike thesrc.VotesService, which votes as the
+// authenticated user).
+type VotesStore interface {
+	// Upvote a post as a user, incrementing the post's score if the user had
+	// not already upvoted it (and is not shadow-banned or a vote suspect
+	// whose votes are nullified).
+	Upvote(userID, postID int) error
+
+	// Unvote removes a user's upvote from a post (if any), decrementing its
+	// score (if the upvote was counted).
+	Unvote(userID, postID int) error
+
+	// Voted returns the subset of postIDs that the user has upvoted.
+	Voted(userID int, postIDs []int) (map[int]bool, error)
+
+	// UpvoteComment upvotes a comment as a user, incrementing the comment's
+	// score if the user had not already upvoted it (and is not
+	// shadow-banned).
+	UpvoteComment(userID, commentID int) error
+
+	// UnvoteComment removes a user's upvote from a comment (if any),
+	// decrementing its score (if the upvote was counted).
+	UnvoteComment(userID, commentID int) error
+
+	// VotedComments returns the subset of commentIDs that the user has
+	// upvoted.
+	VotedComments(userID int, commentIDs []int) (map[int]bool, error)
+}
+
+type votesStore struct{ *Datastore }
+
+func (s *votesStore) Upvote(userID, postID int) error {
+	defer s.observe(time.Now(), "Votes.Upvote")
+	if _, err := s.Posts.Get(postID); err != nil {
+		return err
+	}
+	var changed bool
+	err := transact(s.dbh, func(tx modl.SqlExecutor) error {
+		res, err := tx.Exec(`INSERT INTO vote(userid, postid, votedat, shadow, nullified) SELECT $1, $2, $3, EXISTS (SELECT 1 FROM users WHERE id=$1 AND shadowbanned), EXISTS (SELECT 1 FROM vote_suspect WHERE userid=$1 AND nullified) WHERE NOT EXISTS (SELECT 1 FROM vote WHERE userid=$1 AND postid=$2);`, userID, postID, time.Now())
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		res, err = tx.Exec(`UPDATE post SET score=score+1 WHERE id=$1 AND NOT (SELECT shadow OR nullified FROM vote WHERE userid=$2 AND postid=$1);`, postID, userID)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		changed = n > 0
+		return err
+	})
+	if err == nil && changed {
+		s.publishPost(thesrc.EventPostScore, postID)
+	}
+	return err
+}
+
+func (s *votesStore) Unvote(userID, postID int) error {
+	defer s.observe(time.Now(), "Votes.Unvote")
+	var changed bool
+	err := transact(s.dbh, func(tx modl.SqlExecutor) error {
+		var votes []*vote
+		if err := tx.Select(&votes, `SELECT * FROM vote WHERE userid=$1 AND postid=$2;`, userID, postID); err != nil || len(votes) == 0 {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM vote WHERE userid=$1 AND postid=$2;`, userID, postID); err != nil {
+			return err
+		}
+		if votes[0].Shadow || votes[0].Nullified {
+			return nil
+		}
+		_, err := tx.Exec(`UPDATE post SET score=score-1 WHERE id=$1;`, postID)
+		changed = err == nil
+		return err
+	})
+	if err == nil && changed {
+		s.publishPost(thesrc.EventPostScore, postID)
+	}
+	return err
+}
+
+func (s *votesStore) Voted(userID int, postIDs []int) (map[int]bool, error) {
+	defer s.observe(time.Now(), "Votes.Voted")
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	in, args := inList(2, postIDs)
+	var votes []*vote
+	if err := s.dbh.Select(&votes, `SELECT * FROM vote WHERE userid=$1 AND postid IN `+in+`;`, append([]interface{}{userID}, args...)...); err != nil {
+		return nil, err
+	}
+
+	voted := make(map[int]bool, len(votes))
+	for _, v := range votes {
+		voted[v.PostID] = true
+	}
+	return voted, nil
+}
+
+func (s *votesStore) UpvoteComment(userID, commentID int) error {
+	defer s.observe(time.Now(), "Votes.UpvoteComment")
+	if _, err := s.Comments.Get(commentID); err != nil {
+		return err
+	}
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		res, err := tx.Exec(`INSERT INTO comment_vote(userid, commentid, votedat, shadow) SELECT $1, $2, $3, EXISTS (SELECT 1 FROM users WHERE id=$1 AND shadowbanned) WHERE NOT EXISTS (SELECT 1 FROM comment_vote WHERE userid=$1 AND commentid=$2);`, userID, commentID, time.Now())
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE comment SET score=score+1 WHERE id=$1 AND NOT (SELECT shadow FROM comment_vote WHERE userid=$2 AND commentid=$1);`, commentID, userID)
+		return err
+	})
+}
+
+func (s *votesStore) UnvoteComment(userID, commentID int) error {
+	defer s.observe(time.Now(), "Votes.UnvoteComment")
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		var votes []*commentVote
+		if err := tx.Select(&votes, `SELECT * FROM comment_vote WHERE userid=$1 AND commentid=$2;`, userID, commentID); err != nil || len(votes) == 0 {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM comment_vote WHERE userid=$1 AND commentid=$2;`, userID, commentID); err != nil {
+			return err
+		}
+		if votes[0].Shadow {
+			return nil
+		}
+		_, err := tx.Exec(`UPDATE comment SET score=score-1 WHERE id=$1;`, commentID)
+		return err
+	})
+}
+
+func (s *votesStore) VotedComments(userID int, commentIDs []int) (map[int]bool, error) {
+	defer s.observe(time.Now(), "Votes.VotedComments")
+	if len(commentIDs) == 0 {
+		return nil, nil
+	}
+
+	in, args := inList(2, commentIDs)
+	var votes []*commentVote
+	if err := s.dbh.Select(&votes, `SELECT * FROM comment_vote WHERE userid=$1 AND commentid IN `+in+`;`, append([]interface{}{userID}, args...)...); err != nil {
+		return nil, err
+	}
+
+	voted := make(map[int]bool, len(votes))
+	for _, v := range votes {
+		voted[v.CommentID] = true
+	}
+	return voted, nil
+}
+
+type MockVotesStore struct {
+	Upvote_        func(userID, postID int) error
+	Unvote_        func(userID, postID int) error
+	Voted_         func(userID int, postIDs []int) (map[int]bool, error)
+	UpvoteComment_ func(userID, commentID int) error
+	UnvoteComment_ func(userID, commentID int) error
+	VotedComments_ func(userID int, commentIDs []int) (map[int]bool, error)
+}
+
+var _ VotesStore = &MockVotesStore{}
+
+func (s *MockVotesStore) Upvote(userID, postID int) error {
+	if s.Upvote_ == nil {
+		return nil
+	}
+	return s.Upvote_(userID, postID)
+}
+
+func (s *MockVotesStore) Unvote(userID, postID int) error {
+	if s.Unvote_ == nil {
+		return nil
+	}
+	return s.Unvote_(userID, postID)
+}
+
+func (s *MockVotesStore) Voted(userID int, postIDs []int) (map[int]bool, error) {
+	if s.Voted_ == nil {
+		return nil, nil
+	}
+	return s.Voted_(userID, postIDs)
+}
+
+func (s *MockVotesStore) UpvoteComment(userID, commentID int) error {
+	if s.UpvoteComment_ == nil {
+		return nil
+	}
+	return s.UpvoteComment_(userID, commentID)
+}
+
+func (s *MockVotesStore) UnvoteComment(userID, commentID int) error {
+	if s.UnvoteComment_ == nil {
+		return nil
+	}
+	return s.UnvoteComment_(userID, commentID)
+}
+
+func (s *MockVotesStore) VotedComments(userID int, commentIDs []int) (map[int]bool, error) {
+	if s.VotedComments_ == nil {
+		return nil, nil
+	}
+	return s.VotedComments_(userID, commentIDs)
+}
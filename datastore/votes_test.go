@@ -0,0 +1,196 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestVotesStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM vote;`)
+	post := &thesrc.Post{ID: 1, LinkURL: "http://example.com", Score: 3}
+	if err := tx.Insert(post); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	checkScore := func(want int) {
+		post, err := d.Posts.Get(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.Score != want {
+			t.Errorf("got score %d, want %d", post.Score, want)
+		}
+	}
+
+	if err := d.Votes.Upvote(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	checkScore(4)
+
+	// Upvoting again should have no effect.
+	if err := d.Votes.Upvote(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	checkScore(4)
+
+	voted, err := d.Votes.Voted(1, []int{post.ID, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]bool{post.ID: true}; !reflect.DeepEqual(voted, want) {
+		t.Errorf("got voted %v, want %v", voted, want)
+	}
+
+	if err := d.Votes.Unvote(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	checkScore(3)
+
+	// Unvoting again should have no effect.
+	if err := d.Votes.Unvote(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	checkScore(3)
+
+	if err := d.Votes.Upvote(1, 2); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v upvoting nonexistent post, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
+
+func TestVotesStore_shadowBanned_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM vote;`)
+	tx.Exec(`DELETE FROM users;`)
+	post := &thesrc.Post{ID: 1, LinkURL: "http://example.com", Score: 3}
+	if err := tx.Insert(post); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	user := &thesrc.User{Login: "alice"}
+	if err := d.Users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Users.SetShadowBanned(user.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// The vote is recorded but not counted (even when it is removed after
+	// the user is unbanned).
+	if err := d.Votes.Upvote(user.ID, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if voted, _ := d.Votes.Voted(user.ID, []int{post.ID}); !voted[post.ID] {
+		t.Error("!voted")
+	}
+	if p, _ := d.Posts.Get(post.ID); p.Score != 3 {
+		t.Errorf("got score %d after shadow-banned upvote, want 3", p.Score)
+	}
+	if err := d.Users.SetShadowBanned(user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Votes.Unvote(user.ID, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := d.Posts.Get(post.ID); p.Score != 3 {
+		t.Errorf("got score %d after unvoting, want 3", p.Score)
+	}
+}
+
+func TestVotesStore_events_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM vote;`)
+	post := &thesrc.Post{ID: 1, LinkURL: "http://example.com", Score: 3}
+	if err := tx.Insert(post); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	events, cancel := d.Events.Subscribe()
+	defer cancel()
+
+	if err := d.Votes.Upvote(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Votes.Upvote(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(events); n != 1 {
+		t.Fatalf("got %d events, want 1", n)
+	}
+	if e := <-events; e.Type != thesrc.EventPostScore || e.Post.ID != post.ID || e.Post.Score != 4 {
+		t.Errorf("got event %s with post %+v, want score event with score 4", e.Type, e.Post)
+	}
+}
+
+func TestVotesStore_comments_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	tx.Exec(`DELETE FROM comment;`)
+	tx.Exec(`DELETE FROM comment_vote;`)
+	if err := tx.Insert(&thesrc.Post{ID: 1, LinkURL: "http://example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	older := &thesrc.Comment{PostID: 1, Body: "a", SubmittedAt: time.Now().Add(-time.Hour)}
+	newer := &thesrc.Comment{PostID: 1, Body: "b", SubmittedAt: time.Now()}
+	for _, c := range []*thesrc.Comment{older, newer} {
+		if err := tx.Insert(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDatastore(tx)
+	checkFirst := func(want int) {
+		comments, err := d.Comments.ListForPost(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(comments) != 2 || comments[0].ID != want {
+			t.Errorf("got comments %+v, want comment %d first", comments, want)
+		}
+	}
+	checkFirst(older.ID)
+
+	// Upvoting again should have no effect.
+	for i := 0; i < 2; i++ {
+		if err := d.Votes.UpvoteComment(1, newer.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c, _ := d.Comments.Get(newer.ID); c.Score != 1 {
+		t.Errorf("got score %d, want 1", c.Score)
+	}
+	checkFirst(newer.ID)
+
+	voted, err := d.Votes.VotedComments(1, []int{older.ID, newer.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]bool{newer.ID: true}; !reflect.DeepEqual(voted, want) {
+		t.Errorf("got voted %v, want %v", voted, want)
+	}
+
+	if err := d.Votes.UnvoteComment(1, newer.ID); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := d.Comments.Get(newer.ID); c.Score != 0 {
+		t.Errorf("got score %d, want 0", c.Score)
+	}
+	checkFirst(older.ID)
+
+	if err := d.Votes.UpvoteComment(1, newer.ID+100); err != thesrc.ErrCommentNotFound {
+		t.Errorf("got error %v upvoting nonexistent comment, want %v", err, thesrc.ErrCommentNotFound)
+	}
+}
@@ -0,0 +1,137 @@
+package datastore
+
+import (
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.Webhook{}, "webhook").SetKeys(true, "ID")
+	DB.AddTableWithName(thesrc.WebhookDelivery{}, "webhook_delivery").SetKeys(true, "ID")
+}
+
+// WebhooksStore accesses webhooks and their delivery logs in the datastore.
+// Unlike thesrc.WebhooksService, it returns webhooks' secrets, which are
+// needed to sign deliveries.
+type WebhooksStore interface {
+	// Create a webhook. If successful, hook.ID will be the new webhook's ID.
+	Create(hook *thesrc.Webhook) error
+
+	// List all webhooks, oldest first.
+	List() ([]*thesrc.Webhook, error)
+
+	// Delete a webhook and its delivery log. If there is no webhook with
+	// the given ID, thesrc.ErrWebhookNotFound is returned.
+	Delete(id int) error
+
+	// AddDelivery records a delivery attempt. If successful, d.ID will be the
+	// new record's ID.
+	AddDelivery(d *thesrc.WebhookDelivery) error
+
+	// ListDeliveries lists a webhook's delivery attempts, most recent
+	// first.
+	ListDeliveries(webhookID int, opt *thesrc.ListOptions) ([]*thesrc.WebhookDelivery, error)
+}
+
+type webhooksStore struct{ *Datastore }
+
+func (s *webhooksStore) Create(hook *thesrc.Webhook) error {
+	defer s.observe(time.Now(), "Webhooks.Create")
+	if hook.CreatedAt.IsZero() {
+		hook.CreatedAt = time.Now()
+	}
+	return s.dbh.Insert(hook)
+}
+
+func (s *webhooksStore) List() ([]*thesrc.Webhook, error) {
+	defer s.observe(time.Now(), "Webhooks.List")
+	var hooks []*thesrc.Webhook
+	if err := s.dbh.Select(&hooks, `SELECT * FROM webhook ORDER BY id;`); err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+func (s *webhooksStore) Delete(id int) error {
+	defer s.observe(time.Now(), "Webhooks.Delete")
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		res, err := tx.Exec(`DELETE FROM webhook WHERE id=$1;`, id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return thesrc.ErrWebhookNotFound
+		}
+		_, err = tx.Exec(`DELETE FROM webhook_delivery WHERE webhookid=$1;`, id)
+		return err
+	})
+}
+
+func (s *webhooksStore) AddDelivery(d *thesrc.WebhookDelivery) error {
+	defer s.observe(time.Now(), "Webhooks.AddDelivery")
+	if d.AttemptedAt.IsZero() {
+		d.AttemptedAt = time.Now()
+	}
+	return s.dbh.Insert(d)
+}
+
+func (s *webhooksStore) ListDeliveries(webhookID int, opt *thesrc.ListOptions) ([]*thesrc.WebhookDelivery, error) {
+	defer s.observe(time.Now(), "Webhooks.ListDeliveries")
+	if opt == nil {
+		opt = &thesrc.ListOptions{}
+	}
+	var deliveries []*thesrc.WebhookDelivery
+	if err := s.dbh.Select(&deliveries, `SELECT * FROM webhook_delivery WHERE webhookid=$1 ORDER BY id DESC LIMIT $2 OFFSET $3;`, webhookID, opt.PerPageOrDefault(), opt.Offset()); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+type MockWebhooksStore struct {
+	Create_         func(hook *thesrc.Webhook) error
+	List_           func() ([]*thesrc.Webhook, error)
+	Delete_         func(id int) error
+	AddDelivery_    func(d *thesrc.WebhookDelivery) error
+	ListDeliveries_ func(webhookID int, opt *thesrc.ListOptions) ([]*thesrc.WebhookDelivery, error)
+}
+
+var _ WebhooksStore = &MockWebhooksStore{}
+
+func (s *MockWebhooksStore) Create(hook *thesrc.Webhook) error {
+	if s.Create_ == nil {
+		return nil
+	}
+	return s.Create_(hook)
+}
+
+func (s *MockWebhooksStore) List() ([]*thesrc.Webhook, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_()
+}
+
+func (s *MockWebhooksStore) Delete(id int) error {
+	if s.Delete_ == nil {
+		return nil
+	}
+	return s.Delete_(id)
+}
+
+func (s *MockWebhooksStore) AddDelivery(d *thesrc.WebhookDelivery) error {
+	if s.AddDelivery_ == nil {
+		return nil
+	}
+	return s.AddDelivery_(d)
+}
+
+func (s *MockWebhooksStore) ListDeliveries(webhookID int, opt *thesrc.ListOptions) ([]*thesrc.WebhookDelivery, error) {
+	if s.ListDeliveries_ == nil {
+		return nil, nil
+	}
+	return s.ListDeliveries_(webhookID, opt)
+}
@@ -0,0 +1,51 @@
+package datastore
+
+import (
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestWebhooksStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM webhook;`) // test on a clean DB
+	tx.Exec(`DELETE FROM webhook_delivery;`)
+
+	d := NewDatastore(tx)
+	hook := &thesrc.Webhook{URL: "http://example.com/hook", Secret: "s"}
+	if err := d.Webhooks.Create(hook); err != nil {
+		t.Fatal(err)
+	}
+	if hook.ID == 0 {
+		t.Error("want nonzero hook.ID after creating")
+	}
+	if hooks, err := d.Webhooks.List(); err != nil {
+		t.Fatal(err)
+	} else if len(hooks) != 1 || hooks[0].Secret != "s" {
+		t.Errorf("got webhooks %+v, want 1 webhook with its secret", hooks)
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		if err := d.Webhooks.AddDelivery(&thesrc.WebhookDelivery{WebhookID: hook.ID, Event: thesrc.EventPostCreated, PostID: 1, Attempt: attempt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deliveries, err := d.Webhooks.ListDeliveries(hook.ID, &thesrc.ListOptions{PerPage: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 2 || deliveries[0].Attempt != 3 || deliveries[1].Attempt != 2 {
+		t.Errorf("got deliveries %+v, want attempts 3 and 2", deliveries)
+	}
+
+	if err := d.Webhooks.Delete(hook.ID); err != nil {
+		t.Fatal(err)
+	}
+	if deliveries, _ := d.Webhooks.ListDeliveries(hook.ID, nil); len(deliveries) != 0 {
+		t.Errorf("got %d deliveries after deleting webhook, want 0", len(deliveries))
+	}
+	if err := d.Webhooks.Delete(hook.ID); err != thesrc.ErrWebhookNotFound {
+		t.Errorf("got error %v deleting nonexistent webhook, want %v", err, thesrc.ErrWebhookNotFound)
+	}
+}
@@ -0,0 +1,90 @@
+package thesrc
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// DomainStats summarizes the posts that link to pages on a domain.
+type DomainStats struct {
+	// Domain is the canonical domain (see NormalizeDomain).
+	Domain string
+
+	// NumPosts is the number of (visible) posts that link to the domain.
+	NumPosts int
+
+	// AverageScore is the average score of those posts.
+	AverageScore float64
+
+	// NumHidden is the number of posts linking to the domain that were
+	// hidden or killed (by moderators or the spam filter).
+	NumHidden int
+}
+
+// NormalizeDomain returns the canonical form of a domain name: lowercased,
+// without a trailing dot or a leading "www.".
+func NormalizeDomain(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	return strings.TrimPrefix(domain, "www.")
+}
+
+// LinkDomain returns the canonical domain of the host in linkURL (see
+// NormalizeDomain), without its port. It returns "" if linkURL is not an
+// absolute URL.
+func LinkDomain(linkURL string) string {
+	u, err := url.Parse(linkURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := u.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return NormalizeDomain(host)
+}
+
+// DomainsService interacts with the domain-related endpoints in thesrc's API.
+// Like PostsService, it is implemented by both the API client and the
+// datastore.
+type DomainsService interface {
+	// Get statistics about the posts that link to domain.
+	Get(domain string) (*DomainStats, error)
+}
+
+type domainsService struct{ client *Client }
+
+func (s *domainsService) Get(domain string) (*DomainStats, error) {
+	url, err := s.client.url(router.Domain, map[string]string{"Domain": domain}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats *DomainStats
+	_, err = s.client.Do(req, &stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+type MockDomainsService struct {
+	Get_ func(domain string) (*DomainStats, error)
+}
+
+var _ DomainsService = &MockDomainsService{}
+
+func (s *MockDomainsService) Get(domain string) (*DomainStats, error) {
+	if s.Get_ == nil {
+		return nil, nil
+	}
+	return s.Get_(domain)
+}
@@ -0,0 +1,52 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestDomainsService_Get(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &DomainStats{Domain: "example.com", NumPosts: 2, AverageScore: 1.5}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Domain, map[string]string{"Domain": "example.com"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, want)
+	})
+
+	stats, err := client.Domains.Get("example.com")
+	if err != nil {
+		t.Errorf("Domains.Get returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("Domains.Get returned %+v, want %+v", stats, want)
+	}
+}
+
+func TestLinkDomain(t *testing.T) {
+	tests := map[string]string{
+		"http://example.com/a":          "example.com",
+		"https://WWW.Example.com:443/a": "example.com",
+		"http://blog.example.com./":     "blog.example.com",
+		"/relative":                     "",
+		"":                              "",
+	}
+	for linkURL, want := range tests {
+		if got := LinkDomain(linkURL); got != want {
+			t.Errorf("%q: got domain %q, want %q", linkURL, got, want)
+		}
+	}
+}
@@ -2,15 +2,32 @@ package thesrc
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// An ErrorResponse reports errors caused by an API request.
+// An ErrorResponse reports errors caused by an API request. The API responds
+// to failed requests with a JSON-encoded ErrorResponse, which the client
+// decodes, so callers can tell kinds of errors apart by their Code (or
+// HTTPStatusCode).
 type ErrorResponse struct {
-	Response *http.Response `json:",omitempty"`
-	Message  string
+	Response *http.Response `json:"-"`
+
+	// Code identifies the kind of error (e.g., ErrCodeNotFound or
+	// ErrCodeInvalid).
+	Code string `json:",omitempty"`
+
+	// Message describes the error.
+	Message string
+
+	// Fields describes the invalid fields of the request, if the error is
+	// an ErrCodeInvalid error.
+	Fields []*FieldError `json:",omitempty"`
 }
 
 func (r *ErrorResponse) Error() string {
@@ -21,11 +38,119 @@ func (r *ErrorResponse) Error() string {
 
 func (r *ErrorResponse) HTTPStatusCode() int { return r.Response.StatusCode }
 
+// Is reports whether the error is of the kind that target (one of ErrNotFound,
+// ErrUnauthorized, ErrForbidden, or ErrConflict) stands for, so that callers
+// can check for kinds of API errors with errors.Is.
+func (r *ErrorResponse) Is(target error) bool {
+	if r.Response == nil {
+		return false
+	}
+	switch r.Response.StatusCode {
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusForbidden:
+		return target == ErrForbidden
+	case http.StatusConflict:
+		return target == ErrConflict
+	}
+	return false
+}
+
+// Kinds of API errors, for use with errors.Is. For example,
+// errors.Is(err, ErrNotFound) reports whether err is an API error response
+// with HTTP status 404.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrConflict     = errors.New("conflict")
+)
+
+// A RateLimitError is the error that the client returns when an API request
+// is rejected because the user (or IP address) made too many requests. Its
+// ErrorResponse has Code ErrCodeRateLimited.
+type RateLimitError struct {
+	*ErrorResponse
+
+	// Limit is the number of requests allowed per rate limit window, or 0
+	// if the response didn't say.
+	Limit int
+
+	// Reset is when the rate limit resets, and requests will be allowed
+	// again.
+	Reset time.Time
+}
+
+// Unwrap returns e's ErrorResponse, so that errors.As can find it.
+func (e *RateLimitError) Unwrap() error { return e.ErrorResponse }
+
+// newRateLimitError returns the RateLimitError for the 429 response r, with
+// the limit and reset time from its X-RateLimit-* (or Retry-After) headers.
+func newRateLimitError(r *ErrorResponse) *RateLimitError {
+	e := &RateLimitError{ErrorResponse: r}
+	hdr := r.Response.Header
+	e.Limit, _ = strconv.Atoi(hdr.Get("X-RateLimit-Limit"))
+	if reset, err := strconv.ParseInt(hdr.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		e.Reset = time.Unix(reset, 0)
+	} else if d, ok := retryAfter(r.Response); ok {
+		e.Reset = time.Now().Add(d)
+	}
+	return e
+}
+
+// Codes of API errors (see ErrorResponse.Code).
+const (
+	ErrCodeBadRequest   = "bad_request"    // HTTP 400
+	ErrCodeInvalid      = "invalid"        // HTTP 400, with ErrorResponse.Fields
+	ErrCodeUnauthorized = "unauthorized"   // HTTP 401
+	ErrCodeForbidden    = "forbidden"      // HTTP 403
+	ErrCodeNotFound     = "not_found"      // HTTP 404
+	ErrCodeConflict     = "conflict"       // HTTP 409
+	ErrCodeRateLimited  = "rate_limited"   // HTTP 429
+	ErrCodeInternal     = "internal_error" // HTTP 500
+	ErrCodeUnavailable  = "unavailable"    // HTTP 503
+)
+
+// ErrorCode returns the code of the API error err (see ErrorResponse.Code),
+// or "" if err isn't (and doesn't wrap) an *ErrorResponse.
+func ErrorCode(err error) string {
+	var e *ErrorResponse
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+// A FieldError describes why one field of an API request is invalid.
+type FieldError struct {
+	// Field is the name of the invalid field, as in the request's JSON body
+	// or query parameters (e.g., "Login").
+	Field string
+
+	// Message describes why the field is invalid.
+	Message string
+}
+
+// A ValidationError is an error caused by invalid fields in an API request.
+// The API reports it as an ErrCodeInvalid error.
+type ValidationError []*FieldError
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e))
+	for i, f := range e {
+		msgs[i] = f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
 // CheckResponse checks the API response for errors, and returns them if
 // present. A response is considered an error if it has a status code outside
 // the 200 range. API error responses are expected to have either no response
 // body, or a JSON response body that maps to ErrorResponse. Any other
-// response body will be silently ignored.
+// response body will be silently ignored. A 429 response's error is a
+// *RateLimitError.
 func CheckResponse(r *http.Response) error {
 	if c := r.StatusCode; 200 <= c && c <= 299 {
 		return nil
@@ -35,6 +160,9 @@ func CheckResponse(r *http.Response) error {
 	if err == nil && data != nil {
 		json.Unmarshal(data, errorResponse)
 	}
+	if r.StatusCode == http.StatusTooManyRequests {
+		return newRateLimitError(errorResponse)
+	}
 	return errorResponse
 }
 
@@ -0,0 +1,81 @@
+package thesrc
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckResponse(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"Code":"invalid","Message":"m","Fields":[{"Field":"Login","Message":"m"}]}`)),
+	}
+
+	err := CheckResponse(resp)
+	want := &ErrorResponse{Response: resp, Code: ErrCodeInvalid, Message: "m", Fields: []*FieldError{{Field: "Login", Message: "m"}}}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("got error %+v, want %+v", err, want)
+	}
+	if code := ErrorCode(err); code != ErrCodeInvalid {
+		t.Errorf("got error code %q, want %q", code, ErrCodeInvalid)
+	}
+}
+
+func TestCheckResponse_kinds(t *testing.T) {
+	tests := map[int]error{
+		http.StatusNotFound:     ErrNotFound,
+		http.StatusUnauthorized: ErrUnauthorized,
+		http.StatusForbidden:    ErrForbidden,
+		http.StatusConflict:     ErrConflict,
+		http.StatusBadRequest:   nil,
+	}
+	kinds := []error{ErrNotFound, ErrUnauthorized, ErrForbidden, ErrConflict}
+	for status, want := range tests {
+		err := CheckResponse(&http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(""))})
+		for _, kind := range kinds {
+			if got := errors.Is(err, kind); got != (kind == want) {
+				t.Errorf("HTTP %d: got errors.Is(err, %q) == %v, want %v", status, kind, got, !got)
+			}
+		}
+	}
+}
+
+func TestCheckResponse_rateLimit(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Truncate(time.Second)
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header: http.Header{
+			"X-Ratelimit-Limit": {"60"},
+			"X-Ratelimit-Reset": {strconv.FormatInt(reset.Unix(), 10)},
+		},
+		Body: ioutil.NopCloser(strings.NewReader(`{"Code":"rate_limited","Message":"m"}`)),
+	}
+
+	err := CheckResponse(resp)
+	var e *RateLimitError
+	if !errors.As(err, &e) {
+		t.Fatalf("got error %#v, want *RateLimitError", err)
+	}
+	if e.Limit != 60 || !e.Reset.Equal(reset) {
+		t.Errorf("got limit %d and reset %v, want 60 and %v", e.Limit, e.Reset, reset)
+	}
+	if code := ErrorCode(err); code != ErrCodeRateLimited {
+		t.Errorf("got error code %q, want %q", code, ErrCodeRateLimited)
+	}
+	if !IsHTTPErrorCode(err, http.StatusTooManyRequests) {
+		t.Errorf("got error %v, want HTTP %d", err, http.StatusTooManyRequests)
+	}
+
+	// Without X-RateLimit-Reset, Retry-After gives the reset time.
+	resp.Header = http.Header{"Retry-After": {"30"}}
+	resp.Body = ioutil.NopCloser(strings.NewReader(""))
+	if err := CheckResponse(resp).(*RateLimitError); time.Until(err.Reset) < 29*time.Second || time.Until(err.Reset) > 30*time.Second {
+		t.Errorf("got reset %v, want in 30s", err.Reset)
+	}
+}
@@ -0,0 +1,29 @@
+package thesrc
+
+// Types of events that are broadcast when posts change.
+const (
+	// EventPostCreated is broadcast when a new post is submitted.
+	EventPostCreated = "post:created"
+
+	// EventPostScore is broadcast when a post's score changes because it
+	// was upvoted or unvoted.
+	EventPostScore = "post:score"
+
+	// EventPostUpdated is broadcast when a post's title, body, or tags are
+	// edited.
+	EventPostUpdated = "post:updated"
+
+	// EventPostFlagged is broadcast when a user flags a post.
+	EventPostFlagged = "post:flagged"
+)
+
+// An Event describes a change to the site's data, such as a new post or a
+// change in a post's score. Events are pushed to clients of the live
+// updates endpoint and sent to webhooks.
+type Event struct {
+	// Type is the type of event (one of the Event* constants).
+	Type string
+
+	// Post is the post that the event concerns, as of just after the change.
+	Post *Post `json:",omitempty"`
+}
@@ -0,0 +1,70 @@
+// Package events broadcasts changes to the site's data (such as new posts
+// and score changes) to subscribers in the same process, or (with Relay) in
+// all processes sharing a Redis server.
+package events
+
+import (
+	"sync"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// subscriberBuffer is the number of events that may be queued for a
+// subscriber that isn't keeping up. Further events for it are dropped until
+// it catches up.
+const subscriberBuffer = 64
+
+// A Hub broadcasts published events to all of its current subscribers.
+// Publishing never blocks, so a slow subscriber misses events rather than
+// delaying the publisher. A nil *Hub discards all events.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[chan *thesrc.Event]struct{}
+}
+
+// NewHub creates a new hub with no subscribers.
+func NewHub() *Hub {
+	return &Hub{subs: map[chan *thesrc.Event]struct{}{}}
+}
+
+// Subscribe returns a channel that receives all events published after the
+// call. The caller must call cancel when it no longer wants events, after
+// which the channel is closed.
+func (h *Hub) Subscribe() (events <-chan *thesrc.Event, cancel func()) {
+	c := make(chan *thesrc.Event, subscriberBuffer)
+	if h == nil {
+		close(c)
+		return c, func() {}
+	}
+
+	h.mu.Lock()
+	h.subs[c] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, c)
+			close(c)
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Publish sends e to all current subscribers. Subscribers must not modify
+// the event or the post it refers to.
+func (h *Hub) Publish(e *thesrc.Event) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.subs {
+		select {
+		case c <- e:
+		default:
+		}
+	}
+}
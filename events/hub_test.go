@@ -0,0 +1,58 @@
+package events
+
+import (
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestHub(t *testing.T) {
+	h := NewHub()
+
+	c1, cancel1 := h.Subscribe()
+	c2, cancel2 := h.Subscribe()
+	defer cancel2()
+
+	e := &thesrc.Event{Type: thesrc.EventPostCreated, Post: &thesrc.Post{ID: 1}}
+	h.Publish(e)
+	for i, c := range []<-chan *thesrc.Event{c1, c2} {
+		if got := <-c; got != e {
+			t.Errorf("subscriber %d: got event %+v, want %+v", i, got, e)
+		}
+	}
+
+	cancel1()
+	cancel1() // calling cancel again is a no-op
+	if _, ok := <-c1; ok {
+		t.Error("want channel closed after cancel")
+	}
+
+	h.Publish(e)
+	if got := <-c2; got != e {
+		t.Errorf("got event %+v, want %+v", got, e)
+	}
+}
+
+func TestHub_slowSubscriber(t *testing.T) {
+	h := NewHub()
+	c, cancel := h.Subscribe()
+	defer cancel()
+
+	// Publishing must not block even when the subscriber isn't receiving.
+	for i := 0; i < subscriberBuffer*2; i++ {
+		h.Publish(&thesrc.Event{Type: thesrc.EventPostScore})
+	}
+	if n := len(c); n != subscriberBuffer {
+		t.Errorf("got %d queued events, want %d", n, subscriberBuffer)
+	}
+}
+
+func TestHub_nil(t *testing.T) {
+	var h *Hub
+	h.Publish(&thesrc.Event{})
+	c, cancel := h.Subscribe()
+	defer cancel()
+	if _, ok := <-c; ok {
+		t.Error("want closed channel from nil hub")
+	}
+}
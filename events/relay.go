@@ -0,0 +1,119 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/redis"
+)
+
+// Relay shares events between processes through Redis pub/sub. It returns a
+// hub that receives the events published to local (immediately) and to the
+// local hubs of all other processes relaying on the same Redis channel.
+//
+// If the connection to Redis is lost, events from other processes are
+// missed until it is reestablished; local events are always delivered.
+func Relay(local *Hub, c *redis.Client, channel string) *Hub {
+	h := NewHub()
+	r := &relay{hub: h, c: c, channel: channel, origin: newOrigin()}
+
+	events, _ := local.Subscribe()
+	go r.send(events)
+	go r.receive()
+	return h
+}
+
+// relayRetryDelay is how long Relay waits before resubscribing after
+// losing its Redis subscription, doubled after each consecutive failure up
+// to relayMaxRetryDelay.
+const (
+	relayRetryDelay    = time.Second
+	relayMaxRetryDelay = time.Minute
+)
+
+type relay struct {
+	hub     *Hub
+	c       *redis.Client
+	channel string
+
+	// origin identifies this process's messages, so that it doesn't deliver
+	// its own events twice.
+	origin string
+}
+
+// A relayMessage is an event as published to Redis.
+type relayMessage struct {
+	Origin string
+	Event  *thesrc.Event
+}
+
+func newOrigin() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// send delivers local events to the hub and publishes them to Redis. If
+// publishing is slow, local events beyond the subscriber buffer are dropped
+// (as for any slow subscriber).
+func (r *relay) send(events <-chan *thesrc.Event) {
+	for e := range events {
+		r.hub.Publish(e)
+
+		data, err := json.Marshal(relayMessage{Origin: r.origin, Event: e})
+		if err != nil {
+			logging.Default.Log("Encoding event for relay failed", "error", err)
+			continue
+		}
+		if _, err := r.c.Do(context.Background(), "PUBLISH", r.channel, data); err != nil {
+			logging.Default.Log("Relaying event failed", "error", err)
+		}
+	}
+}
+
+// receive delivers the events published by other processes to the hub,
+// resubscribing whenever the subscription fails.
+func (r *relay) receive() {
+	delay := relayRetryDelay
+	for {
+		subscribed, err := r.receiveUntilError()
+		if subscribed {
+			delay = relayRetryDelay
+		}
+		logging.Default.Log("Event relay subscription failed", "error", err, "retry_in_ms", logging.Milliseconds(delay))
+		time.Sleep(delay)
+		if delay *= 2; delay > relayMaxRetryDelay {
+			delay = relayMaxRetryDelay
+		}
+	}
+}
+
+// receiveUntilError subscribes and delivers events until the subscription
+// fails. It reports whether subscribing succeeded.
+func (r *relay) receiveUntilError() (subscribed bool, err error) {
+	sub, err := r.c.Subscribe(context.Background(), r.channel)
+	if err != nil {
+		return false, err
+	}
+	defer sub.Close()
+
+	for {
+		msg, err := sub.Receive()
+		if err != nil {
+			return true, err
+		}
+		var m relayMessage
+		if err := json.Unmarshal(msg.Data, &m); err != nil || m.Event == nil {
+			logging.Default.Log("Invalid relayed event", "data", string(msg.Data))
+			continue
+		}
+		if m.Origin != r.origin {
+			r.hub.Publish(m.Event)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package events
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/redis"
+)
+
+// pubSubServer is a Redis server that only supports PUBLISH and SUBSCRIBE
+// (on a single channel).
+type pubSubServer struct {
+	ln net.Listener
+
+	mu   sync.Mutex
+	subs []*bufio.Writer
+}
+
+func newPubSubServer(t *testing.T) *pubSubServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &pubSubServer{ln: ln}
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(nc)
+		}
+	}()
+	return s
+}
+
+func (s *pubSubServer) serve(nc net.Conn) {
+	defer nc.Close()
+	r, w := bufio.NewReader(nc), bufio.NewWriter(nc)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		switch args[0] {
+		case "SUBSCRIBE":
+			s.subs = append(s.subs, w)
+			fmt.Fprintf(w, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(args[1]), args[1])
+		case "PUBLISH":
+			for _, sw := range s.subs {
+				fmt.Fprintf(sw, "*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(args[1]), args[1], len(args[2]), args[2])
+				sw.Flush()
+			}
+			fmt.Fprintf(w, ":%d\r\n", len(s.subs))
+		}
+		w.Flush()
+		s.mu.Unlock()
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		var size int
+		if _, err := fmt.Fscanf(r, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+		b := make([]byte, size+2)
+		for read := 0; read < len(b); {
+			m, err := r.Read(b[read:])
+			if err != nil {
+				return nil, err
+			}
+			read += m
+		}
+		args[i] = string(b[:size])
+	}
+	return args, nil
+}
+
+func TestRelay(t *testing.T) {
+	s := newPubSubServer(t)
+	defer s.ln.Close()
+	c := &redis.Client{Addr: s.ln.Addr().String()}
+
+	local1, local2 := NewHub(), NewHub()
+	hub1, hub2 := Relay(local1, c, "events"), Relay(local2, c, "events")
+	events1, cancel1 := hub1.Subscribe()
+	defer cancel1()
+	events2, cancel2 := hub2.Subscribe()
+	defer cancel2()
+
+	// Wait for both relays to subscribe.
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		s.mu.Lock()
+		n := len(s.subs)
+		s.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d subscriptions, want 2", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	local1.Publish(&thesrc.Event{Type: thesrc.EventPostCreated, Post: &thesrc.Post{ID: 1}})
+	for i, events := range []<-chan *thesrc.Event{events1, events2} {
+		if e := receiveEvent(t, events); e.Type != thesrc.EventPostCreated || e.Post == nil || e.Post.ID != 1 {
+			t.Errorf("hub %d: got event %+v", i+1, e)
+		}
+	}
+
+	// The publishing process receives its own event only once.
+	local2.Publish(&thesrc.Event{Type: thesrc.EventPostScore, Post: &thesrc.Post{ID: 2}})
+	for i, events := range []<-chan *thesrc.Event{events1, events2} {
+		if e := receiveEvent(t, events); e.Post == nil || e.Post.ID != 2 {
+			t.Errorf("hub %d: got event %+v, want the event for post 2", i+1, e)
+		}
+	}
+	select {
+	case e := <-events1:
+		t.Errorf("hub 1: got unexpected event %+v", e)
+	case e := <-events2:
+		t.Errorf("hub 2: got duplicate event %+v", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func receiveEvent(t *testing.T, events <-chan *thesrc.Event) *thesrc.Event {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return nil
+	}
+}
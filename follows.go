@@ -0,0 +1,157 @@
+package thesrc
+
+import (
+	"fmt"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A Follow is a user's interest in (or, if Hide is set, disinterest in) a
+// topic: a tag or a link domain. Posts about the topics that a user follows
+// are boosted on their front page, and posts about the topics they hide are
+// demoted (see PostListOptions.Personalized).
+type Follow struct {
+	// UserID is the ID of the user who follows the topic.
+	UserID int `json:",omitempty"`
+
+	// Kind is the kind of topic (FollowTag or FollowDomain).
+	Kind string
+
+	// Name is the topic's name: a tag (see NormalizeTag) or a domain (see
+	// NormalizeDomain).
+	Name string
+
+	// Hide is whether the user hid the topic, rather than followed it.
+	Hide bool `json:",omitempty"`
+
+	// CreatedAt is when the user followed (or hid) the topic.
+	CreatedAt time.Time
+}
+
+// Kinds of topics that users can follow (see Follow.Kind).
+const (
+	FollowTag    = "tag"
+	FollowDomain = "domain"
+)
+
+// NormalizeFollow checks that follow's Kind is valid and normalizes its
+// Name.
+func NormalizeFollow(follow *Follow) error {
+	switch follow.Kind {
+	case FollowTag:
+		tag, err := NormalizeTag(follow.Name)
+		if err != nil {
+			return err
+		}
+		follow.Name = tag
+	case FollowDomain:
+		follow.Name = NormalizeDomain(follow.Name)
+		if follow.Name == "" {
+			return fmt.Errorf("domain must not be empty")
+		}
+	default:
+		return fmt.Errorf("invalid kind of topic %q (must be %q or %q)", follow.Kind, FollowTag, FollowDomain)
+	}
+	return nil
+}
+
+// FollowsService interacts with the follow-related endpoints in thesrc's
+// API. It manages the followed and hidden topics of the user that the
+// client is authenticated as.
+type FollowsService interface {
+	// List the topics that the user follows or hides, most recently
+	// followed first.
+	List() ([]*Follow, error)
+
+	// Follow a topic or, if follow.Hide is set, hide it. Following a topic
+	// that the user already hides (or vice versa) replaces the hide. If
+	// successful, follow is updated to reflect the stored follow (with its
+	// Name normalized).
+	Follow(follow *Follow) error
+
+	// Unfollow stops following (or unhides) a topic. Unfollowing a topic
+	// that the user doesn't follow has no effect.
+	Unfollow(kind, name string) error
+}
+
+type followsService struct{ client *Client }
+
+func (s *followsService) List() ([]*Follow, error) {
+	url, err := s.client.url(router.Follows, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var follows []*Follow
+	_, err = s.client.Do(req, &follows)
+	if err != nil {
+		return nil, err
+	}
+
+	return follows, nil
+}
+
+func (s *followsService) Follow(follow *Follow) error {
+	url, err := s.client.url(router.Follow, map[string]string{"Kind": follow.Kind, "Name": follow.Name}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), follow)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, &follow)
+	return err
+}
+
+func (s *followsService) Unfollow(kind, name string) error {
+	url, err := s.client.url(router.Unfollow, map[string]string{"Kind": kind, "Name": name}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+type MockFollowsService struct {
+	List_     func() ([]*Follow, error)
+	Follow_   func(follow *Follow) error
+	Unfollow_ func(kind, name string) error
+}
+
+var _ FollowsService = &MockFollowsService{}
+
+func (s *MockFollowsService) List() ([]*Follow, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_()
+}
+
+func (s *MockFollowsService) Follow(follow *Follow) error {
+	if s.Follow_ == nil {
+		return nil
+	}
+	return s.Follow_(follow)
+}
+
+func (s *MockFollowsService) Unfollow(kind, name string) error {
+	if s.Unfollow_ == nil {
+		return nil
+	}
+	return s.Unfollow_(kind, name)
+}
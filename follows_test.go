@@ -0,0 +1,116 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestFollowsService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*Follow{{UserID: 1, Kind: FollowTag, Name: "go"}, {UserID: 1, Kind: FollowDomain, Name: "example.com", Hide: true}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Follows, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, want)
+	})
+
+	follows, err := client.Follows.List()
+	if err != nil {
+		t.Errorf("Follows.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, follow := range want {
+		normalizeTime(&follow.CreatedAt)
+	}
+	if !reflect.DeepEqual(follows, want) {
+		t.Errorf("Follows.List returned %+v, want %+v", follows, want)
+	}
+}
+
+func TestFollowsService_Follow(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Follow{UserID: 1, Kind: FollowDomain, Name: "example.com", Hide: true}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Follow, map[string]string{"Kind": "domain", "Name": "example.com"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+		testBody(t, r, `{"Kind":"domain","Name":"example.com","Hide":true,"CreatedAt":"0001-01-01T00:00:00Z"}`+"\n")
+
+		writeJSON(w, want)
+	})
+
+	follow := &Follow{Kind: FollowDomain, Name: "example.com", Hide: true}
+	if err := client.Follows.Follow(follow); err != nil {
+		t.Errorf("Follows.Follow returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.CreatedAt)
+	if !reflect.DeepEqual(follow, want) {
+		t.Errorf("Follows.Follow returned %+v, want %+v", follow, want)
+	}
+}
+
+func TestFollowsService_Unfollow(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Unfollow, map[string]string{"Kind": "tag", "Name": "go"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Follows.Unfollow(FollowTag, "go"); err != nil {
+		t.Errorf("Follows.Unfollow returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestNormalizeFollow(t *testing.T) {
+	tests := []struct {
+		follow   Follow
+		wantName string
+		wantErr  bool
+	}{
+		{Follow{Kind: FollowTag, Name: " Go "}, "go", false},
+		{Follow{Kind: FollowTag, Name: "not a tag"}, "", true},
+		{Follow{Kind: FollowDomain, Name: "WWW.Example.com."}, "example.com", false},
+		{Follow{Kind: FollowDomain, Name: " "}, "", true},
+		{Follow{Kind: "user", Name: "alice"}, "", true},
+	}
+	for _, test := range tests {
+		follow := test.follow
+		err := NormalizeFollow(&follow)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%+v: got error %v, want error %v", test.follow, err, test.wantErr)
+			continue
+		}
+		if err == nil && follow.Name != test.wantName {
+			t.Errorf("%+v: got name %q, want %q", test.follow, follow.Name, test.wantName)
+		}
+	}
+}
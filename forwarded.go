@@ -0,0 +1,40 @@
+package thesrc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// ForwardedIPHeader is the HTTP header in which a client sends the signed IP
+// address of the end user on whose behalf it is making a request (see
+// Client.WithForwardedClient). Unlike X-Forwarded-For, it is signed, so the
+// API can trust it no matter which proxies the request passed through.
+const ForwardedIPHeader = "X-Thesrc-Forwarded-IP"
+
+// SignForwardedIP returns the value of the ForwardedIPHeader header that
+// identifies ip as the end user's IP address, signed with key.
+func SignForwardedIP(key []byte, ip string) string {
+	return ip + " " + forwardedIPSignature(key, ip)
+}
+
+// VerifyForwardedIP returns the IP address in the value of a
+// ForwardedIPHeader header, and whether it was signed with key.
+func VerifyForwardedIP(key []byte, header string) (ip string, ok bool) {
+	i := strings.LastIndex(header, " ")
+	if len(key) == 0 || i == -1 {
+		return "", false
+	}
+	ip, sig := header[:i], header[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(forwardedIPSignature(key, ip))) {
+		return "", false
+	}
+	return ip, true
+}
+
+func forwardedIPSignature(key []byte, ip string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("forwarded-ip:" + ip))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
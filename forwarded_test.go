@@ -0,0 +1,47 @@
+package thesrc
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestVerifyForwardedIP(t *testing.T) {
+	key := []byte("k")
+	header := SignForwardedIP(key, "203.0.113.7")
+	if ip, ok := VerifyForwardedIP(key, header); !ok || ip != "203.0.113.7" {
+		t.Errorf("got %q, %v, want the signed IP address", ip, ok)
+	}
+	for _, bad := range []string{"", "203.0.113.7", "198.51.100.1 " + header[len("203.0.113.7 "):]} {
+		if _, ok := VerifyForwardedIP(key, bad); ok {
+			t.Errorf("header %q verified, want it rejected", bad)
+		}
+	}
+	if _, ok := VerifyForwardedIP([]byte("other"), header); ok {
+		t.Error("header verified with another key")
+	}
+}
+
+func TestClient_WithForwardedClient(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if got, want := r.UserAgent(), "browser"; got != want {
+			t.Errorf("got User-Agent %q, want %q", got, want)
+		}
+		if ip, ok := VerifyForwardedIP([]byte("k"), r.Header.Get(ForwardedIPHeader)); !ok || ip != "203.0.113.7" {
+			t.Errorf("got forwarded IP %q (verified: %v), want 203.0.113.7", ip, ok)
+		}
+		writeJSON(w, []*Tag{})
+	})
+
+	client.ForwardingKey = []byte("k")
+	if _, err := client.WithForwardedClient("203.0.113.7", "browser").Tags.List(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Fatal("!called")
+	}
+}
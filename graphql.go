@@ -0,0 +1,87 @@
+package thesrc
+
+import (
+	"encoding/json"
+	"strings"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// GraphQLService interacts with thesrc's GraphQL API endpoint, which exposes
+// posts, comments, users, and votes so that clients can fetch related data
+// (such as a post with its comments and their authors) in one request.
+type GraphQLService interface {
+	// Do executes a GraphQL query or mutation with the given variables and
+	// decodes the "data" member of the result into data. If the result has
+	// errors, they are returned as GraphQLErrors (after data is decoded, if
+	// any).
+	Do(query string, variables map[string]interface{}, data interface{}) error
+}
+
+// A GraphQLError is an error returned by the GraphQL API.
+type GraphQLError struct {
+	Message string `json:"message"`
+
+	// Path is the path of the field that caused the error (field names and
+	// list indexes), if any.
+	Path []interface{} `json:"path,omitempty"`
+}
+
+// GraphQLErrors are the errors returned by a GraphQL request.
+type GraphQLErrors []*GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Message
+	}
+	return "graphql: " + strings.Join(msgs, "; ")
+}
+
+type graphQLService struct{ client *Client }
+
+func (s *graphQLService) Do(query string, variables map[string]interface{}, data interface{}) error {
+	url, err := s.client.url(router.GraphQL, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	body := struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables,omitempty"`
+	}{query, variables}
+	req, err := s.client.NewRequest("POST", url.String(), body)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors GraphQLErrors   `json:"errors"`
+	}
+	if _, err := s.client.Do(req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Data) > 0 && data != nil {
+		if err := json.Unmarshal(resp.Data, data); err != nil {
+			return err
+		}
+	}
+	if len(resp.Errors) > 0 {
+		return resp.Errors
+	}
+	return nil
+}
+
+type MockGraphQLService struct {
+	Do_ func(query string, variables map[string]interface{}, data interface{}) error
+}
+
+var _ GraphQLService = &MockGraphQLService{}
+
+func (s *MockGraphQLService) Do(query string, variables map[string]interface{}, data interface{}) error {
+	if s.Do_ == nil {
+		return nil
+	}
+	return s.Do_(query, variables, data)
+}
@@ -0,0 +1,533 @@
+// Package graphql executes GraphQL queries and mutations against a schema of
+// Go resolver functions.
+//
+// It implements the parts of GraphQL that thesrc's API needs: operations
+// with variables, fields with arguments and aliases, named and inline
+// fragments, and the @include and @skip directives. Schema introspection is
+// not supported, and requests aren't validated before they're executed, so
+// an invalid field only causes an error (and a null value) for that field.
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// A Schema defines the types and fields that may be queried.
+type Schema struct {
+	// Query is the root type of query operations.
+	Query *Object
+
+	// Mutation is the root type of mutation operations, or nil if the schema
+	// doesn't support mutations.
+	Mutation *Object
+}
+
+// An Object is a GraphQL object type.
+type Object struct {
+	// Name is the type's name, as returned by __typename.
+	Name string
+
+	// Fields maps field names to their definitions.
+	Fields map[string]*Field
+}
+
+// A Field defines a field of an Object.
+type Field struct {
+	// Type is the object type of the field's value, or nil if the field's
+	// value is a scalar (which is encoded as JSON).
+	Type *Object
+
+	// List is whether the field's value is a slice of Type.
+	List bool
+
+	// Args defines the arguments that the field accepts.
+	Args map[string]*Arg
+
+	// Resolve returns the field's value. If nil, the value is the Go struct
+	// field of the parent object whose name matches the GraphQL field name
+	// case-insensitively (e.g., "linkURL" resolves to the LinkURL field).
+	Resolve func(p *Params) (interface{}, error)
+}
+
+// ArgType is the type of an argument's value.
+type ArgType int
+
+const (
+	Int     ArgType = iota // coerced to int
+	Float                  // coerced to float64
+	String                 // coerced to string
+	Boolean                // coerced to bool
+)
+
+func (t ArgType) String() string {
+	switch t {
+	case Int:
+		return "Int"
+	case Float:
+		return "Float"
+	case String:
+		return "String"
+	case Boolean:
+		return "Boolean"
+	}
+	return "Unknown"
+}
+
+// An Arg defines an argument of a Field.
+type Arg struct {
+	Type ArgType
+
+	// List is whether the argument is a list of Type. (A single value is
+	// coerced to a list of one.)
+	List bool
+
+	// NonNull is whether the argument is required.
+	NonNull bool
+}
+
+// Params are passed to a Field's Resolve function.
+type Params struct {
+	// Source is the value of the parent object.
+	Source interface{}
+
+	// Args holds the field's arguments, coerced to their types. Arguments
+	// that weren't given are absent.
+	Args map[string]interface{}
+
+	// Context is the value passed to Execute.
+	Context interface{}
+}
+
+// Int returns the named Int argument, or 0 if it wasn't given.
+func (p *Params) Int(name string) int {
+	v, _ := p.Args[name].(int)
+	return v
+}
+
+// String returns the named String argument, or "" if it wasn't given.
+func (p *Params) String(name string) string {
+	v, _ := p.Args[name].(string)
+	return v
+}
+
+// Bool returns the named Boolean argument, or false if it wasn't given.
+func (p *Params) Bool(name string) bool {
+	v, _ := p.Args[name].(bool)
+	return v
+}
+
+// Strings returns the named list of Strings argument, or nil if it wasn't
+// given.
+func (p *Params) Strings(name string) []string {
+	list, _ := p.Args[name].([]interface{})
+	if list == nil {
+		return nil
+	}
+	strs := make([]string, len(list))
+	for i, v := range list {
+		strs[i], _ = v.(string)
+	}
+	return strs
+}
+
+// A Request is a GraphQL request, as sent in the body of a POST request.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// A Response is the result of executing a Request.
+type Response struct {
+	// Data is the result of the operation, or nil if the request couldn't be
+	// executed.
+	Data interface{} `json:"data,omitempty"`
+
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// An Error is an error that occurred while executing a request.
+type Error struct {
+	Message string `json:"message"`
+
+	// Path is the response path of the field that caused the error (field
+	// names and list indexes), if any.
+	Path []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Execute executes req against schema. The context is passed to resolvers in
+// Params.Context.
+func Execute(schema *Schema, req *Request, context interface{}) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	root := schema.Query
+	if op.typ == "mutation" {
+		root = schema.Mutation
+		if root == nil {
+			return &Response{Errors: []*Error{{Message: "mutations are not supported"}}}
+		}
+	}
+
+	vars, err := variableValues(op, req.Variables)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	e := &executor{doc: doc, vars: vars, context: context}
+	data := e.selectionSet(root, nil, op.sel, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+// operation returns the operation named name, or the only operation if name
+// is empty.
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) != 1 {
+			return nil, fmt.Errorf("document must contain exactly one operation unless an operation name is given (found %d)", len(d.operations))
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// variableValues returns the values of op's variables, from given or their
+// defaults.
+func variableValues(op *operation, given map[string]interface{}) (map[string]interface{}, error) {
+	vars := map[string]interface{}{}
+	for _, v := range op.vars {
+		if val, ok := given[v.name]; ok && val != nil {
+			vars[v.name] = val
+		} else if v.def != nil {
+			vars[v.name] = v.def
+		} else if v.nonNull {
+			return nil, fmt.Errorf("variable $%s of non-null type was not provided", v.name)
+		}
+	}
+	return vars, nil
+}
+
+type executor struct {
+	doc     *document
+	vars    map[string]interface{}
+	context interface{}
+	errors  []*Error
+}
+
+func (e *executor) fieldError(path []interface{}, format string, args ...interface{}) {
+	e.errors = append(e.errors, &Error{
+		Message: fmt.Sprintf(format, args...),
+		Path:    append([]interface{}{}, path...),
+	})
+}
+
+// selectionSet resolves the fields in sel on source, an object of type obj.
+func (e *executor) selectionSet(obj *Object, source interface{}, sel []selection, path []interface{}) *orderedMap {
+	keys, fields := e.collectFields(obj, sel, map[string]bool{}, nil, map[string][]*field{})
+	result := &orderedMap{keys: keys, values: make(map[string]interface{}, len(keys))}
+	for _, key := range keys {
+		result.values[key] = e.field(obj, source, fields[key], append(path, key))
+	}
+	return result
+}
+
+// collectFields groups the fields in sel (including those in fragments) by
+// their response keys, in the order in which they first appear.
+func (e *executor) collectFields(obj *Object, sel []selection, visited map[string]bool, keys []string, fields map[string][]*field) ([]string, map[string][]*field) {
+	for _, s := range sel {
+		switch s := s.(type) {
+		case *field:
+			if !e.included(s.directives) {
+				continue
+			}
+			key := s.key()
+			if _, seen := fields[key]; !seen {
+				keys = append(keys, key)
+			}
+			fields[key] = append(fields[key], s)
+
+		case *fragmentSpread:
+			if visited[s.name] || !e.included(s.directives) {
+				continue
+			}
+			visited[s.name] = true
+			frag := e.doc.fragments[s.name]
+			if frag == nil {
+				e.fieldError(nil, "unknown fragment %q", s.name)
+				continue
+			}
+			if frag.on != obj.Name {
+				continue
+			}
+			keys, fields = e.collectFields(obj, frag.sel, visited, keys, fields)
+
+		case *inlineFragment:
+			if !e.included(s.directives) || (s.on != "" && s.on != obj.Name) {
+				continue
+			}
+			keys, fields = e.collectFields(obj, s.sel, visited, keys, fields)
+		}
+	}
+	return keys, fields
+}
+
+// included returns whether a selection with the given directives should be
+// included, according to its @include and @skip directives.
+func (e *executor) included(dirs []*directive) bool {
+	for _, d := range dirs {
+		var ifArg interface{}
+		for _, arg := range d.args {
+			if arg.name == "if" {
+				ifArg, _ = e.value(arg.value)
+			}
+		}
+		cond, _ := ifArg.(bool)
+		switch d.name {
+		case "include":
+			if !cond {
+				return false
+			}
+		case "skip":
+			if cond {
+				return false
+			}
+		default:
+			e.fieldError(nil, "unknown directive @%s", d.name)
+		}
+	}
+	return true
+}
+
+// field resolves the field (requested as fields, which all have the same
+// response key) on source, an object of type obj.
+func (e *executor) field(obj *Object, source interface{}, fields []*field, path []interface{}) interface{} {
+	f := fields[0]
+	if f.name == "__typename" {
+		return obj.Name
+	}
+
+	def := obj.Fields[f.name]
+	if def == nil {
+		e.fieldError(path, "unknown field %q on type %s", f.name, obj.Name)
+		return nil
+	}
+
+	args, err := e.arguments(def, f)
+	if err != nil {
+		e.fieldError(path, "%s", err)
+		return nil
+	}
+
+	var v interface{}
+	if def.Resolve != nil {
+		v, err = def.Resolve(&Params{Source: source, Args: args, Context: e.context})
+	} else {
+		v, err = structField(source, f.name)
+	}
+	if err != nil {
+		e.fieldError(path, "%s", err)
+		return nil
+	}
+
+	var sel []selection
+	for _, f := range fields {
+		sel = append(sel, f.sel...)
+	}
+	if def.Type == nil {
+		if len(sel) > 0 {
+			e.fieldError(path, "field %q of type %s must not have a selection of subfields", f.name, obj.Name)
+			return nil
+		}
+		return v
+	}
+	if len(sel) == 0 {
+		e.fieldError(path, "field %q must have a selection of subfields", f.name)
+		return nil
+	}
+	if !def.List {
+		return e.object(def.Type, v, sel, path)
+	}
+
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || (rv.Kind() == reflect.Slice && rv.IsNil()) {
+		return []interface{}{}
+	}
+	if rv.Kind() != reflect.Slice {
+		e.fieldError(path, "field %q is not a list", f.name)
+		return nil
+	}
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = e.object(def.Type, rv.Index(i).Interface(), sel, append(path, i))
+	}
+	return list
+}
+
+// object resolves sel on v, an object of type obj, or returns nil if v is
+// nil.
+func (e *executor) object(obj *Object, v interface{}, sel []selection, path []interface{}) interface{} {
+	if rv := reflect.ValueOf(v); !rv.IsValid() || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		return nil
+	}
+	return e.selectionSet(obj, v, sel, path)
+}
+
+// arguments returns f's arguments, coerced to the types in def.
+func (e *executor) arguments(def *Field, f *field) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	for _, arg := range f.args {
+		argDef := def.Args[arg.name]
+		if argDef == nil {
+			return nil, fmt.Errorf("unknown argument %q on field %q", arg.name, f.name)
+		}
+		v, ok := e.value(arg.value)
+		if !ok || v == nil {
+			continue
+		}
+		v, err := coerce(argDef, v)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %s", arg.name, err)
+		}
+		args[arg.name] = v
+	}
+	for name, argDef := range def.Args {
+		if _, ok := args[name]; !ok && argDef.NonNull {
+			return nil, fmt.Errorf("missing required argument %q on field %q", name, f.name)
+		}
+	}
+	return args, nil
+}
+
+// value substitutes variables in v. It returns false if v is an unset
+// variable.
+func (e *executor) value(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case variable:
+		val, ok := e.vars[string(v)]
+		return val, ok
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, elem := range v {
+			list[i], _ = e.value(elem)
+		}
+		return list, true
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			if val, ok := e.value(elem); ok {
+				obj[k] = val
+			}
+		}
+		return obj, true
+	}
+	return v, true
+}
+
+// coerce coerces v (from the document or from the request's variables, which
+// are decoded from JSON) to the type in def.
+func coerce(def *Arg, v interface{}) (interface{}, error) {
+	if !def.List {
+		return coerceScalar(def.Type, v)
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v}
+	}
+	coerced := make([]interface{}, len(list))
+	for i, elem := range list {
+		var err error
+		if coerced[i], err = coerceScalar(def.Type, elem); err != nil {
+			return nil, err
+		}
+	}
+	return coerced, nil
+}
+
+func coerceScalar(t ArgType, v interface{}) (interface{}, error) {
+	switch t {
+	case Int:
+		switch v := v.(type) {
+		case int:
+			return v, nil
+		case float64:
+			if v == float64(int(v)) {
+				return int(v), nil
+			}
+		}
+	case Float:
+		switch v := v.(type) {
+		case int:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+	case String:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case Boolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("expected %s, got %v", t, v)
+}
+
+// structField returns the field of the struct (or pointer to struct) v
+// whose name matches name case-insensitively.
+func structField(v interface{}, name string) (interface{}, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() == reflect.Struct {
+		if f := rv.FieldByNameFunc(func(s string) bool { return strings.EqualFold(s, name) }); f.IsValid() {
+			return f.Interface(), nil
+		}
+	}
+	return nil, fmt.Errorf("no value for field %q", name)
+}
+
+// An orderedMap is a JSON object whose keys are encoded in the order in which
+// they were requested.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,179 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type testBook struct {
+	ID     int
+	Title  string
+	Author *testAuthor
+	Tags   []string
+}
+
+type testAuthor struct {
+	Name string
+}
+
+var testBooks = []*testBook{
+	{ID: 1, Title: "a", Author: &testAuthor{Name: "x"}, Tags: []string{"t"}},
+	{ID: 2, Title: "b"},
+}
+
+func testSchema() *Schema {
+	author := &Object{Name: "Author", Fields: map[string]*Field{"name": {}}}
+	book := &Object{Name: "Book", Fields: map[string]*Field{
+		"id":     {},
+		"title":  {},
+		"tags":   {},
+		"author": {Type: author},
+		"fail": {Resolve: func(p *Params) (interface{}, error) {
+			return nil, errors.New("failed")
+		}},
+	}}
+	return &Schema{
+		Query: &Object{Name: "Query", Fields: map[string]*Field{
+			"book": {Type: book, Args: map[string]*Arg{"id": {Type: Int, NonNull: true}}, Resolve: func(p *Params) (interface{}, error) {
+				for _, b := range testBooks {
+					if b.ID == p.Int("id") {
+						return b, nil
+					}
+				}
+				return nil, nil
+			}},
+			"books": {Type: book, List: true, Resolve: func(p *Params) (interface{}, error) {
+				return testBooks, nil
+			}},
+			"echo": {Args: map[string]*Arg{"s": {Type: String}, "list": {Type: Int, List: true}}, Resolve: func(p *Params) (interface{}, error) {
+				if list, ok := p.Args["list"]; ok {
+					return list, nil
+				}
+				return p.String("s"), nil
+			}},
+			"context": {Resolve: func(p *Params) (interface{}, error) {
+				return p.Context, nil
+			}},
+		}},
+		Mutation: &Object{Name: "Mutation", Fields: map[string]*Field{
+			"setTitle": {Type: book, Args: map[string]*Arg{"title": {Type: String, NonNull: true}}, Resolve: func(p *Params) (interface{}, error) {
+				return &testBook{ID: 3, Title: p.String("title")}, nil
+			}},
+		}},
+	}
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		req  Request
+		want string
+	}{
+		{
+			req:  Request{Query: `{ book(id: 1) { title id author { name } tags } }`},
+			want: `{"data":{"book":{"title":"a","id":1,"author":{"name":"x"},"tags":["t"]}}}`,
+		},
+		{
+			req:  Request{Query: `{ books { id author { name } } }`},
+			want: `{"data":{"books":[{"id":1,"author":{"name":"x"}},{"id":2,"author":null}]}}`,
+		},
+		{
+			req:  Request{Query: `{ book(id: 99) { title } }`},
+			want: `{"data":{"book":null}}`,
+		},
+		{
+			req:  Request{Query: `{ first: book(id: 1) { t: title } second: book(id: 2) { title, __typename } }`},
+			want: `{"data":{"first":{"t":"a"},"second":{"title":"b","__typename":"Book"}}}`,
+		},
+		{
+			req:  Request{Query: `query Q($id: Int!, $s: String = "d") { book(id: $id) { title } echo(s: $s) }`, Variables: map[string]interface{}{"id": 2.0}},
+			want: `{"data":{"book":{"title":"b"},"echo":"d"}}`,
+		},
+		{
+			req:  Request{Query: `{ echo(s: "\"q\"\né") }`},
+			want: `{"data":{"echo":"\"q\"\né"}}`,
+		},
+		{
+			req:  Request{Query: `{ echo(list: 1) }`},
+			want: `{"data":{"echo":[1]}}`,
+		},
+		{
+			req:  Request{Query: `{ book(id: 1) { ...F ... on Book { id } ... on Author { name } } } fragment F on Book { title }`},
+			want: `{"data":{"book":{"title":"a","id":1}}}`,
+		},
+		{
+			req:  Request{Query: `query($yes: Boolean!) { book(id: 1) { id @include(if: $yes) title @skip(if: $yes) } }`, Variables: map[string]interface{}{"yes": true}},
+			want: `{"data":{"book":{"id":1}}}`,
+		},
+		{
+			req:  Request{Query: `query A { echo(s: "a") } query B { echo(s: "b") }`, OperationName: "B"},
+			want: `{"data":{"echo":"b"}}`,
+		},
+		{
+			req:  Request{Query: `mutation { setTitle(title: "c") { id title } }`},
+			want: `{"data":{"setTitle":{"id":3,"title":"c"}}}`,
+		},
+		{
+			req:  Request{Query: `{ context }`},
+			want: `{"data":{"context":"ctx"}}`,
+		},
+
+		// Errors
+		{
+			req:  Request{Query: `{ book(id: 1) { title fail } }`},
+			want: `{"data":{"book":{"title":"a","fail":null}},"errors":[{"message":"failed","path":["book","fail"]}]}`,
+		},
+		{
+			req:  Request{Query: `{ books { nope } }`},
+			want: `{"data":{"books":[{"nope":null},{"nope":null}]},"errors":[{"message":"unknown field \"nope\" on type Book","path":["books",0,"nope"]},{"message":"unknown field \"nope\" on type Book","path":["books",1,"nope"]}]}`,
+		},
+		{
+			req:  Request{Query: `{ book { title } }`},
+			want: `{"data":{"book":null},"errors":[{"message":"missing required argument \"id\" on field \"book\"","path":["book"]}]}`,
+		},
+		{
+			req:  Request{Query: `{ book(id: "1") { title } }`},
+			want: `{"data":{"book":null},"errors":[{"message":"argument \"id\": expected Int, got 1","path":["book"]}]}`,
+		},
+		{
+			req:  Request{Query: `{ book(id: 1) }`},
+			want: `{"data":{"book":null},"errors":[{"message":"field \"book\" must have a selection of subfields","path":["book"]}]}`,
+		},
+		{
+			req:  Request{Query: `query($id: Int!) { book(id: $id) { title } }`},
+			want: `{"errors":[{"message":"variable $id of non-null type was not provided"}]}`,
+		},
+		{
+			req:  Request{Query: `{ book(id: 1) { title }`},
+			want: `{"errors":[{"message":"syntax error at line 1, column 24: unexpected end of document"}]}`,
+		},
+		{
+			req:  Request{Query: "{\n  book(id: 1) { title ! } }"},
+			want: `{"errors":[{"message":"syntax error at line 2, column 23: unexpected \"!\""}]}`,
+		},
+		{
+			req:  Request{Query: `query A { echo } query B { echo }`},
+			want: `{"errors":[{"message":"document must contain exactly one operation unless an operation name is given (found 2)"}]}`,
+		},
+	}
+	for _, test := range tests {
+		resp := Execute(testSchema(), &test.req, "ctx")
+		got, err := json.Marshal(resp)
+		if err != nil {
+			t.Errorf("%s: %s", test.req.Query, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.req.Query, got, test.want)
+		}
+	}
+}
+
+func TestExecute_noMutations(t *testing.T) {
+	schema := testSchema()
+	schema.Mutation = nil
+	resp := Execute(schema, &Request{Query: `mutation { setTitle(title: "c") { id } }`}, nil)
+	if resp.Data != nil || len(resp.Errors) != 1 {
+		t.Errorf("got %+v, want a single error", resp)
+	}
+}
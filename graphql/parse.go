@@ -0,0 +1,500 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A document is a parsed GraphQL request document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// An operation is a query or mutation in a document.
+type operation struct {
+	typ  string // "query" or "mutation"
+	name string
+	vars []*varDef
+	sel  []selection
+}
+
+// A varDef is a variable definition, such as "$id: Int! = 1".
+type varDef struct {
+	name    string
+	nonNull bool
+	def     interface{} // default value, or nil
+}
+
+// A selection is a *field, *fragmentSpread, or *inlineFragment.
+type selection interface{}
+
+type field struct {
+	alias, name string
+	args        []*argument
+	directives  []*directive
+	sel         []selection
+}
+
+// key returns the name of the field in the response.
+func (f *field) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type argument struct {
+	name  string
+	value interface{}
+}
+
+type directive struct {
+	name string
+	args []*argument
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+}
+
+type inlineFragment struct {
+	on         string // type condition, or "" if none
+	directives []*directive
+	sel        []selection
+}
+
+type fragment struct {
+	name, on string
+	sel      []selection
+}
+
+// Values in the document are represented as int, float64, string, bool,
+// nil, []interface{}, map[string]interface{}, enumValue, or variable.
+type (
+	enumValue string
+	variable  string
+)
+
+// A SyntaxError is returned when a request document can't be parsed.
+type SyntaxError struct {
+	Line, Column int
+	Msg          string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// parse parses a GraphQL request document.
+func parse(src string) (doc *document, err error) {
+	p := &parser{lexer: lexer{src: src}}
+	defer func() {
+		if e := recover(); e != nil {
+			if se, ok := e.(*SyntaxError); ok {
+				err = se
+				return
+			}
+			panic(e)
+		}
+	}()
+	p.next()
+	return p.parseDocument(), nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	pos  int
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+// errorf panics with a *SyntaxError at offset pos. It is recovered by parse.
+func (l *lexer) errorf(pos int, format string, args ...interface{}) {
+	line, col := 1, 1
+	for _, c := range l.src[:pos] {
+		if c == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	panic(&SyntaxError{Line: line, Column: col, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (l *lexer) lex() token {
+	// Skip whitespace, commas, and comments.
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.pos++
+		} else if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		} else {
+			break
+		}
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: l.pos}
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$():=@[]{}|", c) != -1:
+		l.pos++
+		return token{kind: tokPunct, val: string(c), pos: start}
+	case c == '.':
+		if strings.HasPrefix(l.src[l.pos:], "...") {
+			l.pos += 3
+			return token{kind: tokPunct, val: "...", pos: start}
+		}
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokName, val: l.src[start:l.pos], pos: start}
+	case c == '-' || isDigit(c):
+		return l.lexNumber()
+	case c == '"':
+		return l.lexString()
+	}
+	l.errorf(start, "unexpected character %q", c)
+	panic("unreachable")
+}
+
+func (l *lexer) lexNumber() token {
+	start := l.pos
+	kind := tokInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() {
+		n := l.pos
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+		if l.pos == n {
+			l.errorf(l.pos, "invalid number")
+		}
+	}
+	digits()
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokFloat
+		l.pos++
+		digits()
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		digits()
+	}
+	return token{kind: kind, val: l.src[start:l.pos], pos: start}
+}
+
+func (l *lexer) lexString() token {
+	start := l.pos
+	l.pos++ // opening quote
+	var b []byte
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+			l.errorf(start, "unterminated string")
+		}
+		c := l.src[l.pos]
+		switch c {
+		case '"':
+			l.pos++
+			return token{kind: tokString, val: string(b), pos: start}
+		case '\\':
+			if l.pos+1 >= len(l.src) {
+				l.errorf(start, "unterminated string")
+			}
+			esc := l.src[l.pos+1]
+			l.pos += 2
+			switch esc {
+			case '"', '\\', '/':
+				b = append(b, esc)
+			case 'b':
+				b = append(b, '\b')
+			case 'f':
+				b = append(b, '\f')
+			case 'n':
+				b = append(b, '\n')
+			case 'r':
+				b = append(b, '\r')
+			case 't':
+				b = append(b, '\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					l.errorf(l.pos, "invalid unicode escape")
+				}
+				r, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					l.errorf(l.pos, "invalid unicode escape")
+				}
+				var buf [utf8.UTFMax]byte
+				b = append(b, buf[:utf8.EncodeRune(buf[:], rune(r))]...)
+				l.pos += 4
+			default:
+				l.errorf(l.pos-2, "invalid escape sequence \\%c", esc)
+			}
+		default:
+			b = append(b, c)
+			l.pos++
+		}
+	}
+}
+
+func isLetter(c byte) bool { return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') }
+func isDigit(c byte) bool  { return '0' <= c && c <= '9' }
+
+type parser struct {
+	lexer
+	tok token
+}
+
+func (p *parser) next() { p.tok = p.lex() }
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokPunct && p.tok.val == punct
+}
+
+func (p *parser) skip(punct string) bool {
+	if p.peek(punct) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) {
+	if !p.skip(punct) {
+		p.unexpected()
+	}
+}
+
+func (p *parser) unexpected() {
+	if p.tok.kind == tokEOF {
+		p.errorf(p.tok.pos, "unexpected end of document")
+	}
+	p.errorf(p.tok.pos, "unexpected %q", p.tok.val)
+}
+
+func (p *parser) name() string {
+	if p.tok.kind != tokName {
+		p.unexpected()
+	}
+	name := p.tok.val
+	p.next()
+	return name
+}
+
+func (p *parser) parseDocument() *document {
+	doc := &document{fragments: map[string]*fragment{}}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peek("{"):
+			doc.operations = append(doc.operations, &operation{typ: "query", sel: p.parseSelectionSet()})
+		case p.tok.kind == tokName && (p.tok.val == "query" || p.tok.val == "mutation"):
+			doc.operations = append(doc.operations, p.parseOperation())
+		case p.tok.kind == tokName && p.tok.val == "fragment":
+			pos := p.tok.pos
+			p.next()
+			frag := &fragment{name: p.name()}
+			if p.name() != "on" {
+				p.errorf(pos, "fragment %q has no type condition", frag.name)
+			}
+			frag.on = p.name()
+			frag.sel = p.parseSelectionSet()
+			if _, dup := doc.fragments[frag.name]; dup {
+				p.errorf(pos, "duplicate fragment %q", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+		default:
+			p.unexpected()
+		}
+	}
+	return doc
+}
+
+func (p *parser) parseOperation() *operation {
+	op := &operation{typ: p.name()}
+	if p.tok.kind == tokName {
+		op.name = p.name()
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			p.expect("$")
+			v := &varDef{name: p.name()}
+			p.expect(":")
+			v.nonNull = p.parseType()
+			if p.skip("=") {
+				v.def = p.parseValue(true)
+			}
+			op.vars = append(op.vars, v)
+		}
+	}
+	if p.peek("@") {
+		p.errorf(p.tok.pos, "directives on operations are not supported")
+	}
+	op.sel = p.parseSelectionSet()
+	return op
+}
+
+// parseType parses a variable's type and returns whether it is non-null.
+// Variable values are coerced to the types of the arguments they are used
+// in, so the type itself is otherwise ignored.
+func (p *parser) parseType() (nonNull bool) {
+	if p.skip("[") {
+		p.parseType()
+		p.expect("]")
+	} else {
+		p.name()
+	}
+	return p.skip("!")
+}
+
+func (p *parser) parseSelectionSet() []selection {
+	p.expect("{")
+	var sel []selection
+	for !p.skip("}") {
+		if p.skip("...") {
+			if p.tok.kind == tokName && p.tok.val != "on" {
+				sel = append(sel, &fragmentSpread{name: p.name(), directives: p.parseDirectives()})
+				continue
+			}
+			frag := &inlineFragment{}
+			if p.tok.kind == tokName {
+				p.next() // "on"
+				frag.on = p.name()
+			}
+			frag.directives = p.parseDirectives()
+			frag.sel = p.parseSelectionSet()
+			sel = append(sel, frag)
+			continue
+		}
+
+		f := &field{name: p.name()}
+		if p.skip(":") {
+			f.alias, f.name = f.name, p.name()
+		}
+		f.args = p.parseArguments()
+		f.directives = p.parseDirectives()
+		if p.peek("{") {
+			f.sel = p.parseSelectionSet()
+		}
+		sel = append(sel, f)
+	}
+	if len(sel) == 0 {
+		p.errorf(p.tok.pos, "empty selection set")
+	}
+	return sel
+}
+
+func (p *parser) parseArguments() []*argument {
+	if !p.skip("(") {
+		return nil
+	}
+	var args []*argument
+	for !p.skip(")") {
+		arg := &argument{name: p.name()}
+		p.expect(":")
+		arg.value = p.parseValue(false)
+		args = append(args, arg)
+	}
+	return args
+}
+
+func (p *parser) parseDirectives() []*directive {
+	var dirs []*directive
+	for p.skip("@") {
+		dirs = append(dirs, &directive{name: p.name(), args: p.parseArguments()})
+	}
+	return dirs
+}
+
+// parseValue parses a value. If constant is true, variables are not
+// permitted (as in variables' default values).
+func (p *parser) parseValue(constant bool) interface{} {
+	tok := p.tok
+	switch tok.kind {
+	case tokPunct:
+		switch tok.val {
+		case "$":
+			if constant {
+				p.unexpected()
+			}
+			p.next()
+			return variable(p.name())
+		case "[":
+			p.next()
+			list := []interface{}{}
+			for !p.skip("]") {
+				list = append(list, p.parseValue(constant))
+			}
+			return list
+		case "{":
+			p.next()
+			obj := map[string]interface{}{}
+			for !p.skip("}") {
+				name := p.name()
+				p.expect(":")
+				obj[name] = p.parseValue(constant)
+			}
+			return obj
+		}
+	case tokInt:
+		p.next()
+		n, err := strconv.Atoi(tok.val)
+		if err != nil {
+			p.errorf(tok.pos, "invalid integer %s", tok.val)
+		}
+		return n
+	case tokFloat:
+		p.next()
+		f, err := strconv.ParseFloat(tok.val, 64)
+		if err != nil {
+			p.errorf(tok.pos, "invalid number %s", tok.val)
+		}
+		return f
+	case tokString:
+		p.next()
+		return tok.val
+	case tokName:
+		p.next()
+		switch tok.val {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return enumValue(tok.val)
+	}
+	p.unexpected()
+	panic("unreachable")
+}
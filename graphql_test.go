@@ -0,0 +1,55 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestGraphQLService_Do(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.GraphQL, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"query":"query($id: Int!) { post(id: $id) { title } }","variables":{"id":1}}`+"\n")
+
+		w.Write([]byte(`{"data":{"post":{"title":"t"}}}`))
+	})
+
+	var data struct{ Post struct{ Title string } }
+	err := client.GraphQL.Do("query($id: Int!) { post(id: $id) { title } }", map[string]interface{}{"id": 1}, &data)
+	if err != nil {
+		t.Errorf("GraphQL.Do returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+	if data.Post.Title != "t" {
+		t.Errorf("GraphQL.Do returned data %+v, want post title %q", data, "t")
+	}
+}
+
+func TestGraphQLService_Do_errors(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc(urlPath(t, router.GraphQL, nil), func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"post":null},"errors":[{"message":"m","path":["post"]}]}`))
+	})
+
+	var data struct{ Post *struct{ Title string } }
+	err := client.GraphQL.Do("{ post(id: 1) { title } }", nil, &data)
+	want := GraphQLErrors{{Message: "m", Path: []interface{}{"post"}}}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("GraphQL.Do returned error %v, want %v", err, want)
+	}
+	if data.Post != nil {
+		t.Errorf("GraphQL.Do returned post %+v, want nil", data.Post)
+	}
+}
@@ -0,0 +1,72 @@
+// Package health serves liveness and readiness checks, for Kubernetes
+// probes and load balancer health checks.
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// A Check checks whether one dependency of the server (such as the
+// database) is ready, returning an error if it isn't.
+type Check struct {
+	Name  string
+	Check func() error
+}
+
+// LiveHandler responds with HTTP 200 to show that the process is alive and
+// serving HTTP requests. It checks nothing else, so that a process isn't
+// restarted just because a dependency is down.
+func LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write([]byte("ok\n"))
+	})
+}
+
+// A readiness is the JSON response body of ReadyHandler.
+type readiness struct {
+	Ready  bool
+	Checks map[string]string // check name to "ok" or error message
+}
+
+// ReadyHandler runs checks (concurrently) on each request and responds with
+// HTTP 200 if they all pass, or HTTP 503 if any fails, so that traffic is
+// only routed to the server when it can serve it. The response body lists
+// the result of each check.
+func ReadyHandler(checks ...Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := readiness{Ready: true, Checks: make(map[string]string, len(checks))}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, c := range checks {
+			wg.Add(1)
+			go func(c Check) {
+				defer wg.Done()
+				result := "ok"
+				if err := c.Check(); err != nil {
+					result = err.Error()
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				resp.Checks[c.Name] = result
+				if result != "ok" {
+					resp.Ready = false
+				}
+			}(c)
+		}
+		wg.Wait()
+
+		status := http.StatusOK
+		if !resp.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		data, _ := json.MarshalIndent(resp, "", "  ")
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(status)
+		w.Write(data)
+	})
+}
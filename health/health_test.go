@@ -0,0 +1,47 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestLiveHandler(t *testing.T) {
+	rw := httptest.NewRecorder()
+	LiveHandler().ServeHTTP(rw, httptest.NewRequest("GET", "/healthz", nil))
+	if rw.Code != http.StatusOK {
+		t.Errorf("got HTTP %d, want %d", rw.Code, http.StatusOK)
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	ok := Check{Name: "a", Check: func() error { return nil }}
+	failing := Check{Name: "b", Check: func() error { return errors.New("down") }}
+
+	tests := []struct {
+		checks     []Check
+		wantStatus int
+		want       readiness
+	}{
+		{nil, http.StatusOK, readiness{Ready: true, Checks: map[string]string{}}},
+		{[]Check{ok}, http.StatusOK, readiness{Ready: true, Checks: map[string]string{"a": "ok"}}},
+		{[]Check{ok, failing}, http.StatusServiceUnavailable, readiness{Ready: false, Checks: map[string]string{"a": "ok", "b": "down"}}},
+	}
+	for _, test := range tests {
+		rw := httptest.NewRecorder()
+		ReadyHandler(test.checks...).ServeHTTP(rw, httptest.NewRequest("GET", "/readyz", nil))
+		if rw.Code != test.wantStatus {
+			t.Errorf("%v: got HTTP %d, want %d", test.want.Checks, rw.Code, test.wantStatus)
+		}
+		var got readiness
+		if err := json.Unmarshal(rw.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("got %+v, want %+v", got, test.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package httpstatus records the status codes of HTTP responses, for the
+// middleware that logs, measures, and traces requests.
+package httpstatus
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// A Recorder records the status code written to an http.ResponseWriter. It
+// supports hijacking and flushing if the underlying ResponseWriter does.
+type Recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// NewRecorder returns a Recorder that wraps w. If w is already a Recorder
+// (from an outer middleware), NewRecorder returns it instead of wrapping it
+// again.
+func NewRecorder(w http.ResponseWriter) *Recorder {
+	if rw, ok := w.(*Recorder); ok {
+		return rw
+	}
+	return &Recorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Status returns the status code of the response (http.StatusOK if none was
+// written).
+func (w *Recorder) Status() int { return w.status }
+
+func (w *Recorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records an implicit http.StatusOK if no status was written yet, as
+// the underlying ResponseWriter does.
+func (w *Recorder) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.status = http.StatusOK
+		w.wroteHeader = true
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Hijack lets handlers behind the recorder take over the connection (for
+// example, to upgrade it to a WebSocket).
+func (w *Recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("httpstatus: underlying ResponseWriter does not support hijacking")
+	}
+	if !w.wroteHeader {
+		w.status = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+	}
+	return h.Hijack()
+}
+
+// Flush lets handlers behind the recorder stream responses.
+func (w *Recorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
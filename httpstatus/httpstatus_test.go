@@ -0,0 +1,75 @@
+package httpstatus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	rw := NewRecorder(httptest.NewRecorder())
+	if got := rw.Status(); got != http.StatusOK {
+		t.Errorf("got status %d before writing, want %d", got, http.StatusOK)
+	}
+	rw.WriteHeader(http.StatusNotFound)
+	rw.WriteHeader(http.StatusInternalServerError)
+	if got := rw.Status(); got != http.StatusNotFound {
+		t.Errorf("got status %d, want the first status written (%d)", got, http.StatusNotFound)
+	}
+}
+
+func TestRecorder_Write(t *testing.T) {
+	rw := NewRecorder(httptest.NewRecorder())
+	rw.Write([]byte("x"))
+	rw.WriteHeader(http.StatusNotFound)
+	if got := rw.Status(); got != http.StatusOK {
+		t.Errorf("got status %d, want the implicit status of the write (%d)", got, http.StatusOK)
+	}
+}
+
+func TestNewRecorder_shared(t *testing.T) {
+	rw := NewRecorder(httptest.NewRecorder())
+	if NewRecorder(rw) != rw {
+		t.Error("NewRecorder wrapped a Recorder again, want it returned as is")
+	}
+}
+
+func TestRecorder_Flush(t *testing.T) {
+	w := httptest.NewRecorder()
+	rw := NewRecorder(w)
+	rw.Write([]byte("x"))
+	rw.Flush()
+	if !w.Flushed {
+		t.Error("underlying ResponseWriter wasn't flushed")
+	}
+}
+
+func TestRecorder_Hijack(t *testing.T) {
+	// httptest.ResponseRecorder doesn't support hijacking.
+	rw := NewRecorder(httptest.NewRecorder())
+	if _, _, err := rw.Hijack(); err == nil {
+		t.Error("got no error hijacking an unhijackable ResponseWriter")
+	}
+
+	var status int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := NewRecorder(w)
+		conn, _, err := rw.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		status = rw.Status()
+		conn.Write([]byte("HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n"))
+		conn.Close()
+	}))
+	defer s.Close()
+	resp, err := http.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if status != http.StatusSwitchingProtocols {
+		t.Errorf("got status %d after hijacking, want %d", status, http.StatusSwitchingProtocols)
+	}
+}
@@ -0,0 +1,220 @@
+package importer
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
+)
+
+// A Source is a site that a Crawler imports from, and how often.
+type Source struct {
+	Fetcher
+
+	// Interval is how long to wait between imports from the site.
+	Interval time.Duration
+}
+
+// A Crawler imports posts from sites continuously, each on its own
+// schedule. Unlike repeatedly calling Import, a crawler can persist what it
+// has seen (see StateFile), so that it doesn't resubmit posts or refetch
+// sites early when it is restarted.
+type Crawler struct {
+	Sources []*Source
+
+	// Jitter is the fraction by which each wait between imports is randomly
+	// lengthened or shortened (e.g., 0.1 for up to ±10%), so that sources
+	// with the same interval aren't fetched in lockstep.
+	Jitter float64
+
+	// StateFile (if set) is the JSON file that the crawler's state (the time
+	// that each site was last crawled, and the link URLs of the posts that
+	// were last seen there) is loaded from and saved to.
+	StateFile string
+
+	mu    sync.Mutex
+	state crawlState
+}
+
+// crawlState is the state of a Crawler that is saved to its StateFile.
+type crawlState struct {
+	Sites map[string]*siteState
+}
+
+type siteState struct {
+	// LastCrawled is when the site was last crawled (successfully or not).
+	LastCrawled time.Time
+
+	// Seen holds the link URLs of the most recently seen posts from the site
+	// (at most maxSeenPerSite), which are skipped in later imports.
+	Seen []string
+}
+
+// maxSeenPerSite is the maximum number of link URLs that are remembered
+// per site.
+const maxSeenPerSite = 1000
+
+var crawlDuration = metrics.NewHistogramVec("thesrc_crawler_crawl_duration_seconds",
+	"Time taken to fetch and import posts from each site, by site.",
+	metrics.DefaultBuckets, "site")
+
+// Run crawls the sources until stop is closed. It returns an error only if
+// the crawler's state file can't be loaded; errors crawling individual
+// sites are logged.
+func (c *Crawler) Run(stop <-chan struct{}) error {
+	if err := c.load(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, src := range c.Sources {
+		wg.Add(1)
+		go func(src *Source) {
+			defer wg.Done()
+			c.runSource(src, stop)
+		}(src)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (c *Crawler) runSource(src *Source, stop <-chan struct{}) {
+	// Resume the schedule from the last crawl (if any), so that restarting
+	// the crawler doesn't cause every site to be fetched at once.
+	var wait time.Duration
+	c.mu.Lock()
+	if st := c.state.Sites[src.Site()]; st != nil {
+		wait = src.Interval - time.Since(st.LastCrawled)
+	}
+	c.mu.Unlock()
+
+	for {
+		if wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-stop:
+				return
+			}
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		c.crawl(src)
+		wait = c.jitter(src.Interval)
+	}
+}
+
+// crawl imports posts from src once and saves the crawler's state.
+func (c *Crawler) crawl(src *Source) {
+	site := src.Site()
+	start := time.Now()
+	posts, err := fetchAndImport(src)
+	crawlDuration.ObserveSince(start, site)
+	if err != nil {
+		log.Printf("Error crawling %s: %s.", site, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.state.Sites[site]
+	if st == nil {
+		st = &siteState{}
+		c.state.Sites[site] = st
+	}
+	st.LastCrawled = start
+	for _, post := range posts {
+		if isSeen(post.LinkURL) {
+			st.Seen = appendSeen(st.Seen, post.LinkURL)
+		}
+	}
+	if err := c.save(); err != nil {
+		log.Printf("Error saving crawler state: %s.", err)
+	}
+}
+
+// appendSeen appends linkURL to seen (moving it to the end if it's already
+// there), dropping the oldest link URLs if there are more than
+// maxSeenPerSite.
+func appendSeen(seen []string, linkURL string) []string {
+	for i, u := range seen {
+		if u == linkURL {
+			seen = append(seen[:i], seen[i+1:]...)
+			break
+		}
+	}
+	seen = append(seen, linkURL)
+	if len(seen) > maxSeenPerSite {
+		seen = seen[len(seen)-maxSeenPerSite:]
+	}
+	return seen
+}
+
+// jitter returns d, randomly lengthened or shortened by up to c.Jitter.
+func (c *Crawler) jitter(d time.Duration) time.Duration {
+	if c.Jitter <= 0 {
+		return d
+	}
+	return d + time.Duration((rand.Float64()*2-1)*c.Jitter*float64(d))
+}
+
+// load reads the crawler's state from c.StateFile (if it exists), and marks
+// the link URLs in it as seen.
+func (c *Crawler) load() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state = crawlState{Sites: map[string]*siteState{}}
+	if c.StateFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.StateFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &c.state); err != nil {
+		return err
+	}
+	if c.state.Sites == nil {
+		c.state.Sites = map[string]*siteState{}
+	}
+	for _, st := range c.state.Sites {
+		markSeen(st.Seen)
+	}
+	return nil
+}
+
+// save writes the crawler's state to c.StateFile (if set). The caller must
+// hold c.mu.
+func (c *Crawler) save() error {
+	if c.StateFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(c.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it, so that the state file is
+	// never partially written.
+	tmp, err := os.CreateTemp(filepath.Dir(c.StateFile), ".tmp-"+filepath.Base(c.StateFile))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.StateFile)
+}
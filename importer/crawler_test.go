@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// notifyingFetcher is a mockFetcher that sends on fetched each time it
+// fetches.
+type notifyingFetcher struct {
+	mockFetcher
+	fetched chan struct{}
+}
+
+func (f *notifyingFetcher) Fetch() ([]*thesrc.Post, error) {
+	defer func() { f.fetched <- struct{}{} }()
+	return f.mockFetcher.Fetch()
+}
+
+func TestCrawler(t *testing.T) {
+	var submitted int
+	Posts = &thesrc.MockPostsService{
+		CreateBatch_: func(posts []*thesrc.Post) ([]*thesrc.PostBatchResult, error) {
+			results := make([]*thesrc.PostBatchResult, len(posts))
+			for i, post := range posts {
+				submitted++
+				results[i] = &thesrc.PostBatchResult{Post: post, Created: true}
+			}
+			return results, nil
+		},
+	}
+	Imported = nil
+	resetSeen := func() {
+		seenLinkURLs.Lock()
+		seenLinkURLs.m = map[string]bool{}
+		seenLinkURLs.Unlock()
+	}
+	resetSeen()
+
+	stateFile := filepath.Join(t.TempDir(), "crawl.json")
+	f := &notifyingFetcher{
+		mockFetcher: mockFetcher{posts: []*thesrc.Post{{Title: "a", LinkURL: "http://example.com/crawl"}}},
+		fetched:     make(chan struct{}, 1),
+	}
+
+	// The first run crawls immediately.
+	c := &Crawler{Sources: []*Source{{Fetcher: f, Interval: time.Hour}}, StateFile: stateFile}
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- c.Run(stop) }()
+	select {
+	case <-f.fetched:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for fetch")
+	}
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if want := 1; submitted != want {
+		t.Errorf("got %d submitted posts, want %d", submitted, want)
+	}
+
+	// A restarted crawler resumes the schedule and remembers what it has
+	// seen.
+	resetSeen()
+	c = &Crawler{Sources: []*Source{{Fetcher: f, Interval: time.Hour}}, StateFile: stateFile}
+	stop = make(chan struct{})
+	go func() { done <- c.Run(stop) }()
+	select {
+	case <-f.fetched:
+		t.Error("restarted crawler fetched before its interval elapsed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !isSeen("http://example.com/crawl") {
+		t.Error("link URL from state file is not marked as seen")
+	}
+}
+
+func TestAppendSeen(t *testing.T) {
+	var seen []string
+	for i := 0; i < maxSeenPerSite+1; i++ {
+		seen = appendSeen(seen, strconv.Itoa(i))
+	}
+	if len(seen) != maxSeenPerSite {
+		t.Errorf("got %d seen link URLs, want %d", len(seen), maxSeenPerSite)
+	}
+
+	seen = appendSeen([]string{"a", "b"}, "a")
+	if len(seen) != 2 || seen[0] != "b" || seen[1] != "a" {
+		t.Errorf("got %v, want [b a]", seen)
+	}
+}
@@ -4,50 +4,102 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"sourcegraph.com/sourcegraph/thesrc"
 )
 
 func init() {
-	Fetchers = append(Fetchers, &hackerNews{"top"}, &hackerNews{"newest"}, &hackerNews{"best"})
+	Fetchers = append(Fetchers, &hackerNews{"top"}, &hackerNews{"new"}, &hackerNews{"best"})
 }
 
+// hackerNewsAPI is the base URL of the Hacker News Firebase API.
+var hackerNewsAPI = "https://hacker-news.firebaseio.com/v0/"
+
+const (
+	// hackerNewsLimit is the maximum number of stories to fetch from each
+	// Hacker News list.
+	hackerNewsLimit = 60
+
+	// hackerNewsConcurrency is the number of stories to fetch concurrently.
+	hackerNewsConcurrency = 8
+)
+
 type hackerNews struct {
-	which string
+	which string // "top", "new", or "best"
+}
+
+type hackerNewsItem struct {
+	ID      int
+	Type    string
+	Title   string
+	URL     string
+	Score   int
+	Time    int64
+	Dead    bool
+	Deleted bool
 }
 
 func (f *hackerNews) Fetch() ([]*thesrc.Post, error) {
-	resp, err := http.Get("http://hnify.herokuapp.com/get/" + f.which)
-	if err != nil {
+	var ids []int
+	if err := getJSON(hackerNewsAPI+f.which+"stories.json", &ids); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("non-200 HTTP response status: %d", resp.StatusCode)
+	if len(ids) > hackerNewsLimit {
+		ids = ids[:hackerNewsLimit]
 	}
 
-	var results *struct {
-		Stories []*struct {
-			Title  string
-			Link   string
-			Points int
-		}
+	items := make([]*hackerNewsItem, len(ids))
+	errs := make([]error, len(ids))
+	sem := make(chan struct{}, hackerNewsConcurrency)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i, id int) {
+			defer func() { <-sem; wg.Done() }()
+			errs[i] = getJSON(fmt.Sprintf("%sitem/%d.json", hackerNewsAPI, id), &items[i])
+		}(i, id)
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-		return nil, err
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	posts := make([]*thesrc.Post, len(results.Stories))
-	for i, s := range results.Stories {
-		posts[i] = &thesrc.Post{
-			Title:   s.Title,
-			LinkURL: s.Link,
-			Score:   s.Points,
+	seen := map[string]bool{}
+	var posts []*thesrc.Post
+	for _, item := range items {
+		// Skip text-only stories (Ask HN, etc.), jobs, and removed items.
+		if item == nil || item.Type != "story" || item.URL == "" || item.Dead || item.Deleted || seen[item.URL] {
+			continue
 		}
+		seen[item.URL] = true
+		posts = append(posts, &thesrc.Post{
+			Title:       item.Title,
+			LinkURL:     item.URL,
+			Score:       item.Score,
+			SubmittedAt: time.Unix(item.Time, 0),
+		})
 	}
 
 	return posts, nil
 }
 
-func (f *hackerNews) Site() string { return "hn/" + f.which }
+func (f *hackerNews) Site() string { return "hackernews/" + f.which }
+
+func getJSON(urlStr string, v interface{}) error {
+	resp, err := http.Get(urlStr)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-200 HTTP response status: %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
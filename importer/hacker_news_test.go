@@ -0,0 +1,46 @@
+package importer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestHackerNews_Fetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/topstories.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[1, 2, 3, 4]`)
+	})
+	mux.HandleFunc("/item/1.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 1, "type": "story", "title": "t1", "url": "http://example.com/1", "score": 10, "time": 1400000000}`)
+	})
+	mux.HandleFunc("/item/2.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 2, "type": "story", "title": "Ask HN: t2", "text": "b", "score": 5, "time": 1400000000}`)
+	})
+	mux.HandleFunc("/item/3.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 3, "type": "story", "title": "t3", "url": "http://example.com/1", "score": 1, "time": 1400000000}`)
+	})
+	mux.HandleFunc("/item/4.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 4, "type": "job", "title": "t4", "url": "http://example.com/4", "time": 1400000000}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	defer func(orig string) { hackerNewsAPI = orig }(hackerNewsAPI)
+	hackerNewsAPI = server.URL + "/"
+
+	posts, err := (&hackerNews{"top"}).Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*thesrc.Post{{Title: "t1", LinkURL: "http://example.com/1", Score: 10, SubmittedAt: time.Unix(1400000000, 0)}}
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("got posts %+v, want %+v", posts, want)
+	}
+}
@@ -1,6 +1,13 @@
 package importer
 
-import "sourcegraph.com/sourcegraph/thesrc"
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
+)
 
 var Fetchers = []Fetcher{}
 
@@ -13,26 +20,129 @@ type Fetcher interface {
 	Site() string
 }
 
-var Store = thesrc.NewClient(nil)
+// Posts is the service that imported posts are submitted to. By default, it
+// is the API client's, but it may be any implementation of
+// thesrc.PostsService, such as a datastore's (to import posts in-process,
+// without an API server).
+var Posts thesrc.PostsService = thesrc.NewClient(nil).Posts
+
+var (
+	imports = metrics.NewCounterVec("thesrc_importer_imports_total",
+		"Number of imports (fetches and submissions of posts) from each site, by result (success or failure).",
+		"site", "result")
+	importedPosts = metrics.NewCounterVec("thesrc_importer_posts_total",
+		"Number of posts imported from each site, by whether they were newly created.",
+		"site", "created")
+)
+
+// seenLinkURLs holds the link URLs of posts that Import has already
+// submitted, so that repeated imports (e.g., when polling) skip them.
+var seenLinkURLs = struct {
+	sync.Mutex
+	m map[string]bool
+}{m: map[string]bool{}}
 
-// Import posts fetched by f. If Imported is non-nil, it is called each time a
-// post is successfully imported.
+// Import posts fetched by f. Posts whose LinkURL was already imported by a
+// previous call, and posts that Filter (if set) drops, are skipped. If
+// Imported is non-nil, it is called each time a post is successfully
+// imported.
 func Import(f Fetcher) error {
-	posts, err := f.Fetch()
+	_, err := fetchAndImport(f)
+	return err
+}
+
+// fetchAndImport is like Import, but it also returns the posts that f
+// fetched (whether or not they were imported).
+func fetchAndImport(f Fetcher) (posts []*thesrc.Post, err error) {
+	defer func() {
+		result := "success"
+		if err != nil {
+			result = "failure"
+		}
+		imports.Inc(f.Site(), result)
+	}()
+
+	posts, err = f.Fetch()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	var unseen []*thesrc.Post
 	for _, post := range posts {
-		created, err := Store.Posts.Submit(post)
+		seenLinkURLs.Lock()
+		seen := seenLinkURLs.m[post.LinkURL]
+		seenLinkURLs.m[post.LinkURL] = true
+		seenLinkURLs.Unlock()
+		// Posts that the relevance filter drops are still marked as seen,
+		// so that they aren't scored (and logged) again.
+		if !seen && (Filter == nil || Filter.Keep(f.Site(), post)) {
+			unseen = append(unseen, post)
+		}
+	}
+
+	// Submit posts in batches, and forget the link URLs of posts that failed
+	// to be submitted so that the next import retries them.
+	unsee := func(posts ...*thesrc.Post) {
+		seenLinkURLs.Lock()
+		defer seenLinkURLs.Unlock()
+		for _, post := range posts {
+			delete(seenLinkURLs.m, post.LinkURL)
+		}
+	}
+	for len(unseen) > 0 {
+		batch := unseen
+		if len(batch) > thesrc.MaxBatchSize {
+			batch = batch[:thesrc.MaxBatchSize]
+		}
+
+		results, err := Posts.CreateBatch(batch)
 		if err != nil {
-			return err
+			unsee(unseen...)
+			return posts, err
 		}
-		if Imported != nil {
-			Imported(f.Site(), post, created)
+		unseen = unseen[len(batch):]
+
+		for i, res := range results {
+			post := batch[i]
+			if res.Error != "" {
+				unsee(post)
+				if err == nil {
+					err = fmt.Errorf("importing post with URL %q from %s: %s", post.LinkURL, f.Site(), res.Error)
+				}
+				continue
+			}
+			if res.Post != nil {
+				post = res.Post
+			}
+			importedPosts.Inc(f.Site(), strconv.FormatBool(res.Created))
+			if Imported != nil {
+				Imported(f.Site(), post, res.Created)
+			}
+		}
+		if err != nil {
+			unsee(unseen...)
+			return posts, err
 		}
 	}
-	return nil
+	return posts, nil
+}
+
+// markSeen records linkURLs as already imported, so that Import skips posts
+// with them.
+func markSeen(linkURLs []string) {
+	seenLinkURLs.Lock()
+	defer seenLinkURLs.Unlock()
+	for _, u := range linkURLs {
+		seenLinkURLs.m[u] = true
+	}
+}
+
+// isSeen returns whether a post with linkURL was imported (or marked as seen
+// by markSeen).
+func isSeen(linkURL string) bool {
+	seenLinkURLs.Lock()
+	defer seenLinkURLs.Unlock()
+	return seenLinkURLs.m[linkURL]
 }
 
 // Imported (if non-nil) is called each time a post is successfully imported.
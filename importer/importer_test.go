@@ -1,6 +1,7 @@
 package importer
 
 import (
+	"reflect"
 	"testing"
 
 	"sourcegraph.com/sourcegraph/thesrc"
@@ -15,18 +16,19 @@ func (f *mockFetcher) Fetch() ([]*thesrc.Post, error) { return f.posts, f.err }
 func (f *mockFetcher) Site() string                   { return "mock" }
 
 func TestImport(t *testing.T) {
-	want := &thesrc.Post{Title: "t"}
+	want := &thesrc.Post{Title: "t", LinkURL: "http://example.com/import"}
 
 	var submitCalled bool
-	Store = &thesrc.Client{
-		Posts: &thesrc.MockPostsService{
-			Submit_: func(post *thesrc.Post) (bool, error) {
-				if post.Title != want.Title {
-					t.Errorf("got title %q, want %q", post.Title, want.Title)
-				}
-				submitCalled = true
-				return true, nil
-			},
+	Posts = &thesrc.MockPostsService{
+		CreateBatch_: func(posts []*thesrc.Post) ([]*thesrc.PostBatchResult, error) {
+			if len(posts) != 1 {
+				t.Fatalf("got %d posts, want 1", len(posts))
+			}
+			if posts[0].Title != want.Title {
+				t.Errorf("got title %q, want %q", posts[0].Title, want.Title)
+			}
+			submitCalled = true
+			return []*thesrc.PostBatchResult{{Post: posts[0], Created: true}}, nil
 		},
 	}
 
@@ -48,3 +50,76 @@ func TestImport(t *testing.T) {
 		t.Errorf("got imported == %d, want %d", imported, want)
 	}
 }
+
+func TestImport_dedup(t *testing.T) {
+	var submitted int
+	Posts = &thesrc.MockPostsService{
+		CreateBatch_: func(posts []*thesrc.Post) ([]*thesrc.PostBatchResult, error) {
+			results := make([]*thesrc.PostBatchResult, len(posts))
+			for i, post := range posts {
+				submitted++
+				results[i] = &thesrc.PostBatchResult{Post: post, Created: true}
+			}
+			return results, nil
+		},
+	}
+	Imported = nil
+
+	f := &mockFetcher{posts: []*thesrc.Post{
+		{Title: "a", LinkURL: "http://example.com/dedup"},
+		{Title: "b", LinkURL: "http://example.com/dedup"},
+	}}
+	for i := 0; i < 2; i++ {
+		if err := Import(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := 1; submitted != want {
+		t.Errorf("got %d submitted posts, want %d", submitted, want)
+	}
+}
+
+func TestImport_batchError(t *testing.T) {
+	var submitted []string
+	fail := true
+	Posts = &thesrc.MockPostsService{
+		CreateBatch_: func(posts []*thesrc.Post) ([]*thesrc.PostBatchResult, error) {
+			results := make([]*thesrc.PostBatchResult, len(posts))
+			for i, post := range posts {
+				submitted = append(submitted, post.LinkURL)
+				if fail && post.Title == "bad" {
+					results[i] = &thesrc.PostBatchResult{Error: "invalid"}
+					continue
+				}
+				results[i] = &thesrc.PostBatchResult{Post: post, Created: true}
+			}
+			return results, nil
+		},
+	}
+	var imported int
+	Imported = func(site string, post *thesrc.Post, created bool) {
+		imported++
+	}
+
+	f := &mockFetcher{posts: []*thesrc.Post{
+		{Title: "good", LinkURL: "http://example.com/batch-good"},
+		{Title: "bad", LinkURL: "http://example.com/batch-bad"},
+	}}
+	if err := Import(f); err == nil {
+		t.Fatal("err == nil")
+	}
+	if want := 1; imported != want {
+		t.Errorf("got imported == %d, want %d", imported, want)
+	}
+
+	// The failed post should be retried on the next import.
+	fail = false
+	submitted = nil
+	if err := Import(f); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"http://example.com/batch-bad"}; !reflect.DeepEqual(submitted, want) {
+		t.Errorf("got submitted %v, want %v", submitted, want)
+	}
+}
@@ -4,17 +4,53 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"sourcegraph.com/sourcegraph/thesrc"
 )
 
+// DefaultSubreddits are the subreddits that are imported from by default.
+var DefaultSubreddits = []string{"programming", "golang", "postgresql"}
+
 func init() {
-	Fetchers = append(Fetchers,
-		&subreddit{"programming"}, &subreddit{"golang"}, &subreddit{"postgresql"},
-		// &subreddit{"ruby"}, &subreddit{"node"},
-		// &subreddit{"python"}, , &subreddit{"django"},
-		// &subreddit{"rust"},
-	)
+	for _, name := range DefaultSubreddits {
+		Fetchers = append(Fetchers, Subreddit(name))
+	}
+}
+
+// Subreddit returns a Fetcher that fetches the hot, new, and top posts in the
+// named subreddit (e.g., "golang" for /r/golang).
+func Subreddit(name string) Fetcher { return &subreddit{name} }
+
+// RedditDomains, if non-empty, is the allowlist of link domains to import from
+// Reddit. A domain also matches its subdomains, so "github.com" matches
+// "gist.github.com". If empty, links to all domains are imported.
+var RedditDomains []string
+
+// redditURL is the base URL of Reddit's JSON API.
+var redditURL = "https://www.reddit.com/"
+
+// redditRequestInterval is the minimum time between requests to Reddit, per
+// Reddit's API rules for unauthenticated clients.
+var redditRequestInterval = 2 * time.Second
+
+// redditLimiter spaces out requests to Reddit (across all subreddit fetchers)
+// by at least redditRequestInterval.
+var redditLimiter struct {
+	sync.Mutex
+	last time.Time
+}
+
+func waitForReddit() {
+	redditLimiter.Lock()
+	defer redditLimiter.Unlock()
+	if d := redditRequestInterval - time.Since(redditLimiter.last); d > 0 {
+		time.Sleep(d)
+	}
+	redditLimiter.last = time.Now()
 }
 
 type subreddit struct {
@@ -23,13 +59,8 @@ type subreddit struct {
 
 func (f *subreddit) Fetch() ([]*thesrc.Post, error) {
 	postsMap := map[string]*thesrc.Post{}
-	patterns := []string{
-		"http://www.reddit.com/r/%s/hot.json",
-		"http://www.reddit.com/r/%s/new.json",
-		"http://www.reddit.com/r/%s/top.json",
-	}
-	for _, pat := range patterns {
-		posts2, err := f.fetchOne(fmt.Sprintf(pat, f.name))
+	for _, list := range []string{"hot", "new", "top"} {
+		posts2, err := f.fetchOne(fmt.Sprintf("%sr/%s/%s.json", redditURL, f.name, list))
 		if err != nil {
 			return nil, err
 		}
@@ -46,7 +77,15 @@ func (f *subreddit) Fetch() ([]*thesrc.Post, error) {
 }
 
 func (f *subreddit) fetchOne(urlStr string) ([]*thesrc.Post, error) {
-	resp, err := http.Get(urlStr)
+	req, err := http.NewRequest("GET", urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Reddit throttles requests with generic User-Agents.
+	req.Header.Set("User-Agent", "thesrc-importer/0.1")
+
+	waitForReddit()
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -60,9 +99,10 @@ func (f *subreddit) fetchOne(urlStr string) ([]*thesrc.Post, error) {
 		Data struct {
 			Children []*struct {
 				Data struct {
-					Title string
-					URL   string
-					Score int
+					Title  string
+					URL    string
+					Score  int
+					IsSelf bool `json:"is_self"`
 				}
 			}
 		}
@@ -71,16 +111,40 @@ func (f *subreddit) fetchOne(urlStr string) ([]*thesrc.Post, error) {
 		return nil, err
 	}
 
-	posts := make([]*thesrc.Post, len(results.Data.Children))
-	for i, s := range results.Data.Children {
-		posts[i] = &thesrc.Post{
+	var posts []*thesrc.Post
+	for _, s := range results.Data.Children {
+		// Self posts link back to Reddit, so skip them.
+		if s.Data.IsSelf || !redditDomainAllowed(s.Data.URL) {
+			continue
+		}
+		posts = append(posts, &thesrc.Post{
 			Title:   s.Data.Title,
 			LinkURL: s.Data.URL,
 			Score:   s.Data.Score,
-		}
+		})
 	}
 
 	return posts, nil
 }
 
-func (f *subreddit) Site() string { return "/r/" + f.name }
+// redditDomainAllowed returns whether linkURL's domain is in RedditDomains (or
+// RedditDomains is empty).
+func redditDomainAllowed(linkURL string) bool {
+	if len(RedditDomains) == 0 {
+		return true
+	}
+	u, err := url.Parse(linkURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	for _, d := range RedditDomains {
+		d = strings.ToLower(d)
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *subreddit) Site() string { return "reddit/" + f.name }
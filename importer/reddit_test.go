@@ -0,0 +1,44 @@
+package importer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestSubreddit_Fetch(t *testing.T) {
+	mux := http.NewServeMux()
+	for _, list := range []string{"hot", "new", "top"} {
+		mux.HandleFunc("/r/golang/"+list+".json", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data": {"children": [
+				{"data": {"title": "t1", "url": "http://gist.github.com/1", "score": 10}},
+				{"data": {"title": "t2", "url": "http://example.com/2", "score": 5}},
+				{"data": {"title": "t3", "url": "https://www.reddit.com/r/golang/3", "score": 1, "is_self": true}}
+			]}}`)
+		})
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	defer func(orig string) { redditURL = orig }(redditURL)
+	redditURL = server.URL + "/"
+	defer func(orig time.Duration) { redditRequestInterval = orig }(redditRequestInterval)
+	redditRequestInterval = 0
+	RedditDomains = []string{"github.com"}
+	defer func() { RedditDomains = nil }()
+
+	posts, err := Subreddit("golang").Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*thesrc.Post{{Title: "t1", LinkURL: "http://gist.github.com/1", Score: 10}}
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("got posts %+v, want %+v", posts, want)
+	}
+}
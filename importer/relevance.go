@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/classifier"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
+)
+
+// DefaultMinRelevance is the default value of RelevanceFilter.MinRelevance.
+// Posts with no evidence either way score exactly this, so only posts that
+// look off-topic are dropped.
+const DefaultMinRelevance = 0.5
+
+// A RelevanceFilter drops imported posts that aren't about code (such as
+// politics or general news), before they are submitted.
+type RelevanceFilter struct {
+	// MinRelevance is the relevance score (from 0 to 1; see Score) below
+	// which posts are dropped. See DefaultMinRelevance.
+	MinRelevance float64
+
+	// AllowDomains and DenyDomains are link domains (including their
+	// subdomains) whose posts are always kept or dropped, respectively,
+	// regardless of their scores. AllowDomains takes precedence.
+	AllowDomains []string
+	DenyDomains  []string
+
+	// RejectLog (if set) is written a line of JSON for each dropped post
+	// (with its site, title, link URL, score, and the reason for the score),
+	// for tuning the filter.
+	RejectLog io.Writer
+
+	logMu sync.Mutex
+}
+
+// Filter (if set) is the relevance filter that imported posts are run
+// through before they are submitted.
+var Filter *RelevanceFilter
+
+var rejectedPosts = metrics.NewCounterVec("thesrc_importer_rejected_posts_total",
+	"Number of posts fetched from each site that the relevance filter dropped.",
+	"site")
+
+// codeTopic and offTopic hold the title keywords and link domains that are
+// evidence that a post is, or isn't, about code. (Posts about any of
+// classifier.DefaultTopics are also considered to be about code.)
+var (
+	codeTopic = &classifier.Topic{
+		Keywords: []string{
+			"code", "coding", "programming", "programmer", "programmers", "developer", "developers",
+			"software", "api", "apis", "compiler", "compilers", "library", "framework", "algorithm",
+			"algorithms", "open source", "github", "git", "linux", "kernel", "debugging", "bug", "bugs",
+			"refactoring", "unix", "javascript", "python", "java", "c++", "haskell", "erlang", "ruby",
+			"typescript", "webassembly", "http", "tcp", "json", "docker", "kubernetes",
+		},
+		Domains: []string{
+			"github.com", "gitlab.com", "bitbucket.org", "stackoverflow.com", "sourcegraph.com",
+			"lwn.net", "pkg.go.dev", "npmjs.com", "pypi.org",
+		},
+	}
+	offTopic = &classifier.Topic{
+		Keywords: []string{
+			"election", "elections", "president", "senate", "congress", "parliament", "politics",
+			"political", "democrats", "republicans", "war", "celebrity", "football", "soccer",
+			"baseball", "nfl", "nba", "stock market", "recipe", "horoscope",
+		},
+	}
+)
+
+// Score returns how likely post is to be about code, from 0 to 1, and the
+// reason for the score. Posts with no evidence either way score 0.5.
+func (f *RelevanceFilter) Score(post *thesrc.Post) (score float64, reason string) {
+	score = 0.5
+	var reasons []string
+	if topics := (&classifier.TopicClassifier{}).Classify(post); len(topics) > 0 {
+		score += 0.25
+		reasons = append(reasons, "about "+strings.Join(topics, ", "))
+	}
+	if codeTopic.Matches(post) {
+		score += 0.25
+		reasons = append(reasons, "code keywords or domain")
+	}
+	if offTopic.Matches(post) {
+		score -= 0.25
+		reasons = append(reasons, "off-topic keywords")
+	}
+	if score > 1 {
+		score = 1
+	} else if score < 0 {
+		score = 0
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, "no evidence")
+	}
+	return score, strings.Join(reasons, "; ")
+}
+
+// Keep returns whether post, fetched from site, should be imported. If
+// not, it is logged to f.RejectLog.
+func (f *RelevanceFilter) Keep(site string, post *thesrc.Post) bool {
+	domain := thesrc.LinkDomain(post.LinkURL)
+	if domainIn(domain, f.AllowDomains) {
+		return true
+	}
+
+	var score float64
+	var reason string
+	if domainIn(domain, f.DenyDomains) {
+		reason = "denied domain " + domain
+	} else {
+		score, reason = f.Score(post)
+		if score >= f.MinRelevance {
+			return true
+		}
+	}
+
+	rejectedPosts.Inc(site)
+	if f.RejectLog != nil {
+		line, err := json.Marshal(struct {
+			Time    time.Time
+			Site    string
+			Title   string
+			LinkURL string
+			Score   float64
+			Reason  string
+		}{time.Now(), site, post.Title, post.LinkURL, score, reason})
+		if err == nil {
+			f.logMu.Lock()
+			f.RejectLog.Write(append(line, '\n'))
+			f.logMu.Unlock()
+		}
+	}
+	return false
+}
+
+// domainIn returns whether domain is one of domains or a subdomain of one.
+func domainIn(domain string, domains []string) bool {
+	if domain == "" {
+		return false
+	}
+	for _, d := range domains {
+		d = thesrc.NormalizeDomain(d)
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestRelevanceFilter_Keep(t *testing.T) {
+	var log bytes.Buffer
+	f := &RelevanceFilter{
+		MinRelevance: DefaultMinRelevance,
+		AllowDomains: []string{"politics.example.com"},
+		DenyDomains:  []string{"spam.example.com"},
+		RejectLog:    &log,
+	}
+
+	tests := []struct {
+		post *thesrc.Post
+		keep bool
+	}{
+		{&thesrc.Post{Title: "Writing a compiler in Go", LinkURL: "http://example.com/1"}, true},
+		{&thesrc.Post{Title: "Something interesting", LinkURL: "http://example.com/2"}, true},
+		{&thesrc.Post{Title: "The president's election speech", LinkURL: "http://example.com/3"}, false},
+		{&thesrc.Post{Title: "Election results API", LinkURL: "https://github.com/a/b"}, true},
+		{&thesrc.Post{Title: "Election night", LinkURL: "http://politics.example.com/4"}, true},
+		{&thesrc.Post{Title: "Go tips", LinkURL: "http://www.spam.example.com/5"}, false},
+	}
+	var rejected int
+	for _, test := range tests {
+		if keep := f.Keep("mock", test.post); keep != test.keep {
+			t.Errorf("%q (%s): got keep == %v, want %v", test.post.Title, test.post.LinkURL, keep, test.keep)
+		}
+		if !test.keep {
+			rejected++
+		}
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(log.Bytes()), []byte("\n"))
+	if len(lines) != rejected {
+		t.Fatalf("got %d reject log lines, want %d", len(lines), rejected)
+	}
+	var entry struct {
+		Site, LinkURL, Reason string
+	}
+	if err := json.Unmarshal(lines[1], &entry); err != nil {
+		t.Fatal(err)
+	}
+	if want := "denied domain spam.example.com"; entry.Site != "mock" || entry.LinkURL != "http://www.spam.example.com/5" || entry.Reason != want {
+		t.Errorf("got reject log entry %+v, want reason %q", entry, want)
+	}
+}
+
+func TestImport_filter(t *testing.T) {
+	var submitted []string
+	Posts = &thesrc.MockPostsService{
+		CreateBatch_: func(posts []*thesrc.Post) ([]*thesrc.PostBatchResult, error) {
+			results := make([]*thesrc.PostBatchResult, len(posts))
+			for i, post := range posts {
+				submitted = append(submitted, post.LinkURL)
+				results[i] = &thesrc.PostBatchResult{Post: post, Created: true}
+			}
+			return results, nil
+		},
+	}
+	Imported = nil
+	Filter = &RelevanceFilter{MinRelevance: DefaultMinRelevance}
+	defer func() { Filter = nil }()
+
+	f := &mockFetcher{posts: []*thesrc.Post{
+		{Title: "Go 2 design drafts", LinkURL: "http://example.com/filter/1"},
+		{Title: "Senate passes budget", LinkURL: "http://example.com/filter/2"},
+	}}
+	if err := Import(f); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"http://example.com/filter/1"}; len(submitted) != 1 || submitted[0] != want[0] {
+		t.Errorf("got submitted %v, want %v", submitted, want)
+	}
+}
@@ -0,0 +1,107 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// RSS returns a Fetcher that fetches the items in the RSS or Atom feed at
+// feedURL. Its site name is "rss/" followed by the feed URL without its
+// scheme (e.g., "rss/blog.golang.org/feed.atom").
+func RSS(feedURL string) Fetcher { return &rssFeed{feedURL} }
+
+type rssFeed struct {
+	url string
+}
+
+// feedDoc holds the fields of an RSS 2.0 (<rss>) or Atom (<feed>) document
+// that are imported. Only one of Channel and Entries is set, depending on
+// the format.
+type feedDoc struct {
+	Channel struct {
+		Items []struct {
+			Title   string `xml:"title"`
+			Link    string `xml:"link"`
+			PubDate string `xml:"pubDate"`
+		} `xml:"item"`
+	} `xml:"channel"`
+	Entries []struct {
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+	} `xml:"entry"`
+}
+
+func (f *rssFeed) Fetch() ([]*thesrc.Post, error) {
+	resp, err := http.Get(f.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-200 HTTP response status: %d", resp.StatusCode)
+	}
+
+	var doc feedDoc
+	dec := xml.NewDecoder(resp.Body)
+	// Feeds that declare a non-UTF-8 encoding are nearly always ASCII-compatible,
+	// so decode them as-is rather than failing.
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var posts []*thesrc.Post
+	add := func(title, link, date string, layouts ...string) {
+		title, link = strings.TrimSpace(title), strings.TrimSpace(link)
+		if title == "" || link == "" {
+			return
+		}
+		post := &thesrc.Post{Title: title, LinkURL: link}
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(date)); err == nil {
+				post.SubmittedAt = t
+				break
+			}
+		}
+		posts = append(posts, post)
+	}
+	for _, item := range doc.Channel.Items {
+		add(item.Title, item.Link, item.PubDate, time.RFC1123Z, time.RFC1123)
+	}
+	for _, entry := range doc.Entries {
+		var link string
+		for _, l := range entry.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				link = l.Href
+				break
+			}
+		}
+		date := entry.Published
+		if date == "" {
+			date = entry.Updated
+		}
+		add(entry.Title, link, date, time.RFC3339)
+	}
+	return posts, nil
+}
+
+func (f *rssFeed) Site() string {
+	u, err := url.Parse(f.url)
+	if err != nil || u.Host == "" {
+		return "rss/" + f.url
+	}
+	return "rss/" + u.Host + strings.TrimSuffix(u.Path, "/")
+}
@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestRSS_Fetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed.rss", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0"><channel>
+  <item><title> t1 </title><link>http://example.com/1</link><pubDate>Tue, 13 May 2014 16:53:20 +0000</pubDate></item>
+  <item><title></title><link>http://example.com/2</link></item>
+</channel></rss>`)
+	})
+	mux.HandleFunc("/feed.atom", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <title>t3</title>
+    <link rel="self" href="http://example.com/3.atom"/>
+    <link href="http://example.com/3"/>
+    <updated>2014-05-13T16:53:20Z</updated>
+  </entry>
+</feed>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		want []*thesrc.Post
+	}{
+		{"/feed.rss", []*thesrc.Post{{Title: "t1", LinkURL: "http://example.com/1", SubmittedAt: time.Unix(1400000000, 0)}}},
+		{"/feed.atom", []*thesrc.Post{{Title: "t3", LinkURL: "http://example.com/3", SubmittedAt: time.Unix(1400000000, 0)}}},
+	}
+	for _, test := range tests {
+		posts, err := RSS(server.URL + test.path).Fetch()
+		if err != nil {
+			t.Errorf("%s: %s", test.path, err)
+			continue
+		}
+		if len(posts) != len(test.want) {
+			t.Errorf("%s: got %d posts, want %d", test.path, len(posts), len(test.want))
+			continue
+		}
+		for i, post := range posts {
+			want := test.want[i]
+			if post.Title != want.Title || post.LinkURL != want.LinkURL || !post.SubmittedAt.Equal(want.SubmittedAt) {
+				t.Errorf("%s: got post %+v, want %+v", test.path, post, want)
+			}
+		}
+	}
+}
+
+func TestRSS_Site(t *testing.T) {
+	if got, want := RSS("https://blog.golang.org/feed.atom").Site(), "rss/blog.golang.org/feed.atom"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
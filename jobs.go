@@ -0,0 +1,144 @@
+package thesrc
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A Job is a unit of background work in the job queue, such as delivering
+// an event to a webhook. Workers (see the "thesrc worker" command) run
+// pending jobs, retrying failed jobs with exponential backoff until they
+// have been attempted MaxAttempts times.
+type Job struct {
+	// ID a unique identifier for this job.
+	ID int
+
+	// Kind is the type of work (such as "webhook"), which determines how
+	// the job is run.
+	Kind string
+
+	// Payload is the job's JSON-encoded arguments.
+	Payload string `json:",omitempty"`
+
+	// Key (if set) identifies the job's work, so that the same work isn't
+	// queued again while a job with the same key is pending or running.
+	Key string `json:",omitempty"`
+
+	// State is the job's state (one of the Job* constants).
+	State string
+
+	// Attempts is the number of times the job has been started.
+	Attempts int
+
+	// MaxAttempts is the number of attempts after which a failing job is
+	// given up on (and its State becomes JobFailed).
+	MaxAttempts int
+
+	// LastError is the error that the job's most recent attempt failed
+	// with, if any.
+	LastError string `json:",omitempty"`
+
+	// RunAt is when the job may next be started. For a running job, it is
+	// when the job is assumed to have been abandoned (by a worker that
+	// died) and may be started again.
+	RunAt time.Time
+
+	// CreatedAt is when the job was queued.
+	CreatedAt time.Time
+
+	// UpdatedAt is when the job's state last changed.
+	UpdatedAt time.Time
+}
+
+// The states of a job.
+const (
+	JobPending = "pending" // waiting to run (again)
+	JobRunning = "running" // being run by a worker
+	JobDone    = "done"    // succeeded
+	JobFailed  = "failed"  // failed on its last attempt
+)
+
+// JobsService interacts with the job queue endpoints in thesrc's API. Only
+// admins may use it.
+type JobsService interface {
+	// List jobs, most recently updated first.
+	List(opt *JobListOptions) ([]*Job, error)
+
+	// Retry a failed job, which queues it to run again with its attempts
+	// reset.
+	Retry(id int) error
+}
+
+type JobListOptions struct {
+	// State filters the result set to jobs in this state (one of the Job*
+	// constants).
+	State string `url:",omitempty" json:",omitempty"`
+
+	ListOptions
+}
+
+var (
+	ErrJobNotFound     = errors.New("job not found")
+	ErrJobLeaseExpired = errors.New("job lease expired")
+)
+
+type jobsService struct{ client *Client }
+
+func (s *jobsService) List(opt *JobListOptions) ([]*Job, error) {
+	url, err := s.client.url(router.Jobs, nil, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*Job
+	_, err = s.client.Do(req, &jobs)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (s *jobsService) Retry(id int) error {
+	url, err := s.client.url(router.RetryJob, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+type MockJobsService struct {
+	List_  func(opt *JobListOptions) ([]*Job, error)
+	Retry_ func(id int) error
+}
+
+var _ JobsService = &MockJobsService{}
+
+func (s *MockJobsService) List(opt *JobListOptions) ([]*Job, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
+
+func (s *MockJobsService) Retry(id int) error {
+	if s.Retry_ == nil {
+		return nil
+	}
+	return s.Retry_(id)
+}
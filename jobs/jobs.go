@@ -0,0 +1,187 @@
+// Package jobs runs the background jobs in the job queue (see thesrc.Job),
+// retrying failed jobs with exponential backoff.
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
+)
+
+// Defaults for the Worker fields of the same names.
+const (
+	DefaultPollInterval = time.Second
+	DefaultBackoff      = 10 * time.Second
+	DefaultLease        = 10 * time.Minute
+)
+
+// maxBackoff is the longest that a failed job waits to be retried.
+const maxBackoff = 6 * time.Hour
+
+var ran = metrics.NewCounterVec("thesrc_jobs_total",
+	"Jobs run by job queue workers, by kind and result (done, retry, or failed).", "kind", "result")
+
+// A Handler runs a job of a particular kind. If it returns an error, the job
+// is retried (up to its MaxAttempts).
+type Handler func(job *thesrc.Job) error
+
+// Enqueue queues a job of the given kind, with payload JSON-encoded as its
+// arguments. See datastore.JobsStore.Enqueue for the meaning of key.
+func Enqueue(s datastore.JobsStore, kind, key string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return s.Enqueue(&thesrc.Job{Kind: kind, Key: key, Payload: string(data)})
+}
+
+// Periodic queues a job of the given kind (with no payload) every interval,
+// until stop is closed. The job's key is its kind, so that a job isn't
+// queued again while the previous one is pending or running (even by
+// another process).
+func Periodic(s datastore.JobsStore, kind string, interval time.Duration, stop <-chan struct{}) {
+	for {
+		if err := s.Enqueue(&thesrc.Job{Kind: kind, Key: kind, Payload: "null"}); err != nil {
+			logging.Default.Log("Queueing periodic job failed", "kind", kind, "error", err)
+		}
+		select {
+		case <-time.After(interval):
+		case <-stop:
+			return
+		}
+	}
+}
+
+// A Worker runs queued jobs.
+type Worker struct {
+	Store datastore.JobsStore
+
+	// Handlers maps each kind of job to the handler that runs it. Jobs of
+	// other kinds fail (and are retried, in case a worker that can run them
+	// claims them next time).
+	Handlers map[string]Handler
+
+	// Concurrency is the number of jobs that Run runs at the same time. If
+	// 0, 1 is used.
+	Concurrency int
+
+	// PollInterval is how long to wait before checking for jobs again when
+	// none are ready to run. If 0, DefaultPollInterval is used.
+	PollInterval time.Duration
+
+	// Backoff is how long to wait before retrying a failed job. It doubles
+	// after each failed attempt. If 0, DefaultBackoff is used.
+	Backoff time.Duration
+
+	// Lease is how long a job may run before it is assumed to have been
+	// abandoned and may be run again. A job that runs for longer can't be
+	// completed or failed (see thesrc.ErrJobLeaseExpired). If 0,
+	// DefaultLease is used.
+	Lease time.Duration
+}
+
+// Run runs jobs until stop is closed, and then waits for the jobs that are
+// running to finish.
+func (w *Worker) Run(stop <-chan struct{}) {
+	concurrency, pollInterval := w.Concurrency, w.PollInterval
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if pollInterval == 0 {
+		pollInterval = DefaultPollInterval
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				ok, err := w.RunOnce()
+				if err != nil {
+					logging.Default.Log("Job worker failed", "error", err)
+				}
+				if ok && err == nil {
+					// Check for another job immediately, unless stopping.
+					select {
+					case <-stop:
+						return
+					default:
+						continue
+					}
+				}
+				select {
+				case <-time.After(pollInterval):
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// RunOnce claims and runs the next job that is ready to run, if any, and
+// reports whether it ran one. The returned error is from the job queue, not
+// from the job (which is recorded in the job's LastError).
+func (w *Worker) RunOnce() (bool, error) {
+	lease := w.Lease
+	if lease == 0 {
+		lease = DefaultLease
+	}
+
+	job, err := w.Store.Claim(time.Now(), lease)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	start := time.Now()
+	if err := w.run(job); err != nil {
+		retryAt := time.Now().Add(w.backoff(job.Attempts))
+		result := "retry"
+		if job.Attempts >= job.MaxAttempts {
+			result = "failed"
+		}
+		ran.Inc(job.Kind, result)
+		logging.Default.Log("Job failed", "id", job.ID, "kind", job.Kind, "attempt", job.Attempts, "result", result, "error", err, "duration_ms", logging.Milliseconds(time.Since(start)))
+		return true, w.Store.Fail(job, err.Error(), retryAt)
+	}
+	ran.Inc(job.Kind, "done")
+	return true, w.Store.Complete(job)
+}
+
+// run runs job with its kind's handler, turning a panic into an error.
+func (w *Worker) run(job *thesrc.Job) (err error) {
+	h := w.Handlers[job.Kind]
+	if h == nil {
+		return fmt.Errorf("no handler for jobs of kind %q", job.Kind)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h(job)
+}
+
+// backoff returns how long to wait before retrying a job that failed on the
+// given attempt.
+func (w *Worker) backoff(attempt int) time.Duration {
+	d := w.Backoff
+	if d == 0 {
+		d = DefaultBackoff
+	}
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
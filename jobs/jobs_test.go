@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestWorker_RunOnce(t *testing.T) {
+	store := datastore.NewMemoryDatastore().Jobs
+	var payloads []string
+	fail := true
+	w := &Worker{
+		Store: store,
+		Handlers: map[string]Handler{
+			"test": func(job *thesrc.Job) error {
+				payloads = append(payloads, job.Payload)
+				if fail {
+					return errors.New("x")
+				}
+				return nil
+			},
+		},
+		Backoff: time.Nanosecond,
+	}
+
+	if ok, err := w.RunOnce(); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Error("got ok == true with no jobs queued")
+	}
+
+	if err := Enqueue(store, "test", "", map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := w.RunOnce(); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("got ok == false, want the queued job to run")
+	}
+	if len(payloads) != 1 || payloads[0] != `{"a":1}` {
+		t.Errorf("got payloads %q, want the job's JSON payload", payloads)
+	}
+	if pending, _ := store.List(&thesrc.JobListOptions{State: thesrc.JobPending}); len(pending) != 1 || pending[0].LastError != "x" {
+		t.Fatalf("got pending jobs %+v, want the failed job to be retried", pending)
+	}
+
+	time.Sleep(time.Millisecond)
+	fail = false
+	if _, err := w.RunOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if done, _ := store.List(&thesrc.JobListOptions{State: thesrc.JobDone}); len(done) != 1 || done[0].Attempts != 2 {
+		t.Errorf("got done jobs %+v, want the job done on attempt 2", done)
+	}
+}
+
+func TestWorker_RunOnce_unknownKindAndPanic(t *testing.T) {
+	store := datastore.NewMemoryDatastore().Jobs
+	w := &Worker{
+		Store:    store,
+		Handlers: map[string]Handler{"panic": func(*thesrc.Job) error { panic("boom") }},
+	}
+
+	for _, kind := range []string{"unknown", "panic"} {
+		if err := store.Enqueue(&thesrc.Job{Kind: kind, MaxAttempts: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.RunOnce(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failed, err := store.List(&thesrc.JobListOptions{State: thesrc.JobFailed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 2 {
+		t.Errorf("got failed jobs %+v, want both jobs to have failed", failed)
+	}
+}
+
+func TestWorker_backoff(t *testing.T) {
+	w := &Worker{Backoff: time.Second}
+	tests := map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 100: maxBackoff}
+	for attempt, want := range tests {
+		if got := w.backoff(attempt); got != want {
+			t.Errorf("attempt %d: got backoff %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestUnfurl(t *testing.T) {
+	orig := fetchMetadata
+	defer func() { fetchMetadata = orig }()
+	fetchMetadata = func(linkURL string) (*thesrc.LinkMetadata, error) {
+		if linkURL != "http://example.com" {
+			t.Errorf("got link URL %q", linkURL)
+		}
+		return &thesrc.LinkMetadata{Title: "t", Description: "d"}, nil
+	}
+
+	var set *thesrc.LinkMetadata
+	store := &datastore.MockLinkChecksStore{
+		SetMetadata_: func(postID int, meta *thesrc.LinkMetadata) error {
+			if postID != 1 {
+				t.Errorf("got post ID %d, want 1", postID)
+			}
+			set = meta
+			return nil
+		},
+	}
+	if err := Unfurl(store)(&thesrc.Job{Kind: UnfurlKind, Payload: `{"PostID":1,"LinkURL":"http://example.com"}`}); err != nil {
+		t.Fatal(err)
+	}
+	if set == nil || set.Description != "d" {
+		t.Errorf("got metadata %+v, want the fetched metadata", set)
+	}
+}
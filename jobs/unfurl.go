@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"encoding/json"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/unfurl"
+)
+
+// UnfurlKind is the kind of the jobs that fetch the metadata of posts' link
+// URLs (see Unfurl).
+const UnfurlKind = "unfurl"
+
+// An UnfurlPayload is the payload of an unfurl job.
+type UnfurlPayload struct {
+	PostID  int
+	LinkURL string
+}
+
+// fetchMetadata fetches a link's metadata. It is a variable so that tests
+// can replace it.
+var fetchMetadata = unfurl.Fetch
+
+// Unfurl returns a handler for unfurl jobs, which fetch the metadata of a
+// post's link URL and fill in the post's link description, image, and
+// favicon (where they're empty).
+func Unfurl(s datastore.LinkChecksStore) Handler {
+	return func(job *thesrc.Job) error {
+		var p UnfurlPayload
+		if err := json.Unmarshal([]byte(job.Payload), &p); err != nil {
+			return err
+		}
+		meta, err := fetchMetadata(p.LinkURL)
+		if err != nil {
+			return err
+		}
+		if err := s.SetMetadata(p.PostID, meta); err != nil && err != thesrc.ErrPostNotFound {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestJobsService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*Job{{ID: 1, Kind: "webhook", State: JobFailed, Attempts: 5, MaxAttempts: 5, LastError: "x"}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Jobs, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"State": JobFailed, "PerPage": "5"})
+
+		writeJSON(w, want)
+	})
+
+	jobs, err := client.Jobs.List(&JobListOptions{State: JobFailed, ListOptions: ListOptions{PerPage: 5}})
+	if err != nil {
+		t.Errorf("Jobs.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, job := range want {
+		normalizeTime(&job.RunAt)
+		normalizeTime(&job.CreatedAt)
+		normalizeTime(&job.UpdatedAt)
+	}
+	if !reflect.DeepEqual(jobs, want) {
+		t.Errorf("Jobs.List returned %+v, want %+v", jobs, want)
+	}
+}
+
+func TestJobsService_Retry(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.RetryJob, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Jobs.Retry(1); err != nil {
+		t.Errorf("Jobs.Retry returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
@@ -0,0 +1,118 @@
+// Package linkcheck periodically checks posts' link URLs, marking posts whose
+// links have died and finding archived snapshots of their pages.
+package linkcheck
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/archive"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
+	"sourcegraph.com/sourcegraph/thesrc/unfurl"
+)
+
+// DefaultMaxAge is the default value of Checker.MaxAge.
+const DefaultMaxAge = 7 * 24 * time.Hour
+
+// linkStatus and snapshot check links and find archived snapshots. They are
+// variables so that tests can replace them.
+var (
+	linkStatus = unfurl.Status
+	snapshot   = archive.Snapshot
+)
+
+var checks = metrics.NewCounterVec("thesrc_link_checks_total",
+	"Link URLs checked by the dead link checker, by result (alive, dead, or error).", "result")
+
+// A Checker checks posts' link URLs and records whether they're dead.
+type Checker struct {
+	Store datastore.LinkChecksStore
+
+	// Interval is how long to wait between checks for links that are due to
+	// be checked.
+	Interval time.Duration
+
+	// MaxAge is how long after a link is checked that it is checked again.
+	// If 0, DefaultMaxAge is used.
+	MaxAge time.Duration
+}
+
+// BatchSize is the maximum number of links checked per call to RunOnce.
+var BatchSize = 50
+
+// Run checks links until stop is closed.
+func (c *Checker) Run(stop <-chan struct{}) {
+	for {
+		if _, _, err := c.RunOnce(); err != nil {
+			log.Print("Link checker: ", err)
+		}
+		select {
+		case <-time.After(c.Interval):
+		case <-stop:
+			return
+		}
+	}
+}
+
+// RunOnce checks up to BatchSize links that are due to be checked, and
+// returns the number of links it checked and how many of them were dead.
+func (c *Checker) RunOnce() (checked, dead int, err error) {
+	maxAge := c.MaxAge
+	if maxAge == 0 {
+		maxAge = DefaultMaxAge
+	}
+
+	posts, err := c.Store.ListDue(time.Now().Add(-maxAge), BatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, post := range posts {
+		status, err := linkStatus(post.LinkURL)
+		isDead := isDead(status, err)
+		switch {
+		case isDead:
+			checks.Inc("dead")
+		case err != nil:
+			// The link may be only temporarily unreachable, so record the
+			// check (so that it isn't retried immediately) without marking
+			// it dead.
+			log.Printf("Link checker: post %d (%s): %s", post.ID, post.LinkURL, err)
+			checks.Inc("error")
+		default:
+			checks.Inc("alive")
+		}
+
+		var archiveURL string
+		if isDead && post.LinkArchiveURL == "" {
+			if archiveURL, err = snapshot(post.LinkURL); err != nil {
+				log.Printf("Link checker: finding snapshot of post %d (%s): %s", post.ID, post.LinkURL, err)
+			}
+		}
+		if err := c.Store.Set(post.ID, status, isDead, archiveURL); err != nil {
+			return checked, dead, err
+		}
+		checked++
+		if isDead {
+			dead++
+		}
+	}
+	return checked, dead, nil
+}
+
+// isDead returns whether a link is dead, given the status code that it
+// responded with or the error that prevented it from responding. Only
+// responses that mean the page is gone (404 Not Found and 410 Gone) and
+// domains that no longer exist are considered dead, because other errors
+// (such as 5xx responses and timeouts) are often temporary.
+func isDead(status int, err error) bool {
+	if err != nil {
+		var dnsErr *net.DNSError
+		return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+	}
+	return status == http.StatusNotFound || status == http.StatusGone
+}
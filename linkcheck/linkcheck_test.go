@@ -0,0 +1,80 @@
+package linkcheck
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestChecker_RunOnce(t *testing.T) {
+	origStatus, origSnapshot := linkStatus, snapshot
+	defer func() { linkStatus, snapshot = origStatus, origSnapshot }()
+	linkStatus = func(linkURL string) (int, error) {
+		switch linkURL {
+		case "http://example.com/ok":
+			return http.StatusOK, nil
+		case "http://example.com/gone":
+			return http.StatusNotFound, nil
+		case "http://nxdomain.example.com/":
+			return 0, &net.DNSError{Err: "no such host", Name: "nxdomain.example.com", IsNotFound: true}
+		}
+		return 0, errors.New("timeout")
+	}
+	snapshot = func(linkURL string) (string, error) {
+		if linkURL == "http://example.com/gone" {
+			return "http://web.archive.org/web/2014/http://example.com/gone", nil
+		}
+		return "", nil
+	}
+
+	d := datastore.NewMemoryDatastore()
+	for _, p := range []*thesrc.Post{
+		{LinkURL: "http://example.com/ok"},
+		{LinkURL: "http://example.com/gone"},
+		{LinkURL: "http://nxdomain.example.com/"},
+		{LinkURL: "http://example.com/timeout"},
+		{Body: "self-post"},
+	} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := &Checker{Store: d.LinkChecks}
+	checked, dead, err := c.RunOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != 4 || dead != 2 {
+		t.Errorf("got %d checked and %d dead, want 4 and 2", checked, dead)
+	}
+
+	want := map[int]struct {
+		status     int
+		dead       bool
+		archiveURL string
+	}{
+		1: {http.StatusOK, false, ""},
+		2: {http.StatusNotFound, true, "http://web.archive.org/web/2014/http://example.com/gone"},
+		3: {0, true, ""},
+		4: {0, false, ""},
+	}
+	for id, w := range want {
+		post, err := d.Posts.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.LinkStatus != w.status || post.LinkDead != w.dead || post.LinkArchiveURL != w.archiveURL {
+			t.Errorf("post %d: got LinkStatus %d, LinkDead %v, LinkArchiveURL %q, want %d, %v, %q", id, post.LinkStatus, post.LinkDead, post.LinkArchiveURL, w.status, w.dead, w.archiveURL)
+		}
+	}
+
+	// Links aren't checked again until they're due.
+	if checked, _, err := c.RunOnce(); err != nil || checked != 0 {
+		t.Errorf("got %d checked and error %v on second run, want 0 and nil", checked, err)
+	}
+}
@@ -0,0 +1,96 @@
+package thesrc
+
+import (
+	"net/url"
+	"strings"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// LinkMetadata describes the page that a link URL points to, as extracted
+// from the page's HTML.
+type LinkMetadata struct {
+	// Title is the page's <title> (or, if it has none, its OpenGraph title).
+	Title string `json:",omitempty"`
+
+	// Description is the page's OpenGraph (or meta) description.
+	Description string `json:",omitempty"`
+
+	// ImageURL is the absolute URL of the page's OpenGraph image.
+	ImageURL string `json:",omitempty"`
+
+	// FaviconURL is the absolute URL of the site's favicon.
+	FaviconURL string `json:",omitempty"`
+}
+
+// CanonicalLinkURL returns the canonical form of linkURL, so that different
+// spellings of the same URL are detected as the same link: its scheme and
+// host are lowercased, and its default port, fragment, and tracking query
+// parameters (utm_*) are removed. If linkURL can't be parsed, it is returned
+// unchanged.
+func CanonicalLinkURL(linkURL string) string {
+	u, err := url.Parse(linkURL)
+	if err != nil || u.Host == "" {
+		return linkURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = u.Hostname()
+	}
+	u.Fragment, u.RawFragment = "", ""
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			if strings.HasPrefix(strings.ToLower(k), "utm_") {
+				q.Del(k)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// LinksService interacts with the link-related endpoints in thesrc's API.
+type LinksService interface {
+	// Unfurl fetches the page at linkURL and returns its metadata (for
+	// previewing a link before submitting it).
+	Unfurl(linkURL string) (*LinkMetadata, error)
+}
+
+type linksService struct{ client *Client }
+
+func (s *linksService) Unfurl(linkURL string) (*LinkMetadata, error) {
+	url, err := s.client.url(router.Unfurl, nil, &struct {
+		URL string `url:"url"`
+	}{linkURL})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta *LinkMetadata
+	_, err = s.client.Do(req, &meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+type MockLinksService struct {
+	Unfurl_ func(linkURL string) (*LinkMetadata, error)
+}
+
+var _ LinksService = &MockLinksService{}
+
+func (s *MockLinksService) Unfurl(linkURL string) (*LinkMetadata, error) {
+	if s.Unfurl_ == nil {
+		return nil, nil
+	}
+	return s.Unfurl_(linkURL)
+}
@@ -0,0 +1,57 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestLinksService_Unfurl(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &LinkMetadata{Title: "t"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Unfurl, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"url": "http://example.com"})
+
+		writeJSON(w, want)
+	})
+
+	meta, err := client.Links.Unfurl("http://example.com")
+	if err != nil {
+		t.Errorf("Links.Unfurl returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("Links.Unfurl returned %+v, want %+v", meta, want)
+	}
+}
+
+func TestCanonicalLinkURL(t *testing.T) {
+	tests := map[string]string{
+		"http://example.com/a":                           "http://example.com/a",
+		"HTTP://Example.COM/A":                           "http://example.com/A",
+		"http://example.com:80/a":                        "http://example.com/a",
+		"https://example.com:443/a":                      "https://example.com/a",
+		"http://example.com:8080/a":                      "http://example.com:8080/a",
+		"http://example.com/a#section":                   "http://example.com/a",
+		"http://example.com/a?utm_source=x&utm_medium=y": "http://example.com/a",
+		"http://example.com/a?q=1&utm_campaign=z":        "http://example.com/a?q=1",
+		"not a url": "not a url",
+	}
+	for linkURL, want := range tests {
+		if got := CanonicalLinkURL(linkURL); got != want {
+			t.Errorf("%q: got %q, want %q", linkURL, got, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/httpstatus"
+)
+
+// RequestIDHeader is the HTTP header that carries request IDs, both on
+// incoming requests (if the client or a proxy assigned one) and on
+// responses.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the length of the longest X-Request-ID header value
+// that Handler uses as a request's ID.
+const maxRequestIDLength = 200
+
+// Handler wraps h so that each request is assigned an ID, which is sent
+// back in the response's X-Request-ID header. The ID is the request's own
+// X-Request-ID header, if it has a valid one, or else a new random ID. The
+// request's context carries the ID (see RequestID) and a logger derived from
+// l that includes it in every entry (see FromContext). After h serves the
+// request, an access log entry is written with the request's method, path,
+// route name (see SetRoute), response status, and latency.
+func Handler(l *Logger, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		route := new(string)
+		rl := l.With("request_id", id)
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		ctx = context.WithValue(ctx, routeKey, route)
+		ctx = NewContext(ctx, rl)
+
+		rw := httpstatus.NewRecorder(w)
+		h.ServeHTTP(rw, r.WithContext(ctx))
+
+		rl.Log("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", *route,
+			"status", rw.Status(),
+			"latency_ms", Milliseconds(time.Since(start)),
+		)
+	})
+}
+
+// SetRoute records the name of the route that matched r, for r's access log
+// entry (see Handler). It does nothing if r isn't being served by Handler.
+func SetRoute(r *http.Request, name string) {
+	if route, ok := r.Context().Value(routeKey).(*string); ok {
+		*route = name
+	}
+}
+
+// validRequestID reports whether id may be used as a request ID. To keep
+// logs readable, only short IDs of printable ASCII characters are allowed.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
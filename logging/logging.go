@@ -0,0 +1,137 @@
+// Package logging writes structured logs, with each entry a JSON object on
+// its own line, and gives each HTTP request an ID and a logger (see Handler
+// and FromContext) so that everything logged while serving a request can be
+// correlated.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// A Logger writes structured log entries. Each entry is a JSON object with
+// the time, a message, the logger's fields (see With), and the entry's own
+// fields.
+type Logger struct {
+	out    *output
+	fields []interface{} // alternating keys and values
+}
+
+// output serializes writes to a Logger's writer, which is shared by the
+// loggers derived from it with With.
+type output struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// New creates a Logger that writes entries to w.
+func New(w io.Writer) *Logger {
+	return &Logger{out: &output{w: w}}
+}
+
+// Default is the logger used when no other logger is available (see
+// FromContext).
+var Default = New(os.Stderr)
+
+// With returns a logger that adds the fields in keyvals, a list of
+// alternating keys and values, to every entry that it writes.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	return &Logger{out: l.out, fields: fields}
+}
+
+// Log writes an entry with message msg and the fields in keyvals, a list of
+// alternating keys and values. Values are encoded as JSON, except errors,
+// which are logged as their message.
+func (l *Logger) Log(msg string, keyvals ...interface{}) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	writeValue(&buf, time.Now().UTC().Format(time.RFC3339Nano))
+	buf.WriteString(`,"msg":`)
+	writeValue(&buf, msg)
+	writeFields(&buf, l.fields)
+	writeFields(&buf, keyvals)
+	buf.WriteString("}\n")
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.w.Write(buf.Bytes())
+}
+
+// Printf writes an entry whose message is formatted as with fmt.Sprintf.
+func (l *Logger) Printf(format string, v ...interface{}) {
+	l.Log(fmt.Sprintf(format, v...))
+}
+
+func writeFields(buf *bytes.Buffer, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i += 2 {
+		buf.WriteByte(',')
+		writeValue(buf, fmt.Sprint(keyvals[i]))
+		buf.WriteByte(':')
+		if i+1 < len(keyvals) {
+			writeValue(buf, keyvals[i+1])
+		} else {
+			buf.WriteString("null")
+		}
+	}
+}
+
+func writeValue(buf *bytes.Buffer, v interface{}) {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(data)
+}
+
+// Milliseconds returns d in (fractional) milliseconds, the unit that
+// durations are logged in.
+func Milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+	routeKey
+)
+
+// NewContext returns a copy of ctx that carries l (see FromContext).
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the logger carried by ctx, or Default if ctx (which
+// may be nil) carries none. For the context of an HTTP request served by
+// Handler, it is a logger that includes the request ID in its entries.
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey).(*Logger); ok {
+			return l
+		}
+	}
+	return Default
+}
+
+// RequestID returns the ID of the HTTP request whose context (or a context
+// derived from it) is ctx, or "" if there is none. Ctx may be nil.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogger_Log(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf).With("a", 1)
+	l.Log("m", "b", "x", "err", errors.New("e"), "odd")
+
+	line := buf.String()
+	if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+		t.Errorf("got %q, want a single line", line)
+	}
+	if want := `"msg":"m","a":1,"b":"x","err":"e","odd":null}`; !strings.Contains(line, want) {
+		t.Errorf("got %q, want it to contain %q", line, want)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := entry["time"]; !ok {
+		t.Errorf("got entry %v, want a time", entry)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if l := FromContext(nil); l != Default {
+		t.Errorf("got logger %v for nil context, want Default", l)
+	}
+	if l := FromContext(context.Background()); l != Default {
+		t.Errorf("got logger %v for context without logger, want Default", l)
+	}
+	l := New(nil)
+	if got := FromContext(NewContext(context.Background(), l)); got != l {
+		t.Errorf("got logger %v, want %v", got, l)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string // "" means a new random ID
+	}{
+		{"", ""},
+		{"abc-123", "abc-123"},
+		{"bad\nid", ""},
+		{strings.Repeat("x", maxRequestIDLength+1), ""},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		var gotID string
+		h := Handler(New(&buf), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotID = RequestID(r.Context())
+			SetRoute(r, "post")
+			FromContext(r.Context()).Log("in handler")
+			w.WriteHeader(http.StatusTeapot)
+		}))
+
+		req, _ := http.NewRequest("GET", "/posts/1", nil)
+		if test.header != "" {
+			req.Header.Set(RequestIDHeader, test.header)
+		}
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, req)
+
+		if test.want != "" && gotID != test.want {
+			t.Errorf("%q: got request ID %q, want %q", test.header, gotID, test.want)
+		}
+		if test.want == "" && (gotID == "" || gotID == test.header) {
+			t.Errorf("%q: got request ID %q, want a new ID", test.header, gotID)
+		}
+		if h := rw.Header().Get(RequestIDHeader); h != gotID {
+			t.Errorf("%q: got response %s %q, want %q", test.header, RequestIDHeader, h, gotID)
+		}
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("%q: got log %q, want 2 entries", test.header, buf.String())
+		}
+		var handlerEntry, accessEntry map[string]interface{}
+		json.Unmarshal([]byte(lines[0]), &handlerEntry)
+		json.Unmarshal([]byte(lines[1]), &accessEntry)
+		if handlerEntry["request_id"] != gotID {
+			t.Errorf("%q: got handler log entry %v, want request_id %q", test.header, handlerEntry, gotID)
+		}
+		for k, v := range map[string]interface{}{"msg": "request", "request_id": gotID, "method": "GET", "path": "/posts/1", "route": "post", "status": float64(http.StatusTeapot)} {
+			if accessEntry[k] != v {
+				t.Errorf("%q: got access log %s %v, want %v", test.header, k, accessEntry[k], v)
+			}
+		}
+		if _, ok := accessEntry["latency_ms"].(float64); !ok {
+			t.Errorf("%q: got access log entry %v, want latency_ms", test.header, accessEntry)
+		}
+	}
+}
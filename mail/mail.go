@@ -0,0 +1,100 @@
+// Package mail sends email, such as password reset messages, through an SMTP
+// server.
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// A Message is a plain-text email message.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// A Sender sends email messages.
+type Sender interface {
+	Send(msg *Message) error
+}
+
+// SMTPSender sends messages through an SMTP server.
+type SMTPSender struct {
+	// Addr is the host:port address of the SMTP server.
+	Addr string
+
+	// From is the address that messages are sent from.
+	From string
+
+	// Username and Password (if Username is set) authenticate to the server
+	// with PLAIN authentication, which the server must offer over TLS
+	// (STARTTLS) unless it is on localhost.
+	Username, Password string
+}
+
+var errHeaderNewline = errors.New("mail header contains a newline")
+
+func (s *SMTPSender) Send(msg *Message) error {
+	data, err := format(s.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	return smtp.SendMail(s.Addr, auth, s.From, []string{msg.To}, data)
+}
+
+// format returns the RFC 5322 representation of msg, sent from from at t.
+func format(from string, msg *Message, t time.Time) ([]byte, error) {
+	for _, v := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, errHeaderNewline
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&buf, "Date: %s\r\n", t.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.Replace(strings.Replace(msg.Body, "\r\n", "\n", -1), "\n", "\r\n", -1))
+	return buf.Bytes(), nil
+}
+
+// LogSender logs messages instead of sending them, for development.
+type LogSender struct{}
+
+func (LogSender) Send(msg *Message) error {
+	log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+type MockSender struct {
+	Send_ func(msg *Message) error
+}
+
+var _ Sender = &MockSender{}
+
+func (s *MockSender) Send(msg *Message) error {
+	if s.Send_ == nil {
+		return nil
+	}
+	return s.Send_(msg)
+}
@@ -0,0 +1,38 @@
+package mail
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	msg := &Message{To: "alice@example.com", Subject: "Hello", Body: "line 1\nline 2\n"}
+	date := time.Date(2014, 6, 25, 12, 0, 0, 0, time.UTC)
+
+	data, err := format("thesrc <noreply@example.com>", msg, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "From: thesrc <noreply@example.com>\r\n" +
+		"To: alice@example.com\r\n" +
+		"Subject: Hello\r\n" +
+		"Date: Wed, 25 Jun 2014 12:00:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"line 1\r\nline 2\r\n"
+	if string(data) != want {
+		t.Errorf("got message %q, want %q", data, want)
+	}
+}
+
+func TestFormat_headerInjection(t *testing.T) {
+	for _, msg := range []*Message{
+		{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hello"},
+		{To: "alice@example.com", Subject: "Hello\nBcc: eve@example.com"},
+	} {
+		if _, err := format("noreply@example.com", msg, time.Now()); err != errHeaderNewline {
+			t.Errorf("%+v: got error %v, want %v", msg, err, errHeaderNewline)
+		}
+	}
+}
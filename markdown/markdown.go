@@ -0,0 +1,21 @@
+// Package markdown renders user-submitted Markdown (in post and comment
+// bodies) to HTML that is safe to display.
+package markdown
+
+import (
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/russross/blackfriday"
+)
+
+// policy permits the HTML that Markdown produces (links, emphasis, lists,
+// code blocks, etc.) but strips scripts, styles, event handlers, and other
+// markup that could be used for XSS. Links are marked rel="nofollow".
+var policy = bluemonday.UGCPolicy()
+
+// HTML renders src as Markdown and returns the sanitized HTML.
+func HTML(src string) string {
+	if src == "" {
+		return ""
+	}
+	return string(policy.SanitizeBytes(blackfriday.MarkdownCommon([]byte(src))))
+}
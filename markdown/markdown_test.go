@@ -0,0 +1,23 @@
+package markdown
+
+import "testing"
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"", ""},
+		{"hello *world*", "<p>hello <em>world</em></p>\n"},
+		{"`x := 1`", "<p><code>x := 1</code></p>\n"},
+		{"[a](http://example.com)", `<p><a href="http://example.com" rel="nofollow">a</a></p>` + "\n"},
+		{"<script>alert(1)</script>", "\n"},
+		{`<a href="javascript:alert(1)">a</a>`, "<p>a</p>\n"},
+		{`<img src="x" onerror="alert(1)">`, "<p><img src=\"x\"></p>\n"},
+	}
+	for _, test := range tests {
+		if got := HTML(test.src); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.src, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc/httpstatus"
+)
+
+var (
+	httpRequests = NewCounterVec("thesrc_http_requests_total",
+		"Number of HTTP requests, by server (api or app), route name, and response status code.",
+		"server", "route", "code")
+	httpRequestDuration = NewHistogramVec("thesrc_http_request_duration_seconds",
+		"HTTP request latencies, by server (api or app) and route name.",
+		DefaultBuckets, "server", "route")
+)
+
+// Handler serves all metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		WriteTo(w)
+	})
+}
+
+// InstrumentRoutes wraps the handler of each named route in m so that its
+// request counts and latencies are recorded, labeled with server and the
+// route's name. It must be called after the routes' handlers are set.
+func InstrumentRoutes(server string, m *mux.Router) {
+	m.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if h := route.GetHandler(); h != nil && route.GetName() != "" {
+			route.Handler(instrument(server, route.GetName(), h))
+		}
+		return nil
+	})
+}
+
+func instrument(server, route string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := httpstatus.NewRecorder(w)
+		h.ServeHTTP(rw, r)
+		httpRequests.Inc(server, route, strconv.Itoa(rw.Status()))
+		httpRequestDuration.ObserveSince(start, server, route)
+	})
+}
@@ -0,0 +1,254 @@
+// Package metrics collects counters and histograms and exports them in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A metric is a collection of time series that can write itself in the
+// Prometheus text format.
+type metric interface {
+	write(w io.Writer)
+}
+
+var registry struct {
+	sync.Mutex
+	metrics map[string]metric
+}
+
+func register(name string, m metric) {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.metrics == nil {
+		registry.metrics = map[string]metric{}
+	}
+	if _, dup := registry.metrics[name]; dup {
+		panic("metrics: duplicate metric " + name)
+	}
+	registry.metrics[name] = m
+}
+
+// WriteTo writes all metrics to w in the Prometheus text format (version
+// 0.0.4).
+func WriteTo(w io.Writer) {
+	registry.Lock()
+	names := make([]string, 0, len(registry.metrics))
+	for name := range registry.metrics {
+		names = append(names, name)
+	}
+	metrics := registry.metrics
+	registry.Unlock()
+
+	sort.Strings(names)
+	for _, name := range names {
+		metrics[name].write(w)
+	}
+}
+
+// A CounterVec is a set of counters, partitioned by label values.
+type CounterVec struct {
+	name, help string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]float64 // keyed by joined label values
+}
+
+// NewCounterVec creates and registers a counter with the given label names.
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labelNames: labelNames, values: map[string]float64{}}
+	register(name, c)
+	return c
+}
+
+// Inc increments the counter with the given label values by 1.
+func (c *CounterVec) Inc(labelValues ...string) { c.Add(1, labelValues...) }
+
+// Add adds v to the counter with the given label values.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	key := labelKey(c.labelNames, labelValues)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+// Value returns the current value of the counter with the given label values.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	key := labelKey(c.labelNames, labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeHeader(w, c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labelNames, key, ""), formatFloat(c.values[key]))
+	}
+}
+
+// A funcMetric is an unpartitioned gauge or counter whose value is read from
+// a function each time the metrics are written.
+type funcMetric struct {
+	name, help, typ string
+	fn              func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is fn's result when the metrics
+// are written. It is for values that another package already tracks, such as
+// the size of a connection pool.
+func NewGaugeFunc(name, help string, fn func() float64) {
+	register(name, &funcMetric{name: name, help: help, typ: "gauge", fn: fn})
+}
+
+// NewCounterFunc is like NewGaugeFunc, but for a value that only increases
+// (such as a total tracked by another package).
+func NewCounterFunc(name, help string, fn func() float64) {
+	register(name, &funcMetric{name: name, help: help, typ: "counter", fn: fn})
+}
+
+func (m *funcMetric) write(w io.Writer) {
+	writeHeader(w, m.name, m.help, m.typ)
+	fmt.Fprintf(w, "%s %s\n", m.name, formatFloat(m.fn()))
+}
+
+// DefaultBuckets are the default histogram buckets, in seconds, suitable for
+// request and query latencies.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// A HistogramVec is a set of histograms, partitioned by label values.
+type HistogramVec struct {
+	name, help string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	values map[string]*histogram // keyed by joined label values
+}
+
+type histogram struct {
+	counts []uint64 // cumulative counts, one per bucket
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec creates and registers a histogram with the given buckets
+// (in increasing order) and label names.
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, labelNames: labelNames, buckets: buckets, values: map[string]*histogram{}}
+	register(name, h)
+	return h
+}
+
+// Observe adds an observation of v to the histogram with the given label
+// values.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := labelKey(h.labelNames, labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hist := h.values[key]
+	if hist == nil {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hist
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			hist.counts[i]++
+		}
+	}
+	hist.count++
+	hist.sum += v
+}
+
+// ObserveSince observes the number of seconds elapsed since start. It is
+// convenient to defer:
+//
+//	defer h.ObserveSince(time.Now(), "label")
+func (h *HistogramVec) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.name, h.help, "histogram")
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		hist := h.values[key]
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labelNames, key, formatFloat(b)), hist.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labelNames, key, "+Inf"), hist.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labelNames, key, ""), formatFloat(hist.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labelNames, key, ""), hist.count)
+	}
+}
+
+// labelSep separates label values in map keys. It can't appear in valid
+// UTF-8 label values.
+const labelSep = "\xff"
+
+func labelKey(labelNames, labelValues []string) string {
+	if len(labelValues) != len(labelNames) {
+		panic(fmt.Sprintf("metrics: got %d label values, want %d (%v)", len(labelValues), len(labelNames), labelNames))
+	}
+	return strings.Join(labelValues, labelSep)
+}
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// formatLabels formats the label set identified by key, plus an "le" label
+// if le is non-empty.
+func formatLabels(labelNames []string, key, le string) string {
+	var pairs []string
+	if len(labelNames) > 0 {
+		for i, v := range strings.Split(key, labelSep) {
+			pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labelNames[i], labelValueEscaper.Replace(v)))
+		}
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf(`le="%s"`, le))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
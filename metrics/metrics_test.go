@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestWriteTo(t *testing.T) {
+	c := NewCounterVec("test_counter_total", "A test counter.", "a")
+	c.Inc("x")
+	c.Add(2, `y"z`)
+	h := NewHistogramVec("test_duration_seconds", "A test histogram.", []float64{1, 2})
+	h.Observe(1.5)
+	NewGaugeFunc("test_gauge", "A test gauge.", func() float64 { return 3 })
+
+	var buf bytes.Buffer
+	WriteTo(&buf)
+
+	for _, want := range []string{
+		"# TYPE test_counter_total counter\n",
+		`test_counter_total{a="x"} 1` + "\n",
+		`test_counter_total{a="y\"z"} 2` + "\n",
+		"# TYPE test_duration_seconds histogram\n",
+		`test_duration_seconds_bucket{le="1"} 0` + "\n",
+		`test_duration_seconds_bucket{le="2"} 1` + "\n",
+		`test_duration_seconds_bucket{le="+Inf"} 1` + "\n",
+		"test_duration_seconds_sum 1.5\n",
+		"test_duration_seconds_count 1\n",
+		"# TYPE test_gauge gauge\n",
+		"test_gauge 3\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestInstrumentRoutes(t *testing.T) {
+	m := mux.NewRouter()
+	m.Path("/x").Name("x").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	InstrumentRoutes("test", m)
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/x", nil))
+
+	if got := httpRequests.Value("test", "x", "418"); got != 1 {
+		t.Errorf("got %v requests, want 1", got)
+	}
+}
@@ -0,0 +1,115 @@
+// Package mock provides implementations of the services of thesrc's API
+// client whose behavior is set by function fields, so that programs that use
+// a *thesrc.Client (such as importers and bots) can be unit-tested without an
+// API server.
+//
+// Create a client with NewClient, and set the function fields of the mocks
+// that the code under test calls:
+//
+//	client, mocks := mock.NewClient()
+//	mocks.Posts.Submit_ = func(post *thesrc.Post) (bool, error) {
+//		submitted = append(submitted, post)
+//		return true, nil
+//	}
+//	runBot(client)
+//
+// A mock method whose function field is nil does nothing and returns zero
+// values (and a nil error).
+package mock
+
+import "sourcegraph.com/sourcegraph/thesrc"
+
+// The mock services. They are the same types as thesrc.MockPostsService and
+// so on, under shorter names.
+type (
+	PostsService         = thesrc.MockPostsService
+	CommentsService      = thesrc.MockCommentsService
+	UsersService         = thesrc.MockUsersService
+	VotesService         = thesrc.MockVotesService
+	TagsService          = thesrc.MockTagsService
+	DomainsService       = thesrc.MockDomainsService
+	LinksService         = thesrc.MockLinksService
+	TokensService        = thesrc.MockTokensService
+	TwoFactorService     = thesrc.MockTwoFactorService
+	SessionsService      = thesrc.MockSessionsService
+	FollowsService       = thesrc.MockFollowsService
+	WebhooksService      = thesrc.MockWebhooksService
+	JobsService          = thesrc.MockJobsService
+	VoteSuspectsService  = thesrc.MockVoteSuspectsService
+	ClientRecordsService = thesrc.MockClientRecordsService
+	AuditLogService      = thesrc.MockAuditLogService
+	SiteService          = thesrc.MockSiteService
+	NotificationsService = thesrc.MockNotificationsService
+	GraphQLService       = thesrc.MockGraphQLService
+)
+
+// Services are the mock services of a client created by NewClient.
+type Services struct {
+	Posts         *PostsService
+	Comments      *CommentsService
+	Users         *UsersService
+	Votes         *VotesService
+	Tags          *TagsService
+	Domains       *DomainsService
+	Links         *LinksService
+	Tokens        *TokensService
+	TwoFactor     *TwoFactorService
+	Sessions      *SessionsService
+	Follows       *FollowsService
+	Webhooks      *WebhooksService
+	Jobs          *JobsService
+	VoteSuspects  *VoteSuspectsService
+	ClientRecords *ClientRecordsService
+	AuditLog      *AuditLogService
+	Site          *SiteService
+	Notifications *NotificationsService
+	GraphQL       *GraphQLService
+}
+
+// NewClient returns a client all of whose services are mocks, and the
+// mocks.
+func NewClient() (*thesrc.Client, *Services) {
+	s := &Services{
+		Posts:         &PostsService{},
+		Comments:      &CommentsService{},
+		Users:         &UsersService{},
+		Votes:         &VotesService{},
+		Tags:          &TagsService{},
+		Domains:       &DomainsService{},
+		Links:         &LinksService{},
+		Tokens:        &TokensService{},
+		TwoFactor:     &TwoFactorService{},
+		Sessions:      &SessionsService{},
+		Follows:       &FollowsService{},
+		Webhooks:      &WebhooksService{},
+		Jobs:          &JobsService{},
+		VoteSuspects:  &VoteSuspectsService{},
+		ClientRecords: &ClientRecordsService{},
+		AuditLog:      &AuditLogService{},
+		Site:          &SiteService{},
+		Notifications: &NotificationsService{},
+		GraphQL:       &GraphQLService{},
+	}
+	c := &thesrc.Client{
+		Posts:         s.Posts,
+		Comments:      s.Comments,
+		Users:         s.Users,
+		Votes:         s.Votes,
+		Tags:          s.Tags,
+		Domains:       s.Domains,
+		Links:         s.Links,
+		Tokens:        s.Tokens,
+		TwoFactor:     s.TwoFactor,
+		Sessions:      s.Sessions,
+		Follows:       s.Follows,
+		Webhooks:      s.Webhooks,
+		Jobs:          s.Jobs,
+		VoteSuspects:  s.VoteSuspects,
+		ClientRecords: s.ClientRecords,
+		AuditLog:      s.AuditLog,
+		Site:          s.Site,
+		Notifications: s.Notifications,
+		GraphQL:       s.GraphQL,
+	}
+	return c, s
+}
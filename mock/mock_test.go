@@ -0,0 +1,37 @@
+package mock
+
+import (
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestNewClient(t *testing.T) {
+	client, mocks := NewClient()
+
+	var submitted []*thesrc.Post
+	mocks.Posts.Submit_ = func(post *thesrc.Post) (bool, error) {
+		submitted = append(submitted, post)
+		return true, nil
+	}
+
+	post := &thesrc.Post{Title: "t", LinkURL: "http://example.com"}
+	created, err := client.Posts.Submit(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Error("!created")
+	}
+	if len(submitted) != 1 || submitted[0] != post {
+		t.Errorf("got submitted %v, want [%v]", submitted, post)
+	}
+
+	// Mock methods whose function fields are nil return zero values.
+	if posts, err := client.Posts.List(nil); err != nil || posts != nil {
+		t.Errorf("got (%v, %v), want (nil, nil)", posts, err)
+	}
+	if err := client.Votes.Upvote(1); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,103 @@
+package thesrc
+
+import (
+	"fmt"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A BulkModeration is an action that a moderator takes on many posts (and,
+// for BulkKill, comments) at once, such as from the checkboxes on the
+// moderation page. It is applied all or nothing: if any of the posts or
+// comments doesn't exist, none of them are changed.
+type BulkModeration struct {
+	// Action is the action to take (one of the Bulk* constants).
+	Action string
+
+	// PostIDs and CommentIDs are the IDs of the posts and comments to act
+	// on. Only BulkKill acts on comments. Together they may have at most
+	// MaxBulkModeration IDs.
+	PostIDs    []int `json:",omitempty"`
+	CommentIDs []int `json:",omitempty"`
+
+	// Tags are the tags to remove from the posts (for BulkUntag) or to
+	// replace the posts' tags with (for BulkRetag).
+	Tags []string `json:",omitempty"`
+}
+
+// The actions that a BulkModeration can take.
+const (
+	BulkKill  = "kill"  // kill the posts and comments
+	BulkUntag = "untag" // remove Tags from the posts
+	BulkRetag = "retag" // replace the posts' tags with Tags
+	BulkQueue = "queue" // hide the posts and move them to the moderation queue
+)
+
+// MaxBulkModeration is the maximum number of posts and comments that a
+// BulkModeration may act on.
+const MaxBulkModeration = 100
+
+// Validate returns a ValidationError describing the problems found with m,
+// if any. It expects m.Tags to be normalized (see NormalizeTags).
+func (m *BulkModeration) Validate() error {
+	var errs ValidationError
+	switch m.Action {
+	case BulkKill, BulkQueue, BulkUntag, BulkRetag:
+	default:
+		errs = append(errs, &FieldError{Field: "Action", Message: fmt.Sprintf("invalid bulk moderation action %q", m.Action)})
+	}
+	if n := len(m.PostIDs) + len(m.CommentIDs); n == 0 {
+		errs = append(errs, &FieldError{Field: "PostIDs", Message: "at least one post or comment is required"})
+	} else if n > MaxBulkModeration {
+		errs = append(errs, &FieldError{Field: "PostIDs", Message: fmt.Sprintf("at most %d posts and comments may be moderated at once", MaxBulkModeration)})
+	}
+	if len(m.CommentIDs) > 0 && m.Action != BulkKill {
+		errs = append(errs, &FieldError{Field: "CommentIDs", Message: fmt.Sprintf("comments can't be moderated with action %q", m.Action)})
+	}
+	if m.Action == BulkUntag && len(m.Tags) == 0 {
+		errs = append(errs, &FieldError{Field: "Tags", Message: "at least one tag to remove is required"})
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// ModerationService interacts with the bulk moderation endpoint in thesrc's
+// API. Only moderators may use it.
+type ModerationService interface {
+	// Bulk takes a moderation action on many posts and comments at once,
+	// recording an audit log entry for each of them (with the reason
+	// given by Client.WithAuditReason, if any).
+	Bulk(mod *BulkModeration) error
+}
+
+type moderationService struct{ client *Client }
+
+func (s *moderationService) Bulk(mod *BulkModeration) error {
+	url, err := s.client.url(router.BulkModerate, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), mod)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+type MockModerationService struct {
+	Bulk_ func(mod *BulkModeration) error
+}
+
+var _ ModerationService = &MockModerationService{}
+
+func (s *MockModerationService) Bulk(mod *BulkModeration) error {
+	if s.Bulk_ == nil {
+		return nil
+	}
+	return s.Bulk_(mod)
+}
@@ -0,0 +1,52 @@
+package thesrc
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestModerationService_Bulk(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.BulkModerate, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"Action":"retag","PostIDs":[1,2],"Tags":["golang"]}`+"\n")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.Moderation.Bulk(&BulkModeration{Action: BulkRetag, PostIDs: []int{1, 2}, Tags: []string{"golang"}})
+	if err != nil {
+		t.Errorf("Moderation.Bulk returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestBulkModeration_Validate(t *testing.T) {
+	tests := []struct {
+		mod   BulkModeration
+		valid bool
+	}{
+		{BulkModeration{Action: BulkKill, PostIDs: []int{1}, CommentIDs: []int{2}}, true},
+		{BulkModeration{Action: BulkRetag, PostIDs: []int{1}}, true},
+		{BulkModeration{Action: BulkUntag, PostIDs: []int{1}, Tags: []string{"go"}}, true},
+		{BulkModeration{Action: "delete", PostIDs: []int{1}}, false},
+		{BulkModeration{Action: BulkKill}, false},
+		{BulkModeration{Action: BulkKill, PostIDs: make([]int, MaxBulkModeration+1)}, false},
+		{BulkModeration{Action: BulkQueue, CommentIDs: []int{1}}, false},
+		{BulkModeration{Action: BulkUntag, PostIDs: []int{1}}, false},
+	}
+	for _, test := range tests {
+		if err := test.mod.Validate(); (err == nil) != test.valid {
+			t.Errorf("%+v: got error %v, want valid == %v", test.mod, err, test.valid)
+		}
+	}
+}
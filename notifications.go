@@ -0,0 +1,222 @@
+package thesrc
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A Notification tells a user that someone replied to their post or comment,
+// or mentioned them.
+type Notification struct {
+	// ID a unique identifier for this notification.
+	ID int `json:",omitempty"`
+
+	// UserID is the ID of the user being notified.
+	UserID int
+
+	// Type is the kind of notification (NotificationReply or
+	// NotificationMention).
+	Type string
+
+	// ActorUserID is the ID of the user who replied or mentioned the user.
+	ActorUserID int
+
+	// ActorLogin is the login of the user with ID ActorUserID. It is only
+	// set in API responses.
+	ActorLogin string `db:"-" json:",omitempty"`
+
+	// PostID is the ID of the post that the reply or mention is on.
+	PostID int
+
+	// PostTitle is the title of the post with ID PostID. It is only set in
+	// API responses.
+	PostTitle string `db:"-" json:",omitempty"`
+
+	// CommentID is the ID of the reply (or of the comment with the mention),
+	// or 0 if the user was mentioned in the body of a post.
+	CommentID int `json:",omitempty"`
+
+	// CreatedAt is when the notification was created.
+	CreatedAt time.Time
+
+	// Read is whether the user has marked the notification as read.
+	Read bool
+}
+
+const (
+	// NotificationReply is the type of notification that a user receives
+	// when someone comments on their post or replies to their comment.
+	NotificationReply = "reply"
+
+	// NotificationMention is the type of notification that a user receives
+	// when someone mentions them (as "@login") in a post or comment.
+	NotificationMention = "mention"
+)
+
+// mentionPattern matches an @mention of a login. The "@" must not follow a
+// letter or number, so that email addresses aren't treated as mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^a-zA-Z0-9_@-])@([a-zA-Z0-9_-]{2,40})\b`)
+
+// Mentions returns the logins that are @mentioned in body (a post or
+// comment body), in the order they first appear and without duplicates.
+// Logins are compared case-insensitively.
+func Mentions(body string) []string {
+	var logins []string
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		if login := m[1]; !seen[strings.ToLower(login)] {
+			seen[strings.ToLower(login)] = true
+			logins = append(logins, login)
+		}
+	}
+	return logins
+}
+
+// NotificationsService interacts with the notification-related endpoints in
+// thesrc's API. It manages the notifications of the user that the client is
+// authenticated as.
+type NotificationsService interface {
+	// List the user's notifications, newest first.
+	List(opt *NotificationListOptions) ([]*Notification, error)
+
+	// UnreadCount returns the number of the user's unread notifications.
+	UnreadCount() (int, error)
+
+	// MarkRead marks one of the user's notifications as read.
+	MarkRead(id int) error
+
+	// MarkAllRead marks all of the user's notifications as read.
+	MarkAllRead() error
+}
+
+type NotificationListOptions struct {
+	// Unread filters the result set to only notifications that haven't been
+	// marked as read.
+	Unread bool `url:",omitempty" json:",omitempty"`
+
+	ListOptions
+}
+
+// NotificationCount is the response of the API's unread notification count
+// endpoint.
+type NotificationCount struct {
+	Unread int
+}
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+)
+
+type notificationsService struct{ client *Client }
+
+func (s *notificationsService) List(opt *NotificationListOptions) ([]*Notification, error) {
+	url, err := s.client.url(router.Notifications, nil, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var notifications []*Notification
+	_, err = s.client.Do(req, &notifications)
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (s *notificationsService) UnreadCount() (int, error) {
+	url, err := s.client.url(router.UnreadNotificationCount, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var count NotificationCount
+	_, err = s.client.Do(req, &count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count.Unread, nil
+}
+
+func (s *notificationsService) MarkRead(id int) error {
+	url, err := s.client.url(router.MarkNotificationRead, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *notificationsService) MarkAllRead() error {
+	url, err := s.client.url(router.MarkAllNotificationsRead, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+type MockNotificationsService struct {
+	List_        func(opt *NotificationListOptions) ([]*Notification, error)
+	UnreadCount_ func() (int, error)
+	MarkRead_    func(id int) error
+	MarkAllRead_ func() error
+}
+
+var _ NotificationsService = &MockNotificationsService{}
+
+func (s *MockNotificationsService) List(opt *NotificationListOptions) ([]*Notification, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
+
+func (s *MockNotificationsService) UnreadCount() (int, error) {
+	if s.UnreadCount_ == nil {
+		return 0, nil
+	}
+	return s.UnreadCount_()
+}
+
+func (s *MockNotificationsService) MarkRead(id int) error {
+	if s.MarkRead_ == nil {
+		return nil
+	}
+	return s.MarkRead_(id)
+}
+
+func (s *MockNotificationsService) MarkAllRead() error {
+	if s.MarkAllRead_ == nil {
+		return nil
+	}
+	return s.MarkAllRead_()
+}
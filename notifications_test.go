@@ -0,0 +1,125 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestNotificationsService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*Notification{{ID: 1, UserID: 2, Type: NotificationReply, ActorUserID: 3, ActorLogin: "bob", PostID: 4, CommentID: 5}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Notifications, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"Unread": "true"})
+
+		writeJSON(w, want)
+	})
+
+	notifications, err := client.Notifications.List(&NotificationListOptions{Unread: true})
+	if err != nil {
+		t.Errorf("Notifications.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, n := range want {
+		normalizeTime(&n.CreatedAt)
+	}
+	if !reflect.DeepEqual(notifications, want) {
+		t.Errorf("Notifications.List returned %+v, want %+v", notifications, want)
+	}
+}
+
+func TestNotificationsService_UnreadCount(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.UnreadNotificationCount, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, NotificationCount{Unread: 3})
+	})
+
+	n, err := client.Notifications.UnreadCount()
+	if err != nil {
+		t.Errorf("Notifications.UnreadCount returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+	if n != 3 {
+		t.Errorf("Notifications.UnreadCount returned %d, want 3", n)
+	}
+}
+
+func TestNotificationsService_MarkRead(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.MarkNotificationRead, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Notifications.MarkRead(1); err != nil {
+		t.Errorf("Notifications.MarkRead returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestNotificationsService_MarkAllRead(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.MarkAllNotificationsRead, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Notifications.MarkAllRead(); err != nil {
+		t.Errorf("Notifications.MarkAllRead returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := map[string][]string{
+		"":                             nil,
+		"@alice":                       {"alice"},
+		"thanks @alice and @Bob_2!":    {"alice", "Bob_2"},
+		"@alice @ALICE":                {"alice"},
+		"mail alice@example.com":       nil,
+		"(@bob) @x":                    {"bob"}, // logins are at least 2 characters
+		"see `code` and\n@carol-d: hi": {"carol-d"},
+	}
+	for body, want := range tests {
+		if got := Mentions(body); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got mentions %q, want %q", body, got, want)
+		}
+	}
+}
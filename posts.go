@@ -1,9 +1,12 @@
 package thesrc
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"sourcegraph.com/sourcegraph/thesrc/router"
@@ -17,12 +20,47 @@ type Post struct {
 	// Title of the post.
 	Title string
 
-	// LinkURL is the URL to a link that this post is about.
+	// LinkURL is the URL to a link that this post is about. It is empty for
+	// self-posts (text posts such as questions), whose content is their Body.
 	LinkURL string
 
-	// Body of the post.
+	// Domain is the canonical domain of LinkURL (see LinkDomain). It is set
+	// when the post is submitted.
+	Domain string `json:",omitempty"`
+
+	// LinkDescription, LinkImageURL, and LinkFaviconURL describe the page at
+	// LinkURL. They are fetched from the page when a post is submitted
+	// without a title (see LinkMetadata).
+	LinkDescription string `json:",omitempty"`
+	LinkImageURL    string `json:",omitempty"`
+	LinkFaviconURL  string `json:",omitempty"`
+
+	// ThumbnailURL is the URL of a small image representing the page at
+	// LinkURL. It is set in the background some time after the post is
+	// submitted (if at all).
+	ThumbnailURL string `json:",omitempty"`
+
+	// LinkStatus is the HTTP status code that LinkURL responded with when
+	// the dead link checker last checked it (0 if it hasn't been checked, or
+	// didn't respond).
+	LinkStatus int `json:",omitempty"`
+
+	// LinkDead is whether the dead link checker found that LinkURL no longer
+	// exists (e.g., it responded with 404 Not Found).
+	LinkDead bool `json:",omitempty"`
+
+	// LinkArchiveURL is the URL of a snapshot of LinkURL in the Internet
+	// Archive's Wayback Machine, if one was saved when the post was
+	// submitted or found when its link died.
+	LinkArchiveURL string `json:",omitempty"`
+
+	// Body of the post, in Markdown.
 	Body string
 
+	// BodyHTML is Body rendered as sanitized HTML. It is only set in API
+	// responses to requests with the RenderBody query parameter.
+	BodyHTML string `db:"-" json:",omitempty"`
+
 	// SubmittedAt is when the post was submitted.
 	SubmittedAt time.Time
 
@@ -32,11 +70,100 @@ type Post struct {
 	// Score in points.
 	Score int
 
+	// Velocity is how quickly the post is gaining traction: the number of
+	// votes and comments (each comment counting as CommentVelocityWeight
+	// votes) it received per hour in the recent past (see SortTrending). It
+	// is updated periodically, not as votes and comments are made.
+	Velocity float64 `json:",omitempty"`
+
 	// Classification is the output of the classifier on this post.
 	Classification string
+
+	// Tags are the (normalized) names of the topics this post is tagged with.
+	Tags []string `db:"-" json:",omitempty"`
+
+	// Voted is whether the user that requested this post has upvoted it. It
+	// is only set for authenticated API requests.
+	Voted bool `db:"-" json:",omitempty"`
+
+	// Saved is whether the user that requested this post has saved it. It
+	// is only set for authenticated API requests.
+	Saved bool `db:"-" json:",omitempty"`
+
+	// HiddenByUser is whether the user that requested this post has hidden
+	// it from their post listings (see PostsService.Hide). It is only set for
+	// authenticated API requests.
+	HiddenByUser bool `db:"-" json:",omitempty"`
+
+	// Flags is the number of users who have flagged this post.
+	Flags int `json:",omitempty"`
+
+	// Hidden is whether this post is hidden from post listings. Posts are
+	// hidden automatically when they receive enough flags, or by a moderator.
+	Hidden bool `json:",omitempty"`
+
+	// Dead is whether this post has been killed by a moderator. Dead posts
+	// are not listed and are only visible to moderators.
+	Dead bool `json:",omitempty"`
+
+	// SpamScore is the score given to this post by the spam filter, if the
+	// filter held it for moderation (in which case it is also hidden).
+	SpamScore float64 `json:",omitempty"`
+
+	// Queued is whether a moderator moved this post to the moderation queue
+	// (see BulkQueue), in which case it is also hidden. It is cleared when
+	// the post is next moderated.
+	Queued bool `json:",omitempty"`
+
+	// Reports is the number of times this post has been reported as abusive
+	// (see Report) since a moderator last moderated it (or killed or queued
+	// it in bulk), which clears it.
+	Reports int `json:",omitempty"`
+
+	// DeletedAt is when this post was deleted (see PostsService.Delete), or
+	// nil if it hasn't been. Deleted posts are only visible to admins, in
+	// lists of deleted posts (see PostListOptions.Deleted).
+	DeletedAt *time.Time `json:",omitempty"`
+
+	// EditedAt is when this post's title or body was last edited, or nil if
+	// it hasn't been. Moderators can see what was edited (see
+	// PostsService.Revisions).
+	EditedAt *time.Time `json:",omitempty"`
+
+	// Version is incremented each time the post is edited or moderated,
+	// starting at 1. Updates must give the version that they are based on,
+	// so that they fail (with ErrPostVersionConflict) instead of overwriting
+	// changes made since (see PostsService.Update).
+	Version int `json:",omitempty"`
+
+	// IdempotencyKey is the key that the post was submitted with (see
+	// IdempotencyKeyHeader), if any. Submitting a post with the same key as
+	// one of the author's previous (undeleted) posts returns the previous
+	// post instead of creating a new one. It is sent in a header, not in
+	// the post's JSON.
+	IdempotencyKey string `json:"-"`
 }
 
+// IdempotencyKeyHeader is the HTTP header in which a client sends a unique
+// key (of at most MaxIdempotencyKeyLength bytes) with a request to submit a
+// post, so that retrying the request (e.g., after a network error) doesn't
+// create a duplicate post.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// PostVersionHeader is the HTTP header in which the API sends a post's
+// Version (with the post), and in which a client may send the version that
+// an edit or moderation of the post is based on (instead of the Version
+// field).
+const PostVersionHeader = "Post-Version"
+
+// MaxIdempotencyKeyLength is the maximum length of an idempotency key.
+const MaxIdempotencyKeyLength = 255
+
 // PostsService interacts with the post-related endpoints in thesrc's API.
+//
+// It is implemented both by the API client (Client.Posts) and by the
+// datastore that the API server uses (see package datastore), so programs
+// such as importers can work with posts either remotely or in-process.
 type PostsService interface {
 	// Get a post.
 	Get(id int) (*Post, error)
@@ -46,15 +173,178 @@ type PostsService interface {
 
 	// Submit a post. If this post's link URL has never been submitted, post.ID
 	// will be a new ID, and created will be true. If it has been submitted
-	// before, post.ID will be the ID of the previous post, and created will be
-	// false.
+	// before (or its author already submitted a post with the same
+	// IdempotencyKey), post.ID will be the ID of the previous post, and
+	// created will be false.
 	Submit(post *Post) (created bool, err error)
+
+	// Preview validates post and returns it as it would be submitted,
+	// without submitting it (for previewing a post before submitting it).
+	Preview(post *Post) (*PostPreview, error)
+
+	// CreateBatch submits up to MaxBatchSize posts at once, in a single
+	// transaction. Each post is submitted (and updated) as by Submit, and the
+	// returned results are in the same order as posts. Invalid posts are rejected
+	// individually (see PostBatchResult.Error); err is non-nil only if the
+	// whole batch failed.
+	CreateBatch(posts []*Post) ([]*PostBatchResult, error)
+
+	// Update a post's title, body, and tags to those of post. (A post's link
+	// URL can't be changed.) post.Version must be the version of the post
+	// that the update is based on: if the post has changed since, Update
+	// fails with ErrPostVersionConflict (or, through the API, an ErrConflict
+	// error). The datastore skips this check if post.Version is 0, but the
+	// API requires it. If successful, post is updated to reflect the updated
+	// post.
+	Update(id int, post *Post) error
+
+	// Delete a post. Deleted posts are no longer visible, but they (and their
+	// comments, votes, flags, and tags) are kept until they are purged after
+	// a retention period, until which an admin may undelete them.
+	Delete(id int) error
+
+	// Undelete restores a deleted post that hasn't been purged yet. Only
+	// admins may undelete posts.
+	Undelete(id int) error
+
+	// Flag a post as inappropriate, as the user that the client is
+	// authenticated as. Flagging a post that the user has already flagged has
+	// no effect.
+	Flag(id int) error
+
+	// Moderate sets a post's moderation status. Only moderators and admins
+	// may moderate posts. If mod.Version is set, Moderate fails (like
+	// Update) if the post has changed since that version.
+	Moderate(id int, mod *PostModeration) error
+
+	// Save a post to the saved posts of the user that the client is
+	// authenticated as (see PostListOptions.Saved). Saving a post that the
+	// user has already saved has no effect.
+	Save(id int) error
+
+	// Unsave removes a post from the authenticated user's saved posts (if
+	// it is there).
+	Unsave(id int) error
+
+	// Hide a post from the post listings of the user that the client is
+	// authenticated as. (Unlike Moderate, this doesn't affect other users.)
+	Hide(id int) error
+
+	// Unhide shows a post that the authenticated user hid in their post
+	// listings again.
+	Unhide(id int) error
+
+	// Related lists the posts most closely related to a post: those that
+	// share its tags, its link's domain, or words in its title. Hidden and
+	// dead posts are omitted.
+	Related(id int, opt *RelatedPostsOptions) ([]*Post, error)
+
+	// Revisions lists the versions of a post's title, link URL, and body,
+	// oldest first, starting with the post as it was submitted. A post that
+	// has never been edited has no revisions. Only moderators and admins may
+	// list revisions.
+	Revisions(id int) ([]*PostRevision, error)
+}
+
+// A PostModeration is the moderation status of a post.
+type PostModeration struct {
+	// Hidden is whether the post is hidden from post listings.
+	Hidden bool
+
+	// Dead is whether the post is killed (hidden from everyone but
+	// moderators).
+	Dead bool
+
+	// Version (if nonzero) is the version of the post that the moderation
+	// is based on (see Post.Version).
+	Version int `json:",omitempty"`
+}
+
+// A PostRevision is a version of a post's title, link URL, and body. A
+// revision is recorded each time a post is edited (see
+// PostsService.Revisions).
+type PostRevision struct {
+	// ID a unique identifier for this revision.
+	ID int
+
+	// PostID is the ID of the post that this is a version of.
+	PostID int
+
+	// Title, LinkURL, and Body are the post's as of this version.
+	Title   string
+	LinkURL string
+	Body    string
+
+	// EditorUserID is the ID of the user who made this version (the post's
+	// author, for the version it was submitted as).
+	EditorUserID int
+
+	// EditorLogin is the login of the user with ID EditorUserID. It is only
+	// set by PostsService.Revisions.
+	EditorLogin string `db:"-" json:",omitempty"`
+
+	// CreatedAt is when this version was made.
+	CreatedAt time.Time
+}
+
+// MaxBatchSize is the maximum number of posts that may be submitted in one
+// call to PostsService.CreateBatch.
+const MaxBatchSize = 100
+
+// A PostPreview is a post as it would be submitted (see
+// PostsService.Preview).
+type PostPreview struct {
+	// Post is the post with its link URL canonicalized (see
+	// CanonicalLinkURL), its tags normalized, its title filled in from its
+	// link's page (if it had none), and its BodyHTML rendered.
+	Post *Post
+
+	// Existing is the post that was already submitted with the same link
+	// URL, if any. If it is set, submitting Post would not create a new post.
+	Existing *Post `json:",omitempty"`
+}
+
+// A PostBatchResult is the outcome of submitting one post of a batch (see
+// PostsService.CreateBatch).
+type PostBatchResult struct {
+	// Post is the submitted post or, if a post with the same link URL was
+	// already submitted, that post. It is nil if the post was rejected.
+	Post *Post `json:",omitempty"`
+
+	// Created is whether the post was newly created.
+	Created bool
+
+	// Error describes why the post was rejected, if it was.
+	Error string `json:",omitempty"`
 }
 
 var (
 	ErrPostNotFound = errors.New("post not found")
+
+	// ErrPostLinkURLTaken is returned when undeleting a post whose link URL
+	// has since been submitted again.
+	ErrPostLinkURLTaken = errors.New("another post has the same link URL")
+
+	// ErrPostVersionConflict is returned when updating or moderating a post
+	// that has changed since the version that the update is based on.
+	ErrPostVersionConflict = errors.New("post was changed by someone else; reload it and try again")
 )
 
+// PostEditWindow is how long after submitting a post that its author may
+// edit or delete it. Admins may edit or delete posts at any time.
+const PostEditWindow = 2 * time.Hour
+
+// CanEditPost returns whether user may edit or delete post (at time now).
+func CanEditPost(user *User, post *Post, now time.Time) bool {
+	if user == nil {
+		return false
+	}
+	if user.HasRole(RoleAdmin) {
+		return true
+	}
+	return post.AuthorUserID != 0 && post.AuthorUserID == user.ID && now.Sub(post.SubmittedAt) < PostEditWindow
+}
+
 type postsService struct{ client *Client }
 
 func (s *postsService) Get(id int) (*Post, error) {
@@ -78,12 +368,186 @@ func (s *postsService) Get(id int) (*Post, error) {
 }
 
 type PostListOptions struct {
-	// CodeOnly filters the result set to only those posts whose links contain code.
+	// CodeOnly filters the result set to only those posts whose links contain
+	// code, and self-posts (which have no links to classify).
 	CodeOnly bool
 
+	// Sort is the order in which to list posts (SortNew, SortTop, SortBest,
+	// or SortTrending). If empty, SortNew is used.
+	Sort string `url:",omitempty" json:",omitempty"`
+
+	// Tag filters the result set to only those posts tagged with Tag.
+	Tag string `url:",omitempty" json:",omitempty"`
+
+	// Domain filters the result set to only those posts whose links are on
+	// this domain (see NormalizeDomain).
+	Domain string `url:",omitempty" json:",omitempty"`
+
+	// LinkURL filters the result set to only the post (if any) with this
+	// link URL.
+	LinkURL string `url:",omitempty" json:",omitempty"`
+
+	// AuthorUserID filters the result set to only those posts submitted by
+	// the user with this ID.
+	AuthorUserID int `url:",omitempty" json:",omitempty"`
+
+	// RenderBody is whether to set the BodyHTML field of each post.
+	RenderBody bool `url:",omitempty" json:",omitempty"`
+
+	// Flagged filters the result set to only those posts that have been
+	// flagged, reported, held by the spam filter, or queued by a moderator
+	// (i.e., the moderation queue), including hidden and dead posts (which are
+	// otherwise omitted). Only moderators may list flagged posts.
+	Flagged bool `url:",omitempty" json:",omitempty"`
+
+	// Deleted filters the result set to only those posts that have been
+	// deleted and not yet purged (which are otherwise omitted). Only admins
+	// may list deleted posts.
+	Deleted bool `url:",omitempty" json:",omitempty"`
+
+	// Saved filters the result set to only those posts that the
+	// authenticated user has saved.
+	Saved bool `url:",omitempty" json:",omitempty"`
+
+	// SavedByUserID filters the result set to only those posts saved by the
+	// user with this ID. It is set by the API server (for Saved lists), not
+	// by clients.
+	SavedByUserID int `url:"-" json:"-" schema:"-"`
+
+	// ExcludeHiddenByUserID omits posts that the user with this ID has hidden
+	// (see PostsService.Hide). It is set by the API server from the request's
+	// authentication, not by clients.
+	ExcludeHiddenByUserID int `url:"-" json:"-" schema:"-"`
+
+	// Personalized is whether to rank the posts about the topics that the
+	// authenticated user follows higher, and those about the topics they
+	// hide lower (see FollowsService). It only affects SortTop lists, and is
+	// ignored for unauthenticated requests.
+	Personalized bool `url:",omitempty" json:",omitempty"`
+
+	// PersonalizedForUserID is the ID of the user whose followed and hidden
+	// topics personalize the ranking. It is set by the API server (for
+	// Personalized lists), not by clients.
+	PersonalizedForUserID int `url:"-" json:"-" schema:"-"`
+
+	// After is a cursor (see PostCursorAfter) that filters the result set to
+	// only those posts after it in the list, for cursor-based pagination. When
+	// it is set, Page is ignored.
+	After string `url:",omitempty" json:",omitempty"`
+
+	// SinceID filters the result set to only those posts whose ID is greater
+	// than SinceID (i.e., that were created after it).
+	SinceID int `url:",omitempty" json:",omitempty"`
+
+	// Show filters the result set to only Show posts (see IsShowPost).
+	Show bool `url:",omitempty" json:",omitempty"`
+
+	// Period filters the result set to only those posts submitted within the
+	// past day, week, month, or year (PeriodDay, PeriodWeek, PeriodMonth, or
+	// PeriodYear), as for "top of the week" listings. If empty or PeriodAll,
+	// posts of any age are listed.
+	Period string `url:",omitempty" json:",omitempty"`
+
+	// Query is a search query (see ParsePostQuery), such as
+	// `author:alice tag:go rust`. The API server translates it into the
+	// AuthorUserID, Domain, Tag, and SearchTerms filters.
+	Query string `url:",omitempty" json:",omitempty"`
+
+	// SearchTerms filters the result set to only those posts whose titles or
+	// bodies contain all of these lowercased terms. It is set by the API
+	// server from Query, not by clients.
+	SearchTerms []string `url:"-" json:"-" schema:"-"`
+
+	// ViewerUserID is the ID of the user viewing the list. Posts by
+	// shadow-banned users are omitted unless they were submitted by the
+	// viewer (or Flagged is set). It is set by the API server from the
+	// request's authentication, not by clients.
+	ViewerUserID int `url:"-" json:"-" schema:"-"`
+
+	ListOptions
+}
+
+// RelatedPostsOptions specifies options for listing related posts (see
+// PostsService.Related).
+type RelatedPostsOptions struct {
+	// ViewerUserID is the ID of the user viewing the list (see
+	// PostListOptions.ViewerUserID). It is set by the API server, not by
+	// clients.
+	ViewerUserID int `url:"-" json:"-" schema:"-"`
+
 	ListOptions
 }
 
+// Sort orders for listing posts.
+const (
+	// SortNew lists the most recently submitted posts first.
+	SortNew = "new"
+
+	// SortTop lists posts by their score, decayed by their age, using the
+	// Hacker News ranking formula: (score-1) / (ageInHours+2)^1.8.
+	SortTop = "top"
+
+	// SortBest lists posts by their score, regardless of their age (so it is
+	// usually combined with a Period).
+	SortBest = "best"
+
+	// SortTrending lists the posts that are gaining votes and comments the
+	// fastest first (see Post.Velocity).
+	SortTrending = "trending"
+)
+
+// CommentVelocityWeight is the number of votes that a comment counts as in a
+// post's Velocity, because commenting takes more engagement than voting.
+const CommentVelocityWeight = 2
+
+// ValidSort returns whether sort is a valid PostListOptions.Sort value.
+func ValidSort(sort string) bool {
+	switch sort {
+	case "", SortNew, SortTop, SortBest, SortTrending:
+		return true
+	}
+	return false
+}
+
+// ShowPostTitlePrefix begins the titles of Show posts, in which users show
+// something they've made.
+const ShowPostTitlePrefix = "Show thesrc:"
+
+// IsShowPost returns whether post is a Show post, i.e., whether its title
+// begins with ShowPostTitlePrefix (in any case).
+func IsShowPost(post *Post) bool {
+	return strings.HasPrefix(strings.ToLower(post.Title), strings.ToLower(ShowPostTitlePrefix))
+}
+
+// Time periods for listing posts (see PostListOptions.Period).
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodYear  = "year"
+	PeriodAll   = "all"
+)
+
+var periodDurations = map[string]time.Duration{
+	PeriodDay:   24 * time.Hour,
+	PeriodWeek:  7 * 24 * time.Hour,
+	PeriodMonth: 30 * 24 * time.Hour,
+	PeriodYear:  365 * 24 * time.Hour,
+}
+
+// ValidPeriod returns whether period is a valid PostListOptions.Period
+// value.
+func ValidPeriod(period string) bool {
+	_, ok := periodDurations[period]
+	return ok || period == "" || period == PeriodAll
+}
+
+// PeriodDuration returns the length of period (a PostListOptions.Period
+// value), or 0 if it is empty, PeriodAll, or invalid.
+func PeriodDuration(period string) time.Duration {
+	return periodDurations[period]
+}
+
 func (s *postsService) List(opt *PostListOptions) ([]*Post, error) {
 	url, err := s.client.url(router.Posts, nil, opt)
 	if err != nil {
@@ -114,6 +578,16 @@ func (s *postsService) Submit(post *Post) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	key := post.IdempotencyKey
+	if key == "" && s.client.MaxRetries > 0 {
+		// Make retries of the request idempotent.
+		if key, err = newIdempotencyKey(); err != nil {
+			return false, err
+		}
+	}
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
 
 	resp, err := s.client.Do(req, &post)
 	if err != nil {
@@ -123,10 +597,254 @@ func (s *postsService) Submit(post *Post) (bool, error) {
 	return resp.StatusCode == http.StatusCreated, nil
 }
 
+// newIdempotencyKey returns a random idempotency key (see
+// IdempotencyKeyHeader).
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *postsService) Preview(post *Post) (*PostPreview, error) {
+	url, err := s.client.url(router.PreviewPost, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), post)
+	if err != nil {
+		return nil, err
+	}
+
+	var preview *PostPreview
+	_, err = s.client.Do(req, &preview)
+	if err != nil {
+		return nil, err
+	}
+
+	return preview, nil
+}
+
+func (s *postsService) CreateBatch(posts []*Post) ([]*PostBatchResult, error) {
+	url, err := s.client.url(router.CreatePostBatch, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), posts)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*PostBatchResult
+	_, err = s.client.Do(req, &results)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, res := range results {
+		if res.Post != nil && i < len(posts) {
+			*posts[i] = *res.Post
+			res.Post = posts[i]
+		}
+	}
+	return results, nil
+}
+
+func (s *postsService) Update(id int, post *Post) error {
+	url, err := s.client.url(router.UpdatePost, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), post)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, &post)
+	return err
+}
+
+func (s *postsService) Delete(id int) error {
+	url, err := s.client.url(router.DeletePost, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *postsService) Undelete(id int) error {
+	url, err := s.client.url(router.UndeletePost, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *postsService) Flag(id int) error {
+	url, err := s.client.url(router.FlagPost, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *postsService) Moderate(id int, mod *PostModeration) error {
+	url, err := s.client.url(router.ModeratePost, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), mod)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *postsService) Save(id int) error {
+	url, err := s.client.url(router.SavePost, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *postsService) Unsave(id int) error {
+	url, err := s.client.url(router.UnsavePost, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *postsService) Hide(id int) error {
+	url, err := s.client.url(router.HidePost, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *postsService) Unhide(id int) error {
+	url, err := s.client.url(router.UnhidePost, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *postsService) Related(id int, opt *RelatedPostsOptions) ([]*Post, error) {
+	url, err := s.client.url(router.RelatedPosts, map[string]string{"ID": strconv.Itoa(id)}, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []*Post
+	_, err = s.client.Do(req, &posts)
+	if err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+func (s *postsService) Revisions(id int) ([]*PostRevision, error) {
+	url, err := s.client.url(router.PostRevisions, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []*PostRevision
+	_, err = s.client.Do(req, &revisions)
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
 type MockPostsService struct {
-	Get_    func(id int) (*Post, error)
-	List_   func(opt *PostListOptions) ([]*Post, error)
-	Submit_ func(post *Post) (bool, error)
+	Get_         func(id int) (*Post, error)
+	List_        func(opt *PostListOptions) ([]*Post, error)
+	Submit_      func(post *Post) (bool, error)
+	Preview_     func(post *Post) (*PostPreview, error)
+	CreateBatch_ func(posts []*Post) ([]*PostBatchResult, error)
+	Update_      func(id int, post *Post) error
+	Delete_      func(id int) error
+	Undelete_    func(id int) error
+	Flag_        func(id int) error
+	Moderate_    func(id int, mod *PostModeration) error
+	Save_        func(id int) error
+	Unsave_      func(id int) error
+	Hide_        func(id int) error
+	Unhide_      func(id int) error
+	Related_     func(id int, opt *RelatedPostsOptions) ([]*Post, error)
+	Revisions_   func(id int) ([]*PostRevision, error)
 }
 
 var _ PostsService = &MockPostsService{}
@@ -151,3 +869,94 @@ func (s *MockPostsService) Submit(post *Post) (bool, error) {
 	}
 	return s.Submit_(post)
 }
+
+func (s *MockPostsService) Preview(post *Post) (*PostPreview, error) {
+	if s.Preview_ == nil {
+		return nil, nil
+	}
+	return s.Preview_(post)
+}
+
+func (s *MockPostsService) CreateBatch(posts []*Post) ([]*PostBatchResult, error) {
+	if s.CreateBatch_ == nil {
+		return nil, nil
+	}
+	return s.CreateBatch_(posts)
+}
+
+func (s *MockPostsService) Update(id int, post *Post) error {
+	if s.Update_ == nil {
+		return nil
+	}
+	return s.Update_(id, post)
+}
+
+func (s *MockPostsService) Delete(id int) error {
+	if s.Delete_ == nil {
+		return nil
+	}
+	return s.Delete_(id)
+}
+
+func (s *MockPostsService) Undelete(id int) error {
+	if s.Undelete_ == nil {
+		return nil
+	}
+	return s.Undelete_(id)
+}
+
+func (s *MockPostsService) Flag(id int) error {
+	if s.Flag_ == nil {
+		return nil
+	}
+	return s.Flag_(id)
+}
+
+func (s *MockPostsService) Moderate(id int, mod *PostModeration) error {
+	if s.Moderate_ == nil {
+		return nil
+	}
+	return s.Moderate_(id, mod)
+}
+
+func (s *MockPostsService) Save(id int) error {
+	if s.Save_ == nil {
+		return nil
+	}
+	return s.Save_(id)
+}
+
+func (s *MockPostsService) Unsave(id int) error {
+	if s.Unsave_ == nil {
+		return nil
+	}
+	return s.Unsave_(id)
+}
+
+func (s *MockPostsService) Hide(id int) error {
+	if s.Hide_ == nil {
+		return nil
+	}
+	return s.Hide_(id)
+}
+
+func (s *MockPostsService) Unhide(id int) error {
+	if s.Unhide_ == nil {
+		return nil
+	}
+	return s.Unhide_(id)
+}
+
+func (s *MockPostsService) Related(id int, opt *RelatedPostsOptions) ([]*Post, error) {
+	if s.Related_ == nil {
+		return nil, nil
+	}
+	return s.Related_(id, opt)
+}
+
+func (s *MockPostsService) Revisions(id int) ([]*PostRevision, error) {
+	if s.Revisions_ == nil {
+		return nil, nil
+	}
+	return s.Revisions_(id)
+}
@@ -1,9 +1,11 @@
 package thesrc
 
 import (
+	"context"
 	"net/http"
 	"reflect"
 	"testing"
+	"time"
 
 	"sourcegraph.com/sourcegraph/thesrc/router"
 )
@@ -105,6 +107,35 @@ func TestPostsService_Submit_new(t *testing.T) {
 	}
 }
 
+func TestPostsService_Preview(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &PostPreview{Post: &Post{Title: "t"}, Existing: &Post{ID: 1, Title: "t"}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.PreviewPost, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		writeJSON(w, want)
+	})
+
+	preview, err := client.Posts.Preview(&Post{Title: "t"})
+	if err != nil {
+		t.Errorf("Posts.Preview returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.Post.SubmittedAt)
+	normalizeTime(&want.Existing.SubmittedAt)
+	if !reflect.DeepEqual(preview, want) {
+		t.Errorf("Posts.Preview returned %+v, want %+v", preview, want)
+	}
+}
+
 func TestPostsService_Submit_existing(t *testing.T) {
 	setup()
 	defer teardown()
@@ -139,3 +170,415 @@ func TestPostsService_Submit_existing(t *testing.T) {
 		t.Errorf("Posts.Submit returned %+v, want %+v", post, want)
 	}
 }
+
+func TestPostsService_Submit_idempotencyKey(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var keys []string
+	mux.HandleFunc(urlPath(t, router.SubmitPost, nil), func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		if len(keys) == 2 {
+			// Fail the first try of the retried request.
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, &Post{ID: 1})
+	})
+
+	if _, err := client.Posts.Submit(&Post{Title: "t", IdempotencyKey: "k"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys are generated for requests that may be retried.
+	c := client.WithContext(context.Background())
+	c.MaxRetries, c.RetryBackoff = 1, time.Millisecond
+	if _, err := c.Posts.Submit(&Post{Title: "t"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Posts.Submit(&Post{Title: "t"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 4 {
+		t.Fatalf("got %d requests, want 4", len(keys))
+	}
+	if keys[0] != "k" {
+		t.Errorf("got key %q, want %q", keys[0], "k")
+	}
+	if keys[1] == "" || keys[2] != keys[1] {
+		t.Errorf("got keys %q and %q for a retried request, want the same generated key", keys[1], keys[2])
+	}
+	if keys[3] != "" {
+		t.Errorf("got key %q for a request without retries, want none", keys[3])
+	}
+}
+
+func TestPostsService_CreateBatch(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*PostBatchResult{
+		{Post: &Post{ID: 1, Title: "a"}, Created: true},
+		{Error: "invalid"},
+	}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.CreatePostBatch, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `[{"Title":"a","LinkURL":"","Body":"","SubmittedAt":"0001-01-01T00:00:00Z","AuthorUserID":0,"Score":0,"Classification":""},{"Title":"b","LinkURL":"","Body":"","SubmittedAt":"0001-01-01T00:00:00Z","AuthorUserID":0,"Score":0,"Classification":""}]`+"\n")
+
+		writeJSON(w, want)
+	})
+
+	posts := []*Post{{Title: "a"}, {Title: "b"}}
+	results, err := client.Posts.CreateBatch(posts)
+	if err != nil {
+		t.Errorf("Posts.CreateBatch returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, res := range results {
+		if res.Post != nil {
+			normalizeTime(&res.Post.SubmittedAt)
+		}
+	}
+	normalizeTime(&want[0].Post.SubmittedAt)
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Posts.CreateBatch returned %+v, want %+v", results, want)
+	}
+	if posts[0].ID != 1 {
+		t.Errorf("got post ID %d, want the submitted post to be updated with ID 1", posts[0].ID)
+	}
+}
+
+func TestPostsService_List_sort(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Posts, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"CodeOnly": "false", "Sort": "top"})
+
+		writeJSON(w, []*Post{})
+	})
+
+	_, err := client.Posts.List(&PostListOptions{Sort: SortTop})
+	if err != nil {
+		t.Errorf("Posts.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestPostsService_Update(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Post{ID: 1, Title: "t2"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.UpdatePost, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+
+		writeJSON(w, want)
+	})
+
+	post := &Post{Title: "t2"}
+	if err := client.Posts.Update(1, post); err != nil {
+		t.Errorf("Posts.Update returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.SubmittedAt)
+	if !reflect.DeepEqual(post, want) {
+		t.Errorf("Posts.Update returned %+v, want %+v", post, want)
+	}
+}
+
+func TestPostsService_Delete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.DeletePost, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Posts.Delete(1); err != nil {
+		t.Errorf("Posts.Delete returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestPostsService_Undelete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.UndeletePost, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Posts.Undelete(1); err != nil {
+		t.Errorf("Posts.Undelete returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestPostsService_Flag(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.FlagPost, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Posts.Flag(1); err != nil {
+		t.Errorf("Posts.Flag returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestPostsService_Save(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.SavePost, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Posts.Save(1); err != nil {
+		t.Errorf("Posts.Save returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestPostsService_Unsave(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.UnsavePost, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Posts.Unsave(1); err != nil {
+		t.Errorf("Posts.Unsave returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestPostsService_Hide(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.HidePost, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Posts.Hide(1); err != nil {
+		t.Errorf("Posts.Hide returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestPostsService_Unhide(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.UnhidePost, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Posts.Unhide(1); err != nil {
+		t.Errorf("Posts.Unhide returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestPostsService_Related(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*Post{{ID: 2}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.RelatedPosts, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"PerPage": "5"})
+
+		writeJSON(w, want)
+	})
+
+	posts, err := client.Posts.Related(1, &RelatedPostsOptions{ListOptions: ListOptions{PerPage: 5}})
+	if err != nil {
+		t.Errorf("Posts.Related returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, p := range want {
+		normalizeTime(&p.SubmittedAt)
+	}
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("Posts.Related returned %+v, want %+v", posts, want)
+	}
+}
+
+func TestPostsService_Moderate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.ModeratePost, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+		testBody(t, r, `{"Hidden":true,"Dead":false}`+"\n")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Posts.Moderate(1, &PostModeration{Hidden: true}); err != nil {
+		t.Errorf("Posts.Moderate returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestCanEditPost(t *testing.T) {
+	now := time.Now()
+	post := &Post{AuthorUserID: 1, SubmittedAt: now.Add(-time.Hour)}
+	oldPost := &Post{AuthorUserID: 1, SubmittedAt: now.Add(-PostEditWindow - time.Minute)}
+	anonPost := &Post{SubmittedAt: now}
+
+	tests := []struct {
+		user *User
+		post *Post
+		want bool
+	}{
+		{nil, post, false},
+		{&User{ID: 1}, post, true},
+		{&User{ID: 2}, post, false},
+		{&User{ID: 1}, oldPost, false},
+		{&User{ID: 2, Role: RoleAdmin}, oldPost, true},
+		{&User{ID: 2, Role: RoleModerator}, oldPost, false},
+		{&User{ID: 0}, anonPost, false},
+	}
+	for _, test := range tests {
+		if got := CanEditPost(test.user, test.post, now); got != test.want {
+			t.Errorf("CanEditPost(%+v, %+v): got %v, want %v", test.user, test.post, got, test.want)
+		}
+	}
+}
+
+func TestIsShowPost(t *testing.T) {
+	tests := map[string]bool{
+		"Show thesrc: my parser": true,
+		"show THESRC: my parser": true,
+		"Show thesrc my parser":  false,
+		"My parser":              false,
+		"":                       false,
+	}
+	for title, want := range tests {
+		if got := IsShowPost(&Post{Title: title}); got != want {
+			t.Errorf("%q: got %v, want %v", title, got, want)
+		}
+	}
+}
+
+func TestPostsService_Revisions(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*PostRevision{{ID: 1, PostID: 1, Title: "t"}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.PostRevisions, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, want)
+	})
+
+	revisions, err := client.Posts.Revisions(1)
+	if err != nil {
+		t.Errorf("Posts.Revisions returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, rev := range want {
+		normalizeTime(&rev.CreatedAt)
+	}
+	if !reflect.DeepEqual(revisions, want) {
+		t.Errorf("Posts.Revisions returned %+v, want %+v", revisions, want)
+	}
+}
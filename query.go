@@ -0,0 +1,115 @@
+package thesrc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// A PostQuery is a parsed post search query (see ParsePostQuery).
+type PostQuery struct {
+	// Author is the login of the user who submitted the posts (author:alice).
+	Author string
+
+	// Domain is the domain of the posts' links (domain:golang.org), in the
+	// form returned by NormalizeDomain.
+	Domain string
+
+	// Tag is a tag of the posts (tag:go), in the form returned by
+	// NormalizeTag.
+	Tag string
+
+	// Terms are the lowercased words and phrases that the posts' titles or
+	// bodies must all contain.
+	Terms []string
+}
+
+// ParsePostQuery parses a post search query, such as
+//
+//	author:alice domain:golang.org tag:go rust "error handling"
+//
+// Each of the author:, domain:, and tag: qualifiers may appear at most once.
+// The other words, and double-quoted phrases, are search terms.
+func ParsePostQuery(q string) (*PostQuery, error) {
+	tokens, err := splitQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	var pq PostQuery
+	for _, tok := range tokens {
+		i := strings.Index(tok.text, ":")
+		if tok.quoted || i == -1 {
+			pq.Terms = append(pq.Terms, strings.ToLower(tok.text))
+			continue
+		}
+
+		key, value := strings.ToLower(tok.text[:i]), tok.text[i+1:]
+		var field *string
+		switch key {
+		case "author":
+			field = &pq.Author
+		case "domain":
+			field = &pq.Domain
+			value = NormalizeDomain(value)
+		case "tag":
+			field = &pq.Tag
+			if value != "" {
+				if value, err = NormalizeTag(value); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			// Not a qualifier (e.g., "C++:" or a URL).
+			pq.Terms = append(pq.Terms, strings.ToLower(tok.text))
+			continue
+		}
+		if value == "" {
+			return nil, fmt.Errorf("%s: must have a value", key)
+		}
+		if *field != "" {
+			return nil, fmt.Errorf("%s: may appear only once", key)
+		}
+		*field = value
+	}
+	return &pq, nil
+}
+
+type queryToken struct {
+	text   string
+	quoted bool // whether any part of text was double-quoted
+}
+
+// splitQuery splits q into whitespace-separated tokens, keeping
+// double-quoted phrases (which may contain whitespace) together.
+func splitQuery(q string) ([]queryToken, error) {
+	var (
+		tokens  []queryToken
+		cur     strings.Builder
+		quoted  bool
+		inQuote bool
+	)
+	flush := func() {
+		if strings.TrimSpace(cur.String()) != "" {
+			tokens = append(tokens, queryToken{cur.String(), quoted})
+		}
+		cur.Reset()
+		quoted = false
+	}
+	for _, c := range q {
+		switch {
+		case c == '"':
+			quoted, inQuote = true, !inQuote
+		case unicode.IsSpace(c) && !inQuote:
+			flush()
+		default:
+			cur.WriteRune(c)
+		}
+	}
+	if inQuote {
+		return nil, errors.New("unterminated quoted phrase")
+	}
+	flush()
+	return tokens, nil
+}
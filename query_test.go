@@ -0,0 +1,38 @@
+package thesrc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePostQuery(t *testing.T) {
+	tests := map[string]*PostQuery{
+		"":            {},
+		"rust":        {Terms: []string{"rust"}},
+		"  Rust  Go ": {Terms: []string{"rust", "go"}},
+		`author:alice domain:Golang.org tag:Go rust "Error  handling"`: {
+			Author: "alice",
+			Domain: "golang.org",
+			Tag:    "go",
+			Terms:  []string{"rust", "error  handling"},
+		},
+		`"tag:go" http://example.com c++:`: {Terms: []string{"tag:go", "http://example.com", "c++:"}},
+		`Tag:go`:                           {Tag: "go"},
+	}
+	for q, want := range tests {
+		got, err := ParsePostQuery(q)
+		if err != nil {
+			t.Errorf("%q: %s", q, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got %+v, want %+v", q, got, want)
+		}
+	}
+
+	for _, q := range []string{`tag:`, `tag:go tag:rust`, `author:a author:b`, `"unterminated`} {
+		if _, err := ParsePostQuery(q); err == nil {
+			t.Errorf("%q: got no error, want error", q)
+		}
+	}
+}
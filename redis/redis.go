@@ -0,0 +1,393 @@
+// Package redis is a minimal Redis client, with just the commands and
+// pub/sub that thesrc uses to share state (such as sessions and rate limits)
+// between the servers of a deployment.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is the default value of Client.Timeout.
+const DefaultTimeout = 5 * time.Second
+
+// maxIdleConns is the number of idle connections that a client keeps open
+// for reuse.
+const maxIdleConns = 16
+
+// A Client sends commands to a Redis server. It is safe for concurrent use,
+// and it reuses connections.
+type Client struct {
+	// Addr is the host:port address of the server.
+	Addr string
+
+	// Password (if set) authenticates to the server.
+	Password string
+
+	// DB is the number of the database to select.
+	DB int
+
+	// Timeout is how long connecting or a command may take, unless the
+	// command's context has an earlier deadline. If 0, DefaultTimeout is
+	// used.
+	Timeout time.Duration
+
+	mu   sync.Mutex
+	idle []*conn
+}
+
+// New returns a client for the server at rawurl, which has the form
+// redis://[:password@]host[:port][/db].
+func New(rawurl string) (*Client, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("redis: URL %q must begin with redis://", rawurl)
+	}
+
+	c := &Client{Addr: u.Host}
+	if u.Port() == "" {
+		c.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.Password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if c.DB, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("redis: invalid database %q in URL", db)
+		}
+	}
+	return c, nil
+}
+
+// An Error is an error reply from the server.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// ErrNil is returned by the reply conversion functions (such as String) for
+// a null reply, such as the reply to GET for a key that doesn't exist.
+var ErrNil = errors.New("redis: nil reply")
+
+// Do sends a command (such as "GET", "key") and returns the server's reply:
+// a string (for simple strings), []byte (for bulk strings), int64,
+// []interface{} (for arrays), or nil. An error reply is returned as an
+// Error. Arguments may be strings, []byte, ints, int64s, or float64s.
+func (c *Client) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := cn.do(ctx, c.timeout(), args)
+	c.put(cn, err)
+	return reply, err
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout == 0 {
+		return DefaultTimeout
+	}
+	return c.Timeout
+}
+
+// get returns an idle connection, or a new one.
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return cn, nil
+	}
+	c.mu.Unlock()
+	return c.dial(ctx)
+}
+
+// put returns cn to the idle pool after a command that returned err. If
+// the connection may be broken, it is closed instead.
+func (c *Client) put(cn *conn, err error) {
+	if _, ok := err.(Error); err != nil && !ok {
+		cn.Close()
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.idle) >= maxIdleConns {
+		cn.Close()
+		return
+	}
+	c.idle = append(c.idle, cn)
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	d := net.Dialer{Timeout: c.timeout()}
+	nc, err := d.DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+	if c.Password != "" {
+		if _, err := cn.do(ctx, c.timeout(), []interface{}{"AUTH", c.Password}); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.DB != 0 {
+		if _, err := cn.do(ctx, c.timeout(), []interface{}{"SELECT", c.DB}); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// A Message is a message received by a Subscription.
+type Message struct {
+	Channel string
+	Data    []byte
+}
+
+// A Subscription receives the messages published to a channel. It holds a
+// connection of its own, which is closed by Close.
+type Subscription struct {
+	cn *conn
+}
+
+// Subscribe subscribes to channel.
+func (c *Client) Subscribe(ctx context.Context, channel string) (*Subscription, error) {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The reply to SUBSCRIBE is a confirmation, in the same form as a
+	// message.
+	reply, err := cn.do(ctx, c.timeout(), []interface{}{"SUBSCRIBE", channel})
+	if err != nil {
+		cn.Close()
+		return nil, err
+	}
+	if v, ok := reply.([]interface{}); !ok || len(v) != 3 || !isString(v[0], "subscribe") {
+		cn.Close()
+		return nil, fmt.Errorf("redis: unexpected reply to SUBSCRIBE: %v", reply)
+	}
+	// Messages may take any amount of time to arrive.
+	cn.SetDeadline(time.Time{})
+	return &Subscription{cn: cn}, nil
+}
+
+// Receive waits for the next message. It returns an error once the
+// subscription is closed or its connection is lost.
+func (s *Subscription) Receive() (*Message, error) {
+	for {
+		reply, err := readReply(s.cn.r)
+		if err != nil {
+			return nil, err
+		}
+		v, ok := reply.([]interface{})
+		if !ok || len(v) != 3 || !isString(v[0], "message") {
+			// Skip other notifications, such as pong replies.
+			continue
+		}
+		channel, _ := String(v[1], nil)
+		data, _ := Bytes(v[2], nil)
+		return &Message{Channel: channel, Data: data}, nil
+	}
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() error {
+	return s.cn.Close()
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func (cn *conn) do(ctx context.Context, timeout time.Duration, args []interface{}) (interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	cn.SetDeadline(deadline)
+
+	if err := writeCommand(cn.w, args); err != nil {
+		return nil, err
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, err
+	}
+	reply, err := readReply(cn.r)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(Error); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// writeCommand writes args as an array of bulk strings, which is how
+// commands are sent.
+func writeCommand(w *bufio.Writer, args []interface{}) error {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var s string
+		switch arg := arg.(type) {
+		case string:
+			s = arg
+		case []byte:
+			s = string(arg)
+		case int:
+			s = strconv.Itoa(arg)
+		case int64:
+			s = strconv.FormatInt(arg, 10)
+		case float64:
+			s = strconv.FormatFloat(arg, 'f', -1, 64)
+		default:
+			return fmt.Errorf("redis: unsupported argument type %T", arg)
+		}
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+	}
+	return nil
+}
+
+// readReply reads a reply. Error replies are returned as an Error value
+// (not as an error), because they may be elements of an array.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply line %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		v := make([]interface{}, n)
+		for i := range v {
+			if v[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("redis: malformed reply line %q", line)
+}
+
+func isString(reply interface{}, s string) bool {
+	got, err := String(reply, nil)
+	return err == nil && got == s
+}
+
+// String converts a reply to a string. It may be used to wrap a call to
+// Do, as in String(c.Do(ctx, "GET", key)).
+func String(reply interface{}, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	switch reply := reply.(type) {
+	case string:
+		return reply, nil
+	case []byte:
+		return string(reply), nil
+	case int64:
+		return strconv.FormatInt(reply, 10), nil
+	case nil:
+		return "", ErrNil
+	}
+	return "", fmt.Errorf("redis: unexpected reply type %T for string", reply)
+}
+
+// Bytes converts a reply to a []byte, like String.
+func Bytes(reply interface{}, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch reply := reply.(type) {
+	case []byte:
+		return reply, nil
+	case string:
+		return []byte(reply), nil
+	case nil:
+		return nil, ErrNil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply type %T for bytes", reply)
+}
+
+// Int64 converts a reply to an int64, like String.
+func Int64(reply interface{}, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch reply := reply.(type) {
+	case int64:
+		return reply, nil
+	case []byte:
+		return strconv.ParseInt(string(reply), 10, 64)
+	case string:
+		return strconv.ParseInt(reply, 10, 64)
+	case nil:
+		return 0, ErrNil
+	}
+	return 0, fmt.Errorf("redis: unexpected reply type %T for integer", reply)
+}
+
+// Strings converts an array reply to a []string, like String. Null elements
+// (such as the values of missing keys in the reply to MGET) are "".
+func Strings(reply interface{}, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	v, ok := reply.([]interface{})
+	if !ok {
+		if reply == nil {
+			return nil, ErrNil
+		}
+		return nil, fmt.Errorf("redis: unexpected reply type %T for array", reply)
+	}
+	ss := make([]string, len(v))
+	for i, e := range v {
+		if e == nil {
+			continue
+		}
+		if ss[i], err = String(e, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ss, nil
+}
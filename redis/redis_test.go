@@ -0,0 +1,205 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is a Redis server that supports a few commands, for testing the
+// client.
+type fakeServer struct {
+	ln       net.Listener
+	password string
+
+	mu   sync.Mutex
+	data map[string]string
+	subs map[string][]*bufio.Writer
+}
+
+func newFakeServer(t *testing.T, password string) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln, password: password, data: map[string]string{}, subs: map[string][]*bufio.Writer{}}
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(nc)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) serve(nc net.Conn) {
+	defer nc.Close()
+	r, w := bufio.NewReader(nc), bufio.NewWriter(nc)
+	authed := s.password == ""
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]interface{}) {
+			args = append(args, string(arg.([]byte)))
+		}
+
+		s.mu.Lock()
+		switch {
+		case args[0] == "AUTH":
+			if authed = args[1] == s.password; authed {
+				fmt.Fprint(w, "+OK\r\n")
+			} else {
+				fmt.Fprint(w, "-WRONGPASS invalid password\r\n")
+			}
+		case !authed:
+			fmt.Fprint(w, "-NOAUTH Authentication required.\r\n")
+		case args[0] == "PING":
+			fmt.Fprint(w, "+PONG\r\n")
+		case args[0] == "SET":
+			s.data[args[1]] = args[2]
+			fmt.Fprint(w, "+OK\r\n")
+		case args[0] == "GET":
+			if v, ok := s.data[args[1]]; ok {
+				fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+			} else {
+				fmt.Fprint(w, "$-1\r\n")
+			}
+		case args[0] == "INCR":
+			n, _ := strconv.Atoi(s.data[args[1]])
+			s.data[args[1]] = strconv.Itoa(n + 1)
+			fmt.Fprintf(w, ":%d\r\n", n+1)
+		case args[0] == "MGET":
+			fmt.Fprintf(w, "*%d\r\n", len(args)-1)
+			for _, key := range args[1:] {
+				if v, ok := s.data[key]; ok {
+					fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+				} else {
+					fmt.Fprint(w, "$-1\r\n")
+				}
+			}
+		case args[0] == "SUBSCRIBE":
+			s.subs[args[1]] = append(s.subs[args[1]], w)
+			fmt.Fprintf(w, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(args[1]), args[1])
+		case args[0] == "PUBLISH":
+			for _, sw := range s.subs[args[1]] {
+				fmt.Fprintf(sw, "*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(args[1]), args[1], len(args[2]), args[2])
+				sw.Flush()
+			}
+			fmt.Fprintf(w, ":%d\r\n", len(s.subs[args[1]]))
+		default:
+			fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		w.Flush()
+		s.mu.Unlock()
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := map[string]*Client{
+		"redis://localhost":                  {Addr: "localhost:6379"},
+		"redis://:secret@example.com:6380/2": {Addr: "example.com:6380", Password: "secret", DB: 2},
+	}
+	for rawurl, want := range tests {
+		c, err := New(rawurl)
+		if err != nil {
+			t.Errorf("%s: %s", rawurl, err)
+			continue
+		}
+		if c.Addr != want.Addr || c.Password != want.Password || c.DB != want.DB {
+			t.Errorf("%s: got %+v, want %+v", rawurl, c, want)
+		}
+	}
+
+	for _, bad := range []string{"http://localhost", "redis://localhost/x"} {
+		if _, err := New(bad); err == nil {
+			t.Errorf("%s: got no error", bad)
+		}
+	}
+}
+
+func TestClient_Do(t *testing.T) {
+	s := newFakeServer(t, "secret")
+	defer s.ln.Close()
+	c := &Client{Addr: s.ln.Addr().String(), Password: "secret"}
+	ctx := context.Background()
+
+	if reply, err := c.Do(ctx, "PING"); err != nil || reply != "PONG" {
+		t.Errorf("PING: got %v (error %v), want PONG", reply, err)
+	}
+	if _, err := c.Do(ctx, "SET", "k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := String(c.Do(ctx, "GET", "k")); err != nil || v != "v" {
+		t.Errorf("GET: got %q (error %v), want %q", v, err, "v")
+	}
+	if _, err := String(c.Do(ctx, "GET", "missing")); err != ErrNil {
+		t.Errorf("GET missing key: got error %v, want %v", err, ErrNil)
+	}
+	if n, err := Int64(c.Do(ctx, "INCR", "n")); err != nil || n != 1 {
+		t.Errorf("INCR: got %d (error %v), want 1", n, err)
+	}
+	if v, err := Strings(c.Do(ctx, "MGET", "k", "missing", "n")); err != nil || !reflect.DeepEqual(v, []string{"v", "", "1"}) {
+		t.Errorf("MGET: got %q (error %v)", v, err)
+	}
+
+	// Error replies are returned as errors, and the connection is reused.
+	if _, err := c.Do(ctx, "BOGUS", 1, int64(2), 3.5); err == nil {
+		t.Error("got no error for unknown command")
+	} else if _, ok := err.(Error); !ok {
+		t.Errorf("got error %v (%T), want an Error", err, err)
+	}
+	if n := len(c.idle); n != 1 {
+		t.Errorf("got %d idle connections, want 1", n)
+	}
+}
+
+func TestClient_Do_badPassword(t *testing.T) {
+	s := newFakeServer(t, "secret")
+	defer s.ln.Close()
+	c := &Client{Addr: s.ln.Addr().String(), Password: "wrong"}
+
+	if _, err := c.Do(context.Background(), "PING"); err == nil {
+		t.Error("got no error with wrong password")
+	}
+}
+
+func TestSubscription(t *testing.T) {
+	s := newFakeServer(t, "")
+	defer s.ln.Close()
+	c := &Client{Addr: s.ln.Addr().String(), Timeout: time.Second}
+	ctx := context.Background()
+
+	sub, err := c.Subscribe(ctx, "ch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	if _, err := c.Do(ctx, "PUBLISH", "ch", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := sub.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Channel != "ch" || string(msg.Data) != "hello" {
+		t.Errorf("got message %+v, want %q on %q", msg, "hello", "ch")
+	}
+
+	sub.Close()
+	if _, err := sub.Receive(); err == nil {
+		t.Error("got no error receiving after Close")
+	}
+}
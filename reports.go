@@ -0,0 +1,145 @@
+package thesrc
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A Report is a report that a post is abusive. Unlike flags, reports may be
+// made by anyone, even visitors without an account, so each client may only
+// make a few of them (see the "thesrc serve -report-rate-limit" flag).
+// Reported posts are listed in the moderation queue.
+type Report struct {
+	// ID a unique identifier for this report.
+	ID int `json:",omitempty"`
+
+	// PostID is the ID of the reported post.
+	PostID int
+
+	// Category is what the post was reported for (one of the Report*
+	// constants).
+	Category string
+
+	// Details is what the reporter wrote about the post, if anything.
+	Details string `json:",omitempty"`
+
+	// ClientHash is a keyed hash of the reporter's IP address, used to limit
+	// how many reports each client may make. It is never sent to clients.
+	ClientHash string `json:"-"`
+
+	// CreatedAt is when the post was reported.
+	CreatedAt time.Time
+}
+
+// Report categories (see Report.Category).
+const (
+	ReportSpam       = "spam"
+	ReportHarassment = "harassment"
+	ReportIllegal    = "illegal"
+	ReportOffTopic   = "off-topic"
+	ReportOther      = "other"
+)
+
+// ReportCategories lists the report categories, in the order that the app
+// shows them.
+var ReportCategories = []string{ReportSpam, ReportHarassment, ReportIllegal, ReportOffTopic, ReportOther}
+
+// ValidReportCategory returns whether category is a valid Report.Category
+// value.
+func ValidReportCategory(category string) bool {
+	for _, c := range ReportCategories {
+		if category == c {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// ErrReportRateLimited is returned when a client has made too many
+	// reports recently to make another.
+	ErrReportRateLimited = errors.New("too many reports; try again later")
+)
+
+// MaxReportDetailsLength is the maximum length (in bytes) of a report's
+// Details.
+const MaxReportDetailsLength = 1000
+
+// ReportsService interacts with the abuse report endpoints in thesrc's API.
+type ReportsService interface {
+	// Create reports the post with ID report.PostID. It doesn't require
+	// authentication.
+	Create(report *Report) error
+
+	// List reports, newest first. Only moderators may list reports.
+	List(opt *ReportListOptions) ([]*Report, error)
+}
+
+type ReportListOptions struct {
+	// PostID (if set) filters the result set to reports of the post with
+	// this ID.
+	PostID int `url:",omitempty" json:",omitempty"`
+
+	ListOptions
+}
+
+type reportsService struct{ client *Client }
+
+func (s *reportsService) Create(report *Report) error {
+	url, err := s.client.url(router.ReportPost, map[string]string{"ID": strconv.Itoa(report.PostID)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), report)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, report)
+	return err
+}
+
+func (s *reportsService) List(opt *ReportListOptions) ([]*Report, error) {
+	url, err := s.client.url(router.Reports, nil, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []*Report
+	_, err = s.client.Do(req, &reports)
+	if err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+type MockReportsService struct {
+	Create_ func(report *Report) error
+	List_   func(opt *ReportListOptions) ([]*Report, error)
+}
+
+var _ ReportsService = &MockReportsService{}
+
+func (s *MockReportsService) Create(report *Report) error {
+	if s.Create_ == nil {
+		return nil
+	}
+	return s.Create_(report)
+}
+
+func (s *MockReportsService) List(opt *ReportListOptions) ([]*Report, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
@@ -0,0 +1,82 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestReportsService_Create(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Report{ID: 3, PostID: 1, Category: ReportSpam, Details: "ads"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.ReportPost, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, want)
+	})
+
+	report := &Report{PostID: 1, Category: ReportSpam, Details: "ads"}
+	if err := client.Reports.Create(report); err != nil {
+		t.Errorf("Reports.Create returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.CreatedAt)
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Reports.Create returned %+v, want %+v", report, want)
+	}
+}
+
+func TestReportsService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*Report{{ID: 1, PostID: 2, Category: ReportHarassment}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Reports, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"PostID": "2"})
+
+		writeJSON(w, want)
+	})
+
+	reports, err := client.Reports.List(&ReportListOptions{PostID: 2})
+	if err != nil {
+		t.Errorf("Reports.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, r := range want {
+		normalizeTime(&r.CreatedAt)
+	}
+	if !reflect.DeepEqual(reports, want) {
+		t.Errorf("Reports.List returned %+v, want %+v", reports, want)
+	}
+}
+
+func TestValidReportCategory(t *testing.T) {
+	for _, c := range ReportCategories {
+		if !ValidReportCategory(c) {
+			t.Errorf("%q: got invalid, want valid", c)
+		}
+	}
+	if ValidReportCategory("boring") {
+		t.Error(`"boring": got valid, want invalid`)
+	}
+}
@@ -2,10 +2,112 @@ package router
 
 import "github.com/gorilla/mux"
 
+// API-only routes
+const (
+	Authenticate = "user:authenticate"
+	CurrentUser  = "user:current"
+	Unfurl       = "link:unfurl"
+	Live         = "live"
+	PostsStream  = "posts:stream"
+
+	CreatePostBatch = "post:create-batch"
+	PreviewPost     = "post:preview"
+	RelatedPosts    = "post:related"
+
+	Domain = "domain"
+
+	UnreadNotificationCount = "notifications:unread-count"
+
+	GraphQL = "graphql"
+
+	Webhooks          = "webhooks"
+	CreateWebhook     = "webhook:create"
+	DeleteWebhook     = "webhook:delete"
+	WebhookDeliveries = "webhook:deliveries"
+
+	SiteStatus       = "site:status"
+	UpdateSiteStatus = "site:update-status"
+
+	OpenAPISpec = "openapi"
+	APIDocs     = "docs"
+)
+
 func API() *mux.Router {
 	m := mux.NewRouter()
 	m.Path("/posts").Methods("GET").Name(Posts)
 	m.Path("/posts").Methods("POST").Name(SubmitPost)
+	m.Path("/posts/stream").Methods("GET").Name(PostsStream)
+	m.Path("/posts/batch").Methods("POST").Name(CreatePostBatch)
+	m.Path("/posts/preview").Methods("POST").Name(PreviewPost)
+	m.Path("/posts/{ID:.+}/comments").Methods("GET").Name(PostComments)
+	m.Path("/posts/{ID:.+}/vote").Methods("PUT").Name(Upvote)
+	m.Path("/posts/{ID:.+}/vote").Methods("DELETE").Name(Unvote)
+	m.Path("/posts/{ID:.+}/flag").Methods("PUT").Name(FlagPost)
+	m.Path("/posts/{ID:.+}/moderation").Methods("PUT").Name(ModeratePost)
+	m.Path("/posts/{ID:.+}/reports").Methods("POST").Name(ReportPost)
+	m.Path("/posts/{ID:.+}/undelete").Methods("PUT").Name(UndeletePost)
+	m.Path("/posts/{ID:.+}/save").Methods("PUT").Name(SavePost)
+	m.Path("/posts/{ID:.+}/save").Methods("DELETE").Name(UnsavePost)
+	m.Path("/posts/{ID:.+}/hide").Methods("PUT").Name(HidePost)
+	m.Path("/posts/{ID:.+}/hide").Methods("DELETE").Name(UnhidePost)
+	m.Path("/posts/{ID:.+}/related").Methods("GET").Name(RelatedPosts)
+	m.Path("/posts/{ID:.+}/revisions").Methods("GET").Name(PostRevisions)
 	m.Path("/posts/{ID:.+}").Methods("GET").Name(Post)
+	m.Path("/posts/{ID:.+}").Methods("PUT").Name(UpdatePost)
+	m.Path("/posts/{ID:.+}").Methods("DELETE").Name(DeletePost)
+	m.Path("/comments").Methods("GET").Name(Comments)
+	m.Path("/comments").Methods("POST").Name(CreateComment)
+	m.Path("/comments/{ID:.+}/vote").Methods("PUT").Name(UpvoteComment)
+	m.Path("/comments/{ID:.+}/vote").Methods("DELETE").Name(UnvoteComment)
+	m.Path("/comments/{ID:.+}").Methods("GET").Name(Comment)
+	m.Path("/users").Methods("POST").Name(Signup)
+	m.Path("/users/{Login}/shadow-ban").Methods("PUT").Name(ShadowBanUser)
+	m.Path("/users/{Login}").Methods("GET").Name(User)
+	m.Path("/user").Methods("GET").Name(CurrentUser)
+	m.Path("/user").Methods("PUT").Name(UpdateUserSettings)
+	m.Path("/user/password").Methods("PUT").Name(ChangePassword)
+	m.Path("/password-reset").Methods("POST").Name(RequestPasswordReset)
+	m.Path("/password-reset").Methods("PUT").Name(ResetPassword)
+	m.Path("/user/two-factor").Methods("PUT").Name(EnableTwoFactor)
+	m.Path("/user/two-factor").Methods("DELETE").Name(DisableTwoFactor)
+	m.Path("/user/two-factor/setup").Methods("POST").Name(SetUpTwoFactor)
+	m.Path("/user/two-factor/recovery-codes").Methods("POST").Name(RegenerateRecoveryCodes)
+	m.Path("/user/sessions").Methods("GET").Name(Sessions)
+	m.Path("/user/sessions/current").Methods("DELETE").Name(RevokeCurrentSession)
+	m.Path("/user/sessions/{ID:[0-9]+}").Methods("DELETE").Name(RevokeSession)
+	m.Path("/auth").Methods("POST").Name(Authenticate)
+	m.Path("/auth/two-factor").Methods("POST").Name(AuthenticateTwoFactor)
+	m.Path("/domains/{Domain}").Methods("GET").Name(Domain)
+	m.Path("/tags").Methods("GET").Name(Tags)
+	m.Path("/unfurl").Methods("GET").Name(Unfurl)
+	m.Path("/notifications").Methods("GET").Name(Notifications)
+	m.Path("/notifications/unread-count").Methods("GET").Name(UnreadNotificationCount)
+	m.Path("/notifications/read").Methods("PUT").Name(MarkAllNotificationsRead)
+	m.Path("/notifications/{ID:.+}/read").Methods("PUT").Name(MarkNotificationRead)
+	m.Path("/tokens").Methods("GET").Name(Tokens)
+	m.Path("/tokens").Methods("POST").Name(CreateToken)
+	m.Path("/tokens/{ID:.+}").Methods("DELETE").Name(RevokeToken)
+	m.Path("/follows").Methods("GET").Name(Follows)
+	m.Path("/follows/{Kind}/{Name}").Methods("PUT").Name(Follow)
+	m.Path("/follows/{Kind}/{Name}").Methods("DELETE").Name(Unfollow)
+	m.Path("/live").Methods("GET").Name(Live)
+	m.Path("/graphql").Methods("GET", "POST").Name(GraphQL)
+	m.Path("/admin/status").Methods("GET").Name(SiteStatus)
+	m.Path("/admin/status").Methods("PUT").Name(UpdateSiteStatus)
+	m.Path("/webhooks").Methods("GET").Name(Webhooks)
+	m.Path("/webhooks").Methods("POST").Name(CreateWebhook)
+	m.Path("/webhooks/{ID:.+}/deliveries").Methods("GET").Name(WebhookDeliveries)
+	m.Path("/webhooks/{ID:.+}").Methods("DELETE").Name(DeleteWebhook)
+	m.Path("/jobs").Methods("GET").Name(Jobs)
+	m.Path("/jobs/{ID:[0-9]+}/retry").Methods("POST").Name(RetryJob)
+	m.Path("/moderation/bulk").Methods("POST").Name(BulkModerate)
+	m.Path("/reports").Methods("GET").Name(Reports)
+	m.Path("/vote-suspects").Methods("GET").Name(VoteSuspects)
+	m.Path("/vote-suspects/{Login}/nullified").Methods("PUT").Name(NullifyVoteSuspect)
+	m.Path("/vote-suspects/{Login}").Methods("DELETE").Name(DismissVoteSuspect)
+	m.Path("/client-records").Methods("GET").Name(ClientRecords)
+	m.Path("/audit-log").Methods("GET").Name(AuditLog)
+	m.Path("/openapi.json").Methods("GET").Name(OpenAPISpec)
+	m.Path("/docs").Methods("GET").Name(APIDocs)
 	return m
 }
@@ -5,13 +5,114 @@ import "github.com/gorilla/mux"
 // App-only routes
 const (
 	SubmitPostForm = "post:submit-form"
+	SignupForm     = "user:signup-form"
+	LogInForm      = "user:login-form"
+	LogIn          = "user:login"
+	LogOut         = "user:logout"
+	RSSFeed        = "feed:rss"
+	AtomFeed       = "feed:atom"
+	NewPosts       = "posts:new"
+	TopPosts       = "posts:top"
+	BestPosts      = "posts:best"
+	TrendingPosts  = "posts:trending"
+	ShowPosts      = "posts:show"
+	SearchPosts    = "posts:search"
+	TagPosts       = "tag:posts"
+	DomainPosts    = "domain:posts"
+	EditPostForm   = "post:edit-form"
+	ReportPostForm = "post:report-form"
+	PostReported   = "post:reported"
+	Moderation     = "moderation"
+	SavedPosts     = "saved"
+	Settings       = "settings"
+	SetTheme       = "theme"
+	Sitemap        = "sitemap"
+	SitemapPage    = "sitemap:page"
+
+	ForgotPasswordForm = "user:forgot-password-form"
+	ResetPasswordForm  = "user:reset-password-form"
+
+	TwoFactor = "two-factor"
 )
 
 func App() *mux.Router {
 	m := mux.NewRouter()
 	m.Path("/").Methods("GET").Name(Posts)
+	m.Path("/new").Methods("GET").Name(NewPosts)
+	m.Path("/top").Methods("GET").Name(TopPosts)
+	m.Path("/best").Methods("GET").Name(BestPosts)
+	m.Path("/trending").Methods("GET").Name(TrendingPosts)
+	m.Path("/search").Methods("GET").Name(SearchPosts)
+	m.Path("/show").Methods("GET").Name(ShowPosts)
+	m.Path("/p/{ID:.+}/comments").Methods("POST").Name(CreateComment)
+	m.Path("/p/{ID:.+}/vote").Methods("POST").Name(Upvote)
+	m.Path("/p/{ID:.+}/unvote").Methods("POST").Name(Unvote)
+	m.Path("/p/{ID:.+}/edit").Methods("GET").Name(EditPostForm)
+	m.Path("/p/{ID:.+}/edit").Methods("POST").Name(UpdatePost)
+	m.Path("/p/{ID:.+}/delete").Methods("POST").Name(DeletePost)
+	m.Path("/p/{ID:.+}/flag").Methods("POST").Name(FlagPost)
+	m.Path("/p/{ID:.+}/moderate").Methods("POST").Name(ModeratePost)
+	m.Path("/p/{ID:.+}/report").Methods("GET").Name(ReportPostForm)
+	m.Path("/p/{ID:.+}/report").Methods("POST").Name(ReportPost)
+	m.Path("/p/{ID:.+}/reported").Methods("GET").Name(PostReported)
+	m.Path("/p/{ID:.+}/save").Methods("POST").Name(SavePost)
+	m.Path("/p/{ID:.+}/unsave").Methods("POST").Name(UnsavePost)
+	m.Path("/p/{ID:.+}/hide").Methods("POST").Name(HidePost)
+	m.Path("/p/{ID:.+}/unhide").Methods("POST").Name(UnhidePost)
+	m.Path("/p/{ID:.+}/revisions").Methods("GET").Name(PostRevisions)
 	m.Path("/p/{ID:.+}").Methods("GET").Name(Post)
+	m.Path("/comments/{ID:.+}/vote").Methods("POST").Name(UpvoteComment)
+	m.Path("/comments/{ID:.+}/unvote").Methods("POST").Name(UnvoteComment)
 	m.Path("/submit").Methods("GET").Name(SubmitPostForm)
 	m.Path("/posts").Methods("POST").Name(SubmitPost)
+	m.Path("/signup").Methods("GET").Name(SignupForm)
+	m.Path("/signup").Methods("POST").Name(Signup)
+	m.Path("/login").Methods("GET").Name(LogInForm)
+	m.Path("/login").Methods("POST").Name(LogIn)
+	m.Path("/login/two-factor").Methods("POST").Name(AuthenticateTwoFactor)
+	m.Path("/logout").Methods("POST").Name(LogOut)
+	m.Path("/forgot-password").Methods("GET").Name(ForgotPasswordForm)
+	m.Path("/forgot-password").Methods("POST").Name(RequestPasswordReset)
+	m.Path("/reset-password").Methods("GET").Name(ResetPasswordForm)
+	m.Path("/reset-password").Methods("POST").Name(ResetPassword)
+	m.Path("/feed.rss").Methods("GET").Name(RSSFeed)
+	m.Path("/feed.atom").Methods("GET").Name(AtomFeed)
+	m.Path("/sitemap.xml").Methods("GET").Name(Sitemap)
+	m.Path("/sitemap-{Page:[0-9]+}.xml").Methods("GET").Name(SitemapPage)
+	m.Path("/t/{Tag}").Methods("GET").Name(TagPosts)
+	m.Path("/from/{Domain}").Methods("GET").Name(DomainPosts)
+	m.Path("/users/{Login}/shadow-ban").Methods("POST").Name(ShadowBanUser)
+	m.Path("/users/{Login}").Methods("GET").Name(User)
+	m.Path("/moderation").Methods("GET").Name(Moderation)
+	m.Path("/moderation/bulk").Methods("POST").Name(BulkModerate)
+	m.Path("/moderation/vote-suspects/{Login}/nullify").Methods("POST").Name(NullifyVoteSuspect)
+	m.Path("/moderation/vote-suspects/{Login}/dismiss").Methods("POST").Name(DismissVoteSuspect)
+	m.Path("/saved").Methods("GET").Name(SavedPosts)
+	m.Path("/notifications").Methods("GET").Name(Notifications)
+	m.Path("/notifications/read").Methods("POST").Name(MarkAllNotificationsRead)
+	m.Path("/notifications/{ID:.+}/read").Methods("POST").Name(MarkNotificationRead)
+	m.Path("/theme").Methods("POST").Name(SetTheme)
+	m.Path("/settings").Methods("GET").Name(Settings)
+	m.Path("/settings").Methods("POST").Name(UpdateUserSettings)
+	m.Path("/settings/password").Methods("POST").Name(ChangePassword)
+	m.Path("/settings/two-factor").Methods("GET").Name(TwoFactor)
+	m.Path("/settings/two-factor").Methods("POST").Name(EnableTwoFactor)
+	m.Path("/settings/two-factor/setup").Methods("POST").Name(SetUpTwoFactor)
+	m.Path("/settings/two-factor/disable").Methods("POST").Name(DisableTwoFactor)
+	m.Path("/settings/two-factor/recovery-codes").Methods("POST").Name(RegenerateRecoveryCodes)
+	m.Path("/settings/sessions").Methods("GET").Name(Sessions)
+	m.Path("/settings/sessions/{ID:[0-9]+}/revoke").Methods("POST").Name(RevokeSession)
+	m.Path("/settings/tokens").Methods("GET").Name(Tokens)
+	m.Path("/settings/tokens").Methods("POST").Name(CreateToken)
+	m.Path("/settings/tokens/{ID:.+}/revoke").Methods("POST").Name(RevokeToken)
+	m.Path("/settings/follows").Methods("GET").Name(Follows)
+	m.Path("/settings/follows").Methods("POST").Name(Follow)
+	m.Path("/settings/follows/unfollow").Methods("POST").Name(Unfollow)
+	m.Path("/admin/jobs").Methods("GET").Name(Jobs)
+	m.Path("/admin/jobs/{ID:[0-9]+}/retry").Methods("POST").Name(RetryJob)
+	m.Path("/admin/clients").Methods("GET").Name(ClientRecords)
+	m.Path("/admin/audit-log").Methods("GET").Name(AuditLog)
+	m.Path("/admin/deleted-posts").Methods("GET").Name(DeletedPosts)
+	m.Path("/admin/deleted-posts/{ID:[0-9]+}/undelete").Methods("POST").Name(UndeletePost)
 	return m
 }
@@ -4,4 +4,73 @@ const (
 	Post       = "post"
 	SubmitPost = "post:submit"
 	Posts      = "posts"
+	Upvote     = "post:upvote"
+	Unvote     = "post:unvote"
+	UpdatePost = "post:update"
+	DeletePost = "post:delete"
+
+	UndeletePost  = "post:undelete"
+	PostRevisions = "post:revisions"
+	DeletedPosts  = "posts:deleted"
+
+	FlagPost     = "post:flag"
+	ModeratePost = "post:moderate"
+	BulkModerate = "moderation:bulk"
+	ReportPost   = "post:report"
+	Reports      = "reports"
+
+	SavePost   = "post:save"
+	UnsavePost = "post:unsave"
+	HidePost   = "post:hide"
+	UnhidePost = "post:unhide"
+
+	Comment       = "comment"
+	Comments      = "comments"
+	CreateComment = "comment:create"
+	PostComments  = "post:comments"
+	UpvoteComment = "comment:upvote"
+	UnvoteComment = "comment:unvote"
+
+	User          = "user"
+	Signup        = "user:signup"
+	ShadowBanUser = "user:shadow-ban"
+
+	UpdateUserSettings   = "user:update-settings"
+	ChangePassword       = "user:change-password"
+	RequestPasswordReset = "user:request-password-reset"
+	ResetPassword        = "user:reset-password"
+
+	Tags = "tags"
+
+	Notifications            = "notifications"
+	MarkNotificationRead     = "notification:mark-read"
+	MarkAllNotificationsRead = "notifications:mark-read"
+
+	Tokens      = "tokens"
+	CreateToken = "token:create"
+	RevokeToken = "token:revoke"
+
+	SetUpTwoFactor          = "two-factor:setup"
+	EnableTwoFactor         = "two-factor:enable"
+	DisableTwoFactor        = "two-factor:disable"
+	RegenerateRecoveryCodes = "two-factor:regenerate-recovery-codes"
+	AuthenticateTwoFactor   = "two-factor:authenticate"
+
+	Sessions             = "sessions"
+	RevokeSession        = "session:revoke"
+	RevokeCurrentSession = "session:revoke-current"
+
+	Follows  = "follows"
+	Follow   = "follow"
+	Unfollow = "unfollow"
+
+	Jobs     = "jobs"
+	RetryJob = "job:retry"
+
+	VoteSuspects       = "vote-suspects"
+	NullifyVoteSuspect = "vote-suspect:nullify"
+	DismissVoteSuspect = "vote-suspect:dismiss"
+
+	ClientRecords = "client-records"
+	AuditLog      = "audit-log"
 )
@@ -0,0 +1,1015 @@
+// The Posts service mirrors thesrc.PostsService over gRPC, for internal
+// services and importers that want typed access to posts. See package rpc
+// for the server.
+//
+// Regenerate posts.pb.go and posts_grpc.pb.go after changing this file with:
+//
+//   go generate sourcegraph.com/sourcegraph/thesrc/rpc
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: posts.proto
+
+package rpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// A Post is a thesrc.Post.
+type Post struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title           string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	LinkUrl         string                 `protobuf:"bytes,3,opt,name=link_url,json=linkUrl,proto3" json:"link_url,omitempty"`
+	Domain          string                 `protobuf:"bytes,4,opt,name=domain,proto3" json:"domain,omitempty"`
+	LinkDescription string                 `protobuf:"bytes,5,opt,name=link_description,json=linkDescription,proto3" json:"link_description,omitempty"`
+	LinkImageUrl    string                 `protobuf:"bytes,6,opt,name=link_image_url,json=linkImageUrl,proto3" json:"link_image_url,omitempty"`
+	LinkFaviconUrl  string                 `protobuf:"bytes,7,opt,name=link_favicon_url,json=linkFaviconUrl,proto3" json:"link_favicon_url,omitempty"`
+	ThumbnailUrl    string                 `protobuf:"bytes,8,opt,name=thumbnail_url,json=thumbnailUrl,proto3" json:"thumbnail_url,omitempty"`
+	Body            string                 `protobuf:"bytes,9,opt,name=body,proto3" json:"body,omitempty"`
+	BodyHtml        string                 `protobuf:"bytes,10,opt,name=body_html,json=bodyHtml,proto3" json:"body_html,omitempty"`
+	// submitted_at_unix_nano is when the post was submitted, in nanoseconds
+	// since the Unix epoch.
+	SubmittedAtUnixNano int64    `protobuf:"varint,11,opt,name=submitted_at_unix_nano,json=submittedAtUnixNano,proto3" json:"submitted_at_unix_nano,omitempty"`
+	AuthorUserId        int64    `protobuf:"varint,12,opt,name=author_user_id,json=authorUserId,proto3" json:"author_user_id,omitempty"`
+	Score               int64    `protobuf:"varint,13,opt,name=score,proto3" json:"score,omitempty"`
+	Classification      string   `protobuf:"bytes,14,opt,name=classification,proto3" json:"classification,omitempty"`
+	Tags                []string `protobuf:"bytes,15,rep,name=tags,proto3" json:"tags,omitempty"`
+	Voted               bool     `protobuf:"varint,16,opt,name=voted,proto3" json:"voted,omitempty"`
+	Saved               bool     `protobuf:"varint,17,opt,name=saved,proto3" json:"saved,omitempty"`
+	HiddenByUser        bool     `protobuf:"varint,18,opt,name=hidden_by_user,json=hiddenByUser,proto3" json:"hidden_by_user,omitempty"`
+	Flags               int64    `protobuf:"varint,19,opt,name=flags,proto3" json:"flags,omitempty"`
+	Hidden              bool     `protobuf:"varint,20,opt,name=hidden,proto3" json:"hidden,omitempty"`
+	Dead                bool     `protobuf:"varint,21,opt,name=dead,proto3" json:"dead,omitempty"`
+	SpamScore           float64  `protobuf:"fixed64,22,opt,name=spam_score,json=spamScore,proto3" json:"spam_score,omitempty"`
+	LinkStatus          int64    `protobuf:"varint,23,opt,name=link_status,json=linkStatus,proto3" json:"link_status,omitempty"`
+	LinkDead            bool     `protobuf:"varint,24,opt,name=link_dead,json=linkDead,proto3" json:"link_dead,omitempty"`
+	LinkArchiveUrl      string   `protobuf:"bytes,25,opt,name=link_archive_url,json=linkArchiveUrl,proto3" json:"link_archive_url,omitempty"`
+	// version is the post's edit version (see thesrc.Post.Version). Updates
+	// that send it fail if the post has changed since.
+	Version       int64 `protobuf:"varint,26,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Post) Reset() {
+	*x = Post{}
+	mi := &file_posts_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Post) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Post) ProtoMessage() {}
+
+func (x *Post) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Post.ProtoReflect.Descriptor instead.
+func (*Post) Descriptor() ([]byte, []int) {
+	return file_posts_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Post) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Post) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Post) GetLinkUrl() string {
+	if x != nil {
+		return x.LinkUrl
+	}
+	return ""
+}
+
+func (x *Post) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *Post) GetLinkDescription() string {
+	if x != nil {
+		return x.LinkDescription
+	}
+	return ""
+}
+
+func (x *Post) GetLinkImageUrl() string {
+	if x != nil {
+		return x.LinkImageUrl
+	}
+	return ""
+}
+
+func (x *Post) GetLinkFaviconUrl() string {
+	if x != nil {
+		return x.LinkFaviconUrl
+	}
+	return ""
+}
+
+func (x *Post) GetThumbnailUrl() string {
+	if x != nil {
+		return x.ThumbnailUrl
+	}
+	return ""
+}
+
+func (x *Post) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+func (x *Post) GetBodyHtml() string {
+	if x != nil {
+		return x.BodyHtml
+	}
+	return ""
+}
+
+func (x *Post) GetSubmittedAtUnixNano() int64 {
+	if x != nil {
+		return x.SubmittedAtUnixNano
+	}
+	return 0
+}
+
+func (x *Post) GetAuthorUserId() int64 {
+	if x != nil {
+		return x.AuthorUserId
+	}
+	return 0
+}
+
+func (x *Post) GetScore() int64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Post) GetClassification() string {
+	if x != nil {
+		return x.Classification
+	}
+	return ""
+}
+
+func (x *Post) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Post) GetVoted() bool {
+	if x != nil {
+		return x.Voted
+	}
+	return false
+}
+
+func (x *Post) GetSaved() bool {
+	if x != nil {
+		return x.Saved
+	}
+	return false
+}
+
+func (x *Post) GetHiddenByUser() bool {
+	if x != nil {
+		return x.HiddenByUser
+	}
+	return false
+}
+
+func (x *Post) GetFlags() int64 {
+	if x != nil {
+		return x.Flags
+	}
+	return 0
+}
+
+func (x *Post) GetHidden() bool {
+	if x != nil {
+		return x.Hidden
+	}
+	return false
+}
+
+func (x *Post) GetDead() bool {
+	if x != nil {
+		return x.Dead
+	}
+	return false
+}
+
+func (x *Post) GetSpamScore() float64 {
+	if x != nil {
+		return x.SpamScore
+	}
+	return 0
+}
+
+func (x *Post) GetLinkStatus() int64 {
+	if x != nil {
+		return x.LinkStatus
+	}
+	return 0
+}
+
+func (x *Post) GetLinkDead() bool {
+	if x != nil {
+		return x.LinkDead
+	}
+	return false
+}
+
+func (x *Post) GetLinkArchiveUrl() string {
+	if x != nil {
+		return x.LinkArchiveUrl
+	}
+	return ""
+}
+
+func (x *Post) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type PostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PostRequest) Reset() {
+	*x = PostRequest{}
+	mi := &file_posts_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PostRequest) ProtoMessage() {}
+
+func (x *PostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PostRequest.ProtoReflect.Descriptor instead.
+func (*PostRequest) Descriptor() ([]byte, []int) {
+	return file_posts_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PostRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// A ListPostsRequest holds the options of thesrc.PostListOptions that
+// clients may set.
+type ListPostsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CodeOnly      bool                   `protobuf:"varint,1,opt,name=code_only,json=codeOnly,proto3" json:"code_only,omitempty"`
+	Sort          string                 `protobuf:"bytes,2,opt,name=sort,proto3" json:"sort,omitempty"`
+	Tag           string                 `protobuf:"bytes,3,opt,name=tag,proto3" json:"tag,omitempty"`
+	Domain        string                 `protobuf:"bytes,4,opt,name=domain,proto3" json:"domain,omitempty"`
+	AuthorUserId  int64                  `protobuf:"varint,5,opt,name=author_user_id,json=authorUserId,proto3" json:"author_user_id,omitempty"`
+	RenderBody    bool                   `protobuf:"varint,6,opt,name=render_body,json=renderBody,proto3" json:"render_body,omitempty"`
+	Flagged       bool                   `protobuf:"varint,7,opt,name=flagged,proto3" json:"flagged,omitempty"`
+	Saved         bool                   `protobuf:"varint,8,opt,name=saved,proto3" json:"saved,omitempty"`
+	SinceId       int64                  `protobuf:"varint,9,opt,name=since_id,json=sinceId,proto3" json:"since_id,omitempty"`
+	PerPage       int32                  `protobuf:"varint,10,opt,name=per_page,json=perPage,proto3" json:"per_page,omitempty"`
+	Page          int32                  `protobuf:"varint,11,opt,name=page,proto3" json:"page,omitempty"`
+	Period        string                 `protobuf:"bytes,12,opt,name=period,proto3" json:"period,omitempty"`
+	Show          bool                   `protobuf:"varint,13,opt,name=show,proto3" json:"show,omitempty"`
+	Query         string                 `protobuf:"bytes,14,opt,name=query,proto3" json:"query,omitempty"`
+	After         string                 `protobuf:"bytes,15,opt,name=after,proto3" json:"after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPostsRequest) Reset() {
+	*x = ListPostsRequest{}
+	mi := &file_posts_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPostsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPostsRequest) ProtoMessage() {}
+
+func (x *ListPostsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPostsRequest.ProtoReflect.Descriptor instead.
+func (*ListPostsRequest) Descriptor() ([]byte, []int) {
+	return file_posts_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListPostsRequest) GetCodeOnly() bool {
+	if x != nil {
+		return x.CodeOnly
+	}
+	return false
+}
+
+func (x *ListPostsRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *ListPostsRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *ListPostsRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *ListPostsRequest) GetAuthorUserId() int64 {
+	if x != nil {
+		return x.AuthorUserId
+	}
+	return 0
+}
+
+func (x *ListPostsRequest) GetRenderBody() bool {
+	if x != nil {
+		return x.RenderBody
+	}
+	return false
+}
+
+func (x *ListPostsRequest) GetFlagged() bool {
+	if x != nil {
+		return x.Flagged
+	}
+	return false
+}
+
+func (x *ListPostsRequest) GetSaved() bool {
+	if x != nil {
+		return x.Saved
+	}
+	return false
+}
+
+func (x *ListPostsRequest) GetSinceId() int64 {
+	if x != nil {
+		return x.SinceId
+	}
+	return 0
+}
+
+func (x *ListPostsRequest) GetPerPage() int32 {
+	if x != nil {
+		return x.PerPage
+	}
+	return 0
+}
+
+func (x *ListPostsRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListPostsRequest) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+func (x *ListPostsRequest) GetShow() bool {
+	if x != nil {
+		return x.Show
+	}
+	return false
+}
+
+func (x *ListPostsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *ListPostsRequest) GetAfter() string {
+	if x != nil {
+		return x.After
+	}
+	return ""
+}
+
+type SubmitPostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Post          *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
+	Created       bool                   `protobuf:"varint,2,opt,name=created,proto3" json:"created,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitPostResponse) Reset() {
+	*x = SubmitPostResponse{}
+	mi := &file_posts_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitPostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitPostResponse) ProtoMessage() {}
+
+func (x *SubmitPostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitPostResponse.ProtoReflect.Descriptor instead.
+func (*SubmitPostResponse) Descriptor() ([]byte, []int) {
+	return file_posts_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SubmitPostResponse) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+func (x *SubmitPostResponse) GetCreated() bool {
+	if x != nil {
+		return x.Created
+	}
+	return false
+}
+
+type CreatePostBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Posts         []*Post                `protobuf:"bytes,1,rep,name=posts,proto3" json:"posts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreatePostBatchRequest) Reset() {
+	*x = CreatePostBatchRequest{}
+	mi := &file_posts_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatePostBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePostBatchRequest) ProtoMessage() {}
+
+func (x *CreatePostBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePostBatchRequest.ProtoReflect.Descriptor instead.
+func (*CreatePostBatchRequest) Descriptor() ([]byte, []int) {
+	return file_posts_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreatePostBatchRequest) GetPosts() []*Post {
+	if x != nil {
+		return x.Posts
+	}
+	return nil
+}
+
+// A PostBatchResult is a thesrc.PostBatchResult.
+type PostBatchResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Post          *Post                  `protobuf:"bytes,1,opt,name=post,proto3" json:"post,omitempty"`
+	Created       bool                   `protobuf:"varint,2,opt,name=created,proto3" json:"created,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PostBatchResult) Reset() {
+	*x = PostBatchResult{}
+	mi := &file_posts_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PostBatchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PostBatchResult) ProtoMessage() {}
+
+func (x *PostBatchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PostBatchResult.ProtoReflect.Descriptor instead.
+func (*PostBatchResult) Descriptor() ([]byte, []int) {
+	return file_posts_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PostBatchResult) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+func (x *PostBatchResult) GetCreated() bool {
+	if x != nil {
+		return x.Created
+	}
+	return false
+}
+
+func (x *PostBatchResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type CreatePostBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*PostBatchResult     `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreatePostBatchResponse) Reset() {
+	*x = CreatePostBatchResponse{}
+	mi := &file_posts_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatePostBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePostBatchResponse) ProtoMessage() {}
+
+func (x *CreatePostBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePostBatchResponse.ProtoReflect.Descriptor instead.
+func (*CreatePostBatchResponse) Descriptor() ([]byte, []int) {
+	return file_posts_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CreatePostBatchResponse) GetResults() []*PostBatchResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type UpdatePostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Post          *Post                  `protobuf:"bytes,2,opt,name=post,proto3" json:"post,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdatePostRequest) Reset() {
+	*x = UpdatePostRequest{}
+	mi := &file_posts_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePostRequest) ProtoMessage() {}
+
+func (x *UpdatePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePostRequest.ProtoReflect.Descriptor instead.
+func (*UpdatePostRequest) Descriptor() ([]byte, []int) {
+	return file_posts_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdatePostRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdatePostRequest) GetPost() *Post {
+	if x != nil {
+		return x.Post
+	}
+	return nil
+}
+
+type ModeratePostRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Hidden bool                   `protobuf:"varint,2,opt,name=hidden,proto3" json:"hidden,omitempty"`
+	Dead   bool                   `protobuf:"varint,3,opt,name=dead,proto3" json:"dead,omitempty"`
+	// version (if nonzero) is the version of the post that the moderator saw.
+	// If the post has changed since, the request fails and nothing changes.
+	Version       int64 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ModeratePostRequest) Reset() {
+	*x = ModeratePostRequest{}
+	mi := &file_posts_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModeratePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModeratePostRequest) ProtoMessage() {}
+
+func (x *ModeratePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModeratePostRequest.ProtoReflect.Descriptor instead.
+func (*ModeratePostRequest) Descriptor() ([]byte, []int) {
+	return file_posts_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ModeratePostRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ModeratePostRequest) GetHidden() bool {
+	if x != nil {
+		return x.Hidden
+	}
+	return false
+}
+
+func (x *ModeratePostRequest) GetDead() bool {
+	if x != nil {
+		return x.Dead
+	}
+	return false
+}
+
+func (x *ModeratePostRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_posts_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_posts_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_posts_proto_rawDescGZIP(), []int{9}
+}
+
+var File_posts_proto protoreflect.FileDescriptor
+
+const file_posts_proto_rawDesc = "" +
+	"\n" +
+	"\vposts.proto\x12\x06thesrc\"\x92\x06\n" +
+	"\x04Post\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x19\n" +
+	"\blink_url\x18\x03 \x01(\tR\alinkUrl\x12\x16\n" +
+	"\x06domain\x18\x04 \x01(\tR\x06domain\x12)\n" +
+	"\x10link_description\x18\x05 \x01(\tR\x0flinkDescription\x12$\n" +
+	"\x0elink_image_url\x18\x06 \x01(\tR\flinkImageUrl\x12(\n" +
+	"\x10link_favicon_url\x18\a \x01(\tR\x0elinkFaviconUrl\x12#\n" +
+	"\rthumbnail_url\x18\b \x01(\tR\fthumbnailUrl\x12\x12\n" +
+	"\x04body\x18\t \x01(\tR\x04body\x12\x1b\n" +
+	"\tbody_html\x18\n" +
+	" \x01(\tR\bbodyHtml\x123\n" +
+	"\x16submitted_at_unix_nano\x18\v \x01(\x03R\x13submittedAtUnixNano\x12$\n" +
+	"\x0eauthor_user_id\x18\f \x01(\x03R\fauthorUserId\x12\x14\n" +
+	"\x05score\x18\r \x01(\x03R\x05score\x12&\n" +
+	"\x0eclassification\x18\x0e \x01(\tR\x0eclassification\x12\x12\n" +
+	"\x04tags\x18\x0f \x03(\tR\x04tags\x12\x14\n" +
+	"\x05voted\x18\x10 \x01(\bR\x05voted\x12\x14\n" +
+	"\x05saved\x18\x11 \x01(\bR\x05saved\x12$\n" +
+	"\x0ehidden_by_user\x18\x12 \x01(\bR\fhiddenByUser\x12\x14\n" +
+	"\x05flags\x18\x13 \x01(\x03R\x05flags\x12\x16\n" +
+	"\x06hidden\x18\x14 \x01(\bR\x06hidden\x12\x12\n" +
+	"\x04dead\x18\x15 \x01(\bR\x04dead\x12\x1d\n" +
+	"\n" +
+	"spam_score\x18\x16 \x01(\x01R\tspamScore\x12\x1f\n" +
+	"\vlink_status\x18\x17 \x01(\x03R\n" +
+	"linkStatus\x12\x1b\n" +
+	"\tlink_dead\x18\x18 \x01(\bR\blinkDead\x12(\n" +
+	"\x10link_archive_url\x18\x19 \x01(\tR\x0elinkArchiveUrl\x12\x18\n" +
+	"\aversion\x18\x1a \x01(\x03R\aversion\"\x1d\n" +
+	"\vPostRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x86\x03\n" +
+	"\x10ListPostsRequest\x12\x1b\n" +
+	"\tcode_only\x18\x01 \x01(\bR\bcodeOnly\x12\x12\n" +
+	"\x04sort\x18\x02 \x01(\tR\x04sort\x12\x10\n" +
+	"\x03tag\x18\x03 \x01(\tR\x03tag\x12\x16\n" +
+	"\x06domain\x18\x04 \x01(\tR\x06domain\x12$\n" +
+	"\x0eauthor_user_id\x18\x05 \x01(\x03R\fauthorUserId\x12\x1f\n" +
+	"\vrender_body\x18\x06 \x01(\bR\n" +
+	"renderBody\x12\x18\n" +
+	"\aflagged\x18\a \x01(\bR\aflagged\x12\x14\n" +
+	"\x05saved\x18\b \x01(\bR\x05saved\x12\x19\n" +
+	"\bsince_id\x18\t \x01(\x03R\asinceId\x12\x19\n" +
+	"\bper_page\x18\n" +
+	" \x01(\x05R\aperPage\x12\x12\n" +
+	"\x04page\x18\v \x01(\x05R\x04page\x12\x16\n" +
+	"\x06period\x18\f \x01(\tR\x06period\x12\x12\n" +
+	"\x04show\x18\r \x01(\bR\x04show\x12\x14\n" +
+	"\x05query\x18\x0e \x01(\tR\x05query\x12\x14\n" +
+	"\x05after\x18\x0f \x01(\tR\x05after\"P\n" +
+	"\x12SubmitPostResponse\x12 \n" +
+	"\x04post\x18\x01 \x01(\v2\f.thesrc.PostR\x04post\x12\x18\n" +
+	"\acreated\x18\x02 \x01(\bR\acreated\"<\n" +
+	"\x16CreatePostBatchRequest\x12\"\n" +
+	"\x05posts\x18\x01 \x03(\v2\f.thesrc.PostR\x05posts\"c\n" +
+	"\x0fPostBatchResult\x12 \n" +
+	"\x04post\x18\x01 \x01(\v2\f.thesrc.PostR\x04post\x12\x18\n" +
+	"\acreated\x18\x02 \x01(\bR\acreated\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"L\n" +
+	"\x17CreatePostBatchResponse\x121\n" +
+	"\aresults\x18\x01 \x03(\v2\x17.thesrc.PostBatchResultR\aresults\"E\n" +
+	"\x11UpdatePostRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12 \n" +
+	"\x04post\x18\x02 \x01(\v2\f.thesrc.PostR\x04post\"k\n" +
+	"\x13ModeratePostRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x16\n" +
+	"\x06hidden\x18\x02 \x01(\bR\x06hidden\x12\x12\n" +
+	"\x04dead\x18\x03 \x01(\bR\x04dead\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\x03R\aversion\"\a\n" +
+	"\x05Empty2\xe0\x04\n" +
+	"\x05Posts\x12(\n" +
+	"\x03Get\x12\x13.thesrc.PostRequest\x1a\f.thesrc.Post\x120\n" +
+	"\x04List\x12\x18.thesrc.ListPostsRequest\x1a\f.thesrc.Post0\x01\x122\n" +
+	"\x06Submit\x12\f.thesrc.Post\x1a\x1a.thesrc.SubmitPostResponse\x12N\n" +
+	"\vCreateBatch\x12\x1e.thesrc.CreatePostBatchRequest\x1a\x1f.thesrc.CreatePostBatchResponse\x121\n" +
+	"\x06Update\x12\x19.thesrc.UpdatePostRequest\x1a\f.thesrc.Post\x12,\n" +
+	"\x06Delete\x12\x13.thesrc.PostRequest\x1a\r.thesrc.Empty\x12*\n" +
+	"\x04Flag\x12\x13.thesrc.PostRequest\x1a\r.thesrc.Empty\x126\n" +
+	"\bModerate\x12\x1b.thesrc.ModeratePostRequest\x1a\r.thesrc.Empty\x12*\n" +
+	"\x04Save\x12\x13.thesrc.PostRequest\x1a\r.thesrc.Empty\x12,\n" +
+	"\x06Unsave\x12\x13.thesrc.PostRequest\x1a\r.thesrc.Empty\x12*\n" +
+	"\x04Hide\x12\x13.thesrc.PostRequest\x1a\r.thesrc.Empty\x12,\n" +
+	"\x06Unhide\x12\x13.thesrc.PostRequest\x1a\r.thesrc.EmptyB(Z&sourcegraph.com/sourcegraph/thesrc/rpcb\x06proto3"
+
+var (
+	file_posts_proto_rawDescOnce sync.Once
+	file_posts_proto_rawDescData []byte
+)
+
+func file_posts_proto_rawDescGZIP() []byte {
+	file_posts_proto_rawDescOnce.Do(func() {
+		file_posts_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_posts_proto_rawDesc), len(file_posts_proto_rawDesc)))
+	})
+	return file_posts_proto_rawDescData
+}
+
+var file_posts_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_posts_proto_goTypes = []any{
+	(*Post)(nil),                    // 0: thesrc.Post
+	(*PostRequest)(nil),             // 1: thesrc.PostRequest
+	(*ListPostsRequest)(nil),        // 2: thesrc.ListPostsRequest
+	(*SubmitPostResponse)(nil),      // 3: thesrc.SubmitPostResponse
+	(*CreatePostBatchRequest)(nil),  // 4: thesrc.CreatePostBatchRequest
+	(*PostBatchResult)(nil),         // 5: thesrc.PostBatchResult
+	(*CreatePostBatchResponse)(nil), // 6: thesrc.CreatePostBatchResponse
+	(*UpdatePostRequest)(nil),       // 7: thesrc.UpdatePostRequest
+	(*ModeratePostRequest)(nil),     // 8: thesrc.ModeratePostRequest
+	(*Empty)(nil),                   // 9: thesrc.Empty
+}
+var file_posts_proto_depIdxs = []int32{
+	0,  // 0: thesrc.SubmitPostResponse.post:type_name -> thesrc.Post
+	0,  // 1: thesrc.CreatePostBatchRequest.posts:type_name -> thesrc.Post
+	0,  // 2: thesrc.PostBatchResult.post:type_name -> thesrc.Post
+	5,  // 3: thesrc.CreatePostBatchResponse.results:type_name -> thesrc.PostBatchResult
+	0,  // 4: thesrc.UpdatePostRequest.post:type_name -> thesrc.Post
+	1,  // 5: thesrc.Posts.Get:input_type -> thesrc.PostRequest
+	2,  // 6: thesrc.Posts.List:input_type -> thesrc.ListPostsRequest
+	0,  // 7: thesrc.Posts.Submit:input_type -> thesrc.Post
+	4,  // 8: thesrc.Posts.CreateBatch:input_type -> thesrc.CreatePostBatchRequest
+	7,  // 9: thesrc.Posts.Update:input_type -> thesrc.UpdatePostRequest
+	1,  // 10: thesrc.Posts.Delete:input_type -> thesrc.PostRequest
+	1,  // 11: thesrc.Posts.Flag:input_type -> thesrc.PostRequest
+	8,  // 12: thesrc.Posts.Moderate:input_type -> thesrc.ModeratePostRequest
+	1,  // 13: thesrc.Posts.Save:input_type -> thesrc.PostRequest
+	1,  // 14: thesrc.Posts.Unsave:input_type -> thesrc.PostRequest
+	1,  // 15: thesrc.Posts.Hide:input_type -> thesrc.PostRequest
+	1,  // 16: thesrc.Posts.Unhide:input_type -> thesrc.PostRequest
+	0,  // 17: thesrc.Posts.Get:output_type -> thesrc.Post
+	0,  // 18: thesrc.Posts.List:output_type -> thesrc.Post
+	3,  // 19: thesrc.Posts.Submit:output_type -> thesrc.SubmitPostResponse
+	6,  // 20: thesrc.Posts.CreateBatch:output_type -> thesrc.CreatePostBatchResponse
+	0,  // 21: thesrc.Posts.Update:output_type -> thesrc.Post
+	9,  // 22: thesrc.Posts.Delete:output_type -> thesrc.Empty
+	9,  // 23: thesrc.Posts.Flag:output_type -> thesrc.Empty
+	9,  // 24: thesrc.Posts.Moderate:output_type -> thesrc.Empty
+	9,  // 25: thesrc.Posts.Save:output_type -> thesrc.Empty
+	9,  // 26: thesrc.Posts.Unsave:output_type -> thesrc.Empty
+	9,  // 27: thesrc.Posts.Hide:output_type -> thesrc.Empty
+	9,  // 28: thesrc.Posts.Unhide:output_type -> thesrc.Empty
+	17, // [17:29] is the sub-list for method output_type
+	5,  // [5:17] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_posts_proto_init() }
+func file_posts_proto_init() {
+	if File_posts_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_posts_proto_rawDesc), len(file_posts_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_posts_proto_goTypes,
+		DependencyIndexes: file_posts_proto_depIdxs,
+		MessageInfos:      file_posts_proto_msgTypes,
+	}.Build()
+	File_posts_proto = out.File
+	file_posts_proto_goTypes = nil
+	file_posts_proto_depIdxs = nil
+}
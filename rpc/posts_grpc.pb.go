@@ -0,0 +1,577 @@
+// The Posts service mirrors thesrc.PostsService over gRPC, for internal
+// services and importers that want typed access to posts. See package rpc
+// for the server.
+//
+// Regenerate posts.pb.go and posts_grpc.pb.go after changing this file with:
+//
+//   go generate sourcegraph.com/sourcegraph/thesrc/rpc
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: posts.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Posts_Get_FullMethodName         = "/thesrc.Posts/Get"
+	Posts_List_FullMethodName        = "/thesrc.Posts/List"
+	Posts_Submit_FullMethodName      = "/thesrc.Posts/Submit"
+	Posts_CreateBatch_FullMethodName = "/thesrc.Posts/CreateBatch"
+	Posts_Update_FullMethodName      = "/thesrc.Posts/Update"
+	Posts_Delete_FullMethodName      = "/thesrc.Posts/Delete"
+	Posts_Flag_FullMethodName        = "/thesrc.Posts/Flag"
+	Posts_Moderate_FullMethodName    = "/thesrc.Posts/Moderate"
+	Posts_Save_FullMethodName        = "/thesrc.Posts/Save"
+	Posts_Unsave_FullMethodName      = "/thesrc.Posts/Unsave"
+	Posts_Hide_FullMethodName        = "/thesrc.Posts/Hide"
+	Posts_Unhide_FullMethodName      = "/thesrc.Posts/Unhide"
+)
+
+// PostsClient is the client API for Posts service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PostsClient interface {
+	// Get a post.
+	Get(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Post, error)
+	// List posts, streaming them in order.
+	List(ctx context.Context, in *ListPostsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Post], error)
+	// Submit a post (see thesrc.PostsService.Submit).
+	Submit(ctx context.Context, in *Post, opts ...grpc.CallOption) (*SubmitPostResponse, error)
+	// CreateBatch submits up to 100 posts at once, in a single transaction.
+	CreateBatch(ctx context.Context, in *CreatePostBatchRequest, opts ...grpc.CallOption) (*CreatePostBatchResponse, error)
+	// Update a post's title, body, and tags.
+	Update(ctx context.Context, in *UpdatePostRequest, opts ...grpc.CallOption) (*Post, error)
+	// Delete a post, and its comments, votes, flags, and tags.
+	Delete(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Flag a post as inappropriate.
+	Flag(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Moderate sets a post's moderation status (moderators only).
+	Moderate(ctx context.Context, in *ModeratePostRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Save a post to the authenticated user's saved posts.
+	Save(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Unsave removes a post from the authenticated user's saved posts.
+	Unsave(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Hide a post from the authenticated user's post listings.
+	Hide(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Unhide shows a hidden post in the authenticated user's post listings
+	// again.
+	Unhide(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type postsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPostsClient(cc grpc.ClientConnInterface) PostsClient {
+	return &postsClient{cc}
+}
+
+func (c *postsClient) Get(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Post, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Post)
+	err := c.cc.Invoke(ctx, Posts_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postsClient) List(ctx context.Context, in *ListPostsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Post], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Posts_ServiceDesc.Streams[0], Posts_List_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListPostsRequest, Post]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Posts_ListClient = grpc.ServerStreamingClient[Post]
+
+func (c *postsClient) Submit(ctx context.Context, in *Post, opts ...grpc.CallOption) (*SubmitPostResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubmitPostResponse)
+	err := c.cc.Invoke(ctx, Posts_Submit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postsClient) CreateBatch(ctx context.Context, in *CreatePostBatchRequest, opts ...grpc.CallOption) (*CreatePostBatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreatePostBatchResponse)
+	err := c.cc.Invoke(ctx, Posts_CreateBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postsClient) Update(ctx context.Context, in *UpdatePostRequest, opts ...grpc.CallOption) (*Post, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Post)
+	err := c.cc.Invoke(ctx, Posts_Update_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postsClient) Delete(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Posts_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postsClient) Flag(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Posts_Flag_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postsClient) Moderate(ctx context.Context, in *ModeratePostRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Posts_Moderate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postsClient) Save(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Posts_Save_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postsClient) Unsave(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Posts_Unsave_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postsClient) Hide(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Posts_Hide_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postsClient) Unhide(ctx context.Context, in *PostRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Posts_Unhide_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PostsServer is the server API for Posts service.
+// All implementations must embed UnimplementedPostsServer
+// for forward compatibility.
+type PostsServer interface {
+	// Get a post.
+	Get(context.Context, *PostRequest) (*Post, error)
+	// List posts, streaming them in order.
+	List(*ListPostsRequest, grpc.ServerStreamingServer[Post]) error
+	// Submit a post (see thesrc.PostsService.Submit).
+	Submit(context.Context, *Post) (*SubmitPostResponse, error)
+	// CreateBatch submits up to 100 posts at once, in a single transaction.
+	CreateBatch(context.Context, *CreatePostBatchRequest) (*CreatePostBatchResponse, error)
+	// Update a post's title, body, and tags.
+	Update(context.Context, *UpdatePostRequest) (*Post, error)
+	// Delete a post, and its comments, votes, flags, and tags.
+	Delete(context.Context, *PostRequest) (*Empty, error)
+	// Flag a post as inappropriate.
+	Flag(context.Context, *PostRequest) (*Empty, error)
+	// Moderate sets a post's moderation status (moderators only).
+	Moderate(context.Context, *ModeratePostRequest) (*Empty, error)
+	// Save a post to the authenticated user's saved posts.
+	Save(context.Context, *PostRequest) (*Empty, error)
+	// Unsave removes a post from the authenticated user's saved posts.
+	Unsave(context.Context, *PostRequest) (*Empty, error)
+	// Hide a post from the authenticated user's post listings.
+	Hide(context.Context, *PostRequest) (*Empty, error)
+	// Unhide shows a hidden post in the authenticated user's post listings
+	// again.
+	Unhide(context.Context, *PostRequest) (*Empty, error)
+	mustEmbedUnimplementedPostsServer()
+}
+
+// UnimplementedPostsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPostsServer struct{}
+
+func (UnimplementedPostsServer) Get(context.Context, *PostRequest) (*Post, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedPostsServer) List(*ListPostsRequest, grpc.ServerStreamingServer[Post]) error {
+	return status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedPostsServer) Submit(context.Context, *Post) (*SubmitPostResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Submit not implemented")
+}
+func (UnimplementedPostsServer) CreateBatch(context.Context, *CreatePostBatchRequest) (*CreatePostBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBatch not implemented")
+}
+func (UnimplementedPostsServer) Update(context.Context, *UpdatePostRequest) (*Post, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedPostsServer) Delete(context.Context, *PostRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedPostsServer) Flag(context.Context, *PostRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Flag not implemented")
+}
+func (UnimplementedPostsServer) Moderate(context.Context, *ModeratePostRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Moderate not implemented")
+}
+func (UnimplementedPostsServer) Save(context.Context, *PostRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Save not implemented")
+}
+func (UnimplementedPostsServer) Unsave(context.Context, *PostRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unsave not implemented")
+}
+func (UnimplementedPostsServer) Hide(context.Context, *PostRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Hide not implemented")
+}
+func (UnimplementedPostsServer) Unhide(context.Context, *PostRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unhide not implemented")
+}
+func (UnimplementedPostsServer) mustEmbedUnimplementedPostsServer() {}
+func (UnimplementedPostsServer) testEmbeddedByValue()               {}
+
+// UnsafePostsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PostsServer will
+// result in compilation errors.
+type UnsafePostsServer interface {
+	mustEmbedUnimplementedPostsServer()
+}
+
+func RegisterPostsServer(s grpc.ServiceRegistrar, srv PostsServer) {
+	// If the following call pancis, it indicates UnimplementedPostsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Posts_ServiceDesc, srv)
+}
+
+func _Posts_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostsServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Posts_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostsServer).Get(ctx, req.(*PostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Posts_List_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListPostsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PostsServer).List(m, &grpc.GenericServerStream[ListPostsRequest, Post]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Posts_ListServer = grpc.ServerStreamingServer[Post]
+
+func _Posts_Submit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Post)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostsServer).Submit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Posts_Submit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostsServer).Submit(ctx, req.(*Post))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Posts_CreateBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePostBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostsServer).CreateBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Posts_CreateBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostsServer).CreateBatch(ctx, req.(*CreatePostBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Posts_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostsServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Posts_Update_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostsServer).Update(ctx, req.(*UpdatePostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Posts_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostsServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Posts_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostsServer).Delete(ctx, req.(*PostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Posts_Flag_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostsServer).Flag(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Posts_Flag_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostsServer).Flag(ctx, req.(*PostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Posts_Moderate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModeratePostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostsServer).Moderate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Posts_Moderate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostsServer).Moderate(ctx, req.(*ModeratePostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Posts_Save_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostsServer).Save(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Posts_Save_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostsServer).Save(ctx, req.(*PostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Posts_Unsave_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostsServer).Unsave(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Posts_Unsave_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostsServer).Unsave(ctx, req.(*PostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Posts_Hide_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostsServer).Hide(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Posts_Hide_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostsServer).Hide(ctx, req.(*PostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Posts_Unhide_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostsServer).Unhide(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Posts_Unhide_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostsServer).Unhide(ctx, req.(*PostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Posts_ServiceDesc is the grpc.ServiceDesc for Posts service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Posts_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "thesrc.Posts",
+	HandlerType: (*PostsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _Posts_Get_Handler,
+		},
+		{
+			MethodName: "Submit",
+			Handler:    _Posts_Submit_Handler,
+		},
+		{
+			MethodName: "CreateBatch",
+			Handler:    _Posts_CreateBatch_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _Posts_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _Posts_Delete_Handler,
+		},
+		{
+			MethodName: "Flag",
+			Handler:    _Posts_Flag_Handler,
+		},
+		{
+			MethodName: "Moderate",
+			Handler:    _Posts_Moderate_Handler,
+		},
+		{
+			MethodName: "Save",
+			Handler:    _Posts_Save_Handler,
+		},
+		{
+			MethodName: "Unsave",
+			Handler:    _Posts_Unsave_Handler,
+		},
+		{
+			MethodName: "Hide",
+			Handler:    _Posts_Hide_Handler,
+		},
+		{
+			MethodName: "Unhide",
+			Handler:    _Posts_Unhide_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "List",
+			Handler:       _Posts_List_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "posts.proto",
+}
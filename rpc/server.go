@@ -0,0 +1,272 @@
+// Package rpc serves thesrc's posts over gRPC (see posts.proto), for internal
+// services and importers that want typed, streaming-capable access to posts.
+// Clients are created with NewPostsClient.
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative posts.proto
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// Server implements the Posts gRPC service by calling thesrc's API with
+// Client, authenticated as the caller (see WithAuthToken). Like the app, it
+// is a client of the API, so the API's access rules and rate limits apply.
+type Server struct {
+	Client *thesrc.Client
+
+	UnimplementedPostsServer
+}
+
+var _ PostsServer = (*Server)(nil)
+
+// WithAuthToken returns a copy of ctx that authenticates the gRPC requests
+// made with it as the user that token (an API token; see
+// thesrc.TokensService) was issued to.
+func WithAuthToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+// authToken returns the API token that the gRPC request (with context ctx)
+// is authenticated with, or "" if there is none.
+func authToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, v := range md.Get("authorization") {
+		if token := strings.TrimPrefix(v, "Bearer "); token != v {
+			return token
+		}
+	}
+	return ""
+}
+
+// posts returns the PostsService to use for the gRPC request with context
+// ctx.
+func (s *Server) posts(ctx context.Context) thesrc.PostsService {
+	c := s.Client.WithContext(ctx)
+	if token := authToken(ctx); token != "" {
+		c = c.WithAuthToken(token)
+	}
+	return c.Posts
+}
+
+func (s *Server) Get(ctx context.Context, req *PostRequest) (*Post, error) {
+	post, err := s.posts(ctx).Get(int(req.Id))
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return toPost(post), nil
+}
+
+func (s *Server) List(req *ListPostsRequest, stream Posts_ListServer) error {
+	posts, err := s.posts(stream.Context()).List(&thesrc.PostListOptions{
+		CodeOnly:     req.CodeOnly,
+		Sort:         req.Sort,
+		Tag:          req.Tag,
+		Domain:       req.Domain,
+		AuthorUserID: int(req.AuthorUserId),
+		RenderBody:   req.RenderBody,
+		Flagged:      req.Flagged,
+		Saved:        req.Saved,
+		SinceID:      int(req.SinceId),
+		Period:       req.Period,
+		Show:         req.Show,
+		Query:        req.Query,
+		After:        req.After,
+		ListOptions:  thesrc.ListOptions{PerPage: int(req.PerPage), Page: int(req.Page)},
+	})
+	if err != nil {
+		return rpcError(err)
+	}
+	for _, post := range posts {
+		if err := stream.Send(toPost(post)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) Submit(ctx context.Context, req *Post) (*SubmitPostResponse, error) {
+	post := fromPost(req)
+	created, err := s.posts(ctx).Submit(post)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return &SubmitPostResponse{Post: toPost(post), Created: created}, nil
+}
+
+func (s *Server) CreateBatch(ctx context.Context, req *CreatePostBatchRequest) (*CreatePostBatchResponse, error) {
+	posts := make([]*thesrc.Post, len(req.Posts))
+	for i, post := range req.Posts {
+		posts[i] = fromPost(post)
+	}
+	results, err := s.posts(ctx).CreateBatch(posts)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	resp := &CreatePostBatchResponse{Results: make([]*PostBatchResult, len(results))}
+	for i, res := range results {
+		resp.Results[i] = &PostBatchResult{Post: toPost(res.Post), Created: res.Created, Error: res.Error}
+	}
+	return resp, nil
+}
+
+func (s *Server) Update(ctx context.Context, req *UpdatePostRequest) (*Post, error) {
+	if req.Post == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing post")
+	}
+	post := fromPost(req.Post)
+	if err := s.posts(ctx).Update(int(req.Id), post); err != nil {
+		return nil, rpcError(err)
+	}
+	return toPost(post), nil
+}
+
+func (s *Server) Delete(ctx context.Context, req *PostRequest) (*Empty, error) {
+	return empty(s.posts(ctx).Delete(int(req.Id)))
+}
+
+func (s *Server) Flag(ctx context.Context, req *PostRequest) (*Empty, error) {
+	return empty(s.posts(ctx).Flag(int(req.Id)))
+}
+
+func (s *Server) Moderate(ctx context.Context, req *ModeratePostRequest) (*Empty, error) {
+	return empty(s.posts(ctx).Moderate(int(req.Id), &thesrc.PostModeration{Hidden: req.Hidden, Dead: req.Dead, Version: int(req.Version)}))
+}
+
+func (s *Server) Save(ctx context.Context, req *PostRequest) (*Empty, error) {
+	return empty(s.posts(ctx).Save(int(req.Id)))
+}
+
+func (s *Server) Unsave(ctx context.Context, req *PostRequest) (*Empty, error) {
+	return empty(s.posts(ctx).Unsave(int(req.Id)))
+}
+
+func (s *Server) Hide(ctx context.Context, req *PostRequest) (*Empty, error) {
+	return empty(s.posts(ctx).Hide(int(req.Id)))
+}
+
+func (s *Server) Unhide(ctx context.Context, req *PostRequest) (*Empty, error) {
+	return empty(s.posts(ctx).Unhide(int(req.Id)))
+}
+
+func empty(err error) (*Empty, error) {
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return &Empty{}, nil
+}
+
+// rpcError converts an error from thesrc's API to a gRPC error with the
+// status code that corresponds to the API's HTTP status code.
+func rpcError(err error) error {
+	code := codes.Unknown
+	if e, ok := err.(interface {
+		HTTPStatusCode() int
+	}); ok {
+		switch e.HTTPStatusCode() {
+		case http.StatusBadRequest:
+			code = codes.InvalidArgument
+		case http.StatusUnauthorized:
+			code = codes.Unauthenticated
+		case http.StatusForbidden:
+			code = codes.PermissionDenied
+		case http.StatusNotFound:
+			code = codes.NotFound
+		case http.StatusConflict:
+			code = codes.AlreadyExists
+		case http.StatusTooManyRequests:
+			code = codes.ResourceExhausted
+		case http.StatusServiceUnavailable:
+			code = codes.Unavailable
+		}
+	}
+	return status.Error(code, err.Error())
+}
+
+func toPost(p *thesrc.Post) *Post {
+	if p == nil {
+		return nil
+	}
+	var submittedAt int64
+	if !p.SubmittedAt.IsZero() {
+		submittedAt = p.SubmittedAt.UnixNano()
+	}
+	return &Post{
+		Id:                  int64(p.ID),
+		Title:               p.Title,
+		LinkUrl:             p.LinkURL,
+		Domain:              p.Domain,
+		LinkDescription:     p.LinkDescription,
+		LinkImageUrl:        p.LinkImageURL,
+		LinkFaviconUrl:      p.LinkFaviconURL,
+		ThumbnailUrl:        p.ThumbnailURL,
+		Body:                p.Body,
+		BodyHtml:            p.BodyHTML,
+		SubmittedAtUnixNano: submittedAt,
+		AuthorUserId:        int64(p.AuthorUserID),
+		Score:               int64(p.Score),
+		Classification:      p.Classification,
+		Tags:                p.Tags,
+		Voted:               p.Voted,
+		Saved:               p.Saved,
+		HiddenByUser:        p.HiddenByUser,
+		Flags:               int64(p.Flags),
+		Hidden:              p.Hidden,
+		Dead:                p.Dead,
+		SpamScore:           p.SpamScore,
+		LinkStatus:          int64(p.LinkStatus),
+		LinkDead:            p.LinkDead,
+		LinkArchiveUrl:      p.LinkArchiveURL,
+		Version:             int64(p.Version),
+	}
+}
+
+func fromPost(p *Post) *thesrc.Post {
+	if p == nil {
+		return nil
+	}
+	var submittedAt time.Time
+	if p.SubmittedAtUnixNano != 0 {
+		submittedAt = time.Unix(0, p.SubmittedAtUnixNano).UTC()
+	}
+	return &thesrc.Post{
+		ID:              int(p.Id),
+		Title:           p.Title,
+		LinkURL:         p.LinkUrl,
+		Domain:          p.Domain,
+		LinkDescription: p.LinkDescription,
+		LinkImageURL:    p.LinkImageUrl,
+		LinkFaviconURL:  p.LinkFaviconUrl,
+		ThumbnailURL:    p.ThumbnailUrl,
+		Body:            p.Body,
+		BodyHTML:        p.BodyHtml,
+		SubmittedAt:     submittedAt,
+		AuthorUserID:    int(p.AuthorUserId),
+		Score:           int(p.Score),
+		Classification:  p.Classification,
+		Tags:            p.Tags,
+		Voted:           p.Voted,
+		Saved:           p.Saved,
+		HiddenByUser:    p.HiddenByUser,
+		Flags:           int(p.Flags),
+		Hidden:          p.Hidden,
+		Dead:            p.Dead,
+		SpamScore:       p.SpamScore,
+		LinkStatus:      int(p.LinkStatus),
+		LinkDead:        p.LinkDead,
+		LinkArchiveURL:  p.LinkArchiveUrl,
+		Version:         int(p.Version),
+	}
+}
@@ -0,0 +1,199 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func newTestServer(posts *thesrc.MockPostsService) *Server {
+	return &Server{Client: &thesrc.Client{Posts: posts}}
+}
+
+func TestServer_Get(t *testing.T) {
+	submittedAt := time.Date(2014, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestServer(&thesrc.MockPostsService{
+		Get_: func(id int) (*thesrc.Post, error) {
+			if id != 1 {
+				return nil, &thesrc.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound, Request: &http.Request{URL: &url.URL{}}}, Message: "post not found"}
+			}
+			return &thesrc.Post{ID: 1, Title: "t", SubmittedAt: submittedAt, Tags: []string{"go"}}, nil
+		},
+	})
+
+	post, err := s.Get(context.Background(), &PostRequest{Id: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Post{Id: 1, Title: "t", SubmittedAtUnixNano: submittedAt.UnixNano(), Tags: []string{"go"}}
+	if !proto.Equal(post, want) {
+		t.Errorf("got post %+v, want %+v", post, want)
+	}
+	if got := fromPost(post); !got.SubmittedAt.Equal(submittedAt) {
+		t.Errorf("got SubmittedAt %s after round trip, want %s", got.SubmittedAt, submittedAt)
+	}
+
+	if _, err := s.Get(context.Background(), &PostRequest{Id: 2}); status.Code(err) != codes.NotFound {
+		t.Errorf("got error %v for nonexistent post, want code NotFound", err)
+	}
+}
+
+type listServer struct {
+	grpc.ServerStream
+	posts []*Post
+}
+
+func (s *listServer) Context() context.Context { return context.Background() }
+func (s *listServer) Send(p *Post) error       { s.posts = append(s.posts, p); return nil }
+
+func TestServer_List(t *testing.T) {
+	s := newTestServer(&thesrc.MockPostsService{
+		List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+			want := &thesrc.PostListOptions{Tag: "go", Sort: thesrc.SortTop, ListOptions: thesrc.ListOptions{Page: 2}}
+			if !reflect.DeepEqual(opt, want) {
+				t.Errorf("got options %+v, want %+v", opt, want)
+			}
+			return []*thesrc.Post{{ID: 1}, {ID: 2}}, nil
+		},
+	})
+
+	stream := &listServer{}
+	if err := s.List(&ListPostsRequest{Tag: "go", Sort: thesrc.SortTop, Page: 2}, stream); err != nil {
+		t.Fatal(err)
+	}
+	if len(stream.posts) != 2 || stream.posts[0].Id != 1 || stream.posts[1].Id != 2 {
+		t.Errorf("got streamed posts %+v, want posts 1 and 2", stream.posts)
+	}
+}
+
+func TestServer_Submit(t *testing.T) {
+	s := newTestServer(&thesrc.MockPostsService{
+		Submit_: func(post *thesrc.Post) (bool, error) {
+			if post.LinkURL != "http://example.com" {
+				t.Errorf("got submitted post %+v, want link URL http://example.com", post)
+			}
+			post.ID = 3
+			return true, nil
+		},
+	})
+
+	resp, err := s.Submit(context.Background(), &Post{LinkUrl: "http://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&SubmitPostResponse{Post: &Post{Id: 3, LinkUrl: "http://example.com"}, Created: true}); !proto.Equal(resp, want) {
+		t.Errorf("got %+v, want %+v", resp, want)
+	}
+}
+
+func TestServer_Moderate(t *testing.T) {
+	var called bool
+	s := newTestServer(&thesrc.MockPostsService{
+		Moderate_: func(id int, mod *thesrc.PostModeration) error {
+			if id != 1 || !reflect.DeepEqual(mod, &thesrc.PostModeration{Dead: true, Version: 2}) {
+				t.Errorf("got moderation %+v of post %d, want dead post 1 at version 2", mod, id)
+			}
+			called = true
+			return nil
+		},
+	})
+
+	if _, err := s.Moderate(context.Background(), &ModeratePostRequest{Id: 1, Dead: true, Version: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Error("!called")
+	}
+}
+
+// TestServer_grpc tests that posts' versions survive a round trip over a
+// gRPC connection, in both directions.
+func TestServer_grpc(t *testing.T) {
+	var moderated *thesrc.PostModeration
+	s := newTestServer(&thesrc.MockPostsService{
+		Get_: func(id int) (*thesrc.Post, error) {
+			return &thesrc.Post{ID: id, Title: "t", Version: 3}, nil
+		},
+		Update_: func(id int, post *thesrc.Post) error {
+			if post.Version != 3 {
+				t.Errorf("got updated post version %d, want 3", post.Version)
+			}
+			post.Version++
+			return nil
+		},
+		Moderate_: func(id int, mod *thesrc.PostModeration) error {
+			moderated = mod
+			return nil
+		},
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	RegisterPostsServer(srv, s)
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := NewPostsClient(conn)
+	ctx := context.Background()
+
+	post, err := c.Get(ctx, &PostRequest{Id: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Version != 3 {
+		t.Errorf("got version %d, want 3", post.Version)
+	}
+	post, err = c.Update(ctx, &UpdatePostRequest{Id: 1, Post: post})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Version != 4 {
+		t.Errorf("got version %d after updating, want 4", post.Version)
+	}
+	if _, err := c.Moderate(ctx, &ModeratePostRequest{Id: 1, Hidden: true, Version: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (&thesrc.PostModeration{Hidden: true, Version: 4}); !reflect.DeepEqual(moderated, want) {
+		t.Errorf("got moderation %+v, want %+v", moderated, want)
+	}
+}
+
+func TestAuthToken(t *testing.T) {
+	tests := []struct {
+		md   metadata.MD
+		want string
+	}{
+		{nil, ""},
+		{metadata.Pairs("authorization", "Bearer t"), "t"},
+		{metadata.Pairs("authorization", "Basic x"), ""},
+	}
+	for _, test := range tests {
+		ctx := context.Background()
+		if test.md != nil {
+			ctx = metadata.NewIncomingContext(ctx, test.md)
+		}
+		if got := authToken(ctx); got != test.want {
+			t.Errorf("%v: got token %q, want %q", test.md, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package thesrc
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A Session is a login session: the API token issued when a user logs in
+// (see UsersService.Authenticate) and what is known about where it is used.
+// Sessions expire after going unused for too long, and the expiration is
+// extended each time a session is used.
+type Session struct {
+	// ID a unique identifier for this session.
+	ID int `json:",omitempty"`
+
+	// UserID is the ID of the user that this session authenticates as.
+	UserID int
+
+	// Hash is the SHA-256 hash of the session's token. It is never included
+	// in API responses.
+	Hash []byte `json:"-"`
+
+	// UserAgent is the User-Agent of the client that logged in.
+	UserAgent string `json:",omitempty"`
+
+	// CreatedAt is when the user logged in.
+	CreatedAt time.Time
+
+	// LastUsedAt is (approximately) when the session's token was last used.
+	LastUsedAt time.Time
+
+	// Current is whether this is the session that the client is
+	// authenticated with.
+	Current bool `db:"-" json:",omitempty"`
+}
+
+// SessionsService interacts with the session endpoints in thesrc's API. It
+// manages the sessions of the user that the client is authenticated as.
+type SessionsService interface {
+	// List the user's active sessions, most recently used first.
+	List() ([]*Session, error)
+
+	// Revoke one of the user's sessions, logging it out.
+	Revoke(id int) error
+
+	// RevokeCurrent revokes the session that the client is authenticated
+	// with, logging it out.
+	RevokeCurrent() error
+}
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+type sessionsService struct{ client *Client }
+
+func (s *sessionsService) List() ([]*Session, error) {
+	url, err := s.client.url(router.Sessions, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*Session
+	_, err = s.client.Do(req, &sessions)
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (s *sessionsService) Revoke(id int) error {
+	url, err := s.client.url(router.RevokeSession, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *sessionsService) RevokeCurrent() error {
+	url, err := s.client.url(router.RevokeCurrentSession, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+type MockSessionsService struct {
+	List_          func() ([]*Session, error)
+	Revoke_        func(id int) error
+	RevokeCurrent_ func() error
+}
+
+var _ SessionsService = &MockSessionsService{}
+
+func (s *MockSessionsService) List() ([]*Session, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_()
+}
+
+func (s *MockSessionsService) Revoke(id int) error {
+	if s.Revoke_ == nil {
+		return nil
+	}
+	return s.Revoke_(id)
+}
+
+func (s *MockSessionsService) RevokeCurrent() error {
+	if s.RevokeCurrent_ == nil {
+		return nil
+	}
+	return s.RevokeCurrent_()
+}
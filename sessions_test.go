@@ -0,0 +1,83 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestSessionsService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*Session{{ID: 1, UserID: 2, UserAgent: "ua", Current: true}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Sessions, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, want)
+	})
+
+	sessions, err := client.Sessions.List()
+	if err != nil {
+		t.Errorf("Sessions.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, session := range want {
+		normalizeTime(&session.CreatedAt)
+		normalizeTime(&session.LastUsedAt)
+	}
+	if !reflect.DeepEqual(sessions, want) {
+		t.Errorf("Sessions.List returned %+v, want %+v", sessions, want)
+	}
+}
+
+func TestSessionsService_Revoke(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.RevokeSession, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Sessions.Revoke(1); err != nil {
+		t.Errorf("Sessions.Revoke returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestSessionsService_RevokeCurrent(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.RevokeCurrentSession, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Sessions.RevokeCurrent(); err != nil {
+		t.Errorf("Sessions.RevokeCurrent returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
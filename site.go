@@ -0,0 +1,80 @@
+package thesrc
+
+import "sourcegraph.com/sourcegraph/thesrc/router"
+
+// SiteStatus describes the operating state of the whole site.
+type SiteStatus struct {
+	// ReadOnly is whether the site is in read-only mode, in which requests
+	// that would change data fail with HTTP 503 (ErrCodeUnavailable). It is
+	// for use during migrations and incident response.
+	ReadOnly bool
+}
+
+// SiteService interacts with the site administration endpoints in thesrc's
+// API.
+type SiteService interface {
+	// Status gets the site's status.
+	Status() (*SiteStatus, error)
+
+	// UpdateStatus sets the site's status (e.g., to turn read-only mode on
+	// or off). Only admins may update it.
+	UpdateStatus(status *SiteStatus) error
+}
+
+type siteService struct{ client *Client }
+
+func (s *siteService) Status() (*SiteStatus, error) {
+	url, err := s.client.url(router.SiteStatus, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var status *SiteStatus
+	_, err = s.client.Do(req, &status)
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+func (s *siteService) UpdateStatus(status *SiteStatus) error {
+	url, err := s.client.url(router.UpdateSiteStatus, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), status)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, status)
+	return err
+}
+
+type MockSiteService struct {
+	Status_       func() (*SiteStatus, error)
+	UpdateStatus_ func(status *SiteStatus) error
+}
+
+var _ SiteService = &MockSiteService{}
+
+func (s *MockSiteService) Status() (*SiteStatus, error) {
+	if s.Status_ == nil {
+		return &SiteStatus{}, nil
+	}
+	return s.Status_()
+}
+
+func (s *MockSiteService) UpdateStatus(status *SiteStatus) error {
+	if s.UpdateStatus_ == nil {
+		return nil
+	}
+	return s.UpdateStatus_(status)
+}
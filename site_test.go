@@ -0,0 +1,59 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestSiteService_Status(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &SiteStatus{ReadOnly: true}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.SiteStatus, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, want)
+	})
+
+	status, err := client.Site.Status()
+	if err != nil {
+		t.Errorf("Site.Status returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("Site.Status returned %+v, want %+v", status, want)
+	}
+}
+
+func TestSiteService_UpdateStatus(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.UpdateSiteStatus, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+		testBody(t, r, `{"ReadOnly":true}`+"\n")
+
+		writeJSON(w, &SiteStatus{ReadOnly: true})
+	})
+
+	if err := client.Site.UpdateStatus(&SiteStatus{ReadOnly: true}); err != nil {
+		t.Errorf("Site.UpdateStatus returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
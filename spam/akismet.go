@@ -0,0 +1,75 @@
+package spam
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Akismet gives a score of 1 to posts that the Akismet spam detection
+// service (https://akismet.com) considers spam.
+type Akismet struct {
+	// Key is the Akismet API key.
+	Key string
+
+	// Site is the URL of the site (e.g., "https://thesrc.org/").
+	Site string
+
+	// Client sends requests to Akismet. If nil, a client with a 10-second
+	// timeout is used.
+	Client *http.Client
+
+	// endpoint (if set) overrides the Akismet API URL, for tests.
+	endpoint string
+}
+
+var defaultAkismetClient = &http.Client{Timeout: 10 * time.Second}
+
+func (a *Akismet) Name() string { return "akismet" }
+
+func (a *Akismet) Score(s *Submission) (float64, string, error) {
+	endpoint := a.endpoint
+	if endpoint == "" {
+		endpoint = "https://" + a.Key + ".rest.akismet.com/1.1/comment-check"
+	}
+	client := a.Client
+	if client == nil {
+		client = defaultAkismetClient
+	}
+
+	form := url.Values{
+		"blog":               {a.Site},
+		"user_ip":            {s.UserIP},
+		"user_agent":         {s.UserAgent},
+		"comment_type":       {"forum-post"},
+		"comment_content":    {s.Post.Title + "\n\n" + s.Post.Body},
+		"comment_author_url": {s.Post.LinkURL},
+	}
+	if !s.Post.SubmittedAt.IsZero() {
+		form.Set("comment_date_gmt", s.Post.SubmittedAt.UTC().Format(time.RFC3339))
+	}
+	resp, err := client.PostForm(endpoint, form)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, "", err
+	}
+
+	switch strings.TrimSpace(string(body)) {
+	case "true":
+		return 1, "Akismet considers it spam", nil
+	case "false":
+		return 0, "", nil
+	}
+	msg := resp.Header.Get("X-akismet-debug-help")
+	if msg == "" {
+		msg = strings.TrimSpace(string(body))
+	}
+	return 0, "", fmt.Errorf("unexpected Akismet response (HTTP %d): %s", resp.StatusCode, msg)
+}
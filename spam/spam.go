@@ -0,0 +1,212 @@
+// Package spam scores submitted posts for spam, so that likely spam can be
+// held for moderation instead of appearing on the front page.
+package spam
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
+)
+
+// DefaultThreshold is the default value of Filter.Threshold.
+const DefaultThreshold = 1.0
+
+// A Submission is a post being submitted, and information about the request
+// that submitted it.
+type Submission struct {
+	Post *thesrc.Post
+
+	// UserIP and UserAgent are the IP address and User-Agent header of the
+	// client that submitted the post, if known.
+	UserIP    string
+	UserAgent string
+}
+
+// A Check is one stage of the spam scoring pipeline.
+type Check interface {
+	// Name identifies the check in logs and metrics.
+	Name() string
+
+	// Score returns how likely the submission is to be spam (0 if there is
+	// no evidence that it is spam, and 1 if the check alone should hold it)
+	// and, if the score is positive, the reason for it.
+	Score(s *Submission) (score float64, reason string, err error)
+}
+
+// A Filter runs submissions through a pipeline of checks.
+type Filter struct {
+	Checks []Check
+
+	// Threshold is the total score at or above which a submission is
+	// considered spam. If 0, DefaultThreshold is used.
+	Threshold float64
+}
+
+// A Result is the outcome of running a submission through a Filter.
+type Result struct {
+	// Score is the sum of the scores of each check.
+	Score float64
+
+	// Reasons describe why each check that gave a positive score did so.
+	Reasons []string
+
+	// Spam is whether Score is at or above the filter's threshold.
+	Spam bool
+}
+
+var checkResults = metrics.NewCounterVec("thesrc_spam_checks_total",
+	"Spam checks run on submitted posts, by check and result (clean, spam, or error).",
+	"check", "result")
+
+// Check runs s through each of f's checks. A check that fails is logged and
+// ignored, so that an unavailable service (such as Akismet) doesn't prevent
+// posts from being submitted.
+func (f *Filter) Check(s *Submission) *Result {
+	threshold := f.Threshold
+	if threshold == 0 {
+		threshold = DefaultThreshold
+	}
+
+	res := &Result{}
+	for _, c := range f.Checks {
+		score, reason, err := c.Score(s)
+		if err != nil {
+			log.Printf("Spam check %s on post with URL %q: %s", c.Name(), s.Post.LinkURL, err)
+			checkResults.Inc(c.Name(), "error")
+			continue
+		}
+		if score <= 0 {
+			checkResults.Inc(c.Name(), "clean")
+			continue
+		}
+		checkResults.Inc(c.Name(), "spam")
+		res.Score += score
+		res.Reasons = append(res.Reasons, fmt.Sprintf("%s: %s", c.Name(), reason))
+	}
+	res.Spam = res.Score >= threshold
+	return res
+}
+
+// Blocklist gives a score of 1 to posts whose link URL is on a blocked
+// domain (or a subdomain of one) or contains a blocked substring.
+type Blocklist struct {
+	// Domains are the blocked domains.
+	Domains []string
+
+	// Substrings are blocked substrings of link URLs (matched
+	// case-insensitively).
+	Substrings []string
+}
+
+func (b *Blocklist) Name() string { return "blocklist" }
+
+func (b *Blocklist) Score(s *Submission) (float64, string, error) {
+	domain := thesrc.LinkDomain(s.Post.LinkURL)
+	for _, d := range b.Domains {
+		d = thesrc.NormalizeDomain(d)
+		if domain != "" && (domain == d || strings.HasSuffix(domain, "."+d)) {
+			return 1, fmt.Sprintf("domain %s is blocked", d), nil
+		}
+	}
+	linkURL := strings.ToLower(s.Post.LinkURL)
+	for _, sub := range b.Substrings {
+		if sub != "" && strings.Contains(linkURL, strings.ToLower(sub)) {
+			return 1, fmt.Sprintf("link URL contains %q", sub), nil
+		}
+	}
+	return 0, "", nil
+}
+
+// DefaultMinDomainPosts is the default value of DomainReputation.MinPosts.
+const DefaultMinDomainPosts = 3
+
+// DomainReputation scores posts by the fraction of previous posts from the
+// same domain that were hidden or killed (by moderators or by this filter).
+type DomainReputation struct {
+	Domains thesrc.DomainsService
+
+	// MinPosts is the number of previous posts from a domain needed to judge
+	// its reputation. If 0, DefaultMinDomainPosts is used.
+	MinPosts int
+}
+
+func (d *DomainReputation) Name() string { return "domain-reputation" }
+
+func (d *DomainReputation) Score(s *Submission) (float64, string, error) {
+	domain := thesrc.LinkDomain(s.Post.LinkURL)
+	if domain == "" {
+		return 0, "", nil
+	}
+
+	stats, err := d.Domains.Get(domain)
+	if err != nil {
+		return 0, "", err
+	}
+
+	minPosts := d.MinPosts
+	if minPosts == 0 {
+		minPosts = DefaultMinDomainPosts
+	}
+	total := stats.NumPosts + stats.NumHidden
+	if total < minPosts || stats.NumHidden == 0 {
+		return 0, "", nil
+	}
+	return float64(stats.NumHidden) / float64(total), fmt.Sprintf("%d of %d posts from %s were removed", stats.NumHidden, total, domain), nil
+}
+
+// Defaults for the SubmissionRate fields of the same names.
+const (
+	DefaultMaxPosts = 10
+	DefaultWindow   = time.Hour
+)
+
+// SubmissionRate gives a score of 1 to posts by users who have already
+// submitted MaxPosts (listed) posts in the past Window.
+type SubmissionRate struct {
+	Posts thesrc.PostsService
+
+	// MaxPosts is the number of posts that a user may submit in Window
+	// before further posts are considered spam. If 0, DefaultMaxPosts is
+	// used.
+	MaxPosts int
+
+	// Window is the period over which submissions are counted. If 0,
+	// DefaultWindow is used.
+	Window time.Duration
+}
+
+func (r *SubmissionRate) Name() string { return "submission-rate" }
+
+func (r *SubmissionRate) Score(s *Submission) (float64, string, error) {
+	if s.Post.AuthorUserID == 0 {
+		return 0, "", nil
+	}
+
+	maxPosts, window := r.MaxPosts, r.Window
+	if maxPosts == 0 {
+		maxPosts = DefaultMaxPosts
+	}
+	if window == 0 {
+		window = DefaultWindow
+	}
+
+	posts, err := r.Posts.List(&thesrc.PostListOptions{
+		AuthorUserID: s.Post.AuthorUserID,
+		Sort:         thesrc.SortNew,
+		ListOptions:  thesrc.ListOptions{PerPage: maxPosts},
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	if len(posts) < maxPosts {
+		return 0, "", nil
+	}
+	if oldest := posts[maxPosts-1].SubmittedAt; time.Since(oldest) < window {
+		return 1, fmt.Sprintf("user submitted %d posts in the past %s", maxPosts, window), nil
+	}
+	return 0, "", nil
+}
@@ -0,0 +1,142 @@
+package spam
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+type mockCheck struct {
+	score float64
+	err   error
+}
+
+func (c mockCheck) Name() string { return "mock" }
+func (c mockCheck) Score(s *Submission) (float64, string, error) {
+	return c.score, "because", c.err
+}
+
+func TestFilter(t *testing.T) {
+	f := &Filter{Checks: []Check{mockCheck{score: 0.5}, mockCheck{err: errors.New("x")}, mockCheck{score: 0}}}
+	sub := &Submission{Post: &thesrc.Post{LinkURL: "http://example.com"}}
+
+	res := f.Check(sub)
+	if res.Spam || res.Score != 0.5 || len(res.Reasons) != 1 {
+		t.Errorf("got result %+v, want score 0.5 with 1 reason, not spam", res)
+	}
+
+	f.Checks = append(f.Checks, mockCheck{score: 0.5})
+	if res := f.Check(sub); !res.Spam {
+		t.Errorf("got result %+v, want spam (score at threshold)", res)
+	}
+}
+
+func TestBlocklist(t *testing.T) {
+	b := &Blocklist{Domains: []string{"spam.com"}, Substrings: []string{"casino"}}
+	tests := map[string]bool{
+		"http://spam.com/a":           true,
+		"http://www.Spam.com/a":       true,
+		"http://sub.spam.com/a":       true,
+		"http://notspam.com/a":        false,
+		"http://example.com/CASINO/a": true,
+		"http://example.com/golang":   false,
+	}
+	for linkURL, want := range tests {
+		score, _, err := b.Score(&Submission{Post: &thesrc.Post{LinkURL: linkURL}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := score > 0; got != want {
+			t.Errorf("%q: got blocked %v, want %v", linkURL, got, want)
+		}
+	}
+}
+
+func TestDomainReputation(t *testing.T) {
+	stats := map[string]*thesrc.DomainStats{
+		"bad.com":  {Domain: "bad.com", NumPosts: 1, NumHidden: 3},
+		"new.com":  {Domain: "new.com", NumHidden: 1},
+		"good.com": {Domain: "good.com", NumPosts: 10},
+	}
+	d := &DomainReputation{Domains: &thesrc.MockDomainsService{
+		Get_: func(domain string) (*thesrc.DomainStats, error) { return stats[domain], nil },
+	}}
+	tests := map[string]float64{
+		"http://bad.com/":  0.75,
+		"http://new.com/":  0, // too few posts to judge
+		"http://good.com/": 0,
+	}
+	for linkURL, want := range tests {
+		score, _, err := d.Score(&Submission{Post: &thesrc.Post{LinkURL: linkURL}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if score != want {
+			t.Errorf("%q: got score %v, want %v", linkURL, score, want)
+		}
+	}
+}
+
+func TestSubmissionRate(t *testing.T) {
+	var submittedAt time.Time
+	r := &SubmissionRate{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if opt.AuthorUserID != 1 {
+					t.Errorf("got author %d, want 1", opt.AuthorUserID)
+				}
+				return []*thesrc.Post{{SubmittedAt: time.Now()}, {SubmittedAt: submittedAt}}, nil
+			},
+		},
+		MaxPosts: 2,
+		Window:   time.Hour,
+	}
+	sub := &Submission{Post: &thesrc.Post{AuthorUserID: 1}}
+
+	submittedAt = time.Now().Add(-time.Minute)
+	if score, _, _ := r.Score(sub); score != 1 {
+		t.Errorf("got score %v for 2 posts in the past minute, want 1", score)
+	}
+
+	submittedAt = time.Now().Add(-2 * time.Hour)
+	if score, _, _ := r.Score(sub); score != 0 {
+		t.Errorf("got score %v for 2 posts in the past 2 hours, want 0", score)
+	}
+}
+
+func TestAkismet(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.PostFormValue("user_ip"), "1.2.3.4"; got != want {
+			t.Errorf("got user_ip %q, want %q", got, want)
+		}
+		switch r.PostFormValue("comment_author_url") {
+		case "http://spam.com/":
+			w.Write([]byte("true"))
+		case "http://example.com/":
+			w.Write([]byte("false"))
+		default:
+			w.Header().Set("X-akismet-debug-help", "bad request")
+			w.Write([]byte("invalid"))
+		}
+	}))
+	defer s.Close()
+
+	a := &Akismet{Key: "k", Site: "http://thesrc.org/", endpoint: s.URL}
+	score := func(linkURL string) (float64, error) {
+		score, _, err := a.Score(&Submission{Post: &thesrc.Post{LinkURL: linkURL}, UserIP: "1.2.3.4"})
+		return score, err
+	}
+	if s, err := score("http://spam.com/"); err != nil || s != 1 {
+		t.Errorf("got score %v and error %v for spam, want 1", s, err)
+	}
+	if s, err := score("http://example.com/"); err != nil || s != 0 {
+		t.Errorf("got score %v and error %v for ham, want 0", s, err)
+	}
+	if _, err := score("http://other.com/"); err == nil {
+		t.Error("got nil error for invalid Akismet response")
+	}
+}
@@ -0,0 +1,111 @@
+package thesrc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A Tag is a topic (such as "golang" or "postgresql") that posts may be tagged
+// with.
+type Tag struct {
+	// Name is the tag's unique, normalized name.
+	Name string
+
+	// NumPosts is the number of posts tagged with this tag.
+	NumPosts int `json:",omitempty"`
+}
+
+// MaxTagsPerPost is the maximum number of tags that a post may have.
+const MaxTagsPerPost = 5
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9+#.-]{0,29}$`)
+
+// NormalizeTag returns the normalized (trimmed and lowercased) form of tag,
+// or an error if tag is not a valid tag name.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if !tagPattern.MatchString(tag) {
+		return "", fmt.Errorf("invalid tag %q (tags must be 1-30 characters long and contain only letters, numbers, '+', '#', '.', and '-')", tag)
+	}
+	return tag, nil
+}
+
+// NormalizeTags normalizes each tag in tags (see NormalizeTag) and removes
+// duplicates.
+func NormalizeTags(tags []string) ([]string, error) {
+	var norm []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			norm = append(norm, tag)
+		}
+	}
+	if len(norm) > MaxTagsPerPost {
+		return nil, fmt.Errorf("a post may have at most %d tags", MaxTagsPerPost)
+	}
+	return norm, nil
+}
+
+// SplitTags splits a comma- or space-separated list of tags, as entered in a
+// form field.
+func SplitTags(s string) []string {
+	return strings.FieldsFunc(s, func(c rune) bool { return c == ',' || c == ' ' })
+}
+
+// TagsService interacts with the tag-related endpoints in thesrc's API. Like
+// PostsService, it is implemented by both the API client and the datastore.
+type TagsService interface {
+	// List tags, most-used first.
+	List(opt *TagListOptions) ([]*Tag, error)
+}
+
+type TagListOptions struct {
+	// Prefix filters the result set to only those tags whose names begin with
+	// Prefix (for autocompletion).
+	Prefix string `url:",omitempty" json:",omitempty"`
+
+	ListOptions
+}
+
+type tagsService struct{ client *Client }
+
+func (s *tagsService) List(opt *TagListOptions) ([]*Tag, error) {
+	url, err := s.client.url(router.Tags, nil, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []*Tag
+	_, err = s.client.Do(req, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+type MockTagsService struct {
+	List_ func(opt *TagListOptions) ([]*Tag, error)
+}
+
+var _ TagsService = &MockTagsService{}
+
+func (s *MockTagsService) List(opt *TagListOptions) ([]*Tag, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
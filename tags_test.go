@@ -0,0 +1,68 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestTagsService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*Tag{{Name: "golang", NumPosts: 3}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Tags, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"Prefix": "go"})
+
+		writeJSON(w, want)
+	})
+
+	tags, err := client.Tags.List(&TagListOptions{Prefix: "go"})
+	if err != nil {
+		t.Errorf("Tags.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("Tags.List returned %+v, want %+v", tags, want)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{tags: nil, want: nil},
+		{tags: []string{" GoLang ", "c++", "golang"}, want: []string{"golang", "c++"}},
+		{tags: []string{"two words"}, wantErr: true},
+		{tags: []string{""}, wantErr: true},
+		{tags: []string{"a", "b", "c", "d", "e", "f"}, wantErr: true},
+	}
+	for _, test := range tests {
+		tags, err := NormalizeTags(test.tags)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%q: got nil error, want error", test.tags)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: NormalizeTags returned error: %v", test.tags, err)
+			continue
+		}
+		if !reflect.DeepEqual(tags, test.want) {
+			t.Errorf("%q: got %q, want %q", test.tags, tags, test.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+)
+
+// Thumbnail dimensions. Images are scaled to Width; taller images (such as
+// screenshots of long pages) are cropped to MaxHeight, keeping the top.
+const (
+	Width     = 240
+	MaxHeight = 180
+)
+
+// resize scales src to Width pixels wide (cropping its height to
+// MaxHeight), averaging the source pixels that cover each destination pixel.
+func resize(src image.Image) image.Image {
+	b := src.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return src
+	}
+
+	w := Width
+	if b.Dx() < w {
+		w = b.Dx() // don't scale up
+	}
+	h := b.Dy() * w / b.Dx()
+	if h > MaxHeight {
+		h = MaxHeight
+	}
+	if h == 0 {
+		h = 1
+	}
+	// The height of the source region that the thumbnail covers.
+	srcH := h * b.Dx() / w
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*srcH/h, b.Min.Y+(y+1)*srcH/h
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			if x1 == x0 {
+				x1++
+			}
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
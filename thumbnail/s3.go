@@ -0,0 +1,146 @@
+package thumbnail
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Storage stores thumbnails in an Amazon S3 bucket (or an S3-compatible
+// service). The bucket must allow public reads of the stored objects (e.g.,
+// with a bucket policy).
+type S3Storage struct {
+	Bucket string
+	Region string // e.g., "us-east-1"
+
+	// Prefix is prepended to the names of stored objects (e.g.,
+	// "thumbnails/").
+	Prefix string
+
+	// AccessKeyID and SecretAccessKey are the AWS credentials to sign requests
+	// with.
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// Endpoint is the URL of the bucket. If empty, it is
+	// https://<Bucket>.s3.<Region>.amazonaws.com.
+	Endpoint string
+
+	// BaseURL is the public URL prefix of stored objects (e.g., a CDN in
+	// front of the bucket). If empty, Endpoint is used.
+	BaseURL string
+
+	// HTTPClient is used to make requests to S3. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	now func() time.Time // for testing
+}
+
+func (s *S3Storage) endpoint() string {
+	if s.Endpoint != "" {
+		return strings.TrimSuffix(s.Endpoint, "/")
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com", s.Bucket, s.Region)
+}
+
+func (s *S3Storage) Put(name string, data []byte, contentType string) (string, error) {
+	key := s.Prefix + name
+	u, err := url.Parse(s.endpoint() + "/" + key)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("PUT", u.String(), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Cache-Control", "public, max-age=31536000")
+	s.sign(req, data)
+
+	c := s.HTTPClient
+	if c == nil {
+		c = http.DefaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("S3 PUT %s: HTTP status %d: %s", key, resp.StatusCode, body)
+	}
+
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = s.endpoint() + "/"
+	}
+	return baseURL + key, nil
+}
+
+// sign signs req (whose body is payload) with AWS Signature Version 4. See
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html.
+func (s *S3Storage) sign(req *http.Request, payload []byte) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	payloadHash := hexSHA256(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := []string{"cache-control", "content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	var canonicalHeaders strings.Builder
+	for _, h := range signedHeaders {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", h, strings.TrimSpace(v))
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,52 @@
+package thumbnail
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestS3Storage_Put(t *testing.T) {
+	var called bool
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if r.Method != "PUT" || r.URL.Path != "/thumbs/1.jpg" {
+			t.Errorf("got %s %s, want PUT /thumbs/1.jpg", r.Method, r.URL.Path)
+		}
+		wantAuthz := "AWS4-HMAC-SHA256 Credential=AKID/20150830/us-east-1/s3/aws4_request, SignedHeaders=cache-control;content-type;host;x-amz-content-sha256;x-amz-date, Signature="
+		if authz := r.Header.Get("Authorization"); !strings.HasPrefix(authz, wantAuthz) || len(authz) != len(wantAuthz)+64 {
+			t.Errorf("got Authorization %q, want prefix %q and 64-digit signature", authz, wantAuthz)
+		}
+		if got, want := r.Header.Get("X-Amz-Date"), "20150830T123600Z"; got != want {
+			t.Errorf("got X-Amz-Date %q, want %q", got, want)
+		}
+		if body, _ := io.ReadAll(r.Body); string(body) != "data" {
+			t.Errorf("got body %q, want %q", body, "data")
+		}
+	}))
+	defer s.Close()
+
+	storage := &S3Storage{
+		Bucket:          "b",
+		Region:          "us-east-1",
+		Prefix:          "thumbs/",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Endpoint:        s.URL,
+		BaseURL:         "https://cdn.example.com/",
+		now:             func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+	}
+	url, err := storage.Put("1.jpg", []byte("data"), "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	if want := "https://cdn.example.com/thumbs/1.jpg"; url != want {
+		t.Errorf("got URL %q, want %q", url, want)
+	}
+}
@@ -0,0 +1,52 @@
+package thumbnail
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// A Storage stores thumbnail images and serves them at public URLs.
+type Storage interface {
+	// Put stores data under name and returns the URL it can be fetched from.
+	Put(name string, data []byte, contentType string) (url string, err error)
+}
+
+// DirStorage stores thumbnails as files in a local directory, which must be
+// served (e.g., by http.FileServer) at BaseURL.
+type DirStorage struct {
+	// Dir is the directory to store thumbnails in. It is created if it
+	// doesn't exist.
+	Dir string
+
+	// BaseURL is the URL prefix that Dir is served at, such as
+	// "/thumbnails/".
+	BaseURL string
+}
+
+func (s *DirStorage) Put(name string, data []byte, contentType string) (string, error) {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file and rename it, so that a partially written
+	// thumbnail is never served.
+	tmp, err := os.CreateTemp(s.Dir, ".tmp-"+name)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.Dir, name)); err != nil {
+		return "", err
+	}
+	return s.BaseURL + name, nil
+}
@@ -0,0 +1,188 @@
+// Package thumbnail generates thumbnail images for posts' linked pages, using
+// each page's OpenGraph image or (if it has none) a screenshot.
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
+	"sourcegraph.com/sourcegraph/thesrc/unfurl"
+)
+
+// ScreenshotCommand, if set, is the command (and arguments) run to take a
+// screenshot of a page that has no OpenGraph image. In the arguments,
+// "{{url}}" is replaced by the page's URL and "{{file}}" by the path of the
+// PNG file to write. For example:
+//
+//	chromium --headless --disable-gpu --window-size=1280,800 --screenshot={{file}} {{url}}
+//
+// Note that unlike unfurl.Fetch, the command may connect to any address.
+var ScreenshotCommand []string
+
+// ScreenshotTimeout is how long ScreenshotCommand may run.
+var ScreenshotTimeout = 30 * time.Second
+
+// maxImageSize is the largest OpenGraph image that is downloaded.
+const maxImageSize = 5 << 20
+
+var errNoImage = errors.New("no image found")
+
+// fetchMetadata and download fetch pages and images. They are variables so
+// that tests can replace them.
+var (
+	fetchMetadata = unfurl.Fetch
+	download      = unfurl.Download
+)
+
+var generated = metrics.NewCounterVec("thesrc_thumbnails_total",
+	"Thumbnails attempted, by source (og:image or screenshot) or error.", "result")
+
+// A Worker generates thumbnails for posts that don't have them yet.
+type Worker struct {
+	Store   datastore.ThumbnailsStore
+	Storage Storage
+
+	// Interval is how long to wait between checks for new posts.
+	Interval time.Duration
+}
+
+// batchSize is the maximum number of posts processed per check.
+const batchSize = 20
+
+// Run generates thumbnails until stop is closed.
+func (w *Worker) Run(stop <-chan struct{}) {
+	for {
+		if _, err := w.RunOnce(); err != nil {
+			log.Print("Thumbnail worker: ", err)
+		}
+		select {
+		case <-time.After(w.Interval):
+		case <-stop:
+			return
+		}
+	}
+}
+
+// RunOnce generates thumbnails for up to batchSize pending posts and
+// returns the number of thumbnails it generated. Posts whose thumbnails
+// can't be generated are not retried.
+func (w *Worker) RunOnce() (int, error) {
+	posts, err := w.Store.ListPending(batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, post := range posts {
+		url, err := w.generate(post)
+		if err != nil {
+			log.Printf("Thumbnail for post %d (%s): %s", post.ID, post.LinkURL, err)
+		} else {
+			n++
+		}
+		if err := w.Store.Set(post.ID, url); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// generate makes and stores a thumbnail for post, returning its URL.
+func (w *Worker) generate(post *thesrc.Post) (string, error) {
+	img, source, err := postImage(post)
+	if err != nil {
+		generated.Inc("error")
+		return "", err
+	}
+	generated.Inc(source)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resize(img), &jpeg.Options{Quality: 80}); err != nil {
+		return "", err
+	}
+	return w.Storage.Put(fmt.Sprintf("%d.jpg", post.ID), buf.Bytes(), "image/jpeg")
+}
+
+// postImage returns a representative image for post's linked page, and its
+// source ("og:image" or "screenshot").
+func postImage(post *thesrc.Post) (image.Image, string, error) {
+	imageURL := post.LinkImageURL
+	if imageURL == "" {
+		if meta, err := fetchMetadata(post.LinkURL); err == nil {
+			imageURL = meta.ImageURL
+		}
+	}
+
+	var imgErr error
+	if imageURL != "" {
+		data, _, err := download(imageURL, maxImageSize)
+		if err == nil {
+			img, _, err := image.Decode(bytes.NewReader(data))
+			if err == nil {
+				return img, "og:image", nil
+			}
+			imgErr = fmt.Errorf("decoding %s: %s", imageURL, err)
+		} else {
+			imgErr = err
+		}
+	}
+
+	if len(ScreenshotCommand) == 0 {
+		if imgErr != nil {
+			return nil, "", imgErr
+		}
+		return nil, "", errNoImage
+	}
+	img, err := screenshot(post.LinkURL)
+	if err != nil {
+		return nil, "", err
+	}
+	return img, "screenshot", nil
+}
+
+// screenshot runs ScreenshotCommand to take a screenshot of the page at
+// pageURL.
+func screenshot(pageURL string) (image.Image, error) {
+	dir, err := os.MkdirTemp("", "thesrc-screenshot")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "screenshot.png")
+
+	args := make([]string, len(ScreenshotCommand))
+	for i, arg := range ScreenshotCommand {
+		args[i] = strings.NewReplacer("{{url}}", pageURL, "{{file}}", file).Replace(arg)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ScreenshotTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("screenshot command failed: %s\n%s", err, out)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	return img, err
+}
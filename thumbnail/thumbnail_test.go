@@ -0,0 +1,109 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestWorker_RunOnce(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 600, 400))); err != nil {
+		t.Fatal(err)
+	}
+
+	origFetch, origDownload := fetchMetadata, download
+	defer func() { fetchMetadata, download = origFetch, origDownload }()
+	fetchMetadata = func(linkURL string) (*thesrc.LinkMetadata, error) {
+		if linkURL == "http://example.com/fetched" {
+			return &thesrc.LinkMetadata{ImageURL: "http://example.com/og.png"}, nil
+		}
+		return &thesrc.LinkMetadata{}, nil
+	}
+	download = func(urlStr string, maxSize int64) ([]byte, string, error) {
+		if urlStr == "http://example.com/og.png" {
+			return pngData.Bytes(), "image/png", nil
+		}
+		return nil, "", errors.New("not found")
+	}
+
+	d := datastore.NewMemoryDatastore()
+	for _, p := range []*thesrc.Post{
+		{LinkURL: "http://example.com/stored", LinkImageURL: "http://example.com/og.png"},
+		{LinkURL: "http://example.com/fetched"},
+		{LinkURL: "http://example.com/none"},
+	} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := t.TempDir()
+	w := &Worker{Store: d.Thumbnails, Storage: &DirStorage{Dir: dir, BaseURL: "/thumbnails/"}}
+	n, err := w.RunOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("got %d thumbnails, want 2", n)
+	}
+
+	wantURLs := map[int]string{1: "/thumbnails/1.jpg", 2: "/thumbnails/2.jpg", 3: ""}
+	for id, want := range wantURLs {
+		post, err := d.Posts.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.ThumbnailURL != want {
+			t.Errorf("post %d: got ThumbnailURL %q, want %q", id, post.ThumbnailURL, want)
+		}
+	}
+
+	f, err := os.Open(filepath.Join(dir, "1.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != Width || cfg.Height != 160 {
+		t.Errorf("got thumbnail size %dx%d, want %dx%d", cfg.Width, cfg.Height, Width, 160)
+	}
+
+	// Posts are only attempted once.
+	if n, err := w.RunOnce(); err != nil || n != 0 {
+		t.Errorf("got %d thumbnails and error %v on second run, want 0 and nil", n, err)
+	}
+}
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		w, h         int
+		wantW, wantH int
+	}{
+		{480, 360, Width, MaxHeight},
+		{1280, 4000, Width, MaxHeight},
+		{960, 200, Width, 50},
+		{100, 50, 100, 50},
+	}
+	for _, test := range tests {
+		src := image.NewRGBA(image.Rect(0, 0, test.w, test.h))
+		for x := 0; x < test.w; x++ {
+			src.Set(x, 0, color.White)
+		}
+		dst := resize(src)
+		if b := dst.Bounds(); b.Dx() != test.wantW || b.Dy() != test.wantH {
+			t.Errorf("%dx%d: got %dx%d, want %dx%d", test.w, test.h, b.Dx(), b.Dy(), test.wantW, test.wantH)
+		}
+	}
+}
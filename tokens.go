@@ -0,0 +1,144 @@
+package thesrc
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A Token is a personal API token, which a user creates to authenticate
+// scripts and command-line tools (such as "thesrc post") as themselves.
+// Unlike the tokens issued by UsersService.Authenticate, personal tokens
+// don't expire; they remain valid until they are revoked.
+type Token struct {
+	// ID a unique identifier for this token.
+	ID int `json:",omitempty"`
+
+	// UserID is the ID of the user that this token authenticates as.
+	UserID int
+
+	// Name describes what the token is used for.
+	Name string
+
+	// Hash is the SHA-256 hash of the token's value. It is never included
+	// in API responses.
+	Hash []byte `json:"-"`
+
+	// Value is the secret token itself. It is only set in the response to
+	// TokensService.Create, because only its hash is stored.
+	Value string `db:"-" json:",omitempty"`
+
+	// CreatedAt is when the token was created.
+	CreatedAt time.Time
+}
+
+// PersonalTokenPrefix begins the value of every personal API token, to
+// distinguish them from the tokens issued by UsersService.Authenticate.
+const PersonalTokenPrefix = "thesrc_"
+
+// TokensService interacts with the personal API token endpoints in thesrc's
+// API. It manages the tokens of the user that the client is authenticated
+// as.
+type TokensService interface {
+	// List the user's personal API tokens.
+	List() ([]*Token, error)
+
+	// Create a personal API token. The returned token's Value is the secret
+	// token, which can't be retrieved again later.
+	Create(name string) (*Token, error)
+
+	// Revoke (delete) one of the user's personal API tokens.
+	Revoke(id int) error
+}
+
+var (
+	ErrTokenNotFound = errors.New("token not found")
+)
+
+type tokensService struct{ client *Client }
+
+func (s *tokensService) List() ([]*Token, error) {
+	url, err := s.client.url(router.Tokens, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []*Token
+	_, err = s.client.Do(req, &tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *tokensService) Create(name string) (*Token, error) {
+	url, err := s.client.url(router.CreateToken, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), &Token{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	var token *Token
+	_, err = s.client.Do(req, &token)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (s *tokensService) Revoke(id int) error {
+	url, err := s.client.url(router.RevokeToken, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+type MockTokensService struct {
+	List_   func() ([]*Token, error)
+	Create_ func(name string) (*Token, error)
+	Revoke_ func(id int) error
+}
+
+var _ TokensService = &MockTokensService{}
+
+func (s *MockTokensService) List() ([]*Token, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_()
+}
+
+func (s *MockTokensService) Create(name string) (*Token, error) {
+	if s.Create_ == nil {
+		return nil, nil
+	}
+	return s.Create_(name)
+}
+
+func (s *MockTokensService) Revoke(id int) error {
+	if s.Revoke_ == nil {
+		return nil
+	}
+	return s.Revoke_(id)
+}
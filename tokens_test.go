@@ -0,0 +1,110 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestTokensService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*Token{{ID: 1, UserID: 2, Name: "n"}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Tokens, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, want)
+	})
+
+	tokens, err := client.Tokens.List()
+	if err != nil {
+		t.Errorf("Tokens.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, token := range want {
+		normalizeTime(&token.CreatedAt)
+	}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("Tokens.List returned %+v, want %+v", tokens, want)
+	}
+}
+
+func TestTokensService_Create(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Token{ID: 1, UserID: 2, Name: "n", Value: PersonalTokenPrefix + "x"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.CreateToken, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"UserID":0,"Name":"n","CreatedAt":"0001-01-01T00:00:00Z"}`+"\n")
+
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, want)
+	})
+
+	token, err := client.Tokens.Create("n")
+	if err != nil {
+		t.Errorf("Tokens.Create returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.CreatedAt)
+	if !reflect.DeepEqual(token, want) {
+		t.Errorf("Tokens.Create returned %+v, want %+v", token, want)
+	}
+}
+
+func TestTokensService_Revoke(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.RevokeToken, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Tokens.Revoke(1); err != nil {
+		t.Errorf("Tokens.Revoke returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestNewClient_AuthTokenOption(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc(urlPath(t, router.CurrentUser, nil), func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Authorization"), "Bearer tok"; got != want {
+			t.Errorf("got Authorization header %q, want %q", got, want)
+		}
+		writeJSON(w, &User{ID: 1})
+	})
+
+	c := NewClient(nil, AuthTokenOption("tok"))
+	c.BaseURL = client.BaseURL
+	if _, err := c.Users.Current(); err != nil {
+		t.Fatal(err)
+	}
+}
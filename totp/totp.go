@@ -0,0 +1,117 @@
+// Package totp implements time-based one-time passwords (TOTP, RFC 6238),
+// the 6-digit codes generated by authenticator apps (such as Google
+// Authenticator) for two-factor authentication.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits is the number of digits in a code.
+	Digits = 6
+
+	// Period is how long each code is valid for.
+	Period = 30 * time.Second
+
+	// Skew is the number of periods before and after the current one whose
+	// codes are also accepted by Validate, to allow for clock drift and for
+	// codes that expire while they are being typed.
+	Skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret returns a new random secret, base32-encoded (as authenticator
+// apps expect it to be entered).
+func NewSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// Code returns the code for secret at time t.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return code(key, uint64(t.Unix()/int64(Period/time.Second))), nil
+}
+
+// Validate returns whether code is the code for secret at time t (or within
+// Skew periods of t). Spaces in code are ignored.
+func Validate(secret, code string, t time.Time) bool {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return false
+	}
+	code = strings.Replace(code, " ", "", -1)
+	if len(code) != Digits {
+		return false
+	}
+	step := t.Unix() / int64(Period/time.Second)
+	for i := -Skew; i <= Skew; i++ {
+		if subtle.ConstantTimeCompare([]byte(code), []byte(codeAt(key, step+int64(i)))) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// URL returns the otpauth URL of secret, which authenticator apps read from
+// QR codes. The account (such as a login) and issuer (the site's name) are
+// shown in the app to identify the secret.
+func URL(issuer, account, secret string) string {
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: url.Values{"secret": {secret}, "issuer": {issuer}}.Encode(),
+	}
+	return u.String()
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.Replace(secret, " ", "", -1))
+	key, err := encoding.DecodeString(strings.TrimRight(secret, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %s", err)
+	}
+	if len(key) == 0 {
+		return nil, errors.New("empty TOTP secret")
+	}
+	return key, nil
+}
+
+func codeAt(key []byte, step int64) string {
+	if step < 0 {
+		return ""
+	}
+	return code(key, uint64(step))
+}
+
+// code returns the HOTP (RFC 4226) code for key and counter.
+func code(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0xf
+	n := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, n%1000000)
+}
@@ -0,0 +1,84 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA-1 test vectors of RFC 6238, Appendix B, truncated to 6 digits.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, test := range tests {
+		code, err := Code(rfcSecret, time.Unix(test.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != test.want {
+			t.Errorf("%d: got code %q, want %q", test.unix, code, test.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"050471", true},
+		{"050 471", true},
+		{"081804", true}, // previous period
+		{"050472", false},
+		{"", false},
+		{"50471", false},
+	}
+	for _, test := range tests {
+		if got := Validate(rfcSecret, test.code, now); got != test.want {
+			t.Errorf("%q: got %v, want %v", test.code, got, test.want)
+		}
+	}
+
+	if Validate(rfcSecret, "050471", now.Add(2*Period)) {
+		t.Error("got valid for code from 2 periods ago, want invalid")
+	}
+	for _, secret := range []string{"not base32!", ""} {
+		if Validate(secret, "050471", now) {
+			t.Errorf("%q: got valid for invalid secret, want invalid", secret)
+		}
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := Code(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Validate(strings.ToLower(secret), code, time.Now()) {
+		t.Errorf("code %q for new secret %q is invalid", code, secret)
+	}
+}
+
+func TestURL(t *testing.T) {
+	got := URL("thesrc", "alice", "JBSWY3DPEHPK3PXP")
+	want := "otpauth://totp/thesrc:alice?issuer=thesrc&secret=JBSWY3DPEHPK3PXP"
+	if got != want {
+		t.Errorf("got URL %q, want %q", got, want)
+	}
+}
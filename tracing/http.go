@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"sourcegraph.com/sourcegraph/thesrc/httpstatus"
+)
+
+// Handler wraps h so that each request is traced with a server span, which
+// continues the caller's trace if the request has a traceparent header. The
+// span is carried by the request's context, so spans started while serving
+// the request are its children.
+func Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if Exporter == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, span := Start(Extract(r.Context(), r.Header), KindServer, r.Method,
+			"http.method", r.Method,
+			"http.target", r.URL.RequestURI(),
+		)
+		rw := httpstatus.NewRecorder(w)
+		h.ServeHTTP(rw, r.WithContext(ctx))
+
+		span.SetAttributes("http.status_code", rw.Status())
+		if rw.Status() >= 500 {
+			span.SetError(errors.New("HTTP " + strconv.Itoa(rw.Status())))
+		}
+		span.Finish()
+	})
+}
+
+// SetRoute names the span of r (see Handler) after the route that matched
+// r.
+func SetRoute(r *http.Request, name string) {
+	if span := SpanFromContext(r.Context()); span != nil {
+		span.SetName(r.Method + " " + name)
+		span.SetAttributes("http.route", name)
+	}
+}
@@ -0,0 +1,236 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// An OTLPExporter exports spans in batches to an OpenTelemetry collector
+// (or any other backend that accepts OTLP/HTTP with JSON encoding).
+type OTLPExporter struct {
+	// URL is the collector's OTLP/HTTP traces endpoint (for example,
+	// "http://localhost:4318/v1/traces").
+	URL string
+
+	// ServiceName is the service.name resource attribute of the exported
+	// spans.
+	ServiceName string
+
+	// Headers are added to each export request (for example, to
+	// authenticate to a hosted collector).
+	Headers http.Header
+
+	// BatchSize is the number of spans that, once buffered, are exported
+	// without waiting for the next flush. If 0, 512 is used.
+	BatchSize int
+
+	// MaxQueueSize is the number of spans that may be buffered. Spans that
+	// end while the buffer is full are dropped. If 0, 2048 is used.
+	MaxQueueSize int
+
+	// HTTPClient is used to make export requests. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	mu      sync.Mutex
+	spans   []*Span
+	flushMu sync.Mutex // held while exporting, so batches are sent in order
+}
+
+// ExportSpan buffers s to be exported in the next batch.
+func (e *OTLPExporter) ExportSpan(s *Span) {
+	batchSize, maxQueueSize := e.BatchSize, e.MaxQueueSize
+	if batchSize <= 0 {
+		batchSize = 512
+	}
+	if maxQueueSize <= 0 {
+		maxQueueSize = 2048
+	}
+
+	e.mu.Lock()
+	if len(e.spans) >= maxQueueSize {
+		e.mu.Unlock()
+		return
+	}
+	e.spans = append(e.spans, s)
+	full := len(e.spans) == batchSize
+	e.mu.Unlock()
+
+	if full {
+		go func() {
+			if err := e.Flush(); err != nil {
+				log.Print("Exporting traces: ", err)
+			}
+		}()
+	}
+}
+
+// Run flushes buffered spans every interval until stop is closed, logging
+// export errors.
+func (e *OTLPExporter) Run(interval time.Duration, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := e.Flush(); err != nil {
+				log.Print("Exporting traces: ", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Flush exports all buffered spans. If exporting them fails, they are
+// dropped and the error is returned.
+func (e *OTLPExporter) Flush() error {
+	e.flushMu.Lock()
+	defer e.flushMu.Unlock()
+
+	e.mu.Lock()
+	spans := e.spans
+	e.spans = nil
+	e.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", e.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, vs := range e.Headers {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	c := e.HTTPClient
+	if c == nil {
+		c = http.DefaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("exporting %d spans: %s", len(spans), err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("exporting %d spans: HTTP %d from %s", len(spans), resp.StatusCode, e.URL)
+	}
+	return nil
+}
+
+// The following types are the JSON encoding of an OTLP
+// ExportTraceServiceRequest (see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding).
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 2 is STATUS_CODE_ERROR
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func (e *OTLPExporter) request(spans []*Span) *otlpRequest {
+	scope := otlpScopeSpans{Spans: make([]otlpSpan, len(spans))}
+	scope.Scope.Name = "sourcegraph.com/sourcegraph/thesrc/tracing"
+	for i, s := range spans {
+		s.mu.Lock()
+		o := otlpSpan{
+			TraceID:           hex.EncodeToString(s.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.SpanID[:]),
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.attrs),
+		}
+		if s.ParentID != [8]byte{} {
+			o.ParentSpanID = hex.EncodeToString(s.ParentID[:])
+		}
+		if s.Err != "" {
+			o.Status = &otlpStatus{Code: 2, Message: s.Err}
+		}
+		s.mu.Unlock()
+		scope.Spans[i] = o
+	}
+
+	return &otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes([]interface{}{"service.name", e.ServiceName})},
+		ScopeSpans: []otlpScopeSpans{scope},
+	}}}
+}
+
+func otlpAttributes(keyvals []interface{}) []otlpKeyValue {
+	attrs := make([]otlpKeyValue, 0, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		var v map[string]interface{}
+		switch x := keyvals[i+1].(type) {
+		case string:
+			v = map[string]interface{}{"stringValue": x}
+		case bool:
+			v = map[string]interface{}{"boolValue": x}
+		case int:
+			v = map[string]interface{}{"intValue": strconv.Itoa(x)}
+		case int64:
+			v = map[string]interface{}{"intValue": strconv.FormatInt(x, 10)}
+		case float64:
+			v = map[string]interface{}{"doubleValue": x}
+		case error:
+			v = map[string]interface{}{"stringValue": x.Error()}
+		default:
+			v = map[string]interface{}{"stringValue": fmt.Sprint(x)}
+		}
+		attrs = append(attrs, otlpKeyValue{Key: fmt.Sprint(keyvals[i]), Value: v})
+	}
+	return attrs
+}
@@ -0,0 +1,224 @@
+// Package tracing records OpenTelemetry-compatible trace spans of HTTP
+// requests, API calls, template rendering, and datastore operations, and
+// exports them to an OpenTelemetry collector over OTLP (see OTLPExporter).
+// Traces are propagated between services with the W3C traceparent header.
+//
+// Tracing is disabled (and costs almost nothing) unless Exporter is set.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A SpanExporter receives spans when they end.
+type SpanExporter interface {
+	ExportSpan(*Span)
+}
+
+var (
+	// Exporter receives spans when they end. If nil, tracing is disabled.
+	Exporter SpanExporter
+
+	// SampleRatio is the fraction of traces (started by requests without
+	// a traceparent header) that are recorded and exported. Traces
+	// continued from a traceparent header are recorded if the caller
+	// recorded them.
+	SampleRatio = 1.0
+)
+
+// A SpanKind describes a span's relationship to its parent and children
+// (as in OpenTelemetry).
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1 // an operation within the process
+	KindServer   SpanKind = 2 // serving a request from a remote client
+	KindClient   SpanKind = 3 // a request to a remote service (such as the database)
+)
+
+// A Span records one operation (such as serving an HTTP request or running
+// a query) within a trace. A nil *Span is valid and records nothing, so
+// callers need not check whether tracing is enabled.
+type Span struct {
+	TraceID  [16]byte
+	SpanID   [8]byte
+	ParentID [8]byte // zero for a trace's root span
+	Name     string
+	Kind     SpanKind
+	Start    time.Time
+	End      time.Time
+
+	// Err (if set) is the error that the operation failed with.
+	Err string
+
+	sampled bool
+
+	mu    sync.Mutex
+	attrs []interface{} // alternating keys and values
+}
+
+// Attrs returns the span's attributes, as a list of alternating keys and
+// values.
+func (s *Span) Attrs() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]interface{}(nil), s.attrs...)
+}
+
+// SetName renames the span (for example, once an HTTP request's route is
+// known).
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.Name = name
+	s.mu.Unlock()
+}
+
+// SetAttributes adds the attributes in keyvals, a list of alternating keys
+// and values, to the span.
+func (s *Span) SetAttributes(keyvals ...interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, keyvals...)
+	s.mu.Unlock()
+}
+
+// SetError marks the span's operation as having failed with err (if
+// non-nil).
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.Err = err.Error()
+	s.mu.Unlock()
+}
+
+// Finish ends the span and, if its trace is sampled, exports it.
+func (s *Span) Finish() {
+	s.finishAt(time.Now())
+}
+
+func (s *Span) finishAt(end time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.End = end
+	s.mu.Unlock()
+	if s.sampled && Exporter != nil {
+		Exporter.ExportSpan(s)
+	}
+}
+
+type contextKey int
+
+const spanKey contextKey = 0
+
+// SpanFromContext returns the span carried by ctx, or nil if there is none
+// (or ctx is nil).
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(spanKey).(*Span)
+	return s
+}
+
+// Start starts a span named name, with the attributes in keyvals, as a child
+// of the span carried by ctx (or as the root span of a new trace). It returns
+// the span and a copy of ctx that carries it. Callers must call the span's
+// Finish method when the operation ends. If tracing is disabled, Start
+// returns ctx and a nil span.
+func Start(ctx context.Context, kind SpanKind, name string, keyvals ...interface{}) (context.Context, *Span) {
+	if Exporter == nil {
+		return ctx, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	s := &Span{Name: name, Kind: kind, Start: time.Now(), attrs: keyvals}
+	if parent := SpanFromContext(ctx); parent != nil {
+		s.TraceID = parent.TraceID
+		s.ParentID = parent.SpanID
+		s.sampled = parent.sampled
+	} else {
+		randomID(s.TraceID[:])
+		s.sampled = mathrand.Float64() < SampleRatio
+	}
+	randomID(s.SpanID[:])
+	return context.WithValue(ctx, spanKey, s), s
+}
+
+// Record records a span named name for an operation that started at start
+// and has just ended, as a child of the span carried by ctx. It does nothing
+// if ctx carries no span, so operations are only traced as part of a traced
+// request.
+func Record(ctx context.Context, kind SpanKind, name string, start time.Time, keyvals ...interface{}) {
+	if SpanFromContext(ctx) == nil {
+		return
+	}
+	_, s := Start(ctx, kind, name, keyvals...)
+	s.Start = start
+	s.Finish()
+}
+
+func randomID(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+}
+
+// TraceparentHeader is the W3C Trace Context header that propagates traces
+// between services.
+const TraceparentHeader = "traceparent"
+
+// Inject adds a traceparent header for the span carried by ctx (if any) to
+// h, so that the service that h is sent to continues the trace.
+func Inject(ctx context.Context, h http.Header) {
+	s := SpanFromContext(ctx)
+	if s == nil {
+		return
+	}
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	h.Set(TraceparentHeader, fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(s.TraceID[:]), hex.EncodeToString(s.SpanID[:]), flags))
+}
+
+// Extract returns a copy of ctx that carries the remote span described by
+// h's traceparent header, so that spans started with it continue the remote
+// trace. If h has no valid traceparent header, ctx is returned.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	parts := strings.Split(h.Get(TraceparentHeader), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return ctx
+	}
+	var s Span
+	var flags [1]byte
+	if _, err := hex.Decode(s.TraceID[:], []byte(parts[1])); err != nil || s.TraceID == [16]byte{} {
+		return ctx
+	}
+	if _, err := hex.Decode(s.SpanID[:], []byte(parts[2])); err != nil || s.SpanID == [8]byte{} {
+		return ctx
+	}
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return ctx
+	}
+	s.sampled = flags[0]&1 == 1
+	return context.WithValue(ctx, spanKey, &s)
+}
@@ -0,0 +1,203 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a SpanExporter that records the spans it receives.
+type recorder struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (r *recorder) ExportSpan(s *Span) {
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+}
+
+// setup enables tracing with a recorder, and returns the recorder and a func
+// that disables tracing again.
+func setup(sampleRatio float64) (*recorder, func()) {
+	rec := &recorder{}
+	Exporter, SampleRatio = rec, sampleRatio
+	return rec, func() { Exporter, SampleRatio = nil, 1 }
+}
+
+func TestStart_disabled(t *testing.T) {
+	ctx := context.Background()
+	ctx2, span := Start(ctx, KindInternal, "s")
+	if span != nil || ctx2 != ctx {
+		t.Errorf("got span %v, want nil when tracing is disabled", span)
+	}
+	// Nil spans are no-ops.
+	span.SetName("x")
+	span.SetAttributes("k", "v")
+	span.SetError(errors.New("e"))
+	span.Finish()
+}
+
+func TestStart(t *testing.T) {
+	rec, teardown := setup(1)
+	defer teardown()
+
+	ctx, parent := Start(context.Background(), KindServer, "parent")
+	_, child := Start(ctx, KindInternal, "child", "k", "v")
+	child.Finish()
+	Record(ctx, KindClient, "recorded", time.Now().Add(-time.Second))
+	parent.Finish()
+
+	if len(rec.spans) != 3 {
+		t.Fatalf("got %d exported spans, want 3", len(rec.spans))
+	}
+	recorded := rec.spans[1]
+	for _, s := range []*Span{child, recorded} {
+		if s.TraceID != parent.TraceID || s.ParentID != parent.SpanID {
+			t.Errorf("%s: got trace %x parent %x, want trace %x parent %x", s.Name, s.TraceID, s.ParentID, parent.TraceID, parent.SpanID)
+		}
+	}
+	if parent.ParentID != [8]byte{} {
+		t.Errorf("got root span parent %x, want none", parent.ParentID)
+	}
+	if d := recorded.End.Sub(recorded.Start); d < time.Second {
+		t.Errorf("got recorded span duration %s, want at least 1s", d)
+	}
+}
+
+func TestStart_notSampled(t *testing.T) {
+	rec, teardown := setup(0)
+	defer teardown()
+
+	ctx, span := Start(context.Background(), KindServer, "s")
+	h := http.Header{}
+	Inject(ctx, h)
+	span.Finish()
+
+	if len(rec.spans) != 0 {
+		t.Errorf("got %d exported spans, want none", len(rec.spans))
+	}
+	if got := SpanFromContext(Extract(context.Background(), h)); got == nil || got.sampled {
+		t.Errorf("got propagated span %+v, want an unsampled span", got)
+	}
+}
+
+func TestRecord_noParent(t *testing.T) {
+	rec, teardown := setup(1)
+	defer teardown()
+
+	Record(nil, KindClient, "s", time.Now())
+	if len(rec.spans) != 0 {
+		t.Errorf("got %d exported spans, want none without a parent span", len(rec.spans))
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	_, teardown := setup(1)
+	defer teardown()
+
+	ctx, span := Start(context.Background(), KindClient, "s")
+	h := http.Header{}
+	Inject(ctx, h)
+
+	remote := SpanFromContext(Extract(context.Background(), h))
+	if remote == nil || remote.TraceID != span.TraceID || remote.SpanID != span.SpanID || !remote.sampled {
+		t.Errorf("got extracted span %+v, want %+v", remote, span)
+	}
+
+	for _, tp := range []string{
+		"",
+		"00-00000000000000000000000000000000-0000000000000001-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		h := http.Header{TraceparentHeader: {tp}}
+		if s := SpanFromContext(Extract(context.Background(), h)); s != nil {
+			t.Errorf("%q: got extracted span %+v, want none", tp, s)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec, teardown := setup(1)
+	defer teardown()
+
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRoute(r, "post")
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	req, _ := http.NewRequest("GET", "/posts/1", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.spans) != 1 {
+		t.Fatalf("got %d exported spans, want 1", len(rec.spans))
+	}
+	s := rec.spans[0]
+	if s.Name != "GET post" || s.Kind != KindServer || s.Err == "" {
+		t.Errorf("got span %+v, want failed server span GET post", s)
+	}
+	if got := []byte{s.TraceID[0], s.ParentID[0]}; got[0] != 0x4b || got[1] != 0x00 {
+		t.Errorf("got trace %x parent %x, want the caller's", s.TraceID, s.ParentID)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var got map[string]interface{}
+	var gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("Api-Key")
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	e := &OTLPExporter{URL: srv.URL, ServiceName: "svc", Headers: http.Header{"Api-Key": {"k"}}}
+	_, teardown := setup(1)
+	Exporter = e
+	defer teardown()
+
+	_, span := Start(context.Background(), KindServer, "s", "n", 1, "b", true)
+	span.SetError(errors.New("e"))
+	span.Finish()
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotHeader != "k" {
+		t.Errorf("got Api-Key header %q, want %q", gotHeader, "k")
+	}
+	rs := got["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	if attrs, _ := json.Marshal(rs["resource"]); string(attrs) != `{"attributes":[{"key":"service.name","value":{"stringValue":"svc"}}]}` {
+		t.Errorf("got resource %s", attrs)
+	}
+	s := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+	if s["name"] != "s" || s["kind"] != float64(KindServer) || len(s["traceId"].(string)) != 32 || len(s["spanId"].(string)) != 16 {
+		t.Errorf("got span %v", s)
+	}
+	if _, ok := s["parentSpanId"]; ok {
+		t.Errorf("got parentSpanId in root span %v", s)
+	}
+	if attrs, _ := json.Marshal(s["attributes"]); string(attrs) != `[{"key":"n","value":{"intValue":"1"}},{"key":"b","value":{"boolValue":true}}]` {
+		t.Errorf("got attributes %s", attrs)
+	}
+	if status, _ := json.Marshal(s["status"]); string(status) != `{"code":2,"message":"e"}` {
+		t.Errorf("got status %s", status)
+	}
+
+	// Nothing is sent when no spans are buffered.
+	got = nil
+	if err := e.Flush(); err != nil || got != nil {
+		t.Errorf("got %v (error %v) after flushing no spans, want nothing sent", got, err)
+	}
+}
@@ -0,0 +1,49 @@
+// Package trending periodically updates posts' velocities (how quickly they
+// are gaining votes and comments), so that the posts gaining traction the
+// fastest can be listed first (see thesrc.SortTrending).
+package trending
+
+import (
+	"log"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+// DefaultWindow is the default value of Worker.Window.
+const DefaultWindow = 6 * time.Hour
+
+// A Worker updates posts' velocities.
+type Worker struct {
+	Store datastore.TrendingStore
+
+	// Interval is how long to wait between updates.
+	Interval time.Duration
+
+	// Window is the period (ending now) over which votes and comments are
+	// counted. If 0, DefaultWindow is used.
+	Window time.Duration
+}
+
+// Run updates velocities until stop is closed.
+func (w *Worker) Run(stop <-chan struct{}) {
+	for {
+		if _, err := w.RunOnce(); err != nil {
+			log.Print("Trending worker: ", err)
+		}
+		select {
+		case <-time.After(w.Interval):
+		case <-stop:
+			return
+		}
+	}
+}
+
+// RunOnce updates velocities and returns the number of posts updated.
+func (w *Worker) RunOnce() (int, error) {
+	window := w.Window
+	if window == 0 {
+		window = DefaultWindow
+	}
+	return w.Store.UpdateVelocities(window)
+}
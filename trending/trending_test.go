@@ -0,0 +1,46 @@
+package trending
+
+import (
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestWorker_RunOnce(t *testing.T) {
+	var gotWindow time.Duration
+	w := &Worker{Store: &datastore.MockTrendingStore{
+		UpdateVelocities_: func(window time.Duration) (int, error) {
+			gotWindow = window
+			return 3, nil
+		},
+	}}
+	if n, err := w.RunOnce(); err != nil || n != 3 {
+		t.Errorf("got %d updated and error %v, want 3 and nil", n, err)
+	}
+	if gotWindow != DefaultWindow {
+		t.Errorf("got window %s, want default %s", gotWindow, DefaultWindow)
+	}
+}
+
+func TestWorker_RunOnce_memory(t *testing.T) {
+	d := datastore.NewMemoryDatastore()
+	post := &thesrc.Post{LinkURL: "http://example.com"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Votes.Upvote(1, post.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Worker{Store: d.Trending, Window: time.Hour}
+	if _, err := w.RunOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := d.Posts.Get(post.ID); err != nil {
+		t.Fatal(err)
+	} else if p.Velocity != 1 {
+		t.Errorf("got velocity %v, want 1 vote per hour", p.Velocity)
+	}
+}
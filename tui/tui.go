@@ -0,0 +1,471 @@
+// Package tui is an interactive terminal reader for thesrc (see "thesrc
+// tui"). It lists posts, shows their comments, opens their links in a web
+// browser, and votes on them, all through the API client.
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// Keys that HandleKey recognizes, other than the printable characters
+// (such as "j" and "q"), which are passed as themselves.
+const (
+	KeyUp    = "up"
+	KeyDown  = "down"
+	KeyLeft  = "left"
+	KeyRight = "right"
+	KeyEnter = "enter"
+	KeyEsc   = "esc"
+)
+
+// help is shown in the status line when the user presses "?".
+const help = "j/k: move  o: open  c: comments  u: vote  n/p: next/prev page  r: reload  q: quit"
+
+// A Reader is a terminal reader session.
+type Reader struct {
+	// Client is the API client that posts and comments are listed and voted
+	// on through.
+	Client *thesrc.Client
+
+	// Options selects the posts to list. Its Page is changed as the user
+	// pages through the list.
+	Options thesrc.PostListOptions
+
+	// OpenURL opens a URL in a web browser.
+	OpenURL func(url string) error
+
+	// PostURL returns the URL of a post's page on the site, which is opened
+	// for self-posts (which have no link).
+	PostURL func(post *thesrc.Post) string
+
+	posts    []*thesrc.Post
+	selected int // index in posts of the selected post
+	top      int // index in posts of the first post shown
+
+	viewing  bool             // whether the selected post's comments are shown
+	comments []*threadComment // the selected post's comments, in thread order
+	scroll   int              // index of the first comment view line shown
+
+	status string // message shown in the status line
+}
+
+// A threadComment is a comment and its depth in its thread (0 for
+// top-level comments).
+type threadComment struct {
+	*thesrc.Comment
+	Depth int
+}
+
+// Load lists the posts that Options selects.
+func (r *Reader) Load() error {
+	posts, err := r.Client.Posts.List(&r.Options)
+	if err != nil {
+		return err
+	}
+	r.posts, r.selected, r.top = posts, 0, 0
+	return nil
+}
+
+// Run runs the reader: it loads the posts, then draws the reader to out
+// and handles the keys read from in until the user quits. The terminal
+// should be in raw mode. size returns the terminal's width and height.
+func (r *Reader) Run(in io.Reader, out io.Writer, size func() (width, height int, err error)) error {
+	if err := r.Load(); err != nil {
+		return err
+	}
+
+	// Use the alternate screen, so that the terminal's contents are
+	// restored when the reader exits, and hide the cursor.
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	keys := bufio.NewReader(in)
+	for {
+		width, height, err := size()
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+		fmt.Fprint(out, "\x1b[H\x1b[2J"+strings.Join(r.Render(width, height), "\r\n"))
+
+		key, err := readKey(keys)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if r.HandleKey(key) {
+			return nil
+		}
+	}
+}
+
+// readKey reads a key press from keys, translating the escape sequences of
+// arrow keys.
+func readKey(keys *bufio.Reader) (string, error) {
+	c, err := keys.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch c {
+	case '\x1b':
+		if keys.Buffered() < 2 {
+			return KeyEsc, nil
+		}
+		seq := make([]byte, 2)
+		if _, err := io.ReadFull(keys, seq); err != nil {
+			return "", err
+		}
+		switch string(seq) {
+		case "[A":
+			return KeyUp, nil
+		case "[B":
+			return KeyDown, nil
+		case "[C":
+			return KeyRight, nil
+		case "[D":
+			return KeyLeft, nil
+		}
+		return KeyEsc, nil
+	case '\r', '\n':
+		return KeyEnter, nil
+	case 3: // Ctrl-C
+		return "q", nil
+	}
+	keys.UnreadByte()
+	ch, _, err := keys.ReadRune()
+	return string(ch), err
+}
+
+// HandleKey handles a key press (a printable character, or one of the Key
+// constants), and reports whether the user quit.
+func (r *Reader) HandleKey(key string) (quit bool) {
+	r.status = ""
+	if r.viewing {
+		switch key {
+		case "q", "c", "h", KeyLeft, KeyEsc:
+			r.viewing = false
+		case "j", KeyDown:
+			r.scroll++
+		case "k", KeyUp:
+			if r.scroll > 0 {
+				r.scroll--
+			}
+		case "o", KeyEnter:
+			r.open()
+		case "u":
+			r.vote()
+		case "?":
+			r.status = help
+		}
+		return false
+	}
+
+	switch key {
+	case "q":
+		return true
+	case "j", KeyDown:
+		if r.selected < len(r.posts)-1 {
+			r.selected++
+		}
+	case "k", KeyUp:
+		if r.selected > 0 {
+			r.selected--
+		}
+	case "o", KeyEnter:
+		r.open()
+	case "c", "l", KeyRight:
+		r.viewComments()
+	case "u":
+		r.vote()
+	case "n":
+		r.turnPage(1)
+	case "p":
+		if r.Options.PageOrDefault() == 1 {
+			r.status = "This is the first page."
+		} else {
+			r.turnPage(-1)
+		}
+	case "r":
+		r.setError(r.Load())
+	case "?":
+		r.status = help
+	}
+	return false
+}
+
+// post returns the selected post, or nil if there are no posts.
+func (r *Reader) post() *thesrc.Post {
+	if r.selected >= len(r.posts) {
+		return nil
+	}
+	return r.posts[r.selected]
+}
+
+// open opens the selected post's link (or, for self-posts, its page) in a
+// browser.
+func (r *Reader) open() {
+	post := r.post()
+	if post == nil {
+		return
+	}
+	url := post.LinkURL
+	if url == "" || r.viewing {
+		url = r.PostURL(post)
+	}
+	if err := r.OpenURL(url); err != nil {
+		r.setError(err)
+		return
+	}
+	r.status = "Opened " + url
+}
+
+// vote upvotes the selected post, or removes the user's vote if they have
+// already upvoted it.
+func (r *Reader) vote() {
+	post := r.post()
+	if post == nil {
+		return
+	}
+	if post.Voted {
+		if err := r.Client.Votes.Unvote(post.ID); err != nil {
+			r.setError(err)
+			return
+		}
+		post.Voted = false
+		post.Score--
+		r.status = "Removed your vote."
+	} else {
+		if err := r.Client.Votes.Upvote(post.ID); err != nil {
+			r.setError(err)
+			return
+		}
+		post.Voted = true
+		post.Score++
+		r.status = "Upvoted."
+	}
+}
+
+// viewComments shows the selected post's comments.
+func (r *Reader) viewComments() {
+	post := r.post()
+	if post == nil {
+		return
+	}
+	comments, err := r.Client.Comments.ListForPost(post.ID)
+	if err != nil {
+		r.setError(err)
+		return
+	}
+	r.comments = flattenThreads(thesrc.ThreadComments(comments), 0)
+	r.viewing, r.scroll = true, 0
+}
+
+// turnPage lists the page of posts delta pages after the current one. If
+// that page is empty, the current page is kept.
+func (r *Reader) turnPage(delta int) {
+	page := r.Options.PageOrDefault()
+	r.Options.Page = page + delta
+	posts, err := r.Client.Posts.List(&r.Options)
+	if err != nil || len(posts) == 0 {
+		r.Options.Page = page
+		if err != nil {
+			r.setError(err)
+		} else {
+			r.status = "There are no more posts."
+		}
+		return
+	}
+	r.posts, r.selected, r.top = posts, 0, 0
+}
+
+// setError shows err (if non-nil) in the status line.
+func (r *Reader) setError(err error) {
+	var rateLimit *thesrc.RateLimitError
+	switch {
+	case err == nil:
+	case errors.Is(err, thesrc.ErrUnauthorized):
+		r.status = "Error: log in first (set -token or THESRC_TOKEN to a personal API token)."
+	case errors.As(err, &rateLimit):
+		r.status = fmt.Sprintf("Error: too many requests; try again in %s.", time.Until(rateLimit.Reset).Round(time.Second))
+	default:
+		r.status = "Error: " + err.Error()
+	}
+}
+
+// flattenThreads returns the comments in threads in thread order: each
+// comment followed by its replies, with their depths.
+func flattenThreads(threads []*thesrc.CommentThread, depth int) []*threadComment {
+	var comments []*threadComment
+	for _, t := range threads {
+		comments = append(comments, &threadComment{t.Comment, depth})
+		comments = append(comments, flattenThreads(t.Replies, depth+1)...)
+	}
+	return comments
+}
+
+// Render returns the lines of the reader's screen, for a terminal of the
+// given size. The selected post's line is shown in reverse video.
+func (r *Reader) Render(width, height int) []string {
+	var title string
+	var body []string
+	rows := height - 2 // less the title and status lines
+	if r.viewing {
+		title, body = r.renderComments(width, rows)
+	} else {
+		title, body = r.renderList(width, rows)
+	}
+	for len(body) < rows {
+		body = append(body, "")
+	}
+
+	status := r.status
+	if status == "" {
+		status = "Press ? for help."
+	}
+	lines := append([]string{"\x1b[1m" + truncate(title, width) + "\x1b[0m"}, body...)
+	return append(lines, truncate(status, width))
+}
+
+func (r *Reader) renderList(width, rows int) (string, []string) {
+	sort := r.Options.Sort
+	if sort == "" {
+		sort = thesrc.SortNew
+	}
+	title := fmt.Sprintf("thesrc: %s posts, page %d", sort, r.Options.PageOrDefault())
+	if len(r.posts) == 0 {
+		return title, []string{"No posts."}
+	}
+
+	if r.selected < r.top {
+		r.top = r.selected
+	} else if r.selected >= r.top+rows {
+		r.top = r.selected - rows + 1
+	}
+	var lines []string
+	for i := r.top; i < len(r.posts) && i < r.top+rows; i++ {
+		post := r.posts[i]
+		vote := " "
+		if post.Voted {
+			vote = "*"
+		}
+		domain := post.Domain
+		if post.LinkURL == "" {
+			domain = "self"
+		}
+		line := truncate(fmt.Sprintf("%s%4d  %s  (%s, %s)", vote, post.Score, oneLine(post.Title), domain, age(post.SubmittedAt)), width)
+		if i == r.selected {
+			line = "\x1b[7m" + line + strings.Repeat(" ", width-utf8.RuneCountInString(line)) + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	return title, lines
+}
+
+func (r *Reader) renderComments(width, rows int) (string, []string) {
+	post := r.post()
+	title := fmt.Sprintf("%s (%d points, %s)", oneLine(post.Title), post.Score, age(post.SubmittedAt))
+
+	var lines []string
+	if post.LinkURL != "" {
+		lines = append(lines, truncate(post.LinkURL, width))
+	}
+	if post.Body != "" {
+		lines = append(lines, "")
+		lines = append(lines, wrap(post.Body, width)...)
+	}
+	lines = append(lines, "", fmt.Sprintf("%d comments", len(r.comments)))
+	for _, c := range r.comments {
+		indent := strings.Repeat("  ", c.Depth)
+		if len(indent) > width/2 {
+			indent = indent[:width/2]
+		}
+		lines = append(lines, "", indent+fmt.Sprintf("[%d] %s", c.Score, age(c.SubmittedAt)))
+		for _, line := range wrap(c.Body, width-len(indent)) {
+			lines = append(lines, indent+line)
+		}
+	}
+
+	if max := len(lines) - rows; r.scroll > max {
+		r.scroll = max
+	}
+	if r.scroll < 0 {
+		r.scroll = 0
+	}
+	lines = lines[r.scroll:]
+	if len(lines) > rows {
+		lines = lines[:rows]
+	}
+	return title, lines
+}
+
+// wrap splits text into lines of at most width characters, breaking lines
+// at spaces (or within words that are too long).
+func wrap(text string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var lines []string
+	for _, para := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			for utf8.RuneCountInString(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				w := []rune(word)
+				lines = append(lines, string(w[:width]))
+				word = string(w[width:])
+			}
+			switch {
+			case line == "":
+				line = word
+			case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// truncate shortens s to at most width characters.
+func truncate(s string, width int) string {
+	if width < 0 {
+		width = 0
+	}
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width])
+}
+
+// oneLine replaces the line breaks and tabs in s with spaces.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// age formats how long ago t was briefly, in its largest whole unit (e.g.,
+// "5m ago" or "2d ago").
+func age(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
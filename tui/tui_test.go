@@ -0,0 +1,175 @@
+package tui
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// newReader returns a reader of 2 pages of posts (3 posts, then 1), whose
+// client's votes are recorded in votes and whose opened URLs are recorded
+// in opened.
+func newReader(votes *[]string, opened *[]string) *Reader {
+	pages := [][]*thesrc.Post{
+		{
+			{ID: 1, Title: "a", LinkURL: "http://example.com/a", Domain: "example.com", Score: 3},
+			{ID: 2, Title: "b", Score: 2},
+			{ID: 3, Title: "c", LinkURL: "http://example.com/c", Domain: "example.com", Score: 1, Voted: true},
+		},
+		{{ID: 4, Title: "d", LinkURL: "http://example.com/d", Domain: "example.com"}},
+	}
+	return &Reader{
+		Client: &thesrc.Client{
+			Posts: &thesrc.MockPostsService{
+				List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+					if page := opt.PageOrDefault(); page <= len(pages) {
+						return pages[page-1], nil
+					}
+					return nil, nil
+				},
+			},
+			Comments: &thesrc.MockCommentsService{
+				ListForPost_: func(postID int) ([]*thesrc.Comment, error) {
+					return []*thesrc.Comment{
+						{ID: 10, PostID: postID, Body: "top", Score: 2},
+						{ID: 11, PostID: postID, Body: "other", Score: 1},
+						{ID: 12, PostID: postID, ParentID: 10, Body: "reply"},
+					}, nil
+				},
+			},
+			Votes: &thesrc.MockVotesService{
+				Upvote_: func(postID int) error {
+					*votes = append(*votes, "up")
+					return nil
+				},
+				Unvote_: func(postID int) error {
+					*votes = append(*votes, "un")
+					return nil
+				},
+			},
+		},
+		OpenURL: func(url string) error {
+			*opened = append(*opened, url)
+			return nil
+		},
+		PostURL: func(post *thesrc.Post) string { return "http://thesrc/p/" + strconv.Itoa(post.ID) },
+	}
+}
+
+func TestReader_HandleKey(t *testing.T) {
+	var votes, opened []string
+	r := newReader(&votes, &opened)
+	if err := r.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"k", "o", "j", KeyEnter, "j", "j", "o", "u", "u", "k", "u"} {
+		if r.HandleKey(key) {
+			t.Fatalf("quit on key %q", key)
+		}
+	}
+	if want := []string{"http://example.com/a", "http://thesrc/p/2", "http://example.com/c"}; !reflect.DeepEqual(opened, want) {
+		t.Errorf("got opened %v, want %v", opened, want)
+	}
+	if want := []string{"un", "up", "up"}; !reflect.DeepEqual(votes, want) {
+		t.Errorf("got votes %v, want %v", votes, want)
+	}
+	if p := r.posts[1]; !p.Voted || p.Score != 3 {
+		t.Errorf("got post 2 voted %v with score %d, want voted with score 3", p.Voted, p.Score)
+	}
+
+	r.HandleKey("n")
+	if page, id := r.Options.PageOrDefault(), r.post().ID; page != 2 || id != 4 {
+		t.Errorf("after next page, got page %d post %d, want page 2 post 4", page, id)
+	}
+	r.HandleKey("n")
+	if page := r.Options.PageOrDefault(); page != 2 {
+		t.Errorf("after next page past the last, got page %d, want 2", page)
+	}
+	r.HandleKey("p")
+	if page := r.Options.PageOrDefault(); page != 1 {
+		t.Errorf("after previous page, got page %d, want 1", page)
+	}
+
+	if !r.HandleKey("q") {
+		t.Error("got no quit on q")
+	}
+}
+
+func TestReader_comments(t *testing.T) {
+	var votes, opened []string
+	r := newReader(&votes, &opened)
+	if err := r.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	r.HandleKey("c")
+	if !r.viewing {
+		t.Fatal("got no comments view after c")
+	}
+	var bodies []string
+	for _, c := range r.comments {
+		bodies = append(bodies, strings.Repeat(">", c.Depth)+c.Body)
+	}
+	if want := []string{"top", ">reply", "other"}; !reflect.DeepEqual(bodies, want) {
+		t.Errorf("got comments %v, want %v", bodies, want)
+	}
+
+	screen := strings.Join(r.Render(40, 20), "\n")
+	for _, want := range []string{"3 comments", "[2] ", "  reply"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("comments view doesn't contain %q:\n%s", want, screen)
+		}
+	}
+
+	if r.HandleKey("q") {
+		t.Error("got quit on q in the comments view, want back to the list")
+	}
+	if r.viewing {
+		t.Error("got comments view after q, want the list")
+	}
+}
+
+func TestReader_Render(t *testing.T) {
+	var votes, opened []string
+	r := newReader(&votes, &opened)
+	if err := r.Load(); err != nil {
+		t.Fatal(err)
+	}
+	r.HandleKey("j")
+
+	lines := r.Render(30, 4)
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	if !strings.HasPrefix(lines[2], "\x1b[7m") || !strings.Contains(lines[2], " b  (self") {
+		t.Errorf("got line %q, want post b selected", lines[2])
+	}
+
+	// The list scrolls to keep the selected post on the screen.
+	r.HandleKey("j")
+	if lines := r.Render(30, 4); !strings.Contains(lines[2], " c  ") || !strings.HasPrefix(lines[2], "\x1b[7m") {
+		t.Errorf("got line %q, want post c selected", lines[2])
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"a b c", 3, []string{"a b", "c"}},
+		{"abcdef gh", 4, []string{"abcd", "ef", "gh"}},
+		{"a\n\nb", 10, []string{"a", "", "b"}},
+		{"héllo wörld", 5, []string{"héllo", "wörld"}},
+	}
+	for _, test := range tests {
+		if got := wrap(test.text, test.width); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("wrap(%q, %d): got %q, want %q", test.text, test.width, got, test.want)
+		}
+	}
+}
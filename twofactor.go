@@ -0,0 +1,202 @@
+package thesrc
+
+import "sourcegraph.com/sourcegraph/thesrc/router"
+
+// A TwoFactorSetup is a new secret for a user's authenticator app, which
+// isn't used until the user enables two-factor authentication with it (see
+// TwoFactorService.Setup).
+type TwoFactorSetup struct {
+	// Secret is the base32-encoded TOTP secret, which the user can type
+	// into their authenticator app.
+	Secret string
+
+	// URL is the otpauth URL of the secret, which authenticator apps read
+	// from QR codes.
+	URL string
+}
+
+// A TwoFactorEnable is the body of a request to enable two-factor
+// authentication.
+type TwoFactorEnable struct {
+	// Secret is the Secret of a TwoFactorSetup.
+	Secret string
+
+	// Code is the current code from the user's authenticator app, which
+	// shows that the app was set up with Secret.
+	Code string
+}
+
+// NumRecoveryCodes is the number of recovery codes that users get when
+// they enable two-factor authentication. Each recovery code may be used
+// once instead of a code from their authenticator app (such as if they lose
+// their phone).
+const NumRecoveryCodes = 10
+
+// TwoFactorService interacts with the two-factor authentication endpoints
+// in thesrc's API. Except for Authenticate, it manages the two-factor
+// authentication of the user that the client is authenticated as.
+type TwoFactorService interface {
+	// Setup returns a new TOTP secret for the user's authenticator app.
+	Setup() (*TwoFactorSetup, error)
+
+	// Enable two-factor authentication, and return the user's recovery
+	// codes, which can't be retrieved again later.
+	Enable(enable *TwoFactorEnable) ([]string, error)
+
+	// Disable two-factor authentication. The code is a current code from
+	// the user's authenticator app or a recovery code.
+	Disable(code string) error
+
+	// RegenerateRecoveryCodes replaces the user's recovery codes with new
+	// ones, and returns them. The code is a current code from the user's
+	// authenticator app or a recovery code.
+	RegenerateRecoveryCodes(code string) ([]string, error)
+
+	// Authenticate finishes authenticating a user with two-factor
+	// authentication enabled, given the TwoFactorToken returned by
+	// UsersService.Authenticate and a current code from the user's
+	// authenticator app or a recovery code.
+	Authenticate(token, code string) (*Auth, error)
+}
+
+type twoFactorService struct{ client *Client }
+
+func (s *twoFactorService) Setup() (*TwoFactorSetup, error) {
+	url, err := s.client.url(router.SetUpTwoFactor, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var setup *TwoFactorSetup
+	_, err = s.client.Do(req, &setup)
+	if err != nil {
+		return nil, err
+	}
+
+	return setup, nil
+}
+
+func (s *twoFactorService) Enable(enable *TwoFactorEnable) ([]string, error) {
+	url, err := s.client.url(router.EnableTwoFactor, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), enable)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	_, err = s.client.Do(req, &codes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *twoFactorService) Disable(code string) error {
+	url, err := s.client.url(router.DisableTwoFactor, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), struct{ Code string }{code})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *twoFactorService) RegenerateRecoveryCodes(code string) ([]string, error) {
+	url, err := s.client.url(router.RegenerateRecoveryCodes, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), struct{ Code string }{code})
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	_, err = s.client.Do(req, &codes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *twoFactorService) Authenticate(token, code string) (*Auth, error) {
+	url, err := s.client.url(router.AuthenticateTwoFactor, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), struct{ Token, Code string }{token, code})
+	if err != nil {
+		return nil, err
+	}
+
+	var auth *Auth
+	_, err = s.client.Do(req, &auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+type MockTwoFactorService struct {
+	Setup_                   func() (*TwoFactorSetup, error)
+	Enable_                  func(enable *TwoFactorEnable) ([]string, error)
+	Disable_                 func(code string) error
+	RegenerateRecoveryCodes_ func(code string) ([]string, error)
+	Authenticate_            func(token, code string) (*Auth, error)
+}
+
+var _ TwoFactorService = &MockTwoFactorService{}
+
+func (s *MockTwoFactorService) Setup() (*TwoFactorSetup, error) {
+	if s.Setup_ == nil {
+		return nil, nil
+	}
+	return s.Setup_()
+}
+
+func (s *MockTwoFactorService) Enable(enable *TwoFactorEnable) ([]string, error) {
+	if s.Enable_ == nil {
+		return nil, nil
+	}
+	return s.Enable_(enable)
+}
+
+func (s *MockTwoFactorService) Disable(code string) error {
+	if s.Disable_ == nil {
+		return nil
+	}
+	return s.Disable_(code)
+}
+
+func (s *MockTwoFactorService) RegenerateRecoveryCodes(code string) ([]string, error) {
+	if s.RegenerateRecoveryCodes_ == nil {
+		return nil, nil
+	}
+	return s.RegenerateRecoveryCodes_(code)
+}
+
+func (s *MockTwoFactorService) Authenticate(token, code string) (*Auth, error) {
+	if s.Authenticate_ == nil {
+		return nil, nil
+	}
+	return s.Authenticate_(token, code)
+}
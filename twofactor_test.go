@@ -0,0 +1,118 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestTwoFactorService_Setup(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &TwoFactorSetup{Secret: "s", URL: "otpauth://totp/thesrc:alice?secret=s"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.SetUpTwoFactor, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+
+		writeJSON(w, want)
+	})
+
+	tfSetup, err := client.TwoFactor.Setup()
+	if err != nil {
+		t.Errorf("TwoFactor.Setup returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	if !reflect.DeepEqual(tfSetup, want) {
+		t.Errorf("TwoFactor.Setup returned %+v, want %+v", tfSetup, want)
+	}
+}
+
+func TestTwoFactorService_Enable(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []string{"aaaaa-aaaaa", "bbbbb-bbbbb"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.EnableTwoFactor, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+		testBody(t, r, `{"Secret":"s","Code":"123456"}`+"\n")
+
+		writeJSON(w, want)
+	})
+
+	codes, err := client.TwoFactor.Enable(&TwoFactorEnable{Secret: "s", Code: "123456"})
+	if err != nil {
+		t.Errorf("TwoFactor.Enable returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	if !reflect.DeepEqual(codes, want) {
+		t.Errorf("TwoFactor.Enable returned %+v, want %+v", codes, want)
+	}
+}
+
+func TestTwoFactorService_Disable(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.DisableTwoFactor, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+		testBody(t, r, `{"Code":"123456"}`+"\n")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.TwoFactor.Disable("123456"); err != nil {
+		t.Errorf("TwoFactor.Disable returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestTwoFactorService_Authenticate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Auth{User: &User{ID: 1, Login: "alice"}, Token: "t"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.AuthenticateTwoFactor, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"Token":"tf","Code":"123456"}`+"\n")
+
+		writeJSON(w, want)
+	})
+
+	auth, err := client.TwoFactor.Authenticate("tf", "123456")
+	if err != nil {
+		t.Errorf("TwoFactor.Authenticate returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.User.RegisteredAt)
+	if !reflect.DeepEqual(auth, want) {
+		t.Errorf("TwoFactor.Authenticate returned %+v, want %+v", auth, want)
+	}
+}
@@ -0,0 +1,190 @@
+// Package unfurl fetches link URLs and extracts metadata (title, description,
+// image, and favicon) from their pages.
+package unfurl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// maxPageSize is the most bytes of a page that are read. Metadata is in the
+// <head>, so there's no need to read huge pages in full.
+const maxPageSize = 1 << 20
+
+// httpClient fetches pages. Because the URLs come from users, it refuses to
+// connect to private, loopback, and link-local addresses (so that unfurling
+// can't be used to probe the server's internal network).
+var httpClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: denyInternalAddresses,
+		}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		return nil
+	},
+}
+
+var errInternalAddress = errors.New("refusing to fetch internal network address")
+
+func denyInternalAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return errInternalAddress
+	}
+	return nil
+}
+
+// Fetch fetches the page at linkURL and returns its metadata. If the page
+// isn't HTML, only FaviconURL is set.
+func Fetch(linkURL string) (*thesrc.LinkMetadata, error) {
+	req, err := http.NewRequest("GET", linkURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "thesrc-unfurl/0.1")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-200 HTTP response status: %d", resp.StatusCode)
+	}
+
+	// Resolve relative URLs against the final (post-redirect) page URL.
+	pageURL := resp.Request.URL
+	meta := &thesrc.LinkMetadata{
+		FaviconURL: pageURL.ResolveReference(&url.URL{Path: "/favicon.ico"}).String(),
+	}
+
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt != "text/html" && mt != "application/xhtml+xml" {
+		return meta, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	metaContent := func(selectors ...string) string {
+		for _, sel := range selectors {
+			if v := strings.TrimSpace(doc.Find(sel).First().AttrOr("content", "")); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	resolve := func(ref string) string {
+		if ref == "" {
+			return ""
+		}
+		u, err := pageURL.Parse(ref)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return ""
+		}
+		return u.String()
+	}
+
+	meta.Title = strings.Join(strings.Fields(doc.Find("head title").First().Text()), " ")
+	if meta.Title == "" {
+		meta.Title = metaContent(`meta[property="og:title"]`)
+	}
+	meta.Description = metaContent(`meta[property="og:description"]`, `meta[name="description"]`)
+	meta.ImageURL = resolve(metaContent(`meta[property="og:image"]`))
+
+	doc.Find("link[rel][href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		for _, rel := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+			if rel == "icon" {
+				if u := resolve(s.AttrOr("href", "")); u != "" {
+					meta.FaviconURL = u
+					return false
+				}
+			}
+		}
+		return true
+	})
+
+	return meta, nil
+}
+
+// Download fetches the resource at urlStr (subject to the same restrictions
+// as Fetch) and returns its body and Content-Type. It returns an error if
+// the body is larger than maxSize bytes.
+func Download(urlStr string, maxSize int64) (data []byte, contentType string, err error) {
+	req, err := http.NewRequest("GET", urlStr, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", "thesrc-unfurl/0.1")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("non-200 HTTP response status: %d", resp.StatusCode)
+	}
+
+	data, err = io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, "", fmt.Errorf("%s is larger than %d bytes", urlStr, maxSize)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// Status returns the HTTP status code that linkURL responds with (after
+// following redirects). It makes a HEAD request, falling back to a GET
+// request for servers that don't support HEAD.
+func Status(linkURL string) (int, error) {
+	status := func(method string) (int, error) {
+		req, err := http.NewRequest(method, linkURL, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("User-Agent", "thesrc-unfurl/0.1")
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	code, err := status("HEAD")
+	if err == nil && (code == http.StatusMethodNotAllowed || code == http.StatusNotImplemented) {
+		return status("GET")
+	}
+	return code, err
+}
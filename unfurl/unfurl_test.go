@@ -0,0 +1,103 @@
+package unfurl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head>
+<title>
+  A   page
+</title>
+<meta property="og:description" content="About the page">
+<meta name="description" content="Not this one">
+<meta property="og:image" content="/img/preview.png">
+<link rel="shortcut icon" href="/static/icon.png">
+</head><body></body></html>`))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	orig := httpClient
+	httpClient = http.DefaultClient
+	defer func() { httpClient = orig }()
+
+	tests := []struct {
+		path string
+		want *thesrc.LinkMetadata
+	}{
+		{"/redirect", &thesrc.LinkMetadata{
+			Title:       "A page",
+			Description: "About the page",
+			ImageURL:    s.URL + "/img/preview.png",
+			FaviconURL:  s.URL + "/static/icon.png",
+		}},
+		{"/pdf", &thesrc.LinkMetadata{FaviconURL: s.URL + "/favicon.ico"}},
+	}
+	for _, test := range tests {
+		meta, err := Fetch(s.URL + test.path)
+		if err != nil {
+			t.Errorf("%s: %s", test.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(meta, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.path, meta, test.want)
+		}
+	}
+}
+
+func TestFetch_internalAddress(t *testing.T) {
+	s := httptest.NewServer(http.NotFoundHandler())
+	defer s.Close()
+
+	if _, err := Fetch(s.URL); err == nil {
+		t.Error("got no error fetching a loopback address, want error")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	orig := httpClient
+	httpClient = http.DefaultClient
+	defer func() { httpClient = orig }()
+
+	tests := map[string]int{"/ok": http.StatusOK, "/gone": http.StatusGone, "/no-head": http.StatusNotFound}
+	for path, want := range tests {
+		status, err := Status(s.URL + path)
+		if err != nil {
+			t.Errorf("%s: %s", path, err)
+			continue
+		}
+		if status != want {
+			t.Errorf("%s: got status %d, want %d", path, status, want)
+		}
+	}
+}
@@ -0,0 +1,542 @@
+package thesrc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A User is a registered user of thesrc.
+type User struct {
+	// ID a unique identifier for this user.
+	ID int `json:",omitempty"`
+
+	// Login is the user's unique username.
+	Login string
+
+	// DisplayName is the name that the user chose to be shown as, instead of
+	// their login. It may be empty.
+	DisplayName string `json:",omitempty"`
+
+	// Email is the user's email address.
+	Email string `json:",omitempty"`
+
+	// DigestSubscribed is whether the user wants to receive the email digest
+	// of top posts. It is private, like Email.
+	DigestSubscribed bool `json:",omitempty"`
+
+	// Theme is the user's preferred color theme (ThemeLight or ThemeDark),
+	// or "" for the default. It is private, like Email.
+	Theme string `json:",omitempty"`
+
+	// DefaultSort is the order in which the user's front page lists posts
+	// (see PostListOptions.Sort), or "" for the default (SortTop). It is
+	// private, like Email.
+	DefaultSort string `json:",omitempty"`
+
+	// PasswordHash is the bcrypt hash of the user's password. It is never
+	// included in API responses.
+	PasswordHash []byte `json:"-"`
+
+	// PasswordChangedAt is when the user last changed (or reset) their
+	// password.
+	PasswordChangedAt time.Time `json:"-"`
+
+	// TOTPSecret is the secret of the user's authenticator app, if they have
+	// enabled two-factor authentication (see TwoFactorService). It is never
+	// included in API responses.
+	TOTPSecret string `json:"-"`
+
+	// RecoveryCodeHashes is the space-separated, hex-encoded SHA-256 hashes
+	// of the user's unused two-factor recovery codes. It is never included
+	// in API responses.
+	RecoveryCodeHashes string `json:"-"`
+
+	// TwoFactorEnabled is whether the user has enabled two-factor
+	// authentication. It is only set by UsersService.Current.
+	TwoFactorEnabled bool `db:"-" json:",omitempty"`
+
+	// RegisteredAt is when the user signed up.
+	RegisteredAt time.Time
+
+	// Role determines what the user may do on thesrc (see HasRole). It can
+	// only be changed with the "thesrc grant-role" command.
+	Role string `json:",omitempty"`
+
+	// ShadowBanned is whether the user is shadow-banned: their posts are
+	// omitted from post listings (except their own) and their votes don't
+	// count toward posts' scores, without any indication to them. It is only
+	// included in API responses to moderators.
+	ShadowBanned bool `json:",omitempty"`
+
+	// Karma is the total score of the user's posts and comments. It is only
+	// set by UsersService.Get.
+	Karma int `db:"-" json:",omitempty"`
+
+	// UnreadNotifications is the number of the user's unread notifications.
+	// It is only set by UsersService.Current, so that clients can show it
+	// on every page without another request.
+	UnreadNotifications int `db:"-" json:",omitempty"`
+}
+
+// Name returns the name to show for u: its DisplayName, or its Login if it
+// has none.
+func (u *User) Name() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Login
+}
+
+// Color themes (see User.Theme).
+const (
+	ThemeLight = "light"
+	ThemeDark  = "dark"
+)
+
+// ValidTheme returns whether theme is a valid User.Theme value.
+func ValidTheme(theme string) bool {
+	return theme == "" || theme == ThemeLight || theme == ThemeDark
+}
+
+// User roles, from least to most privileged. Each role may do everything
+// that the less privileged roles may.
+const (
+	// RoleMember is the role of regular users, who may submit, vote on,
+	// comment on, and flag posts.
+	RoleMember = "member"
+
+	// RoleModerator is the role of users who may also hide and kill posts
+	// and view the moderation queue.
+	RoleModerator = "moderator"
+
+	// RoleAdmin is the role of users who may also edit and delete any post
+	// and shadow-ban users.
+	RoleAdmin = "admin"
+)
+
+var roleRanks = map[string]int{RoleMember: 1, RoleModerator: 2, RoleAdmin: 3}
+
+// ValidRole returns whether role is a valid User.Role value.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole returns whether u has role or a more privileged role. A user with
+// no role is a member. HasRole returns false if u is nil.
+func (u *User) HasRole(role string) bool {
+	if u == nil || !ValidRole(role) {
+		return false
+	}
+	userRole := u.Role
+	if userRole == "" {
+		userRole = RoleMember
+	}
+	return roleRanks[userRole] >= roleRanks[role]
+}
+
+// A NewUser is the information needed to sign up as a new user.
+type NewUser struct {
+	Login    string
+	Email    string
+	Password string
+}
+
+// MinPasswordLength is the minimum length of a user's password.
+const MinPasswordLength = 8
+
+var loginPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{2,40}$`)
+
+// Validate returns a ValidationError describing the problems found with u, if
+// any.
+func (u *NewUser) Validate() error {
+	var errs ValidationError
+	if !loginPattern.MatchString(u.Login) {
+		errs = append(errs, &FieldError{Field: "Login", Message: "login must be 2-40 characters long and contain only letters, numbers, '-', and '_'"})
+	}
+	if len(u.Password) < MinPasswordLength {
+		errs = append(errs, &FieldError{Field: "Password", Message: fmt.Sprintf("password must be at least %d characters long", MinPasswordLength)})
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// UserSettings are the settings that users may change on their own accounts
+// (see UsersService.UpdateSettings).
+type UserSettings struct {
+	DisplayName      string
+	Email            string
+	DigestSubscribed bool
+	Theme            string
+	DefaultSort      string
+}
+
+// MaxDisplayNameLength is the maximum length of a user's DisplayName.
+const MaxDisplayNameLength = 50
+
+// Validate returns a ValidationError describing the problems found with s,
+// if any.
+func (s *UserSettings) Validate() error {
+	var errs ValidationError
+	if len(s.DisplayName) > MaxDisplayNameLength {
+		errs = append(errs, &FieldError{Field: "DisplayName", Message: fmt.Sprintf("display name must be at most %d characters long", MaxDisplayNameLength)})
+	}
+	if s.Email != "" && !strings.Contains(s.Email, "@") {
+		errs = append(errs, &FieldError{Field: "Email", Message: "invalid email address"})
+	}
+	if s.DigestSubscribed && s.Email == "" {
+		errs = append(errs, &FieldError{Field: "DigestSubscribed", Message: "an email address is required to subscribe to the digest"})
+	}
+	if !ValidTheme(s.Theme) {
+		errs = append(errs, &FieldError{Field: "Theme", Message: fmt.Sprintf("invalid theme %q", s.Theme)})
+	}
+	if !ValidSort(s.DefaultSort) {
+		errs = append(errs, &FieldError{Field: "DefaultSort", Message: fmt.Sprintf("invalid sort order %q", s.DefaultSort)})
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// A PasswordChange is the body of a request to change the authenticated
+// user's password.
+type PasswordChange struct {
+	CurrentPassword string
+	NewPassword     string
+}
+
+// A PasswordReset is the body of a request to reset a user's password with
+// the token emailed to them (see UsersService.RequestPasswordReset).
+type PasswordReset struct {
+	Token       string
+	NewPassword string
+}
+
+// An Auth is the result of successfully authenticating as a user.
+type Auth struct {
+	// User is the authenticated user.
+	User *User `json:",omitempty"`
+
+	// Token is an API token that authenticates requests as User. Use it by
+	// setting Client.AuthToken (or calling Client.WithAuthToken). It is the
+	// token of a new Session, so it remains valid until it is revoked (see
+	// SessionsService) or goes unused for too long.
+	Token string `json:",omitempty"`
+
+	// TwoFactorToken is set (instead of User and Token) if the user has
+	// enabled two-factor authentication. To finish authenticating, pass it
+	// and a code from the user's authenticator app to
+	// TwoFactorService.Authenticate.
+	TwoFactorToken string `json:",omitempty"`
+}
+
+// UsersService interacts with the user- and authentication-related endpoints
+// in thesrc's API.
+type UsersService interface {
+	// Signup registers a new user account.
+	Signup(user *NewUser) (*Auth, error)
+
+	// Authenticate a user by login and password. If the user has enabled
+	// two-factor authentication, the returned Auth only has a
+	// TwoFactorToken.
+	Authenticate(login, password string) (*Auth, error)
+
+	// Current returns the user that the client is authenticated as.
+	Current() (*User, error)
+
+	// Get a user's public profile by login. The user's email address is
+	// omitted.
+	Get(login string) (*User, error)
+
+	// SetShadowBanned shadow-bans (or un-shadow-bans) a user (see
+	// User.ShadowBanned). Only admins may shadow-ban users.
+	SetShadowBanned(login string, banned bool) error
+
+	// UpdateSettings updates the settings of the user that the client is
+	// authenticated as, and returns the updated user.
+	UpdateSettings(settings *UserSettings) (*User, error)
+
+	// ChangePassword changes the authenticated user's password, if
+	// change.CurrentPassword is their current password. The user's existing
+	// sessions (including the client's) are revoked, so it returns a new
+	// one. Personal API tokens (see TokensService) remain valid.
+	ChangePassword(change *PasswordChange) (*Auth, error)
+
+	// RequestPasswordReset emails a link to reset their password to the user
+	// with the given login or email address. It succeeds even if there is no
+	// such user, so that it can't be used to find out who has an account.
+	RequestPasswordReset(login string) error
+
+	// ResetPassword sets a user's password using a token from a password
+	// reset email, revoking all of the user's sessions, and
+	// authenticates as the user (as Authenticate does, so the user may
+	// still need to enter a two-factor authentication code).
+	ResetPassword(reset *PasswordReset) (*Auth, error)
+}
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+)
+
+type usersService struct{ client *Client }
+
+func (s *usersService) Signup(user *NewUser) (*Auth, error) {
+	url, err := s.client.url(router.Signup, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), user)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth *Auth
+	_, err = s.client.Do(req, &auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+func (s *usersService) Authenticate(login, password string) (*Auth, error) {
+	url, err := s.client.url(router.Authenticate, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), struct{ Login, Password string }{login, password})
+	if err != nil {
+		return nil, err
+	}
+
+	var auth *Auth
+	_, err = s.client.Do(req, &auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+func (s *usersService) Current() (*User, error) {
+	url, err := s.client.url(router.CurrentUser, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *User
+	_, err = s.client.Do(req, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *usersService) Get(login string) (*User, error) {
+	url, err := s.client.url(router.User, map[string]string{"Login": login}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *User
+	_, err = s.client.Do(req, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// A UserShadowBan is the body of a request to set a user's shadow-ban
+// status.
+type UserShadowBan struct {
+	ShadowBanned bool
+}
+
+func (s *usersService) SetShadowBanned(login string, banned bool) error {
+	url, err := s.client.url(router.ShadowBanUser, map[string]string{"Login": login}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), &UserShadowBan{ShadowBanned: banned})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *usersService) UpdateSettings(settings *UserSettings) (*User, error) {
+	url, err := s.client.url(router.UpdateUserSettings, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *User
+	_, err = s.client.Do(req, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *usersService) ChangePassword(change *PasswordChange) (*Auth, error) {
+	url, err := s.client.url(router.ChangePassword, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), change)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth *Auth
+	_, err = s.client.Do(req, &auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+func (s *usersService) RequestPasswordReset(login string) error {
+	url, err := s.client.url(router.RequestPasswordReset, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), struct{ Login string }{login})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *usersService) ResetPassword(reset *PasswordReset) (*Auth, error) {
+	url, err := s.client.url(router.ResetPassword, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), reset)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth *Auth
+	_, err = s.client.Do(req, &auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+type MockUsersService struct {
+	Signup_               func(user *NewUser) (*Auth, error)
+	Authenticate_         func(login, password string) (*Auth, error)
+	Current_              func() (*User, error)
+	Get_                  func(login string) (*User, error)
+	SetShadowBanned_      func(login string, banned bool) error
+	UpdateSettings_       func(settings *UserSettings) (*User, error)
+	ChangePassword_       func(change *PasswordChange) (*Auth, error)
+	RequestPasswordReset_ func(login string) error
+	ResetPassword_        func(reset *PasswordReset) (*Auth, error)
+}
+
+var _ UsersService = &MockUsersService{}
+
+func (s *MockUsersService) Signup(user *NewUser) (*Auth, error) {
+	if s.Signup_ == nil {
+		return nil, nil
+	}
+	return s.Signup_(user)
+}
+
+func (s *MockUsersService) Authenticate(login, password string) (*Auth, error) {
+	if s.Authenticate_ == nil {
+		return nil, nil
+	}
+	return s.Authenticate_(login, password)
+}
+
+func (s *MockUsersService) Current() (*User, error) {
+	if s.Current_ == nil {
+		return nil, nil
+	}
+	return s.Current_()
+}
+
+func (s *MockUsersService) Get(login string) (*User, error) {
+	if s.Get_ == nil {
+		return nil, nil
+	}
+	return s.Get_(login)
+}
+
+func (s *MockUsersService) SetShadowBanned(login string, banned bool) error {
+	if s.SetShadowBanned_ == nil {
+		return nil
+	}
+	return s.SetShadowBanned_(login, banned)
+}
+
+func (s *MockUsersService) UpdateSettings(settings *UserSettings) (*User, error) {
+	if s.UpdateSettings_ == nil {
+		return nil, nil
+	}
+	return s.UpdateSettings_(settings)
+}
+
+func (s *MockUsersService) ChangePassword(change *PasswordChange) (*Auth, error) {
+	if s.ChangePassword_ == nil {
+		return nil, nil
+	}
+	return s.ChangePassword_(change)
+}
+
+func (s *MockUsersService) RequestPasswordReset(login string) error {
+	if s.RequestPasswordReset_ == nil {
+		return nil
+	}
+	return s.RequestPasswordReset_(login)
+}
+
+func (s *MockUsersService) ResetPassword(reset *PasswordReset) (*Auth, error) {
+	if s.ResetPassword_ == nil {
+		return nil, nil
+	}
+	return s.ResetPassword_(reset)
+}
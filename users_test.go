@@ -0,0 +1,328 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestUsersService_Signup(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Auth{User: &User{ID: 1, Login: "alice"}, Token: "tok"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Signup, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"Login":"alice","Email":"","Password":"password"}`+"\n")
+
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, want)
+	})
+
+	auth, err := client.Users.Signup(&NewUser{Login: "alice", Password: "password"})
+	if err != nil {
+		t.Errorf("Users.Signup returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.User.RegisteredAt)
+	if !reflect.DeepEqual(auth, want) {
+		t.Errorf("Users.Signup returned %+v, want %+v", auth, want)
+	}
+}
+
+func TestUsersService_Authenticate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Auth{User: &User{ID: 1, Login: "alice"}, Token: "tok"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Authenticate, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"Login":"alice","Password":"password"}`+"\n")
+
+		writeJSON(w, want)
+	})
+
+	auth, err := client.Users.Authenticate("alice", "password")
+	if err != nil {
+		t.Errorf("Users.Authenticate returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.User.RegisteredAt)
+	if !reflect.DeepEqual(auth, want) {
+		t.Errorf("Users.Authenticate returned %+v, want %+v", auth, want)
+	}
+}
+
+func TestUsersService_Current(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &User{ID: 1, Login: "alice"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.CurrentUser, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		if got, want := r.Header.Get("Authorization"), "Bearer tok"; got != want {
+			t.Errorf("got Authorization header %q, want %q", got, want)
+		}
+
+		writeJSON(w, want)
+	})
+
+	user, err := client.WithAuthToken("tok").Users.Current()
+	if err != nil {
+		t.Errorf("Users.Current returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.RegisteredAt)
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("Users.Current returned %+v, want %+v", user, want)
+	}
+}
+
+func TestUsersService_Get(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &User{ID: 1, Login: "alice", Karma: 3}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.User, map[string]string{"Login": "alice"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, want)
+	})
+
+	user, err := client.Users.Get("alice")
+	if err != nil {
+		t.Errorf("Users.Get returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.RegisteredAt)
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("Users.Get returned %+v, want %+v", user, want)
+	}
+}
+
+func TestUsersService_SetShadowBanned(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.ShadowBanUser, map[string]string{"Login": "alice"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+		testBody(t, r, `{"ShadowBanned":true}`+"\n")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Users.SetShadowBanned("alice", true); err != nil {
+		t.Errorf("Users.SetShadowBanned returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestUsersService_UpdateSettings(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &User{ID: 1, Login: "alice", DisplayName: "Alice", Theme: ThemeDark}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.UpdateUserSettings, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+		testBody(t, r, `{"DisplayName":"Alice","Email":"","DigestSubscribed":false,"Theme":"dark","DefaultSort":""}`+"\n")
+
+		writeJSON(w, want)
+	})
+
+	user, err := client.Users.UpdateSettings(&UserSettings{DisplayName: "Alice", Theme: ThemeDark})
+	if err != nil {
+		t.Errorf("Users.UpdateSettings returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.RegisteredAt)
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("Users.UpdateSettings returned %+v, want %+v", user, want)
+	}
+}
+
+func TestUsersService_ChangePassword(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Auth{User: &User{ID: 1, Login: "alice"}, Token: "newtok"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.ChangePassword, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+		testBody(t, r, `{"CurrentPassword":"old","NewPassword":"new"}`+"\n")
+
+		writeJSON(w, want)
+	})
+
+	auth, err := client.Users.ChangePassword(&PasswordChange{CurrentPassword: "old", NewPassword: "new"})
+	if err != nil {
+		t.Errorf("Users.ChangePassword returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.User.RegisteredAt)
+	if !reflect.DeepEqual(auth, want) {
+		t.Errorf("Users.ChangePassword returned %+v, want %+v", auth, want)
+	}
+}
+
+func TestUsersService_RequestPasswordReset(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.RequestPasswordReset, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"Login":"alice@example.com"}`+"\n")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Users.RequestPasswordReset("alice@example.com"); err != nil {
+		t.Errorf("Users.RequestPasswordReset returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestUsersService_ResetPassword(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Auth{User: &User{ID: 1, Login: "alice"}, Token: "tok"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.ResetPassword, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+		testBody(t, r, `{"Token":"reset","NewPassword":"new"}`+"\n")
+
+		writeJSON(w, want)
+	})
+
+	auth, err := client.Users.ResetPassword(&PasswordReset{Token: "reset", NewPassword: "new"})
+	if err != nil {
+		t.Errorf("Users.ResetPassword returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.User.RegisteredAt)
+	if !reflect.DeepEqual(auth, want) {
+		t.Errorf("Users.ResetPassword returned %+v, want %+v", auth, want)
+	}
+}
+
+func TestUserSettings_Validate(t *testing.T) {
+	tests := []struct {
+		settings UserSettings
+		valid    bool
+	}{
+		{UserSettings{}, true},
+		{UserSettings{DisplayName: "Alice", Email: "alice@example.com", DigestSubscribed: true, Theme: ThemeDark, DefaultSort: SortNew}, true},
+		{UserSettings{DisplayName: strings.Repeat("a", MaxDisplayNameLength+1)}, false},
+		{UserSettings{Email: "alice"}, false},
+		{UserSettings{DigestSubscribed: true}, false},
+		{UserSettings{Theme: "neon"}, false},
+		{UserSettings{DefaultSort: "random"}, false},
+	}
+	for _, test := range tests {
+		if err := test.settings.Validate(); (err == nil) != test.valid {
+			t.Errorf("%+v: got error %v, want valid %v", test.settings, err, test.valid)
+		}
+	}
+}
+
+func TestNewUser_Validate(t *testing.T) {
+	tests := []struct {
+		user  NewUser
+		valid bool
+	}{
+		{NewUser{Login: "alice", Password: "password"}, true},
+		{NewUser{Login: "a_b-c1", Password: "password"}, true},
+		{NewUser{Login: "a", Password: "password"}, false},
+		{NewUser{Login: "al ice", Password: "password"}, false},
+		{NewUser{Login: "alice", Password: "short"}, false},
+	}
+	for _, test := range tests {
+		if err := test.user.Validate(); (err == nil) != test.valid {
+			t.Errorf("%+v: got error %v, want valid == %v", test.user, err, test.valid)
+		}
+	}
+}
+
+func TestUser_HasRole(t *testing.T) {
+	tests := []struct {
+		user *User
+		role string
+		want bool
+	}{
+		{nil, RoleMember, false},
+		{&User{}, RoleMember, true},
+		{&User{}, RoleModerator, false},
+		{&User{Role: RoleMember}, RoleModerator, false},
+		{&User{Role: RoleModerator}, RoleMember, true},
+		{&User{Role: RoleModerator}, RoleModerator, true},
+		{&User{Role: RoleModerator}, RoleAdmin, false},
+		{&User{Role: RoleAdmin}, RoleModerator, true},
+		{&User{Role: RoleAdmin}, "superuser", false},
+		{&User{Role: "superuser"}, RoleMember, false},
+	}
+	for _, test := range tests {
+		if got := test.user.HasRole(test.role); got != test.want {
+			t.Errorf("%+v.HasRole(%q): got %v, want %v", test.user, test.role, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package thesrc
+
+import (
+	"errors"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A VoteSuspect is a user whose recent votes match a pattern of vote fraud,
+// such as a voting ring, as found by vote analysis (see the "thesrc serve
+// -vote-analysis" flag). Suspects wait in the moderation queue until a
+// moderator nullifies their votes or dismisses them.
+type VoteSuspect struct {
+	// UserID is the ID of the suspected user.
+	UserID int
+
+	// Login is the suspected user's login.
+	Login string
+
+	// Reason describes the patterns that the user's votes matched, such as
+	// "ring: voted for 4 posts by alice, who voted for 3 of theirs".
+	Reason string
+
+	// FlaggedAt is when the user's votes last matched a pattern.
+	FlaggedAt time.Time
+
+	// Nullified is whether the user's votes on posts are excluded from
+	// posts' scores and velocities (as if they were shadow-banned). Posts
+	// they vote on while nullified don't count either.
+	Nullified bool `json:",omitempty"`
+
+	// Dismissed is whether a moderator dismissed the user as a suspect.
+	// Dismissed suspects aren't listed or flagged again.
+	Dismissed bool `json:",omitempty"`
+}
+
+// VoteSuspectsService interacts with the vote fraud endpoints in thesrc's
+// API. Only moderators may use it.
+type VoteSuspectsService interface {
+	// List vote suspects that haven't been dismissed, most recently flagged
+	// first.
+	List(opt *ListOptions) ([]*VoteSuspect, error)
+
+	// SetNullified nullifies (or restores) the post votes of the suspect
+	// with the given login (see VoteSuspect.Nullified).
+	SetNullified(login string, nullified bool) error
+
+	// Dismiss clears the suspect with the given login, restoring their
+	// votes if they were nullified.
+	Dismiss(login string) error
+}
+
+// A VoteSuspectNullification is the body of a request to set a vote
+// suspect's Nullified field.
+type VoteSuspectNullification struct {
+	Nullified bool
+}
+
+var (
+	ErrVoteSuspectNotFound = errors.New("vote suspect not found")
+)
+
+type voteSuspectsService struct{ client *Client }
+
+func (s *voteSuspectsService) List(opt *ListOptions) ([]*VoteSuspect, error) {
+	url, err := s.client.url(router.VoteSuspects, nil, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var suspects []*VoteSuspect
+	_, err = s.client.Do(req, &suspects)
+	if err != nil {
+		return nil, err
+	}
+
+	return suspects, nil
+}
+
+func (s *voteSuspectsService) SetNullified(login string, nullified bool) error {
+	url, err := s.client.url(router.NullifyVoteSuspect, map[string]string{"Login": login}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), &VoteSuspectNullification{Nullified: nullified})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *voteSuspectsService) Dismiss(login string) error {
+	url, err := s.client.url(router.DismissVoteSuspect, map[string]string{"Login": login}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+type MockVoteSuspectsService struct {
+	List_         func(opt *ListOptions) ([]*VoteSuspect, error)
+	SetNullified_ func(login string, nullified bool) error
+	Dismiss_      func(login string) error
+}
+
+var _ VoteSuspectsService = &MockVoteSuspectsService{}
+
+func (s *MockVoteSuspectsService) List(opt *ListOptions) ([]*VoteSuspect, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
+
+func (s *MockVoteSuspectsService) SetNullified(login string, nullified bool) error {
+	if s.SetNullified_ == nil {
+		return nil
+	}
+	return s.SetNullified_(login, nullified)
+}
+
+func (s *MockVoteSuspectsService) Dismiss(login string) error {
+	if s.Dismiss_ == nil {
+		return nil
+	}
+	return s.Dismiss_(login)
+}
@@ -0,0 +1,84 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestVoteSuspectsService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*VoteSuspect{{UserID: 1, Login: "alice", Reason: "ring: voted for 3 posts by bob, who voted for 3 of theirs"}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.VoteSuspects, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"PerPage": "5"})
+
+		writeJSON(w, want)
+	})
+
+	suspects, err := client.VoteSuspects.List(&ListOptions{PerPage: 5})
+	if err != nil {
+		t.Errorf("VoteSuspects.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, s := range want {
+		normalizeTime(&s.FlaggedAt)
+	}
+	if !reflect.DeepEqual(suspects, want) {
+		t.Errorf("VoteSuspects.List returned %+v, want %+v", suspects, want)
+	}
+}
+
+func TestVoteSuspectsService_SetNullified(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.NullifyVoteSuspect, map[string]string{"Login": "alice"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+		testBody(t, r, `{"Nullified":true}`+"\n")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.VoteSuspects.SetNullified("alice", true); err != nil {
+		t.Errorf("VoteSuspects.SetNullified returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestVoteSuspectsService_Dismiss(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.DismissVoteSuspect, map[string]string{"Login": "alice"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.VoteSuspects.Dismiss("alice"); err != nil {
+		t.Errorf("VoteSuspects.Dismiss returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
@@ -0,0 +1,228 @@
+// Package votefraud analyzes recent votes for patterns of vote fraud, and
+// flags the users whose votes match them as vote suspects (see
+// thesrc.VoteSuspect) for moderators to review.
+//
+// The patterns are:
+//
+//   - rings: two users who each vote for several of the other's posts;
+//   - author affinity: a user who votes almost only for one author's posts
+//     (as sock puppet accounts do for their owner);
+//   - correlated timing: two users who repeatedly vote for the same posts
+//     within moments of each other;
+//   - shared IP addresses: two users who each cast several votes from the
+//     same IP address.
+//
+// IP addresses are only known for votes with client records (see
+// thesrc.ClientRecord), and only as hashes with a salt that rotates, so
+// votes from the same address in different salt periods don't match.
+package votefraud
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/logging"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
+)
+
+// DefaultWindow is the default value of Analyzer.Window.
+const DefaultWindow = 7 * 24 * time.Hour
+
+// Thresholds of the patterns.
+const (
+	// ringMinVotes is the number of each other's posts that both users in a
+	// ring must have voted for.
+	ringMinVotes = 3
+
+	// affinityMinVotes is the number of votes (for other users' posts) that
+	// a user must have cast to be analyzed for author affinity, and
+	// affinityMinShare is the share of those votes that must be for one
+	// author.
+	affinityMinVotes = 5
+	affinityMinShare = 0.8
+
+	// timingGap is how close together two users' votes for the same post
+	// must be to be correlated, and timingMinPosts is the number of posts
+	// that they must both have voted for that closely. timingMinShare is
+	// the share of the less active user's votes that must be correlated,
+	// so that two very active users aren't flagged just for voting on the
+	// same popular posts.
+	timingGap      = time.Minute
+	timingMinPosts = 5
+	timingMinShare = 0.5
+
+	// sharedIPMinVotes is the number of votes that each of two users must
+	// have cast from the same IP address. It is more than one so that users
+	// who happen to share an address (on the same network, say) once or
+	// twice aren't flagged.
+	sharedIPMinVotes = 3
+)
+
+var flagged = metrics.NewCounterVec("thesrc_vote_suspects_flagged_total",
+	"Users newly flagged as vote suspects by vote analysis, by whether their votes were nullified.", "nullified")
+
+// An Analyzer flags users whose recent votes match patterns of vote fraud.
+type Analyzer struct {
+	Store datastore.VoteSuspectsStore
+
+	// Window is the period (ending now) whose votes are analyzed. If 0,
+	// DefaultWindow is used.
+	Window time.Duration
+
+	// Nullify is whether newly flagged users' votes are nullified
+	// immediately, instead of counting until a moderator nullifies them.
+	Nullify bool
+}
+
+// RunOnce analyzes recent votes, flags the users whose votes match patterns
+// of vote fraud, and returns the number of users who weren't already
+// flagged.
+func (a *Analyzer) RunOnce() (int, error) {
+	window := a.Window
+	if window == 0 {
+		window = DefaultWindow
+	}
+	votes, err := a.Store.RecentVotes(time.Now().Add(-window))
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, suspect := range Analyze(votes) {
+		created, err := a.Store.Flag(suspect)
+		if err != nil {
+			return n, err
+		}
+		if !created {
+			continue
+		}
+		n++
+		if a.Nullify {
+			if err := a.Store.SetNullified(suspect.UserID, true); err != nil {
+				return n, err
+			}
+		}
+		flagged.Inc(strconv.FormatBool(a.Nullify))
+		logging.Default.Log("Flagged vote suspect", "user_id", suspect.UserID, "login", suspect.Login, "reason", suspect.Reason, "nullified", a.Nullify)
+	}
+	return n, nil
+}
+
+// Analyze returns the users whose votes match patterns of vote fraud, in
+// order of user ID. Votes for the voter's own posts are ignored.
+func Analyze(votes []*datastore.VoteRecord) []*thesrc.VoteSuspect {
+	logins := map[int]string{}
+	reasons := map[int][]string{}
+	flag := func(userID int, format string, args ...interface{}) {
+		reasons[userID] = append(reasons[userID], fmt.Sprintf(format, args...))
+	}
+
+	byVoter := map[int][]*datastore.VoteRecord{}
+	byPost := map[int][]*datastore.VoteRecord{}
+	votesFor := map[[2]int]int{}     // keyed by {voter, author}
+	byIP := map[string]map[int]int{} // number of votes by IP hash, then voter
+	for _, v := range votes {
+		logins[v.UserID] = v.Login
+		if v.AuthorUserID != 0 {
+			logins[v.AuthorUserID] = v.AuthorLogin
+		}
+		if v.AuthorUserID == v.UserID {
+			continue
+		}
+		byVoter[v.UserID] = append(byVoter[v.UserID], v)
+		byPost[v.PostID] = append(byPost[v.PostID], v)
+		if v.AuthorUserID != 0 {
+			votesFor[[2]int{v.UserID, v.AuthorUserID}]++
+		}
+		if v.IPHash != "" {
+			if byIP[v.IPHash] == nil {
+				byIP[v.IPHash] = map[int]int{}
+			}
+			byIP[v.IPHash][v.UserID]++
+		}
+	}
+
+	// Rings.
+	for key, n := range votesFor {
+		voter, author := key[0], key[1]
+		if voter < author && n >= ringMinVotes && votesFor[[2]int{author, voter}] >= ringMinVotes {
+			m := votesFor[[2]int{author, voter}]
+			flag(voter, "ring: voted for %d posts by %s, who voted for %d of theirs", n, logins[author], m)
+			flag(author, "ring: voted for %d posts by %s, who voted for %d of theirs", m, logins[voter], n)
+		}
+	}
+
+	// Author affinity.
+	for key, n := range votesFor {
+		voter, author := key[0], key[1]
+		total := len(byVoter[voter])
+		if total >= affinityMinVotes && float64(n) >= affinityMinShare*float64(total) {
+			flag(voter, "author affinity: %d of %d votes were for posts by %s", n, total, logins[author])
+		}
+	}
+
+	// Correlated timing.
+	together := map[[2]int]int{} // keyed by {user, user} (lower ID first)
+	for _, postVotes := range byPost {
+		sort.Slice(postVotes, func(i, j int) bool { return postVotes[i].VotedAt.Before(postVotes[j].VotedAt) })
+		pairs := map[[2]int]bool{}
+		for i, v := range postVotes {
+			for _, w := range postVotes[i+1:] {
+				if w.VotedAt.Sub(v.VotedAt) > timingGap {
+					break
+				}
+				pair := [2]int{v.UserID, w.UserID}
+				if pair[0] > pair[1] {
+					pair[0], pair[1] = pair[1], pair[0]
+				}
+				pairs[pair] = true
+			}
+		}
+		for pair := range pairs {
+			together[pair]++
+		}
+	}
+	for pair, n := range together {
+		fewest := len(byVoter[pair[0]])
+		if m := len(byVoter[pair[1]]); m < fewest {
+			fewest = m
+		}
+		if n >= timingMinPosts && float64(n) >= timingMinShare*float64(fewest) {
+			flag(pair[0], "timing: voted within %s of %s on %d posts", timingGap, logins[pair[1]], n)
+			flag(pair[1], "timing: voted within %s of %s on %d posts", timingGap, logins[pair[0]], n)
+		}
+	}
+
+	// Shared IP addresses. The same pair of users is only flagged once, for
+	// the address that they shared the most votes from.
+	shared := map[[2]int][2]int{} // keyed by {user, user} (lower ID first)
+	for _, voters := range byIP {
+		for u, n := range voters {
+			for w, m := range voters {
+				if u < w && n >= sharedIPMinVotes && m >= sharedIPMinVotes {
+					pair := [2]int{u, w}
+					if prev := shared[pair]; n+m > prev[0]+prev[1] {
+						shared[pair] = [2]int{n, m}
+					}
+				}
+			}
+		}
+	}
+	for pair, counts := range shared {
+		flag(pair[0], "shared IP: cast %d votes from an IP address that %s cast %d from", counts[0], logins[pair[1]], counts[1])
+		flag(pair[1], "shared IP: cast %d votes from an IP address that %s cast %d from", counts[1], logins[pair[0]], counts[0])
+	}
+
+	suspects := make([]*thesrc.VoteSuspect, 0, len(reasons))
+	for userID, rs := range reasons {
+		sort.Strings(rs)
+		suspects = append(suspects, &thesrc.VoteSuspect{UserID: userID, Login: logins[userID], Reason: strings.Join(rs, "; ")})
+	}
+	sort.Slice(suspects, func(i, j int) bool { return suspects[i].UserID < suspects[j].UserID })
+	return suspects
+}
@@ -0,0 +1,171 @@
+package votefraud
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+var start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// vote returns a vote by user on post (by author), minutes after start.
+func vote(user, post, author int, minutes float64) *datastore.VoteRecord {
+	login := func(id int) string { return string(rune('a' + id - 1)) }
+	return &datastore.VoteRecord{
+		UserID: user, Login: login(user),
+		PostID: post, AuthorUserID: author, AuthorLogin: login(author),
+		VotedAt: start.Add(time.Duration(minutes * float64(time.Minute))),
+	}
+}
+
+// reasons returns the reasons that each suspect was flagged, keyed by login.
+func reasons(suspects []*thesrc.VoteSuspect) map[string]string {
+	m := map[string]string{}
+	for _, s := range suspects {
+		m[s.Login] = s.Reason
+	}
+	return m
+}
+
+func TestAnalyze_ring(t *testing.T) {
+	var votes []*datastore.VoteRecord
+	for i := 0; i < 3; i++ {
+		votes = append(votes, vote(1, 10+i, 2, float64(60*i)), vote(2, 20+i, 1, float64(60*i+30)))
+	}
+	// c votes for a's posts too, but a doesn't vote for c's.
+	for i := 0; i < 3; i++ {
+		votes = append(votes, vote(3, 20+i, 1, float64(60*i+300)))
+	}
+
+	got := reasons(Analyze(votes))
+	if !strings.Contains(got["a"], "ring: voted for 3 posts by b") || !strings.Contains(got["b"], "ring: voted for 3 posts by a") {
+		t.Errorf("got reasons %q, want a and b flagged as a ring", got)
+	}
+	if _, present := got["c"]; present {
+		t.Errorf("got c flagged (%q), want only a and b", got["c"])
+	}
+}
+
+func TestAnalyze_authorAffinity(t *testing.T) {
+	var votes []*datastore.VoteRecord
+	for i := 0; i < 5; i++ {
+		votes = append(votes, vote(1, 10+i, 2, float64(60*i)))   // a votes only for b
+		votes = append(votes, vote(3, 10+i, 2, float64(60*i+7))) // c votes for b and others
+		votes = append(votes, vote(3, 20+i, 4, float64(60*i+9)))
+	}
+	// Votes for one's own posts don't count.
+	votes = append(votes, vote(2, 10, 2, 0))
+
+	got := reasons(Analyze(votes))
+	if want := "author affinity: 5 of 5 votes were for posts by b"; got["a"] != want {
+		t.Errorf("got a's reason %q, want %q", got["a"], want)
+	}
+	if len(got) != 1 {
+		t.Errorf("got suspects %q, want only a", got)
+	}
+}
+
+func TestAnalyze_timing(t *testing.T) {
+	var votes []*datastore.VoteRecord
+	for i := 0; i < 5; i++ {
+		post, author := 10+i, 100+i // all different authors
+		votes = append(votes, vote(1, post, author, float64(60*i)), vote(2, post, author, float64(60*i)+0.5))
+		votes = append(votes, vote(3, post, author, float64(60*i+30))) // c votes later
+	}
+
+	got := reasons(Analyze(votes))
+	if want := "timing: voted within 1m0s of b on 5 posts"; got["a"] != want {
+		t.Errorf("got a's reason %q, want %q", got["a"], want)
+	}
+	if _, present := got["b"]; !present {
+		t.Error("b wasn't flagged")
+	}
+	if _, present := got["c"]; present {
+		t.Errorf("got c flagged (%q), want only a and b", got["c"])
+	}
+}
+
+func TestAnalyze_sharedIP(t *testing.T) {
+	var votes []*datastore.VoteRecord
+	add := func(v *datastore.VoteRecord, ipHash string) {
+		v.IPHash = ipHash
+		votes = append(votes, v)
+	}
+	for i := 0; i < 3; i++ {
+		post, author := 10+i, 100+i // all different authors
+		add(vote(1, post, author, float64(600*i)), "x")
+		add(vote(2, post+10, author, float64(600*i+300)), "x")
+		add(vote(3, post+20, author, float64(600*i+400)), "y")
+	}
+	// c votes once from a and b's address.
+	add(vote(3, 40, 100, 5000), "x")
+	// Votes without client records don't match each other.
+	for i := 0; i < 3; i++ {
+		add(vote(4, 50+i, 100+i, float64(7000+600*i)), "")
+		add(vote(5, 60+i, 100+i, float64(7300+600*i)), "")
+	}
+
+	got := reasons(Analyze(votes))
+	if want := "shared IP: cast 3 votes from an IP address that b cast 3 from"; got["a"] != want {
+		t.Errorf("got a's reason %q, want %q", got["a"], want)
+	}
+	if len(got) != 2 {
+		t.Errorf("got suspects %q, want only a and b", got)
+	}
+}
+
+func TestAnalyzer_RunOnce(t *testing.T) {
+	d := datastore.NewMemoryDatastore()
+	var users []*thesrc.User
+	for _, login := range []string{"alice", "bob"} {
+		user := &thesrc.User{Login: login}
+		if err := d.Users.Create(user); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+	}
+	alice, bob := users[0], users[1]
+
+	// Alice and bob vote for each other's posts.
+	var bobPosts []*thesrc.Post
+	for i := 0; i < 3; i++ {
+		for _, author := range users {
+			post := &thesrc.Post{LinkURL: "http://example.com/" + author.Login + "/" + string(rune('0'+i)), AuthorUserID: author.ID}
+			if _, err := d.Posts.Submit(post); err != nil {
+				t.Fatal(err)
+			}
+			voter := alice
+			if author == alice {
+				voter = bob
+			} else {
+				bobPosts = append(bobPosts, post)
+			}
+			if err := d.Votes.Upvote(voter.ID, post.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	a := &Analyzer{Store: d.VoteSuspects, Nullify: true}
+	if n, err := a.RunOnce(); err != nil || n != 2 {
+		t.Fatalf("got %d flagged and error %v, want 2 and nil", n, err)
+	}
+	suspects, err := d.VoteSuspects.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suspects) != 2 || !suspects[0].Nullified || !suspects[1].Nullified {
+		t.Errorf("got suspects %+v, want alice and bob with nullified votes", suspects)
+	}
+	if p, _ := d.Posts.Get(bobPosts[0].ID); p.Score != 0 {
+		t.Errorf("got score %d of bob's post, want 0 with alice's vote nullified", p.Score)
+	}
+
+	// Running again doesn't flag them again.
+	if n, err := a.RunOnce(); err != nil || n != 0 {
+		t.Errorf("got %d flagged and error %v running again, want 0 and nil", n, err)
+	}
+}
@@ -0,0 +1,95 @@
+package thesrc
+
+import (
+	"strconv"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// VotesService interacts with the vote-related endpoints in thesrc's API.
+// Votes are cast by the user that the client is authenticated as.
+type VotesService interface {
+	// Upvote a post. Upvoting a post that the user has already upvoted has
+	// no effect.
+	Upvote(postID int) error
+
+	// Unvote removes the user's upvote from a post, if any.
+	Unvote(postID int) error
+
+	// UpvoteComment upvotes a comment. Upvoting a comment that the user has
+	// already upvoted has no effect.
+	UpvoteComment(commentID int) error
+
+	// UnvoteComment removes the user's upvote from a comment, if any.
+	UnvoteComment(commentID int) error
+}
+
+type votesService struct{ client *Client }
+
+func (s *votesService) Upvote(postID int) error {
+	return s.vote("PUT", router.Upvote, postID)
+}
+
+func (s *votesService) Unvote(postID int) error {
+	return s.vote("DELETE", router.Unvote, postID)
+}
+
+func (s *votesService) UpvoteComment(commentID int) error {
+	return s.vote("PUT", router.UpvoteComment, commentID)
+}
+
+func (s *votesService) UnvoteComment(commentID int) error {
+	return s.vote("DELETE", router.UnvoteComment, commentID)
+}
+
+func (s *votesService) vote(method, routeName string, id int) error {
+	url, err := s.client.url(routeName, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest(method, url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+type MockVotesService struct {
+	Upvote_        func(postID int) error
+	Unvote_        func(postID int) error
+	UpvoteComment_ func(commentID int) error
+	UnvoteComment_ func(commentID int) error
+}
+
+var _ VotesService = &MockVotesService{}
+
+func (s *MockVotesService) Upvote(postID int) error {
+	if s.Upvote_ == nil {
+		return nil
+	}
+	return s.Upvote_(postID)
+}
+
+func (s *MockVotesService) Unvote(postID int) error {
+	if s.Unvote_ == nil {
+		return nil
+	}
+	return s.Unvote_(postID)
+}
+
+func (s *MockVotesService) UpvoteComment(commentID int) error {
+	if s.UpvoteComment_ == nil {
+		return nil
+	}
+	return s.UpvoteComment_(commentID)
+}
+
+func (s *MockVotesService) UnvoteComment(commentID int) error {
+	if s.UnvoteComment_ == nil {
+		return nil
+	}
+	return s.UnvoteComment_(commentID)
+}
@@ -0,0 +1,190 @@
+package thesrc
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A Webhook is a URL that the server notifies, with an HTTP POST request,
+// when posts are created, updated, or flagged. The request body is the
+// JSON-encoded Event, and its X-Thesrc-Signature header is "sha256=" followed
+// by the hex-encoded HMAC-SHA256 of the body, keyed by the webhook's Secret.
+type Webhook struct {
+	// ID a unique identifier for this webhook.
+	ID int `json:",omitempty"`
+
+	// URL is the http or https URL that events are POSTed to.
+	URL string
+
+	// Secret is the key used to sign the webhook's requests. It is only set
+	// in the response to WebhooksService.Create. If it is empty when the
+	// webhook is created, a random secret is generated.
+	Secret string `json:",omitempty"`
+
+	// CreatedAt is when the webhook was registered.
+	CreatedAt time.Time
+}
+
+// A WebhookDelivery is a record of an attempt to send an event to a webhook.
+// Failed deliveries are retried, and each attempt is recorded separately.
+type WebhookDelivery struct {
+	// ID a unique identifier for this delivery attempt.
+	ID int
+
+	// WebhookID is the ID of the webhook that the event was sent to.
+	WebhookID int
+
+	// Event is the type of the event that was sent (one of the Event*
+	// constants).
+	Event string
+
+	// PostID is the ID of the post that the event concerns.
+	PostID int
+
+	// Attempt is the number of this attempt (starting at 1) to deliver the
+	// event.
+	Attempt int
+
+	// StatusCode is the HTTP status code of the webhook's response, or 0 if
+	// it did not respond. The delivery succeeded if it is 2xx.
+	StatusCode int `json:",omitempty"`
+
+	// Error describes why the delivery failed, if it did.
+	Error string `json:",omitempty"`
+
+	// AttemptedAt is when the delivery was attempted.
+	AttemptedAt time.Time
+}
+
+// WebhooksService interacts with the webhook-related endpoints in thesrc's
+// API. Only admins may manage webhooks.
+type WebhooksService interface {
+	// List all registered webhooks (without their secrets).
+	List() ([]*Webhook, error)
+
+	// Create (register) a webhook. If successful, hook's ID, Secret, and
+	// CreatedAt are set.
+	Create(hook *Webhook) error
+
+	// Delete a webhook and its delivery log.
+	Delete(id int) error
+
+	// ListDeliveries lists a webhook's delivery attempts, most recent first.
+	ListDeliveries(id int, opt *ListOptions) ([]*WebhookDelivery, error)
+}
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+type webhooksService struct{ client *Client }
+
+func (s *webhooksService) List() ([]*Webhook, error) {
+	url, err := s.client.url(router.Webhooks, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []*Webhook
+	_, err = s.client.Do(req, &hooks)
+	if err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+func (s *webhooksService) Create(hook *Webhook) error {
+	url, err := s.client.url(router.CreateWebhook, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), hook)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, hook)
+	return err
+}
+
+func (s *webhooksService) Delete(id int) error {
+	url, err := s.client.url(router.DeleteWebhook, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *webhooksService) ListDeliveries(id int, opt *ListOptions) ([]*WebhookDelivery, error) {
+	url, err := s.client.url(router.WebhookDeliveries, map[string]string{"ID": strconv.Itoa(id)}, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []*WebhookDelivery
+	_, err = s.client.Do(req, &deliveries)
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+type MockWebhooksService struct {
+	List_           func() ([]*Webhook, error)
+	Create_         func(hook *Webhook) error
+	Delete_         func(id int) error
+	ListDeliveries_ func(id int, opt *ListOptions) ([]*WebhookDelivery, error)
+}
+
+var _ WebhooksService = &MockWebhooksService{}
+
+func (s *MockWebhooksService) List() ([]*Webhook, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_()
+}
+
+func (s *MockWebhooksService) Create(hook *Webhook) error {
+	if s.Create_ == nil {
+		return nil
+	}
+	return s.Create_(hook)
+}
+
+func (s *MockWebhooksService) Delete(id int) error {
+	if s.Delete_ == nil {
+		return nil
+	}
+	return s.Delete_(id)
+}
+
+func (s *MockWebhooksService) ListDeliveries(id int, opt *ListOptions) ([]*WebhookDelivery, error) {
+	if s.ListDeliveries_ == nil {
+		return nil, nil
+	}
+	return s.ListDeliveries_(id, opt)
+}
@@ -0,0 +1,256 @@
+// Package webhooks sends events (such as new posts) to the webhooks that
+// admins have registered, retrying failed deliveries with exponential
+// backoff (in process or on the job queue) and recording each attempt in the
+// delivery log.
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/metrics"
+)
+
+const (
+	// EventHeader is the HTTP header that contains the type of the event in
+	// a webhook request.
+	EventHeader = "X-Thesrc-Event"
+
+	// SignatureHeader is the HTTP header that contains a webhook request's
+	// signature (see Sign).
+	SignatureHeader = "X-Thesrc-Signature"
+)
+
+// Defaults for the Dispatcher fields of the same names.
+const (
+	DefaultMaxAttempts = 5
+	DefaultBackoff     = 10 * time.Second
+)
+
+// events are the types of events that are sent to webhooks.
+var events = map[string]bool{
+	thesrc.EventPostCreated: true,
+	thesrc.EventPostUpdated: true,
+	thesrc.EventPostFlagged: true,
+}
+
+var deliveries = metrics.NewCounterVec("thesrc_webhook_deliveries_total",
+	"Webhook delivery attempts, by result (success, failure, or error).", "result")
+
+// Sign returns the signature of a webhook request's payload: "sha256="
+// followed by the hex-encoded HMAC-SHA256 of payload, keyed by secret.
+// Receivers should compute it themselves and compare it to the value of the
+// SignatureHeader.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// A Dispatcher sends events to all registered webhooks.
+type Dispatcher struct {
+	Store datastore.WebhooksStore
+
+	// Client sends webhook requests. If nil, a client with a 10-second
+	// timeout is used.
+	Client *http.Client
+
+	// MaxAttempts is the number of times delivery of an event to a webhook is
+	// attempted before giving up. If 0, DefaultMaxAttempts is used.
+	MaxAttempts int
+
+	// Backoff is how long to wait before retrying a failed delivery. It
+	// doubles after each failed retry. If 0, DefaultBackoff is used.
+	Backoff time.Duration
+
+	// Jobs (if set) is the job queue that deliveries are queued on, as jobs
+	// of kind JobKind, so that they survive restarts. Workers run them with
+	// RunJob, retrying them with the job queue's backoff instead of Backoff.
+	Jobs datastore.JobsStore
+
+	wg sync.WaitGroup
+}
+
+// JobKind is the kind of the jobs that deliver events to webhooks (see
+// Dispatcher.Jobs).
+const JobKind = "webhook"
+
+// jobPayload is the payload of a job that delivers an event to a webhook.
+type jobPayload struct {
+	WebhookID int
+	Event     *thesrc.Event
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// Run sends the events received on c to all registered webhooks, until c is
+// closed. Deliveries (and their retries) happen in the background; call Wait
+// to wait for them to finish.
+func (d *Dispatcher) Run(c <-chan *thesrc.Event) {
+	for e := range c {
+		if !events[e.Type] {
+			continue
+		}
+
+		hooks, err := d.Store.List()
+		if err != nil {
+			log.Printf("Webhooks: %s (event %s dropped)", err, e.Type)
+			continue
+		}
+		for _, hook := range hooks {
+			if d.Jobs != nil {
+				d.enqueue(hook, e)
+				continue
+			}
+			d.wg.Add(1)
+			go func(hook *thesrc.Webhook, e *thesrc.Event) {
+				defer d.wg.Done()
+				d.deliver(hook, e)
+			}(hook, e)
+		}
+	}
+}
+
+// Wait waits for all deliveries started by Run to finish.
+func (d *Dispatcher) Wait() { d.wg.Wait() }
+
+func (d *Dispatcher) maxAttempts() int {
+	if d.MaxAttempts == 0 {
+		return DefaultMaxAttempts
+	}
+	return d.MaxAttempts
+}
+
+// enqueue queues a job that delivers e to hook.
+func (d *Dispatcher) enqueue(hook *thesrc.Webhook, e *thesrc.Event) {
+	payload, err := json.Marshal(jobPayload{WebhookID: hook.ID, Event: e})
+	if err != nil {
+		log.Printf("Webhook %d: %s", hook.ID, err)
+		return
+	}
+	if err := d.Jobs.Enqueue(&thesrc.Job{Kind: JobKind, Payload: string(payload), MaxAttempts: d.maxAttempts()}); err != nil {
+		log.Printf("Webhook %d: %s (event %s dropped)", hook.ID, err, e.Type)
+	}
+}
+
+// RunJob runs a job queued by Run (see Dispatcher.Jobs), making one attempt
+// to deliver its event. It returns an error if the attempt failed, so that
+// the job is retried. If the webhook has since been deleted, the job does
+// nothing.
+func (d *Dispatcher) RunJob(job *thesrc.Job) error {
+	var p jobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &p); err != nil {
+		return err
+	}
+	if p.Event == nil {
+		return fmt.Errorf("webhook job %d has no event", job.ID)
+	}
+
+	hooks, err := d.Store.List()
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if hook.ID == p.WebhookID {
+			payload, err := json.Marshal(p.Event)
+			if err != nil {
+				return err
+			}
+			return d.attempt(hook, p.Event, payload, job.Attempts)
+		}
+	}
+	return nil
+}
+
+// deliver sends e to hook, retrying until the hook responds with a 2xx
+// status or MaxAttempts attempts have been made.
+func (d *Dispatcher) deliver(hook *thesrc.Webhook, e *thesrc.Event) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("Webhook %d: %s", hook.ID, err)
+		return
+	}
+
+	maxAttempts, backoff := d.maxAttempts(), d.Backoff
+	if backoff == 0 {
+		backoff = DefaultBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		if d.attempt(hook, e, payload, attempt) == nil || attempt >= maxAttempts {
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// attempt sends e (encoded as payload) to hook once and records the attempt
+// in the delivery log. It returns an error unless the hook responded with a
+// 2xx status.
+func (d *Dispatcher) attempt(hook *thesrc.Webhook, e *thesrc.Event, payload []byte, attempt int) error {
+	var postID int
+	if e.Post != nil {
+		postID = e.Post.ID
+	}
+
+	rec := &thesrc.WebhookDelivery{WebhookID: hook.ID, Event: e.Type, PostID: postID, Attempt: attempt}
+	var err error
+	rec.StatusCode, err = d.send(hook, e.Type, payload)
+	switch {
+	case err != nil:
+		rec.Error = err.Error()
+		deliveries.Inc("error")
+	case rec.StatusCode < 200 || rec.StatusCode >= 300:
+		rec.Error = fmt.Sprintf("webhook responded with HTTP %d", rec.StatusCode)
+		deliveries.Inc("failure")
+	default:
+		deliveries.Inc("success")
+	}
+	if err := d.Store.AddDelivery(rec); err != nil {
+		log.Printf("Webhook %d: recording delivery: %s", hook.ID, err)
+	}
+
+	if rec.Error != "" {
+		return errors.New(rec.Error)
+	}
+	return nil
+}
+
+// send POSTs payload to hook and returns the HTTP status code of its
+// response.
+func (d *Dispatcher) send(hook *thesrc.Webhook, eventType string, payload []byte) (int, error) {
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "thesrc-webhooks")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, payload))
+
+	client := d.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
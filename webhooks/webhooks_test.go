@@ -0,0 +1,154 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+)
+
+func TestDispatcher(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if got, want := r.Header.Get(SignatureHeader), Sign("s", body); got != want {
+			t.Errorf("got signature %q, want %q", got, want)
+		}
+		if got, want := r.Header.Get(EventHeader), thesrc.EventPostCreated; got != want {
+			t.Errorf("got event header %q, want %q", got, want)
+		}
+		var e thesrc.Event
+		if err := json.Unmarshal(body, &e); err != nil || e.Post == nil || e.Post.ID != 3 {
+			t.Errorf("got payload %s (error %v), want event for post 3", body, err)
+		}
+
+		// Fail the first attempt, to test retrying.
+		if requests == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer s.Close()
+
+	store := datastore.NewMemoryDatastore().Webhooks
+	hook := &thesrc.Webhook{URL: s.URL, Secret: "s"}
+	if err := store.Create(hook); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan *thesrc.Event, 2)
+	c <- &thesrc.Event{Type: thesrc.EventPostScore, Post: &thesrc.Post{ID: 3}} // not sent to webhooks
+	c <- &thesrc.Event{Type: thesrc.EventPostCreated, Post: &thesrc.Post{ID: 3}}
+	close(c)
+
+	d := &Dispatcher{Store: store, Backoff: time.Millisecond}
+	d.Run(c)
+	d.Wait()
+
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2", requests)
+	}
+
+	deliveries, err := store.ListDeliveries(hook.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 2 {
+		t.Fatalf("got %d deliveries, want 2", len(deliveries))
+	}
+	if d := deliveries[0]; d.Attempt != 2 || d.StatusCode != http.StatusOK || d.Error != "" || d.PostID != 3 {
+		t.Errorf("got latest delivery %+v, want successful second attempt", d)
+	}
+	if d := deliveries[1]; d.Attempt != 1 || d.StatusCode != http.StatusInternalServerError || d.Error == "" {
+		t.Errorf("got first delivery %+v, want failed first attempt", d)
+	}
+}
+
+func TestDispatcher_maxAttempts(t *testing.T) {
+	store := datastore.NewMemoryDatastore().Webhooks
+	hook := &thesrc.Webhook{URL: "http://127.0.0.1:0/unreachable"}
+	if err := store.Create(hook); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan *thesrc.Event, 1)
+	c <- &thesrc.Event{Type: thesrc.EventPostFlagged, Post: &thesrc.Post{ID: 1}}
+	close(c)
+
+	d := &Dispatcher{Store: store, MaxAttempts: 3, Backoff: time.Millisecond}
+	d.Run(c)
+	d.Wait()
+
+	deliveries, _ := store.ListDeliveries(hook.ID, nil)
+	if len(deliveries) != 3 {
+		t.Fatalf("got %d deliveries, want 3", len(deliveries))
+	}
+	for _, d := range deliveries {
+		if d.Error == "" || d.StatusCode != 0 {
+			t.Errorf("got delivery %+v, want connection error", d)
+		}
+	}
+}
+
+func TestDispatcher_jobs(t *testing.T) {
+	var requests int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get(EventHeader) != thesrc.EventPostCreated {
+			t.Errorf("got event header %q", r.Header.Get(EventHeader))
+		}
+	}))
+	defer s.Close()
+
+	ds := datastore.NewMemoryDatastore()
+	hook := &thesrc.Webhook{URL: s.URL, Secret: "s"}
+	if err := ds.Webhooks.Create(hook); err != nil {
+		t.Fatal(err)
+	}
+
+	c := make(chan *thesrc.Event, 1)
+	c <- &thesrc.Event{Type: thesrc.EventPostCreated, Post: &thesrc.Post{ID: 3}}
+	close(c)
+
+	d := &Dispatcher{Store: ds.Webhooks, Jobs: ds.Jobs, MaxAttempts: 2}
+	d.Run(c)
+	d.Wait()
+	if requests != 0 {
+		t.Errorf("got %d requests before running the job, want 0", requests)
+	}
+
+	job, err := ds.Jobs.Claim(time.Now(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job == nil || job.Kind != JobKind || job.MaxAttempts != 2 {
+		t.Fatalf("got job %+v, want a queued %s job with MaxAttempts 2", job, JobKind)
+	}
+	if err := d.RunJob(job); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+	if deliveries, _ := ds.Webhooks.ListDeliveries(hook.ID, nil); len(deliveries) != 1 || deliveries[0].PostID != 3 || deliveries[0].Attempt != 1 {
+		t.Errorf("got deliveries %+v, want 1 delivery of the event for post 3", deliveries)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Computed with: printf 'payload' | openssl dgst -sha256 -hmac secret
+	want := "sha256=b82fcb791acec57859b989b430a826488ce2e479fdf92326bd0a2e8375a42ba4"
+	if got := Sign("secret", []byte("payload")); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
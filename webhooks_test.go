@@ -0,0 +1,124 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestWebhooksService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*Webhook{{ID: 1, URL: "http://example.com/hook"}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Webhooks, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, want)
+	})
+
+	hooks, err := client.Webhooks.List()
+	if err != nil {
+		t.Errorf("Webhooks.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, hook := range want {
+		normalizeTime(&hook.CreatedAt)
+	}
+	if !reflect.DeepEqual(hooks, want) {
+		t.Errorf("Webhooks.List returned %+v, want %+v", hooks, want)
+	}
+}
+
+func TestWebhooksService_Create(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Webhook{ID: 1, URL: "http://example.com/hook", Secret: "s"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.CreateWebhook, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"URL":"http://example.com/hook","CreatedAt":"0001-01-01T00:00:00Z"}`+"\n")
+
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, want)
+	})
+
+	hook := &Webhook{URL: "http://example.com/hook"}
+	if err := client.Webhooks.Create(hook); err != nil {
+		t.Errorf("Webhooks.Create returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.CreatedAt)
+	if !reflect.DeepEqual(hook, want) {
+		t.Errorf("Webhooks.Create returned %+v, want %+v", hook, want)
+	}
+}
+
+func TestWebhooksService_Delete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.DeleteWebhook, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.Webhooks.Delete(1); err != nil {
+		t.Errorf("Webhooks.Delete returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestWebhooksService_ListDeliveries(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*WebhookDelivery{{ID: 2, WebhookID: 1, Event: EventPostCreated, PostID: 3, Attempt: 1, StatusCode: 200}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.WebhookDeliveries, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"PerPage": "5"})
+
+		writeJSON(w, want)
+	})
+
+	deliveries, err := client.Webhooks.ListDeliveries(1, &ListOptions{PerPage: 5})
+	if err != nil {
+		t.Errorf("Webhooks.ListDeliveries returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, d := range want {
+		normalizeTime(&d.AttemptedAt)
+	}
+	if !reflect.DeepEqual(deliveries, want) {
+		t.Errorf("Webhooks.ListDeliveries returned %+v, want %+v", deliveries, want)
+	}
+}